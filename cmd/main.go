@@ -1,109 +1,174 @@
 package main
 
 import (
-	"fmt"
-	"mygram/internal/handler"
+	"context"
+	"errors"
+	"mygram/internal/app"
 	"mygram/internal/infrastructure"
-	"mygram/internal/model"
-	"mygram/internal/repository"
-	"mygram/internal/router"
+	"mygram/internal/mailer"
+	"mygram/internal/metrics"
+	"mygram/internal/middleware"
+	"mygram/internal/password"
+	"mygram/internal/ranking"
+	"mygram/internal/ratelimit"
+	"mygram/internal/search"
 	"mygram/internal/service"
-	"mygram/pkg"
-	"mygram/pkg/helper"
+	"mygram/internal/sso"
+	"mygram/internal/tracing"
+	"net"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-// @title			GO PROJECT STRUCTURE
-// @version		2.0
-// @description	golang project structure
-// @termsOfService	http://swagger.io/terms/
-// @contact.name	API Support
-// @contact.url	http://www.swagger.io/support
-// @contact.email	support@swagger.io
-// @license.name	Apache 2.0
-// @license.url	http://www.apache.org/licenses/LICENSE-2.0.html
-// @host			localhost:3000
-// @BasePath		/
-// @schemes		http
 func main() {
+	shutdownTracing, err := tracing.Init(context.Background(), tracing.ConfigFromEnv())
+	if err != nil {
+		panic(err)
+	}
+	defer shutdownTracing(context.Background())
+
 	g := gin.Default()
+	// handlers pass *gin.Context down as context.Context, let it fall back
+	// to the request context so the tracing span reaches services and gorm
+	g.ContextWithFallback = true
+	// X-Forwarded-For is only believed from the proxies in TRUSTED_PROXIES,
+	// comma separated addresses or CIDRs, the client address is otherwise
+	// that of the connection
+	var trustedProxies []string
+	if proxies := os.Getenv("TRUSTED_PROXIES"); proxies != "" {
+		trustedProxies = strings.Split(proxies, ",")
+	}
+	if err := g.SetTrustedProxies(trustedProxies); err != nil {
+		panic(err)
+	}
 	// requirement technical:
 	// [x] middleware untuk recover ketika panic
 	// [x] mengecheck basic auth
 
 	g.Use(gin.Recovery())
 
-	// /public => generate JWT public
-	g.GET("/public", func(ctx *gin.Context) {
-		now := time.Now()
-
-		claim := model.StandardClaim{
-			Jti: fmt.Sprintf("%v", time.Now().UnixNano()),
-			Iss: "go-middleware",
-			Aud: "golang-006",
-			Sub: "public-token",
-			Exp: uint64(now.Add(time.Hour).Unix()),
-			Iat: uint64(now.Unix()),
-			Nbf: uint64(now.Unix()),
-		}
-		token, err := helper.GenerateToken(claim)
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{
-				Message: "error generating public token",
-				Errors:  []string{err.Error()},
-			})
-			return
+	// metrics
+	appMetrics := metrics.NewDefault()
+	g.Use(middleware.Metrics(appMetrics))
+	g.Use(middleware.Tracing())
+
+	// /metrics is served on its own port, METRICS_ADDR, kept off the public
+	// API
+	metricsAddr := ":9090"
+	if addr := os.Getenv("METRICS_ADDR"); addr != "" {
+		metricsAddr = addr
+	}
+	metricsServer := &http.Server{Addr: metricsAddr, Handler: appMetrics.Handler()}
+	defer metricsServer.Shutdown(context.Background())
+	go func() {
+		if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			panic(err)
 		}
-		ctx.JSON(http.StatusOK, map[string]any{"token": token})
-	})
+	}()
 
-	gorm := infrastructure.NewGormPostgres()
+	gorm := infrastructure.NewGormPostgres(infrastructure.PostgresConfigFromEnv())
+	defer gorm.Close()
+	for _, node := range gorm.Nodes() {
+		if err := appMetrics.InstrumentGorm(node.DB, "mygram-"+node.Name); err != nil {
+			panic(err)
+		}
+		if err := tracing.InstrumentGorm(node.DB); err != nil {
+			panic(err)
+		}
+	}
+	g.Use(middleware.DBSession)
+	uow := infrastructure.NewUnitOfWork(gorm)
 	
 	// usersGroup.Use(middleware.CheckAuthBasic)
 	// usersGroup.Use(middleware.CheckAuthBearer)
-	
-	
-	usersGroup := g.Group("/users")
+
+	// rate limiting, shared through redis when more than one instance runs
+	var rateLimitStore interface {
+		ratelimit.Store
+		ratelimit.LockoutStore
+	} = ratelimit.NewMemoryStore()
+	if addr := os.Getenv("REDIS_ADDR"); addr != "" {
+		rateLimitStore = ratelimit.NewRedisStore(redis.NewClient(&redis.Options{Addr: addr}))
+	}
+
+	accountCfg := service.DefaultAccountConfig()
+	if baseURL := os.Getenv("APP_BASE_URL"); baseURL != "" {
+		accountCfg.BaseURL = baseURL
+	}
+
+	// unversioned paths, announce a sunset with API_UNVERSIONED_SUNSET
+	unversioned := app.DefaultUnversionedDeprecation()
+	if sunset := os.Getenv("API_UNVERSIONED_SUNSET"); sunset != "" {
+		at, err := time.Parse(time.RFC3339, sunset)
+		if err != nil {
+			panic(err)
+		}
+		unversioned.Sunset = at
+	}
+
 	// dependency injection
-	userRepo := repository.NewUserQuery(gorm)
-	userSvc := service.NewUserService(userRepo)
-	userHdl := handler.NewUserHandler(userSvc)
-	userRouter := router.NewUserRouter(usersGroup, userHdl)
+	deps := app.Dependencies{
+		UnitOfWork:     uow,
+		Metrics:        appMetrics,
+		Lockout:        ratelimit.NewLockout(rateLimitStore, ratelimit.DefaultLockoutPolicy()),
+		RateLimiter:    middleware.NewRateLimiter(rateLimitStore, ratelimit.DefaultConfig()),
+		Mailer:         mailer.New(mailer.ConfigFromEnv()),
+		Account:        accountCfg,
+		SSOProviders:   sso.ProvidersFromEnv(),
+		Passwords:      password.NewHasher(password.ParamsFromEnv()),
+		PasswordPolicy: password.DefaultPolicy(),
+		Search:         search.NewPostgresIndex(gorm),
+		Unversioned:    unversioned,
+	}
+	repos := app.NewRepositories(gorm)
+	svcs := app.NewServices(repos, deps)
+
+	// explore ranking
+	rankingJob := ranking.NewJob(repos.Ranking, uow, ranking.DefaultTimeDecay(), ranking.DefaultJobConfig())
+	go rankingJob.Run(context.Background())
+
+	// idempotency keys outlive their TTL until purged
+	go service.PurgeIdempotencyKeys(context.Background(), svcs.Idempotency, time.Hour)
+
 	// mount
-	userRouter.Mount()
-
-
-	photosGroup := g.Group("/photos")
-	photoRepo := repository.NewPhotoQuery(gorm)
-	photoSvc := service.NewPhotoService(photoRepo)
-	photoHdl := handler.NewPhotoHandler(photoSvc)
-	photoRouter := router.NewPhotoRouter(photosGroup, photoHdl)
-	photoRouter.Mount()
-
-	commentsGroup := g.Group("/comments")
-	commentRepo := repository.NewCommentQuery(gorm)
-	commentSvc := service.NewCommentService(commentRepo)
-	commentHdl := handler.NewCommentHandler(commentSvc, photoSvc)
-	commentRouter := router.NewCommentRouter(commentsGroup, commentHdl)
-	commentRouter.Mount()
-
-	socialMediaGroup := g.Group("/social-medias")
-	socialMediaRepo := repository.NewSocialMediaQuery(gorm)
-	socialMediaSvc := service.NewSocialMediaService(socialMediaRepo)
-	socialMediaHdl := handler.NewSocialMediaHandler(socialMediaSvc)
-	socialMediaRouter := router.NewSocialMediaRouter(socialMediaGroup, socialMediaHdl)
-	socialMediaRouter.Mount()
+	app.Mount(&g.RouterGroup, svcs, deps)
 
-	
+	// grpc for internal backends, on its own port, only reachable from the
+	// host unless GRPC_ADDR says otherwise, reflection with GRPC_REFLECTION
+	grpcAddr := "127.0.0.1:3001"
+	if addr := os.Getenv("GRPC_ADDR"); addr != "" {
+		grpcAddr = addr
+	}
+	grpcCfg := app.GRPCConfig{}
+	if reflection := os.Getenv("GRPC_REFLECTION"); reflection != "" {
+		enabled, err := strconv.ParseBool(reflection)
+		if err != nil {
+			panic(err)
+		}
+		grpcCfg.Reflection = enabled
+	}
+	grpcListener, err := net.Listen("tcp", grpcAddr)
+	if err != nil {
+		panic(err)
+	}
+	grpcServer := app.NewGRPCServer(svcs, grpcCfg)
+	defer grpcServer.GracefulStop()
+	go func() {
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			panic(err)
+		}
+	}()
 
-	
 	// swagger
-	g.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	g.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, ginSwagger.URL("/openapi.json")))
 
 	g.Run(":3000")
 	// Product:
@@ -0,0 +1,395 @@
+// Package apitest wires the real services, handlers and routers on top of
+// the in-memory repositories, for HTTP and gRPC level tests without
+// Postgres.
+package apitest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mygram/internal/app"
+	"mygram/internal/mailer"
+	"mygram/internal/metrics"
+	"mygram/internal/middleware"
+	"mygram/internal/model"
+	"mygram/internal/password"
+	"mygram/internal/ranking"
+	"mygram/internal/ratelimit"
+	"mygram/internal/repository/memory"
+	"mygram/internal/search"
+	"mygram/internal/service"
+	"mygram/internal/sso"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const DefaultPassword = "secret123"
+
+type Harness struct {
+	Engine         *gin.Engine
+	GRPC           *grpc.Server
+	Store          *memory.Store
+	RateLimitStore *ratelimit.MemoryStore
+	Outbox         *mailer.Outbox
+	Metrics        *metrics.Metrics
+	Search         *search.EmbeddedIndex
+	Ranking        *ranking.Job
+	Repos          app.Repositories
+	Services       app.Services
+}
+
+type options struct {
+	rateLimits  ratelimit.Config
+	lockout     ratelimit.LockoutPolicy
+	account     service.AccountConfig
+	sso         sso.Providers
+	passwords   password.Argon2idParams
+	policy      password.Policy
+	unversioned middleware.Deprecation
+	deprecated  map[string]middleware.Deprecation
+	proxies     []string
+	grpc        app.GRPCConfig
+}
+
+type Option func(*options)
+
+// WithRateLimits replaces ratelimit.DefaultConfig.
+func WithRateLimits(cfg ratelimit.Config) Option {
+	return func(o *options) {
+		o.rateLimits = cfg
+	}
+}
+
+// WithLockoutPolicy replaces ratelimit.DefaultLockoutPolicy.
+func WithLockoutPolicy(policy ratelimit.LockoutPolicy) Option {
+	return func(o *options) {
+		o.lockout = policy
+	}
+}
+
+// WithAccountConfig replaces service.DefaultAccountConfig.
+func WithAccountConfig(cfg service.AccountConfig) Option {
+	return func(o *options) {
+		o.account = cfg
+	}
+}
+
+// WithSSOProviders sets the OpenID Connect providers, there are none by
+// default.
+func WithSSOProviders(cfgs ...sso.ProviderConfig) Option {
+	return func(o *options) {
+		o.sso = sso.NewProviders(cfgs...)
+	}
+}
+
+// WithPasswordParams replaces password.DefaultArgon2idParams for new
+// hashes. Users from CreateUser keep a hash with the default parameters.
+func WithPasswordParams(params password.Argon2idParams) Option {
+	return func(o *options) {
+		o.passwords = params
+	}
+}
+
+// WithUnversionedDeprecation replaces app.DefaultUnversionedDeprecation.
+func WithUnversionedDeprecation(d middleware.Deprecation) Option {
+	return func(o *options) {
+		o.unversioned = d
+	}
+}
+
+// WithDeprecatedRoutes marks single routes deprecated, none are by default.
+func WithDeprecatedRoutes(routes map[string]middleware.Deprecation) Option {
+	return func(o *options) {
+		o.deprecated = routes
+	}
+}
+
+// WithTrustedProxies sets the proxies whose X-Forwarded-For is believed,
+// there are none by default.
+func WithTrustedProxies(proxies ...string) Option {
+	return func(o *options) {
+		o.proxies = proxies
+	}
+}
+
+// WithPasswordPolicy replaces password.DefaultPolicy.
+func WithPasswordPolicy(policy password.Policy) Option {
+	return func(o *options) {
+		o.policy = policy
+	}
+}
+
+// WithGRPCConfig replaces the gRPC configuration, without reflection by
+// default.
+func WithGRPCConfig(cfg app.GRPCConfig) Option {
+	return func(o *options) {
+		o.grpc = cfg
+	}
+}
+
+func New(opts ...Option) *Harness {
+	gin.SetMode(gin.TestMode)
+
+	o := options{
+		rateLimits:  ratelimit.DefaultConfig(),
+		lockout:     ratelimit.DefaultLockoutPolicy(),
+		account:     service.DefaultAccountConfig(),
+		passwords:   password.DefaultArgon2idParams(),
+		policy:      password.DefaultPolicy(),
+		unversioned: app.DefaultUnversionedDeprecation(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	store := memory.NewStore()
+	repos := app.Repositories{
+		User:                memory.NewUserQuery(store),
+		Photo:               memory.NewPhotoQuery(store),
+		Comment:             memory.NewCommentQuery(store),
+		SocialMedia:         memory.NewSocialMediaQuery(store),
+		UserToken:           memory.NewUserTokenQuery(store),
+		RecoveryCode:        memory.NewRecoveryCodeQuery(store),
+		PersonalAccessToken: memory.NewPersonalAccessTokenQuery(store),
+		UserIdentity:        memory.NewUserIdentityQuery(store),
+		Session:             memory.NewSessionQuery(store),
+		LoginAttempt:        memory.NewLoginAttemptQuery(store),
+		Follow:              memory.NewFollowQuery(store),
+		Block:               memory.NewBlockQuery(store),
+		Mute:                memory.NewMuteQuery(store),
+		Ranking:             memory.NewRankingQuery(store),
+		IdempotencyKey:      memory.NewIdempotencyKeyQuery(store),
+	}
+	rateLimitStore := ratelimit.NewMemoryStore()
+	outbox := mailer.NewOutbox()
+	index := search.NewEmbeddedIndex()
+	deps := app.Dependencies{
+		UnitOfWork:       memory.NewUnitOfWork(store),
+		Metrics:          metrics.NewNop(),
+		Lockout:          ratelimit.NewLockout(rateLimitStore, o.lockout),
+		RateLimiter:      middleware.NewRateLimiter(rateLimitStore, o.rateLimits),
+		Mailer:           outbox,
+		Account:          o.account,
+		SSOProviders:     o.sso,
+		Passwords:        password.NewHasher(o.passwords),
+		PasswordPolicy:   o.policy,
+		Search:           index,
+		Unversioned:      o.unversioned,
+		DeprecatedRoutes: o.deprecated,
+	}
+	svcs := app.NewServices(repos, deps)
+	job := ranking.NewJob(repos.Ranking, deps.UnitOfWork, ranking.DefaultTimeDecay(), ranking.DefaultJobConfig())
+	job.Now = func() time.Time { return store.Now() }
+
+	g := gin.New()
+	g.ContextWithFallback = true
+	if err := g.SetTrustedProxies(o.proxies); err != nil {
+		panic(err)
+	}
+	app.Mount(&g.RouterGroup, svcs, deps)
+
+	return &Harness{
+		Engine:         g,
+		GRPC:           app.NewGRPCServer(svcs, o.grpc),
+		Store:          store,
+		RateLimitStore: rateLimitStore,
+		Outbox:         outbox,
+		Metrics:        deps.Metrics,
+		Search:         index,
+		Ranking:        job,
+		Repos:          repos,
+		Services:       svcs,
+	}
+}
+
+// defaultPasswordHash is computed once, hashing per user makes the suite
+// spend most of its time in argon2id.
+var defaultPasswordHash = sync.OnceValues(func() (string, error) {
+	return password.NewHasher(password.DefaultArgon2idParams()).Hash(DefaultPassword)
+})
+
+// CreateUser stores username@example.com with DefaultPassword and a
+// verified email address.
+func (h *Harness) CreateUser(t testing.TB, username string) model.User {
+	t.Helper()
+
+	verifiedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return h.createUser(t, username, &verifiedAt)
+}
+
+// CreateUnverifiedUser is CreateUser for an account that has not confirmed
+// its email address yet.
+func (h *Harness) CreateUnverifiedUser(t testing.TB, username string) model.User {
+	t.Helper()
+
+	return h.createUser(t, username, nil)
+}
+
+func (h *Harness) createUser(t testing.TB, username string, verifiedAt *time.Time) model.User {
+	t.Helper()
+
+	hash, err := defaultPasswordHash()
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+	user, err := h.Repos.User.CreateUser(context.Background(), model.User{
+		Username: username,
+		Email:    fmt.Sprintf("%s@example.com", username),
+		Password: hash,
+		DoB:      time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+
+		EmailVerifiedAt: verifiedAt,
+	})
+	if err != nil {
+		t.Fatalf("create user %s: %v", username, err)
+	}
+	if err := h.Search.IndexUser(context.Background(), user); err != nil {
+		t.Fatalf("index user %s: %v", username, err)
+	}
+	return user
+}
+
+// Token mints the same access token the login endpoint hands out, for a
+// session from the device requests are sent from.
+func (h *Harness) Token(t testing.TB, user model.User) string {
+	t.Helper()
+
+	session, err := h.Services.Session.StartSession(context.Background(), user, harnessDevice, model.LOGIN_METHOD_PASSWORD, mailer.DefaultLocale)
+	if err != nil {
+		t.Fatalf("start session: %v", err)
+	}
+	token, err := h.Services.User.GenerateUserAccessToken(context.Background(), user, session)
+	if err != nil {
+		t.Fatalf("generate token: %v", err)
+	}
+	return token
+}
+
+// PublicToken gets a guest token from GET /public.
+func (h *Harness) PublicToken(t testing.TB) string {
+	t.Helper()
+
+	rec := h.Request(t, http.MethodGet, "/public", nil, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("public token: %d %s", rec.Code, rec.Body.String())
+	}
+	res := map[string]string{}
+	Decode(t, rec, &res)
+	return res["token"]
+}
+
+// harnessDevice is what the server sees of requests made by the harness,
+// httptest.NewRequest sends no User-Agent from a fixed address.
+var harnessDevice = model.Device{IP: "192.0.2.1"}
+
+// Request serves one request against the engine. body is JSON encoded
+// unless it is already a string, and token is sent as a bearer token when
+// not empty.
+func (h *Harness) Request(t testing.TB, method, path string, body any, token string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	authorization := ""
+	if token != "" {
+		authorization = "Bearer " + token
+	}
+	return h.RequestWithAuthorization(t, method, path, body, authorization)
+}
+
+// RequestWithAuthorization is Request with a raw Authorization header.
+func (h *Harness) RequestWithAuthorization(t testing.TB, method, path string, body any, authorization string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	header := http.Header{}
+	if authorization != "" {
+		header.Set("Authorization", authorization)
+	}
+	return h.RequestWithHeaders(t, method, path, body, header)
+}
+
+// RequestWithHeaders is Request with arbitrary headers.
+func (h *Harness) RequestWithHeaders(t testing.TB, method, path string, body any, header http.Header) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var payload []byte
+	switch b := body.(type) {
+	case nil:
+	case string:
+		payload = []byte(b)
+	default:
+		var err error
+		payload, err = json.Marshal(b)
+		if err != nil {
+			t.Fatalf("marshal body: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	rec := httptest.NewRecorder()
+	h.Engine.ServeHTTP(rec, req)
+	return rec
+}
+
+// GRPCConn serves GRPC in memory and connects to it, until the test ends.
+func (h *Harness) GRPCConn(t testing.TB) *grpc.ClientConn {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	go h.GRPC.Serve(lis)
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial grpc: %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		lis.Close()
+	})
+	return conn
+}
+
+// Decode unmarshals the recorded JSON body into out.
+func Decode(t testing.TB, rec *httptest.ResponseRecorder, out any) {
+	t.Helper()
+
+	if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+		t.Fatalf("decode %q: %v", rec.Body.String(), err)
+	}
+}
+
+// Route identifies a registered endpoint by method and path template.
+type Route struct {
+	Method string
+	Path   string
+}
+
+func (r Route) String() string {
+	return r.Method + " " + r.Path
+}
+
+// Routes lists what is registered on the engine.
+func (h *Harness) Routes() []Route {
+	routes := []Route{}
+	for _, r := range h.Engine.Routes() {
+		routes = append(routes, Route{Method: r.Method, Path: r.Path})
+	}
+	return routes
+}
@@ -0,0 +1,209 @@
+// Package app holds the dependency wiring shared by cmd/main.go and the
+// test harness, so both run the same services, handlers and routers.
+package app
+
+import (
+	"mygram/internal/handler"
+	"mygram/internal/infrastructure"
+	"mygram/internal/mailer"
+	"mygram/internal/metrics"
+	"mygram/internal/middleware"
+	"mygram/internal/openapi"
+	"mygram/internal/password"
+	"mygram/internal/ratelimit"
+	"mygram/internal/repository"
+	"mygram/internal/router"
+	"mygram/internal/rpc"
+	mygramv1 "mygram/internal/rpc/mygram/v1"
+	"mygram/internal/search"
+	"mygram/internal/service"
+	"mygram/internal/sso"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+type Repositories struct {
+	User                repository.UserQuery
+	Photo               repository.PhotoQuery
+	Comment             repository.CommentQuery
+	SocialMedia         repository.SocialMediaQuery
+	UserToken           repository.UserTokenQuery
+	RecoveryCode        repository.RecoveryCodeQuery
+	PersonalAccessToken repository.PersonalAccessTokenQuery
+	UserIdentity        repository.UserIdentityQuery
+	Session             repository.SessionQuery
+	LoginAttempt        repository.LoginAttemptQuery
+	Follow              repository.FollowQuery
+	Block               repository.BlockQuery
+	Mute                repository.MuteQuery
+	Ranking             repository.RankingQuery
+	IdempotencyKey      repository.IdempotencyKeyQuery
+}
+
+func NewRepositories(db infrastructure.GormPostgres) Repositories {
+	return Repositories{
+		User:                repository.NewUserQuery(db),
+		Photo:               repository.NewPhotoQuery(db),
+		Comment:             repository.NewCommentQuery(db),
+		SocialMedia:         repository.NewSocialMediaQuery(db),
+		UserToken:           repository.NewUserTokenQuery(db),
+		RecoveryCode:        repository.NewRecoveryCodeQuery(db),
+		PersonalAccessToken: repository.NewPersonalAccessTokenQuery(db),
+		UserIdentity:        repository.NewUserIdentityQuery(db),
+		Session:             repository.NewSessionQuery(db),
+		LoginAttempt:        repository.NewLoginAttemptQuery(db),
+		Follow:              repository.NewFollowQuery(db),
+		Block:               repository.NewBlockQuery(db),
+		Mute:                repository.NewMuteQuery(db),
+		Ranking:             repository.NewRankingQuery(db),
+		IdempotencyKey:      repository.NewIdempotencyKeyQuery(db),
+	}
+}
+
+type Services struct {
+	User                service.UserService
+	Photo               service.PhotoService
+	Comment             service.CommentService
+	SocialMedia         service.SocialMediaService
+	Account             service.AccountService
+	TwoFactor           service.TwoFactorService
+	PersonalAccessToken service.PersonalAccessTokenService
+	SocialLogin         service.SocialLoginService
+	Session             service.SessionService
+	Follow              service.FollowService
+	Block               service.BlockService
+	Search              service.SearchService
+	Explore             service.ExploreService
+	Idempotency         service.IdempotencyService
+}
+
+// Dependencies are the cross-cutting collaborators shared by services and
+// routers.
+type Dependencies struct {
+	UnitOfWork  infrastructure.UnitOfWork
+	Metrics     *metrics.Metrics
+	Lockout     *ratelimit.Lockout
+	RateLimiter *middleware.RateLimiter
+	Mailer      mailer.Mailer
+	Account     service.AccountConfig
+	// SSOProviders are the OpenID Connect providers users can sign in with.
+	SSOProviders sso.Providers
+	Passwords    password.Hasher
+	// PasswordPolicy applies to passwords chosen at sign up and reset.
+	PasswordPolicy password.Policy
+	// Search is kept in sync by the photo and user services.
+	Search search.Index
+	// Unversioned is how the aliases of /v1 at the root are deprecated.
+	Unversioned middleware.Deprecation
+	// DeprecatedRoutes are the single routes of /v1 on their way out, keyed
+	// by method and path such as "GET /photos/:id".
+	DeprecatedRoutes map[string]middleware.Deprecation
+}
+
+func NewServices(repos Repositories, deps Dependencies) Services {
+	return Services{
+		User:                service.NewUserService(repos.User, repos.Photo, repos.Comment, repos.SocialMedia, deps.UnitOfWork, deps.Metrics, deps.Lockout, deps.Passwords, deps.PasswordPolicy, deps.Search),
+		Photo:               service.NewPhotoService(repos.Photo, deps.UnitOfWork, deps.Metrics, deps.Search),
+		Comment:             service.NewCommentService(repos.Comment, repos.Photo, repos.User, repos.Block, deps.UnitOfWork, deps.Metrics),
+		SocialMedia:         service.NewSocialMediaService(repos.SocialMedia, deps.UnitOfWork),
+		Account:             service.NewAccountService(repos.User, repos.UserToken, repos.Session, repos.PersonalAccessToken, deps.UnitOfWork, deps.Mailer, deps.Account, deps.Passwords, deps.PasswordPolicy),
+		TwoFactor:           service.NewTwoFactorService(repos.User, repos.RecoveryCode, deps.UnitOfWork, deps.Lockout, deps.Passwords),
+		PersonalAccessToken: service.NewPersonalAccessTokenService(repos.PersonalAccessToken, repos.User),
+		SocialLogin:         service.NewSocialLoginService(deps.SSOProviders, repos.User, repos.UserIdentity, deps.UnitOfWork, deps.Passwords, deps.Search),
+		Session:             service.NewSessionService(repos.Session, repos.LoginAttempt, repos.User, deps.Mailer),
+		Follow:              service.NewFollowService(repos.Follow, repos.Block, repos.User, deps.UnitOfWork),
+		Block:               service.NewBlockService(repos.Block, repos.Mute, repos.Follow, repos.User, deps.UnitOfWork),
+		Search:              service.NewSearchService(deps.Search, repos.Photo, repos.User),
+		Explore:             service.NewExploreService(repos.Ranking),
+		Idempotency:         service.NewIdempotencyService(repos.IdempotencyKey),
+	}
+}
+
+// API_V1 is the prefix of the current version of the API.
+const API_V1 = "/v1"
+
+// DefaultUnversionedDeprecation deprecates the unversioned paths, kept as
+// aliases of /v1 while clients move over. Sunset is set once announced.
+func DefaultUnversionedDeprecation() middleware.Deprecation {
+	return middleware.Deprecation{
+		Since:     time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC),
+		Successor: API_V1,
+	}
+}
+
+// Mount registers every resource router under g, as /v1 and as its
+// deprecated unversioned aliases, the OpenAPI document of /v1 and /graphql.
+func Mount(g *gin.RouterGroup, svcs Services, deps Dependencies) {
+	v1 := NewV1(svcs, deps)
+	v1.Mount(g)
+	v1.MountAlias(g, middleware.Deprecated(deps.Unversioned))
+
+	doc := openapi.New(API_V1)
+	for route := range deps.DeprecatedRoutes {
+		method, path, _ := strings.Cut(route, " ")
+		doc.Deprecate(method, path)
+	}
+	router.NewOpenAPIRouter(handler.NewOpenAPIHandler(doc)).Mount(g)
+
+	auth := middleware.NewAuthenticator(svcs.PersonalAccessToken, svcs.Session)
+	graphQLHdl := handler.NewGraphQLHandler(svcs.User, svcs.Photo, svcs.Comment, svcs.SocialMedia, svcs.Explore, svcs.Account)
+	router.NewGraphQLRouter(graphQLHdl, deps.RateLimiter, auth).Mount(g)
+}
+
+// NewV1 builds the routers of /v1.
+func NewV1(svcs Services, deps Dependencies) router.Version {
+	auth := middleware.NewAuthenticator(svcs.PersonalAccessToken, svcs.Session)
+	verified := middleware.RequireVerifiedEmail(svcs.Account)
+	idempotent := middleware.Idempotent(svcs.Idempotency, API_V1)
+
+	publicHdl := handler.NewPublicHandler(svcs.User)
+	userHdl := handler.NewUserHandler(svcs.User, svcs.Account, svcs.TwoFactor, svcs.PersonalAccessToken, svcs.SocialLogin, svcs.Session, svcs.Follow, svcs.Block)
+	photoHdl := handler.NewPhotoHandler(svcs.Photo)
+	commentHdl := handler.NewCommentHandler(svcs.Comment)
+	socialMediaHdl := handler.NewSocialMediaHandler(svcs.SocialMedia)
+	searchHdl := handler.NewSearchHandler(svcs.Search)
+	exploreHdl := handler.NewExploreHandler(svcs.Explore)
+
+	return router.Version{
+		Prefix:     API_V1,
+		Deprecated: deps.DeprecatedRoutes,
+		Routers: []router.Router{
+			router.NewPublicRouter(publicHdl, deps.RateLimiter),
+			router.NewUserRouter(userHdl, deps.RateLimiter, auth),
+			router.NewPhotoRouter(photoHdl, deps.RateLimiter, auth, verified, idempotent),
+			router.NewCommentRouter(commentHdl, deps.RateLimiter, auth, verified, idempotent),
+			router.NewSocialMediaRouter(socialMediaHdl, deps.RateLimiter, auth, verified, idempotent),
+			router.NewSearchRouter(searchHdl, deps.RateLimiter, auth),
+			router.NewExploreRouter(exploreHdl, deps.RateLimiter, auth),
+		},
+	}
+}
+
+// GRPCConfig configures the gRPC server of the internal backends.
+type GRPCConfig struct {
+	// Reflection lists the services and their messages to anybody who can
+	// connect, for tools such as grpcurl.
+	Reflection bool
+}
+
+// NewGRPCServer builds the gRPC server of the internal backends, on the
+// same services as the routers, with health checking and, when configured,
+// reflection.
+func NewGRPCServer(svcs Services, cfg GRPCConfig) *grpc.Server {
+	auth := rpc.NewAuthenticator(svcs.PersonalAccessToken, svcs.Session)
+
+	s := grpc.NewServer(grpc.ChainUnaryInterceptor(rpc.Recover, rpc.Tracing(), rpc.DBSession, auth.Unary))
+	mygramv1.RegisterUserServiceServer(s, rpc.NewUserServer(svcs.User, svcs.Account))
+	mygramv1.RegisterPhotoServiceServer(s, rpc.NewPhotoServer(svcs.Photo, svcs.Account))
+	mygramv1.RegisterCommentServiceServer(s, rpc.NewCommentServer(svcs.Comment, svcs.Account))
+	mygramv1.RegisterSocialMediaServiceServer(s, rpc.NewSocialMediaServer(svcs.SocialMedia, svcs.Account))
+	rpc.RegisterHealth(s)
+	if cfg.Reflection {
+		reflection.Register(s)
+	}
+	return s
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"mygram/internal/middleware"
+	"mygram/internal/model"
+	"mygram/internal/service"
+	"mygram/pkg"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (u *userHandlerImpl) BlockUser(ctx *gin.Context) {
+	u.actOnUser(ctx, u.blockSvc.Block, "The user has been blocked")
+}
+
+func (u *userHandlerImpl) UnblockUser(ctx *gin.Context) {
+	u.actOnUser(ctx, u.blockSvc.Unblock, "The user has been unblocked")
+}
+
+func (u *userHandlerImpl) GetBlocks(ctx *gin.Context) {
+	u.listUsers(ctx, u.blockSvc.GetBlocks)
+}
+
+func (u *userHandlerImpl) MuteUser(ctx *gin.Context) {
+	u.actOnUser(ctx, u.blockSvc.Mute, "The user has been muted")
+}
+
+func (u *userHandlerImpl) UnmuteUser(ctx *gin.Context) {
+	u.actOnUser(ctx, u.blockSvc.Unmute, "The user has been unmuted")
+}
+
+func (u *userHandlerImpl) GetMutes(ctx *gin.Context) {
+	u.listUsers(ctx, u.blockSvc.GetMutes)
+}
+
+// actOnUser runs a block or mute action of the signed in user on the user
+// in the id param.
+func (u *userHandlerImpl) actOnUser(ctx *gin.Context, act func(ctx context.Context, userId uint64, otherId uint64) error, message string) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if id == 0 || err != nil {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: "invalid required param"})
+		return
+	}
+
+	userIdClaim, ok := ctx.Get(middleware.CLAIM_USER_ID)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, pkg.ErrorResponse{Message: "invalid user session"})
+		return
+	}
+	userIdInt, ok := userIdClaim.(float64)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: "invalid user id session"})
+		return
+	}
+
+	err = act(ctx, uint64(userIdInt), uint64(id))
+	if errors.Is(err, service.ErrBlockSelf) || errors.Is(err, service.ErrMuteSelf) {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrUserNotFound) || errors.Is(err, service.ErrBlockNotFound) || errors.Is(err, service.ErrMuteNotFound) {
+		ctx.JSON(http.StatusNotFound, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, map[string]any{
+		"message": message,
+	})
+}
+
+func (u *userHandlerImpl) listUsers(ctx *gin.Context, list func(ctx context.Context, userId uint64) ([]model.ListedUser, error)) {
+	userIdClaim, ok := ctx.Get(middleware.CLAIM_USER_ID)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, pkg.ErrorResponse{Message: "invalid user session"})
+		return
+	}
+	userIdInt, ok := userIdClaim.(float64)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: "invalid user id session"})
+		return
+	}
+
+	users, err := list(ctx, uint64(userIdInt))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, users)
+}
@@ -1,12 +1,14 @@
 package handler
 
 import (
+	"errors"
 	"mygram/internal/middleware"
 	"mygram/internal/model"
 	"mygram/internal/service"
 	"mygram/pkg"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
@@ -15,17 +17,20 @@ import (
 type CommentHandler interface {
 	CreateComment(ctx *gin.Context)
 	GetCommentsByPhotoId(ctx *gin.Context)
+	GetCommentById(ctx *gin.Context)
 	EditComment(ctx *gin.Context)
 	DeleteComment(ctx *gin.Context)
 }
 
+// commentExpandDefault is what comments came with before ?expand=.
+var commentExpandDefault = model.Expand{User: true, Photo: true}
+
 type commentHandlerImpl struct {
-	svc      service.CommentService
-	photoSvc service.PhotoService
+	svc service.CommentService
 }
 
-func NewCommentHandler(svc service.CommentService, photoSvc service.PhotoService) CommentHandler {
-	return &commentHandlerImpl{svc: svc, photoSvc: photoSvc}
+func NewCommentHandler(svc service.CommentService) CommentHandler {
+	return &commentHandlerImpl{svc: svc}
 }
 
 func (c *commentHandlerImpl) CreateComment(ctx *gin.Context) {
@@ -55,23 +60,20 @@ func (c *commentHandlerImpl) CreateComment(ctx *gin.Context) {
 		return
 	}
 
-	photo, err := c.photoSvc.GetPhotoById(ctx, commentCreateReq.PhotoId)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
-		return
-	}
-
-	if photo.ID == 0 {
-		ctx.JSON(http.StatusNotFound, pkg.ErrorResponse{Message: "photo not found"})
-		return
-	}
-
 	comment := model.Comment{}
 	comment.UserId = uint64(userIdInt) 
 	comment.Message = commentCreateReq.Message
 	comment.PhotoId = commentCreateReq.PhotoId
 
 	commentRes, err := c.svc.CreateComment(ctx, comment)
+	if errors.Is(err, service.ErrPhotoNotFound) {
+		ctx.JSON(http.StatusNotFound, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrMentionBlocked) {
+		ctx.JSON(http.StatusForbidden, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
 		return
@@ -92,7 +94,16 @@ func (c *commentHandlerImpl) GetCommentsByPhotoId(ctx *gin.Context) {
 		return
 	}
 
-	comments, err := c.svc.GetCommentsByPhotoId(ctx, uint64(photoId))
+	expand, ok := parseExpand(ctx, commentExpandDefault, model.EXPAND_USER, model.EXPAND_PHOTO)
+	if !ok {
+		return
+	}
+	fields, ok := parseFields(ctx, model.CommentGetRes{})
+	if !ok {
+		return
+	}
+
+	comments, err := c.svc.GetCommentsByPhotoId(ctx, uint64(photoId), middleware.ViewerID(ctx), expand)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
 		return
@@ -103,7 +114,46 @@ func (c *commentHandlerImpl) GetCommentsByPhotoId(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, comments)
+	if middleware.IsGuest(ctx) {
+		for i := range comments {
+			comments[i].User = comments[i].User.Public()
+		}
+	}
+
+	updatedAt := latestUpdatedAt(comments, func(v model.CommentGetRes) time.Time { return v.UpdatedAt })
+	respondETag(ctx, http.StatusOK, updatedAt, comments, fields)
+}
+
+func (c *commentHandlerImpl) GetCommentById(ctx *gin.Context) {
+	commentId, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if commentId == 0 || err != nil {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: "invalid comment ID"})
+		return
+	}
+	expand, ok := parseExpand(ctx, commentExpandDefault, model.EXPAND_USER, model.EXPAND_PHOTO)
+	if !ok {
+		return
+	}
+	fields, ok := parseFields(ctx, model.CommentGetRes{})
+	if !ok {
+		return
+	}
+
+	comment, err := c.svc.GetExpandedComment(ctx, commentId, middleware.ViewerID(ctx), expand)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+	if comment.ID == 0 {
+		ctx.JSON(http.StatusNotFound, pkg.ErrorResponse{Message: "comment not found"})
+		return
+	}
+
+	if middleware.IsGuest(ctx) {
+		comment.User = comment.User.Public()
+	}
+
+	respondETag(ctx, http.StatusOK, comment.UpdatedAt, comment, fields)
 }
 
 func (c *commentHandlerImpl) EditComment(ctx *gin.Context) {
@@ -113,7 +163,7 @@ func (c *commentHandlerImpl) EditComment(ctx *gin.Context) {
 		return
 	}
 
-	comment, err := c.svc.GetCommentById(ctx, uint64(commentId))
+	comment, err := c.svc.GetCommentById(ctx, uint64(commentId), middleware.ViewerID(ctx))
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
 		return
@@ -141,10 +191,13 @@ func (c *commentHandlerImpl) EditComment(ctx *gin.Context) {
 		return
 	}
 
-	commentUpdateReq := model.CommentUpdateReq{}
-	err = ctx.Bind(&commentUpdateReq)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+	updatedAt, ok := checkIfMatch(ctx, comment.UpdatedAt)
+	if !ok {
+		return
+	}
+
+	commentUpdateReq := model.CommentUpdateReq{Message: comment.Message}
+	if !bindUpdate(ctx, &commentUpdateReq) {
 		return
 	}
 
@@ -161,13 +214,21 @@ func (c *commentHandlerImpl) EditComment(ctx *gin.Context) {
 	commentUp.PhotoId = comment.PhotoId
 	commentUp.Message = commentUpdateReq.Message
 
-	commentRes, err := c.svc.EditComment(ctx, commentUp)
+	commentRes, err := c.svc.EditComment(ctx, commentUp, updatedAt)
+	if errors.Is(err, service.ErrPreconditionFailed) {
+		ctx.JSON(http.StatusPreconditionFailed, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrMentionBlocked) {
+		ctx.JSON(http.StatusForbidden, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, commentRes)
+	respondETag(ctx, http.StatusOK, commentRes.UpdatedAt, commentRes, nil)
 }
 
 func (c *commentHandlerImpl) DeleteComment(ctx *gin.Context) {
@@ -177,7 +238,7 @@ func (c *commentHandlerImpl) DeleteComment(ctx *gin.Context) {
 		return
 	}
 
-	comment, err := c.svc.GetCommentById(ctx, uint64(commentId))
+	comment, err := c.svc.GetCommentById(ctx, uint64(commentId), middleware.ViewerID(ctx))
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
 		return
@@ -204,7 +265,7 @@ func (c *commentHandlerImpl) DeleteComment(ctx *gin.Context) {
 		return
 	}
 
-	err = c.svc.DeleteComment(ctx, uint64(commentId))
+	err = c.svc.DeleteComment(ctx, uint64(commentId), uint64(userIdInt))
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
 		return
@@ -213,4 +274,4 @@ func (c *commentHandlerImpl) DeleteComment(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, map[string]any{
 		"message": "Your comment has been successfully deleted" ,
 	})
-}
\ No newline at end of file
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"mygram/pkg"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ETags look like "<version>-<digest>". The version comes from updated_at
+// and is what If-Match checks, the digest of the body tells the
+// representations of a version apart, for If-None-Match: expanded
+// relations, sparse fieldsets and what guests see change without the
+// resource.
+func etag(updatedAt time.Time, body []byte) string {
+	sum := sha256.Sum256(body)
+	return fmt.Sprintf(`"%s-%x"`, etagVersion(updatedAt), sum[:8])
+}
+
+func etagVersion(updatedAt time.Time) string {
+	// the database keeps microseconds
+	return strconv.FormatInt(updatedAt.UnixMicro(), 36)
+}
+
+// checkIfMatch answers 412 when If-Match names no ETag of the current
+// version of a resource updated at updatedAt. It returns the updated_at the
+// write has to be conditional on, zero without the header.
+func checkIfMatch(ctx *gin.Context, updatedAt time.Time) (time.Time, bool) {
+	header := ctx.GetHeader("If-Match")
+	if header == "" {
+		return time.Time{}, true
+	}
+
+	version := etagVersion(updatedAt)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		// weak tags never match, If-Match compares strongly
+		if tag == "*" || strings.HasPrefix(tag, `"`+version+"-") {
+			return updatedAt, true
+		}
+	}
+
+	ctx.JSON(http.StatusPreconditionFailed, pkg.ErrorResponse{
+		Message: "precondition failed",
+		Errors:  []string{"the resource was changed since it was read, fetch it again"},
+	})
+	return time.Time{}, false
+}
+
+// respondETag answers like respondFields with the ETag of the body, or with
+// 304 when it is the one If-None-Match names.
+func respondETag(ctx *gin.Context, status int, updatedAt time.Time, res any, fields []string) {
+	doc, err := renderFields(res, fields)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+	body, err := json.Marshal(doc)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	tag := etag(updatedAt, body)
+	ctx.Header("ETag", tag)
+	if ctx.Request.Method == http.MethodGet && noneMatch(ctx.GetHeader("If-None-Match"), tag) {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+	ctx.Data(status, gin.MIMEJSON+"; charset=utf-8", body)
+}
+
+// latestUpdatedAt is the version of a list, the updated_at of the item
+// changed last. The ETag also hashes the body, which covers items removed.
+func latestUpdatedAt[T any](items []T, updatedAt func(T) time.Time) time.Time {
+	latest := time.Time{}
+	for _, item := range items {
+		if t := updatedAt(item); t.After(latest) {
+			latest = t
+		}
+	}
+	return latest
+}
+
+// noneMatch compares weakly, as If-None-Match does.
+func noneMatch(header string, tag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"mygram/internal/middleware"
+	"mygram/internal/model"
+	"mygram/internal/service"
+	"mygram/pkg"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+const exploreDefaultLimit = 20
+
+type ExploreHandler interface {
+	GetExplore(ctx *gin.Context)
+	GetHashtagExplore(ctx *gin.Context)
+	GetTrendingHashtags(ctx *gin.Context)
+}
+
+type exploreHandlerImpl struct {
+	svc service.ExploreService
+}
+
+func NewExploreHandler(svc service.ExploreService) ExploreHandler {
+	return &exploreHandlerImpl{svc: svc}
+}
+
+func (e *exploreHandlerImpl) GetExplore(ctx *gin.Context) {
+	req, ok := bindExploreReq(ctx)
+	if !ok {
+		return
+	}
+
+	photos, err := e.svc.GetExplore(ctx, req.Limit, req.Offset, middleware.ViewerID(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+	respondExplore(ctx, photos)
+}
+
+func (e *exploreHandlerImpl) GetHashtagExplore(ctx *gin.Context) {
+	req, ok := bindExploreReq(ctx)
+	if !ok {
+		return
+	}
+
+	photos, err := e.svc.GetHashtagExplore(ctx, ctx.Param("tag"), req.Limit, req.Offset, middleware.ViewerID(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+	respondExplore(ctx, photos)
+}
+
+func (e *exploreHandlerImpl) GetTrendingHashtags(ctx *gin.Context) {
+	req, ok := bindExploreReq(ctx)
+	if !ok {
+		return
+	}
+
+	tags, err := e.svc.GetTrendingHashtags(ctx, req.Limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, tags)
+}
+
+func bindExploreReq(ctx *gin.Context) (model.ExploreReq, bool) {
+	req := model.ExploreReq{}
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: err.Error()})
+		return req, false
+	}
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: err.Error()})
+		return req, false
+	}
+	if req.Limit == 0 {
+		req.Limit = exploreDefaultLimit
+	}
+	return req, true
+}
+
+// respondExplore answers an empty list rather than 404, explore is never
+// missing, it may just have nothing ranked yet.
+func respondExplore(ctx *gin.Context, photos []model.PhotoGetRes) {
+	if middleware.IsGuest(ctx) {
+		for i := range photos {
+			photos[i].User = photos[i].User.Public()
+		}
+	}
+	ctx.JSON(http.StatusOK, photos)
+}
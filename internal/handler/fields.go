@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"mygram/internal/model"
+	"mygram/pkg"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseExpand reads ?expand=, a comma separated list out of allowed. Without
+// the query the defaults apply, while an empty one expands nothing.
+func parseExpand(ctx *gin.Context, defaults model.Expand, allowed ...string) (model.Expand, bool) {
+	values, ok := ctx.GetQueryArray("expand")
+	if !ok {
+		return defaults, true
+	}
+
+	expand := model.Expand{}
+	for _, name := range splitList(values) {
+		known := false
+		for _, a := range allowed {
+			known = known || a == name
+		}
+		if !known {
+			ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{
+				Message: fmt.Sprintf("cannot expand %q", name),
+				Errors:  []string{"expand takes " + strings.Join(allowed, ", ")},
+			})
+			return model.Expand{}, false
+		}
+
+		switch name {
+		case model.EXPAND_USER:
+			expand.User = true
+		case model.EXPAND_PHOTO:
+			expand.Photo = true
+		case model.EXPAND_COMMENTS:
+			expand.Comments = true
+		}
+	}
+	return expand, true
+}
+
+// parseFields reads ?fields=, a comma separated list of the json fields of
+// res to answer with. It is nil without the query, for every field.
+func parseFields(ctx *gin.Context, res any) ([]string, bool) {
+	values, ok := ctx.GetQueryArray("fields")
+	if !ok {
+		return nil, true
+	}
+
+	known, _ := jsonFields(reflect.TypeOf(res))
+	fields := splitList(values)
+	for _, field := range fields {
+		if !known[field] {
+			ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: fmt.Sprintf("unknown field %q", field)})
+			return nil, false
+		}
+	}
+	return fields, true
+}
+
+// respondFields answers with res, an object or a list of them, cut down to
+// fields. The id and the expanded relations are always kept.
+func respondFields(ctx *gin.Context, status int, res any, fields []string) {
+	doc, err := renderFields(res, fields)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+	ctx.JSON(status, doc)
+}
+
+func renderFields(res any, fields []string) (any, error) {
+	if fields == nil {
+		return res, nil
+	}
+
+	_, relations := jsonFields(reflect.TypeOf(res))
+	keep := map[string]bool{"id": true}
+	for _, field := range fields {
+		keep[field] = true
+	}
+	for relation := range relations {
+		keep[relation] = true
+	}
+
+	raw, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+	var doc any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+
+	objects := []any{doc}
+	if list, ok := doc.([]any); ok {
+		objects = list
+	}
+	for _, object := range objects {
+		if object, ok := object.(map[string]any); ok {
+			for key := range object {
+				if !keep[key] {
+					delete(object, key)
+				}
+			}
+		}
+	}
+	return doc, nil
+}
+
+// jsonFields lists the json names of a struct, or of the elements of a
+// slice, telling the relations, structs and lists of them, apart.
+func jsonFields(t reflect.Type) (map[string]bool, map[string]bool) {
+	for t.Kind() == reflect.Slice || t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	fields := map[string]bool{}
+	relations := map[string]bool{}
+	if t.Kind() != reflect.Struct {
+		return fields, relations
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		kind := field.Type.Kind()
+		if kind == reflect.Pointer || kind == reflect.Slice {
+			relations[name] = true
+			continue
+		}
+		fields[name] = true
+	}
+	return fields, relations
+}
+
+func splitList(values []string) []string {
+	list := []string{}
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
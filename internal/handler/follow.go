@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"mygram/internal/middleware"
+	"mygram/internal/model"
+	"mygram/internal/service"
+	"mygram/pkg"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+func (u *userHandlerImpl) SetPrivacy(ctx *gin.Context) {
+	userIdClaim, ok := ctx.Get(middleware.CLAIM_USER_ID)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, pkg.ErrorResponse{Message: "invalid user session"})
+		return
+	}
+	userIdInt, ok := userIdClaim.(float64)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: "invalid user id session"})
+		return
+	}
+
+	req := model.PrivacyReq{}
+	if err := ctx.Bind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	if err := u.followSvc.SetPrivate(ctx, uint64(userIdInt), *req.Private); err != nil {
+		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, map[string]any{
+		"private": *req.Private,
+	})
+}
+
+func (u *userHandlerImpl) FollowUser(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if id == 0 || err != nil {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: "invalid required param"})
+		return
+	}
+
+	userIdClaim, ok := ctx.Get(middleware.CLAIM_USER_ID)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, pkg.ErrorResponse{Message: "invalid user session"})
+		return
+	}
+	userIdInt, ok := userIdClaim.(float64)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: "invalid user id session"})
+		return
+	}
+
+	follow, err := u.followSvc.Follow(ctx, uint64(userIdInt), uint64(id))
+	if errors.Is(err, service.ErrFollowSelf) {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrUserNotFound) {
+		ctx.JSON(http.StatusNotFound, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, follow)
+}
+
+func (u *userHandlerImpl) UnfollowUser(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if id == 0 || err != nil {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: "invalid required param"})
+		return
+	}
+
+	userIdClaim, ok := ctx.Get(middleware.CLAIM_USER_ID)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, pkg.ErrorResponse{Message: "invalid user session"})
+		return
+	}
+	userIdInt, ok := userIdClaim.(float64)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: "invalid user id session"})
+		return
+	}
+
+	err = u.followSvc.Unfollow(ctx, uint64(userIdInt), uint64(id))
+	if errors.Is(err, service.ErrFollowNotFound) {
+		ctx.JSON(http.StatusNotFound, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, map[string]any{
+		"message": "You no longer follow this user",
+	})
+}
+
+func (u *userHandlerImpl) GetFollowRequests(ctx *gin.Context) {
+	userIdClaim, ok := ctx.Get(middleware.CLAIM_USER_ID)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, pkg.ErrorResponse{Message: "invalid user session"})
+		return
+	}
+	userIdInt, ok := userIdClaim.(float64)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: "invalid user id session"})
+		return
+	}
+
+	requests, err := u.followSvc.GetFollowRequests(ctx, uint64(userIdInt))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, requests)
+}
+
+func (u *userHandlerImpl) ApproveFollowRequest(ctx *gin.Context) {
+	u.answerFollowRequest(ctx, u.followSvc.ApproveFollowRequest, "The follow request has been approved")
+}
+
+func (u *userHandlerImpl) DenyFollowRequest(ctx *gin.Context) {
+	u.answerFollowRequest(ctx, u.followSvc.DenyFollowRequest, "The follow request has been denied")
+}
+
+func (u *userHandlerImpl) answerFollowRequest(ctx *gin.Context, answer func(ctx context.Context, userId uint64, followerId uint64) error, message string) {
+	followerId, err := strconv.Atoi(ctx.Param("followerId"))
+	if followerId == 0 || err != nil {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: "invalid required param"})
+		return
+	}
+
+	userIdClaim, ok := ctx.Get(middleware.CLAIM_USER_ID)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, pkg.ErrorResponse{Message: "invalid user session"})
+		return
+	}
+	userIdInt, ok := userIdClaim.(float64)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: "invalid user id session"})
+		return
+	}
+
+	err = answer(ctx, uint64(userIdInt), uint64(followerId))
+	if errors.Is(err, service.ErrFollowRequestNotFound) {
+		ctx.JSON(http.StatusNotFound, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, map[string]any{
+		"message": message,
+	})
+}
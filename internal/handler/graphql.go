@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"mygram/internal/middleware"
+	"mygram/internal/service"
+	"mygram/pkg"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graph-gophers/graphql-go"
+)
+
+const (
+	// graphQLMaxBodySize bounds the JSON POSTed, query and variables.
+	graphQLMaxBodySize = 1 << 20
+	// graphQLMaxQueryLength bounds the query the parser reads, which
+	// recurses once per level of nesting.
+	graphQLMaxQueryLength = 16 << 10
+)
+
+type GraphQLHandler interface {
+	Query(ctx *gin.Context)
+	GetSchema(ctx *gin.Context)
+}
+
+type graphQLHandlerImpl struct {
+	resolver *graphQLResolver
+	schema   *graphql.Schema
+}
+
+func NewGraphQLHandler(userSvc service.UserService, photoSvc service.PhotoService, commentSvc service.CommentService, socialMediaSvc service.SocialMediaService, exploreSvc service.ExploreService, accountSvc service.AccountService) GraphQLHandler {
+	r := &graphQLResolver{
+		userSvc:        userSvc,
+		photoSvc:       photoSvc,
+		commentSvc:     commentSvc,
+		socialMediaSvc: socialMediaSvc,
+		exploreSvc:     exploreSvc,
+		accountSvc:     accountSvc,
+	}
+	schema := graphql.MustParseSchema(graphQLSchema, r,
+		graphql.UseStringDescriptions(),
+		graphql.MaxDepth(graphQLMaxDepth),
+		graphql.MaxQueryLength(graphQLMaxQueryLength),
+	)
+	return &graphQLHandlerImpl{resolver: r, schema: schema}
+}
+
+type graphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// Query executes a query POSTed in JSON, or sent with GET in the query,
+// operationName and variables parameters, which may not mutate. Requests
+// refused before execution answer 400, the others 200 with the errors of
+// the fields that failed.
+func (g *graphQLHandlerImpl) Query(ctx *gin.Context) {
+	req := graphQLRequest{}
+	viewer := &graphQLViewer{id: middleware.ViewerID(ctx), guest: middleware.IsGuest(ctx)}
+	if ctx.Request.Method == http.MethodGet {
+		req.Query = ctx.Query("query")
+		req.OperationName = ctx.Query("operationName")
+		if variables := ctx.Query("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: "invalid variables", Errors: []string{err.Error()}})
+				return
+			}
+		}
+		viewer.readOnly = true
+	} else {
+		ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, graphQLMaxBodySize)
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			if maxBytesErr := (*http.MaxBytesError)(nil); errors.As(err, &maxBytesErr) {
+				ctx.JSON(http.StatusRequestEntityTooLarge, pkg.ErrorResponse{Message: "request body too large"})
+				return
+			}
+			ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: err.Error()})
+			return
+		}
+	}
+	if scopes, ok := ctx.Get(middleware.CLAIM_SCOPES); ok {
+		granted, _ := scopes.([]string)
+		viewer.allow = func(scope string) bool { return slices.Contains(granted, scope) }
+	}
+
+	viewer.loaders = newGraphQLLoaders(g.resolver, viewer.id)
+	res := g.schema.Exec(context.WithValue(ctx.Request.Context(), graphQLViewerKey{}, viewer), req.Query, req.OperationName, req.Variables)
+	// the query did not parse or validate when there is no data
+	if len(res.Data) == 0 || viewer.isRefused() {
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+	ctx.JSON(http.StatusOK, res)
+}
+
+// GetSchema answers the schema in the GraphQL schema language.
+func (g *graphQLHandlerImpl) GetSchema(ctx *gin.Context) {
+	ctx.String(http.StatusOK, strings.TrimPrefix(graphQLSchema, "\n"))
+}
@@ -0,0 +1,302 @@
+package handler
+
+import (
+	"context"
+	"mygram/internal/model"
+	"strings"
+	"sync"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+// loader loads values by ID for a GraphQL request, and keeps them for the
+// resolvers, which run concurrently.
+type loader[V any] struct {
+	fetch func(ctx context.Context, ids []uint64) (map[uint64]V, error)
+
+	mu sync.Mutex
+	// cache also remembers the IDs fetch found nothing for.
+	cache map[uint64]*V
+}
+
+func newLoader[V any](fetch func(ctx context.Context, ids []uint64) (map[uint64]V, error)) *loader[V] {
+	return &loader[V]{fetch: fetch, cache: map[uint64]*V{}}
+}
+
+// load returns the values of ids, without those that do not exist or the
+// viewer may not see, fetching those it does not have yet with one call.
+func (l *loader[V]) load(ctx context.Context, ids []uint64) (map[uint64]V, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	missing := []uint64{}
+	seen := map[uint64]bool{}
+	for _, id := range ids {
+		if _, ok := l.cache[id]; !ok && !seen[id] {
+			seen[id] = true
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		fetched, err := l.fetch(ctx, missing)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range missing {
+			if v, ok := fetched[id]; ok {
+				l.cache[id] = &v
+			} else {
+				l.cache[id] = nil
+			}
+		}
+	}
+
+	values := map[uint64]V{}
+	for _, id := range ids {
+		if v := l.cache[id]; v != nil {
+			values[id] = *v
+		}
+	}
+	return values, nil
+}
+
+func (l *loader[V]) clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.cache = map[uint64]*V{}
+}
+
+// one loads a single ID with the loader, nil when there is nothing to see.
+func one[V any](ctx context.Context, l *loader[V], id uint64) (*V, error) {
+	values, err := l.load(ctx, []uint64{id})
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := values[id]; ok {
+		return &v, nil
+	}
+	return nil, nil
+}
+
+// graphQLLoaders are the loaders of a request, for the relations of the
+// schema, each reading on behalf of the viewer.
+type graphQLLoaders struct {
+	users              *loader[model.User]
+	photos             *loader[model.PhotoGetRes]
+	photosByUser       *loader[[]model.PhotoGetRes]
+	commentsByPhoto    *loader[[]model.CommentGetRes]
+	socialMediasByUser *loader[[]model.SocialMediaGetRes]
+}
+
+func newGraphQLLoaders(r *graphQLResolver, viewerId uint64) *graphQLLoaders {
+	return &graphQLLoaders{
+		users: newLoader(func(ctx context.Context, ids []uint64) (map[uint64]model.User, error) {
+			users, err := r.userSvc.GetUsersByIds(ctx, ids, viewerId)
+			return byID(users, err, func(u model.User) uint64 { return u.ID })
+		}),
+		photos: newLoader(func(ctx context.Context, ids []uint64) (map[uint64]model.PhotoGetRes, error) {
+			photos, err := r.photoSvc.GetPhotosByIds(ctx, ids, viewerId)
+			return byID(photos, err, func(p model.PhotoGetRes) uint64 { return p.ID })
+		}),
+		photosByUser: newLoader(func(ctx context.Context, ids []uint64) (map[uint64][]model.PhotoGetRes, error) {
+			photos, err := r.photoSvc.GetPhotosByUserIds(ctx, ids, viewerId)
+			return groupByID(ids, photos, err, func(p model.PhotoGetRes) uint64 { return p.UserId })
+		}),
+		commentsByPhoto: newLoader(func(ctx context.Context, ids []uint64) (map[uint64][]model.CommentGetRes, error) {
+			comments, err := r.commentSvc.GetCommentsByPhotoIds(ctx, ids, viewerId)
+			return groupByID(ids, comments, err, func(c model.CommentGetRes) uint64 { return c.PhotoId })
+		}),
+		socialMediasByUser: newLoader(func(ctx context.Context, ids []uint64) (map[uint64][]model.SocialMediaGetRes, error) {
+			socials, err := r.socialMediaSvc.GetSocialMediasByUserIds(ctx, ids, viewerId)
+			return groupByID(ids, socials, err, func(s model.SocialMediaGetRes) uint64 { return s.UserId })
+		}),
+	}
+}
+
+// clear forgets what was loaded, once a mutation changed it.
+func (l *graphQLLoaders) clear() {
+	l.users.clear()
+	l.photos.clear()
+	l.photosByUser.clear()
+	l.commentsByPhoto.clear()
+	l.socialMediasByUser.clear()
+}
+
+func byID[V any](values []V, err error, id func(V) uint64) (map[uint64]V, error) {
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[uint64]V, len(values))
+	for _, v := range values {
+		res[id(v)] = v
+	}
+	return res, nil
+}
+
+// groupByID groups values by the ID they belong to, with an empty list for
+// the ids nothing belongs to.
+func groupByID[V any](ids []uint64, values []V, err error, id func(V) uint64) (map[uint64][]V, error) {
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[uint64][]V, len(ids))
+	for _, i := range ids {
+		res[i] = []V{}
+	}
+	for _, v := range values {
+		res[id(v)] = append(res[id(v)], v)
+	}
+	return res, nil
+}
+
+// graphQLRelation is a field of the schema that reads the loaders, by an ID
+// of the object it is selected on.
+type graphQLRelation struct {
+	// typ is the type of the objects loaded, empty for a leaf such as
+	// commentCount.
+	typ   string
+	list  bool
+	scope string
+	// load loads the relation of every source with a single call, and
+	// returns the objects loaded.
+	load func(ctx context.Context, l *graphQLLoaders, sources []any) ([]any, error)
+}
+
+// graphQLRelations are the relations of the types of the schema, by field.
+var graphQLRelations = map[string]map[string]graphQLRelation{
+	"User": {
+		"photos": {typ: "Photo", list: true, scope: model.SCOPE_PHOTOS_READ,
+			load: loadMany(func(l *graphQLLoaders) *loader[[]model.PhotoGetRes] { return l.photosByUser }, func(u model.User) uint64 { return u.ID })},
+		"socialMedias": {typ: "SocialMedia", list: true, scope: model.SCOPE_SOCIAL_MEDIAS_READ,
+			load: loadMany(func(l *graphQLLoaders) *loader[[]model.SocialMediaGetRes] { return l.socialMediasByUser }, func(u model.User) uint64 { return u.ID })},
+	},
+	"Photo": {
+		"user": {typ: "User",
+			load: loadOne(func(l *graphQLLoaders) *loader[model.User] { return l.users }, func(p model.PhotoGetRes) uint64 { return p.UserId })},
+		"comments": {typ: "Comment", list: true, scope: model.SCOPE_COMMENTS_READ,
+			load: loadMany(func(l *graphQLLoaders) *loader[[]model.CommentGetRes] { return l.commentsByPhoto }, func(p model.PhotoGetRes) uint64 { return p.ID })},
+		"commentCount": {scope: model.SCOPE_COMMENTS_READ,
+			load: loadMany(func(l *graphQLLoaders) *loader[[]model.CommentGetRes] { return l.commentsByPhoto }, func(p model.PhotoGetRes) uint64 { return p.ID })},
+	},
+	"Comment": {
+		"user": {typ: "User",
+			load: loadOne(func(l *graphQLLoaders) *loader[model.User] { return l.users }, func(c model.CommentGetRes) uint64 { return c.UserId })},
+		// the viewer's own comment can sit under a photo they no longer see
+		"photo": {typ: "Photo", scope: model.SCOPE_PHOTOS_READ,
+			load: loadOne(func(l *graphQLLoaders) *loader[model.PhotoGetRes] { return l.photos }, func(c model.CommentGetRes) uint64 { return c.PhotoId })},
+	},
+	"SocialMedia": {
+		"user": {typ: "User",
+			load: loadOne(func(l *graphQLLoaders) *loader[model.User] { return l.users }, func(s model.SocialMediaGetRes) uint64 { return s.UserId })},
+	},
+}
+
+// loadOne loads the value of each source with pick, by the ID key reads off
+// it.
+func loadOne[S any, V any](pick func(l *graphQLLoaders) *loader[V], key func(source S) uint64) func(ctx context.Context, l *graphQLLoaders, sources []any) ([]any, error) {
+	return func(ctx context.Context, l *graphQLLoaders, sources []any) ([]any, error) {
+		ids := keysOf(sources, key)
+		values, err := pick(l).load(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		loaded := []any{}
+		for _, id := range ids {
+			if v, ok := values[id]; ok {
+				loaded = append(loaded, v)
+			}
+		}
+		return loaded, nil
+	}
+}
+
+// loadMany loads the values each source has with pick, by the ID key reads
+// off it.
+func loadMany[S any, V any](pick func(l *graphQLLoaders) *loader[[]V], key func(source S) uint64) func(ctx context.Context, l *graphQLLoaders, sources []any) ([]any, error) {
+	return func(ctx context.Context, l *graphQLLoaders, sources []any) ([]any, error) {
+		ids := keysOf(sources, key)
+		values, err := pick(l).load(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		loaded := []any{}
+		for _, id := range ids {
+			for _, v := range values[id] {
+				loaded = append(loaded, v)
+			}
+		}
+		return loaded, nil
+	}
+}
+
+// keysOf reads the distinct IDs off sources with key.
+func keysOf[S any](sources []any, key func(source S) uint64) []uint64 {
+	ids := []uint64{}
+	seen := map[uint64]bool{}
+	for _, source := range sources {
+		if id := key(source.(S)); !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// graphQLSelection is a field selected under another, with its own.
+type graphQLSelection struct {
+	name       string
+	selections []*graphQLSelection
+}
+
+// graphQLSelectionsOf are the fields selected under the field the resolver
+// of ctx resolves, in the order of the query.
+func graphQLSelectionsOf(ctx context.Context) []*graphQLSelection {
+	root := &graphQLSelection{}
+	for _, path := range graphql.SelectedFieldNames(ctx) {
+		parent := root
+		for _, name := range strings.Split(path, ".") {
+			var next *graphQLSelection
+			for _, s := range parent.selections {
+				if s.name == name {
+					next = s
+					break
+				}
+			}
+			if next == nil {
+				next = &graphQLSelection{name: name}
+				parent.selections = append(parent.selections, next)
+			}
+			parent = next
+		}
+	}
+	return root.selections
+}
+
+// prefetch loads the relations selected under the field of ctx for sources,
+// of type typ, with one call per relation and level whatever the number of
+// sources, so that their resolvers find them loaded.
+func (l *graphQLLoaders) prefetch(ctx context.Context, typ string, sources []any) error {
+	return l.prefetchSelections(ctx, typ, sources, graphQLSelectionsOf(ctx))
+}
+
+func (l *graphQLLoaders) prefetchSelections(ctx context.Context, typ string, sources []any, selections []*graphQLSelection) error {
+	if len(sources) == 0 {
+		return nil
+	}
+	for _, s := range selections {
+		relation, ok := graphQLRelations[typ][s.name]
+		if !ok {
+			continue
+		}
+		loaded, err := relation.load(ctx, l, sources)
+		if err != nil {
+			return err
+		}
+		if relation.typ != "" {
+			if err := l.prefetchSelections(ctx, relation.typ, loaded, s.selections); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
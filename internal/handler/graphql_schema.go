@@ -0,0 +1,958 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mygram/internal/model"
+	"mygram/internal/service"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/graph-gophers/graphql-go"
+)
+
+const (
+	graphQLMaxDepth      = 10
+	graphQLMaxComplexity = 1000
+	// graphQLListSize is how many items a list without a limit argument is
+	// expected to hold, for the complexity of the selections on them.
+	graphQLListSize = 10
+)
+
+var (
+	errGraphQLSignIn      = errors.New("sign in to make changes")
+	errGraphQLNotVerified = errors.New("confirm your email address before posting")
+	errGraphQLNotOwner    = errors.New("invalid user request")
+	errGraphQLReadOnly    = errors.New("mutations are not allowed in a read-only request")
+)
+
+const graphQLSchema = `
+type Query {
+  """The signed in user, null for guests."""
+  me: User
+  user(id: ID!): User
+  photo(id: ID!): Photo
+  photos(userId: ID!): [Photo!]!
+  comment(id: ID!): Comment
+  comments(photoId: ID!): [Comment!]!
+  socialMedia(id: ID!): SocialMedia
+  socialMedias(userId: ID!): [SocialMedia!]!
+  """Photos of public accounts, best ranked first."""
+  explore(limit: Int = 20, offset: Int = 0): [Photo!]!
+}
+
+"""A MyGram account."""
+type User {
+  id: ID!
+  username: String!
+  """Only the viewer's own."""
+  email: String
+  private: Boolean!
+  createdAt: DateTime!
+  photos: [Photo!]!
+  socialMedias: [SocialMedia!]!
+}
+
+"""An RFC 3339 timestamp."""
+scalar DateTime
+
+type Photo {
+  id: ID!
+  title: String!
+  caption: String!
+  photoUrl: String!
+  createdAt: DateTime!
+  updatedAt: DateTime!
+  user: User
+  comments: [Comment!]!
+  """The comments the viewer sees."""
+  commentCount: Int!
+}
+
+type Comment {
+  id: ID!
+  message: String!
+  createdAt: DateTime!
+  updatedAt: DateTime!
+  user: User
+  photo: Photo
+}
+
+"""A link to a profile elsewhere."""
+type SocialMedia {
+  id: ID!
+  name: String!
+  socialMediaUrl: String!
+  createdAt: DateTime!
+  updatedAt: DateTime!
+  user: User
+}
+
+type Mutation {
+  createPhoto(title: String!, caption: String!, photoUrl: String!): Photo!
+  """Changes the arguments given, and keeps the others."""
+  updatePhoto(id: ID!, title: String, caption: String, photoUrl: String): Photo!
+  deletePhoto(id: ID!): ID!
+  createComment(photoId: ID!, message: String!): Comment!
+  updateComment(id: ID!, message: String!): Comment!
+  deleteComment(id: ID!): ID!
+  createSocialMedia(name: String!, socialMediaUrl: String!): SocialMedia!
+  """Changes the arguments given, and keeps the others."""
+  updateSocialMedia(id: ID!, name: String, socialMediaUrl: String): SocialMedia!
+  deleteSocialMedia(id: ID!): ID!
+}
+`
+
+// graphQLDateTime is the DateTime scalar.
+type graphQLDateTime struct {
+	time.Time
+}
+
+func (graphQLDateTime) ImplementsGraphQLType(name string) bool {
+	return name == "DateTime"
+}
+
+func (t *graphQLDateTime) UnmarshalGraphQL(input any) error {
+	s, ok := input.(string)
+	if !ok {
+		return fmt.Errorf("DateTime cannot represent %v", input)
+	}
+	var err error
+	t.Time, err = time.Parse(time.RFC3339, s)
+	return err
+}
+
+type graphQLViewerKey struct{}
+
+// graphQLViewer is who a GraphQL request reads and writes on behalf of, 0
+// for guests, with the loaders of the request.
+type graphQLViewer struct {
+	id    uint64
+	guest bool
+	// readOnly refuses mutations, for requests sent with GET.
+	readOnly bool
+	// allow reports whether the token was granted a scope, nil granting
+	// every scope.
+	allow   func(scope string) bool
+	loaders *graphQLLoaders
+
+	mu         sync.Mutex
+	complexity int
+	refused    bool
+}
+
+func graphQLViewerFrom(ctx context.Context) *graphQLViewer {
+	return ctx.Value(graphQLViewerKey{}).(*graphQLViewer)
+}
+
+// refuse marks the request refused, for a check that failed before anything
+// was read or written.
+func (v *graphQLViewer) refuse(err error) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.refused = true
+	return err
+}
+
+func (v *graphQLViewer) isRefused() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.refused
+}
+
+// check runs before a field of the query or mutation root loads anything.
+// It refuses the field when the token lacks the scope of the field or of
+// those selected under it, or when it takes the complexity of the request
+// over the limit. size is how many objects of typ the field resolves to,
+// typ is empty when it resolves to a leaf.
+func (v *graphQLViewer) check(ctx context.Context, field string, scope string, typ string, size int) error {
+	if scope != "" && v.allow != nil && !v.allow(scope) {
+		return v.refuse(fmt.Errorf("field %q requires the %s scope", field, scope))
+	}
+	cost, err := v.cost(typ, graphQLSelectionsOf(ctx), size)
+	if err != nil {
+		return v.refuse(err)
+	}
+
+	v.mu.Lock()
+	v.complexity += 1 + cost
+	complexity := v.complexity
+	v.mu.Unlock()
+	if complexity > graphQLMaxComplexity {
+		return v.refuse(fmt.Errorf("the query has a complexity of %d, more than the limit of %d", complexity, graphQLMaxComplexity))
+	}
+	return nil
+}
+
+// cost is the complexity of selections on size objects of typ, each field
+// costing 1 per object, and checks the token may select them.
+func (v *graphQLViewer) cost(typ string, selections []*graphQLSelection, size int) (int, error) {
+	cost := 0
+	for _, s := range selections {
+		cost += size
+		relation, ok := graphQLRelations[typ][s.name]
+		if !ok {
+			continue
+		}
+		if relation.scope != "" && v.allow != nil && !v.allow(relation.scope) {
+			return 0, fmt.Errorf("field %q requires the %s scope", s.name, relation.scope)
+		}
+		if relation.typ == "" {
+			continue
+		}
+		n := size
+		if relation.list {
+			n *= graphQLListSize
+		}
+		child, err := v.cost(relation.typ, s.selections, n)
+		if err != nil {
+			return 0, err
+		}
+		cost += child
+	}
+	return cost, nil
+}
+
+// graphQLResolver resolves the queries and mutations of the GraphQL schema
+// with the services the REST handlers use, checking ownership the way they
+// do.
+type graphQLResolver struct {
+	userSvc        service.UserService
+	photoSvc       service.PhotoService
+	commentSvc     service.CommentService
+	socialMediaSvc service.SocialMediaService
+	exploreSvc     service.ExploreService
+	accountSvc     service.AccountService
+}
+
+func graphQLIDOf(id uint64) graphql.ID {
+	return graphql.ID(strconv.FormatUint(id, 10))
+}
+
+// graphQLID reads the ID argument name.
+func graphQLID(name string, arg graphql.ID) (uint64, error) {
+	id, err := strconv.ParseUint(string(arg), 10, 64)
+	if id == 0 || err != nil {
+		return 0, fmt.Errorf("invalid %s %q", name, arg)
+	}
+	return id, nil
+}
+
+// resolved prefetches the selections of the field of ctx on values, of type
+// typ, and wraps each in its resolver.
+func resolved[V any, R any](ctx context.Context, typ string, values []V, wrap func(V) R) ([]R, error) {
+	sources := make([]any, len(values))
+	for i, v := range values {
+		sources[i] = v
+	}
+	if err := graphQLViewerFrom(ctx).loaders.prefetch(ctx, typ, sources); err != nil {
+		return nil, err
+	}
+	return wrapAll(values, wrap), nil
+}
+
+// resolvedOne is resolved for a single value, nil when there is nothing to
+// see.
+func resolvedOne[V any, R any](ctx context.Context, typ string, value *V, wrap func(V) *R) (*R, error) {
+	if value == nil {
+		return nil, nil
+	}
+	res, err := resolved(ctx, typ, []V{*value}, wrap)
+	if err != nil {
+		return nil, err
+	}
+	return res[0], nil
+}
+
+// wrapAll wraps values in their resolvers.
+func wrapAll[V any, R any](values []V, wrap func(V) R) []R {
+	res := make([]R, len(values))
+	for i, v := range values {
+		res[i] = wrap(v)
+	}
+	return res
+}
+
+type graphQLUser struct {
+	user model.User
+}
+
+func newGraphQLUser(u model.User) *graphQLUser {
+	return &graphQLUser{user: u}
+}
+
+func (u *graphQLUser) ID() graphql.ID {
+	return graphQLIDOf(u.user.ID)
+}
+
+func (u *graphQLUser) Username() string {
+	return u.user.Username
+}
+
+// Email is only the viewer's own, the others see the fields of a
+// PublicProfile.
+func (u *graphQLUser) Email(ctx context.Context) *string {
+	if graphQLViewerFrom(ctx).id != u.user.ID {
+		return nil
+	}
+	return &u.user.Email
+}
+
+func (u *graphQLUser) Private() bool {
+	return u.user.Private
+}
+
+func (u *graphQLUser) CreatedAt() graphQLDateTime {
+	return graphQLDateTime{u.user.CreatedAt}
+}
+
+func (u *graphQLUser) Photos(ctx context.Context) ([]*graphQLPhoto, error) {
+	photos, err := one(ctx, graphQLViewerFrom(ctx).loaders.photosByUser, u.user.ID)
+	if err != nil || photos == nil {
+		return []*graphQLPhoto{}, err
+	}
+	return wrapAll(*photos, newGraphQLPhoto), nil
+}
+
+func (u *graphQLUser) SocialMedias(ctx context.Context) ([]*graphQLSocialMedia, error) {
+	socials, err := one(ctx, graphQLViewerFrom(ctx).loaders.socialMediasByUser, u.user.ID)
+	if err != nil || socials == nil {
+		return []*graphQLSocialMedia{}, err
+	}
+	return wrapAll(*socials, newGraphQLSocialMedia), nil
+}
+
+type graphQLPhoto struct {
+	photo model.PhotoGetRes
+}
+
+func newGraphQLPhoto(p model.PhotoGetRes) *graphQLPhoto {
+	return &graphQLPhoto{photo: p}
+}
+
+func (p *graphQLPhoto) ID() graphql.ID {
+	return graphQLIDOf(p.photo.ID)
+}
+
+func (p *graphQLPhoto) Title() string {
+	return p.photo.Title
+}
+
+func (p *graphQLPhoto) Caption() string {
+	return p.photo.Caption
+}
+
+func (p *graphQLPhoto) PhotoUrl() string {
+	return p.photo.PhotoUrl
+}
+
+func (p *graphQLPhoto) CreatedAt() graphQLDateTime {
+	return graphQLDateTime{p.photo.CreatedAt}
+}
+
+func (p *graphQLPhoto) UpdatedAt() graphQLDateTime {
+	return graphQLDateTime{p.photo.UpdatedAt}
+}
+
+func (p *graphQLPhoto) User(ctx context.Context) (*graphQLUser, error) {
+	user, err := one(ctx, graphQLViewerFrom(ctx).loaders.users, p.photo.UserId)
+	if err != nil || user == nil {
+		return nil, err
+	}
+	return newGraphQLUser(*user), nil
+}
+
+func (p *graphQLPhoto) Comments(ctx context.Context) ([]*graphQLComment, error) {
+	comments, err := one(ctx, graphQLViewerFrom(ctx).loaders.commentsByPhoto, p.photo.ID)
+	if err != nil || comments == nil {
+		return []*graphQLComment{}, err
+	}
+	return wrapAll(*comments, newGraphQLComment), nil
+}
+
+func (p *graphQLPhoto) CommentCount(ctx context.Context) (int32, error) {
+	comments, err := one(ctx, graphQLViewerFrom(ctx).loaders.commentsByPhoto, p.photo.ID)
+	if err != nil || comments == nil {
+		return 0, err
+	}
+	return int32(len(*comments)), nil
+}
+
+type graphQLComment struct {
+	comment model.CommentGetRes
+}
+
+func newGraphQLComment(c model.CommentGetRes) *graphQLComment {
+	return &graphQLComment{comment: c}
+}
+
+func (c *graphQLComment) ID() graphql.ID {
+	return graphQLIDOf(c.comment.ID)
+}
+
+func (c *graphQLComment) Message() string {
+	return c.comment.Message
+}
+
+func (c *graphQLComment) CreatedAt() graphQLDateTime {
+	return graphQLDateTime{c.comment.CreatedAt}
+}
+
+func (c *graphQLComment) UpdatedAt() graphQLDateTime {
+	return graphQLDateTime{c.comment.UpdatedAt}
+}
+
+func (c *graphQLComment) User(ctx context.Context) (*graphQLUser, error) {
+	user, err := one(ctx, graphQLViewerFrom(ctx).loaders.users, c.comment.UserId)
+	if err != nil || user == nil {
+		return nil, err
+	}
+	return newGraphQLUser(*user), nil
+}
+
+func (c *graphQLComment) Photo(ctx context.Context) (*graphQLPhoto, error) {
+	photo, err := one(ctx, graphQLViewerFrom(ctx).loaders.photos, c.comment.PhotoId)
+	if err != nil || photo == nil {
+		return nil, err
+	}
+	return newGraphQLPhoto(*photo), nil
+}
+
+type graphQLSocialMedia struct {
+	social model.SocialMediaGetRes
+}
+
+func newGraphQLSocialMedia(s model.SocialMediaGetRes) *graphQLSocialMedia {
+	return &graphQLSocialMedia{social: s}
+}
+
+func (s *graphQLSocialMedia) ID() graphql.ID {
+	return graphQLIDOf(s.social.ID)
+}
+
+func (s *graphQLSocialMedia) Name() string {
+	return s.social.Name
+}
+
+func (s *graphQLSocialMedia) SocialMediaUrl() string {
+	return s.social.SocialMediaUrl
+}
+
+func (s *graphQLSocialMedia) CreatedAt() graphQLDateTime {
+	return graphQLDateTime{s.social.CreatedAt}
+}
+
+func (s *graphQLSocialMedia) UpdatedAt() graphQLDateTime {
+	return graphQLDateTime{s.social.UpdatedAt}
+}
+
+func (s *graphQLSocialMedia) User(ctx context.Context) (*graphQLUser, error) {
+	user, err := one(ctx, graphQLViewerFrom(ctx).loaders.users, s.social.UserId)
+	if err != nil || user == nil {
+		return nil, err
+	}
+	return newGraphQLUser(*user), nil
+}
+
+// queries
+
+func (r *graphQLResolver) Me(ctx context.Context) (*graphQLUser, error) {
+	viewer := graphQLViewerFrom(ctx)
+	if err := viewer.check(ctx, "me", model.SCOPE_PROFILE_READ, "User", 1); err != nil {
+		return nil, err
+	}
+	if viewer.id == 0 {
+		return nil, nil
+	}
+	user, err := one(ctx, viewer.loaders.users, viewer.id)
+	if err != nil {
+		return nil, err
+	}
+	return resolvedOne(ctx, "User", user, newGraphQLUser)
+}
+
+func (r *graphQLResolver) User(ctx context.Context, args struct{ ID graphql.ID }) (*graphQLUser, error) {
+	viewer := graphQLViewerFrom(ctx)
+	if err := viewer.check(ctx, "user", model.SCOPE_PROFILE_READ, "User", 1); err != nil {
+		return nil, err
+	}
+	id, err := graphQLID("id", args.ID)
+	if err != nil {
+		return nil, err
+	}
+	user, err := one(ctx, viewer.loaders.users, id)
+	if err != nil {
+		return nil, err
+	}
+	return resolvedOne(ctx, "User", user, newGraphQLUser)
+}
+
+func (r *graphQLResolver) Photo(ctx context.Context, args struct{ ID graphql.ID }) (*graphQLPhoto, error) {
+	viewer := graphQLViewerFrom(ctx)
+	if err := viewer.check(ctx, "photo", model.SCOPE_PHOTOS_READ, "Photo", 1); err != nil {
+		return nil, err
+	}
+	id, err := graphQLID("id", args.ID)
+	if err != nil {
+		return nil, err
+	}
+	photo, err := one(ctx, viewer.loaders.photos, id)
+	if err != nil {
+		return nil, err
+	}
+	return resolvedOne(ctx, "Photo", photo, newGraphQLPhoto)
+}
+
+func (r *graphQLResolver) Photos(ctx context.Context, args struct{ UserID graphql.ID }) ([]*graphQLPhoto, error) {
+	viewer := graphQLViewerFrom(ctx)
+	if err := viewer.check(ctx, "photos", model.SCOPE_PHOTOS_READ, "Photo", graphQLListSize); err != nil {
+		return nil, err
+	}
+	userId, err := graphQLID("userId", args.UserID)
+	if err != nil {
+		return nil, err
+	}
+	photos, err := one(ctx, viewer.loaders.photosByUser, userId)
+	if err != nil {
+		return nil, err
+	}
+	return resolved(ctx, "Photo", *photos, newGraphQLPhoto)
+}
+
+func (r *graphQLResolver) Comment(ctx context.Context, args struct{ ID graphql.ID }) (*graphQLComment, error) {
+	viewer := graphQLViewerFrom(ctx)
+	if err := viewer.check(ctx, "comment", model.SCOPE_COMMENTS_READ, "Comment", 1); err != nil {
+		return nil, err
+	}
+	id, err := graphQLID("id", args.ID)
+	if err != nil {
+		return nil, err
+	}
+	return r.resolveComment(ctx, id)
+}
+
+// resolveComment reads a comment by itself, as the viewer's own can sit
+// under a photo they no longer see.
+func (r *graphQLResolver) resolveComment(ctx context.Context, id uint64) (*graphQLComment, error) {
+	comment, err := r.commentSvc.GetExpandedComment(ctx, id, graphQLViewerFrom(ctx).id, model.Expand{})
+	if err != nil || comment.ID == 0 {
+		return nil, err
+	}
+	return resolvedOne(ctx, "Comment", &comment, newGraphQLComment)
+}
+
+func (r *graphQLResolver) Comments(ctx context.Context, args struct{ PhotoID graphql.ID }) ([]*graphQLComment, error) {
+	viewer := graphQLViewerFrom(ctx)
+	if err := viewer.check(ctx, "comments", model.SCOPE_COMMENTS_READ, "Comment", graphQLListSize); err != nil {
+		return nil, err
+	}
+	photoId, err := graphQLID("photoId", args.PhotoID)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := one(ctx, viewer.loaders.commentsByPhoto, photoId)
+	if err != nil {
+		return nil, err
+	}
+	return resolved(ctx, "Comment", *comments, newGraphQLComment)
+}
+
+func (r *graphQLResolver) SocialMedia(ctx context.Context, args struct{ ID graphql.ID }) (*graphQLSocialMedia, error) {
+	viewer := graphQLViewerFrom(ctx)
+	if err := viewer.check(ctx, "socialMedia", model.SCOPE_SOCIAL_MEDIAS_READ, "SocialMedia", 1); err != nil {
+		return nil, err
+	}
+	id, err := graphQLID("id", args.ID)
+	if err != nil {
+		return nil, err
+	}
+	return r.resolveSocialMedia(ctx, id)
+}
+
+func (r *graphQLResolver) resolveSocialMedia(ctx context.Context, id uint64) (*graphQLSocialMedia, error) {
+	social, err := r.socialMediaSvc.GetExpandedSocialMedia(ctx, id, graphQLViewerFrom(ctx).id, model.Expand{})
+	if err != nil || social.ID == 0 {
+		return nil, err
+	}
+	return resolvedOne(ctx, "SocialMedia", &social, newGraphQLSocialMedia)
+}
+
+func (r *graphQLResolver) SocialMedias(ctx context.Context, args struct{ UserID graphql.ID }) ([]*graphQLSocialMedia, error) {
+	viewer := graphQLViewerFrom(ctx)
+	if err := viewer.check(ctx, "socialMedias", model.SCOPE_SOCIAL_MEDIAS_READ, "SocialMedia", graphQLListSize); err != nil {
+		return nil, err
+	}
+	userId, err := graphQLID("userId", args.UserID)
+	if err != nil {
+		return nil, err
+	}
+	socials, err := one(ctx, viewer.loaders.socialMediasByUser, userId)
+	if err != nil {
+		return nil, err
+	}
+	return resolved(ctx, "SocialMedia", *socials, newGraphQLSocialMedia)
+}
+
+func (r *graphQLResolver) Explore(ctx context.Context, args struct {
+	Limit  int32
+	Offset int32
+}) ([]*graphQLPhoto, error) {
+	viewer := graphQLViewerFrom(ctx)
+	req := model.ExploreReq{Limit: int(args.Limit), Offset: int(args.Offset)}
+	size := req.Limit
+	if size == 0 {
+		size = exploreDefaultLimit
+	}
+	if err := viewer.check(ctx, "explore", model.SCOPE_PHOTOS_READ, "Photo", size); err != nil {
+		return nil, err
+	}
+	if err := validator.New().Struct(req); err != nil {
+		return nil, err
+	}
+	req.Limit = size
+	photos, err := r.exploreSvc.GetExplore(ctx, req.Limit, req.Offset, viewer.id)
+	if err != nil {
+		return nil, err
+	}
+	return resolved(ctx, "Photo", photos, newGraphQLPhoto)
+}
+
+// mutations
+
+// writer is the viewer of a mutation, who must be signed in, and have
+// verified their email to create and edit.
+func (r *graphQLResolver) writer(ctx context.Context, verified bool) (*graphQLViewer, error) {
+	viewer := graphQLViewerFrom(ctx)
+	if viewer.readOnly {
+		return nil, viewer.refuse(errGraphQLReadOnly)
+	}
+	if viewer.id == 0 {
+		return nil, errGraphQLSignIn
+	}
+	if verified {
+		ok, err := r.accountSvc.IsEmailVerified(ctx, viewer.id)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, errGraphQLNotVerified
+		}
+	}
+	return viewer, nil
+}
+
+// optionalString replaces current with arg, when given.
+func optionalString(arg *string, current string) string {
+	if arg != nil {
+		return *arg
+	}
+	return current
+}
+
+func (r *graphQLResolver) CreatePhoto(ctx context.Context, args struct {
+	Title    string
+	Caption  string
+	PhotoUrl string
+}) (*graphQLPhoto, error) {
+	if err := graphQLViewerFrom(ctx).check(ctx, "createPhoto", model.SCOPE_PHOTOS_WRITE, "Photo", 1); err != nil {
+		return nil, err
+	}
+	viewer, err := r.writer(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	req := model.PhotoCreateReq{Title: args.Title, Caption: args.Caption, PhotoUrl: args.PhotoUrl}
+	if err := validator.New().Struct(req); err != nil {
+		return nil, err
+	}
+
+	photo, err := r.photoSvc.CreatePhoto(ctx, model.Photo{UserId: viewer.id, Title: req.Title, Caption: req.Caption, PhotoUrl: req.PhotoUrl})
+	if err != nil {
+		return nil, err
+	}
+	viewer.loaders.clear()
+	return r.resolvePhoto(ctx, photo.ID)
+}
+
+func (r *graphQLResolver) resolvePhoto(ctx context.Context, id uint64) (*graphQLPhoto, error) {
+	photo, err := one(ctx, graphQLViewerFrom(ctx).loaders.photos, id)
+	if err != nil {
+		return nil, err
+	}
+	return resolvedOne(ctx, "Photo", photo, newGraphQLPhoto)
+}
+
+// ownPhoto is the photo id when it belongs to the viewer.
+func (r *graphQLResolver) ownPhoto(ctx context.Context, viewer *graphQLViewer, id uint64) (model.Photo, error) {
+	photo, err := r.photoSvc.GetPhotoById(ctx, id, viewer.id)
+	if err != nil {
+		return model.Photo{}, err
+	}
+	if photo.ID == 0 {
+		return model.Photo{}, errors.New("photo not found")
+	}
+	if photo.UserId != viewer.id {
+		return model.Photo{}, errGraphQLNotOwner
+	}
+	return photo, nil
+}
+
+func (r *graphQLResolver) UpdatePhoto(ctx context.Context, args struct {
+	ID       graphql.ID
+	Title    *string
+	Caption  *string
+	PhotoUrl *string
+}) (*graphQLPhoto, error) {
+	if err := graphQLViewerFrom(ctx).check(ctx, "updatePhoto", model.SCOPE_PHOTOS_WRITE, "Photo", 1); err != nil {
+		return nil, err
+	}
+	viewer, err := r.writer(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	id, err := graphQLID("id", args.ID)
+	if err != nil {
+		return nil, err
+	}
+	photo, err := r.ownPhoto(ctx, viewer, id)
+	if err != nil {
+		return nil, err
+	}
+
+	req := model.PhotoUpdateReq{
+		Title:    optionalString(args.Title, photo.Title),
+		Caption:  optionalString(args.Caption, photo.Caption),
+		PhotoUrl: optionalString(args.PhotoUrl, photo.PhotoUrl),
+	}
+	if err := validator.New().Struct(req); err != nil {
+		return nil, err
+	}
+	_, err = r.photoSvc.EditPhoto(ctx, model.Photo{ID: id, UserId: photo.UserId, Title: req.Title, Caption: req.Caption, PhotoUrl: req.PhotoUrl}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	viewer.loaders.clear()
+	return r.resolvePhoto(ctx, id)
+}
+
+func (r *graphQLResolver) DeletePhoto(ctx context.Context, args struct{ ID graphql.ID }) (graphql.ID, error) {
+	if err := graphQLViewerFrom(ctx).check(ctx, "deletePhoto", model.SCOPE_PHOTOS_WRITE, "", 1); err != nil {
+		return "", err
+	}
+	viewer, err := r.writer(ctx, false)
+	if err != nil {
+		return "", err
+	}
+	id, err := graphQLID("id", args.ID)
+	if err != nil {
+		return "", err
+	}
+	if _, err := r.ownPhoto(ctx, viewer, id); err != nil {
+		return "", err
+	}
+
+	if err := r.photoSvc.DeletePhoto(ctx, id, viewer.id); err != nil {
+		return "", err
+	}
+	viewer.loaders.clear()
+	return graphQLIDOf(id), nil
+}
+
+func (r *graphQLResolver) CreateComment(ctx context.Context, args struct {
+	PhotoID graphql.ID
+	Message string
+}) (*graphQLComment, error) {
+	if err := graphQLViewerFrom(ctx).check(ctx, "createComment", model.SCOPE_COMMENTS_WRITE, "Comment", 1); err != nil {
+		return nil, err
+	}
+	viewer, err := r.writer(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	photoId, err := graphQLID("photoId", args.PhotoID)
+	if err != nil {
+		return nil, err
+	}
+	req := model.CommentCreateReq{PhotoId: photoId, Message: args.Message}
+	if err := validator.New().Struct(req); err != nil {
+		return nil, err
+	}
+
+	comment, err := r.commentSvc.CreateComment(ctx, model.Comment{UserId: viewer.id, PhotoId: photoId, Message: req.Message})
+	if err != nil {
+		return nil, err
+	}
+	viewer.loaders.clear()
+	return r.resolveComment(ctx, comment.ID)
+}
+
+// ownComment is the comment id when it belongs to the viewer.
+func (r *graphQLResolver) ownComment(ctx context.Context, viewer *graphQLViewer, id uint64) (model.Comment, error) {
+	comment, err := r.commentSvc.GetCommentById(ctx, id, viewer.id)
+	if err != nil {
+		return model.Comment{}, err
+	}
+	if comment.ID == 0 {
+		return model.Comment{}, errors.New("comment not found")
+	}
+	if comment.UserId != viewer.id {
+		return model.Comment{}, errGraphQLNotOwner
+	}
+	return comment, nil
+}
+
+func (r *graphQLResolver) UpdateComment(ctx context.Context, args struct {
+	ID      graphql.ID
+	Message string
+}) (*graphQLComment, error) {
+	if err := graphQLViewerFrom(ctx).check(ctx, "updateComment", model.SCOPE_COMMENTS_WRITE, "Comment", 1); err != nil {
+		return nil, err
+	}
+	viewer, err := r.writer(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	id, err := graphQLID("id", args.ID)
+	if err != nil {
+		return nil, err
+	}
+	comment, err := r.ownComment(ctx, viewer, id)
+	if err != nil {
+		return nil, err
+	}
+
+	req := model.CommentUpdateReq{Message: args.Message}
+	if err := validator.New().Struct(req); err != nil {
+		return nil, err
+	}
+	_, err = r.commentSvc.EditComment(ctx, model.Comment{ID: id, UserId: comment.UserId, PhotoId: comment.PhotoId, Message: req.Message}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	viewer.loaders.clear()
+	return r.resolveComment(ctx, id)
+}
+
+func (r *graphQLResolver) DeleteComment(ctx context.Context, args struct{ ID graphql.ID }) (graphql.ID, error) {
+	if err := graphQLViewerFrom(ctx).check(ctx, "deleteComment", model.SCOPE_COMMENTS_WRITE, "", 1); err != nil {
+		return "", err
+	}
+	viewer, err := r.writer(ctx, false)
+	if err != nil {
+		return "", err
+	}
+	id, err := graphQLID("id", args.ID)
+	if err != nil {
+		return "", err
+	}
+	if _, err := r.ownComment(ctx, viewer, id); err != nil {
+		return "", err
+	}
+
+	if err := r.commentSvc.DeleteComment(ctx, id, viewer.id); err != nil {
+		return "", err
+	}
+	viewer.loaders.clear()
+	return graphQLIDOf(id), nil
+}
+
+func (r *graphQLResolver) CreateSocialMedia(ctx context.Context, args struct {
+	Name           string
+	SocialMediaUrl string
+}) (*graphQLSocialMedia, error) {
+	if err := graphQLViewerFrom(ctx).check(ctx, "createSocialMedia", model.SCOPE_SOCIAL_MEDIAS_WRITE, "SocialMedia", 1); err != nil {
+		return nil, err
+	}
+	viewer, err := r.writer(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	req := model.SocialMediaReq{Name: args.Name, SocialMediaUrl: args.SocialMediaUrl}
+	if err := validator.New().Struct(req); err != nil {
+		return nil, err
+	}
+
+	social, err := r.socialMediaSvc.CreateSocialMedia(ctx, model.SocialMedia{UserId: viewer.id, Name: req.Name, SocialMediaUrl: req.SocialMediaUrl})
+	if err != nil {
+		return nil, err
+	}
+	viewer.loaders.clear()
+	return r.resolveSocialMedia(ctx, social.ID)
+}
+
+// ownSocialMedia is the social media id when it belongs to the viewer.
+func (r *graphQLResolver) ownSocialMedia(ctx context.Context, viewer *graphQLViewer, id uint64) (model.SocialMedia, error) {
+	social, err := r.socialMediaSvc.GetSocialMediaById(ctx, id, viewer.id)
+	if err != nil {
+		return model.SocialMedia{}, err
+	}
+	if social.ID == 0 {
+		return model.SocialMedia{}, errors.New("social media not found")
+	}
+	if social.UserId != viewer.id {
+		return model.SocialMedia{}, errGraphQLNotOwner
+	}
+	return social, nil
+}
+
+func (r *graphQLResolver) UpdateSocialMedia(ctx context.Context, args struct {
+	ID             graphql.ID
+	Name           *string
+	SocialMediaUrl *string
+}) (*graphQLSocialMedia, error) {
+	if err := graphQLViewerFrom(ctx).check(ctx, "updateSocialMedia", model.SCOPE_SOCIAL_MEDIAS_WRITE, "SocialMedia", 1); err != nil {
+		return nil, err
+	}
+	viewer, err := r.writer(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	id, err := graphQLID("id", args.ID)
+	if err != nil {
+		return nil, err
+	}
+	social, err := r.ownSocialMedia(ctx, viewer, id)
+	if err != nil {
+		return nil, err
+	}
+
+	req := model.SocialMediaReq{
+		Name:           optionalString(args.Name, social.Name),
+		SocialMediaUrl: optionalString(args.SocialMediaUrl, social.SocialMediaUrl),
+	}
+	if err := validator.New().Struct(req); err != nil {
+		return nil, err
+	}
+	_, err = r.socialMediaSvc.EditSocialMedia(ctx, model.SocialMedia{ID: id, UserId: social.UserId, Name: req.Name, SocialMediaUrl: req.SocialMediaUrl}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	viewer.loaders.clear()
+	return r.resolveSocialMedia(ctx, id)
+}
+
+func (r *graphQLResolver) DeleteSocialMedia(ctx context.Context, args struct{ ID graphql.ID }) (graphql.ID, error) {
+	if err := graphQLViewerFrom(ctx).check(ctx, "deleteSocialMedia", model.SCOPE_SOCIAL_MEDIAS_WRITE, "", 1); err != nil {
+		return "", err
+	}
+	viewer, err := r.writer(ctx, false)
+	if err != nil {
+		return "", err
+	}
+	id, err := graphQLID("id", args.ID)
+	if err != nil {
+		return "", err
+	}
+	if _, err := r.ownSocialMedia(ctx, viewer, id); err != nil {
+		return "", err
+	}
+
+	if err := r.socialMediaSvc.DeleteSocialMedia(ctx, id, viewer.id); err != nil {
+		return "", err
+	}
+	viewer.loaders.clear()
+	return graphQLIDOf(id), nil
+}
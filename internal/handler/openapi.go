@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"mygram/internal/openapi"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OpenAPIHandler interface {
+	GetSpec(ctx *gin.Context)
+}
+
+type openAPIHandlerImpl struct {
+	doc *openapi.Document
+}
+
+func NewOpenAPIHandler(doc *openapi.Document) OpenAPIHandler {
+	return &openAPIHandlerImpl{doc: doc}
+}
+
+// GetSpec answers the OpenAPI document of the API.
+func (o *openAPIHandlerImpl) GetSpec(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, o.doc)
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"mygram/pkg"
+	"net/http"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+)
+
+const MIME_MERGE_PATCH = "application/merge-patch+json"
+
+// bindUpdate binds the body of a PUT, which replaces req, or of a PATCH, a
+// JSON merge patch (RFC 7396) applied to req holding the current values.
+func bindUpdate(ctx *gin.Context, req any) bool {
+	if ctx.Request.Method != http.MethodPatch {
+		reflect.ValueOf(req).Elem().SetZero()
+		if err := ctx.Bind(req); err != nil {
+			ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+			return false
+		}
+		return true
+	}
+
+	if contentType := ctx.GetHeader("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (mediaType != MIME_MERGE_PATCH && mediaType != gin.MIMEJSON) {
+			ctx.JSON(http.StatusUnsupportedMediaType, pkg.ErrorResponse{Message: "PATCH takes " + MIME_MERGE_PATCH})
+			return false
+		}
+	}
+
+	var patch any
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&patch); err != nil {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: "invalid merge patch", Errors: []string{err.Error()}})
+		return false
+	}
+	if _, ok := patch.(map[string]any); !ok {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: "a merge patch must be an object"})
+		return false
+	}
+
+	current, err := json.Marshal(req)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+		return false
+	}
+	var doc any
+	if err := json.Unmarshal(current, &doc); err != nil {
+		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+		return false
+	}
+	merged, err := json.Marshal(mergePatch(doc, patch))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+		return false
+	}
+
+	// members the resource does not have are refused rather than dropped
+	decoder := json.NewDecoder(bytes.NewReader(merged))
+	decoder.DisallowUnknownFields()
+	reflect.ValueOf(req).Elem().SetZero()
+	if err := decoder.Decode(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: "invalid merge patch", Errors: []string{err.Error()}})
+		return false
+	}
+	return true
+}
+
+// mergePatch applies patch to target as RFC 7396 describes: objects merge
+// member by member, null removes a member and anything else replaces it.
+func mergePatch(target any, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = map[string]any{}
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergePatch(targetObject[key], value)
+	}
+	return targetObject
+}
@@ -1,12 +1,14 @@
 package handler
 
 import (
+	"errors"
 	"mygram/internal/middleware"
 	"mygram/internal/model"
 	"mygram/internal/service"
 	"mygram/pkg"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
@@ -15,10 +17,14 @@ import (
 type PhotoHandler interface {
 	CreatePhoto(ctx *gin.Context)
 	GetPhotosByUserId(ctx *gin.Context)
+	GetPhotoById(ctx *gin.Context)
 	EditPhoto(ctx *gin.Context)
 	DeletePhoto(ctx *gin.Context)
 }
 
+// photoExpandDefault is what photos came with before ?expand=.
+var photoExpandDefault = model.Expand{User: true}
+
 type photoHandlerImpl struct {
 	svc service.PhotoService
 }
@@ -81,7 +87,16 @@ func (p *photoHandlerImpl) GetPhotosByUserId(ctx *gin.Context) {
 		return
 	}
 
-	photos, err := p.svc.GetPhotosByUserId(ctx, uint64(userId))
+	expand, ok := parseExpand(ctx, photoExpandDefault, model.EXPAND_USER, model.EXPAND_COMMENTS)
+	if !ok {
+		return
+	}
+	fields, ok := parseFields(ctx, model.PhotoGetRes{})
+	if !ok {
+		return
+	}
+
+	photos, err := p.svc.GetPhotosByUserId(ctx, uint64(userId), middleware.ViewerID(ctx), expand)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
 		return
@@ -91,7 +106,46 @@ func (p *photoHandlerImpl) GetPhotosByUserId(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, photos)
+	if middleware.IsGuest(ctx) {
+		for i := range photos {
+			publicPhoto(&photos[i])
+		}
+	}
+
+	updatedAt := latestUpdatedAt(photos, func(v model.PhotoGetRes) time.Time { return v.UpdatedAt })
+	respondETag(ctx, http.StatusOK, updatedAt, photos, fields)
+}
+
+func (p *photoHandlerImpl) GetPhotoById(ctx *gin.Context) {
+	photoId, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if photoId == 0 || err != nil {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: "invalid photo ID"})
+		return
+	}
+	expand, ok := parseExpand(ctx, photoExpandDefault, model.EXPAND_USER, model.EXPAND_COMMENTS)
+	if !ok {
+		return
+	}
+	fields, ok := parseFields(ctx, model.PhotoGetRes{})
+	if !ok {
+		return
+	}
+
+	photo, err := p.svc.GetExpandedPhoto(ctx, photoId, middleware.ViewerID(ctx), expand)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+	if photo.ID == 0 {
+		ctx.JSON(http.StatusNotFound, pkg.ErrorResponse{Message: "photo not found"})
+		return
+	}
+
+	if middleware.IsGuest(ctx) {
+		publicPhoto(&photo)
+	}
+
+	respondETag(ctx, http.StatusOK, photo.UpdatedAt, photo, fields)
 }
 
 func (p *photoHandlerImpl) EditPhoto(ctx *gin.Context) {
@@ -101,7 +155,7 @@ func (p *photoHandlerImpl) EditPhoto(ctx *gin.Context) {
 		return
 	}
 
-	photo, err := p.svc.GetPhotoById(ctx, uint64(photoId))
+	photo, err := p.svc.GetPhotoById(ctx, uint64(photoId), middleware.ViewerID(ctx))
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
 		return
@@ -128,10 +182,13 @@ func (p *photoHandlerImpl) EditPhoto(ctx *gin.Context) {
 		return
 	}
 
-	photoUpdateReq := model.PhotoUpdateReq{}
-	err = ctx.Bind(&photoUpdateReq)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+	updatedAt, ok := checkIfMatch(ctx, photo.UpdatedAt)
+	if !ok {
+		return
+	}
+
+	photoUpdateReq := model.PhotoUpdateReq{Title: photo.Title, Caption: photo.Caption, PhotoUrl: photo.PhotoUrl}
+	if !bindUpdate(ctx, &photoUpdateReq) {
 		return
 	}
 
@@ -149,13 +206,17 @@ func (p *photoHandlerImpl) EditPhoto(ctx *gin.Context) {
 	photoUp.PhotoUrl = photoUpdateReq.PhotoUrl
 	photoUp.UserId = photo.UserId
 
-	photoRes, err := p.svc.EditPhoto(ctx, photoUp)
+	photoRes, err := p.svc.EditPhoto(ctx, photoUp, updatedAt)
+	if errors.Is(err, service.ErrPreconditionFailed) {
+		ctx.JSON(http.StatusPreconditionFailed, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, photoRes)
+	respondETag(ctx, http.StatusOK, photoRes.UpdatedAt, photoRes, nil)
 }
 
 func (p *photoHandlerImpl) DeletePhoto(ctx *gin.Context) {
@@ -165,7 +226,7 @@ func (p *photoHandlerImpl) DeletePhoto(ctx *gin.Context) {
 		return
 	}
 
-	photo, err := p.svc.GetPhotoById(ctx, uint64(photoId))
+	photo, err := p.svc.GetPhotoById(ctx, uint64(photoId), middleware.ViewerID(ctx))
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
 		return
@@ -192,7 +253,7 @@ func (p *photoHandlerImpl) DeletePhoto(ctx *gin.Context) {
 		return
 	}
 
-	err = p.svc.DeletePhoto(ctx, uint64(photoId))
+	err = p.svc.DeletePhoto(ctx, uint64(photoId), uint64(userIdInt))
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
 		return
@@ -202,3 +263,11 @@ func (p *photoHandlerImpl) DeletePhoto(ctx *gin.Context) {
 		"message": "Your photo has been successfully deleted" ,
 	})
 }
+
+// publicPhoto leaves out the emails of the owner and the commenters.
+func publicPhoto(photo *model.PhotoGetRes) {
+	photo.User = photo.User.Public()
+	for i := range photo.Comments {
+		photo.Comments[i].User = photo.Comments[i].User.Public()
+	}
+}
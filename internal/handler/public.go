@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"mygram/internal/service"
+	"mygram/pkg"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PublicHandler interface {
+	GetPublicToken(ctx *gin.Context)
+}
+
+type publicHandlerImpl struct {
+	svc service.UserService
+}
+
+func NewPublicHandler(svc service.UserService) PublicHandler {
+	return &publicHandlerImpl{svc: svc}
+}
+
+// GetPublicToken hands out a guest token, which reads photos, comments,
+// social medias and profiles without an account.
+func (p *publicHandlerImpl) GetPublicToken(ctx *gin.Context) {
+	token, err := p.svc.GeneratePublicToken(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{
+			Message: "error generating public token",
+			Errors:  []string{err.Error()},
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, map[string]any{"token": token})
+}
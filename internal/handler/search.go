@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"mygram/internal/middleware"
+	"mygram/internal/model"
+	"mygram/internal/service"
+	"mygram/pkg"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+const searchDefaultLimit = 20
+
+type SearchHandler interface {
+	Search(ctx *gin.Context)
+}
+
+type searchHandlerImpl struct {
+	svc service.SearchService
+}
+
+func NewSearchHandler(svc service.SearchService) SearchHandler {
+	return &searchHandlerImpl{svc: svc}
+}
+
+// Search finds photos by title and caption, users by username, or hashtags
+// by prefix, picked by the type query and photos by default.
+func (s *searchHandlerImpl) Search(ctx *gin.Context) {
+	req := model.SearchReq{}
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+	if req.Type == "" {
+		req.Type = model.SEARCH_TYPE_PHOTOS
+	}
+	if req.Limit == 0 {
+		req.Limit = searchDefaultLimit
+	}
+
+	scope := model.SCOPE_PHOTOS_READ
+	if req.Type == model.SEARCH_TYPE_USERS {
+		scope = model.SCOPE_PROFILE_READ
+	}
+	if !middleware.CheckScope(ctx, scope) {
+		return
+	}
+
+	var (
+		res any
+		err error
+	)
+	switch req.Type {
+	case model.SEARCH_TYPE_USERS:
+		res, err = s.svc.SearchUsers(ctx, req.Q, req.Limit, middleware.ViewerID(ctx))
+	case model.SEARCH_TYPE_HASHTAGS:
+		res, err = s.svc.SearchHashtags(ctx, req.Q, req.Limit, middleware.ViewerID(ctx))
+	default:
+		var photos []model.PhotoGetRes
+		photos, err = s.svc.SearchPhotos(ctx, req.Q, req.Limit, middleware.ViewerID(ctx))
+		if middleware.IsGuest(ctx) {
+			for i := range photos {
+				photos[i].User = photos[i].User.Public()
+			}
+		}
+		res = photos
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
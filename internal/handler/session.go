@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"errors"
+	"mygram/internal/middleware"
+	"mygram/internal/service"
+	"mygram/pkg"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (u *userHandlerImpl) GetSessions(ctx *gin.Context) {
+	userIdClaim, ok := ctx.Get(middleware.CLAIM_USER_ID)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, pkg.ErrorResponse{Message: "invalid user session"})
+		return
+	}
+	userIdInt, ok := userIdClaim.(float64)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: "invalid user id session"})
+		return
+	}
+
+	sessions, err := u.sessionSvc.GetSessions(ctx, uint64(userIdInt), ctx.GetString(middleware.CLAIM_JTI))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, sessions)
+}
+
+func (u *userHandlerImpl) RevokeSession(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("sessionId"))
+	if id == 0 || err != nil {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: "invalid required param"})
+		return
+	}
+
+	userIdClaim, ok := ctx.Get(middleware.CLAIM_USER_ID)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, pkg.ErrorResponse{Message: "invalid user session"})
+		return
+	}
+	userIdInt, ok := userIdClaim.(float64)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: "invalid user id session"})
+		return
+	}
+
+	err = u.sessionSvc.RevokeSession(ctx, uint64(userIdInt), uint64(id))
+	if errors.Is(err, service.ErrSessionNotFound) {
+		ctx.JSON(http.StatusNotFound, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, map[string]any{
+		"message": "The session has been signed out",
+	})
+}
+
+func (u *userHandlerImpl) RevokeOtherSessions(ctx *gin.Context) {
+	userIdClaim, ok := ctx.Get(middleware.CLAIM_USER_ID)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, pkg.ErrorResponse{Message: "invalid user session"})
+		return
+	}
+	userIdInt, ok := userIdClaim.(float64)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: "invalid user id session"})
+		return
+	}
+
+	revoked, err := u.sessionSvc.RevokeOtherSessions(ctx, uint64(userIdInt), ctx.GetString(middleware.CLAIM_JTI))
+	if errors.Is(err, service.ErrInvalidSession) {
+		ctx.JSON(http.StatusUnauthorized, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, map[string]any{
+		"message": "All other sessions have been signed out",
+		"revoked": revoked,
+	})
+}
+
+func (u *userHandlerImpl) GetLoginHistory(ctx *gin.Context) {
+	userIdClaim, ok := ctx.Get(middleware.CLAIM_USER_ID)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, pkg.ErrorResponse{Message: "invalid user session"})
+		return
+	}
+	userIdInt, ok := userIdClaim.(float64)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: "invalid user id session"})
+		return
+	}
+
+	attempts, err := u.sessionSvc.GetLoginHistory(ctx, uint64(userIdInt))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, attempts)
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"errors"
+	"mygram/internal/model"
+	"mygram/internal/service"
+	"mygram/internal/sso"
+	"mygram/pkg"
+	"net/http"
+	"path"
+
+	"github.com/gin-gonic/gin"
+)
+
+// oauthStateCookie binds the callback to the browser that started the
+// login, which is what stops login CSRF.
+const oauthStateCookie = "oauth_state"
+
+func (u *userHandlerImpl) SocialLogin(ctx *gin.Context) {
+	authURL, stateToken, err := u.socialSvc.Begin(ctx, ctx.Param("provider"))
+	if err != nil {
+		socialLoginError(ctx, err)
+		return
+	}
+
+	// scoped to /users/oauth/:provider, the callback is below it
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(oauthStateCookie, stateToken, 600, path.Dir(ctx.Request.URL.Path), "", ctx.Request.TLS != nil, true)
+	ctx.Redirect(http.StatusFound, authURL)
+}
+
+func (u *userHandlerImpl) SocialLoginCallback(ctx *gin.Context) {
+	stateToken, _ := ctx.Cookie(oauthStateCookie)
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(oauthStateCookie, "", -1, path.Dir(ctx.Request.URL.Path), "", ctx.Request.TLS != nil, true)
+
+	if reason := ctx.Query("error"); reason != "" {
+		message := "identity provider returned " + reason
+		if description := ctx.Query("error_description"); description != "" {
+			message += ": " + description
+		}
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: message})
+		return
+	}
+	if ctx.Query("code") == "" {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: "missing authorization code"})
+		return
+	}
+
+	user, err := u.socialSvc.Complete(ctx, ctx.Param("provider"), stateToken, ctx.Query("state"), ctx.Query("code"))
+	if err != nil {
+		socialLoginError(ctx, err)
+		return
+	}
+
+	u.startSession(ctx, user, model.LOGIN_METHOD_OAUTH+ctx.Param("provider"))
+}
+
+func socialLoginError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrUnknownProvider):
+		ctx.JSON(http.StatusNotFound, pkg.ErrorResponse{Message: err.Error()})
+	case errors.Is(err, service.ErrInvalidOAuthState), errors.Is(err, service.ErrIdentityEmailMissing):
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: err.Error()})
+	case errors.Is(err, sso.ErrCodeExchange), errors.Is(err, sso.ErrInvalidIDToken), errors.Is(err, service.ErrInvalidCredentials):
+		ctx.JSON(http.StatusUnauthorized, pkg.ErrorResponse{Message: err.Error()})
+	case errors.Is(err, service.ErrIdentityEmailTaken):
+		ctx.JSON(http.StatusConflict, pkg.ErrorResponse{Message: err.Error()})
+	case errors.Is(err, sso.ErrDiscovery):
+		ctx.JSON(http.StatusBadGateway, pkg.ErrorResponse{Message: err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+	}
+}
@@ -1,12 +1,14 @@
 package handler
 
 import (
+	"errors"
 	"mygram/internal/middleware"
 	"mygram/internal/model"
 	"mygram/internal/service"
 	"mygram/pkg"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
@@ -15,10 +17,14 @@ import (
 type SocialMediaHandler interface {
 	CreateSocialMedia(ctx *gin.Context)
 	GetSocialMediasByUserId(ctx *gin.Context)
+	GetSocialMediaById(ctx *gin.Context)
 	EditSocialMedia(ctx *gin.Context)
 	DeleteSocialMedia(ctx *gin.Context)
 }
 
+// socialMediaExpandDefault is what social medias came with before ?expand=.
+var socialMediaExpandDefault = model.Expand{User: true}
+
 type socialMediaHandlerImpl struct {
 	svc service.SocialMediaService
 }
@@ -80,7 +86,16 @@ func (s *socialMediaHandlerImpl) GetSocialMediasByUserId(ctx *gin.Context) {
 		return
 	}
 
-	socials, err := s.svc.GetSocialMediasByUserId(ctx, uint64(userId))
+	expand, ok := parseExpand(ctx, socialMediaExpandDefault, model.EXPAND_USER)
+	if !ok {
+		return
+	}
+	fields, ok := parseFields(ctx, model.SocialMediaGetRes{})
+	if !ok {
+		return
+	}
+
+	socials, err := s.svc.GetSocialMediasByUserId(ctx, uint64(userId), middleware.ViewerID(ctx), expand)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
 		return
@@ -90,7 +105,46 @@ func (s *socialMediaHandlerImpl) GetSocialMediasByUserId(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, socials)
+	if middleware.IsGuest(ctx) {
+		for i := range socials {
+			socials[i].User = socials[i].User.Public()
+		}
+	}
+
+	updatedAt := latestUpdatedAt(socials, func(v model.SocialMediaGetRes) time.Time { return v.UpdatedAt })
+	respondETag(ctx, http.StatusOK, updatedAt, socials, fields)
+}
+
+func (s *socialMediaHandlerImpl) GetSocialMediaById(ctx *gin.Context) {
+	socialId, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if socialId == 0 || err != nil {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: "invalid social media ID"})
+		return
+	}
+	expand, ok := parseExpand(ctx, socialMediaExpandDefault, model.EXPAND_USER)
+	if !ok {
+		return
+	}
+	fields, ok := parseFields(ctx, model.SocialMediaGetRes{})
+	if !ok {
+		return
+	}
+
+	social, err := s.svc.GetExpandedSocialMedia(ctx, socialId, middleware.ViewerID(ctx), expand)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+	if social.ID == 0 {
+		ctx.JSON(http.StatusNotFound, pkg.ErrorResponse{Message: "social media not found"})
+		return
+	}
+
+	if middleware.IsGuest(ctx) {
+		social.User = social.User.Public()
+	}
+
+	respondETag(ctx, http.StatusOK, social.UpdatedAt, social, fields)
 }
 
 func (s *socialMediaHandlerImpl) EditSocialMedia(ctx *gin.Context) {
@@ -100,7 +154,7 @@ func (s *socialMediaHandlerImpl) EditSocialMedia(ctx *gin.Context) {
 		return
 	}
 
-	social, err := s.svc.GetSocialMediaById(ctx, uint64(socialId))
+	social, err := s.svc.GetSocialMediaById(ctx, uint64(socialId), middleware.ViewerID(ctx))
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
 		return
@@ -127,10 +181,13 @@ func (s *socialMediaHandlerImpl) EditSocialMedia(ctx *gin.Context) {
 		return
 	}
 
-	socialMediaUpdateReq := model.SocialMediaReq{}
-	err = ctx.Bind(&socialMediaUpdateReq)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+	updatedAt, ok := checkIfMatch(ctx, social.UpdatedAt)
+	if !ok {
+		return
+	}
+
+	socialMediaUpdateReq := model.SocialMediaReq{Name: social.Name, SocialMediaUrl: social.SocialMediaUrl}
+	if !bindUpdate(ctx, &socialMediaUpdateReq) {
 		return
 	}
 
@@ -147,13 +204,17 @@ func (s *socialMediaHandlerImpl) EditSocialMedia(ctx *gin.Context) {
 	socialmediaUp.SocialMediaUrl = socialMediaUpdateReq.SocialMediaUrl
 	socialmediaUp.UserId = social.UserId
 
-	socialMediaRes, err := s.svc.EditSocialMedia(ctx, socialmediaUp)
+	socialMediaRes, err := s.svc.EditSocialMedia(ctx, socialmediaUp, updatedAt)
+	if errors.Is(err, service.ErrPreconditionFailed) {
+		ctx.JSON(http.StatusPreconditionFailed, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, socialMediaRes)
+	respondETag(ctx, http.StatusOK, socialMediaRes.UpdatedAt, socialMediaRes, nil)
 }
 
 func (s *socialMediaHandlerImpl) DeleteSocialMedia(ctx *gin.Context) {
@@ -163,7 +224,7 @@ func (s *socialMediaHandlerImpl) DeleteSocialMedia(ctx *gin.Context) {
 		return
 	}
 
-	social, err := s.svc.GetSocialMediaById(ctx, uint64(socialId))
+	social, err := s.svc.GetSocialMediaById(ctx, uint64(socialId), middleware.ViewerID(ctx))
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
 		return
@@ -190,7 +251,7 @@ func (s *socialMediaHandlerImpl) DeleteSocialMedia(ctx *gin.Context) {
 		return
 	}
 
-	err = s.svc.DeleteSocialMedia(ctx, uint64(socialId))
+	err = s.svc.DeleteSocialMedia(ctx, uint64(socialId), uint64(userIdInt))
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
 		return
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"errors"
+	"math"
+	"mygram/internal/middleware"
+	"mygram/internal/model"
+	"mygram/internal/service"
+	"mygram/pkg"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+func (u *userHandlerImpl) UserSignInMFA(ctx *gin.Context) {
+	req := model.TwoFactorLoginReq{}
+	if err := ctx.Bind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	user, err := u.twoFactorSvc.VerifyLogin(ctx, req.MFAPendingToken, req.Code)
+	if err != nil {
+		twoFactorError(ctx, err)
+		return
+	}
+
+	u.issueToken(ctx, user, model.LOGIN_METHOD_MFA)
+}
+
+func (u *userHandlerImpl) EnrollTwoFactor(ctx *gin.Context) {
+	userIdClaim, ok := ctx.Get(middleware.CLAIM_USER_ID)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, pkg.ErrorResponse{Message: "invalid user session"})
+		return
+	}
+	userIdInt, ok := userIdClaim.(float64)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: "invalid user id session"})
+		return
+	}
+
+	res, err := u.twoFactorSvc.Enroll(ctx, uint64(userIdInt))
+	if err != nil {
+		twoFactorError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (u *userHandlerImpl) ConfirmTwoFactor(ctx *gin.Context) {
+	userIdClaim, ok := ctx.Get(middleware.CLAIM_USER_ID)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, pkg.ErrorResponse{Message: "invalid user session"})
+		return
+	}
+	userIdInt, ok := userIdClaim.(float64)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: "invalid user id session"})
+		return
+	}
+
+	req := model.TwoFactorConfirmReq{}
+	if err := ctx.Bind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	codes, err := u.twoFactorSvc.Confirm(ctx, uint64(userIdInt), req.Code)
+	if err != nil {
+		twoFactorError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.TwoFactorConfirmRes{RecoveryCodes: codes})
+}
+
+func (u *userHandlerImpl) DisableTwoFactor(ctx *gin.Context) {
+	userIdClaim, ok := ctx.Get(middleware.CLAIM_USER_ID)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, pkg.ErrorResponse{Message: "invalid user session"})
+		return
+	}
+	userIdInt, ok := userIdClaim.(float64)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: "invalid user id session"})
+		return
+	}
+
+	req := model.TwoFactorDisableReq{}
+	if err := ctx.Bind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	if err := u.twoFactorSvc.Disable(ctx, uint64(userIdInt), req.Password, req.Code); err != nil {
+		twoFactorError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, map[string]any{
+		"message": "Two-factor authentication has been disabled",
+	})
+}
+
+func twoFactorError(ctx *gin.Context, err error) {
+	lockedErr := &service.AccountLockedError{}
+	switch {
+	case errors.Is(err, service.ErrInvalidCode), errors.Is(err, service.ErrInvalidPendingToken):
+		ctx.JSON(http.StatusUnauthorized, pkg.ErrorResponse{Message: err.Error()})
+	case errors.Is(err, service.ErrTwoFactorEnabled):
+		ctx.JSON(http.StatusConflict, pkg.ErrorResponse{Message: err.Error()})
+	case errors.Is(err, service.ErrTwoFactorNotEnrolled), errors.Is(err, service.ErrTwoFactorNotEnabled):
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: err.Error()})
+	case errors.As(err, &lockedErr):
+		ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(lockedErr.RetryAfter.Seconds()))))
+		ctx.JSON(http.StatusTooManyRequests, pkg.ErrorResponse{Message: err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+	}
+}
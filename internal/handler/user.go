@@ -1,8 +1,13 @@
 package handler
 
 import (
+	"errors"
+	"log"
+	"math"
+	"mygram/internal/mailer"
 	"mygram/internal/middleware"
 	"mygram/internal/model"
+	"mygram/internal/password"
 	"mygram/internal/service"
 	"mygram/pkg"
 	"net/http"
@@ -21,16 +26,72 @@ type UserHandler interface {
 
 	// activity
 	UserSignUp(ctx *gin.Context)
-	
+
+	// account
+	VerifyEmail(ctx *gin.Context)
+	ResendEmailVerification(ctx *gin.Context)
+	ForgotPassword(ctx *gin.Context)
+	ResetPassword(ctx *gin.Context)
+
+	// two-factor authentication
+	UserSignInMFA(ctx *gin.Context)
+	EnrollTwoFactor(ctx *gin.Context)
+	ConfirmTwoFactor(ctx *gin.Context)
+	DisableTwoFactor(ctx *gin.Context)
+
+	// personal access tokens
+	CreatePersonalAccessToken(ctx *gin.Context)
+	GetPersonalAccessTokens(ctx *gin.Context)
+	RevokePersonalAccessToken(ctx *gin.Context)
+
+	// social login
+	SocialLogin(ctx *gin.Context)
+	SocialLoginCallback(ctx *gin.Context)
+
+	// sessions
+	GetSessions(ctx *gin.Context)
+	RevokeSession(ctx *gin.Context)
+	RevokeOtherSessions(ctx *gin.Context)
+	GetLoginHistory(ctx *gin.Context)
+
+	// follows
+	SetPrivacy(ctx *gin.Context)
+	FollowUser(ctx *gin.Context)
+	UnfollowUser(ctx *gin.Context)
+	GetFollowRequests(ctx *gin.Context)
+	ApproveFollowRequest(ctx *gin.Context)
+	DenyFollowRequest(ctx *gin.Context)
+
+	// blocks and mutes
+	BlockUser(ctx *gin.Context)
+	UnblockUser(ctx *gin.Context)
+	GetBlocks(ctx *gin.Context)
+	MuteUser(ctx *gin.Context)
+	UnmuteUser(ctx *gin.Context)
+	GetMutes(ctx *gin.Context)
 }
 
 type userHandlerImpl struct{
-	svc service.UserService
+	svc          service.UserService
+	accountSvc   service.AccountService
+	twoFactorSvc service.TwoFactorService
+	tokenSvc     service.PersonalAccessTokenService
+	socialSvc    service.SocialLoginService
+	sessionSvc   service.SessionService
+	followSvc    service.FollowService
+	blockSvc     service.BlockService
 }
 
-func NewUserHandler(svc service.UserService) UserHandler{
+func NewUserHandler(svc service.UserService, accountSvc service.AccountService, twoFactorSvc service.TwoFactorService, tokenSvc service.PersonalAccessTokenService, socialSvc service.SocialLoginService, sessionSvc service.SessionService, followSvc service.FollowService, blockSvc service.BlockService) UserHandler{
 	return &userHandlerImpl{
-		svc: svc,
+		svc:          svc,
+		accountSvc:   accountSvc,
+		twoFactorSvc: twoFactorSvc,
+		tokenSvc:     tokenSvc,
+		socialSvc:    socialSvc,
+		sessionSvc:   sessionSvc,
+		followSvc:    followSvc,
+		blockSvc:     blockSvc,
 	}
 }
 
@@ -47,11 +108,20 @@ func (u *userHandlerImpl) UserSignUp(ctx *gin.Context) {
 	}
 
 	user, err := u.svc.SignUp(ctx, userSignUp)
+	if password.IsPolicyViolation(err) {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
 		return
 	}
 
+	// the account exists either way, a failed email can be resent
+	if err := u.accountSvc.SendEmailVerification(ctx, user, mailer.Locale(ctx.GetHeader("Accept-Language"))); err != nil {
+		log.Printf("send verification email to user %d: %v", user.ID, err)
+	}
+
 	ctx.JSON(http.StatusCreated, user)
 }
 
@@ -65,17 +135,72 @@ func (u *userHandlerImpl) UserSignIn(ctx *gin.Context) {
 	validate := validator.New()
 	if err := validate.Struct(userSignIn); err != nil {
 		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: err.Error()})
+		return
 	}
 
 	user, err := u.svc.SignIn(ctx, userSignIn)
+	if errors.Is(err, service.ErrInvalidCredentials) {
+		u.recordFailedLogin(ctx, userSignIn.Email, "invalid credentials")
+		ctx.JSON(http.StatusUnauthorized, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+	lockedErr := &service.AccountLockedError{}
+	if errors.As(err, &lockedErr) {
+		u.recordFailedLogin(ctx, userSignIn.Email, "account locked")
+		ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(lockedErr.RetryAfter.Seconds()))))
+		ctx.JSON(http.StatusTooManyRequests, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	u.startSession(ctx, user, model.LOGIN_METHOD_PASSWORD)
+}
+
+// recordFailedLogin adds to the login history of the account signed in to,
+// the answer to the client does not depend on it.
+func (u *userHandlerImpl) recordFailedLogin(ctx *gin.Context, email string, reason string) {
+	if err := u.sessionSvc.RecordFailedLogin(ctx, email, deviceFrom(ctx), model.LOGIN_METHOD_PASSWORD, reason); err != nil {
+		log.Printf("record failed login: %v", err)
+	}
+}
+
+// startSession answers a successful first login step with an access token,
+// or with a pending token when the user still owes a second factor.
+func (u *userHandlerImpl) startSession(ctx *gin.Context, user model.User, method string) {
+	// the first step alone is not enough, the client continues at
+	// /users/login/mfa
+	if user.TotpEnabledAt != nil {
+		pending, err := u.twoFactorSvc.GeneratePendingToken(ctx, user)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusOK, map[string]any{
+			"mfa_required":      true,
+			"mfa_pending_token": pending,
+		})
+		return
+	}
+
+	u.issueToken(ctx, user, method)
+}
+
+// issueToken starts a session for a fully authenticated user and answers
+// with its access token.
+func (u *userHandlerImpl) issueToken(ctx *gin.Context, user model.User, method string) {
+	session, err := u.sessionSvc.StartSession(ctx, user, deviceFrom(ctx), method, mailer.Locale(ctx.GetHeader("Accept-Language")))
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
 		return
 	}
 
-	token, err := u.svc.GenerateUserAccessToken(ctx, user)
+	token, err := u.svc.GenerateUserAccessToken(ctx, user, session)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+		return
 	}
 
 	ctx.JSON(http.StatusOK, map[string]any{
@@ -83,19 +208,12 @@ func (u *userHandlerImpl) UserSignIn(ctx *gin.Context) {
 	})
 }
 
-// ShowUsersById godoc
-//
-//	@Summary		Show users detail
-//	@Description	will fetch 3rd party server to get users data to get detail user
-//	@Tags			users
-//	@Accept			json
-//	@Produce		json
-//	@Param			id	path		int	true	"User ID"
-//	@Success		200	{object}	model.User
-//	@Failure		400	{object}	pkg.ErrorResponse
-//	@Failure		404	{object}	pkg.ErrorResponse
-//	@Failure		500	{object}	pkg.ErrorResponse
-//	@Router			/users/{id} [get]
+func deviceFrom(ctx *gin.Context) model.Device {
+	return model.Device{UserAgent: ctx.Request.UserAgent(), IP: ctx.ClientIP()}
+}
+
+// GetUsersById answers the whole user to themselves and the public profile
+// to anybody else.
 func (u *userHandlerImpl) GetUsersById(ctx *gin.Context) {
 	// get id user
 	id, err := strconv.Atoi(ctx.Param("id"))
@@ -112,7 +230,11 @@ func (u *userHandlerImpl) GetUsersById(ctx *gin.Context) {
 		ctx.JSON(http.StatusNotFound, pkg.ErrorResponse{Message: "user not found"})
 		return
 	}
-	ctx.JSON(http.StatusOK, user)
+	if middleware.ViewerID(ctx) != user.ID {
+		respondETag(ctx, http.StatusOK, user.UpdatedAt, user.PublicProfile(), nil)
+		return
+	}
+	respondETag(ctx, http.StatusOK, user.UpdatedAt, user, nil)
 }
 
 func (u *userHandlerImpl) EditUser(ctx *gin.Context) {
@@ -148,10 +270,13 @@ func (u *userHandlerImpl) EditUser(ctx *gin.Context) {
 		return
 	}
 
-	userEditReq := model.UserEditReq{}
-	err = ctx.Bind(&userEditReq)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+	updatedAt, ok := checkIfMatch(ctx, cekUser.UpdatedAt)
+	if !ok {
+		return
+	}
+
+	userEditReq := model.UserEditReq{Email: cekUser.Email, Username: cekUser.Username}
+	if !bindUpdate(ctx, &userEditReq) {
 		return
 	}
 
@@ -168,29 +293,33 @@ func (u *userHandlerImpl) EditUser(ctx *gin.Context) {
 				Email: userEditReq.Email,
 			}
 
-	UserResponse, err := u.svc.EditUser(ctx, user)
+	// the email is where password resets go, which makes changing it taking
+	// over the account, so personal access tokens cannot
+	if user.Email != cekUser.Email && !middleware.CheckSession(ctx) {
+		return
+	}
+
+	UserResponse, err := u.svc.EditUser(ctx, user, updatedAt)
+	if errors.Is(err, service.ErrPreconditionFailed) {
+		ctx.JSON(http.StatusPreconditionFailed, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, UserResponse)
+	// the edit stands either way, a failed email can be resent
+	if user.Email != cekUser.Email {
+		if err := u.accountSvc.SendEmailVerification(ctx, user, mailer.Locale(ctx.GetHeader("Accept-Language"))); err != nil {
+			log.Printf("send verification email to user %d: %v", user.ID, err)
+		}
+	}
+
+	respondETag(ctx, http.StatusOK, UserResponse.UpdatedAt, UserResponse, nil)
 }
 
-// DeleteUsersById godoc
-//
-//		@Summary		Delete user by selected id
-//		@Description	will delete user with given id from param
-//		@Tags			users
-//		@Accept			json
-//		@Produce		json
-//	 	@Param 			Authorization header string true "bearer token"
-//		@Param			id	path		int	true	"User ID"
-//		@Success		200	{object}	model.User
-//		@Failure		400	{object}	pkg.ErrorResponse
-//		@Failure		404	{object}	pkg.ErrorResponse
-//		@Failure		500	{object}	pkg.ErrorResponse
-//		@Router			/users/{id} [delete]
+// DeleteUsersById deletes the account of the logged in user.
 func (u *userHandlerImpl) DeleteUsersById(ctx *gin.Context) {
 	// get id user
 	id, err := strconv.Atoi(ctx.Param("id"))
@@ -229,3 +358,108 @@ func (u *userHandlerImpl) DeleteUsersById(ctx *gin.Context) {
 		"message": "Your account has been successfully deleted" ,
 	})
 }
+
+func (u *userHandlerImpl) VerifyEmail(ctx *gin.Context) {
+	req := model.EmailVerifyReq{}
+	if err := ctx.Bind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	err := u.accountSvc.VerifyEmail(ctx, req.Token)
+	if errors.Is(err, service.ErrInvalidToken) {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, map[string]any{
+		"message": "Your email has been verified",
+	})
+}
+
+// ResendEmailVerification answers the same whether or not the address
+// belongs to an account.
+func (u *userHandlerImpl) ResendEmailVerification(ctx *gin.Context) {
+	req := model.EmailReq{}
+	if err := ctx.Bind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	if err := u.accountSvc.ResendEmailVerification(ctx, req.Email, mailer.Locale(ctx.GetHeader("Accept-Language"))); err != nil {
+		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, map[string]any{
+		"message": "If the address belongs to an unverified account, a verification email is on its way",
+	})
+}
+
+// ForgotPassword answers the same whether or not the address belongs to an
+// account.
+func (u *userHandlerImpl) ForgotPassword(ctx *gin.Context) {
+	req := model.EmailReq{}
+	if err := ctx.Bind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	if err := u.accountSvc.ForgotPassword(ctx, req.Email, mailer.Locale(ctx.GetHeader("Accept-Language"))); err != nil {
+		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, map[string]any{
+		"message": "If the address belongs to an account, a password reset email is on its way",
+	})
+}
+
+func (u *userHandlerImpl) ResetPassword(ctx *gin.Context) {
+	req := model.PasswordResetReq{}
+	if err := ctx.Bind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	err := u.accountSvc.ResetPassword(ctx, req.Token, req.Password)
+	if errors.Is(err, service.ErrInvalidToken) || password.IsPolicyViolation(err) {
+		ctx.JSON(http.StatusBadRequest, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, map[string]any{
+		"message": "Your password has been reset",
+	})
+}
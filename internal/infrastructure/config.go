@@ -0,0 +1,106 @@
+package infrastructure
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type NodeConfig struct {
+	Host     string
+	Port     string
+	User     string
+	Password string
+	DBName   string
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+type PostgresConfig struct {
+	Primary  NodeConfig
+	Replicas []NodeConfig
+
+	HealthCheckInterval time.Duration
+}
+
+func DefaultPostgresConfig() PostgresConfig {
+	return PostgresConfig{
+		Primary: NodeConfig{
+			Host:     "127.0.0.1",
+			Port:     "5432",
+			User:     "postgres",
+			Password: "ervany",
+			DBName:   "mygram",
+
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+		},
+		HealthCheckInterval: 5 * time.Second,
+	}
+}
+
+// PostgresConfigFromEnv overrides the defaults with DB_* variables. Replicas
+// are listed in DB_REPLICA_HOSTS as comma separated host:port pairs and
+// share the primary credentials, with their own DB_REPLICA_* pool sizing.
+func PostgresConfigFromEnv() PostgresConfig {
+	cfg := DefaultPostgresConfig()
+
+	cfg.Primary.Host = envString("DB_HOST", cfg.Primary.Host)
+	cfg.Primary.Port = envString("DB_PORT", cfg.Primary.Port)
+	cfg.Primary.User = envString("DB_USER", cfg.Primary.User)
+	cfg.Primary.Password = envString("DB_PASSWORD", cfg.Primary.Password)
+	cfg.Primary.DBName = envString("DB_NAME", cfg.Primary.DBName)
+	cfg.Primary.MaxOpenConns = envInt("DB_MAX_OPEN_CONNS", cfg.Primary.MaxOpenConns)
+	cfg.Primary.MaxIdleConns = envInt("DB_MAX_IDLE_CONNS", cfg.Primary.MaxIdleConns)
+	cfg.Primary.ConnMaxLifetime = envDuration("DB_CONN_MAX_LIFETIME", cfg.Primary.ConnMaxLifetime)
+	cfg.Primary.ConnMaxIdleTime = envDuration("DB_CONN_MAX_IDLE_TIME", cfg.Primary.ConnMaxIdleTime)
+	cfg.HealthCheckInterval = envDuration("DB_HEALTH_CHECK_INTERVAL", cfg.HealthCheckInterval)
+
+	hosts := os.Getenv("DB_REPLICA_HOSTS")
+	if hosts == "" {
+		return cfg
+	}
+	for _, hostPort := range strings.Split(hosts, ",") {
+		node := cfg.Primary
+		host, port, found := strings.Cut(strings.TrimSpace(hostPort), ":")
+		node.Host = host
+		if found {
+			node.Port = port
+		}
+		node.MaxOpenConns = envInt("DB_REPLICA_MAX_OPEN_CONNS", node.MaxOpenConns)
+		node.MaxIdleConns = envInt("DB_REPLICA_MAX_IDLE_CONNS", node.MaxIdleConns)
+		node.ConnMaxLifetime = envDuration("DB_REPLICA_CONN_MAX_LIFETIME", node.ConnMaxLifetime)
+		node.ConnMaxIdleTime = envDuration("DB_REPLICA_CONN_MAX_IDLE_TIME", node.ConnMaxIdleTime)
+		cfg.Replicas = append(cfg.Replicas, node)
+	}
+	return cfg
+}
+
+func envString(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func envInt(key string, fallback int) int {
+	v, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func envDuration(key string, fallback time.Duration) time.Duration {
+	v, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return v
+}
@@ -1,43 +1,255 @@
 package infrastructure
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
 )
 
-type GormPostgres interface{
+type GormPostgres interface {
+	// GetConnection returns the primary without any routing, for setup
+	// code such as instrumentation and migrations.
 	GetConnection() *gorm.DB
+	// GetReadConnection returns the transaction running in ctx, otherwise a
+	// healthy replica, or the primary when the request already wrote or no
+	// replica is available. Reads failing on the connection of a replica are
+	// rerun on the primary.
+	GetReadConnection(ctx context.Context) *gorm.DB
+	// GetWriteConnection returns the transaction running in ctx or the
+	// primary, and pins the rest of the request session to the primary.
+	GetWriteConnection(ctx context.Context) *gorm.DB
+	// Nodes lists every connection, primary first.
+	Nodes() []Node
+	Close() error
 }
 
-type gormPostgresImpl struct{
-	master	*gorm.DB
+type Node struct {
+	Name string
+	DB   *gorm.DB
 }
 
-func NewGormPostgres() GormPostgres{
-	return &gormPostgresImpl{
-		master: connect(),
+type replica struct {
+	name    string
+	db      *gorm.DB
+	healthy atomic.Bool
+}
+
+type gormPostgresImpl struct {
+	master   *gorm.DB
+	replicas []*replica
+	next     atomic.Uint64
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func NewGormPostgres(cfg PostgresConfig) GormPostgres {
+	// replicas may be down at boot, the health check brings them in later
+	replicas := []*gorm.DB{}
+	for _, node := range cfg.Replicas {
+		replicas = append(replicas, connect(node, true))
+	}
+
+	g := newGormPostgres(connect(cfg.Primary, false), replicas)
+	if len(g.replicas) > 0 {
+		go g.watchReplicas(cfg.HealthCheckInterval)
 	}
+	return g
 }
 
-func connect() *gorm.DB{
-	host := "127.0.0.1"
-	port := "5432"
-	user := "postgres"
-	password := "ervany"
-	dbname := "mygram"
+// NewGormPostgresFromDB wraps already opened connections, a primary and its
+// replicas. Replicas are only checked once, there is no periodic health
+// check.
+func NewGormPostgresFromDB(primary *gorm.DB, replicas ...*gorm.DB) GormPostgres {
+	return newGormPostgres(primary, replicas)
+}
 
-	connectionString := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", host, port, user, password, dbname)
-	db, err := gorm.Open(postgres.Open(connectionString), &gorm.Config{})
+func newGormPostgres(primary *gorm.DB, replicas []*gorm.DB) *gormPostgresImpl {
+	g := &gormPostgresImpl{
+		master: primary,
+		stop:   make(chan struct{}),
+	}
+	for i, db := range replicas {
+		r := &replica{
+			name: fmt.Sprintf("replica-%d", i),
+			db:   db,
+		}
+		if err := g.registerFailover(r); err != nil {
+			panic(err)
+		}
+		g.replicas = append(g.replicas, r)
+	}
+	g.checkReplicas()
+	return g
+}
+
+func connect(cfg NodeConfig, lazy bool) *gorm.DB {
+	connectionString := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName)
+	db, err := gorm.Open(postgres.Open(connectionString), &gorm.Config{
+		DisableAutomaticPing: lazy,
+	})
 	if err != nil {
 		panic(err)
 	}
 
+	sqlDB, err := db.DB()
+	if err != nil {
+		panic(err)
+	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
 	return db
 }
 
-func (g *gormPostgresImpl) GetConnection() *gorm.DB{
+func (g *gormPostgresImpl) GetConnection() *gorm.DB {
+	return g.master
+}
+
+func (g *gormPostgresImpl) GetReadConnection(ctx context.Context) *gorm.DB {
+	if tx := txFrom(ctx); tx != nil {
+		return tx
+	}
+	if s := sessionFrom(ctx); s != nil && s.wrote.Load() {
+		return g.master
+	}
+
+	n := uint64(len(g.replicas))
+	start := g.next.Add(1)
+	for i := uint64(0); i < n; i++ {
+		r := g.replicas[(start+i)%n]
+		if r.healthy.Load() {
+			return r.db
+		}
+	}
+	return g.master
+}
+
+func (g *gormPostgresImpl) GetWriteConnection(ctx context.Context) *gorm.DB {
+	if s := sessionFrom(ctx); s != nil {
+		s.wrote.Store(true)
+	}
+	if tx := txFrom(ctx); tx != nil {
+		return tx
+	}
 	return g.master
 }
 
+func (g *gormPostgresImpl) Nodes() []Node {
+	nodes := []Node{{Name: "primary", DB: g.master}}
+	for _, r := range g.replicas {
+		nodes = append(nodes, Node{Name: r.name, DB: r.db})
+	}
+	return nodes
+}
+
+func (g *gormPostgresImpl) Close() error {
+	g.stopOnce.Do(func() { close(g.stop) })
+
+	for _, node := range g.Nodes() {
+		sqlDB, err := node.DB.DB()
+		if err != nil {
+			return err
+		}
+		if err := sqlDB.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// registerFailover reruns on the primary the statements that fail on the
+// connection of the replica, and takes the replica out of rotation at once
+// instead of waiting for the next health check. Callers only see the
+// latency.
+func (g *gormPostgresImpl) registerFailover(r *replica) error {
+	cb := r.db.Callback()
+	if err := cb.Query().After("gorm:query").Before("gorm:preload").Register("infrastructure:failover", g.failover(r, callbacks.Query)); err != nil {
+		return err
+	}
+	if err := cb.Row().After("gorm:row").Register("infrastructure:failover", g.failover(r, callbacks.RowQuery)); err != nil {
+		return err
+	}
+	return cb.Raw().After("gorm:raw").Register("infrastructure:failover", g.failover(r, callbacks.RawExec))
+}
+
+func (g *gormPostgresImpl) failover(r *replica, rerun func(*gorm.DB)) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		err := db.Error
+		// Row defers the error of the query to Scan
+		if row, ok := db.Statement.Dest.(*sql.Row); ok && err == nil {
+			err = row.Err()
+		}
+		if !isConnectionError(err) {
+			return
+		}
+		if r.healthy.Swap(false) {
+			log.Printf("%s marked unhealthy: %v", r.name, err)
+		}
+
+		// RowQuery forgets it was asked for Rows once it ran
+		if _, ok := db.Statement.Dest.(*sql.Rows); ok {
+			db.Statement.Settings.Store("rows", true)
+		}
+		db.Error = nil
+		db.Statement.ConnPool = g.master.ConnPool
+		rerun(db)
+	}
+}
+
+func isConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var netErr net.Error
+	var connectErr *pgconn.ConnectError
+	return errors.Is(err, driver.ErrBadConn) || errors.As(err, &netErr) || errors.As(err, &connectErr)
+}
+
+func (g *gormPostgresImpl) watchReplicas(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-g.stop:
+			return
+		case <-ticker.C:
+			g.checkReplicas()
+		}
+	}
+}
+
+func (g *gormPostgresImpl) checkReplicas() {
+	for _, r := range g.replicas {
+		healthy := ping(r.db) == nil
+		if healthy != r.healthy.Swap(healthy) {
+			log.Printf("%s healthy: %v", r.name, healthy)
+		}
+	}
+}
+
+func ping(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return sqlDB.PingContext(ctx)
+}
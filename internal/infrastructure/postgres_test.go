@@ -0,0 +1,149 @@
+package infrastructure_test
+
+import (
+	"context"
+	"mygram/internal/infrastructure"
+	"mygram/internal/model"
+	"mygram/internal/repository"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type cluster struct {
+	db      infrastructure.GormPostgres
+	primary sqlmock.Sqlmock
+	replica sqlmock.Sqlmock
+}
+
+func newCluster(t *testing.T) cluster {
+	t.Helper()
+
+	primary, primaryMock := newMockGorm(t)
+	replica, replicaMock := newMockGorm(t)
+	return cluster{
+		db:      infrastructure.NewGormPostgresFromDB(primary, replica),
+		primary: primaryMock,
+		replica: replicaMock,
+	}
+}
+
+func (c cluster) expectationsWereMet(t *testing.T) {
+	t.Helper()
+
+	if err := c.primary.ExpectationsWereMet(); err != nil {
+		t.Errorf("primary: %v", err)
+	}
+	if err := c.replica.ExpectationsWereMet(); err != nil {
+		t.Errorf("replica: %v", err)
+	}
+}
+
+var connectionReset = &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+
+func photoRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "title", "user_id"}).AddRow(1, "sunset", 1)
+}
+
+func TestReadsGoToTheReplicaAndWritesToThePrimary(t *testing.T) {
+	c := newCluster(t)
+	photoRepo := repository.NewPhotoQuery(c.db)
+	ctx := context.Background()
+
+	c.replica.ExpectQuery(`SELECT .* FROM "photos"`).WillReturnRows(photoRows())
+	c.primary.ExpectBegin()
+	c.primary.ExpectQuery(`INSERT INTO "photos"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	c.primary.ExpectCommit()
+	// without a session the write does not pin later reads
+	c.replica.ExpectQuery(`SELECT .* FROM "photos"`).WillReturnRows(photoRows())
+
+	if _, err := photoRepo.GetPhotoById(ctx, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := photoRepo.CreatePhoto(ctx, model.Photo{Title: "dusk", PhotoUrl: "url", UserId: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := photoRepo.GetPhotoById(ctx, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	c.expectationsWereMet(t)
+}
+
+func TestSessionReadsItsWritesFromThePrimary(t *testing.T) {
+	c := newCluster(t)
+	photoRepo := repository.NewPhotoQuery(c.db)
+	ctx := infrastructure.WithSession(context.Background())
+
+	c.replica.ExpectQuery(`SELECT .* FROM "photos"`).WillReturnRows(photoRows())
+	c.primary.ExpectBegin()
+	c.primary.ExpectQuery(`INSERT INTO "photos"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	c.primary.ExpectCommit()
+	c.primary.ExpectQuery(`SELECT .* FROM "photos"`).WillReturnRows(photoRows())
+
+	if _, err := photoRepo.GetPhotoById(ctx, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := photoRepo.CreatePhoto(ctx, model.Photo{Title: "dusk", PhotoUrl: "url", UserId: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := photoRepo.GetPhotoById(ctx, 2, 1); err != nil {
+		t.Fatal(err)
+	}
+	c.expectationsWereMet(t)
+}
+
+func TestFailedReadIsRerunOnThePrimary(t *testing.T) {
+	c := newCluster(t)
+	photoRepo := repository.NewPhotoQuery(c.db)
+	ctx := context.Background()
+
+	c.replica.ExpectQuery(`SELECT .* FROM "photos"`).WillReturnError(connectionReset)
+	c.primary.ExpectQuery(`SELECT .* FROM "photos"`).WillReturnRows(photoRows())
+	// the replica is out of rotation until the health check brings it back
+	c.primary.ExpectQuery(`SELECT .* FROM "photos"`).WillReturnRows(photoRows())
+
+	for i := 0; i < 2; i++ {
+		photo, err := photoRepo.GetPhotoById(ctx, 1, 1)
+		if err != nil {
+			t.Fatalf("read %d: %v", i+1, err)
+		}
+		if photo.Title != "sunset" {
+			t.Fatalf("read %d: expected the photo from the primary, got %+v", i+1, photo)
+		}
+	}
+	c.expectationsWereMet(t)
+}
+
+func TestFailedRowsAreRerunOnThePrimary(t *testing.T) {
+	tests := map[string]func(ctx context.Context, db infrastructure.GormPostgres) (int, error){
+		"rows": func(ctx context.Context, db infrastructure.GormPostgres) (int, error) {
+			n := 0
+			err := db.GetReadConnection(ctx).WithContext(ctx).Raw("SELECT count(*) FROM photos").Scan(&n).Error
+			return n, err
+		},
+		"row": func(ctx context.Context, db infrastructure.GormPostgres) (int, error) {
+			n := 0
+			err := db.GetReadConnection(ctx).WithContext(ctx).Raw("SELECT count(*) FROM photos").Row().Scan(&n)
+			return n, err
+		},
+	}
+	for name, read := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := newCluster(t)
+
+			c.replica.ExpectQuery(`SELECT count`).WillReturnError(connectionReset)
+			c.primary.ExpectQuery(`SELECT count`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+			n, err := read(context.Background(), c.db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != 3 {
+				t.Fatalf("expected the count from the primary, got %d", n)
+			}
+			c.expectationsWereMet(t)
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package infrastructure
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+type sessionKey struct{}
+
+// session tracks whether the current request has written to the primary,
+// after which its reads must stay on the primary to see their own writes.
+type session struct {
+	wrote atomic.Bool
+}
+
+// WithSession attaches a fresh read-your-writes session to ctx. Contexts
+// without one always read from a replica when possible.
+func WithSession(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sessionKey{}, &session{})
+}
+
+func sessionFrom(ctx context.Context) *session {
+	s, _ := ctx.Value(sessionKey{}).(*session)
+	return s
+}
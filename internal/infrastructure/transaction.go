@@ -0,0 +1,83 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultMaxAttempts = 3
+	retryBackoff       = 20 * time.Millisecond
+)
+
+type txKey struct{}
+
+// UnitOfWork runs a closure inside one database transaction. Repositories
+// pick the transaction up from the context handed to the closure through
+// GetReadConnection and GetWriteConnection, so any number of them can take
+// part without knowing about it.
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type unitOfWorkImpl struct {
+	db          GormPostgres
+	maxAttempts int
+	opts        *sql.TxOptions
+}
+
+func NewUnitOfWork(db GormPostgres) UnitOfWork {
+	return &unitOfWorkImpl{
+		db:          db,
+		maxAttempts: defaultMaxAttempts,
+		opts:        &sql.TxOptions{Isolation: sql.LevelRepeatableRead},
+	}
+}
+
+// Do commits when fn returns nil and rolls back otherwise. A transaction
+// aborted by a serialization failure or deadlock is retried from scratch, so
+// fn must not have side effects outside the database. Nested calls join the
+// outer transaction.
+func (u *unitOfWorkImpl) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if txFrom(ctx) != nil {
+		return fn(ctx)
+	}
+
+	var err error
+	for attempt := 1; attempt <= u.maxAttempts; attempt++ {
+		err = u.db.GetWriteConnection(ctx).
+			WithContext(ctx).
+			Transaction(func(tx *gorm.DB) error {
+				return fn(context.WithValue(ctx, txKey{}, tx))
+			}, u.opts)
+		if !isRetryable(err) || attempt == u.maxAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * retryBackoff):
+		}
+	}
+	return err
+}
+
+func txFrom(ctx context.Context) *gorm.DB {
+	tx, _ := ctx.Value(txKey{}).(*gorm.DB)
+	return tx
+}
+
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	// serialization_failure, deadlock_detected
+	return pgErr.Code == "40001" || pgErr.Code == "40P01"
+}
@@ -0,0 +1,172 @@
+package infrastructure_test
+
+import (
+	"context"
+	"errors"
+	"mygram/internal/infrastructure"
+	"mygram/internal/model"
+	"mygram/internal/repository"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func newMockDB(t *testing.T) (infrastructure.GormPostgres, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock := newMockGorm(t)
+	return infrastructure.NewGormPostgresFromDB(db), mock
+}
+
+func newMockGorm(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, mock
+}
+
+func TestUnitOfWorkRollsBackWhenLaterStepFails(t *testing.T) {
+	db, mock := newMockDB(t)
+	uow := infrastructure.NewUnitOfWork(db)
+	photoRepo := repository.NewPhotoQuery(db)
+	userRepo := repository.NewUserQuery(db)
+
+	deleteErr := errors.New("delete failed")
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "photos"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec(`UPDATE "users" SET "deleted_at"`).
+		WillReturnError(deleteErr)
+	mock.ExpectRollback()
+
+	err := uow.Do(context.Background(), func(ctx context.Context) error {
+		if _, err := photoRepo.CreatePhoto(ctx, model.Photo{Title: "title", PhotoUrl: "url", UserId: 1}); err != nil {
+			return err
+		}
+		return userRepo.DeleteUsersByID(ctx, 1)
+	})
+	if !errors.Is(err, deleteErr) {
+		t.Fatalf("expected %v, got %v", deleteErr, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnitOfWorkCommits(t *testing.T) {
+	db, mock := newMockDB(t)
+	uow := infrastructure.NewUnitOfWork(db)
+	photoRepo := repository.NewPhotoQuery(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "photos"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	err := uow.Do(context.Background(), func(ctx context.Context) error {
+		_, err := photoRepo.CreatePhoto(ctx, model.Photo{Title: "title", PhotoUrl: "url", UserId: 1})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnitOfWorkRetriesSerializationFailure(t *testing.T) {
+	db, mock := newMockDB(t)
+	uow := infrastructure.NewUnitOfWork(db)
+	photoRepo := repository.NewPhotoQuery(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "photos"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit().WillReturnError(&pgconn.PgError{Code: "40001"})
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "photos"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectCommit()
+
+	attempts := 0
+	err := uow.Do(context.Background(), func(ctx context.Context) error {
+		attempts++
+		_, err := photoRepo.CreatePhoto(ctx, model.Photo{Title: "title", PhotoUrl: "url", UserId: 1})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 {
+		t.Fatalf("expected 2 attempts, got %d", attempts)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnitOfWorkNestedCallsJoinOuterTransaction(t *testing.T) {
+	db, mock := newMockDB(t)
+	uow := infrastructure.NewUnitOfWork(db)
+	photoRepo := repository.NewPhotoQuery(db)
+
+	innerErr := errors.New("inner failed")
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "photos"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectRollback()
+
+	err := uow.Do(context.Background(), func(ctx context.Context) error {
+		if _, err := photoRepo.CreatePhoto(ctx, model.Photo{Title: "title", PhotoUrl: "url", UserId: 1}); err != nil {
+			return err
+		}
+		return uow.Do(ctx, func(ctx context.Context) error {
+			return innerErr
+		})
+	})
+	if !errors.Is(err, innerErr) {
+		t.Fatalf("expected %v, got %v", innerErr, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnitOfWorkGivesUpAfterMaxAttempts(t *testing.T) {
+	db, mock := newMockDB(t)
+	uow := infrastructure.NewUnitOfWork(db)
+
+	for i := 0; i < 3; i++ {
+		mock.ExpectBegin()
+		mock.ExpectCommit().WillReturnError(&pgconn.PgError{Code: "40P01"})
+	}
+
+	attempts := 0
+	err := uow.Do(context.Background(), func(ctx context.Context) error {
+		attempts++
+		return nil
+	})
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "40P01" {
+		t.Fatalf("expected the deadlock, got %v", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", attempts)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
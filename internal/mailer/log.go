@@ -0,0 +1,21 @@
+package mailer
+
+import (
+	"context"
+	"log"
+)
+
+type logMailer struct {
+	logger *log.Logger
+}
+
+// NewLogMailer prints the text part of every message instead of sending it,
+// for local development.
+func NewLogMailer(logger *log.Logger) Mailer {
+	return &logMailer{logger: logger}
+}
+
+func (l *logMailer) Send(ctx context.Context, msg Message) error {
+	l.logger.Printf("mail to %s: %s\n%s", msg.To, msg.Subject, msg.Text)
+	return nil
+}
@@ -0,0 +1,61 @@
+// Package mailer sends transactional email. Mailer is implemented by an SMTP
+// client, a logger for development and an in-memory outbox for tests;
+// messages are rendered from the embedded, localized templates.
+package mailer
+
+import (
+	"context"
+	"log"
+	"os"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+const (
+	DriverSMTP = "smtp"
+	DriverLog  = "log"
+)
+
+type Config struct {
+	Driver string
+	From   string
+	SMTP   SMTPConfig
+}
+
+// ConfigFromEnv reads MAIL_DRIVER (smtp or log, defaults to log), MAIL_FROM
+// and the SMTP_* variables.
+func ConfigFromEnv() Config {
+	return Config{
+		Driver: envString("MAIL_DRIVER", DriverLog),
+		From:   envString("MAIL_FROM", "MyGram <no-reply@mygram.local>"),
+		SMTP: SMTPConfig{
+			Host:     envString("SMTP_HOST", "127.0.0.1"),
+			Port:     envString("SMTP_PORT", "587"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+		},
+	}
+}
+
+func New(cfg Config) Mailer {
+	if cfg.Driver == DriverSMTP {
+		return NewSMTPMailer(cfg.SMTP, cfg.From)
+	}
+	return NewLogMailer(log.Default())
+}
+
+func envString(key, fallback string) string {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,44 @@
+package mailer
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// Outbox keeps sent messages in memory for tests.
+type Outbox struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewOutbox() *Outbox {
+	return &Outbox{}
+}
+
+func (o *Outbox) Send(ctx context.Context, msg Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.messages = append(o.messages, msg)
+	return nil
+}
+
+// Messages returns what was sent to the address, oldest first.
+func (o *Outbox) Messages(to string) []Message {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return slices.DeleteFunc(slices.Clone(o.messages), func(m Message) bool {
+		return m.To != to
+	})
+}
+
+// Last returns the newest message sent to the address.
+func (o *Outbox) Last(to string) (Message, bool) {
+	messages := o.Messages(to)
+	if len(messages) == 0 {
+		return Message{}, false
+	}
+	return messages[len(messages)-1], true
+}
@@ -0,0 +1,81 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Templates shipped with the binary, one directory per locale holding
+// <name>.txt and <name>.html. The text template also defines "subject".
+//
+//go:embed templates
+var templateFS embed.FS
+
+const (
+	TemplateVerifyEmail   = "verify_email"
+	TemplatePasswordReset = "password_reset"
+	TemplateNewLogin      = "new_login"
+)
+
+const DefaultLocale = "en"
+
+var locales = []string{"en", "id"}
+
+// Locale picks the first supported language in an Accept-Language header,
+// falling back to DefaultLocale.
+func Locale(acceptLanguage string) string {
+	for _, tag := range strings.Split(acceptLanguage, ",") {
+		tag, _, _ = strings.Cut(tag, ";")
+		tag, _, _ = strings.Cut(strings.TrimSpace(tag), "-")
+		tag = strings.ToLower(tag)
+		for _, l := range locales {
+			if tag == l {
+				return l
+			}
+		}
+	}
+	return DefaultLocale
+}
+
+// Render builds the subject and both bodies of the named template in the
+// locale, or in DefaultLocale when the locale has no translation.
+func Render(name, locale string, data any) (Message, error) {
+	if _, err := templateFS.Open(fmt.Sprintf("templates/%s/%s.txt", locale, name)); err != nil {
+		locale = DefaultLocale
+	}
+	base := fmt.Sprintf("templates/%s/%s", locale, name)
+
+	text, err := texttemplate.ParseFS(templateFS, base+".txt")
+	if err != nil {
+		return Message{}, err
+	}
+	html, err := htmltemplate.ParseFS(templateFS, base+".html")
+	if err != nil {
+		return Message{}, err
+	}
+
+	msg := Message{}
+	buf := &bytes.Buffer{}
+	if err := text.ExecuteTemplate(buf, "subject", data); err != nil {
+		return Message{}, err
+	}
+	msg.Subject = strings.TrimSpace(buf.String())
+
+	buf.Reset()
+	if err := text.Execute(buf, data); err != nil {
+		return Message{}, err
+	}
+	msg.Text = strings.TrimSpace(buf.String()) + "\n"
+
+	buf.Reset()
+	if err := html.Execute(buf, data); err != nil {
+		return Message{}, err
+	}
+	msg.HTML = buf.String()
+
+	return msg, nil
+}
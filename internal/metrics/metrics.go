@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "mygram"
+
+// Metrics holds every collector exposed by Handler. It is created once in
+// main and injected into the middleware, gorm plugin and services so tests can
+// build their own instance on a fresh registry and assert on the counters.
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	HTTPRequests *prometheus.CounterVec
+	HTTPDuration *prometheus.HistogramVec
+
+	DBQueryDuration *prometheus.HistogramVec
+
+	SignUps         prometheus.Counter
+	Logins          *prometheus.CounterVec
+	PhotosCreated   prometheus.Counter
+	CommentsCreated prometheus.Counter
+}
+
+func New(reg *prometheus.Registry) *Metrics {
+	m := &Metrics{
+		Registry: reg,
+		HTTPRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Total HTTP requests by method, route template and status.",
+		}, []string{"method", "route", "status"}),
+		HTTPDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request latency by method, route template and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		DBQueryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "db",
+			Name:      "query_duration_seconds",
+			Help:      "GORM statement latency by operation and table.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"operation", "table"}),
+		SignUps: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "user_signups_total",
+			Help:      "Total successful user registrations.",
+		}),
+		Logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "user_logins_total",
+			Help:      "Total login attempts by result.",
+		}, []string{"result"}),
+		PhotosCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "photos_created_total",
+			Help:      "Total photos created.",
+		}),
+		CommentsCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "comments_created_total",
+			Help:      "Total comments created.",
+		}),
+	}
+
+	reg.MustRegister(
+		m.HTTPRequests,
+		m.HTTPDuration,
+		m.DBQueryDuration,
+		m.SignUps,
+		m.Logins,
+		m.PhotosCreated,
+		m.CommentsCreated,
+	)
+	return m
+}
+
+// NewDefault registers the collectors on a new registry together with the
+// standard go runtime and process collectors.
+func NewDefault() *Metrics {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return New(reg)
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{})
+}
+
+// NewNop returns metrics bound to a throwaway registry, handy when a caller
+// does not care about instrumentation.
+func NewNop() *Metrics {
+	return New(prometheus.NewRegistry())
+}
+
+const (
+	LoginSuccess = "success"
+	LoginFailure = "failure"
+)
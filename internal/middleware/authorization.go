@@ -1,11 +1,15 @@
 package middleware
 
 import (
+	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"mygram/internal/model"
 	"mygram/pkg"
 	"mygram/pkg/helper"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -17,6 +21,12 @@ const (
 
 	CLAIM_USER_ID  = "claim_user_id"
 	CLAIM_USERNAME = "claim_username"
+	// CLAIM_JTI is only set for login sessions.
+	CLAIM_JTI = "claim_jti"
+	// CLAIM_SCOPES is only set for personal access tokens and guests.
+	CLAIM_SCOPES = "claim_scopes"
+	// CLAIM_GUEST is set for the public token, which has no user.
+	CLAIM_GUEST = "claim_guest"
 )
 
 func CheckAuthBasic(ctx *gin.Context) {
@@ -61,7 +71,57 @@ func CheckAuthBasic(ctx *gin.Context) {
 	ctx.Next()
 }
 
-func CheckAuthBearer(ctx *gin.Context) {
+type TokenAuthenticator interface {
+	AuthenticateToken(ctx context.Context, token string) (model.PersonalAccessToken, error)
+}
+
+type SessionAuthenticator interface {
+	AuthenticateSession(ctx context.Context, jti string) (model.Session, error)
+}
+
+// Authenticator checks bearer tokens, either a JWT from login, which must
+// belong to a session that is still signed in, or a personal access token.
+type Authenticator struct {
+	tokens   TokenAuthenticator
+	sessions SessionAuthenticator
+}
+
+func NewAuthenticator(tokens TokenAuthenticator, sessions SessionAuthenticator) *Authenticator {
+	return &Authenticator{tokens: tokens, sessions: sessions}
+}
+
+// guestAccess is what the public token may do on a route.
+type guestAccess int
+
+const (
+	noGuests guestAccess = iota
+	// guestReads lets guests send GET and HEAD requests.
+	guestReads
+	// guestQueries lets guests send any method, to routes that read with
+	// POST and keep guests from writing themselves.
+	guestQueries
+)
+
+// CheckAuthBearer lets users in, with a login session or a personal access
+// token.
+func (a *Authenticator) CheckAuthBearer(ctx *gin.Context) {
+	a.checkAuthBearer(ctx, noGuests)
+}
+
+// CheckAuthBearerOrGuest also lets in the public token from GET /public, for
+// reading only.
+func (a *Authenticator) CheckAuthBearerOrGuest(ctx *gin.Context) {
+	a.checkAuthBearer(ctx, guestReads)
+}
+
+// CheckAuthBearerOrGuestQuery also lets in the public token whatever the
+// method, for /graphql where queries are POSTed. The handler refuses what
+// CLAIM_SCOPES does not grant guests.
+func (a *Authenticator) CheckAuthBearerOrGuestQuery(ctx *gin.Context) {
+	a.checkAuthBearer(ctx, guestQueries)
+}
+
+func (a *Authenticator) checkAuthBearer(ctx *gin.Context, guests guestAccess) {
 	auth := ctx.GetHeader("Authorization")
 
 	authArr := strings.Split(auth, " ")
@@ -81,15 +141,149 @@ func CheckAuthBearer(ctx *gin.Context) {
 	}
 
 	token := authArr[1]
-	claims, err := helper.ValidateToken(token)
+	if strings.HasPrefix(token, model.PERSONAL_ACCESS_TOKEN_PREFIX) {
+		a.checkPersonalAccessToken(ctx, token)
+		return
+	}
+
+	// pending logins are let through ValidateToken only to explain the 401
+	subjects := []string{model.SUBJECT_ACCESS_TOKEN, model.SUBJECT_MFA_PENDING}
+	if guests != noGuests {
+		subjects = append(subjects, model.SUBJECT_PUBLIC_TOKEN)
+	}
+	claims, err := helper.ValidateToken(token, subjects...)
 	if err != nil {
+		reason := "failed to decode"
+		if errors.Is(err, helper.ErrTokenSubject) || errors.Is(err, helper.ErrTokenAudience) || errors.Is(err, helper.ErrTokenIssuer) {
+			reason = err.Error()
+		}
 		ctx.AbortWithStatusJSON(http.StatusUnauthorized, pkg.ErrorResponse{
 			Message: "unauthorized",
-			Errors:  []string{"invalid token", "failed to decode"},
+			Errors:  []string{"invalid token", reason},
+		})
+		return
+	}
+	// a pending login only gets to send its second factor
+	if claims["sub"] == model.SUBJECT_MFA_PENDING {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, pkg.ErrorResponse{
+			Message: "unauthorized",
+			Errors:  []string{"two-factor authentication pending"},
+		})
+		return
+	}
+	if claims["sub"] == model.SUBJECT_PUBLIC_TOKEN {
+		checkGuest(ctx, guests)
+		return
+	}
+
+	jti, _ := claims["jti"].(string)
+	userId, _ := claims["user_id"].(float64)
+	session, err := a.sessions.AuthenticateSession(ctx, jti)
+	if err == nil && float64(session.UserId) != userId {
+		err = errors.New("session belongs to another user")
+	}
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, pkg.ErrorResponse{
+			Message: "unauthorized",
+			Errors:  []string{"invalid token", err.Error()},
 		})
 		return
 	}
 	ctx.Set(CLAIM_USER_ID, claims["user_id"])
 	ctx.Set(CLAIM_USERNAME, claims["username"])
+	ctx.Set(CLAIM_JTI, jti)
 	ctx.Next()
-}
\ No newline at end of file
+}
+
+// checkGuest lets the public token read, guests have no account to write
+// with.
+func checkGuest(ctx *gin.Context, guests guestAccess) {
+	if guests == guestReads && ctx.Request.Method != http.MethodGet && ctx.Request.Method != http.MethodHead {
+		ctx.AbortWithStatusJSON(http.StatusForbidden, pkg.ErrorResponse{
+			Message: "insufficient scope",
+			Errors:  []string{"public tokens can only read, sign in to make changes"},
+		})
+		return
+	}
+	ctx.Set(CLAIM_GUEST, true)
+	ctx.Set(CLAIM_SCOPES, model.GuestScopes)
+	ctx.Next()
+}
+
+// IsGuest tells whether the request was made with the public token.
+func IsGuest(ctx *gin.Context) bool {
+	return ctx.GetBool(CLAIM_GUEST)
+}
+
+// ViewerID is the user a request reads on behalf of, 0 for guests.
+func ViewerID(ctx *gin.Context) uint64 {
+	userId, _ := ctx.Get(CLAIM_USER_ID)
+	id, _ := userId.(float64)
+	return uint64(id)
+}
+
+func (a *Authenticator) checkPersonalAccessToken(ctx *gin.Context, token string) {
+	pat, err := a.tokens.AuthenticateToken(ctx, token)
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, pkg.ErrorResponse{
+			Message: "unauthorized",
+			Errors:  []string{"invalid token", err.Error()},
+		})
+		return
+	}
+	// same type as the JWT claim, handlers assert float64
+	ctx.Set(CLAIM_USER_ID, float64(pat.UserId))
+	ctx.Set(CLAIM_SCOPES, pat.Scopes)
+	ctx.Next()
+}
+
+// RequireScope declares the scope a route needs. Personal access tokens
+// must have been granted it, login sessions have every scope.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !CheckScope(ctx, scope) {
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// CheckScope is RequireScope for handlers whose scope depends on the
+// request. It answers 403 itself and reports whether to go on.
+func CheckScope(ctx *gin.Context, scope string) bool {
+	scopes, ok := ctx.Get(CLAIM_SCOPES)
+	if !ok {
+		return true
+	}
+	if granted, _ := scopes.([]string); !slices.Contains(granted, scope) {
+		ctx.AbortWithStatusJSON(http.StatusForbidden, pkg.ErrorResponse{
+			Message: "insufficient scope",
+			Errors:  []string{"token requires scope " + scope},
+		})
+		return false
+	}
+	return true
+}
+
+// RequireSession keeps a route to login sessions, for account management
+// that personal access tokens must not reach whatever their scopes.
+func RequireSession(ctx *gin.Context) {
+	if !CheckSession(ctx) {
+		return
+	}
+	ctx.Next()
+}
+
+// CheckSession is RequireSession for handlers that only need a login
+// session for some requests. It answers 403 itself and reports whether to
+// go on.
+func CheckSession(ctx *gin.Context) bool {
+	if _, ok := ctx.Get(CLAIM_SCOPES); ok {
+		ctx.AbortWithStatusJSON(http.StatusForbidden, pkg.ErrorResponse{
+			Message: "insufficient scope",
+			Errors:  []string{"route requires a login session"},
+		})
+		return false
+	}
+	return true
+}
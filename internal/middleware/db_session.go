@@ -0,0 +1,14 @@
+package middleware
+
+import (
+	"mygram/internal/infrastructure"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DBSession gives every request its own read-your-writes session, so reads
+// that follow a write in the same request are served by the primary.
+func DBSession(ctx *gin.Context) {
+	ctx.Request = ctx.Request.WithContext(infrastructure.WithSession(ctx.Request.Context()))
+	ctx.Next()
+}
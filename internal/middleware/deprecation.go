@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	HEADER_DEPRECATION = "Deprecation"
+	HEADER_SUNSET      = "Sunset"
+	HEADER_LINK        = "Link"
+)
+
+// Deprecation says since when routes are deprecated and, once decided, when
+// they stop working.
+type Deprecation struct {
+	Since time.Time
+	// Sunset is left zero until a date is announced.
+	Sunset time.Time
+	// Successor is the prefix the routes moved under, linked as the
+	// successor-version of the path of each request.
+	Successor string
+	// Replacement is the path of the route that replaces a single deprecated
+	// one, linked as is in place of Successor.
+	Replacement string
+}
+
+// Deprecated adds the Deprecation (RFC 9745) and Sunset (RFC 8594) headers
+// to every response of the routes it is used on.
+func Deprecated(d Deprecation) gin.HandlerFunc {
+	deprecation := fmt.Sprintf("@%d", d.Since.Unix())
+	sunset := ""
+	if !d.Sunset.IsZero() {
+		sunset = d.Sunset.UTC().Format(http.TimeFormat)
+	}
+
+	return func(ctx *gin.Context) {
+		ctx.Header(HEADER_DEPRECATION, deprecation)
+		if sunset != "" {
+			ctx.Header(HEADER_SUNSET, sunset)
+		}
+		if d.Replacement != "" {
+			ctx.Header(HEADER_LINK, fmt.Sprintf(`<%s>; rel="successor-version"`, d.Replacement))
+		} else if d.Successor != "" {
+			ctx.Header(HEADER_LINK, fmt.Sprintf(`<%s>; rel="successor-version"`, d.Successor+ctx.Request.URL.Path))
+		}
+		ctx.Next()
+	}
+}
+
+// DeprecatedRoutes marks single routes deprecated. routes are keyed by method
+// and path pattern as mounted under prefix, such as "GET /photos/:id", so
+// the same marks hold for a version and its unversioned aliases. Their
+// headers replace those of a deprecation of the whole group.
+func DeprecatedRoutes(prefix string, routes map[string]Deprecation) gin.HandlerFunc {
+	handlers := make(map[string]gin.HandlerFunc, len(routes))
+	for route, d := range routes {
+		handlers[route] = Deprecated(d)
+	}
+
+	return func(ctx *gin.Context) {
+		deprecated, ok := handlers[ctx.Request.Method+" "+strings.TrimPrefix(ctx.FullPath(), prefix)]
+		if !ok {
+			ctx.Next()
+			return
+		}
+		deprecated(ctx)
+	}
+}
@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"mygram/internal/model"
+	"mygram/pkg"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	HEADER_IDEMPOTENCY_KEY     = "Idempotency-Key"
+	HEADER_IDEMPOTENT_REPLAYED = "Idempotent-Replayed"
+	idempotencyKeyMaxLength    = 255
+	// idempotentMaxBodySize bounds the bodies read for their fingerprint,
+	// the size of the JSON the handlers take.
+	idempotentMaxBodySize = 1 << 20
+)
+
+type IdempotencyKeeper interface {
+	Begin(ctx context.Context, userId uint64, key string, fingerprint string) (model.IdempotencyKey, bool, error)
+	Complete(ctx context.Context, id uint64, res model.IdempotentResponse) error
+	Release(ctx context.Context, id uint64) error
+}
+
+// Idempotent makes POST requests carrying an Idempotency-Key safe to retry:
+// the first response for each key of a user is stored and replayed to
+// retries of the same request. Reusing the key for another request, or
+// retrying while the first is still running, is a conflict. It runs after
+// CheckAuthBearer. versionPrefix, such as /v1, is left out of the route
+// requests are told apart by, so a retry through the unversioned alias is
+// the same request.
+func Idempotent(keeper IdempotencyKeeper, versionPrefix string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key := ctx.GetHeader(HEADER_IDEMPOTENCY_KEY)
+		if ctx.Request.Method != http.MethodPost || key == "" {
+			ctx.Next()
+			return
+		}
+		if len(key) > idempotencyKeyMaxLength {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, pkg.ErrorResponse{Message: "invalid idempotency key", Errors: []string{"Idempotency-Key is at most 255 characters"}})
+			return
+		}
+
+		userId, ok := ctx.Get(CLAIM_USER_ID)
+		if !ok {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, pkg.ErrorResponse{Message: "invalid user session"})
+			return
+		}
+		userIdInt, ok := userId.(float64)
+		if !ok {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, pkg.ErrorResponse{Message: "invalid user id session"})
+			return
+		}
+
+		fingerprint, err := requestFingerprint(ctx, versionPrefix)
+		if maxBytesErr := (*http.MaxBytesError)(nil); errors.As(err, &maxBytesErr) {
+			ctx.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, pkg.ErrorResponse{Message: "request body too large"})
+			return
+		}
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, pkg.ErrorResponse{Message: err.Error()})
+			return
+		}
+
+		stored, reserved, err := keeper.Begin(ctx, uint64(userIdInt), key, fingerprint)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+			return
+		}
+		if !reserved {
+			replay(ctx, stored, fingerprint)
+			return
+		}
+
+		// the response is out, a client hanging up must not lose the key
+		bg := context.WithoutCancel(ctx.Request.Context())
+		answered := false
+		defer func() {
+			// the handler panicked, the retry may run it again rather than
+			// wait for the lock to expire
+			if !answered {
+				if err := keeper.Release(bg, stored.ID); err != nil {
+					log.Println("idempotency key error", err.Error())
+				}
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: ctx.Writer}
+		ctx.Writer = recorder
+		ctx.Next()
+		answered = true
+
+		if recorder.Status() >= http.StatusInternalServerError {
+			err = keeper.Release(bg, stored.ID)
+		} else {
+			err = keeper.Complete(bg, stored.ID, model.IdempotentResponse{
+				Status:      recorder.Status(),
+				ContentType: recorder.Header().Get("Content-Type"),
+				Body:        recorder.body.Bytes(),
+			})
+		}
+		if err != nil {
+			log.Println("idempotency key error", err.Error())
+		}
+	}
+}
+
+func replay(ctx *gin.Context, stored model.IdempotencyKey, fingerprint string) {
+	if stored.Fingerprint != fingerprint {
+		ctx.AbortWithStatusJSON(http.StatusConflict, pkg.ErrorResponse{
+			Message: "idempotency key reused",
+			Errors:  []string{"the key was already used for a different request"},
+		})
+		return
+	}
+	if stored.CompletedAt == nil {
+		ctx.Header("Retry-After", strconv.Itoa(ceilSeconds(time.Until(stored.LockedUntil))))
+		ctx.AbortWithStatusJSON(http.StatusConflict, pkg.ErrorResponse{
+			Message: "request in progress",
+			Errors:  []string{"a request with this idempotency key is still being processed"},
+		})
+		return
+	}
+
+	ctx.Header(HEADER_IDEMPOTENT_REPLAYED, "true")
+	ctx.Data(stored.ResponseStatus, stored.ResponseType, stored.ResponseBody)
+	ctx.Abort()
+}
+
+// requestFingerprint hashes the method, the route pattern with its
+// parameters and query, and the body, putting the body back for the
+// handler. Bodies over idempotentMaxBodySize fail with *http.MaxBytesError.
+func requestFingerprint(ctx *gin.Context, versionPrefix string) (string, error) {
+	body := []byte{}
+	if ctx.Request.Body != nil {
+		var err error
+		body, err = io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, idempotentMaxBodySize))
+		if err != nil {
+			return "", err
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	route := strings.TrimPrefix(ctx.FullPath(), versionPrefix)
+	for _, param := range ctx.Params {
+		route += " " + param.Key + "=" + param.Value
+	}
+	if query := ctx.Request.URL.RawQuery; query != "" {
+		route += "?" + query
+	}
+
+	h := sha256.New()
+	h.Write([]byte(ctx.Request.Method + " " + route + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// responseRecorder keeps a copy of the body written through it.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
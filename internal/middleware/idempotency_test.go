@@ -0,0 +1,56 @@
+package middleware_test
+
+import (
+	"context"
+	"mygram/internal/middleware"
+	"mygram/internal/model"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// keeper reserves every key, and records what became of them.
+type keeper struct {
+	completed []uint64
+	released  []uint64
+}
+
+func (k *keeper) Begin(ctx context.Context, userId uint64, key string, fingerprint string) (model.IdempotencyKey, bool, error) {
+	return model.IdempotencyKey{ID: 1}, true, nil
+}
+
+func (k *keeper) Complete(ctx context.Context, id uint64, res model.IdempotentResponse) error {
+	k.completed = append(k.completed, id)
+	return nil
+}
+
+func (k *keeper) Release(ctx context.Context, id uint64) error {
+	k.released = append(k.released, id)
+	return nil
+}
+
+func TestIdempotentReleasesTheKeyWhenTheHandlerPanics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	k := &keeper{}
+
+	g := gin.New()
+	g.Use(gin.CustomRecovery(func(ctx *gin.Context, _ any) { ctx.AbortWithStatus(http.StatusInternalServerError) }))
+	g.Use(func(ctx *gin.Context) { ctx.Set(middleware.CLAIM_USER_ID, float64(1)) })
+	g.Use(middleware.Idempotent(k, ""))
+	g.POST("/photos", func(ctx *gin.Context) { panic("boom") })
+
+	req := httptest.NewRequest(http.MethodPost, "/photos", strings.NewReader(`{}`))
+	req.Header.Set(middleware.HEADER_IDEMPOTENCY_KEY, "k1")
+	rec := httptest.NewRecorder()
+	g.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", rec.Code)
+	}
+	if len(k.released) != 1 || len(k.completed) != 0 {
+		t.Fatalf("expected the key released, got released %v completed %v", k.released, k.completed)
+	}
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"mygram/internal/metrics"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const unmatchedRoute = "unmatched"
+
+// Metrics records request count and latency per route template, so
+// /users/1 and /users/2 end up in the same /users/:id series.
+func Metrics(m *metrics.Metrics) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := strconv.Itoa(ctx.Writer.Status())
+
+		m.HTTPRequests.WithLabelValues(ctx.Request.Method, route, status).Inc()
+		m.HTTPDuration.WithLabelValues(ctx.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,37 @@
+package middleware_test
+
+import (
+	"mygram/internal/metrics"
+	"mygram/internal/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsCountsRequestsByRouteTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := metrics.New(prometheus.NewRegistry())
+
+	g := gin.New()
+	g.Use(middleware.Metrics(m))
+	g.GET("/users/:id", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+
+	for _, path := range []string{"/users/1", "/users/2", "/users/3", "/nowhere"} {
+		g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	if got := testutil.ToFloat64(m.HTTPRequests.WithLabelValues(http.MethodGet, "/users/:id", "200")); got != 3 {
+		t.Fatalf("expected 3 requests of /users/:id, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.HTTPRequests.WithLabelValues(http.MethodGet, "unmatched", "404")); got != 1 {
+		t.Fatalf("expected 1 unmatched request, got %v", got)
+	}
+	// one series per route, not per path
+	if got := testutil.CollectAndCount(m.HTTPDuration); got != 2 {
+		t.Fatalf("expected 2 latency series, got %d", got)
+	}
+}
@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"mygram/internal/ratelimit"
+	"mygram/pkg"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	HEADER_RATELIMIT_LIMIT     = "RateLimit-Limit"
+	HEADER_RATELIMIT_REMAINING = "RateLimit-Remaining"
+	HEADER_RATELIMIT_RESET     = "RateLimit-Reset"
+	HEADER_RATELIMIT_POLICY    = "RateLimit-Policy"
+)
+
+type RateLimiter struct {
+	store  ratelimit.Store
+	config ratelimit.Config
+}
+
+func NewRateLimiter(store ratelimit.Store, config ratelimit.Config) *RateLimiter {
+	return &RateLimiter{store: store, config: config}
+}
+
+// ByIP limits every request of the group by client address.
+func (r *RateLimiter) ByIP(group string) gin.HandlerFunc {
+	limit := r.config[group].IP
+	return func(ctx *gin.Context) {
+		r.take(ctx, fmt.Sprintf("%s:ip:%s", group, ctx.ClientIP()), limit)
+	}
+}
+
+// ByUser limits write requests of the group by the user ID claim, so it has
+// to run after CheckAuthBearer.
+func (r *RateLimiter) ByUser(group string) gin.HandlerFunc {
+	limit := r.config[group].User
+	return func(ctx *gin.Context) {
+		if ctx.Request.Method == http.MethodGet || ctx.Request.Method == http.MethodHead {
+			ctx.Next()
+			return
+		}
+		userId, ok := ctx.Get(CLAIM_USER_ID)
+		if !ok {
+			ctx.Next()
+			return
+		}
+		r.take(ctx, fmt.Sprintf("%s:user:%v", group, userId), limit)
+	}
+}
+
+// ByGuest limits every request of the group made with the public token by
+// client address, so it has to run after CheckAuthBearerOrGuest.
+func (r *RateLimiter) ByGuest(group string) gin.HandlerFunc {
+	limit := r.config[group].Guest
+	return func(ctx *gin.Context) {
+		if !IsGuest(ctx) {
+			ctx.Next()
+			return
+		}
+		r.take(ctx, fmt.Sprintf("%s:guest:%s", group, ctx.ClientIP()), limit)
+	}
+}
+
+// ByLoginEmail limits requests per email submitted in the JSON body, such as
+// login attempts, whichever address they come from.
+func (r *RateLimiter) ByLoginEmail(group string) gin.HandlerFunc {
+	limit := r.config[group].Email
+	return func(ctx *gin.Context) {
+		email := peekEmail(ctx)
+		if email == "" {
+			ctx.Next()
+			return
+		}
+		r.take(ctx, fmt.Sprintf("%s:email:%s", group, email), limit)
+	}
+}
+
+func (r *RateLimiter) take(ctx *gin.Context, key string, limit ratelimit.Limit) {
+	if limit.Disabled() {
+		ctx.Next()
+		return
+	}
+
+	res, err := r.store.Take(ctx, key, limit)
+	if err != nil {
+		// fail open, an unavailable backend should not take the API down
+		log.Println("rate limit backend error", err.Error())
+		ctx.Next()
+		return
+	}
+
+	setRateLimitHeaders(ctx, res, limit)
+	if !res.Allowed {
+		ctx.Header("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+		ctx.AbortWithStatusJSON(http.StatusTooManyRequests, pkg.ErrorResponse{
+			Message: "too many requests",
+			Errors:  []string{fmt.Sprintf("retry after %d seconds", ceilSeconds(res.RetryAfter))},
+		})
+		return
+	}
+	ctx.Next()
+}
+
+// setRateLimitHeaders reports the most restrictive of the limits applied to
+// the request.
+func setRateLimitHeaders(ctx *gin.Context, res ratelimit.Result, limit ratelimit.Limit) {
+	if current := ctx.Writer.Header().Get(HEADER_RATELIMIT_REMAINING); current != "" {
+		if remaining, err := strconv.Atoi(current); err == nil && remaining <= res.Remaining {
+			return
+		}
+	}
+	ctx.Header(HEADER_RATELIMIT_LIMIT, strconv.Itoa(res.Limit))
+	ctx.Header(HEADER_RATELIMIT_REMAINING, strconv.Itoa(res.Remaining))
+	ctx.Header(HEADER_RATELIMIT_RESET, strconv.Itoa(ceilSeconds(res.Reset)))
+	ctx.Header(HEADER_RATELIMIT_POLICY, fmt.Sprintf("%d;w=%d", limit.Requests, ceilSeconds(limit.Period)))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// peekEmail reads the email field of a JSON body and puts the body back for
+// the handler.
+func peekEmail(ctx *gin.Context) string {
+	if ctx.Request.Body == nil {
+		return ""
+	}
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		return ""
+	}
+	ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	payload := struct {
+		Email string `json:"email"`
+	}{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(payload.Email))
+}
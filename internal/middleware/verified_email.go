@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"context"
+	"mygram/pkg"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EmailVerifier interface {
+	IsEmailVerified(ctx context.Context, userId uint64) (bool, error)
+}
+
+// RequireVerifiedEmail refuses creating and editing content until the user
+// confirmed their email address. Reads and deletes stay open. It runs after
+// CheckAuthBearer.
+func RequireVerifiedEmail(verifier EmailVerifier) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.Request.Method != http.MethodPost && ctx.Request.Method != http.MethodPut && ctx.Request.Method != http.MethodPatch {
+			ctx.Next()
+			return
+		}
+
+		userId, ok := ctx.Get(CLAIM_USER_ID)
+		if !ok {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, pkg.ErrorResponse{Message: "invalid user session"})
+			return
+		}
+		userIdInt, ok := userId.(float64)
+		if !ok {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, pkg.ErrorResponse{Message: "invalid user id session"})
+			return
+		}
+
+		verified, err := verifier.IsEmailVerified(ctx, uint64(userIdInt))
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, pkg.ErrorResponse{Message: err.Error()})
+			return
+		}
+		if !verified {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, pkg.ErrorResponse{
+				Message: "email not verified",
+				Errors:  []string{"confirm your email address before posting"},
+			})
+			return
+		}
+		ctx.Next()
+	}
+}
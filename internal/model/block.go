@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// Block works both ways: neither user sees the other's content, and they
+// cannot follow, comment on or mention each other.
+type Block struct {
+	BlockerId uint64    `json:"blocker_id"`
+	BlockedId uint64    `json:"blocked_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Mute hides the muted user's content from the muter only, the muted user
+// is not told.
+type Mute struct {
+	MuterId   uint64    `json:"muter_id"`
+	MutedId   uint64    `json:"muted_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ListedUser is an entry of the lists of blocked and muted users.
+type ListedUser struct {
+	UserId    uint64       `json:"user_id"`
+	User      UserRelation `json:"User" gorm:"foreignKey:UserId;references:ID"`
+	CreatedAt time.Time    `json:"created_at"`
+}
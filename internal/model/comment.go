@@ -36,8 +36,8 @@ type CommentGetRes struct {
 	PhotoId   uint64    	`json:"photo_id"`
 	CreatedAt time.Time 	`json:"created_at"`
 	UpdatedAt time.Time 	`json:"updated_at"`
-	User      UserRelation  `json:"User" gorm:"foreignKey:UserId;references:ID"`
-	Photo     PhotoRelation `json:"Photo" gorm:"foreignKey:PhotoId;references:ID"`
+	User      *UserRelation  `json:"User,omitempty" gorm:"foreignKey:UserId;references:ID"`
+	Photo     *PhotoRelation `json:"Photo,omitempty" gorm:"foreignKey:PhotoId;references:ID"`
 }
 
 type CommentUpdateReq struct {
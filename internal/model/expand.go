@@ -0,0 +1,18 @@
+package model
+
+// Relations ?expand= can load.
+const (
+	EXPAND_USER     = "user"
+	EXPAND_PHOTO    = "photo"
+	EXPAND_COMMENTS = "comments"
+)
+
+// Expand picks the relations loaded with photos, comments and social
+// medias. Those left out are missing from the response.
+type Expand struct {
+	User bool
+	// Photo applies to comments.
+	Photo bool
+	// Comments applies to photos, they come with their users.
+	Comments bool
+}
@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// PhotoEngagement is what the explore ranking scores a photo on.
+type PhotoEngagement struct {
+	PhotoId   uint64
+	CreatedAt time.Time
+	// Comments leaves out the owner's own comments.
+	Comments int
+}
+
+// PhotoRanking is a photo's explore score as of the last ranking run.
+type PhotoRanking struct {
+	PhotoId    uint64 `gorm:"primaryKey"`
+	Score      float64
+	ComputedAt time.Time
+}
+
+type ExploreReq struct {
+	Limit  int `form:"limit" validate:"omitempty,min=1,max=50"`
+	Offset int `form:"offset" validate:"omitempty,min=0"`
+}
+
+// TrendingHashtag adds up the scores of the ranked photos using the tag.
+type TrendingHashtag struct {
+	Tag    string  `json:"tag"`
+	Photos int     `json:"photos"`
+	Score  float64 `json:"score"`
+}
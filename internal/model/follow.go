@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+// Follow states as reported to the follower.
+const (
+	FOLLOW_STATUS_FOLLOWING = "following"
+	FOLLOW_STATUS_REQUESTED = "requested"
+)
+
+// Follow is approved right away for public accounts. Following a private
+// account leaves it pending until the account approves it, only approved
+// followers see a private account's photos, comments and social medias.
+type Follow struct {
+	FollowerId uint64     `json:"follower_id"`
+	FolloweeId uint64     `json:"followee_id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ApprovedAt *time.Time `json:"approved_at"`
+}
+
+func (f Follow) Status() string {
+	if f.ApprovedAt == nil {
+		return FOLLOW_STATUS_REQUESTED
+	}
+	return FOLLOW_STATUS_FOLLOWING
+}
+
+type FollowRes struct {
+	FolloweeId uint64 `json:"followee_id"`
+	Status     string `json:"status"`
+}
+
+// FollowRequest is a pending follow as the account asked sees it.
+type FollowRequest struct {
+	FollowerId  uint64       `json:"follower_id"`
+	User        UserRelation `json:"User" gorm:"foreignKey:FollowerId;references:ID"`
+	RequestedAt time.Time    `json:"requested_at" gorm:"column:created_at"`
+}
+
+type PrivacyReq struct {
+	Private *bool `json:"private" validate:"required"`
+}
@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// IdempotencyKey is the Idempotency-Key a user sent with a request, holding
+// the response to replay on retries. While the first request runs it is
+// locked until LockedUntil and CompletedAt is nil.
+type IdempotencyKey struct {
+	ID     uint64
+	UserId uint64
+	Key    string
+	// Fingerprint is the SHA-256 of the method, URI and body of the request.
+	Fingerprint    string
+	LockedUntil    time.Time
+	ResponseStatus int
+	ResponseType   string
+	ResponseBody   []byte
+	CompletedAt    *time.Time
+	ExpiresAt      time.Time
+	CreatedAt      time.Time
+}
+
+// IdempotentResponse is what is replayed for a completed key.
+type IdempotentResponse struct {
+	Status      int
+	ContentType string
+	Body        []byte
+}
@@ -10,6 +10,14 @@ import "time"
 // iat (issued at time): Time at which the JWT was issued; can be used to determine age of the JWT
 // jti (JWT ID): Unique identifier; can be used to prevent the JWT from being replayed (allows a token to be used only once)
 
+// sub values of the tokens issued by the API
+const (
+	SUBJECT_ACCESS_TOKEN = "access-token"
+	SUBJECT_PUBLIC_TOKEN = "public-token"
+	SUBJECT_MFA_PENDING  = "mfa-pending"
+	SUBJECT_OAUTH_STATE  = "oauth-state"
+)
+
 type StandardClaim struct{
 	Jti string `json:"jti"`
 	Iss string `json:"iss"`
@@ -25,4 +33,11 @@ type AccessClaim struct{
 	UserID		uint64		`json:"user_id"`
 	Username	string		`json:"username"`
 	Dob			time.Time	`json:"dob"`
-}
\ No newline at end of file
+}
+
+// MFAPendingClaim is handed out by the first login step when two-factor
+// authentication is on. It only lets the user send the second factor.
+type MFAPendingClaim struct{
+	StandardClaim
+	UserID		uint64		`json:"user_id"`
+}
@@ -0,0 +1,91 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Scopes a personal access token can be granted. Sessions from login are
+// not limited by scope.
+const (
+	SCOPE_PROFILE_READ        = "profile:read"
+	SCOPE_PROFILE_WRITE       = "profile:write"
+	SCOPE_PHOTOS_READ         = "photos:read"
+	SCOPE_PHOTOS_WRITE        = "photos:write"
+	SCOPE_COMMENTS_READ       = "comments:read"
+	SCOPE_COMMENTS_WRITE      = "comments:write"
+	SCOPE_SOCIAL_MEDIAS_READ  = "social_medias:read"
+	SCOPE_SOCIAL_MEDIAS_WRITE = "social_medias:write"
+)
+
+var Scopes = []string{
+	SCOPE_PROFILE_READ,
+	SCOPE_PROFILE_WRITE,
+	SCOPE_PHOTOS_READ,
+	SCOPE_PHOTOS_WRITE,
+	SCOPE_COMMENTS_READ,
+	SCOPE_COMMENTS_WRITE,
+	SCOPE_SOCIAL_MEDIAS_READ,
+	SCOPE_SOCIAL_MEDIAS_WRITE,
+}
+
+// GuestScopes are what the public token from GET /public can do, read what
+// anybody may see.
+var GuestScopes = []string{
+	SCOPE_PROFILE_READ,
+	SCOPE_PHOTOS_READ,
+	SCOPE_COMMENTS_READ,
+	SCOPE_SOCIAL_MEDIAS_READ,
+}
+
+// PERSONAL_ACCESS_TOKEN_PREFIX starts every personal access token, which
+// tells them apart from JWTs and makes leaked tokens easy to scan for.
+const PERSONAL_ACCESS_TOKEN_PREFIX = "mgp_"
+
+type PersonalAccessToken struct {
+	ID          uint64     `json:"id"`
+	UserId      uint64     `json:"user_id"`
+	Name        string     `json:"name"`
+	TokenPrefix string     `json:"token_prefix"`
+	TokenHash   string     `json:"-"`
+	Scopes      []string   `json:"scopes" gorm:"serializer:json"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	RevokedAt   *time.Time `json:"-"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func (p PersonalAccessToken) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+type PersonalAccessTokenCreateReq struct {
+	Name   string   `json:"name" validate:"required,max=255"`
+	Scopes []string `json:"scopes" validate:"required,min=1"`
+	// ExpiresInDays is optional, tokens without it do not expire.
+	ExpiresInDays int `json:"expires_in_days" validate:"gte=0"`
+}
+
+func (p PersonalAccessTokenCreateReq) Validate() error {
+	validate := validator.New()
+
+	if err := validate.Struct(p); err != nil {
+		return err
+	}
+
+	for _, scope := range p.Scopes {
+		if !slices.Contains(Scopes, scope) {
+			return fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+	return nil
+}
+
+// PersonalAccessTokenCreateRes is the only time Token is shown.
+type PersonalAccessTokenCreateRes struct {
+	PersonalAccessToken
+	Token string `json:"token"`
+}
@@ -41,7 +41,8 @@ type PhotoGetRes struct {
 	UserId    uint64    	`json:"user_id"`
 	CreatedAt time.Time 	`json:"created_at"`
 	UpdatedAt time.Time 	`json:"updated_at"`
-	User      UserRelation  `json:"User" gorm:"foreignKey:UserId;references:ID"`
+	User      *UserRelation  `json:"User,omitempty" gorm:"foreignKey:UserId;references:ID"`
+	Comments  []CommentGetRes `json:"Comments,omitempty" gorm:"foreignKey:PhotoId;references:ID"`
 }
 
 type PhotoUpdateReq struct {
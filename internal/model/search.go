@@ -0,0 +1,19 @@
+package model
+
+const (
+	SEARCH_TYPE_PHOTOS   = "photos"
+	SEARCH_TYPE_USERS    = "users"
+	SEARCH_TYPE_HASHTAGS = "hashtags"
+)
+
+type SearchReq struct {
+	Q     string `form:"q" validate:"required"`
+	Type  string `form:"type" validate:"omitempty,oneof=photos users hashtags"`
+	Limit int    `form:"limit" validate:"omitempty,min=1,max=50"`
+}
+
+// HashtagRes is a hashtag found by search, without the #.
+type HashtagRes struct {
+	Tag    string `json:"tag"`
+	Photos int    `json:"photos"`
+}
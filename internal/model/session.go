@@ -0,0 +1,49 @@
+package model
+
+import "time"
+
+// How a sign in was completed, as shown in the login history.
+const (
+	LOGIN_METHOD_PASSWORD = "password"
+	LOGIN_METHOD_MFA      = "mfa"
+	// LOGIN_METHOD_OAUTH is followed by the provider name.
+	LOGIN_METHOD_OAUTH = "oauth:"
+)
+
+// Device describes the client a request came from.
+type Device struct {
+	UserAgent string
+	IP        string
+}
+
+// Session is one login, identified in its access tokens by their jti.
+type Session struct {
+	ID         uint64     `json:"id"`
+	UserId     uint64     `json:"user_id"`
+	Jti        string     `json:"-"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip" gorm:"column:ip"`
+	ClientName string     `json:"client_name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"-"`
+	// Current marks the session of the request listing them.
+	Current bool `json:"current" gorm:"-"`
+}
+
+type LoginAttempt struct {
+	ID            uint64    `json:"id"`
+	UserId        uint64    `json:"-"`
+	Method        string    `json:"method"`
+	Success       bool      `json:"success"`
+	FailureReason string    `json:"failure_reason,omitempty"`
+	UserAgent     string    `json:"user_agent"`
+	IP            string    `json:"ip" gorm:"column:ip"`
+	ClientName    string    `json:"client_name"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func (s Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
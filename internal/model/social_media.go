@@ -44,5 +44,5 @@ type SocialMediaGetRes struct {
 	UserId         uint64    	 `json:"user_id"`
 	CreatedAt      time.Time 	 `json:"created_at"`
 	UpdatedAt      time.Time 	 `json:"updated_at"`
-	User           UserRelation  `json:"User" gorm:"foreignKey:UserId;references:ID"`
+	User           *UserRelation `json:"User,omitempty" gorm:"foreignKey:UserId;references:ID"`
 }
\ No newline at end of file
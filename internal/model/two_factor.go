@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+type RecoveryCode struct {
+	ID        uint64     `json:"id"`
+	UserId    uint64     `json:"user_id"`
+	CodeHash  string     `json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type TwoFactorEnrollRes struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+	// QRCode is a PNG data URI of ProvisioningURI.
+	QRCode string `json:"qr_code"`
+}
+
+type TwoFactorConfirmReq struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type TwoFactorConfirmRes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type TwoFactorDisableReq struct {
+	Password string `json:"password" validate:"required"`
+	// Code is a TOTP code or a recovery code.
+	Code string `json:"code" validate:"required"`
+}
+
+type TwoFactorLoginReq struct {
+	MFAPendingToken string `json:"mfa_pending_token" validate:"required"`
+	// Code is a TOTP code or a recovery code.
+	Code string `json:"code" validate:"required"`
+}
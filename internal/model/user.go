@@ -14,6 +14,12 @@ type User struct {
 	Email     string    	 `json:"email"`
 	Password  string	     `json:"-"`
 	DoB       time.Time      `json:"dob" gorm:"column:dob"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at" gorm:"column:email_verified_at"`
+	// Private accounts only show their content to approved followers.
+	Private       bool       `json:"private"`
+	TotpSecret    string     `json:"-" gorm:"column:totp_secret"`
+	TotpEnabledAt *time.Time `json:"-" gorm:"column:totp_enabled_at"`
+	TotpLastStep  uint64     `json:"-" gorm:"column:totp_last_step"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"column:deleted_at"`
@@ -46,7 +52,30 @@ type UserResponse struct {
 type UserRelation struct {
 	ID       uint64 `json:"id"`
 	Username string `json:"username"`
-	Email    string `json:"email"`
+	Email    string `json:"email,omitempty"`
+}
+
+// Public leaves out the email, guests see who posted but not how to reach
+// them.
+func (u *UserRelation) Public() *UserRelation {
+	if u == nil {
+		return nil
+	}
+	public := *u
+	public.Email = ""
+	return &public
+}
+
+// PublicProfile is what guests see of a user.
+type PublicProfile struct {
+	ID        uint64    `json:"id"`
+	Username  string    `json:"username"`
+	Private   bool      `json:"private"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (u User) PublicProfile() PublicProfile {
+	return PublicProfile{ID: u.ID, Username: u.Username, Private: u.Private, CreatedAt: u.CreatedAt}
 }
 
 func (u UserSignUp) Validate() error {
@@ -56,9 +85,6 @@ func (u UserSignUp) Validate() error {
 		return err
 	}
 
-	if len(u.Password) < 6 {
-		return errors.New("password less than 6 characters")
-	}
 	dob, err := time.Parse("2006-01-02", u.DoB)
 	if err != nil {
 		return err
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// UserIdentity links a user to the subject an external identity provider
+// knows them by.
+type UserIdentity struct {
+	ID        uint64    `json:"id"`
+	UserId    uint64    `json:"user_id"`
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OAuthStateClaim carries the state, nonce and PKCE verifier of a social
+// login from the login redirect to the callback, in a cookie.
+type OAuthStateClaim struct {
+	StandardClaim
+	Provider string `json:"provider"`
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	TOKEN_PURPOSE_VERIFY_EMAIL   = "verify_email"
+	TOKEN_PURPOSE_PASSWORD_RESET = "password_reset"
+)
+
+// UserToken is a single-use token mailed to the user. Only the SHA-256 of
+// the token is stored.
+type UserToken struct {
+	ID        uint64     `json:"id"`
+	UserId    uint64     `json:"user_id"`
+	Purpose   string     `json:"purpose"`
+	TokenHash string     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type EmailVerifyReq struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type EmailReq struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type PasswordResetReq struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+func (p PasswordResetReq) Validate() error {
+	validate := validator.New()
+
+	if err := validate.Struct(p); err != nil {
+		return err
+	}
+	return nil
+}
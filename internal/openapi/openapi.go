@@ -0,0 +1,258 @@
+package openapi
+
+import (
+	"mygram/pkg"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	SECURITY_BEARER = "bearerAuth"
+	SECURITY_BASIC  = "basicAuth"
+
+	mimeJSON       = "application/json"
+	mimeMergePatch = "application/merge-patch+json"
+)
+
+// auth is what a route accepts as credentials.
+type auth int
+
+const (
+	public auth = iota
+	// guest takes the token from GET /public as well as a user's.
+	guest
+	// user takes a login session or a personal access token.
+	user
+	// session only takes a login session.
+	session
+)
+
+// route documents one registered route.
+type route struct {
+	method  string
+	path    string
+	id      string
+	summary string
+	auth    auth
+	// scope is what a personal access token needs.
+	scope string
+	// query is a struct with form tags, params any other parameters.
+	query  any
+	params []Parameter
+	body   any
+	status int
+	res    any
+	errors []int
+	// conditional routes answer with an ETag, reads honour If-None-Match
+	// and writes If-Match.
+	conditional bool
+	// idempotent routes replay their response to retries with the same
+	// Idempotency-Key.
+	idempotent bool
+}
+
+// oneOf is a response that takes one of several shapes.
+type oneOf []any
+
+// New builds the document of the routes served under server.
+func New(server string) *Document {
+	d := &Document{
+		OpenAPI: VERSION,
+		Info: Info{
+			Title:       "MyGram",
+			Description: "Share photos, comment on them and link your social media.",
+			Version:     "1.0",
+		},
+		Servers: []Server{{URL: server}},
+		Paths:   map[string]PathItem{},
+		Components: Components{
+			Schemas: map[string]*Schema{},
+			SecuritySchemes: map[string]SecurityScheme{
+				SECURITY_BEARER: {
+					Type:         "http",
+					Scheme:       "bearer",
+					BearerFormat: "JWT",
+					Description:  "An access token from login, a personal access token (mgp_...) or the guest token from GET /public.",
+				},
+				SECURITY_BASIC: {
+					Type:        "http",
+					Scheme:      "basic",
+					Description: "For internal deployments, none of the routes below take it.",
+				},
+			},
+		},
+	}
+
+	tags := map[string]bool{}
+	for _, r := range routes {
+		d.add(r)
+		tags[tagOf(r.path)] = true
+	}
+	for tag := range tags {
+		d.Tags = append(d.Tags, Tag{Name: tag})
+	}
+	sort.Slice(d.Tags, func(i, j int) bool { return d.Tags[i].Name < d.Tags[j].Name })
+	return d
+}
+
+func (d *Document) add(r route) {
+	op := &Operation{
+		OperationID: r.id,
+		Summary:     r.summary,
+		Tags:        []string{tagOf(r.path)},
+		Responses:   map[string]*Response{},
+		Security:    []SecurityRequirement{},
+		Scope:       r.scope,
+	}
+
+	path := []string{}
+	for _, segment := range strings.Split(r.path, "/") {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			op.Parameters = append(op.Parameters, pathParameter(name))
+			segment = "{" + name + "}"
+		}
+		path = append(path, segment)
+	}
+	if r.query != nil {
+		op.Parameters = append(op.Parameters, d.parametersOf(r.query)...)
+	}
+	op.Parameters = append(op.Parameters, r.params...)
+
+	if r.body != nil {
+		content := map[string]MediaType{mimeJSON: {Schema: d.schemaOf(reflect.TypeOf(r.body))}}
+		if r.method == http.MethodPatch {
+			content[mimeMergePatch] = content[mimeJSON]
+			op.Description = "Takes a JSON merge patch (RFC 7396) of the fields to change."
+			d.respond(op, http.StatusUnsupportedMediaType, pkg.ErrorResponse{})
+		}
+		op.RequestBody = &RequestBody{Required: true, Content: content}
+	}
+
+	success := d.respond(op, r.status, r.res)
+	switch r.status {
+	case http.StatusFound:
+		success.Headers = map[string]Header{"Location": {Schema: &Schema{Type: "string", Format: "uri"}}}
+	}
+
+	if r.auth != public {
+		op.Security = []SecurityRequirement{{SECURITY_BEARER: {}}}
+		d.respond(op, http.StatusUnauthorized, pkg.ErrorResponse{})
+	}
+	if r.scope != "" || r.auth == session {
+		d.respond(op, http.StatusForbidden, pkg.ErrorResponse{})
+	}
+	if r.scope != "" {
+		op.Description = strings.TrimSpace(op.Description + " Personal access tokens need the " + r.scope + " scope.")
+	}
+	if r.auth == session {
+		op.Description = strings.TrimSpace(op.Description + " Personal access tokens are refused.")
+	}
+
+	if r.conditional {
+		success.Headers = map[string]Header{"ETag": {Schema: &Schema{Type: "string"}}}
+		if r.method == http.MethodGet {
+			op.Parameters = append(op.Parameters, headerParameter("If-None-Match", "Answers 304 while the representation is unchanged."))
+			op.Responses["304"] = &Response{Description: http.StatusText(http.StatusNotModified)}
+		} else {
+			op.Parameters = append(op.Parameters, headerParameter("If-Match", "Only applies the change to this version, * for any."))
+			d.respond(op, http.StatusPreconditionFailed, pkg.ErrorResponse{})
+		}
+	}
+	if r.idempotent {
+		op.Parameters = append(op.Parameters, headerParameter("Idempotency-Key", "Retries with the same key and request are answered with the first response, for a day."))
+		if success.Headers == nil {
+			success.Headers = map[string]Header{}
+		}
+		success.Headers["Idempotent-Replayed"] = Header{Description: "Set on replayed responses.", Schema: &Schema{Type: "boolean"}}
+		d.respond(op, http.StatusConflict, pkg.ErrorResponse{})
+	}
+
+	for _, status := range r.errors {
+		d.respond(op, status, pkg.ErrorResponse{})
+	}
+	// every route is rate limited
+	d.respond(op, http.StatusTooManyRequests, pkg.ErrorResponse{})
+	d.respond(op, http.StatusInternalServerError, pkg.ErrorResponse{})
+
+	key := strings.Join(path, "/")
+	if d.Paths[key] == nil {
+		d.Paths[key] = PathItem{}
+	}
+	d.Paths[key][strings.ToLower(r.method)] = op
+}
+
+// Deprecate marks the operation of the route with method and path, in the
+// form the routers mount it such as /photos/:id, deprecated.
+func (d *Document) Deprecate(method string, path string) {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			segments[i] = "{" + name + "}"
+		}
+	}
+	if op := d.Paths[strings.Join(segments, "/")][strings.ToLower(method)]; op != nil {
+		op.Deprecated = true
+	}
+}
+
+// respond adds the response for status with a JSON body of res, none when
+// res is nil.
+func (d *Document) respond(op *Operation, status int, res any) *Response {
+	response := &Response{Description: http.StatusText(status)}
+	switch res := res.(type) {
+	case nil:
+	case oneOf:
+		schema := &Schema{}
+		for _, option := range res {
+			schema.OneOf = append(schema.OneOf, d.schemaOf(reflect.TypeOf(option)))
+		}
+		response.Content = map[string]MediaType{mimeJSON: {Schema: schema}}
+	default:
+		response.Content = map[string]MediaType{mimeJSON: {Schema: d.schemaOf(reflect.TypeOf(res))}}
+	}
+	op.Responses[strconv.Itoa(status)] = response
+	return response
+}
+
+// pathParameter takes parameters named like id or photoId as IDs.
+func pathParameter(name string) Parameter {
+	schema := &Schema{Type: "string"}
+	if strings.HasSuffix(strings.ToLower(name), "id") {
+		schema = &Schema{Type: "integer", Format: "int64", Minimum: float(1)}
+	}
+	return Parameter{Name: name, In: "path", Required: true, Schema: schema}
+}
+
+func headerParameter(name string, description string) Parameter {
+	return Parameter{Name: name, In: "header", Description: description, Schema: &Schema{Type: "string"}}
+}
+
+func queryId(name string, description string) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Required: true, Schema: &Schema{Type: "integer", Format: "int64", Minimum: float(1)}}
+}
+
+// expandParameter documents ?expand= as read by handler.parseExpand.
+func expandParameter(defaults string, allowed ...string) Parameter {
+	return Parameter{
+		Name:        "expand",
+		In:          "query",
+		Description: "Comma separated relations to embed out of " + strings.Join(allowed, ", ") + ", " + defaults + " by default. Empty embeds none.",
+		Schema:      &Schema{Type: "string"},
+	}
+}
+
+// fieldsParameter documents ?fields= as read by handler.parseFields.
+var fieldsParameter = Parameter{
+	Name:        "fields",
+	In:          "query",
+	Description: "Comma separated fields to answer with. The id and embedded relations are always kept.",
+	Schema:      &Schema{Type: "string"},
+}
+
+func tagOf(path string) string {
+	tag, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	return tag
+}
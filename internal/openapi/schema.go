@@ -0,0 +1,173 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaOf describes values of type t, as encoding/json marshals them.
+// Named structs are added to the components once and referenced.
+func (d *Document) schemaOf(t reflect.Type) *Schema {
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := d.schemaOf(t.Elem())
+		if schema.Ref != "" {
+			return schema
+		}
+		schema.Nullable = true
+		return schema
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64", Minimum: float(0)}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: d.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.objectOf(t)
+		}
+		name := componentName(t)
+		if _, ok := d.Components.Schemas[name]; !ok {
+			// registered first, so types referring to themselves end
+			d.Components.Schemas[name] = &Schema{}
+			d.Components.Schemas[name] = d.objectOf(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	return &Schema{}
+}
+
+func (d *Document) objectOf(t reflect.Type) *Schema {
+	object := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	d.addFields(object, t)
+	return object
+}
+
+// addFields adds the json fields of t, flattening embedded structs the way
+// encoding/json does. Fields validated as required are required.
+func (d *Document) addFields(object *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				d.addFields(object, embedded)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema := d.schemaOf(field.Type)
+		if constrain(schema, field.Tag.Get("validate")) {
+			object.Required = append(object.Required, name)
+		}
+		object.Properties[name] = schema
+	}
+}
+
+// parametersOf lists the query parameters bound from the form tags of the
+// struct v.
+func (d *Document) parametersOf(v any) []Parameter {
+	t := reflect.TypeOf(v)
+	params := []Parameter{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("form"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		schema := d.schemaOf(field.Type)
+		required := constrain(schema, field.Tag.Get("validate"))
+		params = append(params, Parameter{Name: name, In: "query", Required: required, Schema: schema})
+	}
+	return params
+}
+
+// constrain adds the rules of a validate tag that a schema can express to
+// schema and reports whether the value is required.
+func constrain(schema *Schema, tag string) bool {
+	required := false
+	for _, rule := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(rule, "=")
+		if key == "required" {
+			required = true
+			continue
+		}
+		// referenced schemas are shared, the rules stay with the field
+		if schema.Ref != "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(value)
+		switch {
+		case key == "email":
+			schema.Format = "email"
+		case key == "oneof":
+			schema.Enum = strings.Fields(value)
+		case (key == "min" || key == "gte") && err == nil:
+			switch schema.Type {
+			case "string":
+				schema.MinLength = &n
+			case "array":
+				schema.MinItems = &n
+			default:
+				schema.Minimum = float(n)
+			}
+		case (key == "max" || key == "lte") && err == nil:
+			switch schema.Type {
+			case "string":
+				schema.MaxLength = &n
+			case "array":
+				schema.MaxItems = &n
+			default:
+				schema.Maximum = float(n)
+			}
+		}
+	}
+	return required
+}
+
+func componentName(t reflect.Type) string {
+	name := []rune(t.Name())
+	name[0] = unicode.ToUpper(name[0])
+	return string(name)
+}
+
+func float(n int) *float64 {
+	f := float64(n)
+	return &f
+}
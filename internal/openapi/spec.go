@@ -0,0 +1,200 @@
+package openapi
+
+import (
+	"mygram/internal/model"
+	"net/http"
+)
+
+// Answers the handlers build inline.
+type (
+	messageRes struct {
+		Message string `json:"message"`
+	}
+	tokenRes struct {
+		Token string `json:"token"`
+	}
+	// loginRes carries the access token, or a pending token when the user
+	// still owes a second factor at POST /users/login/mfa.
+	loginRes struct {
+		Token           string `json:"token,omitempty"`
+		MFARequired     bool   `json:"mfa_required,omitempty"`
+		MFAPendingToken string `json:"mfa_pending_token,omitempty"`
+	}
+	privacyRes struct {
+		Private bool `json:"private"`
+	}
+	revokedSessionsRes struct {
+		Message string `json:"message"`
+		Revoked int64  `json:"revoked"`
+	}
+)
+
+var (
+	photoExpand       = expandParameter(model.EXPAND_USER, model.EXPAND_USER, model.EXPAND_COMMENTS)
+	commentExpand     = expandParameter(model.EXPAND_USER+" and "+model.EXPAND_PHOTO, model.EXPAND_USER, model.EXPAND_PHOTO)
+	socialMediaExpand = expandParameter(model.EXPAND_USER, model.EXPAND_USER)
+)
+
+// routes lists every route of the routers, TestEveryRouteIsInTheSpec keeps
+// it complete.
+var routes = []route{
+	// public
+	{method: http.MethodGet, path: "/public", id: "GetPublicToken", summary: "Get a guest token, which reads what anybody may see",
+		auth: public, status: http.StatusOK, res: tokenRes{}},
+
+	// account
+	{method: http.MethodPost, path: "/users/register", id: "UserSignUp", summary: "Sign up, a verification email is sent",
+		auth: public, body: model.UserSignUp{}, status: http.StatusCreated, res: model.User{}, errors: []int{http.StatusBadRequest}},
+	{method: http.MethodPost, path: "/users/login", id: "UserSignIn", summary: "Log in with email and password",
+		auth: public, body: model.UserSignIn{}, status: http.StatusOK, res: loginRes{}, errors: []int{http.StatusBadRequest, http.StatusUnauthorized}},
+	{method: http.MethodPost, path: "/users/login/mfa", id: "UserSignInMFA", summary: "Finish logging in with a TOTP or recovery code",
+		auth: public, body: model.TwoFactorLoginReq{}, status: http.StatusOK, res: tokenRes{}, errors: []int{http.StatusBadRequest, http.StatusUnauthorized}},
+	{method: http.MethodPost, path: "/users/verify-email", id: "VerifyEmail", summary: "Verify an email address with the token mailed",
+		auth: public, body: model.EmailVerifyReq{}, status: http.StatusOK, res: messageRes{}, errors: []int{http.StatusBadRequest}},
+	{method: http.MethodPost, path: "/users/verify-email/resend", id: "ResendEmailVerification", summary: "Send the verification email again",
+		auth: public, body: model.EmailReq{}, status: http.StatusAccepted, res: messageRes{}, errors: []int{http.StatusBadRequest}},
+	{method: http.MethodPost, path: "/users/password/forgot", id: "ForgotPassword", summary: "Mail a password reset link",
+		auth: public, body: model.EmailReq{}, status: http.StatusAccepted, res: messageRes{}, errors: []int{http.StatusBadRequest}},
+	{method: http.MethodPost, path: "/users/password/reset", id: "ResetPassword", summary: "Choose a new password with the token mailed, which signs out everywhere",
+		auth: public, body: model.PasswordResetReq{}, status: http.StatusOK, res: messageRes{}, errors: []int{http.StatusBadRequest}},
+	{method: http.MethodGet, path: "/users/oauth/:provider/login", id: "SocialLogin", summary: "Start logging in with an OpenID Connect provider",
+		auth: public, status: http.StatusFound, errors: []int{http.StatusNotFound, http.StatusBadGateway}},
+	{method: http.MethodGet, path: "/users/oauth/:provider/callback", id: "SocialLoginCallback", summary: "Finish logging in with an OpenID Connect provider",
+		auth: public, params: []Parameter{{Name: "code", In: "query", Schema: &Schema{Type: "string"}}, {Name: "state", In: "query", Schema: &Schema{Type: "string"}}},
+		status: http.StatusOK, res: loginRes{}, errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusConflict, http.StatusBadGateway}},
+
+	// users
+	{method: http.MethodGet, path: "/users/:id", id: "GetUsersById", summary: "Get a user, anybody else sees the public profile",
+		auth: guest, scope: model.SCOPE_PROFILE_READ, status: http.StatusOK, res: oneOf{model.User{}, model.PublicProfile{}},
+		errors: []int{http.StatusBadRequest, http.StatusNotFound}, conditional: true},
+	{method: http.MethodPut, path: "/users/:id", id: "EditUser", summary: "Replace your username and email, a new email needs a login session and is verified again",
+		auth: user, scope: model.SCOPE_PROFILE_WRITE, body: model.UserEditReq{}, status: http.StatusOK, res: model.UserResponse{},
+		errors: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound}, conditional: true},
+	{method: http.MethodPatch, path: "/users/:id", id: "PatchUser", summary: "Change your username or email, a new email needs a login session and is verified again",
+		auth: user, scope: model.SCOPE_PROFILE_WRITE, body: model.UserEditReq{}, status: http.StatusOK, res: model.UserResponse{},
+		errors: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound}, conditional: true},
+	{method: http.MethodDelete, path: "/users/:id", id: "DeleteUsersById", summary: "Delete your account",
+		auth: session, status: http.StatusOK, res: messageRes{}, errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+
+	// two-factor authentication
+	{method: http.MethodPost, path: "/users/2fa/enroll", id: "EnrollTwoFactor", summary: "Start enrolling a TOTP authenticator",
+		auth: session, status: http.StatusOK, res: model.TwoFactorEnrollRes{}, errors: []int{http.StatusConflict}},
+	{method: http.MethodPost, path: "/users/2fa/confirm", id: "ConfirmTwoFactor", summary: "Turn two-factor authentication on, answers the recovery codes",
+		auth: session, body: model.TwoFactorConfirmReq{}, status: http.StatusOK, res: model.TwoFactorConfirmRes{}, errors: []int{http.StatusBadRequest, http.StatusConflict}},
+	{method: http.MethodPost, path: "/users/2fa/disable", id: "DisableTwoFactor", summary: "Turn two-factor authentication off",
+		auth: session, body: model.TwoFactorDisableReq{}, status: http.StatusOK, res: messageRes{}, errors: []int{http.StatusBadRequest}},
+
+	// personal access tokens
+	{method: http.MethodPost, path: "/users/tokens", id: "CreatePersonalAccessToken", summary: "Create a personal access token, shown this once",
+		auth: session, body: model.PersonalAccessTokenCreateReq{}, status: http.StatusCreated, res: model.PersonalAccessTokenCreateRes{}, errors: []int{http.StatusBadRequest}},
+	{method: http.MethodGet, path: "/users/tokens", id: "GetPersonalAccessTokens", summary: "List your personal access tokens",
+		auth: session, status: http.StatusOK, res: []model.PersonalAccessToken{}},
+	{method: http.MethodDelete, path: "/users/tokens/:tokenId", id: "RevokePersonalAccessToken", summary: "Revoke a personal access token",
+		auth: session, status: http.StatusOK, res: messageRes{}, errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+
+	// sessions
+	{method: http.MethodGet, path: "/users/me/sessions", id: "GetSessions", summary: "List where you are logged in",
+		auth: session, status: http.StatusOK, res: []model.Session{}},
+	{method: http.MethodDelete, path: "/users/me/sessions", id: "RevokeOtherSessions", summary: "Log out everywhere else",
+		auth: session, status: http.StatusOK, res: revokedSessionsRes{}},
+	{method: http.MethodDelete, path: "/users/me/sessions/:sessionId", id: "RevokeSession", summary: "Log a session out",
+		auth: session, status: http.StatusOK, res: messageRes{}, errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+	{method: http.MethodGet, path: "/users/me/login-history", id: "GetLoginHistory", summary: "List the recent logins to your account",
+		auth: session, status: http.StatusOK, res: []model.LoginAttempt{}},
+
+	// follows
+	{method: http.MethodPut, path: "/users/me/privacy", id: "SetPrivacy", summary: "Make your account private or public",
+		auth: user, scope: model.SCOPE_PROFILE_WRITE, body: model.PrivacyReq{}, status: http.StatusOK, res: privacyRes{}, errors: []int{http.StatusBadRequest}},
+	{method: http.MethodPost, path: "/users/:id/follow", id: "FollowUser", summary: "Follow a user, private accounts approve it first",
+		auth: user, scope: model.SCOPE_PROFILE_WRITE, status: http.StatusOK, res: model.FollowRes{}, errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+	{method: http.MethodDelete, path: "/users/:id/follow", id: "UnfollowUser", summary: "Unfollow a user or withdraw the request",
+		auth: user, scope: model.SCOPE_PROFILE_WRITE, status: http.StatusOK, res: messageRes{}, errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+	{method: http.MethodGet, path: "/users/me/follow-requests", id: "GetFollowRequests", summary: "List the pending requests to follow you",
+		auth: user, scope: model.SCOPE_PROFILE_READ, status: http.StatusOK, res: []model.FollowRequest{}},
+	{method: http.MethodPost, path: "/users/me/follow-requests/:followerId/approve", id: "ApproveFollowRequest", summary: "Approve a request to follow you",
+		auth: user, scope: model.SCOPE_PROFILE_WRITE, status: http.StatusOK, res: messageRes{}, errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+	{method: http.MethodDelete, path: "/users/me/follow-requests/:followerId", id: "DenyFollowRequest", summary: "Deny a request to follow you",
+		auth: user, scope: model.SCOPE_PROFILE_WRITE, status: http.StatusOK, res: messageRes{}, errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+
+	// blocks and mutes
+	{method: http.MethodPost, path: "/users/:id/block", id: "BlockUser", summary: "Block a user, both ways",
+		auth: user, scope: model.SCOPE_PROFILE_WRITE, status: http.StatusOK, res: messageRes{}, errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+	{method: http.MethodDelete, path: "/users/:id/block", id: "UnblockUser", summary: "Unblock a user",
+		auth: user, scope: model.SCOPE_PROFILE_WRITE, status: http.StatusOK, res: messageRes{}, errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+	{method: http.MethodGet, path: "/users/me/blocks", id: "GetBlocks", summary: "List the users you blocked",
+		auth: user, scope: model.SCOPE_PROFILE_READ, status: http.StatusOK, res: []model.ListedUser{}},
+	{method: http.MethodPost, path: "/users/:id/mute", id: "MuteUser", summary: "Hide a user's content from you",
+		auth: user, scope: model.SCOPE_PROFILE_WRITE, status: http.StatusOK, res: messageRes{}, errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+	{method: http.MethodDelete, path: "/users/:id/mute", id: "UnmuteUser", summary: "Unmute a user",
+		auth: user, scope: model.SCOPE_PROFILE_WRITE, status: http.StatusOK, res: messageRes{}, errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+	{method: http.MethodGet, path: "/users/me/mutes", id: "GetMutes", summary: "List the users you muted",
+		auth: user, scope: model.SCOPE_PROFILE_READ, status: http.StatusOK, res: []model.ListedUser{}},
+
+	// photos
+	{method: http.MethodPost, path: "/photos", id: "CreatePhoto", summary: "Post a photo",
+		auth: user, scope: model.SCOPE_PHOTOS_WRITE, body: model.PhotoCreateReq{}, status: http.StatusCreated, res: model.PhotoCreateRes{},
+		errors: []int{http.StatusBadRequest}, idempotent: true},
+	{method: http.MethodGet, path: "/photos", id: "GetPhotosByUserId", summary: "List the photos of a user",
+		auth: guest, scope: model.SCOPE_PHOTOS_READ, params: []Parameter{queryId("user_id", "The owner of the photos."), photoExpand, fieldsParameter},
+		status: http.StatusOK, res: []model.PhotoGetRes{}, errors: []int{http.StatusBadRequest, http.StatusNotFound}, conditional: true},
+	{method: http.MethodGet, path: "/photos/:id", id: "GetPhotoById", summary: "Get a photo",
+		auth: guest, scope: model.SCOPE_PHOTOS_READ, params: []Parameter{photoExpand, fieldsParameter},
+		status: http.StatusOK, res: model.PhotoGetRes{}, errors: []int{http.StatusBadRequest, http.StatusNotFound}, conditional: true},
+	{method: http.MethodPut, path: "/photos/:id", id: "EditPhoto", summary: "Replace a photo of yours",
+		auth: user, scope: model.SCOPE_PHOTOS_WRITE, body: model.PhotoUpdateReq{}, status: http.StatusOK, res: model.PhotoUpdateRes{},
+		errors: []int{http.StatusBadRequest, http.StatusNotFound}, conditional: true},
+	{method: http.MethodPatch, path: "/photos/:id", id: "PatchPhoto", summary: "Change a photo of yours",
+		auth: user, scope: model.SCOPE_PHOTOS_WRITE, body: model.PhotoUpdateReq{}, status: http.StatusOK, res: model.PhotoUpdateRes{},
+		errors: []int{http.StatusBadRequest, http.StatusNotFound}, conditional: true},
+	{method: http.MethodDelete, path: "/photos/:id", id: "DeletePhoto", summary: "Delete a photo of yours",
+		auth: user, scope: model.SCOPE_PHOTOS_WRITE, status: http.StatusOK, res: messageRes{}, errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+
+	// comments
+	{method: http.MethodPost, path: "/comments", id: "CreateComment", summary: "Comment on a photo",
+		auth: user, scope: model.SCOPE_COMMENTS_WRITE, body: model.CommentCreateReq{}, status: http.StatusCreated, res: model.CommentCreateRes{},
+		errors: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound}, idempotent: true},
+	{method: http.MethodGet, path: "/comments", id: "GetCommentsByPhotoId", summary: "List the comments on a photo",
+		auth: guest, scope: model.SCOPE_COMMENTS_READ, params: []Parameter{queryId("photo_id", "The photo commented on."), commentExpand, fieldsParameter},
+		status: http.StatusOK, res: []model.CommentGetRes{}, errors: []int{http.StatusBadRequest, http.StatusNotFound}, conditional: true},
+	{method: http.MethodGet, path: "/comments/:id", id: "GetCommentById", summary: "Get a comment",
+		auth: guest, scope: model.SCOPE_COMMENTS_READ, params: []Parameter{commentExpand, fieldsParameter},
+		status: http.StatusOK, res: model.CommentGetRes{}, errors: []int{http.StatusBadRequest, http.StatusNotFound}, conditional: true},
+	{method: http.MethodPut, path: "/comments/:id", id: "EditComment", summary: "Replace a comment of yours",
+		auth: user, scope: model.SCOPE_COMMENTS_WRITE, body: model.CommentUpdateReq{}, status: http.StatusOK, res: model.CommentUpdateRes{},
+		errors: []int{http.StatusBadRequest, http.StatusNotFound}, conditional: true},
+	{method: http.MethodPatch, path: "/comments/:id", id: "PatchComment", summary: "Change a comment of yours",
+		auth: user, scope: model.SCOPE_COMMENTS_WRITE, body: model.CommentUpdateReq{}, status: http.StatusOK, res: model.CommentUpdateRes{},
+		errors: []int{http.StatusBadRequest, http.StatusNotFound}, conditional: true},
+	{method: http.MethodDelete, path: "/comments/:id", id: "DeleteComment", summary: "Delete a comment of yours",
+		auth: user, scope: model.SCOPE_COMMENTS_WRITE, status: http.StatusOK, res: messageRes{}, errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+
+	// social medias
+	{method: http.MethodPost, path: "/social-medias", id: "CreateSocialMedia", summary: "Link a social media account",
+		auth: user, scope: model.SCOPE_SOCIAL_MEDIAS_WRITE, body: model.SocialMediaReq{}, status: http.StatusCreated, res: model.SocialMediaCreateRes{},
+		errors: []int{http.StatusBadRequest}, idempotent: true},
+	{method: http.MethodGet, path: "/social-medias", id: "GetSocialMediasByUserId", summary: "List the social media of a user",
+		auth: guest, scope: model.SCOPE_SOCIAL_MEDIAS_READ, params: []Parameter{queryId("user_id", "The owner of the social media."), socialMediaExpand, fieldsParameter},
+		status: http.StatusOK, res: []model.SocialMediaGetRes{}, errors: []int{http.StatusBadRequest, http.StatusNotFound}, conditional: true},
+	{method: http.MethodGet, path: "/social-medias/:id", id: "GetSocialMediaById", summary: "Get a social media link",
+		auth: guest, scope: model.SCOPE_SOCIAL_MEDIAS_READ, params: []Parameter{socialMediaExpand, fieldsParameter},
+		status: http.StatusOK, res: model.SocialMediaGetRes{}, errors: []int{http.StatusBadRequest, http.StatusNotFound}, conditional: true},
+	{method: http.MethodPut, path: "/social-medias/:id", id: "EditSocialMedia", summary: "Replace a social media link of yours",
+		auth: user, scope: model.SCOPE_SOCIAL_MEDIAS_WRITE, body: model.SocialMediaReq{}, status: http.StatusOK, res: model.SocialMediaUpdateRes{},
+		errors: []int{http.StatusBadRequest, http.StatusNotFound}, conditional: true},
+	{method: http.MethodPatch, path: "/social-medias/:id", id: "PatchSocialMedia", summary: "Change a social media link of yours",
+		auth: user, scope: model.SCOPE_SOCIAL_MEDIAS_WRITE, body: model.SocialMediaReq{}, status: http.StatusOK, res: model.SocialMediaUpdateRes{},
+		errors: []int{http.StatusBadRequest, http.StatusNotFound}, conditional: true},
+	{method: http.MethodDelete, path: "/social-medias/:id", id: "DeleteSocialMedia", summary: "Delete a social media link of yours",
+		auth: user, scope: model.SCOPE_SOCIAL_MEDIAS_WRITE, status: http.StatusOK, res: messageRes{}, errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+
+	// search and explore
+	{method: http.MethodGet, path: "/search", id: "Search", summary: "Search photos, users or hashtags, users need profile:read and the others photos:read",
+		auth: guest, query: model.SearchReq{}, status: http.StatusOK, res: oneOf{[]model.PhotoGetRes{}, []model.PublicProfile{}, []model.HashtagRes{}},
+		errors: []int{http.StatusBadRequest, http.StatusForbidden}},
+	{method: http.MethodGet, path: "/explore", id: "GetExplore", summary: "List the trending photos",
+		auth: guest, scope: model.SCOPE_PHOTOS_READ, query: model.ExploreReq{}, status: http.StatusOK, res: []model.PhotoGetRes{}, errors: []int{http.StatusBadRequest}},
+	{method: http.MethodGet, path: "/explore/tags", id: "GetTrendingHashtags", summary: "List the trending hashtags",
+		auth: guest, scope: model.SCOPE_PHOTOS_READ, query: model.ExploreReq{}, status: http.StatusOK, res: []model.TrendingHashtag{}, errors: []int{http.StatusBadRequest}},
+	{method: http.MethodGet, path: "/explore/tags/:tag", id: "GetHashtagExplore", summary: "List the trending photos with a hashtag",
+		auth: guest, scope: model.SCOPE_PHOTOS_READ, query: model.ExploreReq{}, status: http.StatusOK, res: []model.PhotoGetRes{}, errors: []int{http.StatusBadRequest}},
+}
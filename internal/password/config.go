@@ -0,0 +1,25 @@
+package password
+
+import (
+	"os"
+	"strconv"
+)
+
+// ParamsFromEnv reads PASSWORD_ARGON2_MEMORY_KIB, PASSWORD_ARGON2_ITERATIONS
+// and PASSWORD_ARGON2_PARALLELISM on top of DefaultArgon2idParams. Existing
+// hashes are upgraded to new parameters as users log in.
+func ParamsFromEnv() Argon2idParams {
+	params := DefaultArgon2idParams()
+	params.Memory = uint32(envUint("PASSWORD_ARGON2_MEMORY_KIB", uint64(params.Memory), 32))
+	params.Iterations = uint32(envUint("PASSWORD_ARGON2_ITERATIONS", uint64(params.Iterations), 32))
+	params.Parallelism = uint8(envUint("PASSWORD_ARGON2_PARALLELISM", uint64(params.Parallelism), 8))
+	return params
+}
+
+func envUint(key string, fallback uint64, bits int) uint64 {
+	v, err := strconv.ParseUint(os.Getenv(key), 10, bits)
+	if err != nil || v == 0 {
+		return fallback
+	}
+	return v
+}
@@ -0,0 +1,145 @@
+package password_test
+
+import (
+	"errors"
+	"mygram/internal/password"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// cheap keeps the tests fast, the format is the same at any cost.
+var cheap = password.Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestHasherRoundTrip(t *testing.T) {
+	hasher := password.NewHasher(cheap)
+
+	hash, err := hasher.Hash("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Fatalf("unexpected hash %q", hash)
+	}
+	if !hasher.Verify("correct horse battery staple", hash) {
+		t.Fatal("password does not verify against its hash")
+	}
+	if hasher.Verify("correct horse battery stapler", hash) {
+		t.Fatal("wrong password verified")
+	}
+	if hasher.NeedsRehash(hash) {
+		t.Fatal("fresh hash needs rehash")
+	}
+
+	other, _ := hasher.Hash("correct horse battery staple")
+	if other == hash {
+		t.Fatal("hashes are not salted")
+	}
+}
+
+// bcrypt only looked at the first 72 bytes
+func TestHasherDoesNotTruncate(t *testing.T) {
+	hasher := password.NewHasher(cheap)
+	long := strings.Repeat("a", 72)
+
+	hash, err := hasher.Hash(long + "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hasher.Verify(long+"c", hash) {
+		t.Fatal("bytes after the 72nd are ignored")
+	}
+}
+
+func TestHasherVerifiesBcrypt(t *testing.T) {
+	hasher := password.NewHasher(cheap)
+	legacy, err := bcrypt.GenerateFromPassword([]byte("secret123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !hasher.Verify("secret123", string(legacy)) {
+		t.Fatal("bcrypt hash does not verify")
+	}
+	if hasher.Verify("secret124", string(legacy)) {
+		t.Fatal("wrong password verified against bcrypt")
+	}
+	if !hasher.NeedsRehash(string(legacy)) {
+		t.Fatal("bcrypt hash should need rehash")
+	}
+}
+
+func TestHasherNeedsRehashOnNewParams(t *testing.T) {
+	hash, err := password.NewHasher(cheap).Hash("secret123")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stronger := cheap
+	stronger.Iterations = 2
+	hasher := password.NewHasher(stronger)
+	if !hasher.Verify("secret123", hash) {
+		t.Fatal("hash with older parameters does not verify")
+	}
+	if !hasher.NeedsRehash(hash) {
+		t.Fatal("hash with older parameters should need rehash")
+	}
+}
+
+func TestHasherRejectsMalformedHashes(t *testing.T) {
+	hasher := password.NewHasher(cheap)
+	for _, hash := range []string{
+		"",
+		"secret123",
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdA",
+		"$argon2id$v=16$m=64,t=1,p=1$c2FsdHNhbHRzYWx0$a2V5",
+		"$argon2i$v=19$m=64,t=1,p=1$c2FsdHNhbHRzYWx0$a2V5",
+		"$argon2id$v=19$m=x,t=1,p=1$c2FsdHNhbHRzYWx0$a2V5",
+	} {
+		if hasher.Verify("secret123", hash) {
+			t.Errorf("%q verified", hash)
+		}
+		if !hasher.NeedsRehash(hash) {
+			t.Errorf("%q should need rehash", hash)
+		}
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	policy := password.DefaultPolicy()
+	tests := map[string]error{
+		"harbor-sunset-42":             nil,
+		"correct horse battery staple": nil,
+		"sunflower":                    nil,
+		"short":                        password.ErrTooShort,
+		"abcdefghij":                   password.ErrTooWeak,
+		"aaaaaaaaaaaa":                 password.ErrTooWeak,
+		"1234567890":                   password.ErrTooWeak,
+		"password123":                  password.ErrBreached,
+		"Password123":                  password.ErrBreached,
+		"qwertyuiop":                   password.ErrBreached,
+	}
+
+	for pw, want := range tests {
+		err := policy.Check(pw)
+		if !errors.Is(err, want) || (want == nil && err != nil) {
+			t.Errorf("%q: expected %v, got %v", pw, want, err)
+		}
+		if want != nil && !password.IsPolicyViolation(err) {
+			t.Errorf("%q: %v is not a policy violation", pw, err)
+		}
+	}
+}
+
+func TestBreachedList(t *testing.T) {
+	list, err := password.NewBreachedList(strings.NewReader("# comment\nHunter2\n\n  letmein  \n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for pw, want := range map[string]bool{"hunter2": true, "HUNTER2": true, "letmein": true, "# comment": false, "": false} {
+		if got := list.Contains(pw); got != want {
+			t.Errorf("%q: expected %v, got %v", pw, want, got)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package password
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	ErrTooShort = errors.New("password is too short")
+	ErrTooWeak  = errors.New("password is too easy to guess")
+	ErrBreached = errors.New("password appears in a list of breached passwords, choose another one")
+)
+
+// IsPolicyViolation tells the errors of Policy.Check apart from failures
+// to check.
+func IsPolicyViolation(err error) bool {
+	return errors.Is(err, ErrTooShort) || errors.Is(err, ErrTooWeak) || errors.Is(err, ErrBreached)
+}
+
+// Policy decides which new passwords are accepted. It does not demand
+// character classes, a long password of lowercase words is fine.
+type Policy struct {
+	// MinLength counts characters, not bytes.
+	MinLength      int
+	MinEntropyBits float64
+	// Breached is skipped when nil.
+	Breached *BreachedList
+}
+
+func DefaultPolicy() Policy {
+	return Policy{
+		MinLength:      8,
+		MinEntropyBits: 30,
+		Breached:       DefaultBreachedList(),
+	}
+}
+
+func (p Policy) Check(password string) error {
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return fmt.Errorf("%w, use at least %d characters", ErrTooShort, p.MinLength)
+	}
+	if Entropy(password) < p.MinEntropyBits {
+		return ErrTooWeak
+	}
+	if p.Breached != nil && p.Breached.Contains(password) {
+		return ErrBreached
+	}
+	return nil
+}
+
+// Entropy estimates the bits of a password from the character classes it
+// draws from. Characters repeating one seen before count half, and ones
+// repeating or continuing the previous character (aaaa, abcd, 4321) a
+// quarter, so padding a short password with a pattern does not help much.
+func Entropy(password string) float64 {
+	var lower, upper, digit, symbol, other bool
+	seen := map[rune]bool{}
+	length := 0.0
+	prev := rune(-1)
+	for _, r := range password {
+		switch {
+		case r > unicode.MaxASCII:
+			other = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+
+		switch {
+		case r == prev || r == prev+1 || r == prev-1:
+			length += 0.25
+		case seen[r]:
+			length += 0.5
+		default:
+			length++
+		}
+		seen[r] = true
+		prev = r
+	}
+
+	pool := 0
+	for _, class := range []struct {
+		used bool
+		size int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if class.used {
+			pool += class.size
+		}
+	}
+	if pool == 0 {
+		return 0
+	}
+	return length * math.Log2(float64(pool))
+}
+
+//go:embed breached.txt
+var breachedTxt string
+
+// BreachedList is a set of known passwords, matched case-insensitively.
+type BreachedList struct {
+	passwords map[string]struct{}
+}
+
+// DefaultBreachedList is the list bundled with the binary, so checks work
+// offline.
+var DefaultBreachedList = sync.OnceValue(func() *BreachedList {
+	list, err := NewBreachedList(strings.NewReader(breachedTxt))
+	if err != nil {
+		panic(err)
+	}
+	return list
+})
+
+// NewBreachedList reads one password per line, lines starting with # are
+// comments.
+func NewBreachedList(r io.Reader) (*BreachedList, error) {
+	list := &BreachedList{passwords: map[string]struct{}{}}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list.passwords[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (b *BreachedList) Contains(password string) bool {
+	_, ok := b.passwords[strings.ToLower(password)]
+	return ok
+}
@@ -0,0 +1,83 @@
+package ranking
+
+import (
+	"context"
+	"log"
+	"mygram/internal/infrastructure"
+	"mygram/internal/model"
+	"mygram/internal/repository"
+	"time"
+)
+
+type JobConfig struct {
+	// Interval is how often the ranking is recomputed.
+	Interval time.Duration
+	// Window is how old photos can be to be ranked at all.
+	Window time.Duration
+}
+
+func DefaultJobConfig() JobConfig {
+	return JobConfig{
+		Interval: 10 * time.Minute,
+		Window:   7 * 24 * time.Hour,
+	}
+}
+
+// Job recomputes the explore ranking. Every instance may run one, each run
+// replaces the whole ranking in a single unit of work.
+type Job struct {
+	rankings repository.RankingQuery
+	uow      infrastructure.UnitOfWork
+	scorer   Scorer
+	cfg      JobConfig
+
+	// Now is the time scores are computed at.
+	Now func() time.Time
+}
+
+func NewJob(rankings repository.RankingQuery, uow infrastructure.UnitOfWork, scorer Scorer, cfg JobConfig) *Job {
+	return &Job{rankings: rankings, uow: uow, scorer: scorer, cfg: cfg, Now: time.Now}
+}
+
+// Refresh scores the photos of the window and replaces the ranking.
+func (j *Job) Refresh(ctx context.Context) error {
+	ctx, span := tracer.Start(ctx, "Job.Refresh")
+	defer span.End()
+
+	now := j.Now()
+	return j.uow.Do(ctx, func(ctx context.Context) error {
+		engagement, err := j.rankings.GetEngagement(ctx, now.Add(-j.cfg.Window))
+		if err != nil {
+			return err
+		}
+
+		rankings := make([]model.PhotoRanking, 0, len(engagement))
+		for _, e := range engagement {
+			rankings = append(rankings, model.PhotoRanking{
+				PhotoId:    e.PhotoId,
+				Score:      j.scorer.Score(e, now),
+				ComputedAt: now,
+			})
+		}
+		return j.rankings.ReplaceRankings(ctx, rankings)
+	})
+}
+
+// Run refreshes at once and then every interval until ctx is done. Failed
+// runs are logged, the previous ranking stays until the next run.
+func (j *Job) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := j.Refresh(ctx); err != nil {
+			log.Printf("refresh explore ranking: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,37 @@
+// Package ranking scores recent photos for explore. A Job recomputes the
+// scores periodically into the photo_rankings table, which explore reads
+// from.
+package ranking
+
+import (
+	"math"
+	"mygram/internal/model"
+	"time"
+)
+
+// Scorer scores a photo's engagement. now is passed in rather than read, so
+// scorers can be tested with a fixed clock.
+type Scorer interface {
+	Score(engagement model.PhotoEngagement, now time.Time) float64
+}
+
+// TimeDecay divides engagement by a power of the photo's age in hours, the
+// way Hacker News ranks stories. Every photo starts with one point, so new
+// photos without comments still show up.
+type TimeDecay struct {
+	// CommentWeight is what each comment adds to the starting point.
+	CommentWeight float64
+	// Gravity is how fast scores drop with age, above 1 old photos fall
+	// behind new ones whatever their comments.
+	Gravity float64
+}
+
+func DefaultTimeDecay() TimeDecay {
+	return TimeDecay{CommentWeight: 1, Gravity: 1.5}
+}
+
+func (t TimeDecay) Score(engagement model.PhotoEngagement, now time.Time) float64 {
+	age := max(now.Sub(engagement.CreatedAt).Hours(), 0)
+	points := 1 + t.CommentWeight*float64(engagement.Comments)
+	return points / math.Pow(age+2, t.Gravity)
+}
@@ -0,0 +1,69 @@
+package ranking_test
+
+import (
+	"context"
+	"math"
+	"mygram/internal/model"
+	"mygram/internal/ranking"
+	"mygram/internal/repository/memory"
+	"testing"
+	"time"
+)
+
+func TestTimeDecay(t *testing.T) {
+	decay := ranking.TimeDecay{CommentWeight: 1, Gravity: 1.5}
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	posted := func(ago time.Duration, comments int) model.PhotoEngagement {
+		return model.PhotoEngagement{CreatedAt: now.Add(-ago), Comments: comments}
+	}
+
+	if got, want := decay.Score(posted(2*time.Hour, 3), now), 4/math.Pow(4, 1.5); math.Abs(got-want) > 1e-9 {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if decay.Score(posted(time.Hour, 0), now) <= decay.Score(posted(2*time.Hour, 0), now) {
+		t.Fatal("expected newer photos to score higher")
+	}
+	if decay.Score(posted(time.Hour, 2), now) <= decay.Score(posted(time.Hour, 1), now) {
+		t.Fatal("expected comments to add to the score")
+	}
+	if decay.Score(posted(48*time.Hour, 10), now) >= decay.Score(posted(time.Hour, 0), now) {
+		t.Fatal("expected age to outweigh comments in the end")
+	}
+	// clocks of other instances may be ahead
+	if got, want := decay.Score(posted(-time.Minute, 0), now), decay.Score(posted(0, 0), now); got != want {
+		t.Fatalf("expected photos from the future scored as new, got %v, want %v", got, want)
+	}
+}
+
+func TestJobScoresAtItsClock(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := memory.NewStore()
+	store.Now = func() time.Time { return now }
+	photos := memory.NewPhotoQuery(store)
+	rankings := memory.NewRankingQuery(store)
+
+	owner, err := memory.NewUserQuery(store).CreateUser(ctx, model.User{Username: "alice", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ago := range []time.Duration{8 * 24 * time.Hour, 2 * time.Hour, 0} {
+		if _, err := photos.CreatePhoto(ctx, model.Photo{Title: "t", UserId: owner.ID, CreatedAt: now.Add(-ago)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	job := ranking.NewJob(rankings, memory.NewUnitOfWork(store), ranking.DefaultTimeDecay(), ranking.DefaultJobConfig())
+	job.Now = func() time.Time { return now }
+	if err := job.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	ranked, err := rankings.GetRankedPhotos(ctx, "", 10, 0, owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranked) != 2 || ranked[0].ID != 3 || ranked[1].ID != 2 {
+		t.Fatalf("expected photos 3 and 2 ranked, the first one is outside the window, got %+v", ranked)
+	}
+}
@@ -0,0 +1,5 @@
+package ranking
+
+import "go.opentelemetry.io/otel"
+
+var tracer = otel.Tracer("mygram/internal/ranking")
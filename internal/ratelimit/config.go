@@ -0,0 +1,84 @@
+package ratelimit
+
+import "time"
+
+// Route groups limits are configured for.
+const (
+	GroupLogin        = "login"
+	GroupAccountMail  = "account-mail"
+	GroupUsers        = "users"
+	GroupPhotos       = "photos"
+	GroupComments     = "comments"
+	GroupSocialMedias = "social-medias"
+	GroupPublic       = "public"
+	GroupSearch       = "search"
+	GroupExplore      = "explore"
+	GroupGraphQL      = "graphql"
+)
+
+type GroupConfig struct {
+	// IP limits every request by client address, before authentication.
+	IP Limit
+	// User limits write requests by the user ID in the token.
+	User Limit
+	// Email limits login attempts, and requests sending mail, by the
+	// submitted email.
+	Email Limit
+	// Guest limits requests made with the public token by client address,
+	// tighter than IP since anybody can get one.
+	Guest Limit
+}
+
+// Config maps a route group to its limits. Groups that are missing are not
+// limited.
+type Config map[string]GroupConfig
+
+func DefaultConfig() Config {
+	return Config{
+		GroupLogin: {
+			IP:    PerMinute(20),
+			Email: Limit{Requests: 10, Period: 15 * time.Minute},
+		},
+		GroupAccountMail: {
+			IP:    PerMinute(10),
+			Email: Limit{Requests: 3, Period: 15 * time.Minute},
+		},
+		GroupUsers: {
+			IP:    PerMinute(120),
+			User:  PerMinute(20),
+			Guest: PerMinute(30),
+		},
+		GroupPhotos: {
+			IP:    PerMinute(300),
+			User:  PerMinute(30),
+			Guest: PerMinute(60),
+		},
+		GroupComments: {
+			IP:    PerMinute(300),
+			User:  PerMinute(60),
+			Guest: PerMinute(60),
+		},
+		GroupSocialMedias: {
+			IP:    PerMinute(300),
+			User:  PerMinute(30),
+			Guest: PerMinute(30),
+		},
+		GroupPublic: {
+			IP: PerMinute(10),
+		},
+		GroupSearch: {
+			IP:    PerMinute(120),
+			Guest: PerMinute(30),
+		},
+		GroupExplore: {
+			IP:    PerMinute(120),
+			Guest: PerMinute(30),
+		},
+		// queries are POSTed too, so User counts reads as well as writes
+		GroupGraphQL: {
+			IP:    PerMinute(300),
+			User:  PerMinute(60),
+			Guest: PerMinute(60),
+		},
+	}
+}
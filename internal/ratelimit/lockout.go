@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// LockoutPolicy locks an account once Threshold consecutive failures
+// happened within Window. The lock starts at BaseDelay and doubles with every
+// further failure, up to MaxDelay.
+type LockoutPolicy struct {
+	Threshold int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	Window    time.Duration
+}
+
+func DefaultLockoutPolicy() LockoutPolicy {
+	return LockoutPolicy{
+		Threshold: 5,
+		BaseDelay: time.Minute,
+		MaxDelay:  time.Hour,
+		Window:    24 * time.Hour,
+	}
+}
+
+func (p LockoutPolicy) lockedUntil(failures int, now time.Time) time.Time {
+	if failures < p.Threshold {
+		return time.Time{}
+	}
+
+	delay := p.BaseDelay
+	for i := p.Threshold; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return now.Add(delay)
+}
+
+type Lock struct {
+	Failures    int
+	LockedUntil time.Time
+}
+
+func (l Lock) Locked(now time.Time) bool {
+	return now.Before(l.LockedUntil)
+}
+
+type LockoutStore interface {
+	GetLock(ctx context.Context, key string) (Lock, error)
+	RecordFailure(ctx context.Context, key string, policy LockoutPolicy) (Lock, error)
+	ResetFailures(ctx context.Context, key string) error
+}
+
+// Lockout tracks failed sign-ins per account key, typically the login email.
+type Lockout struct {
+	store  LockoutStore
+	policy LockoutPolicy
+	now    func() time.Time
+}
+
+func NewLockout(store LockoutStore, policy LockoutPolicy) *Lockout {
+	return &Lockout{store: store, policy: policy, now: time.Now}
+}
+
+// Check returns how long the account stays locked, zero if it is not.
+func (l *Lockout) Check(ctx context.Context, key string) (time.Duration, error) {
+	lock, err := l.store.GetLock(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	now := l.now()
+	if !lock.Locked(now) {
+		return 0, nil
+	}
+	return lock.LockedUntil.Sub(now), nil
+}
+
+func (l *Lockout) Fail(ctx context.Context, key string) error {
+	_, err := l.store.RecordFailure(ctx, key, l.policy)
+	return err
+}
+
+func (l *Lockout) Succeed(ctx context.Context, key string) error {
+	return l.store.ResetFailures(ctx, key)
+}
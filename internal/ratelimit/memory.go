@@ -0,0 +1,114 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	// expires is when the bucket is full again and can be forgotten.
+	expires time.Time
+}
+
+type lockEntry struct {
+	failures    int
+	lockedUntil time.Time
+	expires     time.Time
+}
+
+// MemoryStore keeps buckets and lockouts in process. Limits are per
+// instance, use RedisStore when running more than one.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]bucket
+	locks   map[string]lockEntry
+	sweep   time.Time
+
+	Now func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: map[string]bucket{},
+		locks:   map[string]lockEntry{},
+		Now:     time.Now,
+	}
+}
+
+func (m *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.Now()
+	m.gc(now)
+
+	b := m.buckets[key]
+	if !b.expires.IsZero() && now.After(b.expires) {
+		b = bucket{}
+	}
+	res, tokens := take(limit, b.tokens, b.last, now)
+	m.buckets[key] = bucket{tokens: tokens, last: now, expires: now.Add(res.Reset)}
+	return res, nil
+}
+
+func (m *MemoryStore) GetLock(ctx context.Context, key string) (Lock, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.Now()
+	e, ok := m.locks[key]
+	if !ok || now.After(e.expires) {
+		return Lock{}, nil
+	}
+	return Lock{Failures: e.failures, LockedUntil: e.lockedUntil}, nil
+}
+
+func (m *MemoryStore) RecordFailure(ctx context.Context, key string, policy LockoutPolicy) (Lock, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.Now()
+	e := m.locks[key]
+	if now.After(e.expires) {
+		e = lockEntry{}
+	}
+	e.failures++
+	e.lockedUntil = policy.lockedUntil(e.failures, now)
+	e.expires = now.Add(policy.Window)
+	if e.lockedUntil.After(e.expires) {
+		e.expires = e.lockedUntil
+	}
+	m.locks[key] = e
+	return Lock{Failures: e.failures, LockedUntil: e.lockedUntil}, nil
+}
+
+func (m *MemoryStore) ResetFailures(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.locks, key)
+	return nil
+}
+
+// gc drops expired entries at most once a minute, so the maps do not grow
+// with every address that ever made a request.
+func (m *MemoryStore) gc(now time.Time) {
+	if now.Before(m.sweep) {
+		return
+	}
+	m.sweep = now.Add(time.Minute)
+
+	for key, b := range m.buckets {
+		if now.After(b.expires) {
+			delete(m.buckets, key)
+		}
+	}
+	for key, e := range m.locks {
+		if now.After(e.expires) {
+			delete(m.locks, key)
+		}
+	}
+}
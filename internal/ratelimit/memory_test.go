@@ -0,0 +1,73 @@
+package ratelimit_test
+
+import (
+	"context"
+	"mygram/internal/ratelimit"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreRefillsOverTime(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := ratelimit.NewMemoryStore()
+	store.Now = func() time.Time { return now }
+	limit := ratelimit.PerMinute(2)
+
+	for i := 0; i < 2; i++ {
+		res, err := store.Take(ctx, "k", limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !res.Allowed {
+			t.Fatalf("request %d should be allowed", i+1)
+		}
+	}
+
+	res, _ := store.Take(ctx, "k", limit)
+	if res.Allowed {
+		t.Fatal("third request should be denied")
+	}
+	if res.RetryAfter != 30*time.Second {
+		t.Fatalf("expected retry after 30s, got %v", res.RetryAfter)
+	}
+
+	now = now.Add(30 * time.Second)
+	res, _ = store.Take(ctx, "k", limit)
+	if !res.Allowed {
+		t.Fatal("request after refill should be allowed")
+	}
+	if res.Remaining != 0 {
+		t.Fatalf("expected 0 remaining, got %d", res.Remaining)
+	}
+}
+
+func TestLockoutGrowsProgressively(t *testing.T) {
+	ctx := context.Background()
+	store := ratelimit.NewMemoryStore()
+	policy := ratelimit.LockoutPolicy{Threshold: 2, BaseDelay: time.Minute, MaxDelay: 3 * time.Minute, Window: time.Hour}
+
+	expected := []time.Duration{0, time.Minute, 2 * time.Minute, 3 * time.Minute}
+	for i, want := range expected {
+		now := time.Now()
+		lock, err := store.RecordFailure(ctx, "alice", policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := time.Duration(0)
+		if !lock.LockedUntil.IsZero() {
+			got = lock.LockedUntil.Sub(now).Round(time.Minute)
+		}
+		if got != want {
+			t.Fatalf("failure %d: expected lock %v, got %v", i+1, want, got)
+		}
+	}
+
+	if err := store.ResetFailures(ctx, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	lock, _ := store.GetLock(ctx, "alice")
+	if lock.Failures != 0 {
+		t.Fatalf("expected reset, got %d failures", lock.Failures)
+	}
+}
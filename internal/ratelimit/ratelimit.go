@@ -0,0 +1,98 @@
+// Package ratelimit implements token bucket rate limiting and progressive
+// account lockout, with an in-memory backend for a single instance and a
+// Redis backend shared by every instance.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit allows Requests per Period on average with bursts of up to Burst
+// requests. Burst defaults to Requests. The zero Limit disables limiting.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+func PerMinute(requests int) Limit {
+	return Limit{Requests: requests, Period: time.Minute}
+}
+
+func PerHour(requests int) Limit {
+	return Limit{Requests: requests, Period: time.Hour}
+}
+
+func (l Limit) Disabled() bool {
+	return l.Requests <= 0 || l.Period <= 0
+}
+
+func (l Limit) capacity() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return float64(l.Requests)
+}
+
+// rate is the refill speed in tokens per second.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next request would be allowed, zero
+	// when Allowed.
+	RetryAfter time.Duration
+}
+
+// Store keeps the buckets. Take removes one token from the bucket at key.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// take applies the token bucket algorithm to a bucket last seen at last
+// holding tokens. It is shared by the backends so they agree on the math.
+func take(limit Limit, tokens float64, last, now time.Time) (Result, float64) {
+	capacity := limit.capacity()
+	rate := limit.rate()
+
+	if last.IsZero() {
+		tokens = capacity
+	} else if elapsed := now.Sub(last).Seconds(); elapsed > 0 {
+		tokens = math.Min(capacity, tokens+elapsed*rate)
+	}
+
+	allowed := tokens >= 1
+	if allowed {
+		tokens--
+	}
+	return newResult(limit, tokens, allowed), tokens
+}
+
+// newResult describes a bucket holding tokens after the request was counted.
+func newResult(limit Limit, tokens float64, allowed bool) Result {
+	capacity := limit.capacity()
+	rate := limit.rate()
+
+	res := Result{
+		Allowed:   allowed,
+		Limit:     int(capacity),
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((capacity - tokens) / rate),
+	}
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
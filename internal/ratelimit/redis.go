@@ -0,0 +1,114 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// takeScript refills and takes from the bucket atomically, using the Redis
+// clock so instances with skewed clocks agree.
+var takeScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) + tonumber(t[2]) / 1000000
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil then
+	tokens = capacity
+	ts = now
+end
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil((capacity - tokens) / rate * 1000) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// RedisStore shares buckets and lockouts between every instance pointing at
+// the same Redis.
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{client: client, prefix: "mygram:ratelimit:"}
+}
+
+func (r *RedisStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	res, err := takeScript.Run(ctx, r.client, []string{r.prefix + "bucket:" + key},
+		limit.capacity(), limit.rate()).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+
+	allowed, _ := res[0].(int64)
+	tokensStr, _ := res[1].(string)
+	tokens, err := strconv.ParseFloat(tokensStr, 64)
+	if err != nil {
+		return Result{}, err
+	}
+	return newResult(limit, tokens, allowed == 1), nil
+}
+
+func (r *RedisStore) GetLock(ctx context.Context, key string) (Lock, error) {
+	vals, err := r.client.HMGet(ctx, r.prefix+"lock:"+key, "failures", "locked_until").Result()
+	if err != nil {
+		return Lock{}, err
+	}
+	return parseLock(vals), nil
+}
+
+func (r *RedisStore) RecordFailure(ctx context.Context, key string, policy LockoutPolicy) (Lock, error) {
+	k := r.prefix + "lock:" + key
+
+	failures, err := r.client.HIncrBy(ctx, k, "failures", 1).Result()
+	if err != nil {
+		return Lock{}, err
+	}
+
+	now := time.Now()
+	lock := Lock{Failures: int(failures), LockedUntil: policy.lockedUntil(int(failures), now)}
+	ttl := policy.Window
+	if until := lock.LockedUntil.Sub(now); until > ttl {
+		ttl = until
+	}
+
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if !lock.LockedUntil.IsZero() {
+			pipe.HSet(ctx, k, "locked_until", lock.LockedUntil.UnixMilli())
+		}
+		pipe.PExpire(ctx, k, ttl)
+		return nil
+	})
+	return lock, err
+}
+
+func (r *RedisStore) ResetFailures(ctx context.Context, key string) error {
+	return r.client.Del(ctx, r.prefix+"lock:"+key).Err()
+}
+
+func parseLock(vals []any) Lock {
+	lock := Lock{}
+	if s, ok := vals[0].(string); ok {
+		lock.Failures, _ = strconv.Atoi(s)
+	}
+	if s, ok := vals[1].(string); ok {
+		if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+			lock.LockedUntil = time.UnixMilli(ms)
+		}
+	}
+	return lock
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"mygram/internal/infrastructure"
+	"mygram/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type BlockQuery interface {
+	CreateBlock(ctx context.Context, block model.Block) (model.Block, error)
+	GetBlock(ctx context.Context, blockerId uint64, blockedId uint64) (model.Block, error)
+	// DeleteBlock reports false when there was no block.
+	DeleteBlock(ctx context.Context, blockerId uint64, blockedId uint64) (bool, error)
+	// IsBlocked tells whether either user blocked the other.
+	IsBlocked(ctx context.Context, userId uint64, otherId uint64) (bool, error)
+	GetBlocks(ctx context.Context, blockerId uint64) ([]model.ListedUser, error)
+}
+
+type blockQueryImpl struct {
+	db infrastructure.GormPostgres
+}
+
+func NewBlockQuery(db infrastructure.GormPostgres) BlockQuery {
+	return &blockQueryImpl{db: db}
+}
+
+func (b *blockQueryImpl) CreateBlock(ctx context.Context, block model.Block) (model.Block, error) {
+	ctx, span := tracer.Start(ctx, "BlockQuery.CreateBlock")
+	defer span.End()
+
+	db := b.db.GetWriteConnection(ctx)
+	if err := db.
+		WithContext(ctx).
+		Table("blocks").
+		Create(&block).Error; err != nil {
+		return model.Block{}, err
+	}
+	return block, nil
+}
+
+func (b *blockQueryImpl) GetBlock(ctx context.Context, blockerId uint64, blockedId uint64) (model.Block, error) {
+	ctx, span := tracer.Start(ctx, "BlockQuery.GetBlock")
+	defer span.End()
+
+	db := b.db.GetReadConnection(ctx)
+	block := model.Block{}
+	if err := db.
+		WithContext(ctx).
+		Table("blocks").
+		Where("blocker_id = ?", blockerId).
+		Where("blocked_id = ?", blockedId).
+		Find(&block).Error; err != nil {
+		return model.Block{}, err
+	}
+	return block, nil
+}
+
+func (b *blockQueryImpl) DeleteBlock(ctx context.Context, blockerId uint64, blockedId uint64) (bool, error) {
+	ctx, span := tracer.Start(ctx, "BlockQuery.DeleteBlock")
+	defer span.End()
+
+	db := b.db.GetWriteConnection(ctx)
+	res := db.
+		WithContext(ctx).
+		Table("blocks").
+		Where("blocker_id = ?", blockerId).
+		Where("blocked_id = ?", blockedId).
+		Delete(&model.Block{})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (b *blockQueryImpl) IsBlocked(ctx context.Context, userId uint64, otherId uint64) (bool, error) {
+	ctx, span := tracer.Start(ctx, "BlockQuery.IsBlocked")
+	defer span.End()
+
+	db := b.db.GetReadConnection(ctx)
+	var count int64
+	if err := db.
+		WithContext(ctx).
+		Table("blocks").
+		Where("(blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)", userId, otherId, otherId, userId).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (b *blockQueryImpl) GetBlocks(ctx context.Context, blockerId uint64) ([]model.ListedUser, error) {
+	ctx, span := tracer.Start(ctx, "BlockQuery.GetBlocks")
+	defer span.End()
+
+	db := b.db.GetReadConnection(ctx)
+	blocks := []model.ListedUser{}
+	if err := db.
+		WithContext(ctx).
+		Table("blocks").
+		Select("blocked_id AS user_id, created_at").
+		Where("blocker_id = ?", blockerId).
+		Where("blocked_id IN (SELECT id FROM users WHERE deleted_at IS NULL)").
+		Order("created_at DESC, blocked_id ASC").
+		Preload("User", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, username").Table("users").Where("deleted_at is null")
+		}).
+		Find(&blocks).Error; err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
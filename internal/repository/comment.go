@@ -4,16 +4,29 @@ import (
 	"context"
 	"mygram/internal/infrastructure"
 	"mygram/internal/model"
-
-	"gorm.io/gorm"
+	"time"
 )
 
 type CommentQuery interface {
 	CreateComment(ctx context.Context, comment model.Comment) (model.Comment, error)
-	GetCommentsByPhotoId(ctx context.Context, photoId uint64) ([]model.CommentGetRes, error)
+	// GetCommentsByPhotoId, GetCommentById and GetExpandedComment only
+	// return comments viewerId may see, see visibleComment. The list also
+	// leaves out users viewerId muted.
+	GetCommentsByPhotoId(ctx context.Context, photoId uint64, viewerId uint64, expand model.Expand) ([]model.CommentGetRes, error)
 	EditComment(ctx context.Context, comment model.Comment) error
-	GetCommentById(ctx context.Context, id uint64) (model.Comment, error)
+	// EditCommentIfUnmodified is EditComment for a comment last updated at
+	// updatedAt. It reports false and changes nothing when the comment was
+	// updated since.
+	EditCommentIfUnmodified(ctx context.Context, comment model.Comment, updatedAt time.Time) (bool, error)
+	GetCommentById(ctx context.Context, id uint64, viewerId uint64) (model.Comment, error)
+	GetExpandedComment(ctx context.Context, id uint64, viewerId uint64, expand model.Expand) (model.CommentGetRes, error)
 	DeleteComment(ctx context.Context, id uint64) error
+	// DeleteCommentsByUserId deletes the comments of userId and every
+	// comment on the photos of userId.
+	DeleteCommentsByUserId(ctx context.Context, userId uint64) error
+	// GetCommentsByPhotoIds is GetCommentsByPhotoId for several photos at
+	// once, without relations, ordered by ID.
+	GetCommentsByPhotoIds(ctx context.Context, photoIds []uint64, viewerId uint64) ([]model.CommentGetRes, error)
 }
 
 type commentQueryImpl struct {
@@ -25,7 +38,10 @@ func NewCommentQuery(db infrastructure.GormPostgres) CommentQuery {
 }
 
 func (c *commentQueryImpl) CreateComment(ctx context.Context, comment model.Comment) (model.Comment, error) {
-	db := c.db.GetConnection()
+	ctx, span := tracer.Start(ctx, "CommentQuery.CreateComment")
+	defer span.End()
+
+	db := c.db.GetWriteConnection(ctx)
 
 	if err := db.
 		WithContext(ctx).
@@ -36,21 +52,21 @@ func (c *commentQueryImpl) CreateComment(ctx context.Context, comment model.Comm
 	return comment, nil
 }
 
-func (c *commentQueryImpl) GetCommentsByPhotoId(ctx context.Context, photoId uint64) ([]model.CommentGetRes, error) {
-	db := c.db.GetConnection()
+func (c *commentQueryImpl) GetCommentsByPhotoId(ctx context.Context, photoId uint64, viewerId uint64, expand model.Expand) ([]model.CommentGetRes, error) {
+	ctx, span := tracer.Start(ctx, "CommentQuery.GetCommentsByPhotoId")
+	defer span.End()
+
+	db := c.db.GetReadConnection(ctx)
 	comments := []model.CommentGetRes{}
 
-	if err := db.
+	query := db.
 		WithContext(ctx).
 		Table("comments").
 		Where("photo_id = ?", photoId).
 		Where("deleted_at IS NULL").
-		Preload("User", func(db *gorm.DB) *gorm.DB {
-			return db.Select("id, email, username").Table("users").Where("deleted_at is null")
-		}).
-		Preload("Photo", func(db *gorm.DB) *gorm.DB {
-			return db.Select("id, title, caption, url, user_id").Table("photos").Where("deleted_at is null")
-		}).
+		Where(visibleComment(), viewer(viewerId)).
+		Where(notMuted("comments.user_id"), viewer(viewerId))
+	if err := expandComments(query, expand, viewerId).
 		Find(&comments).
 		Error; err != nil {
 		return nil, err
@@ -60,7 +76,10 @@ func (c *commentQueryImpl) GetCommentsByPhotoId(ctx context.Context, photoId uin
 }
 
 func (c *commentQueryImpl) EditComment(ctx context.Context, comment model.Comment) error {
-	db := c.db.GetConnection()
+	ctx, span := tracer.Start(ctx, "CommentQuery.EditComment")
+	defer span.End()
+
+	db := c.db.GetWriteConnection(ctx)
 	if err := db.
 		WithContext(ctx).
 		Table("comments").
@@ -72,8 +91,28 @@ func (c *commentQueryImpl) EditComment(ctx context.Context, comment model.Commen
 	return nil
 }
 
-func (c *commentQueryImpl) GetCommentById(ctx context.Context, id uint64) (model.Comment, error) {
-	db := c.db.GetConnection()
+func (c *commentQueryImpl) EditCommentIfUnmodified(ctx context.Context, comment model.Comment, updatedAt time.Time) (bool, error) {
+	ctx, span := tracer.Start(ctx, "CommentQuery.EditCommentIfUnmodified")
+	defer span.End()
+
+	db := c.db.GetWriteConnection(ctx)
+	res := db.
+		WithContext(ctx).
+		Table("comments").
+		Where("updated_at = ?", updatedAt).
+		Where("deleted_at IS NULL").
+		Updates(&comment)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (c *commentQueryImpl) GetCommentById(ctx context.Context, id uint64, viewerId uint64) (model.Comment, error) {
+	ctx, span := tracer.Start(ctx, "CommentQuery.GetCommentById")
+	defer span.End()
+
+	db := c.db.GetReadConnection(ctx)
 	comment := model.Comment{}
 
 	if err := db.
@@ -81,6 +120,7 @@ func (c *commentQueryImpl) GetCommentById(ctx context.Context, id uint64) (model
 		Table("comments").
 		Where("id = ?", id).
 		Where("deleted_at IS NULL").
+		Where(visibleComment(), viewer(viewerId)).
 		Find(&comment).
 		Error; err != nil {
 		return model.Comment{}, err
@@ -89,8 +129,32 @@ func (c *commentQueryImpl) GetCommentById(ctx context.Context, id uint64) (model
 	return comment, nil
 }
 
+func (c *commentQueryImpl) GetExpandedComment(ctx context.Context, id uint64, viewerId uint64, expand model.Expand) (model.CommentGetRes, error) {
+	ctx, span := tracer.Start(ctx, "CommentQuery.GetExpandedComment")
+	defer span.End()
+
+	db := c.db.GetReadConnection(ctx)
+	comment := model.CommentGetRes{}
+	query := db.
+		WithContext(ctx).
+		Table("comments").
+		Where("id = ?", id).
+		Where("deleted_at IS NULL").
+		Where(visibleComment(), viewer(viewerId))
+	if err := expandComments(query, expand, viewerId).
+		Find(&comment).
+		Error; err != nil {
+		return model.CommentGetRes{}, err
+	}
+
+	return comment, nil
+}
+
 func (c *commentQueryImpl) DeleteComment(ctx context.Context, id uint64) error {
-	db := c.db.GetConnection()
+	ctx, span := tracer.Start(ctx, "CommentQuery.DeleteComment")
+	defer span.End()
+
+	db := c.db.GetWriteConnection(ctx)
 
 	if err := db.
 		WithContext(ctx).
@@ -102,3 +166,43 @@ func (c *commentQueryImpl) DeleteComment(ctx context.Context, id uint64) error {
 
 	return nil
 }
+
+func (c *commentQueryImpl) DeleteCommentsByUserId(ctx context.Context, userId uint64) error {
+	ctx, span := tracer.Start(ctx, "CommentQuery.DeleteCommentsByUserId")
+	defer span.End()
+
+	db := c.db.GetWriteConnection(ctx)
+	photos := db.Table("photos").Select("id").Where("user_id = ?", userId)
+	return db.
+		WithContext(ctx).
+		Table("comments").
+		Where("user_id = ? OR photo_id IN (?)", userId, photos).
+		Delete(&model.Comment{}).
+		Error
+}
+
+func (c *commentQueryImpl) GetCommentsByPhotoIds(ctx context.Context, photoIds []uint64, viewerId uint64) ([]model.CommentGetRes, error) {
+	ctx, span := tracer.Start(ctx, "CommentQuery.GetCommentsByPhotoIds")
+	defer span.End()
+
+	db := c.db.GetReadConnection(ctx)
+	comments := []model.CommentGetRes{}
+	if len(photoIds) == 0 {
+		return comments, nil
+	}
+
+	if err := db.
+		WithContext(ctx).
+		Table("comments").
+		Where("photo_id IN ?", photoIds).
+		Where("deleted_at IS NULL").
+		Where(visibleComment(), viewer(viewerId)).
+		Where(notMuted("comments.user_id"), viewer(viewerId)).
+		Order("id").
+		Find(&comments).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
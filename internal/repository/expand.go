@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"mygram/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// userRelation loads what UserRelation holds of users that still exist.
+func userRelation(db *gorm.DB) *gorm.DB {
+	return db.Select("id, email, username").Table("users").Where("deleted_at is null")
+}
+
+// expandPhotos preloads the relations of PhotoGetRes that expand asks for.
+// Comments follow the same rules as GetCommentsByPhotoId.
+func expandPhotos(db *gorm.DB, expand model.Expand, viewerId uint64) *gorm.DB {
+	if expand.User {
+		db = db.Preload("User", userRelation)
+	}
+	if expand.Comments {
+		db = db.
+			Preload("Comments", func(db *gorm.DB) *gorm.DB {
+				return db.Table("comments").
+					Where("deleted_at IS NULL").
+					Where(visibleComment(), viewer(viewerId)).
+					Where(notMuted("comments.user_id"), viewer(viewerId)).
+					Order("id")
+			}).
+			Preload("Comments.User", userRelation)
+	}
+	return db
+}
+
+// expandComments preloads the relations of CommentGetRes that expand asks
+// for.
+func expandComments(db *gorm.DB, expand model.Expand, viewerId uint64) *gorm.DB {
+	if expand.User {
+		db = db.Preload("User", userRelation)
+	}
+	if expand.Photo {
+		// the viewer's own comment can sit under a photo they no longer see
+		db = db.Preload("Photo", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, title, caption, url, user_id").Table("photos").Where("deleted_at is null").
+				Where(visibleOwner("photos.user_id"), viewer(viewerId))
+		})
+	}
+	return db
+}
+
+// expandSocialMedias preloads the relations of SocialMediaGetRes that
+// expand asks for.
+func expandSocialMedias(db *gorm.DB, expand model.Expand) *gorm.DB {
+	if expand.User {
+		db = db.Preload("User", userRelation)
+	}
+	return db
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"mygram/internal/infrastructure"
+	"mygram/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type FollowQuery interface {
+	CreateFollow(ctx context.Context, follow model.Follow) (model.Follow, error)
+	GetFollow(ctx context.Context, followerId uint64, followeeId uint64) (model.Follow, error)
+	// DeleteFollow reports false when there was no follow or request.
+	DeleteFollow(ctx context.Context, followerId uint64, followeeId uint64) (bool, error)
+	// ApproveFollow reports false when there was no pending request.
+	ApproveFollow(ctx context.Context, followerId uint64, followeeId uint64, approvedAt time.Time) (bool, error)
+	// ApproveAllFollows approves every pending request to followeeId.
+	ApproveAllFollows(ctx context.Context, followeeId uint64, approvedAt time.Time) error
+	GetPendingFollows(ctx context.Context, followeeId uint64) ([]model.FollowRequest, error)
+}
+
+type followQueryImpl struct {
+	db infrastructure.GormPostgres
+}
+
+func NewFollowQuery(db infrastructure.GormPostgres) FollowQuery {
+	return &followQueryImpl{db: db}
+}
+
+func (f *followQueryImpl) CreateFollow(ctx context.Context, follow model.Follow) (model.Follow, error) {
+	ctx, span := tracer.Start(ctx, "FollowQuery.CreateFollow")
+	defer span.End()
+
+	db := f.db.GetWriteConnection(ctx)
+	if err := db.
+		WithContext(ctx).
+		Table("follows").
+		Create(&follow).Error; err != nil {
+		return model.Follow{}, err
+	}
+	return follow, nil
+}
+
+func (f *followQueryImpl) GetFollow(ctx context.Context, followerId uint64, followeeId uint64) (model.Follow, error) {
+	ctx, span := tracer.Start(ctx, "FollowQuery.GetFollow")
+	defer span.End()
+
+	db := f.db.GetReadConnection(ctx)
+	follow := model.Follow{}
+	if err := db.
+		WithContext(ctx).
+		Table("follows").
+		Where("follower_id = ?", followerId).
+		Where("followee_id = ?", followeeId).
+		Find(&follow).Error; err != nil {
+		return model.Follow{}, err
+	}
+	return follow, nil
+}
+
+func (f *followQueryImpl) DeleteFollow(ctx context.Context, followerId uint64, followeeId uint64) (bool, error) {
+	ctx, span := tracer.Start(ctx, "FollowQuery.DeleteFollow")
+	defer span.End()
+
+	db := f.db.GetWriteConnection(ctx)
+	res := db.
+		WithContext(ctx).
+		Table("follows").
+		Where("follower_id = ?", followerId).
+		Where("followee_id = ?", followeeId).
+		Delete(&model.Follow{})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (f *followQueryImpl) ApproveFollow(ctx context.Context, followerId uint64, followeeId uint64, approvedAt time.Time) (bool, error) {
+	ctx, span := tracer.Start(ctx, "FollowQuery.ApproveFollow")
+	defer span.End()
+
+	db := f.db.GetWriteConnection(ctx)
+	res := db.
+		WithContext(ctx).
+		Table("follows").
+		Where("follower_id = ?", followerId).
+		Where("followee_id = ?", followeeId).
+		Where("approved_at IS NULL").
+		Update("approved_at", approvedAt)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (f *followQueryImpl) ApproveAllFollows(ctx context.Context, followeeId uint64, approvedAt time.Time) error {
+	ctx, span := tracer.Start(ctx, "FollowQuery.ApproveAllFollows")
+	defer span.End()
+
+	db := f.db.GetWriteConnection(ctx)
+	return db.
+		WithContext(ctx).
+		Table("follows").
+		Where("followee_id = ?", followeeId).
+		Where("approved_at IS NULL").
+		Update("approved_at", approvedAt).
+		Error
+}
+
+func (f *followQueryImpl) GetPendingFollows(ctx context.Context, followeeId uint64) ([]model.FollowRequest, error) {
+	ctx, span := tracer.Start(ctx, "FollowQuery.GetPendingFollows")
+	defer span.End()
+
+	db := f.db.GetReadConnection(ctx)
+	requests := []model.FollowRequest{}
+	if err := db.
+		WithContext(ctx).
+		Table("follows").
+		Select("follower_id, created_at").
+		Where("followee_id = ?", followeeId).
+		Where("approved_at IS NULL").
+		Where("follower_id IN (SELECT id FROM users WHERE deleted_at IS NULL)").
+		Order("created_at ASC, follower_id ASC").
+		Preload("User", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, username").Table("users").Where("deleted_at is null")
+		}).
+		Find(&requests).Error; err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"mygram/internal/infrastructure"
+	"mygram/internal/model"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyKeyQuery interface {
+	// ReserveKey stores key and reports true, unless the user already has
+	// it. The user's keys expired at now are dropped first, and a lock left
+	// past its time by a request that never finished is taken over by one
+	// with the same fingerprint. Otherwise the stored key is returned.
+	ReserveKey(ctx context.Context, key model.IdempotencyKey, now time.Time) (model.IdempotencyKey, bool, error)
+	CompleteKey(ctx context.Context, id uint64, res model.IdempotentResponse, completedAt time.Time) error
+	// ReleaseKey drops a key whose request failed, so a retry runs again.
+	ReleaseKey(ctx context.Context, id uint64) error
+	// DeleteExpiredKeys drops the keys of every user expired at now and
+	// returns how many.
+	DeleteExpiredKeys(ctx context.Context, now time.Time) (int64, error)
+}
+
+type idempotencyKeyQueryImpl struct {
+	db infrastructure.GormPostgres
+}
+
+func NewIdempotencyKeyQuery(db infrastructure.GormPostgres) IdempotencyKeyQuery {
+	return &idempotencyKeyQueryImpl{db: db}
+}
+
+func (i *idempotencyKeyQueryImpl) ReserveKey(ctx context.Context, key model.IdempotencyKey, now time.Time) (model.IdempotencyKey, bool, error) {
+	ctx, span := tracer.Start(ctx, "IdempotencyKeyQuery.ReserveKey")
+	defer span.End()
+
+	db := i.db.GetWriteConnection(ctx)
+	if err := db.
+		WithContext(ctx).
+		Table("idempotency_keys").
+		Where("user_id = ?", key.UserId).
+		Where("expires_at <= ?", now).
+		Delete(&model.IdempotencyKey{}).Error; err != nil {
+		return model.IdempotencyKey{}, false, err
+	}
+
+	res := db.
+		WithContext(ctx).
+		Table("idempotency_keys").
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&key)
+	if res.Error != nil {
+		return model.IdempotencyKey{}, false, res.Error
+	}
+	if res.RowsAffected == 1 {
+		return key, true, nil
+	}
+
+	res = db.
+		WithContext(ctx).
+		Table("idempotency_keys").
+		Where("user_id = ?", key.UserId).
+		Where("key = ?", key.Key).
+		Where("fingerprint = ?", key.Fingerprint).
+		Where("completed_at IS NULL").
+		Where("locked_until <= ?", now).
+		Update("locked_until", key.LockedUntil)
+	if res.Error != nil {
+		return model.IdempotencyKey{}, false, res.Error
+	}
+	taken := res.RowsAffected == 1
+
+	stored := model.IdempotencyKey{}
+	if err := db.
+		WithContext(ctx).
+		Table("idempotency_keys").
+		Where("user_id = ?", key.UserId).
+		Where("key = ?", key.Key).
+		Find(&stored).Error; err != nil {
+		return model.IdempotencyKey{}, false, err
+	}
+	return stored, taken, nil
+}
+
+func (i *idempotencyKeyQueryImpl) CompleteKey(ctx context.Context, id uint64, res model.IdempotentResponse, completedAt time.Time) error {
+	ctx, span := tracer.Start(ctx, "IdempotencyKeyQuery.CompleteKey")
+	defer span.End()
+
+	db := i.db.GetWriteConnection(ctx)
+	return db.
+		WithContext(ctx).
+		Table("idempotency_keys").
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"response_status": res.Status,
+			"response_type":   res.ContentType,
+			"response_body":   res.Body,
+			"completed_at":    completedAt,
+		}).
+		Error
+}
+
+func (i *idempotencyKeyQueryImpl) ReleaseKey(ctx context.Context, id uint64) error {
+	ctx, span := tracer.Start(ctx, "IdempotencyKeyQuery.ReleaseKey")
+	defer span.End()
+
+	db := i.db.GetWriteConnection(ctx)
+	return db.
+		WithContext(ctx).
+		Table("idempotency_keys").
+		Where("id = ?", id).
+		Where("completed_at IS NULL").
+		Delete(&model.IdempotencyKey{}).
+		Error
+}
+
+func (i *idempotencyKeyQueryImpl) DeleteExpiredKeys(ctx context.Context, now time.Time) (int64, error) {
+	ctx, span := tracer.Start(ctx, "IdempotencyKeyQuery.DeleteExpiredKeys")
+	defer span.End()
+
+	db := i.db.GetWriteConnection(ctx)
+	res := db.
+		WithContext(ctx).
+		Table("idempotency_keys").
+		Where("expires_at <= ?", now).
+		Delete(&model.IdempotencyKey{})
+	return res.RowsAffected, res.Error
+}
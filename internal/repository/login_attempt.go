@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"mygram/internal/infrastructure"
+	"mygram/internal/model"
+)
+
+type LoginAttemptQuery interface {
+	CreateAttempt(ctx context.Context, attempt model.LoginAttempt) (model.LoginAttempt, error)
+	// GetAttemptsByUserId lists the latest attempts first.
+	GetAttemptsByUserId(ctx context.Context, userId uint64, limit int) ([]model.LoginAttempt, error)
+}
+
+type loginAttemptQueryImpl struct {
+	db infrastructure.GormPostgres
+}
+
+func NewLoginAttemptQuery(db infrastructure.GormPostgres) LoginAttemptQuery {
+	return &loginAttemptQueryImpl{db: db}
+}
+
+func (l *loginAttemptQueryImpl) CreateAttempt(ctx context.Context, attempt model.LoginAttempt) (model.LoginAttempt, error) {
+	ctx, span := tracer.Start(ctx, "LoginAttemptQuery.CreateAttempt")
+	defer span.End()
+
+	db := l.db.GetWriteConnection(ctx)
+	if err := db.
+		WithContext(ctx).
+		Table("login_attempts").
+		Create(&attempt).Error; err != nil {
+		return model.LoginAttempt{}, err
+	}
+	return attempt, nil
+}
+
+func (l *loginAttemptQueryImpl) GetAttemptsByUserId(ctx context.Context, userId uint64, limit int) ([]model.LoginAttempt, error) {
+	ctx, span := tracer.Start(ctx, "LoginAttemptQuery.GetAttemptsByUserId")
+	defer span.End()
+
+	db := l.db.GetReadConnection(ctx)
+	attempts := []model.LoginAttempt{}
+	if err := db.
+		WithContext(ctx).
+		Table("login_attempts").
+		Where("user_id = ?", userId).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Find(&attempts).Error; err != nil {
+		return nil, err
+	}
+	return attempts, nil
+}
@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"context"
+	"mygram/internal/model"
+	"mygram/internal/repository"
+)
+
+type blockKey struct {
+	blockerId uint64
+	blockedId uint64
+}
+
+type blockQueryImpl struct {
+	store *Store
+}
+
+func NewBlockQuery(store *Store) repository.BlockQuery {
+	return &blockQueryImpl{store: store}
+}
+
+func (b *blockQueryImpl) CreateBlock(ctx context.Context, block model.Block) (model.Block, error) {
+	s := b.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[block.BlockerId]; !ok {
+		return model.Block{}, foreignKeyViolation("fk_blocks_blocker_id")
+	}
+	if _, ok := s.users[block.BlockedId]; !ok {
+		return model.Block{}, foreignKeyViolation("fk_blocks_blocked_id")
+	}
+	key := blockKey{block.BlockerId, block.BlockedId}
+	if _, ok := s.blocks[key]; ok {
+		return model.Block{}, uniqueViolation("blocks_pkey")
+	}
+
+	block.CreatedAt = s.Now()
+	s.blocks[key] = block
+	return block, nil
+}
+
+func (b *blockQueryImpl) GetBlock(ctx context.Context, blockerId uint64, blockedId uint64) (model.Block, error) {
+	s := b.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.blocks[blockKey{blockerId, blockedId}], nil
+}
+
+func (b *blockQueryImpl) DeleteBlock(ctx context.Context, blockerId uint64, blockedId uint64) (bool, error) {
+	s := b.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := blockKey{blockerId, blockedId}
+	if _, ok := s.blocks[key]; !ok {
+		return false, nil
+	}
+	delete(s.blocks, key)
+	return true, nil
+}
+
+func (b *blockQueryImpl) IsBlocked(ctx context.Context, userId uint64, otherId uint64) (bool, error) {
+	s := b.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.blocked(userId, otherId), nil
+}
+
+func (b *blockQueryImpl) GetBlocks(ctx context.Context, blockerId uint64) ([]model.ListedUser, error) {
+	s := b.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	blocks := []model.ListedUser{}
+	for key, block := range s.blocks {
+		if key.blockerId == blockerId {
+			blocks = s.appendListedUser(blocks, key.blockedId, block.CreatedAt)
+		}
+	}
+	sortListedUsers(blocks)
+	return blocks, nil
+}
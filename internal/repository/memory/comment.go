@@ -0,0 +1,197 @@
+package memory
+
+import (
+	"context"
+	"mygram/internal/model"
+	"mygram/internal/repository"
+	"time"
+)
+
+type commentQueryImpl struct {
+	store *Store
+}
+
+func NewCommentQuery(store *Store) repository.CommentQuery {
+	return &commentQueryImpl{store: store}
+}
+
+func (c *commentQueryImpl) CreateComment(ctx context.Context, comment model.Comment) (model.Comment, error) {
+	s := c.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.photos[comment.PhotoId]; !ok {
+		return model.Comment{}, foreignKeyViolation("fk_comments_photo_id")
+	}
+	if _, ok := s.users[comment.UserId]; !ok {
+		return model.Comment{}, foreignKeyViolation("fk_comments_user_id")
+	}
+
+	now := s.Now()
+	if comment.ID == 0 {
+		comment.ID = s.nextID("comments")
+	}
+	if comment.CreatedAt.IsZero() {
+		comment.CreatedAt = now
+	}
+	comment.UpdatedAt = now
+	s.comments[comment.ID] = comment
+	return comment, nil
+}
+
+func (c *commentQueryImpl) GetCommentsByPhotoId(ctx context.Context, photoId uint64, viewerId uint64, expand model.Expand) ([]model.CommentGetRes, error) {
+	s := c.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	comments := []model.CommentGetRes{}
+	for _, id := range sortedIDs(s.comments) {
+		comment := s.comments[id]
+		if comment.PhotoId != photoId || comment.DeletedAt.Valid || !s.visibleComment(comment, viewerId) || s.muted(comment.UserId, viewerId) {
+			continue
+		}
+		comments = append(comments, s.commentGetRes(comment, expand, viewerId))
+	}
+	return comments, nil
+}
+
+func (c *commentQueryImpl) GetCommentsByPhotoIds(ctx context.Context, photoIds []uint64, viewerId uint64) ([]model.CommentGetRes, error) {
+	s := c.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	photos := map[uint64]bool{}
+	for _, id := range photoIds {
+		photos[id] = true
+	}
+	comments := []model.CommentGetRes{}
+	for _, id := range sortedIDs(s.comments) {
+		comment := s.comments[id]
+		if !photos[comment.PhotoId] || comment.DeletedAt.Valid || !s.visibleComment(comment, viewerId) || s.muted(comment.UserId, viewerId) {
+			continue
+		}
+		comments = append(comments, s.commentGetRes(comment, model.Expand{}, viewerId))
+	}
+	return comments, nil
+}
+
+func (c *commentQueryImpl) EditComment(ctx context.Context, comment model.Comment) error {
+	s := c.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.editComment(comment)
+}
+
+func (c *commentQueryImpl) EditCommentIfUnmodified(ctx context.Context, comment model.Comment, updatedAt time.Time) (bool, error) {
+	s := c.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.comments[comment.ID]
+	if !ok || current.DeletedAt.Valid || !current.UpdatedAt.Equal(updatedAt) {
+		return false, nil
+	}
+	return true, s.editComment(comment)
+}
+
+func (s *Store) editComment(comment model.Comment) error {
+	current, ok := s.comments[comment.ID]
+	if !ok || current.DeletedAt.Valid {
+		return nil
+	}
+
+	if comment.Message != "" {
+		current.Message = comment.Message
+	}
+	if comment.UserId != 0 {
+		if _, ok := s.users[comment.UserId]; !ok {
+			return foreignKeyViolation("fk_comments_user_id")
+		}
+		current.UserId = comment.UserId
+	}
+	if comment.PhotoId != 0 {
+		if _, ok := s.photos[comment.PhotoId]; !ok {
+			return foreignKeyViolation("fk_comments_photo_id")
+		}
+		current.PhotoId = comment.PhotoId
+	}
+
+	current.UpdatedAt = s.Now()
+	s.comments[comment.ID] = current
+	return nil
+}
+
+func (c *commentQueryImpl) GetCommentById(ctx context.Context, id uint64, viewerId uint64) (model.Comment, error) {
+	s := c.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	comment, ok := s.comments[id]
+	if !ok || comment.DeletedAt.Valid || !s.visibleComment(comment, viewerId) {
+		return model.Comment{}, nil
+	}
+	return comment, nil
+}
+
+func (c *commentQueryImpl) DeleteComment(ctx context.Context, id uint64) error {
+	s := c.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	comment, ok := s.comments[id]
+	if !ok || comment.DeletedAt.Valid {
+		return nil
+	}
+	comment.DeletedAt = s.deletedAt()
+	s.comments[id] = comment
+	return nil
+}
+
+func (c *commentQueryImpl) DeleteCommentsByUserId(ctx context.Context, userId uint64) error {
+	s := c.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, comment := range s.comments {
+		if comment.DeletedAt.Valid {
+			continue
+		}
+		if comment.UserId == userId || s.photos[comment.PhotoId].UserId == userId {
+			comment.DeletedAt = s.deletedAt()
+			s.comments[id] = comment
+		}
+	}
+	return nil
+}
+
+func (c *commentQueryImpl) GetExpandedComment(ctx context.Context, id uint64, viewerId uint64, expand model.Expand) (model.CommentGetRes, error) {
+	s := c.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	comment, ok := s.comments[id]
+	if !ok || comment.DeletedAt.Valid || !s.visibleComment(comment, viewerId) {
+		return model.CommentGetRes{}, nil
+	}
+	return s.commentGetRes(comment, expand, viewerId), nil
+}
+
+// commentGetRes mirrors repository.expandComments.
+func (s *Store) commentGetRes(comment model.Comment, expand model.Expand, viewerId uint64) model.CommentGetRes {
+	res := model.CommentGetRes{
+		ID:        comment.ID,
+		Message:   comment.Message,
+		UserId:    comment.UserId,
+		PhotoId:   comment.PhotoId,
+		CreatedAt: comment.CreatedAt,
+		UpdatedAt: comment.UpdatedAt,
+	}
+	if expand.User {
+		res.User = s.userRelation(comment.UserId)
+	}
+	if expand.Photo {
+		res.Photo = s.photoRelation(comment.PhotoId, viewerId)
+	}
+	return res
+}
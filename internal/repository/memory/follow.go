@@ -0,0 +1,123 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"mygram/internal/model"
+	"mygram/internal/repository"
+	"slices"
+	"time"
+)
+
+type followKey struct {
+	followerId uint64
+	followeeId uint64
+}
+
+type followQueryImpl struct {
+	store *Store
+}
+
+func NewFollowQuery(store *Store) repository.FollowQuery {
+	return &followQueryImpl{store: store}
+}
+
+func (f *followQueryImpl) CreateFollow(ctx context.Context, follow model.Follow) (model.Follow, error) {
+	s := f.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[follow.FollowerId]; !ok {
+		return model.Follow{}, foreignKeyViolation("fk_follows_follower_id")
+	}
+	if _, ok := s.users[follow.FolloweeId]; !ok {
+		return model.Follow{}, foreignKeyViolation("fk_follows_followee_id")
+	}
+	key := followKey{follow.FollowerId, follow.FolloweeId}
+	if _, ok := s.follows[key]; ok {
+		return model.Follow{}, uniqueViolation("follows_pkey")
+	}
+
+	follow.CreatedAt = s.Now()
+	s.follows[key] = follow
+	return follow, nil
+}
+
+func (f *followQueryImpl) GetFollow(ctx context.Context, followerId uint64, followeeId uint64) (model.Follow, error) {
+	s := f.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.follows[followKey{followerId, followeeId}], nil
+}
+
+func (f *followQueryImpl) DeleteFollow(ctx context.Context, followerId uint64, followeeId uint64) (bool, error) {
+	s := f.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := followKey{followerId, followeeId}
+	if _, ok := s.follows[key]; !ok {
+		return false, nil
+	}
+	delete(s.follows, key)
+	return true, nil
+}
+
+func (f *followQueryImpl) ApproveFollow(ctx context.Context, followerId uint64, followeeId uint64, approvedAt time.Time) (bool, error) {
+	s := f.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := followKey{followerId, followeeId}
+	follow, ok := s.follows[key]
+	if !ok || follow.ApprovedAt != nil {
+		return false, nil
+	}
+	follow.ApprovedAt = &approvedAt
+	s.follows[key] = follow
+	return true, nil
+}
+
+func (f *followQueryImpl) ApproveAllFollows(ctx context.Context, followeeId uint64, approvedAt time.Time) error {
+	s := f.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, follow := range s.follows {
+		if key.followeeId == followeeId && follow.ApprovedAt == nil {
+			follow.ApprovedAt = &approvedAt
+			s.follows[key] = follow
+		}
+	}
+	return nil
+}
+
+func (f *followQueryImpl) GetPendingFollows(ctx context.Context, followeeId uint64) ([]model.FollowRequest, error) {
+	s := f.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	requests := []model.FollowRequest{}
+	for key, follow := range s.follows {
+		if key.followeeId != followeeId || follow.ApprovedAt != nil {
+			continue
+		}
+		if user, ok := s.users[key.followerId]; !ok || user.DeletedAt.Valid {
+			continue
+		}
+		relation := s.userRelation(key.followerId)
+		requests = append(requests, model.FollowRequest{
+			FollowerId:  key.followerId,
+			User:        model.UserRelation{ID: relation.ID, Username: relation.Username},
+			RequestedAt: follow.CreatedAt,
+		})
+	}
+	slices.SortFunc(requests, func(a, b model.FollowRequest) int {
+		if c := a.RequestedAt.Compare(b.RequestedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.FollowerId, b.FollowerId)
+	})
+	return requests, nil
+}
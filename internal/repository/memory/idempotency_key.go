@@ -0,0 +1,92 @@
+package memory
+
+import (
+	"context"
+	"mygram/internal/model"
+	"mygram/internal/repository"
+	"time"
+)
+
+type idempotencyKeyQueryImpl struct {
+	store *Store
+}
+
+func NewIdempotencyKeyQuery(store *Store) repository.IdempotencyKeyQuery {
+	return &idempotencyKeyQueryImpl{store: store}
+}
+
+func (i *idempotencyKeyQueryImpl) ReserveKey(ctx context.Context, key model.IdempotencyKey, now time.Time) (model.IdempotencyKey, bool, error) {
+	s := i.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, other := range s.idempotencyKeys {
+		if other.UserId == key.UserId && !other.ExpiresAt.After(now) {
+			delete(s.idempotencyKeys, id)
+		}
+	}
+
+	for _, id := range sortedIDs(s.idempotencyKeys) {
+		other := s.idempotencyKeys[id]
+		if other.UserId != key.UserId || other.Key != key.Key {
+			continue
+		}
+		if other.Fingerprint == key.Fingerprint && other.CompletedAt == nil && !other.LockedUntil.After(now) {
+			other.LockedUntil = key.LockedUntil
+			s.idempotencyKeys[id] = other
+			return other, true, nil
+		}
+		return other, false, nil
+	}
+
+	if _, ok := s.users[key.UserId]; !ok {
+		return model.IdempotencyKey{}, false, foreignKeyViolation("fk_idempotency_keys_user_id")
+	}
+	key.ID = s.nextID("idempotency_keys")
+	key.CreatedAt = s.Now()
+	s.idempotencyKeys[key.ID] = key
+	return key, true, nil
+}
+
+func (i *idempotencyKeyQueryImpl) CompleteKey(ctx context.Context, id uint64, res model.IdempotentResponse, completedAt time.Time) error {
+	s := i.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.idempotencyKeys[id]
+	if !ok {
+		return nil
+	}
+	key.ResponseStatus = res.Status
+	key.ResponseType = res.ContentType
+	key.ResponseBody = res.Body
+	key.CompletedAt = &completedAt
+	s.idempotencyKeys[id] = key
+	return nil
+}
+
+func (i *idempotencyKeyQueryImpl) ReleaseKey(ctx context.Context, id uint64) error {
+	s := i.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if key, ok := s.idempotencyKeys[id]; ok && key.CompletedAt == nil {
+		delete(s.idempotencyKeys, id)
+	}
+	return nil
+}
+
+func (i *idempotencyKeyQueryImpl) DeleteExpiredKeys(ctx context.Context, now time.Time) (int64, error) {
+	s := i.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	for id, key := range s.idempotencyKeys {
+		if !key.ExpiresAt.After(now) {
+			delete(s.idempotencyKeys, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,92 @@
+package memory_test
+
+import (
+	"context"
+	"mygram/internal/model"
+	"mygram/internal/repository/memory"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyKeyLocksAndExpires(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	alice, err := memory.NewUserQuery(store).CreateUser(ctx, model.User{Username: "alice", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := memory.NewIdempotencyKeyQuery(store)
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	reserve := func(at time.Time, fingerprint string) (model.IdempotencyKey, bool) {
+		t.Helper()
+		key, ok, err := repo.ReserveKey(ctx, model.IdempotencyKey{
+			UserId:      alice.ID,
+			Key:         "k1",
+			Fingerprint: fingerprint,
+			LockedUntil: at.Add(time.Minute),
+			ExpiresAt:   at.Add(24 * time.Hour),
+		}, at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key, ok
+	}
+
+	first, ok := reserve(now, "a")
+	if !ok {
+		t.Fatal("expected the key reserved")
+	}
+	if _, ok := reserve(now.Add(time.Second), "a"); ok {
+		t.Fatal("expected the key locked while in progress")
+	}
+	// a request that never finished gives the lock up to its retries
+	if _, ok := reserve(now.Add(2*time.Minute), "b"); ok {
+		t.Fatal("expected a stale lock kept from another request")
+	}
+	if _, ok := reserve(now.Add(2*time.Minute), "a"); !ok {
+		t.Fatal("expected a stale lock taken over")
+	}
+
+	if err := repo.CompleteKey(ctx, first.ID, model.IdempotentResponse{Status: 201}, now.Add(2*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	stored, ok := reserve(now.Add(time.Hour), "a")
+	if ok || stored.ResponseStatus != 201 {
+		t.Fatalf("expected the completed key, got %+v", stored)
+	}
+	if _, ok := reserve(now.Add(25*time.Hour), "b"); !ok {
+		t.Fatal("expected the key free again after a day")
+	}
+}
+
+func TestExpiredIdempotencyKeysArePurgedForEveryUser(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	users := memory.NewUserQuery(store)
+	repo := memory.NewIdempotencyKeyQuery(store)
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i, name := range []string{"alice", "bob", "carol"} {
+		user, err := users.CreateUser(ctx, model.User{Username: name, Email: name + "@example.com"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// alice's and bob's keys are expired, carol's is not
+		expiresAt := now.Add(time.Duration(i-1) * time.Hour)
+		if _, _, err := repo.ReserveKey(ctx, model.IdempotencyKey{UserId: user.ID, Key: "k1", Fingerprint: "a", ExpiresAt: expiresAt}, now.Add(-24*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deleted, err := repo.DeleteExpiredKeys(ctx, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 2 {
+		t.Fatalf("expected 2 keys purged, got %d", deleted)
+	}
+	if deleted, _ := repo.DeleteExpiredKeys(ctx, now); deleted != 0 {
+		t.Fatalf("expected the unexpired key kept, got %d purged", deleted)
+	}
+}
@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"mygram/internal/model"
+	"mygram/internal/repository"
+	"slices"
+	"time"
+)
+
+type muteKey struct {
+	muterId uint64
+	mutedId uint64
+}
+
+type muteQueryImpl struct {
+	store *Store
+}
+
+func NewMuteQuery(store *Store) repository.MuteQuery {
+	return &muteQueryImpl{store: store}
+}
+
+func (m *muteQueryImpl) CreateMute(ctx context.Context, mute model.Mute) (model.Mute, error) {
+	s := m.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[mute.MuterId]; !ok {
+		return model.Mute{}, foreignKeyViolation("fk_mutes_muter_id")
+	}
+	if _, ok := s.users[mute.MutedId]; !ok {
+		return model.Mute{}, foreignKeyViolation("fk_mutes_muted_id")
+	}
+	key := muteKey{mute.MuterId, mute.MutedId}
+	if _, ok := s.mutes[key]; ok {
+		return model.Mute{}, uniqueViolation("mutes_pkey")
+	}
+
+	mute.CreatedAt = s.Now()
+	s.mutes[key] = mute
+	return mute, nil
+}
+
+func (m *muteQueryImpl) GetMute(ctx context.Context, muterId uint64, mutedId uint64) (model.Mute, error) {
+	s := m.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.mutes[muteKey{muterId, mutedId}], nil
+}
+
+func (m *muteQueryImpl) DeleteMute(ctx context.Context, muterId uint64, mutedId uint64) (bool, error) {
+	s := m.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := muteKey{muterId, mutedId}
+	if _, ok := s.mutes[key]; !ok {
+		return false, nil
+	}
+	delete(s.mutes, key)
+	return true, nil
+}
+
+func (m *muteQueryImpl) GetMutes(ctx context.Context, muterId uint64) ([]model.ListedUser, error) {
+	s := m.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	mutes := []model.ListedUser{}
+	for key, mute := range s.mutes {
+		if key.muterId == muterId {
+			mutes = s.appendListedUser(mutes, key.mutedId, mute.CreatedAt)
+		}
+	}
+	sortListedUsers(mutes)
+	return mutes, nil
+}
+
+// appendListedUser skips deleted users like the join in Postgres does.
+func (s *Store) appendListedUser(users []model.ListedUser, userId uint64, createdAt time.Time) []model.ListedUser {
+	if user, ok := s.users[userId]; !ok || user.DeletedAt.Valid {
+		return users
+	}
+	relation := s.userRelation(userId)
+	return append(users, model.ListedUser{
+		UserId:    userId,
+		User:      model.UserRelation{ID: relation.ID, Username: relation.Username},
+		CreatedAt: createdAt,
+	})
+}
+
+// sortListedUsers orders newest first, like GetBlocks and GetMutes.
+func sortListedUsers(users []model.ListedUser) {
+	slices.SortFunc(users, func(a, b model.ListedUser) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.UserId, b.UserId)
+	})
+}
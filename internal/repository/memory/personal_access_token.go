@@ -0,0 +1,113 @@
+package memory
+
+import (
+	"context"
+	"mygram/internal/model"
+	"mygram/internal/repository"
+	"slices"
+	"time"
+)
+
+type personalAccessTokenQueryImpl struct {
+	store *Store
+}
+
+func NewPersonalAccessTokenQuery(store *Store) repository.PersonalAccessTokenQuery {
+	return &personalAccessTokenQueryImpl{store: store}
+}
+
+func (p *personalAccessTokenQueryImpl) CreateToken(ctx context.Context, token model.PersonalAccessToken) (model.PersonalAccessToken, error) {
+	s := p.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user, ok := s.users[token.UserId]; !ok || user.DeletedAt.Valid {
+		return model.PersonalAccessToken{}, foreignKeyViolation("fk_personal_access_tokens_user_id")
+	}
+	for _, other := range s.personalAccessTokens {
+		if other.TokenHash == token.TokenHash {
+			return model.PersonalAccessToken{}, uniqueViolation("personal_access_tokens_token_hash_key")
+		}
+	}
+
+	token.ID = s.nextID("personal_access_tokens")
+	token.Scopes = slices.Clone(token.Scopes)
+	token.CreatedAt = s.Now()
+	s.personalAccessTokens[token.ID] = token
+	return token, nil
+}
+
+func (p *personalAccessTokenQueryImpl) GetTokensByUserId(ctx context.Context, userId uint64) ([]model.PersonalAccessToken, error) {
+	s := p.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tokens := []model.PersonalAccessToken{}
+	ids := sortedIDs(s.personalAccessTokens)
+	slices.Reverse(ids)
+	for _, id := range ids {
+		token := s.personalAccessTokens[id]
+		if token.UserId == userId && token.RevokedAt == nil {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+func (p *personalAccessTokenQueryImpl) GetTokenByHash(ctx context.Context, hash string) (model.PersonalAccessToken, error) {
+	s := p.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, token := range s.personalAccessTokens {
+		if token.TokenHash == hash {
+			return token, nil
+		}
+	}
+	return model.PersonalAccessToken{}, nil
+}
+
+func (p *personalAccessTokenQueryImpl) RevokeToken(ctx context.Context, userId uint64, id uint64) (bool, error) {
+	s := p.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.personalAccessTokens[id]
+	if !ok || token.UserId != userId || token.RevokedAt != nil {
+		return false, nil
+	}
+	now := s.Now()
+	token.RevokedAt = &now
+	s.personalAccessTokens[id] = token
+	return true, nil
+}
+
+func (p *personalAccessTokenQueryImpl) RevokeTokensByUserId(ctx context.Context, userId uint64) error {
+	s := p.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.Now()
+	for id, token := range s.personalAccessTokens {
+		if token.UserId != userId || token.RevokedAt != nil {
+			continue
+		}
+		token.RevokedAt = &now
+		s.personalAccessTokens[id] = token
+	}
+	return nil
+}
+
+func (p *personalAccessTokenQueryImpl) TouchToken(ctx context.Context, id uint64, usedAt time.Time) error {
+	s := p.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.personalAccessTokens[id]
+	if !ok {
+		return nil
+	}
+	token.LastUsedAt = &usedAt
+	s.personalAccessTokens[id] = token
+	return nil
+}
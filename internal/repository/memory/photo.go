@@ -0,0 +1,228 @@
+package memory
+
+import (
+	"context"
+	"mygram/internal/model"
+	"mygram/internal/repository"
+	"time"
+)
+
+type photoQueryImpl struct {
+	store *Store
+}
+
+func NewPhotoQuery(store *Store) repository.PhotoQuery {
+	return &photoQueryImpl{store: store}
+}
+
+func (p *photoQueryImpl) CreatePhoto(ctx context.Context, photo model.Photo) (model.Photo, error) {
+	s := p.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[photo.UserId]; !ok {
+		return model.Photo{}, foreignKeyViolation("fk_photo_user_id")
+	}
+
+	now := s.Now()
+	if photo.ID == 0 {
+		photo.ID = s.nextID("photos")
+	}
+	if photo.CreatedAt.IsZero() {
+		photo.CreatedAt = now
+	}
+	photo.UpdatedAt = now
+	s.photos[photo.ID] = photo
+	return photo, nil
+}
+
+func (p *photoQueryImpl) GetPhotosByUserId(ctx context.Context, userId uint64, viewerId uint64, expand model.Expand) ([]model.PhotoGetRes, error) {
+	s := p.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	photos := []model.PhotoGetRes{}
+	for _, id := range sortedIDs(s.photos) {
+		photo := s.photos[id]
+		if photo.UserId != userId || photo.DeletedAt.Valid || !s.visibleOwner(photo.UserId, viewerId) {
+			continue
+		}
+		photos = append(photos, s.photoGetRes(photo, expand, viewerId))
+	}
+	return photos, nil
+}
+
+func (p *photoQueryImpl) EditPhoto(ctx context.Context, photo model.Photo) error {
+	s := p.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.editPhoto(photo)
+}
+
+func (p *photoQueryImpl) EditPhotoIfUnmodified(ctx context.Context, photo model.Photo, updatedAt time.Time) (bool, error) {
+	s := p.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.photos[photo.ID]
+	if !ok || current.DeletedAt.Valid || !current.UpdatedAt.Equal(updatedAt) {
+		return false, nil
+	}
+	return true, s.editPhoto(photo)
+}
+
+func (s *Store) editPhoto(photo model.Photo) error {
+	current, ok := s.photos[photo.ID]
+	if !ok || current.DeletedAt.Valid {
+		return nil
+	}
+
+	if photo.Title != "" {
+		current.Title = photo.Title
+	}
+	if photo.Caption != "" {
+		current.Caption = photo.Caption
+	}
+	if photo.PhotoUrl != "" {
+		current.PhotoUrl = photo.PhotoUrl
+	}
+	if photo.UserId != 0 {
+		if _, ok := s.users[photo.UserId]; !ok {
+			return foreignKeyViolation("fk_photo_user_id")
+		}
+		current.UserId = photo.UserId
+	}
+
+	current.UpdatedAt = s.Now()
+	s.photos[photo.ID] = current
+	return nil
+}
+
+func (p *photoQueryImpl) GetPhotoById(ctx context.Context, id uint64, viewerId uint64) (model.Photo, error) {
+	s := p.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	photo, ok := s.photos[id]
+	if !ok || photo.DeletedAt.Valid || !s.visibleOwner(photo.UserId, viewerId) {
+		return model.Photo{}, nil
+	}
+	return photo, nil
+}
+
+func (p *photoQueryImpl) GetExpandedPhoto(ctx context.Context, id uint64, viewerId uint64, expand model.Expand) (model.PhotoGetRes, error) {
+	s := p.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	photo, ok := s.photos[id]
+	if !ok || photo.DeletedAt.Valid || !s.visibleOwner(photo.UserId, viewerId) {
+		return model.PhotoGetRes{}, nil
+	}
+	return s.photoGetRes(photo, expand, viewerId), nil
+}
+
+func (p *photoQueryImpl) DeletePhoto(ctx context.Context, id uint64) error {
+	s := p.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	photo, ok := s.photos[id]
+	if !ok || photo.DeletedAt.Valid {
+		return nil
+	}
+	photo.DeletedAt = s.deletedAt()
+	s.photos[id] = photo
+	return nil
+}
+
+func (p *photoQueryImpl) DeletePhotosByUserId(ctx context.Context, userId uint64) error {
+	s := p.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, photo := range s.photos {
+		if photo.UserId == userId && !photo.DeletedAt.Valid {
+			photo.DeletedAt = s.deletedAt()
+			s.photos[id] = photo
+		}
+	}
+	return nil
+}
+
+func (s *Store) photoRelation(id uint64, viewerId uint64) *model.PhotoRelation {
+	photo, ok := s.photos[id]
+	if !ok || photo.DeletedAt.Valid || !s.visibleOwner(photo.UserId, viewerId) {
+		return nil
+	}
+	return &model.PhotoRelation{
+		ID:       photo.ID,
+		Title:    photo.Title,
+		Caption:  photo.Caption,
+		PhotoUrl: photo.PhotoUrl,
+		UserId:   photo.UserId,
+	}
+}
+
+func (p *photoQueryImpl) GetPhotosByIds(ctx context.Context, ids []uint64, viewerId uint64) ([]model.PhotoGetRes, error) {
+	s := p.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	photos := []model.PhotoGetRes{}
+	for _, id := range ids {
+		photo, ok := s.photos[id]
+		if !ok || photo.DeletedAt.Valid || !s.visibleOwner(photo.UserId, viewerId) {
+			continue
+		}
+		photos = append(photos, s.photoGetRes(photo, model.Expand{User: true}, viewerId))
+	}
+	return photos, nil
+}
+
+func (p *photoQueryImpl) GetPhotosByUserIds(ctx context.Context, userIds []uint64, viewerId uint64) ([]model.PhotoGetRes, error) {
+	s := p.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	owners := map[uint64]bool{}
+	for _, id := range userIds {
+		owners[id] = true
+	}
+	photos := []model.PhotoGetRes{}
+	for _, id := range sortedIDs(s.photos) {
+		photo := s.photos[id]
+		if !owners[photo.UserId] || photo.DeletedAt.Valid || !s.visibleOwner(photo.UserId, viewerId) {
+			continue
+		}
+		photos = append(photos, s.photoGetRes(photo, model.Expand{}, viewerId))
+	}
+	return photos, nil
+}
+
+// photoGetRes mirrors repository.expandPhotos.
+func (s *Store) photoGetRes(photo model.Photo, expand model.Expand, viewerId uint64) model.PhotoGetRes {
+	res := model.PhotoGetRes{
+		ID:        photo.ID,
+		Title:     photo.Title,
+		Caption:   photo.Caption,
+		PhotoUrl:  photo.PhotoUrl,
+		UserId:    photo.UserId,
+		CreatedAt: photo.CreatedAt,
+		UpdatedAt: photo.UpdatedAt,
+	}
+	if expand.User {
+		res.User = s.userRelation(photo.UserId)
+	}
+	if expand.Comments {
+		for _, id := range sortedIDs(s.comments) {
+			comment := s.comments[id]
+			if comment.PhotoId != photo.ID || comment.DeletedAt.Valid || !s.visibleComment(comment, viewerId) || s.muted(comment.UserId, viewerId) {
+				continue
+			}
+			res.Comments = append(res.Comments, s.commentGetRes(comment, model.Expand{User: true}, viewerId))
+		}
+	}
+	return res
+}
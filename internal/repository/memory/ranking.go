@@ -0,0 +1,123 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"mygram/internal/model"
+	"mygram/internal/repository"
+	"mygram/internal/search"
+	"slices"
+	"time"
+)
+
+type rankingQueryImpl struct {
+	store *Store
+}
+
+func NewRankingQuery(store *Store) repository.RankingQuery {
+	return &rankingQueryImpl{store: store}
+}
+
+func (r *rankingQueryImpl) GetEngagement(ctx context.Context, since time.Time) ([]model.PhotoEngagement, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	comments := map[uint64]int{}
+	for _, comment := range s.comments {
+		photo := s.photos[comment.PhotoId]
+		if !comment.DeletedAt.Valid && comment.UserId != photo.UserId {
+			comments[comment.PhotoId]++
+		}
+	}
+
+	engagement := []model.PhotoEngagement{}
+	for _, id := range sortedIDs(s.photos) {
+		photo := s.photos[id]
+		if photo.DeletedAt.Valid || photo.CreatedAt.Before(since) {
+			continue
+		}
+		engagement = append(engagement, model.PhotoEngagement{PhotoId: id, CreatedAt: photo.CreatedAt, Comments: comments[id]})
+	}
+	return engagement, nil
+}
+
+func (r *rankingQueryImpl) ReplaceRankings(ctx context.Context, rankings []model.PhotoRanking) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, ranking := range rankings {
+		if _, ok := s.photos[ranking.PhotoId]; !ok {
+			return foreignKeyViolation("fk_photo_rankings_photo_id")
+		}
+	}
+	s.rankings = map[uint64]model.PhotoRanking{}
+	for _, ranking := range rankings {
+		s.rankings[ranking.PhotoId] = ranking
+	}
+	return nil
+}
+
+func (r *rankingQueryImpl) GetRankedPhotos(ctx context.Context, tag string, limit int, offset int, viewerId uint64) ([]model.PhotoGetRes, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ranked := []model.PhotoRanking{}
+	for id, ranking := range s.rankings {
+		photo, ok := s.photos[id]
+		if !ok || photo.DeletedAt.Valid || !s.discoverable(photo.UserId, viewerId) {
+			continue
+		}
+		if tag != "" && !slices.Contains(search.Hashtags(photo.Title+" "+photo.Caption), tag) {
+			continue
+		}
+		ranked = append(ranked, ranking)
+	}
+	slices.SortFunc(ranked, func(a, b model.PhotoRanking) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), cmp.Compare(b.PhotoId, a.PhotoId))
+	})
+
+	photos := []model.PhotoGetRes{}
+	for i := offset; i < len(ranked) && len(photos) < limit; i++ {
+		photos = append(photos, s.photoGetRes(s.photos[ranked[i].PhotoId], model.Expand{User: true}, viewerId))
+	}
+	return photos, nil
+}
+
+func (r *rankingQueryImpl) GetTrendingHashtags(ctx context.Context, limit int) ([]model.TrendingHashtag, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	byTag := map[string]*model.TrendingHashtag{}
+	for id, ranking := range s.rankings {
+		photo, ok := s.photos[id]
+		if !ok || photo.DeletedAt.Valid {
+			continue
+		}
+		if owner, ok := s.users[photo.UserId]; !ok || owner.DeletedAt.Valid || owner.Private {
+			continue
+		}
+		for _, tag := range search.Hashtags(photo.Title + " " + photo.Caption) {
+			if byTag[tag] == nil {
+				byTag[tag] = &model.TrendingHashtag{Tag: tag}
+			}
+			byTag[tag].Photos++
+			byTag[tag].Score += ranking.Score
+		}
+	}
+
+	tags := []model.TrendingHashtag{}
+	for _, tag := range byTag {
+		tags = append(tags, *tag)
+	}
+	slices.SortFunc(tags, func(a, b model.TrendingHashtag) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), cmp.Compare(a.Tag, b.Tag))
+	})
+	if len(tags) > limit {
+		tags = tags[:limit]
+	}
+	return tags, nil
+}
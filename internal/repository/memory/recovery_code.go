@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"context"
+	"mygram/internal/model"
+	"mygram/internal/repository"
+)
+
+type recoveryCodeQueryImpl struct {
+	store *Store
+}
+
+func NewRecoveryCodeQuery(store *Store) repository.RecoveryCodeQuery {
+	return &recoveryCodeQueryImpl{store: store}
+}
+
+func (r *recoveryCodeQueryImpl) ReplaceRecoveryCodes(ctx context.Context, userId uint64, hashes []string) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user, ok := s.users[userId]; !ok || user.DeletedAt.Valid {
+		return foreignKeyViolation("fk_user_recovery_codes_user_id")
+	}
+
+	s.deleteRecoveryCodes(userId)
+	for _, hash := range hashes {
+		id := s.nextID("user_recovery_codes")
+		s.recoveryCodes[id] = model.RecoveryCode{
+			ID:        id,
+			UserId:    userId,
+			CodeHash:  hash,
+			CreatedAt: s.Now(),
+		}
+	}
+	return nil
+}
+
+func (r *recoveryCodeQueryImpl) UseRecoveryCode(ctx context.Context, userId uint64, hash string) (bool, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, code := range s.recoveryCodes {
+		if code.UserId == userId && code.CodeHash == hash && code.UsedAt == nil {
+			now := s.Now()
+			code.UsedAt = &now
+			s.recoveryCodes[id] = code
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *recoveryCodeQueryImpl) DeleteRecoveryCodes(ctx context.Context, userId uint64) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deleteRecoveryCodes(userId)
+	return nil
+}
+
+func (s *Store) deleteRecoveryCodes(userId uint64) {
+	for id, code := range s.recoveryCodes {
+		if code.UserId == userId {
+			delete(s.recoveryCodes, id)
+		}
+	}
+}
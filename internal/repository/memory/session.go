@@ -0,0 +1,181 @@
+package memory
+
+import (
+	"context"
+	"mygram/internal/model"
+	"mygram/internal/repository"
+	"slices"
+	"time"
+)
+
+type sessionQueryImpl struct {
+	store *Store
+}
+
+func NewSessionQuery(store *Store) repository.SessionQuery {
+	return &sessionQueryImpl{store: store}
+}
+
+func (q *sessionQueryImpl) CreateSession(ctx context.Context, session model.Session) (model.Session, error) {
+	s := q.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user, ok := s.users[session.UserId]; !ok || user.DeletedAt.Valid {
+		return model.Session{}, foreignKeyViolation("fk_user_sessions_user_id")
+	}
+	for _, other := range s.sessions {
+		if other.Jti == session.Jti {
+			return model.Session{}, uniqueViolation("user_sessions_jti_key")
+		}
+	}
+
+	now := s.Now()
+	session.ID = s.nextID("user_sessions")
+	session.CreatedAt = now
+	if session.LastSeenAt.IsZero() {
+		session.LastSeenAt = now
+	}
+	s.sessions[session.ID] = session
+	return session, nil
+}
+
+func (q *sessionQueryImpl) GetSessionByJti(ctx context.Context, jti string) (model.Session, error) {
+	s := q.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, session := range s.sessions {
+		if session.Jti == jti {
+			return session, nil
+		}
+	}
+	return model.Session{}, nil
+}
+
+func (q *sessionQueryImpl) GetActiveSessionsByUserId(ctx context.Context, userId uint64) ([]model.Session, error) {
+	s := q.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sessions := []model.Session{}
+	now := s.Now()
+	for _, id := range sortedIDs(s.sessions) {
+		session := s.sessions[id]
+		if session.UserId == userId && session.Active(now) {
+			sessions = append(sessions, session)
+		}
+	}
+	slices.Reverse(sessions)
+	slices.SortStableFunc(sessions, func(a, b model.Session) int {
+		return b.LastSeenAt.Compare(a.LastSeenAt)
+	})
+	return sessions, nil
+}
+
+func (q *sessionQueryImpl) HasSessions(ctx context.Context, userId uint64, userAgent string) (bool, bool, error) {
+	s := q.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ever, fromUserAgent := false, false
+	for _, session := range s.sessions {
+		if session.UserId != userId {
+			continue
+		}
+		ever = true
+		if session.UserAgent == userAgent {
+			fromUserAgent = true
+		}
+	}
+	return ever, fromUserAgent, nil
+}
+
+func (q *sessionQueryImpl) RevokeSession(ctx context.Context, userId uint64, id uint64) (bool, error) {
+	s := q.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+	now := s.Now()
+	if !ok || session.UserId != userId || !session.Active(now) {
+		return false, nil
+	}
+	session.RevokedAt = &now
+	s.sessions[id] = session
+	return true, nil
+}
+
+func (q *sessionQueryImpl) RevokeOtherSessions(ctx context.Context, userId uint64, keepId uint64) (int64, error) {
+	s := q.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var revoked int64
+	now := s.Now()
+	for id, session := range s.sessions {
+		if session.UserId != userId || id == keepId || !session.Active(now) {
+			continue
+		}
+		session.RevokedAt = &now
+		s.sessions[id] = session
+		revoked++
+	}
+	return revoked, nil
+}
+
+func (q *sessionQueryImpl) TouchSession(ctx context.Context, id uint64, seenAt time.Time) error {
+	s := q.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok {
+		return nil
+	}
+	session.LastSeenAt = seenAt
+	s.sessions[id] = session
+	return nil
+}
+
+type loginAttemptQueryImpl struct {
+	store *Store
+}
+
+func NewLoginAttemptQuery(store *Store) repository.LoginAttemptQuery {
+	return &loginAttemptQueryImpl{store: store}
+}
+
+func (q *loginAttemptQueryImpl) CreateAttempt(ctx context.Context, attempt model.LoginAttempt) (model.LoginAttempt, error) {
+	s := q.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[attempt.UserId]; !ok {
+		return model.LoginAttempt{}, foreignKeyViolation("fk_login_attempts_user_id")
+	}
+
+	attempt.ID = s.nextID("login_attempts")
+	attempt.CreatedAt = s.Now()
+	s.loginAttempts[attempt.ID] = attempt
+	return attempt, nil
+}
+
+func (q *loginAttemptQueryImpl) GetAttemptsByUserId(ctx context.Context, userId uint64, limit int) ([]model.LoginAttempt, error) {
+	s := q.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	attempts := []model.LoginAttempt{}
+	ids := sortedIDs(s.loginAttempts)
+	slices.Reverse(ids)
+	for _, id := range ids {
+		if len(attempts) == limit {
+			break
+		}
+		if attempt := s.loginAttempts[id]; attempt.UserId == userId {
+			attempts = append(attempts, attempt)
+		}
+	}
+	return attempts, nil
+}
@@ -0,0 +1,185 @@
+package memory
+
+import (
+	"context"
+	"mygram/internal/model"
+	"mygram/internal/repository"
+	"time"
+)
+
+type socialMediaQueryImpl struct {
+	store *Store
+}
+
+func NewSocialMediaQuery(store *Store) repository.SocialMediaQuery {
+	return &socialMediaQueryImpl{store: store}
+}
+
+func (m *socialMediaQueryImpl) CreateSocialMedia(ctx context.Context, social model.SocialMedia) (model.SocialMedia, error) {
+	s := m.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[social.UserId]; !ok {
+		return model.SocialMedia{}, foreignKeyViolation("fk_social_medias_user_id")
+	}
+
+	now := s.Now()
+	if social.ID == 0 {
+		social.ID = s.nextID("social_medias")
+	}
+	if social.CreatedAt.IsZero() {
+		social.CreatedAt = now
+	}
+	social.UpdatedAt = now
+	s.socialMedias[social.ID] = social
+	return social, nil
+}
+
+func (m *socialMediaQueryImpl) GetSocialMediasByUserId(ctx context.Context, userId uint64, viewerId uint64, expand model.Expand) ([]model.SocialMediaGetRes, error) {
+	s := m.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	socials := []model.SocialMediaGetRes{}
+	for _, id := range sortedIDs(s.socialMedias) {
+		social := s.socialMedias[id]
+		if social.UserId != userId || social.DeletedAt.Valid || !s.visibleOwner(social.UserId, viewerId) {
+			continue
+		}
+		socials = append(socials, s.socialMediaGetRes(social, expand))
+	}
+	return socials, nil
+}
+
+func (m *socialMediaQueryImpl) GetSocialMediasByUserIds(ctx context.Context, userIds []uint64, viewerId uint64) ([]model.SocialMediaGetRes, error) {
+	s := m.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	owners := map[uint64]bool{}
+	for _, id := range userIds {
+		owners[id] = true
+	}
+	socials := []model.SocialMediaGetRes{}
+	for _, id := range sortedIDs(s.socialMedias) {
+		social := s.socialMedias[id]
+		if !owners[social.UserId] || social.DeletedAt.Valid || !s.visibleOwner(social.UserId, viewerId) {
+			continue
+		}
+		socials = append(socials, s.socialMediaGetRes(social, model.Expand{}))
+	}
+	return socials, nil
+}
+
+func (m *socialMediaQueryImpl) EditSocialMedia(ctx context.Context, social model.SocialMedia) error {
+	s := m.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.editSocialMedia(social)
+}
+
+func (m *socialMediaQueryImpl) EditSocialMediaIfUnmodified(ctx context.Context, social model.SocialMedia, updatedAt time.Time) (bool, error) {
+	s := m.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.socialMedias[social.ID]
+	if !ok || current.DeletedAt.Valid || !current.UpdatedAt.Equal(updatedAt) {
+		return false, nil
+	}
+	return true, s.editSocialMedia(social)
+}
+
+func (s *Store) editSocialMedia(social model.SocialMedia) error {
+	current, ok := s.socialMedias[social.ID]
+	if !ok || current.DeletedAt.Valid {
+		return nil
+	}
+
+	if social.Name != "" {
+		current.Name = social.Name
+	}
+	if social.SocialMediaUrl != "" {
+		current.SocialMediaUrl = social.SocialMediaUrl
+	}
+	if social.UserId != 0 {
+		if _, ok := s.users[social.UserId]; !ok {
+			return foreignKeyViolation("fk_social_medias_user_id")
+		}
+		current.UserId = social.UserId
+	}
+
+	current.UpdatedAt = s.Now()
+	s.socialMedias[social.ID] = current
+	return nil
+}
+
+func (m *socialMediaQueryImpl) GetSocialMediaById(ctx context.Context, id uint64, viewerId uint64) (model.SocialMedia, error) {
+	s := m.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	social, ok := s.socialMedias[id]
+	if !ok || social.DeletedAt.Valid || !s.visibleOwner(social.UserId, viewerId) {
+		return model.SocialMedia{}, nil
+	}
+	return social, nil
+}
+
+func (m *socialMediaQueryImpl) DeleteSocialMedia(ctx context.Context, id uint64) error {
+	s := m.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	social, ok := s.socialMedias[id]
+	if !ok || social.DeletedAt.Valid {
+		return nil
+	}
+	social.DeletedAt = s.deletedAt()
+	s.socialMedias[id] = social
+	return nil
+}
+
+func (m *socialMediaQueryImpl) DeleteSocialMediasByUserId(ctx context.Context, userId uint64) error {
+	s := m.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, social := range s.socialMedias {
+		if social.UserId == userId && !social.DeletedAt.Valid {
+			social.DeletedAt = s.deletedAt()
+			s.socialMedias[id] = social
+		}
+	}
+	return nil
+}
+
+func (m *socialMediaQueryImpl) GetExpandedSocialMedia(ctx context.Context, id uint64, viewerId uint64, expand model.Expand) (model.SocialMediaGetRes, error) {
+	s := m.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	social, ok := s.socialMedias[id]
+	if !ok || social.DeletedAt.Valid || !s.visibleOwner(social.UserId, viewerId) {
+		return model.SocialMediaGetRes{}, nil
+	}
+	return s.socialMediaGetRes(social, expand), nil
+}
+
+// socialMediaGetRes mirrors repository.expandSocialMedias.
+func (s *Store) socialMediaGetRes(social model.SocialMedia, expand model.Expand) model.SocialMediaGetRes {
+	res := model.SocialMediaGetRes{
+		ID:             social.ID,
+		Name:           social.Name,
+		SocialMediaUrl: social.SocialMediaUrl,
+		UserId:         social.UserId,
+		CreatedAt:      social.CreatedAt,
+		UpdatedAt:      social.UpdatedAt,
+	}
+	if expand.User {
+		res.User = s.userRelation(social.UserId)
+	}
+	return res
+}
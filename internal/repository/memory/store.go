@@ -0,0 +1,192 @@
+// Package memory holds in-memory implementations of the repository
+// interfaces for tests. They follow what the Postgres schema and GORM would
+// do: soft deletes, unique users.email and users.username (including soft
+// deleted rows), foreign keys, and Updates skipping zero-value fields.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"mygram/internal/model"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+type Store struct {
+	mu sync.RWMutex
+
+	users                map[uint64]model.User
+	photos               map[uint64]model.Photo
+	comments             map[uint64]model.Comment
+	socialMedias         map[uint64]model.SocialMedia
+	userTokens           map[uint64]model.UserToken
+	recoveryCodes        map[uint64]model.RecoveryCode
+	personalAccessTokens map[uint64]model.PersonalAccessToken
+	userIdentities       map[uint64]model.UserIdentity
+	sessions             map[uint64]model.Session
+	loginAttempts        map[uint64]model.LoginAttempt
+	follows              map[followKey]model.Follow
+	blocks               map[blockKey]model.Block
+	mutes                map[muteKey]model.Mute
+	rankings             map[uint64]model.PhotoRanking
+	idempotencyKeys      map[uint64]model.IdempotencyKey
+
+	seq map[string]uint64
+
+	// Now is used for created_at, updated_at and deleted_at.
+	Now func() time.Time
+}
+
+func NewStore() *Store {
+	return &Store{
+		users:                map[uint64]model.User{},
+		photos:               map[uint64]model.Photo{},
+		comments:             map[uint64]model.Comment{},
+		socialMedias:         map[uint64]model.SocialMedia{},
+		userTokens:           map[uint64]model.UserToken{},
+		recoveryCodes:        map[uint64]model.RecoveryCode{},
+		personalAccessTokens: map[uint64]model.PersonalAccessToken{},
+		userIdentities:       map[uint64]model.UserIdentity{},
+		sessions:             map[uint64]model.Session{},
+		loginAttempts:        map[uint64]model.LoginAttempt{},
+		follows:              map[followKey]model.Follow{},
+		blocks:               map[blockKey]model.Block{},
+		mutes:                map[muteKey]model.Mute{},
+		rankings:             map[uint64]model.PhotoRanking{},
+		idempotencyKeys:      map[uint64]model.IdempotencyKey{},
+		seq:                  map[string]uint64{},
+		Now:                  time.Now,
+	}
+}
+
+func (s *Store) nextID(table string) uint64 {
+	s.seq[table]++
+	return s.seq[table]
+}
+
+func (s *Store) deletedAt() gorm.DeletedAt {
+	return gorm.DeletedAt{Time: s.Now(), Valid: true}
+}
+
+type snapshot struct {
+	users                map[uint64]model.User
+	photos               map[uint64]model.Photo
+	comments             map[uint64]model.Comment
+	socialMedias         map[uint64]model.SocialMedia
+	userTokens           map[uint64]model.UserToken
+	recoveryCodes        map[uint64]model.RecoveryCode
+	personalAccessTokens map[uint64]model.PersonalAccessToken
+	userIdentities       map[uint64]model.UserIdentity
+	sessions             map[uint64]model.Session
+	loginAttempts        map[uint64]model.LoginAttempt
+	follows              map[followKey]model.Follow
+	blocks               map[blockKey]model.Block
+	mutes                map[muteKey]model.Mute
+	rankings             map[uint64]model.PhotoRanking
+	idempotencyKeys      map[uint64]model.IdempotencyKey
+}
+
+func (s *Store) snapshot() snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return snapshot{
+		users:                maps.Clone(s.users),
+		photos:               maps.Clone(s.photos),
+		comments:             maps.Clone(s.comments),
+		socialMedias:         maps.Clone(s.socialMedias),
+		userTokens:           maps.Clone(s.userTokens),
+		recoveryCodes:        maps.Clone(s.recoveryCodes),
+		personalAccessTokens: maps.Clone(s.personalAccessTokens),
+		userIdentities:       maps.Clone(s.userIdentities),
+		sessions:             maps.Clone(s.sessions),
+		loginAttempts:        maps.Clone(s.loginAttempts),
+		follows:              maps.Clone(s.follows),
+		blocks:               maps.Clone(s.blocks),
+		mutes:                maps.Clone(s.mutes),
+		rankings:             maps.Clone(s.rankings),
+		idempotencyKeys:      maps.Clone(s.idempotencyKeys),
+	}
+}
+
+func (s *Store) restore(snap snapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users = snap.users
+	s.photos = snap.photos
+	s.comments = snap.comments
+	s.socialMedias = snap.socialMedias
+	s.userTokens = snap.userTokens
+	s.recoveryCodes = snap.recoveryCodes
+	s.personalAccessTokens = snap.personalAccessTokens
+	s.userIdentities = snap.userIdentities
+	s.sessions = snap.sessions
+	s.loginAttempts = snap.loginAttempts
+	s.follows = snap.follows
+	s.blocks = snap.blocks
+	s.mutes = snap.mutes
+	s.rankings = snap.rankings
+	s.idempotencyKeys = snap.idempotencyKeys
+	// sequences are not transactional in Postgres either
+}
+
+// UnitOfWork rolls the store back to where it was when fn returns an error.
+// It gives atomicity but no isolation: concurrent writers during a failing
+// unit of work are rolled back with it.
+type UnitOfWork struct {
+	store *Store
+}
+
+func NewUnitOfWork(store *Store) *UnitOfWork {
+	return &UnitOfWork{store: store}
+}
+
+type txKey struct{}
+
+func (u *UnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(txKey{}) != nil {
+		return fn(ctx)
+	}
+
+	snap := u.store.snapshot()
+	if err := fn(context.WithValue(ctx, txKey{}, true)); err != nil {
+		u.store.restore(snap)
+		return err
+	}
+	return nil
+}
+
+func uniqueViolation(constraint string) error {
+	return &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           "23505",
+		Message:        fmt.Sprintf("duplicate key value violates unique constraint %q", constraint),
+		ConstraintName: constraint,
+	}
+}
+
+func foreignKeyViolation(constraint string) error {
+	return &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           "23503",
+		Message:        fmt.Sprintf("insert or update on table violates foreign key constraint %q", constraint),
+		ConstraintName: constraint,
+	}
+}
+
+// sortedIDs returns the keys in insertion order, which is what Postgres
+// hands back for these unordered queries on a fresh table.
+func sortedIDs[T any](rows map[uint64]T) []uint64 {
+	ids := make([]uint64, 0, len(rows))
+	for id := range rows {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
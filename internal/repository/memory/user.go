@@ -0,0 +1,272 @@
+package memory
+
+import (
+	"context"
+	"mygram/internal/model"
+	"mygram/internal/repository"
+	"time"
+)
+
+type userQueryImpl struct {
+	store *Store
+}
+
+func NewUserQuery(store *Store) repository.UserQuery {
+	return &userQueryImpl{store: store}
+}
+
+func (u *userQueryImpl) CreateUser(ctx context.Context, user model.User) (model.User, error) {
+	s := u.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkUserUnique(user); err != nil {
+		return model.User{}, err
+	}
+
+	now := s.Now()
+	if user.ID == 0 {
+		user.ID = s.nextID("users")
+	}
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = now
+	}
+	user.UpdatedAt = now
+	s.users[user.ID] = user
+	return user, nil
+}
+
+func (u *userQueryImpl) GetUsersByID(ctx context.Context, id uint64) (model.User, error) {
+	s := u.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[id]
+	if !ok || user.DeletedAt.Valid {
+		return model.User{}, nil
+	}
+	return user, nil
+}
+
+func (u *userQueryImpl) GetUserByEmail(ctx context.Context, email string) (model.User, error) {
+	s := u.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if user.Email == email && !user.DeletedAt.Valid {
+			return user, nil
+		}
+	}
+	return model.User{}, nil
+}
+
+func (u *userQueryImpl) GetUserByUsername(ctx context.Context, username string) (model.User, error) {
+	s := u.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if user.Username == username && !user.DeletedAt.Valid {
+			return user, nil
+		}
+	}
+	return model.User{}, nil
+}
+
+func (u *userQueryImpl) GetUsersByIds(ctx context.Context, ids []uint64, viewerId uint64) ([]model.User, error) {
+	s := u.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := []model.User{}
+	for _, id := range ids {
+		user, ok := s.users[id]
+		if !ok || user.DeletedAt.Valid || s.blocked(id, viewerId) {
+			continue
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+func (u *userQueryImpl) UsernameExists(ctx context.Context, username string) (bool, error) {
+	s := u.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if user.Username == username {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (u *userQueryImpl) EditUser(ctx context.Context, user model.User) error {
+	s := u.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.editUser(user)
+}
+
+func (u *userQueryImpl) EditUserIfUnmodified(ctx context.Context, user model.User, updatedAt time.Time) (bool, error) {
+	s := u.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.users[user.ID]
+	if !ok || current.DeletedAt.Valid || !current.UpdatedAt.Equal(updatedAt) {
+		return false, nil
+	}
+	return true, s.editUser(user)
+}
+
+func (s *Store) editUser(user model.User) error {
+	current, ok := s.users[user.ID]
+	if !ok || current.DeletedAt.Valid {
+		return nil
+	}
+
+	if user.Username != "" {
+		current.Username = user.Username
+	}
+	if user.Email != "" {
+		current.Email = user.Email
+	}
+	if user.Password != "" {
+		current.Password = user.Password
+	}
+	if !user.DoB.IsZero() {
+		current.DoB = user.DoB
+	}
+	if user.EmailVerifiedAt != nil {
+		current.EmailVerifiedAt = user.EmailVerifiedAt
+	}
+	if err := s.checkUserUnique(current); err != nil {
+		return err
+	}
+
+	current.UpdatedAt = s.Now()
+	s.users[user.ID] = current
+	return nil
+}
+
+func (u *userQueryImpl) DeleteUsersByID(ctx context.Context, id uint64) error {
+	s := u.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok || user.DeletedAt.Valid {
+		return nil
+	}
+	user.DeletedAt = s.deletedAt()
+	s.users[id] = user
+	return nil
+}
+
+func (u *userQueryImpl) UpdateTOTP(ctx context.Context, id uint64, secret string, enabledAt *time.Time) error {
+	s := u.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return nil
+	}
+	user.TotpSecret = secret
+	user.TotpEnabledAt = enabledAt
+	user.TotpLastStep = 0
+	user.UpdatedAt = s.Now()
+	s.users[id] = user
+	return nil
+}
+
+func (u *userQueryImpl) UseTOTPStep(ctx context.Context, id uint64, step uint64) (bool, error) {
+	s := u.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok || user.TotpLastStep >= step {
+		return false, nil
+	}
+	user.TotpLastStep = step
+	s.users[id] = user
+	return true, nil
+}
+
+func (u *userQueryImpl) UpdatePrivate(ctx context.Context, id uint64, private bool) error {
+	s := u.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return nil
+	}
+	user.Private = private
+	user.UpdatedAt = s.Now()
+	s.users[id] = user
+	return nil
+}
+
+func (u *userQueryImpl) UpdatePasswordHash(ctx context.Context, id uint64, hash string) error {
+	s := u.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return nil
+	}
+	user.Password = hash
+	s.users[id] = user
+	return nil
+}
+
+func (u *userQueryImpl) UnverifyEmail(ctx context.Context, id uint64) error {
+	s := u.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return nil
+	}
+	user.EmailVerifiedAt = nil
+	user.UpdatedAt = s.Now()
+	s.users[id] = user
+	return nil
+}
+
+// checkUserUnique mirrors the unique constraints on users, which also cover
+// soft deleted rows.
+func (s *Store) checkUserUnique(user model.User) error {
+	for id, other := range s.users {
+		if id == user.ID {
+			continue
+		}
+		if other.Email == user.Email {
+			return uniqueViolation("users_email_key")
+		}
+		if other.Username == user.Username {
+			return uniqueViolation("users_username_key")
+		}
+	}
+	return nil
+}
+
+func (s *Store) userRelation(id uint64) *model.UserRelation {
+	user, ok := s.users[id]
+	if !ok || user.DeletedAt.Valid {
+		return nil
+	}
+	return &model.UserRelation{
+		ID:       user.ID,
+		Username: user.Username,
+		Email:    user.Email,
+	}
+}
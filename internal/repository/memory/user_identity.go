@@ -0,0 +1,48 @@
+package memory
+
+import (
+	"context"
+	"mygram/internal/model"
+	"mygram/internal/repository"
+)
+
+type userIdentityQueryImpl struct {
+	store *Store
+}
+
+func NewUserIdentityQuery(store *Store) repository.UserIdentityQuery {
+	return &userIdentityQueryImpl{store: store}
+}
+
+func (u *userIdentityQueryImpl) GetIdentity(ctx context.Context, provider string, subject string) (model.UserIdentity, error) {
+	s := u.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, identity := range s.userIdentities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return model.UserIdentity{}, nil
+}
+
+func (u *userIdentityQueryImpl) CreateIdentity(ctx context.Context, identity model.UserIdentity) (model.UserIdentity, error) {
+	s := u.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user, ok := s.users[identity.UserId]; !ok || user.DeletedAt.Valid {
+		return model.UserIdentity{}, foreignKeyViolation("fk_user_identities_user_id")
+	}
+	for _, other := range s.userIdentities {
+		if other.Provider == identity.Provider && other.Subject == identity.Subject {
+			return model.UserIdentity{}, uniqueViolation("user_identities_provider_subject_key")
+		}
+	}
+
+	identity.ID = s.nextID("user_identities")
+	identity.CreatedAt = s.Now()
+	s.userIdentities[identity.ID] = identity
+	return identity, nil
+}
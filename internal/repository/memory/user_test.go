@@ -0,0 +1,91 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"mygram/internal/model"
+	"mygram/internal/repository/memory"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestUserUniqueConstraintsCoverSoftDeletedRows(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewUserQuery(memory.NewStore())
+
+	alice, err := repo.CreateUser(ctx, model.User{Username: "alice", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.DeleteUsersByID(ctx, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = repo.CreateUser(ctx, model.User{Username: "alice2", Email: "alice@example.com"})
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "23505" {
+		t.Fatalf("expected unique violation, got %v", err)
+	}
+}
+
+func TestUserSoftDelete(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewUserQuery(memory.NewStore())
+
+	alice, err := repo.CreateUser(ctx, model.User{Username: "alice", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.DeleteUsersByID(ctx, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := repo.GetUsersByID(ctx, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != 0 {
+		t.Fatalf("expected soft deleted user to be hidden, got %+v", got)
+	}
+
+	got, err = repo.GetUserByEmail(ctx, alice.Email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != 0 {
+		t.Fatalf("expected soft deleted user to be hidden, got %+v", got)
+	}
+}
+
+func TestUnitOfWorkRollsBackStore(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	users := memory.NewUserQuery(store)
+	photos := memory.NewPhotoQuery(store)
+	uow := memory.NewUnitOfWork(store)
+
+	alice, err := users.CreateUser(ctx, model.User{Username: "alice", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failed := errors.New("failed")
+	err = uow.Do(ctx, func(ctx context.Context) error {
+		if _, err := photos.CreatePhoto(ctx, model.Photo{Title: "t", PhotoUrl: "u", UserId: alice.ID}); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("expected %v, got %v", failed, err)
+	}
+
+	list, err := photos.GetPhotosByUserId(ctx, alice.ID, alice.ID, model.Expand{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 0 {
+		t.Fatalf("expected rollback, found %d photos", len(list))
+	}
+}
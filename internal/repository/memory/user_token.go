@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"context"
+	"mygram/internal/model"
+	"mygram/internal/repository"
+)
+
+type userTokenQueryImpl struct {
+	store *Store
+}
+
+func NewUserTokenQuery(store *Store) repository.UserTokenQuery {
+	return &userTokenQueryImpl{store: store}
+}
+
+func (u *userTokenQueryImpl) CreateToken(ctx context.Context, token model.UserToken) (model.UserToken, error) {
+	s := u.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user, ok := s.users[token.UserId]; !ok || user.DeletedAt.Valid {
+		return model.UserToken{}, foreignKeyViolation("fk_user_tokens_user_id")
+	}
+	for _, other := range s.userTokens {
+		if other.TokenHash == token.TokenHash {
+			return model.UserToken{}, uniqueViolation("user_tokens_token_hash_key")
+		}
+	}
+
+	token.ID = s.nextID("user_tokens")
+	token.CreatedAt = s.Now()
+	s.userTokens[token.ID] = token
+	return token, nil
+}
+
+func (u *userTokenQueryImpl) GetTokenByHash(ctx context.Context, purpose string, hash string) (model.UserToken, error) {
+	s := u.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, token := range s.userTokens {
+		if token.Purpose == purpose && token.TokenHash == hash {
+			return token, nil
+		}
+	}
+	return model.UserToken{}, nil
+}
+
+func (u *userTokenQueryImpl) UseToken(ctx context.Context, id uint64) (bool, error) {
+	s := u.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.userTokens[id]
+	if !ok || token.UsedAt != nil {
+		return false, nil
+	}
+	now := s.Now()
+	token.UsedAt = &now
+	s.userTokens[id] = token
+	return true, nil
+}
+
+func (u *userTokenQueryImpl) DeleteUnusedTokens(ctx context.Context, userId uint64, purpose string) error {
+	s := u.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, token := range s.userTokens {
+		if token.UserId == userId && token.Purpose == purpose && token.UsedAt == nil {
+			delete(s.userTokens, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package memory
+
+import "mygram/internal/model"
+
+// visibleOwner mirrors repository.visibleOwner: public accounts, the
+// viewer's own, and private accounts that approved the viewer, unless
+// either blocked the other.
+func (s *Store) visibleOwner(ownerId uint64, viewerId uint64) bool {
+	owner, ok := s.users[ownerId]
+	if !ok || owner.DeletedAt.Valid || s.blocked(ownerId, viewerId) {
+		return false
+	}
+	if !owner.Private || ownerId == viewerId {
+		return true
+	}
+	follow, ok := s.follows[followKey{viewerId, ownerId}]
+	return ok && follow.ApprovedAt != nil
+}
+
+// visibleComment mirrors repository.visibleComment.
+func (s *Store) visibleComment(comment model.Comment, viewerId uint64) bool {
+	if comment.UserId == viewerId {
+		return true
+	}
+	photo, ok := s.photos[comment.PhotoId]
+	if !ok || photo.DeletedAt.Valid {
+		return false
+	}
+	return s.visibleOwner(comment.UserId, viewerId) && s.visibleOwner(photo.UserId, viewerId)
+}
+
+// discoverable mirrors repository.discoverable.
+func (s *Store) discoverable(ownerId uint64, viewerId uint64) bool {
+	owner, ok := s.users[ownerId]
+	return ok && !owner.DeletedAt.Valid && !owner.Private && !s.blocked(ownerId, viewerId) && !s.muted(ownerId, viewerId)
+}
+
+func (s *Store) blocked(userId uint64, otherId uint64) bool {
+	_, ok := s.blocks[blockKey{userId, otherId}]
+	if !ok {
+		_, ok = s.blocks[blockKey{otherId, userId}]
+	}
+	return ok
+}
+
+// muted mirrors repository.notMuted, negated.
+func (s *Store) muted(userId uint64, viewerId uint64) bool {
+	_, ok := s.mutes[muteKey{viewerId, userId}]
+	return ok
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"mygram/internal/infrastructure"
+	"mygram/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type MuteQuery interface {
+	CreateMute(ctx context.Context, mute model.Mute) (model.Mute, error)
+	GetMute(ctx context.Context, muterId uint64, mutedId uint64) (model.Mute, error)
+	// DeleteMute reports false when there was no mute.
+	DeleteMute(ctx context.Context, muterId uint64, mutedId uint64) (bool, error)
+	GetMutes(ctx context.Context, muterId uint64) ([]model.ListedUser, error)
+}
+
+type muteQueryImpl struct {
+	db infrastructure.GormPostgres
+}
+
+func NewMuteQuery(db infrastructure.GormPostgres) MuteQuery {
+	return &muteQueryImpl{db: db}
+}
+
+func (m *muteQueryImpl) CreateMute(ctx context.Context, mute model.Mute) (model.Mute, error) {
+	ctx, span := tracer.Start(ctx, "MuteQuery.CreateMute")
+	defer span.End()
+
+	db := m.db.GetWriteConnection(ctx)
+	if err := db.
+		WithContext(ctx).
+		Table("mutes").
+		Create(&mute).Error; err != nil {
+		return model.Mute{}, err
+	}
+	return mute, nil
+}
+
+func (m *muteQueryImpl) GetMute(ctx context.Context, muterId uint64, mutedId uint64) (model.Mute, error) {
+	ctx, span := tracer.Start(ctx, "MuteQuery.GetMute")
+	defer span.End()
+
+	db := m.db.GetReadConnection(ctx)
+	mute := model.Mute{}
+	if err := db.
+		WithContext(ctx).
+		Table("mutes").
+		Where("muter_id = ?", muterId).
+		Where("muted_id = ?", mutedId).
+		Find(&mute).Error; err != nil {
+		return model.Mute{}, err
+	}
+	return mute, nil
+}
+
+func (m *muteQueryImpl) DeleteMute(ctx context.Context, muterId uint64, mutedId uint64) (bool, error) {
+	ctx, span := tracer.Start(ctx, "MuteQuery.DeleteMute")
+	defer span.End()
+
+	db := m.db.GetWriteConnection(ctx)
+	res := db.
+		WithContext(ctx).
+		Table("mutes").
+		Where("muter_id = ?", muterId).
+		Where("muted_id = ?", mutedId).
+		Delete(&model.Mute{})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (m *muteQueryImpl) GetMutes(ctx context.Context, muterId uint64) ([]model.ListedUser, error) {
+	ctx, span := tracer.Start(ctx, "MuteQuery.GetMutes")
+	defer span.End()
+
+	db := m.db.GetReadConnection(ctx)
+	mutes := []model.ListedUser{}
+	if err := db.
+		WithContext(ctx).
+		Table("mutes").
+		Select("muted_id AS user_id, created_at").
+		Where("muter_id = ?", muterId).
+		Where("muted_id IN (SELECT id FROM users WHERE deleted_at IS NULL)").
+		Order("created_at DESC, muted_id ASC").
+		Preload("User", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, username").Table("users").Where("deleted_at is null")
+		}).
+		Find(&mutes).Error; err != nil {
+		return nil, err
+	}
+	return mutes, nil
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"mygram/internal/infrastructure"
+	"mygram/internal/model"
+	"time"
+)
+
+type PersonalAccessTokenQuery interface {
+	CreateToken(ctx context.Context, token model.PersonalAccessToken) (model.PersonalAccessToken, error)
+	// GetTokensByUserId lists the tokens that are not revoked, newest first.
+	GetTokensByUserId(ctx context.Context, userId uint64) ([]model.PersonalAccessToken, error)
+	GetTokenByHash(ctx context.Context, hash string) (model.PersonalAccessToken, error)
+	// RevokeToken reports false when the user has no such active token.
+	RevokeToken(ctx context.Context, userId uint64, id uint64) (bool, error)
+	// RevokeTokensByUserId revokes every active token of the user.
+	RevokeTokensByUserId(ctx context.Context, userId uint64) error
+	TouchToken(ctx context.Context, id uint64, usedAt time.Time) error
+}
+
+type personalAccessTokenQueryImpl struct {
+	db infrastructure.GormPostgres
+}
+
+func NewPersonalAccessTokenQuery(db infrastructure.GormPostgres) PersonalAccessTokenQuery {
+	return &personalAccessTokenQueryImpl{db: db}
+}
+
+func (p *personalAccessTokenQueryImpl) CreateToken(ctx context.Context, token model.PersonalAccessToken) (model.PersonalAccessToken, error) {
+	ctx, span := tracer.Start(ctx, "PersonalAccessTokenQuery.CreateToken")
+	defer span.End()
+
+	db := p.db.GetWriteConnection(ctx)
+	if err := db.
+		WithContext(ctx).
+		Table("personal_access_tokens").
+		Create(&token).Error; err != nil {
+		return model.PersonalAccessToken{}, err
+	}
+	return token, nil
+}
+
+func (p *personalAccessTokenQueryImpl) GetTokensByUserId(ctx context.Context, userId uint64) ([]model.PersonalAccessToken, error) {
+	ctx, span := tracer.Start(ctx, "PersonalAccessTokenQuery.GetTokensByUserId")
+	defer span.End()
+
+	db := p.db.GetReadConnection(ctx)
+	tokens := []model.PersonalAccessToken{}
+	if err := db.
+		WithContext(ctx).
+		Table("personal_access_tokens").
+		Where("user_id = ?", userId).
+		Where("revoked_at IS NULL").
+		Order("id DESC").
+		Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (p *personalAccessTokenQueryImpl) GetTokenByHash(ctx context.Context, hash string) (model.PersonalAccessToken, error) {
+	ctx, span := tracer.Start(ctx, "PersonalAccessTokenQuery.GetTokenByHash")
+	defer span.End()
+
+	// a revoked token has to stop working at once, a lagging replica would
+	// still accept it
+	db := p.db.GetWriteConnection(ctx)
+	token := model.PersonalAccessToken{}
+	if err := db.
+		WithContext(ctx).
+		Table("personal_access_tokens").
+		Where("token_hash = ?", hash).
+		Find(&token).Error; err != nil {
+		return model.PersonalAccessToken{}, err
+	}
+	return token, nil
+}
+
+func (p *personalAccessTokenQueryImpl) RevokeToken(ctx context.Context, userId uint64, id uint64) (bool, error) {
+	ctx, span := tracer.Start(ctx, "PersonalAccessTokenQuery.RevokeToken")
+	defer span.End()
+
+	db := p.db.GetWriteConnection(ctx)
+	res := db.
+		WithContext(ctx).
+		Table("personal_access_tokens").
+		Where("id = ?", id).
+		Where("user_id = ?", userId).
+		Where("revoked_at IS NULL").
+		Update("revoked_at", time.Now())
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (p *personalAccessTokenQueryImpl) RevokeTokensByUserId(ctx context.Context, userId uint64) error {
+	ctx, span := tracer.Start(ctx, "PersonalAccessTokenQuery.RevokeTokensByUserId")
+	defer span.End()
+
+	db := p.db.GetWriteConnection(ctx)
+	return db.
+		WithContext(ctx).
+		Table("personal_access_tokens").
+		Where("user_id = ?", userId).
+		Where("revoked_at IS NULL").
+		Update("revoked_at", time.Now()).
+		Error
+}
+
+func (p *personalAccessTokenQueryImpl) TouchToken(ctx context.Context, id uint64, usedAt time.Time) error {
+	ctx, span := tracer.Start(ctx, "PersonalAccessTokenQuery.TouchToken")
+	defer span.End()
+
+	db := p.db.GetWriteConnection(ctx)
+	if err := db.
+		WithContext(ctx).
+		Table("personal_access_tokens").
+		Where("id = ?", id).
+		Update("last_used_at", usedAt).
+		Error; err != nil {
+		return err
+	}
+	return nil
+}
@@ -4,16 +4,30 @@ import (
 	"context"
 	"mygram/internal/infrastructure"
 	"mygram/internal/model"
-
-	"gorm.io/gorm"
+	"time"
 )
 
 type PhotoQuery interface {
 	CreatePhoto(ctx context.Context, photo model.Photo) (model.Photo, error)
-	GetPhotosByUserId(ctx context.Context, userId uint64) ([]model.PhotoGetRes, error)
+	// GetPhotosByUserId, GetPhotoById and GetExpandedPhoto only return
+	// photos viewerId may see, see visibleOwner.
+	GetPhotosByUserId(ctx context.Context, userId uint64, viewerId uint64, expand model.Expand) ([]model.PhotoGetRes, error)
 	EditPhoto(ctx context.Context, photo model.Photo) error
-	GetPhotoById(ctx context.Context, id uint64) (model.Photo, error)
+	// EditPhotoIfUnmodified is EditPhoto for a photo last updated at
+	// updatedAt. It reports false and changes nothing when the photo was
+	// updated since.
+	EditPhotoIfUnmodified(ctx context.Context, photo model.Photo, updatedAt time.Time) (bool, error)
+	GetPhotoById(ctx context.Context, id uint64, viewerId uint64) (model.Photo, error)
+	GetExpandedPhoto(ctx context.Context, id uint64, viewerId uint64, expand model.Expand) (model.PhotoGetRes, error)
 	DeletePhoto(ctx context.Context, id uint64) error
+	// DeletePhotosByUserId deletes every photo of userId.
+	DeletePhotosByUserId(ctx context.Context, userId uint64) error
+	// GetPhotosByIds returns the photos among ids that viewerId may see, in
+	// no particular order.
+	GetPhotosByIds(ctx context.Context, ids []uint64, viewerId uint64) ([]model.PhotoGetRes, error)
+	// GetPhotosByUserIds is GetPhotosByUserId for several users at once,
+	// without relations, ordered by ID.
+	GetPhotosByUserIds(ctx context.Context, userIds []uint64, viewerId uint64) ([]model.PhotoGetRes, error)
 }
 
 type photoQueryImpl struct {
@@ -25,7 +39,10 @@ func NewPhotoQuery(db infrastructure.GormPostgres) PhotoQuery {
 }
 
 func (p *photoQueryImpl) CreatePhoto(ctx context.Context, photo model.Photo) (model.Photo, error) {
-	db := p.db.GetConnection()
+	ctx, span := tracer.Start(ctx, "PhotoQuery.CreatePhoto")
+	defer span.End()
+
+	db := p.db.GetWriteConnection(ctx)
 
 	if err := db.
 		WithContext(ctx).
@@ -36,18 +53,20 @@ func (p *photoQueryImpl) CreatePhoto(ctx context.Context, photo model.Photo) (mo
 	return photo,nil
 }
 
-func (p *photoQueryImpl) GetPhotosByUserId(ctx context.Context, userId uint64) ([]model.PhotoGetRes, error) {
-	db := p.db.GetConnection()
+func (p *photoQueryImpl) GetPhotosByUserId(ctx context.Context, userId uint64, viewerId uint64, expand model.Expand) ([]model.PhotoGetRes, error) {
+	ctx, span := tracer.Start(ctx, "PhotoQuery.GetPhotosByUserId")
+	defer span.End()
+
+	db := p.db.GetReadConnection(ctx)
 	photos := []model.PhotoGetRes{}
 
-	if err := db.
+	query := db.
 		WithContext(ctx).
 		Table("photos").
 		Where("user_id = ?", userId).
 		Where("deleted_at IS NULL").
-		Preload("User", func(db *gorm.DB) *gorm.DB {
-			return db.Select("id, email, username").Table("users").Where("deleted_at is null")
-		}).
+		Where(visibleOwner("photos.user_id"), viewer(viewerId))
+	if err := expandPhotos(query, expand, viewerId).
 		Find(&photos).
 		Error; err != nil {
 		return nil, err
@@ -57,7 +76,10 @@ func (p *photoQueryImpl) GetPhotosByUserId(ctx context.Context, userId uint64) (
 }
 
 func (p *photoQueryImpl) EditPhoto(ctx context.Context, photo model.Photo) error {
-	db := p.db.GetConnection()
+	ctx, span := tracer.Start(ctx, "PhotoQuery.EditPhoto")
+	defer span.End()
+
+	db := p.db.GetWriteConnection(ctx)
 	if err := db.
 		WithContext(ctx).
 		Table("photos").
@@ -68,14 +90,35 @@ func (p *photoQueryImpl) EditPhoto(ctx context.Context, photo model.Photo) error
 	return nil
 }
 
-func (p *photoQueryImpl) GetPhotoById(ctx context.Context, id uint64) (model.Photo, error) {
-	db := p.db.GetConnection()
+func (p *photoQueryImpl) EditPhotoIfUnmodified(ctx context.Context, photo model.Photo, updatedAt time.Time) (bool, error) {
+	ctx, span := tracer.Start(ctx, "PhotoQuery.EditPhotoIfUnmodified")
+	defer span.End()
+
+	db := p.db.GetWriteConnection(ctx)
+	res := db.
+		WithContext(ctx).
+		Table("photos").
+		Where("updated_at = ?", updatedAt).
+		Where("deleted_at IS NULL").
+		Updates(&photo)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (p *photoQueryImpl) GetPhotoById(ctx context.Context, id uint64, viewerId uint64) (model.Photo, error) {
+	ctx, span := tracer.Start(ctx, "PhotoQuery.GetPhotoById")
+	defer span.End()
+
+	db := p.db.GetReadConnection(ctx)
 	photo := model.Photo{}
 	if err := db.
 		WithContext(ctx).
 		Table("photos").
 		Where("id = ?", id).
 		Where("deleted_at IS NULL").
+		Where(visibleOwner("photos.user_id"), viewer(viewerId)).
 		Find(&photo).
 		Error; err != nil {
 		return model.Photo{}, err
@@ -86,8 +129,32 @@ func (p *photoQueryImpl) GetPhotoById(ctx context.Context, id uint64) (model.Pho
 
 
 
+func (p *photoQueryImpl) GetExpandedPhoto(ctx context.Context, id uint64, viewerId uint64, expand model.Expand) (model.PhotoGetRes, error) {
+	ctx, span := tracer.Start(ctx, "PhotoQuery.GetExpandedPhoto")
+	defer span.End()
+
+	db := p.db.GetReadConnection(ctx)
+	photo := model.PhotoGetRes{}
+	query := db.
+		WithContext(ctx).
+		Table("photos").
+		Where("id = ?", id).
+		Where("deleted_at IS NULL").
+		Where(visibleOwner("photos.user_id"), viewer(viewerId))
+	if err := expandPhotos(query, expand, viewerId).
+		Find(&photo).
+		Error; err != nil {
+		return model.PhotoGetRes{}, err
+	}
+
+	return photo, nil
+}
+
 func (p *photoQueryImpl) DeletePhoto(ctx context.Context, id uint64) error {
-	db := p.db.GetConnection()
+	ctx, span := tracer.Start(ctx, "PhotoQuery.DeletePhoto")
+	defer span.End()
+
+	db := p.db.GetWriteConnection(ctx)
 
 	if err := db.
 		WithContext(ctx).
@@ -99,3 +166,66 @@ func (p *photoQueryImpl) DeletePhoto(ctx context.Context, id uint64) error {
 
 	return nil
 }
+
+func (p *photoQueryImpl) DeletePhotosByUserId(ctx context.Context, userId uint64) error {
+	ctx, span := tracer.Start(ctx, "PhotoQuery.DeletePhotosByUserId")
+	defer span.End()
+
+	db := p.db.GetWriteConnection(ctx)
+	return db.
+		WithContext(ctx).
+		Table("photos").
+		Where("user_id = ?", userId).
+		Delete(&model.Photo{}).
+		Error
+}
+
+func (p *photoQueryImpl) GetPhotosByIds(ctx context.Context, ids []uint64, viewerId uint64) ([]model.PhotoGetRes, error) {
+	ctx, span := tracer.Start(ctx, "PhotoQuery.GetPhotosByIds")
+	defer span.End()
+
+	db := p.db.GetReadConnection(ctx)
+	photos := []model.PhotoGetRes{}
+	if len(ids) == 0 {
+		return photos, nil
+	}
+
+	if err := db.
+		WithContext(ctx).
+		Table("photos").
+		Where("id IN ?", ids).
+		Where("deleted_at IS NULL").
+		Where(visibleOwner("photos.user_id"), viewer(viewerId)).
+		Preload("User", userRelation).
+		Find(&photos).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return photos, nil
+}
+
+func (p *photoQueryImpl) GetPhotosByUserIds(ctx context.Context, userIds []uint64, viewerId uint64) ([]model.PhotoGetRes, error) {
+	ctx, span := tracer.Start(ctx, "PhotoQuery.GetPhotosByUserIds")
+	defer span.End()
+
+	db := p.db.GetReadConnection(ctx)
+	photos := []model.PhotoGetRes{}
+	if len(userIds) == 0 {
+		return photos, nil
+	}
+
+	if err := db.
+		WithContext(ctx).
+		Table("photos").
+		Where("user_id IN ?", userIds).
+		Where("deleted_at IS NULL").
+		Where(visibleOwner("photos.user_id"), viewer(viewerId)).
+		Order("id").
+		Find(&photos).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return photos, nil
+}
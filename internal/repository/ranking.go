@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"mygram/internal/infrastructure"
+	"mygram/internal/model"
+	"time"
+)
+
+// RankingQuery stores the explore ranking computed by ranking.Job.
+type RankingQuery interface {
+	// GetEngagement returns the photos created since then with what they
+	// are scored on.
+	GetEngagement(ctx context.Context, since time.Time) ([]model.PhotoEngagement, error)
+	// ReplaceRankings swaps the whole ranking for a new one.
+	ReplaceRankings(ctx context.Context, rankings []model.PhotoRanking) error
+	// GetRankedPhotos returns ranked photos, those using the hashtag when
+	// tag is not empty, best first. Only discoverable photos are returned,
+	// see discoverable.
+	GetRankedPhotos(ctx context.Context, tag string, limit int, offset int, viewerId uint64) ([]model.PhotoGetRes, error)
+	// GetTrendingHashtags ranks hashtags by the scores of the public photos
+	// using them.
+	GetTrendingHashtags(ctx context.Context, limit int) ([]model.TrendingHashtag, error)
+}
+
+type rankingQueryImpl struct {
+	db infrastructure.GormPostgres
+}
+
+func NewRankingQuery(db infrastructure.GormPostgres) RankingQuery {
+	return &rankingQueryImpl{db: db}
+}
+
+func (r *rankingQueryImpl) GetEngagement(ctx context.Context, since time.Time) ([]model.PhotoEngagement, error) {
+	ctx, span := tracer.Start(ctx, "RankingQuery.GetEngagement")
+	defer span.End()
+
+	db := r.db.GetReadConnection(ctx)
+	engagement := []model.PhotoEngagement{}
+	if err := db.
+		WithContext(ctx).
+		Table("photos p").
+		Select("p.id AS photo_id, p.created_at, COUNT(c.id) AS comments").
+		Joins("LEFT JOIN comments c ON c.photo_id = p.id AND c.deleted_at IS NULL AND c.user_id <> p.user_id").
+		Where("p.deleted_at IS NULL").
+		Where("p.created_at >= ?", since).
+		Group("p.id, p.created_at").
+		Scan(&engagement).Error; err != nil {
+		return nil, err
+	}
+	return engagement, nil
+}
+
+func (r *rankingQueryImpl) ReplaceRankings(ctx context.Context, rankings []model.PhotoRanking) error {
+	ctx, span := tracer.Start(ctx, "RankingQuery.ReplaceRankings")
+	defer span.End()
+
+	db := r.db.GetWriteConnection(ctx).WithContext(ctx)
+	if err := db.Exec("DELETE FROM photo_rankings").Error; err != nil {
+		return err
+	}
+	if len(rankings) == 0 {
+		return nil
+	}
+	return db.Table("photo_rankings").CreateInBatches(rankings, 500).Error
+}
+
+func (r *rankingQueryImpl) GetRankedPhotos(ctx context.Context, tag string, limit int, offset int, viewerId uint64) ([]model.PhotoGetRes, error) {
+	ctx, span := tracer.Start(ctx, "RankingQuery.GetRankedPhotos")
+	defer span.End()
+
+	db := r.db.GetReadConnection(ctx)
+	query := db.
+		WithContext(ctx).
+		Table("photos").
+		Select("photos.*").
+		Joins("JOIN photo_rankings r ON r.photo_id = photos.id").
+		Where("photos.deleted_at IS NULL").
+		Where(discoverable("photos.user_id"), viewer(viewerId))
+	if tag != "" {
+		query = query.Where("photos.id IN (SELECT h.photo_id FROM photo_hashtags h WHERE h.tag = ?)", tag)
+	}
+
+	photos := []model.PhotoGetRes{}
+	if err := query.
+		Order("r.score DESC, photos.id DESC").
+		Limit(limit).
+		Offset(offset).
+		Preload("User", userRelation).
+		Find(&photos).Error; err != nil {
+		return nil, err
+	}
+	return photos, nil
+}
+
+func (r *rankingQueryImpl) GetTrendingHashtags(ctx context.Context, limit int) ([]model.TrendingHashtag, error) {
+	ctx, span := tracer.Start(ctx, "RankingQuery.GetTrendingHashtags")
+	defer span.End()
+
+	db := r.db.GetReadConnection(ctx)
+	tags := []model.TrendingHashtag{}
+	if err := db.
+		WithContext(ctx).
+		Table("photo_hashtags h").
+		Select("h.tag, COUNT(*) AS photos, SUM(r.score) AS score").
+		Joins("JOIN photo_rankings r ON r.photo_id = h.photo_id").
+		Joins("JOIN photos p ON p.id = h.photo_id AND p.deleted_at IS NULL").
+		Joins("JOIN users u ON u.id = p.user_id AND u.deleted_at IS NULL AND u.private = false").
+		Group("h.tag").
+		Order("score DESC, h.tag ASC").
+		Limit(limit).
+		Scan(&tags).Error; err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"mygram/internal/infrastructure"
+	"mygram/internal/model"
+	"time"
+)
+
+type RecoveryCodeQuery interface {
+	// ReplaceRecoveryCodes drops every code of the user and stores hashes.
+	ReplaceRecoveryCodes(ctx context.Context, userId uint64, hashes []string) error
+	// UseRecoveryCode marks the matching unused code used and reports
+	// whether there was one.
+	UseRecoveryCode(ctx context.Context, userId uint64, hash string) (bool, error)
+	DeleteRecoveryCodes(ctx context.Context, userId uint64) error
+}
+
+type recoveryCodeQueryImpl struct {
+	db infrastructure.GormPostgres
+}
+
+func NewRecoveryCodeQuery(db infrastructure.GormPostgres) RecoveryCodeQuery {
+	return &recoveryCodeQueryImpl{db: db}
+}
+
+func (r *recoveryCodeQueryImpl) ReplaceRecoveryCodes(ctx context.Context, userId uint64, hashes []string) error {
+	ctx, span := tracer.Start(ctx, "RecoveryCodeQuery.ReplaceRecoveryCodes")
+	defer span.End()
+
+	if err := r.DeleteRecoveryCodes(ctx, userId); err != nil {
+		return err
+	}
+
+	codes := make([]model.RecoveryCode, 0, len(hashes))
+	for _, hash := range hashes {
+		codes = append(codes, model.RecoveryCode{UserId: userId, CodeHash: hash})
+	}
+
+	db := r.db.GetWriteConnection(ctx)
+	if err := db.
+		WithContext(ctx).
+		Table("user_recovery_codes").
+		Create(&codes).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *recoveryCodeQueryImpl) UseRecoveryCode(ctx context.Context, userId uint64, hash string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "RecoveryCodeQuery.UseRecoveryCode")
+	defer span.End()
+
+	db := r.db.GetWriteConnection(ctx)
+	res := db.
+		WithContext(ctx).
+		Table("user_recovery_codes").
+		Where("user_id = ?", userId).
+		Where("code_hash = ?", hash).
+		Where("used_at IS NULL").
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+func (r *recoveryCodeQueryImpl) DeleteRecoveryCodes(ctx context.Context, userId uint64) error {
+	ctx, span := tracer.Start(ctx, "RecoveryCodeQuery.DeleteRecoveryCodes")
+	defer span.End()
+
+	db := r.db.GetWriteConnection(ctx)
+	if err := db.
+		WithContext(ctx).
+		Table("user_recovery_codes").
+		Where("user_id = ?", userId).
+		Delete(&model.RecoveryCode{}).
+		Error; err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"mygram/internal/infrastructure"
+	"mygram/internal/model"
+	"time"
+)
+
+type SessionQuery interface {
+	CreateSession(ctx context.Context, session model.Session) (model.Session, error)
+	GetSessionByJti(ctx context.Context, jti string) (model.Session, error)
+	// GetActiveSessionsByUserId lists the sessions that are neither revoked
+	// nor expired, most recently seen first.
+	GetActiveSessionsByUserId(ctx context.Context, userId uint64) ([]model.Session, error)
+	// HasSessions reports whether the user ever had a session, and one
+	// from userAgent.
+	HasSessions(ctx context.Context, userId uint64, userAgent string) (ever bool, fromUserAgent bool, err error)
+	// RevokeSession reports false when the user has no such active session.
+	RevokeSession(ctx context.Context, userId uint64, id uint64) (bool, error)
+	// RevokeOtherSessions revokes every active session of the user except
+	// keepId, 0 keeping none, and returns how many.
+	RevokeOtherSessions(ctx context.Context, userId uint64, keepId uint64) (int64, error)
+	TouchSession(ctx context.Context, id uint64, seenAt time.Time) error
+}
+
+type sessionQueryImpl struct {
+	db infrastructure.GormPostgres
+}
+
+func NewSessionQuery(db infrastructure.GormPostgres) SessionQuery {
+	return &sessionQueryImpl{db: db}
+}
+
+func (s *sessionQueryImpl) CreateSession(ctx context.Context, session model.Session) (model.Session, error) {
+	ctx, span := tracer.Start(ctx, "SessionQuery.CreateSession")
+	defer span.End()
+
+	db := s.db.GetWriteConnection(ctx)
+	if err := db.
+		WithContext(ctx).
+		Table("user_sessions").
+		Create(&session).Error; err != nil {
+		return model.Session{}, err
+	}
+	return session, nil
+}
+
+// GetSessionByJti runs on every authenticated request, against the primary
+// so a session is usable right after login and dead right after revocation.
+func (s *sessionQueryImpl) GetSessionByJti(ctx context.Context, jti string) (model.Session, error) {
+	ctx, span := tracer.Start(ctx, "SessionQuery.GetSessionByJti")
+	defer span.End()
+
+	db := s.db.GetWriteConnection(ctx)
+	session := model.Session{}
+	if err := db.
+		WithContext(ctx).
+		Table("user_sessions").
+		Where("jti = ?", jti).
+		Find(&session).Error; err != nil {
+		return model.Session{}, err
+	}
+	return session, nil
+}
+
+func (s *sessionQueryImpl) GetActiveSessionsByUserId(ctx context.Context, userId uint64) ([]model.Session, error) {
+	ctx, span := tracer.Start(ctx, "SessionQuery.GetActiveSessionsByUserId")
+	defer span.End()
+
+	db := s.db.GetReadConnection(ctx)
+	sessions := []model.Session{}
+	if err := db.
+		WithContext(ctx).
+		Table("user_sessions").
+		Where("user_id = ?", userId).
+		Where("revoked_at IS NULL").
+		Where("expires_at > ?", time.Now()).
+		Order("last_seen_at DESC, id DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (s *sessionQueryImpl) HasSessions(ctx context.Context, userId uint64, userAgent string) (bool, bool, error) {
+	ctx, span := tracer.Start(ctx, "SessionQuery.HasSessions")
+	defer span.End()
+
+	db := s.db.GetReadConnection(ctx)
+	res := struct {
+		Total         int64
+		FromUserAgent int64
+	}{}
+	if err := db.
+		WithContext(ctx).
+		Table("user_sessions").
+		Select("count(*) AS total, count(*) FILTER (WHERE user_agent = ?) AS from_user_agent", userAgent).
+		Where("user_id = ?", userId).
+		Scan(&res).Error; err != nil {
+		return false, false, err
+	}
+	return res.Total > 0, res.FromUserAgent > 0, nil
+}
+
+func (s *sessionQueryImpl) RevokeSession(ctx context.Context, userId uint64, id uint64) (bool, error) {
+	ctx, span := tracer.Start(ctx, "SessionQuery.RevokeSession")
+	defer span.End()
+
+	db := s.db.GetWriteConnection(ctx)
+	res := db.
+		WithContext(ctx).
+		Table("user_sessions").
+		Where("id = ?", id).
+		Where("user_id = ?", userId).
+		Where("revoked_at IS NULL").
+		Where("expires_at > ?", time.Now()).
+		Update("revoked_at", time.Now())
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (s *sessionQueryImpl) RevokeOtherSessions(ctx context.Context, userId uint64, keepId uint64) (int64, error) {
+	ctx, span := tracer.Start(ctx, "SessionQuery.RevokeOtherSessions")
+	defer span.End()
+
+	db := s.db.GetWriteConnection(ctx)
+	res := db.
+		WithContext(ctx).
+		Table("user_sessions").
+		Where("user_id = ?", userId).
+		Where("id <> ?", keepId).
+		Where("revoked_at IS NULL").
+		Where("expires_at > ?", time.Now()).
+		Update("revoked_at", time.Now())
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	return res.RowsAffected, nil
+}
+
+func (s *sessionQueryImpl) TouchSession(ctx context.Context, id uint64, seenAt time.Time) error {
+	ctx, span := tracer.Start(ctx, "SessionQuery.TouchSession")
+	defer span.End()
+
+	db := s.db.GetWriteConnection(ctx)
+	if err := db.
+		WithContext(ctx).
+		Table("user_sessions").
+		Where("id = ?", id).
+		Update("last_seen_at", seenAt).
+		Error; err != nil {
+		return err
+	}
+	return nil
+}
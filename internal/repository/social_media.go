@@ -4,16 +4,28 @@ import (
 	"context"
 	"mygram/internal/infrastructure"
 	"mygram/internal/model"
-
-	"gorm.io/gorm"
+	"time"
 )
 
 type SocialMediaQuery interface {
 	CreateSocialMedia(ctx context.Context, social model.SocialMedia) (model.SocialMedia, error)
-	GetSocialMediasByUserId(ctx context.Context, userId uint64) ([]model.SocialMediaGetRes, error)
+	// GetSocialMediasByUserId, GetSocialMediaById and
+	// GetExpandedSocialMedia only return social medias viewerId may see, see
+	// visibleOwner.
+	GetSocialMediasByUserId(ctx context.Context, userId uint64, viewerId uint64, expand model.Expand) ([]model.SocialMediaGetRes, error)
 	EditSocialMedia(ctx context.Context, social model.SocialMedia) error
-	GetSocialMediaById(ctx context.Context, id uint64) (model.SocialMedia, error)
+	// EditSocialMediaIfUnmodified is EditSocialMedia for a social media last updated at
+	// updatedAt. It reports false and changes nothing when the social media was
+	// updated since.
+	EditSocialMediaIfUnmodified(ctx context.Context, social model.SocialMedia, updatedAt time.Time) (bool, error)
+	GetSocialMediaById(ctx context.Context, id uint64, viewerId uint64) (model.SocialMedia, error)
+	GetExpandedSocialMedia(ctx context.Context, id uint64, viewerId uint64, expand model.Expand) (model.SocialMediaGetRes, error)
 	DeleteSocialMedia(ctx context.Context, id uint64) error
+	// DeleteSocialMediasByUserId deletes every social media of userId.
+	DeleteSocialMediasByUserId(ctx context.Context, userId uint64) error
+	// GetSocialMediasByUserIds is GetSocialMediasByUserId for several users
+	// at once, without relations, ordered by ID.
+	GetSocialMediasByUserIds(ctx context.Context, userIds []uint64, viewerId uint64) ([]model.SocialMediaGetRes, error)
 }
 
 type socialMediaQueryImpl struct {
@@ -25,7 +37,10 @@ func NewSocialMediaQuery(db infrastructure.GormPostgres) SocialMediaQuery {
 }
 
 func (s *socialMediaQueryImpl) CreateSocialMedia(ctx context.Context, social model.SocialMedia) (model.SocialMedia, error) {
-	db := s.db.GetConnection()
+	ctx, span := tracer.Start(ctx, "SocialMediaQuery.CreateSocialMedia")
+	defer span.End()
+
+	db := s.db.GetWriteConnection(ctx)
 
 	if err := db.
 		WithContext(ctx).
@@ -38,18 +53,20 @@ func (s *socialMediaQueryImpl) CreateSocialMedia(ctx context.Context, social mod
 	return social, nil
 }
 
-func (s *socialMediaQueryImpl) GetSocialMediasByUserId(ctx context.Context, userId uint64) ([]model.SocialMediaGetRes, error) {
-	db := s.db.GetConnection()
+func (s *socialMediaQueryImpl) GetSocialMediasByUserId(ctx context.Context, userId uint64, viewerId uint64, expand model.Expand) ([]model.SocialMediaGetRes, error) {
+	ctx, span := tracer.Start(ctx, "SocialMediaQuery.GetSocialMediasByUserId")
+	defer span.End()
+
+	db := s.db.GetReadConnection(ctx)
 	socials := []model.SocialMediaGetRes{}
 
-	if err := db.
+	query := db.
 		WithContext(ctx).
 		Table("social_medias").
 		Where("user_id = ?", userId).
 		Where("deleted_at IS NULL").
-		Preload("User", func(db *gorm.DB) *gorm.DB {
-			return db.Select("id, email, username").Table("users").Where("deleted_at is null")
-		}).
+		Where(visibleOwner("social_medias.user_id"), viewer(viewerId))
+	if err := expandSocialMedias(query, expand).
 		Find(&socials).
 		Error; err != nil {
 		return nil, err
@@ -59,7 +76,10 @@ func (s *socialMediaQueryImpl) GetSocialMediasByUserId(ctx context.Context, user
 }
 
 func (s *socialMediaQueryImpl) EditSocialMedia(ctx context.Context, social model.SocialMedia) error {
-	db := s.db.GetConnection()
+	ctx, span := tracer.Start(ctx, "SocialMediaQuery.EditSocialMedia")
+	defer span.End()
+
+	db := s.db.GetWriteConnection(ctx)
 	if err := db.
 		WithContext(ctx).
 		Table("social_medias").
@@ -71,8 +91,28 @@ func (s *socialMediaQueryImpl) EditSocialMedia(ctx context.Context, social model
 	return nil
 }
 
-func (s *socialMediaQueryImpl) GetSocialMediaById(ctx context.Context, id uint64) (model.SocialMedia, error) {
-	db := s.db.GetConnection()
+func (s *socialMediaQueryImpl) EditSocialMediaIfUnmodified(ctx context.Context, social model.SocialMedia, updatedAt time.Time) (bool, error) {
+	ctx, span := tracer.Start(ctx, "SocialMediaQuery.EditSocialMediaIfUnmodified")
+	defer span.End()
+
+	db := s.db.GetWriteConnection(ctx)
+	res := db.
+		WithContext(ctx).
+		Table("social_medias").
+		Where("updated_at = ?", updatedAt).
+		Where("deleted_at IS NULL").
+		Updates(&social)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (s *socialMediaQueryImpl) GetSocialMediaById(ctx context.Context, id uint64, viewerId uint64) (model.SocialMedia, error) {
+	ctx, span := tracer.Start(ctx, "SocialMediaQuery.GetSocialMediaById")
+	defer span.End()
+
+	db := s.db.GetReadConnection(ctx)
 	social := model.SocialMedia{}
 
 	if err := db.
@@ -80,6 +120,7 @@ func (s *socialMediaQueryImpl) GetSocialMediaById(ctx context.Context, id uint64
 		Table("social_medias").
 		Where("id = ?", id).
 		Where("deleted_at IS NULL").
+		Where(visibleOwner("social_medias.user_id"), viewer(viewerId)).
 		Find(&social).
 		Error; err != nil {
 		return model.SocialMedia{}, err
@@ -88,8 +129,32 @@ func (s *socialMediaQueryImpl) GetSocialMediaById(ctx context.Context, id uint64
 	return social, nil
 }
 
+func (s *socialMediaQueryImpl) GetExpandedSocialMedia(ctx context.Context, id uint64, viewerId uint64, expand model.Expand) (model.SocialMediaGetRes, error) {
+	ctx, span := tracer.Start(ctx, "SocialMediaQuery.GetExpandedSocialMedia")
+	defer span.End()
+
+	db := s.db.GetReadConnection(ctx)
+	social := model.SocialMediaGetRes{}
+	query := db.
+		WithContext(ctx).
+		Table("social_medias").
+		Where("id = ?", id).
+		Where("deleted_at IS NULL").
+		Where(visibleOwner("social_medias.user_id"), viewer(viewerId))
+	if err := expandSocialMedias(query, expand).
+		Find(&social).
+		Error; err != nil {
+		return model.SocialMediaGetRes{}, err
+	}
+
+	return social, nil
+}
+
 func (s *socialMediaQueryImpl) DeleteSocialMedia(ctx context.Context, id uint64) error {
-	db := s.db.GetConnection()
+	ctx, span := tracer.Start(ctx, "SocialMediaQuery.DeleteSocialMedia")
+	defer span.End()
+
+	db := s.db.GetWriteConnection(ctx)
 
 	if err := db.
 		WithContext(ctx).
@@ -101,3 +166,41 @@ func (s *socialMediaQueryImpl) DeleteSocialMedia(ctx context.Context, id uint64)
 
 	return nil
 }
+
+func (s *socialMediaQueryImpl) DeleteSocialMediasByUserId(ctx context.Context, userId uint64) error {
+	ctx, span := tracer.Start(ctx, "SocialMediaQuery.DeleteSocialMediasByUserId")
+	defer span.End()
+
+	db := s.db.GetWriteConnection(ctx)
+	return db.
+		WithContext(ctx).
+		Table("social_medias").
+		Where("user_id = ?", userId).
+		Delete(&model.SocialMedia{}).
+		Error
+}
+
+func (s *socialMediaQueryImpl) GetSocialMediasByUserIds(ctx context.Context, userIds []uint64, viewerId uint64) ([]model.SocialMediaGetRes, error) {
+	ctx, span := tracer.Start(ctx, "SocialMediaQuery.GetSocialMediasByUserIds")
+	defer span.End()
+
+	db := s.db.GetReadConnection(ctx)
+	socials := []model.SocialMediaGetRes{}
+	if len(userIds) == 0 {
+		return socials, nil
+	}
+
+	if err := db.
+		WithContext(ctx).
+		Table("social_medias").
+		Where("user_id IN ?", userIds).
+		Where("deleted_at IS NULL").
+		Where(visibleOwner("social_medias.user_id"), viewer(viewerId)).
+		Order("id").
+		Find(&socials).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return socials, nil
+}
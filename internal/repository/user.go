@@ -4,14 +4,41 @@ import (
 	"context"
 	"mygram/internal/infrastructure"
 	"mygram/internal/model"
+	"time"
 )
 
 type UserQuery interface {
 	GetUserByEmail(ctx context.Context, email string) (model.User, error)
+	GetUserByUsername(ctx context.Context, username string) (model.User, error)
+	// GetUsersByIds returns the users among ids that are not blocked either
+	// way with viewerId, in no particular order.
+	GetUsersByIds(ctx context.Context, ids []uint64, viewerId uint64) ([]model.User, error)
 	GetUsersByID(ctx context.Context, id uint64) (model.User, error)
 	EditUser(ctx context.Context, user model.User) error
+	// EditUserIfUnmodified is EditUser for a user last updated at
+	// updatedAt. It reports false and changes nothing when the user was
+	// updated since.
+	EditUserIfUnmodified(ctx context.Context, user model.User, updatedAt time.Time) (bool, error)
 	DeleteUsersByID(ctx context.Context, id uint64) error
 	CreateUser(ctx context.Context, user model.User) (model.User, error)
+	// UsernameExists also counts soft deleted users, whose usernames stay
+	// taken.
+	UsernameExists(ctx context.Context, username string) (bool, error)
+
+	// UpdateTOTP sets the TOTP secret and when it was enabled, an empty
+	// secret and nil turn two-factor authentication off.
+	UpdateTOTP(ctx context.Context, id uint64, secret string, enabledAt *time.Time) error
+	// UseTOTPStep records the time step of an accepted TOTP code and
+	// reports false when the step, or a later one, was already used.
+	UseTOTPStep(ctx context.Context, id uint64, step uint64) (bool, error)
+
+	UpdatePrivate(ctx context.Context, id uint64, private bool) error
+	// UpdatePasswordHash replaces the hash of the same password, keeping
+	// updated_at since nothing a client sees changed.
+	UpdatePasswordHash(ctx context.Context, id uint64, hash string) error
+	// UnverifyEmail clears email_verified_at, which EditUser cannot since it
+	// skips zero values.
+	UnverifyEmail(ctx context.Context, id uint64) error
 }
 
 type UserCommand interface {
@@ -27,7 +54,10 @@ func NewUserQuery(db infrastructure.GormPostgres) UserQuery {
 }
 
 func (u *userQueryImpl) CreateUser(ctx context.Context, user model.User) (model.User, error) {
-	db := u.db.GetConnection()
+	ctx, span := tracer.Start(ctx, "UserQuery.CreateUser")
+	defer span.End()
+
+	db := u.db.GetWriteConnection(ctx)
 	if err := db.
 		WithContext(ctx).
 		Table("users").
@@ -38,7 +68,10 @@ func (u *userQueryImpl) CreateUser(ctx context.Context, user model.User) (model.
 }
 
 func (u *userQueryImpl) GetUsersByID(ctx context.Context, id uint64) (model.User, error) {
-	db := u.db.GetConnection()
+	ctx, span := tracer.Start(ctx, "UserQuery.GetUsersByID")
+	defer span.End()
+
+	db := u.db.GetReadConnection(ctx)
 	users := model.User{}
 	if err := db.
 			WithContext(ctx).
@@ -46,13 +79,16 @@ func (u *userQueryImpl) GetUsersByID(ctx context.Context, id uint64) (model.User
 			Where("id = ?", id).
 			Where("deleted_at IS NULL").
 			Find(&users).Error; err != nil {
-		return model.User{}, nil
+		return model.User{}, err
 	}
 	return users, nil
 }
 
 func (u *userQueryImpl) GetUserByEmail(ctx context.Context, email string) (model.User, error) {
-	db := u.db.GetConnection()
+	ctx, span := tracer.Start(ctx, "UserQuery.GetUserByEmail")
+	defer span.End()
+
+	db := u.db.GetReadConnection(ctx)
 	user := model.User{}
 	if err := db.
 			WithContext(ctx).
@@ -60,13 +96,70 @@ func (u *userQueryImpl) GetUserByEmail(ctx context.Context, email string) (model
 			Where("email = ?", email).
 			Where("deleted_at IS NULL").
 			Find(&user).Error; err != nil {
-		return model.User{}, nil
+		return model.User{}, err
+	}
+	return user, nil
+}
+
+func (u *userQueryImpl) GetUserByUsername(ctx context.Context, username string) (model.User, error) {
+	ctx, span := tracer.Start(ctx, "UserQuery.GetUserByUsername")
+	defer span.End()
+
+	db := u.db.GetReadConnection(ctx)
+	user := model.User{}
+	if err := db.
+		WithContext(ctx).
+		Table("users").
+		Where("username = ?", username).
+		Where("deleted_at IS NULL").
+		Find(&user).Error; err != nil {
+		return model.User{}, err
 	}
 	return user, nil
 }
 
+func (u *userQueryImpl) GetUsersByIds(ctx context.Context, ids []uint64, viewerId uint64) ([]model.User, error) {
+	ctx, span := tracer.Start(ctx, "UserQuery.GetUsersByIds")
+	defer span.End()
+
+	db := u.db.GetReadConnection(ctx)
+	users := []model.User{}
+	if len(ids) == 0 {
+		return users, nil
+	}
+	if err := db.
+		WithContext(ctx).
+		Table("users").
+		Where("id IN ?", ids).
+		Where("deleted_at IS NULL").
+		Where(notBlocked("users.id"), viewer(viewerId)).
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (u *userQueryImpl) UsernameExists(ctx context.Context, username string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "UserQuery.UsernameExists")
+	defer span.End()
+
+	db := u.db.GetReadConnection(ctx)
+	var count int64
+	if err := db.
+		WithContext(ctx).
+		Table("users").
+		Where("username = ?", username).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
 func (u *userQueryImpl) EditUser(ctx context.Context, user model.User) error {
-	db := u.db.GetConnection()
+	ctx, span := tracer.Start(ctx, "UserQuery.EditUser")
+	defer span.End()
+
+	db := u.db.GetWriteConnection(ctx)
 	if err := db.
 		WithContext(ctx).
 		Table("users").
@@ -77,8 +170,28 @@ func (u *userQueryImpl) EditUser(ctx context.Context, user model.User) error {
 	return nil
 }
 
+func (u *userQueryImpl) EditUserIfUnmodified(ctx context.Context, user model.User, updatedAt time.Time) (bool, error) {
+	ctx, span := tracer.Start(ctx, "UserQuery.EditUserIfUnmodified")
+	defer span.End()
+
+	db := u.db.GetWriteConnection(ctx)
+	res := db.
+		WithContext(ctx).
+		Table("users").
+		Where("updated_at = ?", updatedAt).
+		Where("deleted_at IS NULL").
+		Updates(&user)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
 func (u *userQueryImpl) DeleteUsersByID(ctx context.Context, id uint64) error {
-	db := u.db.GetConnection()
+	ctx, span := tracer.Start(ctx, "UserQuery.DeleteUsersByID")
+	defer span.End()
+
+	db := u.db.GetWriteConnection(ctx)
 	if err := db.
 		WithContext(ctx).
 		Table("users").
@@ -87,4 +200,96 @@ func (u *userQueryImpl) DeleteUsersByID(ctx context.Context, id uint64) error {
 		return err
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+func (u *userQueryImpl) UpdateTOTP(ctx context.Context, id uint64, secret string, enabledAt *time.Time) error {
+	ctx, span := tracer.Start(ctx, "UserQuery.UpdateTOTP")
+	defer span.End()
+
+	db := u.db.GetWriteConnection(ctx)
+	if err := db.
+		WithContext(ctx).
+		Table("users").
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"totp_secret":     secret,
+			"totp_enabled_at": enabledAt,
+			"totp_last_step":  0,
+			"updated_at":      time.Now(),
+		}).
+		Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (u *userQueryImpl) UseTOTPStep(ctx context.Context, id uint64, step uint64) (bool, error) {
+	ctx, span := tracer.Start(ctx, "UserQuery.UseTOTPStep")
+	defer span.End()
+
+	db := u.db.GetWriteConnection(ctx)
+	res := db.
+		WithContext(ctx).
+		Table("users").
+		Where("id = ?", id).
+		Where("totp_last_step < ?", step).
+		Update("totp_last_step", step)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (u *userQueryImpl) UpdatePrivate(ctx context.Context, id uint64, private bool) error {
+	ctx, span := tracer.Start(ctx, "UserQuery.UpdatePrivate")
+	defer span.End()
+
+	db := u.db.GetWriteConnection(ctx)
+	if err := db.
+		WithContext(ctx).
+		Table("users").
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"private":    private,
+			"updated_at": time.Now(),
+		}).
+		Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (u *userQueryImpl) UpdatePasswordHash(ctx context.Context, id uint64, hash string) error {
+	ctx, span := tracer.Start(ctx, "UserQuery.UpdatePasswordHash")
+	defer span.End()
+
+	db := u.db.GetWriteConnection(ctx)
+	if err := db.
+		WithContext(ctx).
+		Table("users").
+		Where("id = ?", id).
+		UpdateColumn("password", hash).
+		Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (u *userQueryImpl) UnverifyEmail(ctx context.Context, id uint64) error {
+	ctx, span := tracer.Start(ctx, "UserQuery.UnverifyEmail")
+	defer span.End()
+
+	db := u.db.GetWriteConnection(ctx)
+	if err := db.
+		WithContext(ctx).
+		Table("users").
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"email_verified_at": nil,
+			"updated_at":        time.Now(),
+		}).
+		Error; err != nil {
+		return err
+	}
+	return nil
+}
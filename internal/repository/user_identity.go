@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"mygram/internal/infrastructure"
+	"mygram/internal/model"
+)
+
+type UserIdentityQuery interface {
+	GetIdentity(ctx context.Context, provider string, subject string) (model.UserIdentity, error)
+	CreateIdentity(ctx context.Context, identity model.UserIdentity) (model.UserIdentity, error)
+}
+
+type userIdentityQueryImpl struct {
+	db infrastructure.GormPostgres
+}
+
+func NewUserIdentityQuery(db infrastructure.GormPostgres) UserIdentityQuery {
+	return &userIdentityQueryImpl{db: db}
+}
+
+func (u *userIdentityQueryImpl) GetIdentity(ctx context.Context, provider string, subject string) (model.UserIdentity, error) {
+	ctx, span := tracer.Start(ctx, "UserIdentityQuery.GetIdentity")
+	defer span.End()
+
+	db := u.db.GetReadConnection(ctx)
+	identity := model.UserIdentity{}
+	if err := db.
+		WithContext(ctx).
+		Table("user_identities").
+		Where("provider = ?", provider).
+		Where("subject = ?", subject).
+		Find(&identity).Error; err != nil {
+		return model.UserIdentity{}, err
+	}
+	return identity, nil
+}
+
+func (u *userIdentityQueryImpl) CreateIdentity(ctx context.Context, identity model.UserIdentity) (model.UserIdentity, error) {
+	ctx, span := tracer.Start(ctx, "UserIdentityQuery.CreateIdentity")
+	defer span.End()
+
+	db := u.db.GetWriteConnection(ctx)
+	if err := db.
+		WithContext(ctx).
+		Table("user_identities").
+		Create(&identity).Error; err != nil {
+		return model.UserIdentity{}, err
+	}
+	return identity, nil
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"mygram/internal/infrastructure"
+	"mygram/internal/model"
+	"time"
+)
+
+type UserTokenQuery interface {
+	CreateToken(ctx context.Context, token model.UserToken) (model.UserToken, error)
+	GetTokenByHash(ctx context.Context, purpose string, hash string) (model.UserToken, error)
+	// UseToken marks the token used and reports false when it already was.
+	UseToken(ctx context.Context, id uint64) (bool, error)
+	// DeleteUnusedTokens drops the outstanding tokens of a user for purpose.
+	DeleteUnusedTokens(ctx context.Context, userId uint64, purpose string) error
+}
+
+type userTokenQueryImpl struct {
+	db infrastructure.GormPostgres
+}
+
+func NewUserTokenQuery(db infrastructure.GormPostgres) UserTokenQuery {
+	return &userTokenQueryImpl{db: db}
+}
+
+func (u *userTokenQueryImpl) CreateToken(ctx context.Context, token model.UserToken) (model.UserToken, error) {
+	ctx, span := tracer.Start(ctx, "UserTokenQuery.CreateToken")
+	defer span.End()
+
+	db := u.db.GetWriteConnection(ctx)
+	if err := db.
+		WithContext(ctx).
+		Table("user_tokens").
+		Create(&token).Error; err != nil {
+		return model.UserToken{}, err
+	}
+	return token, nil
+}
+
+func (u *userTokenQueryImpl) GetTokenByHash(ctx context.Context, purpose string, hash string) (model.UserToken, error) {
+	ctx, span := tracer.Start(ctx, "UserTokenQuery.GetTokenByHash")
+	defer span.End()
+
+	// tokens are read right after being mailed, a lagging replica would
+	// reject them
+	db := u.db.GetWriteConnection(ctx)
+	token := model.UserToken{}
+	if err := db.
+		WithContext(ctx).
+		Table("user_tokens").
+		Where("purpose = ?", purpose).
+		Where("token_hash = ?", hash).
+		Find(&token).Error; err != nil {
+		return model.UserToken{}, err
+	}
+	return token, nil
+}
+
+func (u *userTokenQueryImpl) UseToken(ctx context.Context, id uint64) (bool, error) {
+	ctx, span := tracer.Start(ctx, "UserTokenQuery.UseToken")
+	defer span.End()
+
+	db := u.db.GetWriteConnection(ctx)
+	res := db.
+		WithContext(ctx).
+		Table("user_tokens").
+		Where("id = ?", id).
+		Where("used_at IS NULL").
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (u *userTokenQueryImpl) DeleteUnusedTokens(ctx context.Context, userId uint64, purpose string) error {
+	ctx, span := tracer.Start(ctx, "UserTokenQuery.DeleteUnusedTokens")
+	defer span.End()
+
+	db := u.db.GetWriteConnection(ctx)
+	if err := db.
+		WithContext(ctx).
+		Table("user_tokens").
+		Where("user_id = ?", userId).
+		Where("purpose = ?", purpose).
+		Where("used_at IS NULL").
+		Delete(&model.UserToken{}).
+		Error; err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package repository
+
+import "database/sql"
+
+// viewer binds the @viewer argument of the conditions below.
+func viewer(viewerId uint64) sql.NamedArg {
+	return sql.Named("viewer", viewerId)
+}
+
+// Viewer, VisibleOwner and NotBlocked are the conditions below for the
+// queries of other packages, such as the search index.
+func Viewer(viewerId uint64) sql.NamedArg {
+	return viewer(viewerId)
+}
+
+func VisibleOwner(column string) string {
+	return visibleOwner(column)
+}
+
+func NotBlocked(column string) string {
+	return notBlocked(column)
+}
+
+// visibleOwner is a condition on the user ID in column that holds when the
+// viewer may see that user's content: public accounts, the viewer's own and
+// private accounts that approved the viewer as a follower, unless either
+// blocked the other. Guests are viewer 0, which matches no user, so they
+// only see public accounts.
+func visibleOwner(column string) string {
+	return column + ` IN (
+		SELECT v.id FROM users v
+		WHERE v.deleted_at IS NULL AND (
+			v.private = false
+			OR v.id = @viewer
+			OR EXISTS (
+				SELECT 1 FROM follows f
+				WHERE f.follower_id = @viewer AND f.followee_id = v.id AND f.approved_at IS NOT NULL
+			)
+		) AND ` + notBlocked("v.id") + `
+	)`
+}
+
+// notBlocked holds when neither the user ID in column nor the viewer
+// blocked the other.
+func notBlocked(column string) string {
+	return `NOT EXISTS (
+		SELECT 1 FROM blocks b
+		WHERE (b.blocker_id = ` + column + ` AND b.blocked_id = @viewer)
+			OR (b.blocker_id = @viewer AND b.blocked_id = ` + column + `)
+	)`
+}
+
+// visibleComment holds for comments on photos the viewer may see, written by
+// users the viewer may see, and for the viewer's own comments wherever they
+// are.
+func visibleComment() string {
+	return `(comments.user_id = @viewer OR (` + visibleOwner("comments.user_id") + ` AND comments.photo_id IN (
+		SELECT p.id FROM photos p
+		WHERE p.deleted_at IS NULL AND ` + visibleOwner("p.user_id") + `
+	)))`
+}
+
+// notMuted holds when the viewer did not mute the user ID in column. Muted
+// users are left out of what the viewer is shown without them knowing, they
+// still see the viewer's content.
+func notMuted(column string) string {
+	return column + ` NOT IN (SELECT m.muted_id FROM mutes m WHERE m.muter_id = @viewer)`
+}
+
+// discoverable holds for content the viewer is shown unasked, such as on
+// explore: public accounts only, even those the viewer follows, without
+// blocked or muted users.
+func discoverable(column string) string {
+	return column + ` IN (
+		SELECT v.id FROM users v
+		WHERE v.deleted_at IS NULL AND v.private = false AND ` + notBlocked("v.id") + `
+	) AND ` + notMuted(column)
+}
@@ -0,0 +1,229 @@
+package router_test
+
+import (
+	"mygram/internal/apitest"
+	"mygram/internal/model"
+	"mygram/internal/service"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var linkPattern = regexp.MustCompile(`https?://\S+\?token=\S+`)
+
+// mailedToken reads the token from the link in the last email sent to addr.
+func mailedToken(t *testing.T, h *apitest.Harness, addr string) string {
+	t.Helper()
+
+	msg, ok := h.Outbox.Last(addr)
+	if !ok {
+		t.Fatalf("no email sent to %s", addr)
+	}
+	link, err := url.Parse(linkPattern.FindString(msg.Text))
+	if err != nil {
+		t.Fatalf("parse link in %q: %v", msg.Text, err)
+	}
+	token := link.Query().Get("token")
+	if token == "" {
+		t.Fatalf("no token in email %q", msg.Text)
+	}
+	if !strings.Contains(msg.HTML, token) {
+		t.Fatalf("html part does not carry the token")
+	}
+	return token
+}
+
+func TestSignUpRequiresEmailVerificationBeforePosting(t *testing.T) {
+	h := apitest.New()
+
+	rec := h.Request(t, http.MethodPost, "/users/register", model.UserSignUp{Username: "carol", Email: "carol@example.com", Password: "harbor-sunset-42", DoB: "2000-01-01"}, "")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("register: %d %s", rec.Code, rec.Body.String())
+	}
+	carol := model.User{}
+	apitest.Decode(t, rec, &carol)
+	token := h.Token(t, carol)
+	photo := model.PhotoCreateReq{Title: "t", Caption: "c", PhotoUrl: "u"}
+
+	rec = h.Request(t, http.MethodPost, "/photos", photo, token)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 before verification, got %d", rec.Code)
+	}
+	// reading is still allowed
+	if rec := h.Request(t, http.MethodGet, "/photos?user_id=1", nil, token); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected read to go through, got %d", rec.Code)
+	}
+
+	verification := mailedToken(t, h, "carol@example.com")
+	rec = h.Request(t, http.MethodPost, "/users/verify-email", model.EmailVerifyReq{Token: verification}, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("verify: %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = h.Request(t, http.MethodPost, "/photos", photo, token)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201 after verification, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// tokens are single use
+	rec = h.Request(t, http.MethodPost, "/users/verify-email", model.EmailVerifyReq{Token: verification}, "")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected reused token to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestChangingEmailRequiresVerifyingItAgain(t *testing.T) {
+	h := apitest.New()
+	alice := h.CreateUser(t, "alice")
+	token := h.Token(t, alice)
+	photo := model.PhotoCreateReq{Title: "t", Caption: "c", PhotoUrl: "u"}
+
+	// keeping the address keeps it verified
+	rec := h.Request(t, http.MethodPatch, "/users/1", map[string]any{"username": "alice2"}, token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("edit: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := h.Request(t, http.MethodPost, "/photos", photo, token); rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201 with the address kept, got %d", rec.Code)
+	}
+
+	rec = h.Request(t, http.MethodPatch, "/users/1", map[string]any{"email": "alice@example.org"}, token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("edit: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := h.Request(t, http.MethodPost, "/photos", photo, token); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 before the new address is verified, got %d", rec.Code)
+	}
+
+	verification := mailedToken(t, h, "alice@example.org")
+	rec = h.Request(t, http.MethodPost, "/users/verify-email", model.EmailVerifyReq{Token: verification}, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("verify: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := h.Request(t, http.MethodPost, "/photos", photo, token); rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201 after verification, got %d", rec.Code)
+	}
+}
+
+func TestResendVerificationReplacesPreviousToken(t *testing.T) {
+	h := apitest.New()
+	h.CreateUnverifiedUser(t, "carol")
+
+	send := func() string {
+		rec := h.Request(t, http.MethodPost, "/users/verify-email/resend", model.EmailReq{Email: "carol@example.com"}, "")
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("resend: %d %s", rec.Code, rec.Body.String())
+		}
+		return mailedToken(t, h, "carol@example.com")
+	}
+	first, second := send(), send()
+
+	rec := h.Request(t, http.MethodPost, "/users/verify-email", model.EmailVerifyReq{Token: first}, "")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected replaced token to be rejected, got %d", rec.Code)
+	}
+	rec = h.Request(t, http.MethodPost, "/users/verify-email", model.EmailVerifyReq{Token: second}, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("verify: %d %s", rec.Code, rec.Body.String())
+	}
+
+	// verified accounts and unknown addresses get no email
+	sent := len(h.Outbox.Messages("carol@example.com"))
+	h.Request(t, http.MethodPost, "/users/verify-email/resend", model.EmailReq{Email: "carol@example.com"}, "")
+	if got := len(h.Outbox.Messages("carol@example.com")); got != sent {
+		t.Fatalf("expected no email for a verified account, got %d new", got-sent)
+	}
+	h.Request(t, http.MethodPost, "/users/verify-email/resend", model.EmailReq{Email: "nobody@example.com"}, "")
+	if got := len(h.Outbox.Messages("nobody@example.com")); got != 0 {
+		t.Fatalf("expected no email for an unknown address, got %d", got)
+	}
+}
+
+func TestPasswordReset(t *testing.T) {
+	h := apitest.New()
+	h.CreateUser(t, "alice")
+
+	rec := h.Request(t, http.MethodPost, "/users/password/forgot", model.EmailReq{Email: "alice@example.com"}, "")
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("forgot: %d %s", rec.Code, rec.Body.String())
+	}
+	token := mailedToken(t, h, "alice@example.com")
+
+	rec = h.Request(t, http.MethodPost, "/users/password/reset", model.PasswordResetReq{Token: token, Password: "new-secret"}, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("reset: %d %s", rec.Code, rec.Body.String())
+	}
+
+	login := func(password string) int {
+		return h.Request(t, http.MethodPost, "/users/login", model.UserSignIn{Email: "alice@example.com", Password: password}, "").Code
+	}
+	if code := login(apitest.DefaultPassword); code != http.StatusUnauthorized {
+		t.Fatalf("expected old password to fail, got %d", code)
+	}
+	if code := login("new-secret"); code != http.StatusOK {
+		t.Fatalf("expected new password to work, got %d", code)
+	}
+
+	rec = h.Request(t, http.MethodPost, "/users/password/reset", model.PasswordResetReq{Token: token, Password: "another-secret"}, "")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected reused token to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestPasswordResetSignsOutEverywhere(t *testing.T) {
+	h := apitest.New()
+	session := h.Token(t, h.CreateUser(t, "alice"))
+	pat := newAccessToken(t, h, session, model.SCOPE_PROFILE_READ).Token
+
+	h.Request(t, http.MethodPost, "/users/password/forgot", model.EmailReq{Email: "alice@example.com"}, "")
+	rec := h.Request(t, http.MethodPost, "/users/password/reset", model.PasswordResetReq{Token: mailedToken(t, h, "alice@example.com"), Password: "new-secret"}, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("reset: %d %s", rec.Code, rec.Body.String())
+	}
+
+	for name, token := range map[string]string{"session": session, "personal access token": pat} {
+		if rec := h.Request(t, http.MethodGet, "/users/1", nil, token); rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected the %s to be refused after the reset, got %d", name, rec.Code)
+		}
+	}
+}
+
+func TestPasswordResetTokenExpires(t *testing.T) {
+	cfg := service.DefaultAccountConfig()
+	cfg.PasswordResetTTL = -time.Minute
+	h := apitest.New(apitest.WithAccountConfig(cfg))
+	h.CreateUser(t, "alice")
+
+	h.Request(t, http.MethodPost, "/users/password/forgot", model.EmailReq{Email: "alice@example.com"}, "")
+	token := mailedToken(t, h, "alice@example.com")
+
+	rec := h.Request(t, http.MethodPost, "/users/password/reset", model.PasswordResetReq{Token: token, Password: "new-secret"}, "")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected expired token to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestAccountEmailsAreLocalized(t *testing.T) {
+	h := apitest.New()
+	h.CreateUser(t, "alice")
+
+	req := model.EmailReq{Email: "alice@example.com"}
+	h.Request(t, http.MethodPost, "/users/password/forgot", req, "")
+	english, _ := h.Outbox.Last("alice@example.com")
+
+	rec := h.RequestWithHeaders(t, http.MethodPost, "/users/password/forgot", req, http.Header{"Accept-Language": {"id-ID,id;q=0.9,en;q=0.8"}})
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("forgot: %d %s", rec.Code, rec.Body.String())
+	}
+	indonesian, _ := h.Outbox.Last("alice@example.com")
+
+	if english.Subject != "Reset your MyGram password" {
+		t.Fatalf("unexpected english subject %q", english.Subject)
+	}
+	if indonesian.Subject != "Atur ulang kata sandi MyGram kamu" {
+		t.Fatalf("unexpected indonesian subject %q", indonesian.Subject)
+	}
+}
@@ -0,0 +1,140 @@
+package router_test
+
+import (
+	"mygram/internal/apitest"
+	"mygram/internal/model"
+	"net/http"
+	"testing"
+)
+
+func TestBlockHidesUsersFromEachOther(t *testing.T) {
+	f := newFixture(t)
+	rec := f.h.Request(t, http.MethodPost, "/photos", model.PhotoCreateReq{Title: "t", Caption: "c", PhotoUrl: "https://img.example.com/2.jpg"}, f.bobToken)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create photo: %d %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := f.h.Request(t, http.MethodPost, "/users/2/block", nil, f.aliceToken); rec.Code != http.StatusOK {
+		t.Fatalf("block: %d %s", rec.Code, rec.Body.String())
+	}
+
+	expectStatus(t, f.h, f.bobToken, aliceContent, http.StatusNotFound)
+	expectStatus(t, f.h, f.aliceToken, []string{"/photos?user_id=2"}, http.StatusNotFound)
+	expectStatus(t, f.h, f.guestToken, aliceContent, http.StatusOK)
+
+	if rec := f.h.Request(t, http.MethodPost, "/comments", model.CommentCreateReq{Message: "hi", PhotoId: 1}, f.bobToken); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected blocked user not to comment, got %d", rec.Code)
+	}
+	if rec := f.h.Request(t, http.MethodPost, "/users/1/follow", nil, f.bobToken); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected blocked user not to follow, got %d", rec.Code)
+	}
+
+	rec = f.h.Request(t, http.MethodGet, "/users/me/blocks", nil, f.aliceToken)
+	blocks := []model.ListedUser{}
+	apitest.Decode(t, rec, &blocks)
+	if len(blocks) != 1 || blocks[0].UserId != 2 || blocks[0].User.Username != "bob" {
+		t.Fatalf("expected bob blocked, got %+v", blocks)
+	}
+
+	// bob cannot lift alice's block
+	if rec := f.h.Request(t, http.MethodDelete, "/users/1/block", nil, f.bobToken); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 unblocking someone else's block, got %d", rec.Code)
+	}
+	if rec := f.h.Request(t, http.MethodDelete, "/users/2/block", nil, f.aliceToken); rec.Code != http.StatusOK {
+		t.Fatalf("unblock: %d %s", rec.Code, rec.Body.String())
+	}
+	expectStatus(t, f.h, f.bobToken, aliceContent, http.StatusOK)
+}
+
+func TestBlockRemovesFollowsBothWays(t *testing.T) {
+	f := newFixture(t)
+	followUser(t, f.h, f.aliceToken, "/users/2/follow")
+	followUser(t, f.h, f.bobToken, "/users/1/follow")
+
+	if rec := f.h.Request(t, http.MethodPost, "/users/1/block", nil, f.bobToken); rec.Code != http.StatusOK {
+		t.Fatalf("block: %d %s", rec.Code, rec.Body.String())
+	}
+	for path, token := range map[string]string{"/users/2/follow": f.aliceToken, "/users/1/follow": f.bobToken} {
+		if rec := f.h.Request(t, http.MethodDelete, path, nil, token); rec.Code != http.StatusNotFound {
+			t.Fatalf("DELETE %s: expected the follow to be gone, got %d", path, rec.Code)
+		}
+	}
+
+	// blocking again is a no-op
+	if rec := f.h.Request(t, http.MethodPost, "/users/1/block", nil, f.bobToken); rec.Code != http.StatusOK {
+		t.Fatalf("block again: %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestMentioningBlockedUsersIsRefused(t *testing.T) {
+	f := newFixture(t)
+	carol := f.h.CreateUser(t, "carol")
+	carolToken := f.h.Token(t, carol)
+
+	if rec := f.h.Request(t, http.MethodPost, "/users/3/block", nil, f.aliceToken); rec.Code != http.StatusOK {
+		t.Fatalf("block: %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec := f.h.Request(t, http.MethodPost, "/photos", model.PhotoCreateReq{Title: "t", Caption: "c", PhotoUrl: "https://img.example.com/2.jpg"}, f.bobToken)
+	photo := model.PhotoCreateRes{}
+	apitest.Decode(t, rec, &photo)
+
+	for _, tc := range []struct {
+		token   string
+		message string
+		status  int
+	}{
+		{carolToken, "look @alice", http.StatusForbidden},
+		{f.aliceToken, "hey @carol!", http.StatusForbidden},
+		{carolToken, "look @bob", http.StatusCreated},
+		{carolToken, "mail carol@alice.example", http.StatusCreated},
+		{f.aliceToken, "@nobody here", http.StatusCreated},
+	} {
+		rec := f.h.Request(t, http.MethodPost, "/comments", model.CommentCreateReq{Message: tc.message, PhotoId: photo.ID}, tc.token)
+		if rec.Code != tc.status {
+			t.Fatalf("comment %q: expected %d, got %d: %s", tc.message, tc.status, rec.Code, rec.Body.String())
+		}
+	}
+
+	// nor can a mention be edited in
+	if rec := f.h.Request(t, http.MethodPut, "/comments/1", model.CommentUpdateReq{Message: "cc @carol"}, f.aliceToken); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 editing in a mention, got %d", rec.Code)
+	}
+}
+
+func TestMutedUsersCommentsAreHiddenFromMuterOnly(t *testing.T) {
+	f := newFixture(t)
+	if rec := f.h.Request(t, http.MethodPost, "/comments", model.CommentCreateReq{Message: "hi", PhotoId: 1}, f.bobToken); rec.Code != http.StatusCreated {
+		t.Fatalf("comment: %d %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := f.h.Request(t, http.MethodPost, "/users/2/mute", nil, f.aliceToken); rec.Code != http.StatusOK {
+		t.Fatalf("mute: %d %s", rec.Code, rec.Body.String())
+	}
+
+	for token, want := range map[string]int{f.aliceToken: 1, f.bobToken: 2, f.guestToken: 2} {
+		rec := f.h.Request(t, http.MethodGet, "/comments?photo_id=1", nil, token)
+		comments := []model.CommentGetRes{}
+		apitest.Decode(t, rec, &comments)
+		if len(comments) != want {
+			t.Fatalf("expected %d comments, got %+v", want, comments)
+		}
+	}
+
+	rec := f.h.Request(t, http.MethodGet, "/users/me/mutes", nil, f.aliceToken)
+	mutes := []model.ListedUser{}
+	apitest.Decode(t, rec, &mutes)
+	if len(mutes) != 1 || mutes[0].User.Username != "bob" {
+		t.Fatalf("expected bob muted, got %+v", mutes)
+	}
+
+	if rec := f.h.Request(t, http.MethodDelete, "/users/2/mute", nil, f.aliceToken); rec.Code != http.StatusOK {
+		t.Fatalf("unmute: %d %s", rec.Code, rec.Body.String())
+	}
+	rec = f.h.Request(t, http.MethodGet, "/comments?photo_id=1", nil, f.aliceToken)
+	comments := []model.CommentGetRes{}
+	apitest.Decode(t, rec, &comments)
+	if len(comments) != 2 {
+		t.Fatalf("expected bob's comment back, got %+v", comments)
+	}
+}
@@ -3,27 +3,40 @@ package router
 import (
 	"mygram/internal/handler"
 	"mygram/internal/middleware"
+	"mygram/internal/model"
+	"mygram/internal/ratelimit"
 
 	"github.com/gin-gonic/gin"
 )
 
 type CommentRouter interface {
-	Mount()
+	Mount(g *gin.RouterGroup)
 }
 
 type commentRouterImpl struct {
-	v       *gin.RouterGroup
 	handler handler.CommentHandler
+	limiter *middleware.RateLimiter
+	auth *middleware.Authenticator
+	verified gin.HandlerFunc
+	idempotent gin.HandlerFunc
 }
 
-func NewCommentRouter(v *gin.RouterGroup, handler handler.CommentHandler) PhotoRouter {
-	return &commentRouterImpl{v: v, handler: handler}
+func NewCommentRouter(handler handler.CommentHandler, limiter *middleware.RateLimiter, auth *middleware.Authenticator, verified gin.HandlerFunc, idempotent gin.HandlerFunc) PhotoRouter {
+	return &commentRouterImpl{handler: handler, limiter: limiter, auth: auth, verified: verified, idempotent: idempotent}
 }
 
-func (c *commentRouterImpl) Mount() {
-	c.v.Use(middleware.CheckAuthBearer)
-	c.v.POST("", c.handler.CreateComment)
-	c.v.GET("", c.handler.GetCommentsByPhotoId)
-	c.v.PUT("/:id", c.handler.EditComment)
-	c.v.DELETE("/:id", c.handler.DeleteComment)
+func (c *commentRouterImpl) Mount(g *gin.RouterGroup) {
+	v := g.Group("/comments")
+	v.Use(c.limiter.ByIP(ratelimit.GroupComments))
+	// guests can read, the authenticator refuses their writes
+	v.Use(c.auth.CheckAuthBearerOrGuest)
+	v.Use(c.limiter.ByGuest(ratelimit.GroupComments))
+	v.Use(c.limiter.ByUser(ratelimit.GroupComments))
+	v.Use(c.verified)
+	v.POST("", middleware.RequireScope(model.SCOPE_COMMENTS_WRITE), c.idempotent, c.handler.CreateComment)
+	v.GET("", middleware.RequireScope(model.SCOPE_COMMENTS_READ), c.handler.GetCommentsByPhotoId)
+	v.GET("/:id", middleware.RequireScope(model.SCOPE_COMMENTS_READ), c.handler.GetCommentById)
+	v.PUT("/:id", middleware.RequireScope(model.SCOPE_COMMENTS_WRITE), c.handler.EditComment)
+	v.PATCH("/:id", middleware.RequireScope(model.SCOPE_COMMENTS_WRITE), c.handler.EditComment)
+	v.DELETE("/:id", middleware.RequireScope(model.SCOPE_COMMENTS_WRITE), c.handler.DeleteComment)
 }
\ No newline at end of file
@@ -0,0 +1,179 @@
+package router_test
+
+import (
+	"context"
+	"errors"
+	"mygram/internal/apitest"
+	"mygram/internal/model"
+	"mygram/internal/service"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// tickingClock moves the store's clock a second on every read, so that
+// each write gets a new version.
+func tickingClock(h *apitest.Harness) {
+	now := time.Now()
+	h.Store.Now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+}
+
+func conditional(t *testing.T, h *apitest.Harness, method string, path string, body any, token string, header string, tag string) (int, string) {
+	t.Helper()
+
+	rec := h.RequestWithHeaders(t, method, path, body, http.Header{
+		"Authorization": {"Bearer " + token},
+		header:          {tag},
+	})
+	return rec.Code, rec.Header().Get("ETag")
+}
+
+func TestGetAnswersNotModified(t *testing.T) {
+	f := newFixture(t)
+	tickingClock(f.h)
+
+	rec := f.h.Request(t, http.MethodGet, "/photos/1", nil, f.bobToken)
+	tag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || tag == "" {
+		t.Fatalf("expected an ETag, got %d %q", rec.Code, tag)
+	}
+
+	rec = f.h.RequestWithHeaders(t, http.MethodGet, "/photos/1", nil, http.Header{"Authorization": {"Bearer " + f.bobToken}, "If-None-Match": {`"other", ` + tag}})
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Fatalf("expected 304 without a body, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	// another representation of the same version
+	if code, expanded := conditional(t, f.h, http.MethodGet, "/photos/1?expand=comments", nil, f.bobToken, "If-None-Match", tag); code != http.StatusOK || expanded == tag {
+		t.Fatalf("expected the expanded photo with its own ETag, got %d %q", code, expanded)
+	}
+
+	if rec := f.h.Request(t, http.MethodPatch, "/photos/1", `{"title": "dusk"}`, f.aliceToken); rec.Code != http.StatusOK {
+		t.Fatalf("patch: %d %s", rec.Code, rec.Body.String())
+	}
+	if code, _ := conditional(t, f.h, http.MethodGet, "/photos/1", nil, f.bobToken, "If-None-Match", tag); code != http.StatusOK {
+		t.Fatalf("expected the edited photo, got %d", code)
+	}
+
+	for _, path := range []string{"/users/1", "/comments/1", "/social-medias/1"} {
+		rec := f.h.Request(t, http.MethodGet, path, nil, f.bobToken)
+		if code, _ := conditional(t, f.h, http.MethodGet, path, nil, f.bobToken, "If-None-Match", rec.Header().Get("ETag")); code != http.StatusNotModified {
+			t.Fatalf("%s: expected 304, got %d", path, code)
+		}
+	}
+}
+
+func TestListsAnswerNotModified(t *testing.T) {
+	f := newFixture(t)
+	tickingClock(f.h)
+
+	for _, path := range []string{"/photos?user_id=1", "/comments?photo_id=1", "/social-medias?user_id=1"} {
+		rec := f.h.Request(t, http.MethodGet, path, nil, f.bobToken)
+		tag := rec.Header().Get("ETag")
+		if rec.Code != http.StatusOK || tag == "" {
+			t.Fatalf("%s: expected an ETag, got %d %q", path, rec.Code, tag)
+		}
+		if code, _ := conditional(t, f.h, http.MethodGet, path, nil, f.bobToken, "If-None-Match", tag); code != http.StatusNotModified {
+			t.Fatalf("%s: expected 304, got %d", path, code)
+		}
+	}
+
+	// a new item changes the list
+	rec := f.h.Request(t, http.MethodGet, "/comments?photo_id=1", nil, f.bobToken)
+	tag := rec.Header().Get("ETag")
+	comment(t, f.h, f.bobToken, 1)
+	if code, changed := conditional(t, f.h, http.MethodGet, "/comments?photo_id=1", nil, f.bobToken, "If-None-Match", tag); code != http.StatusOK || changed == tag {
+		t.Fatalf("expected the comments with a new ETag, got %d %q", code, changed)
+	}
+}
+
+func TestPatchMergesIntoCurrentValues(t *testing.T) {
+	f := newFixture(t)
+
+	rec := f.h.RequestWithHeaders(t, http.MethodPatch, "/photos/1", `{"caption": "at the lake"}`, http.Header{
+		"Authorization": {"Bearer " + f.aliceToken},
+		"Content-Type":  {"application/merge-patch+json"},
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("patch: %d %s", rec.Code, rec.Body.String())
+	}
+
+	photo := model.PhotoGetRes{}
+	apitest.Decode(t, f.h.Request(t, http.MethodGet, "/photos/1", nil, f.aliceToken), &photo)
+	if photo.Title != "sunset" || photo.Caption != "at the lake" || photo.PhotoUrl != "https://img.example.com/1.jpg" {
+		t.Fatalf("expected only the caption changed, got %+v", photo)
+	}
+
+	rec = f.h.RequestWithHeaders(t, http.MethodPatch, "/photos/1", `caption=x`, http.Header{
+		"Authorization": {"Bearer " + f.aliceToken},
+		"Content-Type":  {"application/x-www-form-urlencoded"},
+	})
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected 415, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestConcurrentEditsConflict(t *testing.T) {
+	f := newFixture(t)
+	tickingClock(f.h)
+
+	// two clients read the same version, one of them with comments
+	first := f.h.Request(t, http.MethodGet, "/photos/1", nil, f.aliceToken).Header().Get("ETag")
+	second := f.h.Request(t, http.MethodGet, "/photos/1?expand=comments", nil, f.aliceToken).Header().Get("ETag")
+
+	code, latest := conditional(t, f.h, http.MethodPatch, "/photos/1", `{"title": "first"}`, f.aliceToken, "If-Match", first)
+	if code != http.StatusOK || latest == "" || latest == first {
+		t.Fatalf("expected the first write with a new ETag, got %d %q", code, latest)
+	}
+	if code, _ := conditional(t, f.h, http.MethodPatch, "/photos/1", `{"title": "second"}`, f.aliceToken, "If-Match", second); code != http.StatusPreconditionFailed {
+		t.Fatalf("expected 412 for the second write, got %d", code)
+	}
+	if code, _ := conditional(t, f.h, http.MethodPut, "/photos/1", model.PhotoUpdateReq{Title: "second", Caption: "c", PhotoUrl: "u"}, f.aliceToken, "If-Match", second); code != http.StatusPreconditionFailed {
+		t.Fatalf("expected 412 for PUT too, got %d", code)
+	}
+
+	photo := model.PhotoGetRes{}
+	apitest.Decode(t, f.h.Request(t, http.MethodGet, "/photos/1", nil, f.aliceToken), &photo)
+	if photo.Title != "first" {
+		t.Fatalf("expected the first write to stay, got %+v", photo)
+	}
+
+	// the ETag of the write response is good for the next one
+	if code, _ := conditional(t, f.h, http.MethodPatch, "/photos/1", `{"title": "second"}`, f.aliceToken, "If-Match", latest); code != http.StatusOK {
+		t.Fatalf("expected the write with the latest ETag to pass, got %d", code)
+	}
+	if code, _ := conditional(t, f.h, http.MethodPatch, "/photos/1", `{"title": "third"}`, f.aliceToken, "If-Match", "*"); code != http.StatusOK {
+		t.Fatalf("expected If-Match: * to pass, got %d", code)
+	}
+
+	for _, path := range []string{"/users/1", "/comments/1", "/social-medias/1"} {
+		if code, _ := conditional(t, f.h, http.MethodPatch, path, `{}`, f.aliceToken, "If-Match", `"stale-0"`); code != http.StatusPreconditionFailed {
+			t.Fatalf("%s: expected 412, got %d", path, code)
+		}
+	}
+}
+
+func TestEditIsConditionalOnTheStoredVersion(t *testing.T) {
+	f := newFixture(t)
+	tickingClock(f.h)
+	ctx := context.Background()
+
+	photo, err := f.h.Services.Photo.GetPhotoById(ctx, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read := photo.UpdatedAt
+	photo.Title = "first"
+	if _, err := f.h.Services.Photo.EditPhoto(ctx, photo, read); err != nil {
+		t.Fatal(err)
+	}
+
+	// a write that passed the If-Match check just before the first one
+	photo.Title = "second"
+	if _, err := f.h.Services.Photo.EditPhoto(ctx, photo, read); !errors.Is(err, service.ErrPreconditionFailed) {
+		t.Fatalf("expected %v, got %v", service.ErrPreconditionFailed, err)
+	}
+}
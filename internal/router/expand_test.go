@@ -0,0 +1,130 @@
+package router_test
+
+import (
+	"mygram/internal/apitest"
+	"mygram/internal/model"
+	"net/http"
+	"testing"
+)
+
+func getJSON(t *testing.T, h *apitest.Harness, token string, path string) map[string]any {
+	t.Helper()
+
+	rec := h.Request(t, http.MethodGet, path, nil, token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("%s: %d %s", path, rec.Code, rec.Body.String())
+	}
+	res := map[string]any{}
+	apitest.Decode(t, rec, &res)
+	return res
+}
+
+func expectKeys(t *testing.T, res map[string]any, keys ...string) {
+	t.Helper()
+
+	if len(res) != len(keys) {
+		t.Fatalf("expected keys %v, got %v", keys, res)
+	}
+	for _, key := range keys {
+		if _, ok := res[key]; !ok {
+			t.Fatalf("expected keys %v, got %v", keys, res)
+		}
+	}
+}
+
+func TestSingleResourcesKeepTheirDefaultRelations(t *testing.T) {
+	f := newFixture(t)
+
+	photo := model.PhotoGetRes{}
+	apitest.Decode(t, f.h.Request(t, http.MethodGet, "/photos/1", nil, f.bobToken), &photo)
+	if photo.Title != "sunset" || photo.User == nil || photo.User.Username != "alice" || photo.Comments != nil {
+		t.Fatalf("expected the photo with its user, got %+v", photo)
+	}
+
+	cmt := model.CommentGetRes{}
+	apitest.Decode(t, f.h.Request(t, http.MethodGet, "/comments/1", nil, f.bobToken), &cmt)
+	if cmt.Message != "nice" || cmt.User == nil || cmt.Photo == nil || cmt.Photo.Title != "sunset" {
+		t.Fatalf("expected the comment with its user and photo, got %+v", cmt)
+	}
+
+	social := model.SocialMediaGetRes{}
+	apitest.Decode(t, f.h.Request(t, http.MethodGet, "/social-medias/1", nil, f.bobToken), &social)
+	if social.Name != "instagram" || social.User == nil {
+		t.Fatalf("expected the social media with its user, got %+v", social)
+	}
+}
+
+func TestExpandPicksRelations(t *testing.T) {
+	f := newFixture(t)
+
+	res := getJSON(t, f.h, f.bobToken, "/photos/1?expand=")
+	if _, ok := res["User"]; ok {
+		t.Fatalf("expected no user with an empty expand, got %v", res)
+	}
+
+	res = getJSON(t, f.h, f.bobToken, "/comments/1?expand=photo")
+	if _, ok := res["User"]; ok {
+		t.Fatalf("expected only the photo, got %v", res)
+	}
+	if _, ok := res["Photo"]; !ok {
+		t.Fatalf("expected the photo, got %v", res)
+	}
+
+	comment(t, f.h, f.bobToken, 1)
+	photo := model.PhotoGetRes{}
+	apitest.Decode(t, f.h.Request(t, http.MethodGet, "/photos/1?expand=comments", nil, f.bobToken), &photo)
+	if photo.User != nil || len(photo.Comments) != 2 || photo.Comments[1].User == nil || photo.Comments[1].User.Username != "bob" {
+		t.Fatalf("expected both comments with their users, got %+v", photo)
+	}
+}
+
+func TestExpandedCommentsKeepToWhatTheViewerMaySee(t *testing.T) {
+	f := newFixture(t)
+	carolToken := f.h.Token(t, f.h.CreateUser(t, "carol"))
+	comment(t, f.h, f.bobToken, 1)
+	comment(t, f.h, carolToken, 1)
+
+	if rec := f.h.Request(t, http.MethodPost, "/users/2/mute", nil, f.aliceToken); rec.Code != http.StatusOK {
+		t.Fatalf("mute: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := f.h.Request(t, http.MethodPost, "/users/3/block", nil, f.aliceToken); rec.Code != http.StatusOK {
+		t.Fatalf("block: %d %s", rec.Code, rec.Body.String())
+	}
+
+	photo := model.PhotoGetRes{}
+	apitest.Decode(t, f.h.Request(t, http.MethodGet, "/photos/1?expand=comments", nil, f.aliceToken), &photo)
+	if len(photo.Comments) != 1 || photo.Comments[0].UserId != 1 {
+		t.Fatalf("expected only alice's comment, got %+v", photo.Comments)
+	}
+
+	photo = model.PhotoGetRes{}
+	apitest.Decode(t, f.h.Request(t, http.MethodGet, "/photos/1?expand=comments", nil, f.bobToken), &photo)
+	// blocks and mutes are alice's, bob still sees everyone
+	if len(photo.Comments) != 3 {
+		t.Fatalf("expected every comment, got %+v", photo.Comments)
+	}
+}
+
+func TestFieldsCutResponsesDown(t *testing.T) {
+	f := newFixture(t)
+
+	expectKeys(t, getJSON(t, f.h, f.bobToken, "/photos/1?fields=title&expand="), "id", "title")
+	// expanded relations stay
+	expectKeys(t, getJSON(t, f.h, f.bobToken, "/comments/1?fields=message,created_at&expand=user"), "id", "message", "created_at", "User")
+
+	rec := f.h.Request(t, http.MethodGet, "/social-medias?user_id=1&fields=name&expand=", nil, f.bobToken)
+	socials := []map[string]any{}
+	apitest.Decode(t, rec, &socials)
+	if len(socials) != 1 {
+		t.Fatalf("expected one social media, got %v", socials)
+	}
+	expectKeys(t, socials[0], "id", "name")
+}
+
+func TestPrivateResourcesAreNotFound(t *testing.T) {
+	f := newFixture(t)
+	setPrivate(t, f.h, f.aliceToken, true)
+
+	expectStatus(t, f.h, f.bobToken, []string{"/photos/1", "/comments/1", "/social-medias/1"}, http.StatusNotFound)
+	expectStatus(t, f.h, f.aliceToken, []string{"/photos/1", "/comments/1", "/social-medias/1"}, http.StatusOK)
+}
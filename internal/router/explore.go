@@ -0,0 +1,35 @@
+package router
+
+import (
+	"mygram/internal/handler"
+	"mygram/internal/middleware"
+	"mygram/internal/model"
+	"mygram/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ExploreRouter interface {
+	Mount(g *gin.RouterGroup)
+}
+
+type exploreRouterImpl struct {
+	handler handler.ExploreHandler
+	limiter *middleware.RateLimiter
+	auth    *middleware.Authenticator
+}
+
+func NewExploreRouter(handler handler.ExploreHandler, limiter *middleware.RateLimiter, auth *middleware.Authenticator) ExploreRouter {
+	return &exploreRouterImpl{handler: handler, limiter: limiter, auth: auth}
+}
+
+func (e *exploreRouterImpl) Mount(g *gin.RouterGroup) {
+	v := g.Group("/explore")
+	v.Use(e.limiter.ByIP(ratelimit.GroupExplore))
+	v.Use(e.auth.CheckAuthBearerOrGuest)
+	v.Use(e.limiter.ByGuest(ratelimit.GroupExplore))
+	v.Use(middleware.RequireScope(model.SCOPE_PHOTOS_READ))
+	v.GET("", e.handler.GetExplore)
+	v.GET("/tags", e.handler.GetTrendingHashtags)
+	v.GET("/tags/:tag", e.handler.GetHashtagExplore)
+}
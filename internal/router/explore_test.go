@@ -0,0 +1,142 @@
+package router_test
+
+import (
+	"context"
+	"fmt"
+	"mygram/internal/apitest"
+	"mygram/internal/model"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func refreshRanking(t *testing.T, h *apitest.Harness) {
+	t.Helper()
+
+	if err := h.Ranking.Refresh(context.Background()); err != nil {
+		t.Fatalf("refresh ranking: %v", err)
+	}
+}
+
+func explorePhotos(t *testing.T, h *apitest.Harness, token string, path string) []uint64 {
+	t.Helper()
+
+	rec := h.Request(t, http.MethodGet, path, nil, token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("%s: %d %s", path, rec.Code, rec.Body.String())
+	}
+	photos := []model.PhotoGetRes{}
+	apitest.Decode(t, rec, &photos)
+
+	ids := []uint64{}
+	for _, photo := range photos {
+		ids = append(ids, photo.ID)
+	}
+	return ids
+}
+
+func comment(t *testing.T, h *apitest.Harness, token string, photoId uint64) {
+	t.Helper()
+
+	rec := h.Request(t, http.MethodPost, "/comments", model.CommentCreateReq{Message: "wow", PhotoId: photoId}, token)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("comment: %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestExploreRanksByDecayedEngagement(t *testing.T) {
+	f := newFixture(t)
+	carolToken := f.h.Token(t, f.h.CreateUser(t, "carol"))
+	base := time.Now()
+	now := base
+	f.h.Store.Now = func() time.Time { return now }
+
+	now = base.Add(-8 * 24 * time.Hour)
+	tooOld := postPhoto(t, f.h, f.bobToken, "archive", "last month")
+
+	now = base.Add(-30 * time.Hour)
+	old := postPhoto(t, f.h, f.bobToken, "yesterday", "busy thread")
+	for i := 0; i < 3; i++ {
+		comment(t, f.h, carolToken, old.ID)
+	}
+
+	now = base.Add(-time.Hour)
+	fresh := postPhoto(t, f.h, f.bobToken, "just now", "quiet")
+
+	now = base
+	comment(t, f.h, carolToken, fresh.ID)
+	comment(t, f.h, f.aliceToken, fresh.ID)
+	// the owner's own comments do not count
+	for i := 0; i < 5; i++ {
+		comment(t, f.h, f.bobToken, old.ID)
+	}
+	refreshRanking(t, f.h)
+
+	ids := explorePhotos(t, f.h, carolToken, "/explore")
+	expectIds(t, ids, fresh.ID, 1, old.ID)
+	for _, id := range ids {
+		if id == tooOld.ID {
+			t.Fatalf("expected photos older than the window left out, got %v", ids)
+		}
+	}
+	expectIds(t, explorePhotos(t, f.h, carolToken, "/explore?limit=1&offset=1"), 1)
+
+	// a day later the fresh photo's comments no longer outweigh its age
+	now = base.Add(24 * time.Hour)
+	comment(t, f.h, carolToken, 1)
+	comment(t, f.h, f.bobToken, 1)
+	refreshRanking(t, f.h)
+	expectIds(t, explorePhotos(t, f.h, carolToken, "/explore"), 1, fresh.ID, old.ID)
+}
+
+func TestExploreLeavesOutPrivateBlockedAndMuted(t *testing.T) {
+	f := newFixture(t)
+	carol := f.h.CreateUser(t, "carol")
+	carolToken := f.h.Token(t, carol)
+	daveToken := f.h.Token(t, f.h.CreateUser(t, "dave"))
+	bobs := postPhoto(t, f.h, f.bobToken, "bob", "b")
+	daves := postPhoto(t, f.h, daveToken, "dave", "d")
+	refreshRanking(t, f.h)
+
+	expectIds(t, explorePhotos(t, f.h, carolToken, "/explore"), daves.ID, bobs.ID, 1)
+
+	// not even approved followers find private accounts on explore
+	followUser(t, f.h, carolToken, "/users/1/follow")
+	setPrivate(t, f.h, f.aliceToken, true)
+	if rec := f.h.Request(t, http.MethodPost, "/users/4/mute", nil, carolToken); rec.Code != http.StatusOK {
+		t.Fatalf("mute: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := f.h.Request(t, http.MethodPost, "/users/3/block", nil, f.bobToken); rec.Code != http.StatusOK {
+		t.Fatalf("block: %d %s", rec.Code, rec.Body.String())
+	}
+
+	expectIds(t, explorePhotos(t, f.h, carolToken, "/explore"))
+	expectIds(t, explorePhotos(t, f.h, f.guestToken, "/explore"), daves.ID, bobs.ID)
+
+	if rec := f.h.Request(t, http.MethodDelete, fmt.Sprintf("/photos/%d", daves.ID), nil, daveToken); rec.Code != http.StatusOK {
+		t.Fatalf("delete photo: %d %s", rec.Code, rec.Body.String())
+	}
+	expectIds(t, explorePhotos(t, f.h, f.guestToken, "/explore"), bobs.ID)
+}
+
+func TestExploreTrendingHashtags(t *testing.T) {
+	f := newFixture(t)
+	carolToken := f.h.Token(t, f.h.CreateUser(t, "carol"))
+	kopi := postPhoto(t, f.h, f.bobToken, "pagi", "#Kopi #senja")
+	teh := postPhoto(t, f.h, f.bobToken, "sore", "#teh #senja")
+	comment(t, f.h, carolToken, teh.ID)
+	postPhoto(t, f.h, carolToken, "malam", "#kopi")
+	setPrivate(t, f.h, carolToken, true)
+	refreshRanking(t, f.h)
+
+	rec := f.h.Request(t, http.MethodGet, "/explore/tags", nil, f.guestToken)
+	tags := []model.TrendingHashtag{}
+	apitest.Decode(t, rec, &tags)
+	if len(tags) != 3 || tags[0].Tag != "senja" || tags[0].Photos != 2 || tags[1].Tag != "teh" || tags[2].Tag != "kopi" || tags[2].Photos != 1 {
+		t.Fatalf("expected senja, teh and kopi without the private photo, got %+v", tags)
+	}
+
+	expectIds(t, explorePhotos(t, f.h, f.aliceToken, "/explore/tags/senja"), teh.ID, kopi.ID)
+	expectIds(t, explorePhotos(t, f.h, f.aliceToken, "/explore/tags/KOPI"), kopi.ID)
+	expectIds(t, explorePhotos(t, f.h, carolToken, "/explore/tags/kopi"), kopi.ID)
+}
@@ -0,0 +1,170 @@
+package router_test
+
+import (
+	"context"
+	"mygram/internal/apitest"
+	"mygram/internal/model"
+	"net/http"
+	"testing"
+)
+
+func setPrivate(t *testing.T, h *apitest.Harness, token string, private bool) {
+	t.Helper()
+
+	rec := h.Request(t, http.MethodPut, "/users/me/privacy", map[string]any{"private": private}, token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("set privacy: %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func followUser(t *testing.T, h *apitest.Harness, token string, path string) model.FollowRes {
+	t.Helper()
+
+	rec := h.Request(t, http.MethodPost, path, nil, token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("follow: %d %s", rec.Code, rec.Body.String())
+	}
+	res := model.FollowRes{}
+	apitest.Decode(t, rec, &res)
+	return res
+}
+
+// aliceContent are the reads of alice's photos, comments and social medias.
+var aliceContent = []string{"/photos?user_id=1", "/comments?photo_id=1", "/social-medias?user_id=1"}
+
+func expectStatus(t *testing.T, h *apitest.Harness, token string, paths []string, status int) {
+	t.Helper()
+
+	for _, path := range paths {
+		if rec := h.Request(t, http.MethodGet, path, nil, token); rec.Code != status {
+			t.Fatalf("GET %s: expected %d, got %d: %s", path, status, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestPrivateAccountHidesContent(t *testing.T) {
+	f := newFixture(t)
+	setPrivate(t, f.h, f.aliceToken, true)
+
+	expectStatus(t, f.h, f.bobToken, aliceContent, http.StatusNotFound)
+	expectStatus(t, f.h, f.guestToken, aliceContent, http.StatusNotFound)
+	expectStatus(t, f.h, f.aliceToken, aliceContent, http.StatusOK)
+
+	// the photo cannot be reached by id either
+	if rec := f.h.Request(t, http.MethodPost, "/comments", model.CommentCreateReq{Message: "hi", PhotoId: 1}, f.bobToken); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 commenting on a hidden photo, got %d", rec.Code)
+	}
+
+	// the profile stays visible and says it is private
+	rec := f.h.Request(t, http.MethodGet, "/users/1", nil, f.guestToken)
+	profile := model.PublicProfile{}
+	apitest.Decode(t, rec, &profile)
+	if rec.Code != http.StatusOK || !profile.Private {
+		t.Fatalf("expected private profile, got %d %+v", rec.Code, profile)
+	}
+}
+
+func TestPrivateAuthorsCommentsAreHiddenOnPublicPhotos(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	photo, err := f.h.Services.Photo.CreatePhoto(ctx, model.Photo{Title: "t", PhotoUrl: "u", UserId: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, userId := range []uint64{1, 2} {
+		if _, err := f.h.Services.Comment.CreateComment(ctx, model.Comment{Message: "hello", PhotoId: photo.ID, UserId: userId}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	setPrivate(t, f.h, f.aliceToken, true)
+
+	comments, err := f.h.Services.Comment.GetCommentsByPhotoId(ctx, photo.ID, 0, model.Expand{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) != 1 || comments[0].UserId != 2 {
+		t.Fatalf("expected only bob's comment for guests, got %+v", comments)
+	}
+
+	comments, err = f.h.Services.Comment.GetCommentsByPhotoId(ctx, photo.ID, 1, model.Expand{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) != 2 {
+		t.Fatalf("expected alice to see her own comment, got %+v", comments)
+	}
+}
+
+func TestApprovedFollowerSeesPrivateAccount(t *testing.T) {
+	f := newFixture(t)
+	setPrivate(t, f.h, f.aliceToken, true)
+
+	if res := followUser(t, f.h, f.bobToken, "/users/1/follow"); res.Status != model.FOLLOW_STATUS_REQUESTED {
+		t.Fatalf("expected a follow request, got %+v", res)
+	}
+	expectStatus(t, f.h, f.bobToken, aliceContent, http.StatusNotFound)
+
+	rec := f.h.Request(t, http.MethodGet, "/users/me/follow-requests", nil, f.aliceToken)
+	requests := []model.FollowRequest{}
+	apitest.Decode(t, rec, &requests)
+	if len(requests) != 1 || requests[0].FollowerId != 2 || requests[0].User.Username != "bob" {
+		t.Fatalf("expected bob's request, got %+v", requests)
+	}
+
+	if rec := f.h.Request(t, http.MethodPost, "/users/me/follow-requests/2/approve", nil, f.aliceToken); rec.Code != http.StatusOK {
+		t.Fatalf("approve: %d %s", rec.Code, rec.Body.String())
+	}
+	expectStatus(t, f.h, f.bobToken, aliceContent, http.StatusOK)
+	expectStatus(t, f.h, f.guestToken, aliceContent, http.StatusNotFound)
+	if res := followUser(t, f.h, f.bobToken, "/users/1/follow"); res.Status != model.FOLLOW_STATUS_FOLLOWING {
+		t.Fatalf("expected following again to keep the approval, got %+v", res)
+	}
+
+	if rec := f.h.Request(t, http.MethodDelete, "/users/1/follow", nil, f.bobToken); rec.Code != http.StatusOK {
+		t.Fatalf("unfollow: %d %s", rec.Code, rec.Body.String())
+	}
+	expectStatus(t, f.h, f.bobToken, aliceContent, http.StatusNotFound)
+}
+
+func TestDeniedFollowRequestIsDropped(t *testing.T) {
+	f := newFixture(t)
+	setPrivate(t, f.h, f.aliceToken, true)
+	followUser(t, f.h, f.bobToken, "/users/1/follow")
+
+	if rec := f.h.Request(t, http.MethodDelete, "/users/me/follow-requests/2", nil, f.aliceToken); rec.Code != http.StatusOK {
+		t.Fatalf("deny: %d %s", rec.Code, rec.Body.String())
+	}
+	expectStatus(t, f.h, f.bobToken, aliceContent, http.StatusNotFound)
+
+	rec := f.h.Request(t, http.MethodGet, "/users/me/follow-requests", nil, f.aliceToken)
+	requests := []model.FollowRequest{}
+	apitest.Decode(t, rec, &requests)
+	if len(requests) != 0 {
+		t.Fatalf("expected no requests left, got %+v", requests)
+	}
+	if rec := f.h.Request(t, http.MethodPost, "/users/me/follow-requests/2/approve", nil, f.aliceToken); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected denied request to be gone, got %d", rec.Code)
+	}
+}
+
+func TestGoingPublicApprovesPendingRequests(t *testing.T) {
+	f := newFixture(t)
+	setPrivate(t, f.h, f.aliceToken, true)
+	followUser(t, f.h, f.bobToken, "/users/1/follow")
+
+	setPrivate(t, f.h, f.aliceToken, false)
+	expectStatus(t, f.h, f.guestToken, aliceContent, http.StatusOK)
+
+	// bob keeps following should alice go private again
+	setPrivate(t, f.h, f.aliceToken, true)
+	expectStatus(t, f.h, f.bobToken, aliceContent, http.StatusOK)
+}
+
+func TestFollowingPublicAccountIsImmediate(t *testing.T) {
+	f := newFixture(t)
+
+	if res := followUser(t, f.h, f.aliceToken, "/users/2/follow"); res.Status != model.FOLLOW_STATUS_FOLLOWING || res.FolloweeId != 2 {
+		t.Fatalf("expected to follow bob at once, got %+v", res)
+	}
+}
@@ -0,0 +1,37 @@
+package router
+
+import (
+	"mygram/internal/handler"
+	"mygram/internal/middleware"
+	"mygram/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GraphQLRouter interface {
+	Mount(g *gin.RouterGroup)
+}
+
+type graphQLRouterImpl struct {
+	handler handler.GraphQLHandler
+	limiter *middleware.RateLimiter
+	auth    *middleware.Authenticator
+}
+
+func NewGraphQLRouter(handler handler.GraphQLHandler, limiter *middleware.RateLimiter, auth *middleware.Authenticator) GraphQLRouter {
+	return &graphQLRouterImpl{handler: handler, limiter: limiter, auth: auth}
+}
+
+// Mount serves /graphql outside of the versions, the schema evolves by
+// adding fields instead. Scopes are checked per field by the handler.
+func (q *graphQLRouterImpl) Mount(g *gin.RouterGroup) {
+	v := g.Group("/graphql")
+	v.GET("/schema.graphql", q.handler.GetSchema)
+
+	v.Use(q.limiter.ByIP(ratelimit.GroupGraphQL))
+	v.Use(q.auth.CheckAuthBearerOrGuestQuery)
+	v.Use(q.limiter.ByGuest(ratelimit.GroupGraphQL))
+	v.Use(q.limiter.ByUser(ratelimit.GroupGraphQL))
+	v.GET("", q.handler.Query)
+	v.POST("", q.handler.Query)
+}
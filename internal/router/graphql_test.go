@@ -0,0 +1,201 @@
+package router_test
+
+import (
+	"encoding/json"
+	"mygram/internal/apitest"
+	"mygram/internal/model"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func graphQL(t *testing.T, h *apitest.Harness, token string, query string, status int) graphQLResponse {
+	t.Helper()
+
+	rec := h.Request(t, http.MethodPost, "/graphql", map[string]any{"query": query}, token)
+	if rec.Code != status {
+		t.Fatalf("expected %d, got %d: %s", status, rec.Code, rec.Body.String())
+	}
+	res := graphQLResponse{}
+	apitest.Decode(t, rec, &res)
+	return res
+}
+
+func expectGraphQLError(t *testing.T, res graphQLResponse, want string) {
+	t.Helper()
+
+	for _, err := range res.Errors {
+		if strings.Contains(err.Message, want) {
+			return
+		}
+	}
+	t.Fatalf("expected an error containing %q, got %+v", want, res.Errors)
+}
+
+// spans records the spans of the services, every test of the package
+// sharing the global tracer provider.
+var spans = sync.OnceValue(func() *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	return recorder
+})
+
+// countSpans counts the spans named name that ended after the first skip,
+// it is only accurate in tests that do not run in parallel.
+func countSpans(skip int, name string) int {
+	n := 0
+	for _, span := range spans().Ended()[skip:] {
+		if span.Name() == name {
+			n++
+		}
+	}
+	return n
+}
+
+func TestGraphQLLoadsAPhotoPageInOneRequest(t *testing.T) {
+	f := newFixture(t)
+	comment(t, f.h, f.bobToken, 1)
+
+	res := graphQL(t, f.h, f.aliceToken, `{
+		photo(id: 1) {
+			title
+			user { username email }
+			comments { message user { username } }
+			commentCount
+		}
+	}`, http.StatusOK)
+	want := `{"photo":{"title":"sunset","user":{"username":"alice","email":"alice@example.com"},` +
+		`"comments":[{"message":"nice","user":{"username":"alice"}},{"message":"wow","user":{"username":"bob"}}],"commentCount":2}}`
+	if string(res.Data) != want || len(res.Errors) > 0 {
+		t.Fatalf("expected %s, got %s %+v", want, res.Data, res.Errors)
+	}
+}
+
+func TestGraphQLBatchesRelations(t *testing.T) {
+	f := newFixture(t)
+	carolToken := f.h.Token(t, f.h.CreateUser(t, "carol"))
+	for i := 0; i < 4; i++ {
+		photo := postPhoto(t, f.h, f.aliceToken, "day", "out")
+		comment(t, f.h, f.bobToken, photo.ID)
+		comment(t, f.h, carolToken, photo.ID)
+	}
+
+	skip := len(spans().Ended())
+	res := graphQL(t, f.h, f.bobToken, `{
+		photos(userId: 1) {
+			user { username socialMedias { name } }
+			comments { user { username } }
+		}
+	}`, http.StatusOK)
+	if len(res.Errors) > 0 {
+		t.Fatalf("expected no errors, got %+v", res.Errors)
+	}
+
+	// one call per level, whatever the number of photos and comments
+	for name, want := range map[string]int{
+		"PhotoService.GetPhotosByUserIds":             1,
+		"CommentService.GetCommentsByPhotoIds":        1,
+		"UserService.GetUsersByIds":                   2,
+		"SocialMediaService.GetSocialMediasByUserIds": 1,
+		"CommentService.GetCommentsByPhotoId":         0,
+		"SocialMediaService.GetSocialMediasByUserId":  0,
+	} {
+		if got := countSpans(skip, name); got != want {
+			t.Errorf("expected %d calls of %s, got %d", want, name, got)
+		}
+	}
+}
+
+func TestGraphQLOnlyShowsTheViewersOwnEmail(t *testing.T) {
+	f := newFixture(t)
+
+	res := graphQL(t, f.h, f.bobToken, `{ me { email } user(id: 1) { username email } }`, http.StatusOK)
+	want := `{"me":{"email":"bob@example.com"},"user":{"username":"alice","email":null}}`
+	if string(res.Data) != want || len(res.Errors) > 0 {
+		t.Fatalf("expected %s, got %s %+v", want, res.Data, res.Errors)
+	}
+}
+
+func TestGraphQLGuestsReadWithoutEmails(t *testing.T) {
+	f := newFixture(t)
+
+	res := graphQL(t, f.h, f.guestToken, `{ me { id } photo(id: 1) { user { username email } } }`, http.StatusOK)
+	want := `{"me":null,"photo":{"user":{"username":"alice","email":null}}}`
+	if string(res.Data) != want {
+		t.Fatalf("expected %s, got %s", want, res.Data)
+	}
+
+	// guests are only granted the read scopes
+	res = graphQL(t, f.h, f.guestToken, `mutation { deletePhoto(id: 1) }`, http.StatusBadRequest)
+	expectGraphQLError(t, res, "requires the photos:write scope")
+	if rec := f.h.Request(t, http.MethodGet, "/photos/1", nil, f.aliceToken); rec.Code != http.StatusOK {
+		t.Fatalf("expected the photo kept, got %d", rec.Code)
+	}
+}
+
+func TestGraphQLMutationsCheckOwnership(t *testing.T) {
+	f := newFixture(t)
+
+	res := graphQL(t, f.h, f.bobToken, `mutation { updatePhoto(id: 1, title: "mine") { title } }`, http.StatusOK)
+	expectGraphQLError(t, res, "invalid user request")
+
+	res = graphQL(t, f.h, f.aliceToken, `mutation {
+		updatePhoto(id: 1, title: "dusk") { title caption }
+		createComment(photoId: 1, message: "still nice") { message photo { commentCount } }
+	}`, http.StatusOK)
+	want := `{"updatePhoto":{"title":"dusk","caption":"at the beach"},"createComment":{"message":"still nice","photo":{"commentCount":2}}}`
+	if string(res.Data) != want || len(res.Errors) > 0 {
+		t.Fatalf("expected %s, got %s %+v", want, res.Data, res.Errors)
+	}
+
+	unverified := f.h.Token(t, f.h.CreateUnverifiedUser(t, "carol"))
+	res = graphQL(t, f.h, unverified, `mutation { createSocialMedia(name: "x", socialMediaUrl: "https://x.com/carol") { id } }`, http.StatusOK)
+	expectGraphQLError(t, res, "confirm your email address before posting")
+}
+
+func TestGraphQLChecksTokenScopes(t *testing.T) {
+	f := newFixture(t)
+	token := newAccessToken(t, f.h, f.aliceToken, model.SCOPE_PROFILE_READ).Token
+
+	graphQL(t, f.h, token, `{ me { username } }`, http.StatusOK)
+	res := graphQL(t, f.h, token, `{ me { photos { title } } }`, http.StatusBadRequest)
+	expectGraphQLError(t, res, "requires the photos:read scope")
+}
+
+func TestGraphQLRefusesExpensiveQueries(t *testing.T) {
+	f := newFixture(t)
+
+	deep := `{ photo(id: 1) {` + strings.Repeat(` comments { photo {`, 5) + ` id` + strings.Repeat(` } }`, 5) + ` } }`
+	res := graphQL(t, f.h, f.bobToken, deep, http.StatusBadRequest)
+	expectGraphQLError(t, res, "exceeds max depth")
+
+	wide := `{ explore(limit: 50) { comments { user { photos { comments { id } } } } } }`
+	res = graphQL(t, f.h, f.bobToken, wide, http.StatusBadRequest)
+	expectGraphQLError(t, res, "complexity")
+}
+
+func TestGraphQLBoundsTheRequest(t *testing.T) {
+	f := newFixture(t)
+
+	// the parser stops at the length of the query before it recurses that deep
+	nested := strings.Repeat(`{ photo(id: 1) `, 20000) + strings.Repeat(`}`, 20000)
+	graphQL(t, f.h, f.bobToken, nested, http.StatusBadRequest)
+
+	large := `{ me { username } }` + strings.Repeat(` `, 2<<20)
+	rec := f.h.Request(t, http.MethodPost, "/graphql", map[string]any{"query": large}, f.bobToken)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected %d, got %d: %s", http.StatusRequestEntityTooLarge, rec.Code, rec.Body.String())
+	}
+}
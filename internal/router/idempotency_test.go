@@ -0,0 +1,131 @@
+package router_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"mygram/internal/apitest"
+	"mygram/internal/middleware"
+	"mygram/internal/model"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const photoBody = `{"title":"pagi","caption":"kopi","photo_url":"https://img.example.com/p.jpg"}`
+
+func postIdempotent(t *testing.T, h *apitest.Harness, token string, path string, body string, key string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+token)
+	header.Set(middleware.HEADER_IDEMPOTENCY_KEY, key)
+	return h.RequestWithHeaders(t, http.MethodPost, path, body, header)
+}
+
+func TestIdempotencyKeyReplaysTheFirstResponse(t *testing.T) {
+	f := newFixture(t)
+
+	first := postIdempotent(t, f.h, f.bobToken, "/photos", photoBody, "k1")
+	if first.Code != http.StatusCreated {
+		t.Fatalf("create photo: %d %s", first.Code, first.Body.String())
+	}
+	retry := postIdempotent(t, f.h, f.bobToken, "/photos", photoBody, "k1")
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+		t.Fatalf("expected the first response replayed, got %d %s", retry.Code, retry.Body.String())
+	}
+	if retry.Header().Get(middleware.HEADER_IDEMPOTENT_REPLAYED) != "true" {
+		t.Fatalf("expected the replay marked, got %v", retry.Header())
+	}
+
+	created := model.PhotoCreateRes{}
+	apitest.Decode(t, first, &created)
+	// the retry created nothing, so the next photo takes the next id
+	if next := postPhoto(t, f.h, f.bobToken, "siang", "teh"); next.ID != created.ID+1 {
+		t.Fatalf("expected photo %d next, got %d", created.ID+1, next.ID)
+	}
+}
+
+func TestIdempotencyKeyReusedForAnotherRequestConflicts(t *testing.T) {
+	f := newFixture(t)
+
+	if rec := postIdempotent(t, f.h, f.bobToken, "/photos", photoBody, "k1"); rec.Code != http.StatusCreated {
+		t.Fatalf("create photo: %d %s", rec.Code, rec.Body.String())
+	}
+	rec := postIdempotent(t, f.h, f.bobToken, "/photos", `{"title":"malam","caption":"teh","photo_url":"https://img.example.com/p.jpg"}`, "k1")
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for another body, got %d %s", rec.Code, rec.Body.String())
+	}
+	rec = postIdempotent(t, f.h, f.bobToken, "/comments", `{"message":"nice","photo_id":1}`, "k1")
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for another endpoint, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	// keys belong to a user
+	if rec := postIdempotent(t, f.h, f.aliceToken, "/photos", photoBody, "k1"); rec.Code != http.StatusCreated || rec.Header().Get(middleware.HEADER_IDEMPOTENT_REPLAYED) != "" {
+		t.Fatalf("expected alice's request to run, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestIdempotencyKeyIsSharedWithTheUnversionedAlias(t *testing.T) {
+	f := newFixture(t)
+
+	first := postIdempotent(t, f.h, f.bobToken, "/v1/photos", photoBody, "k1")
+	if first.Code != http.StatusCreated {
+		t.Fatalf("create photo: %d %s", first.Code, first.Body.String())
+	}
+	retry := postIdempotent(t, f.h, f.bobToken, "/photos", photoBody, "k1")
+	if retry.Code != http.StatusCreated || retry.Header().Get(middleware.HEADER_IDEMPOTENT_REPLAYED) != "true" {
+		t.Fatalf("expected the retry through the alias replayed, got %d %v", retry.Code, retry.Header())
+	}
+}
+
+func TestIdempotencyKeyInProgressIsLocked(t *testing.T) {
+	f := newFixture(t)
+	bob, err := f.h.Repos.User.GetUserByUsername(context.Background(), "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sum := sha256.Sum256([]byte("POST /photos\n" + photoBody))
+	if _, ok, err := f.h.Services.Idempotency.Begin(context.Background(), bob.ID, "k1", hex.EncodeToString(sum[:])); err != nil || !ok {
+		t.Fatalf("begin: %v %v", ok, err)
+	}
+
+	rec := postIdempotent(t, f.h, f.bobToken, "/photos", photoBody, "k1")
+	if rec.Code != http.StatusConflict || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected 409 with Retry-After while in progress, got %d %v", rec.Code, rec.Header())
+	}
+}
+
+func TestIdempotencyKeyReplaysClientErrors(t *testing.T) {
+	f := newFixture(t)
+
+	rec := postIdempotent(t, f.h, f.bobToken, "/photos", `{"title":""}`, "k1")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d %s", rec.Code, rec.Body.String())
+	}
+	// a client error is an answer too, and replayed as such
+	retry := postIdempotent(t, f.h, f.bobToken, "/photos", `{"title":""}`, "k1")
+	if retry.Code != http.StatusBadRequest || retry.Header().Get(middleware.HEADER_IDEMPOTENT_REPLAYED) != "true" {
+		t.Fatalf("expected the 400 replayed, got %d %v", retry.Code, retry.Header())
+	}
+
+	long := make([]byte, 256)
+	for i := range long {
+		long[i] = 'k'
+	}
+	if rec := postIdempotent(t, f.h, f.bobToken, "/photos", photoBody, string(long)); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a long key, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestIdempotencyKeyBoundsTheBody(t *testing.T) {
+	f := newFixture(t)
+
+	large := `{"title":"pagi","caption":"` + strings.Repeat("k", 2<<20) + `"}`
+	if rec := postIdempotent(t, f.h, f.bobToken, "/photos", large, "k1"); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d %s", rec.Code, rec.Body.String())
+	}
+}
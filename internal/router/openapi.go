@@ -0,0 +1,24 @@
+package router
+
+import (
+	"mygram/internal/handler"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OpenAPIRouter interface {
+	Mount(g *gin.RouterGroup)
+}
+
+type openAPIRouterImpl struct {
+	handler handler.OpenAPIHandler
+}
+
+func NewOpenAPIRouter(handler handler.OpenAPIHandler) OpenAPIRouter {
+	return &openAPIRouterImpl{handler: handler}
+}
+
+// Mount serves the document outside of the versions, it describes them.
+func (o *openAPIRouterImpl) Mount(g *gin.RouterGroup) {
+	g.GET("/openapi.json", o.handler.GetSpec)
+}
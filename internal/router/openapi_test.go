@@ -0,0 +1,75 @@
+package router_test
+
+import (
+	"encoding/json"
+	"mygram/internal/apitest"
+	"mygram/internal/app"
+	"mygram/internal/openapi"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestEveryRouteIsInTheSpec(t *testing.T) {
+	doc := openapi.New(app.API_V1)
+
+	documented := 0
+	for _, r := range apitest.New().Routes() {
+		path, ok := strings.CutPrefix(r.Path, app.API_V1)
+		if !ok {
+			continue
+		}
+		segments := strings.Split(path, "/")
+		for i, segment := range segments {
+			if name, ok := strings.CutPrefix(segment, ":"); ok {
+				segments[i] = "{" + name + "}"
+			}
+		}
+		path = strings.Join(segments, "/")
+
+		if doc.Paths[path][strings.ToLower(r.Method)] == nil {
+			t.Errorf("route %s is missing from the spec as %s %s", r, r.Method, path)
+			continue
+		}
+		documented++
+	}
+
+	operations := 0
+	for _, item := range doc.Paths {
+		operations += len(item)
+	}
+	if operations != documented {
+		t.Errorf("expected the spec to document only the %d routes, got %d operations", documented, operations)
+	}
+}
+
+func TestOpenAPIDocumentIsServed(t *testing.T) {
+	f := newFixture(t)
+
+	rec := f.h.Request(t, http.MethodGet, "/openapi.json", nil, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("get openapi.json: %d %s", rec.Code, rec.Body.String())
+	}
+	var doc openapi.Document
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("decode openapi.json: %v", err)
+	}
+	if doc.OpenAPI != openapi.VERSION || len(doc.Servers) != 1 || doc.Servers[0].URL != app.API_V1 {
+		t.Fatalf("expected an OpenAPI %s document of %s, got %s %v", openapi.VERSION, app.API_V1, doc.OpenAPI, doc.Servers)
+	}
+	if doc.Components.SecuritySchemes[openapi.SECURITY_BEARER].Scheme != "bearer" || doc.Components.SecuritySchemes[openapi.SECURITY_BASIC].Scheme != "basic" {
+		t.Fatalf("expected the bearer and basic schemes, got %v", doc.Components.SecuritySchemes)
+	}
+	if _, ok := doc.Components.Schemas["ErrorResponse"]; !ok {
+		t.Fatalf("expected the error schema, got %v", doc.Components.Schemas)
+	}
+
+	login := doc.Paths["/users/login"]["post"]
+	if login == nil || len(login.Security) != 0 || login.RequestBody == nil || login.Responses["401"] == nil {
+		t.Fatalf("expected login to be public with a body and a 401, got %+v", login)
+	}
+	deleteUser := doc.Paths["/users/{id}"]["delete"]
+	if deleteUser == nil || len(deleteUser.Security) != 1 || deleteUser.Parameters[0].In != "path" {
+		t.Fatalf("expected delete user to take a bearer token and the id, got %+v", deleteUser)
+	}
+}
@@ -0,0 +1,91 @@
+package router_test
+
+import (
+	"context"
+	"mygram/internal/apitest"
+	"mygram/internal/model"
+	"mygram/internal/password"
+	"net/http"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func storedHash(t *testing.T, h *apitest.Harness, id uint64) string {
+	t.Helper()
+
+	user, err := h.Repos.User.GetUsersByID(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user.Password
+}
+
+func TestLoginUpgradesBcryptHash(t *testing.T) {
+	h := apitest.New()
+	alice := h.CreateUser(t, "alice")
+
+	legacy, err := bcrypt.GenerateFromPassword([]byte(apitest.DefaultPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Repos.User.EditUser(context.Background(), model.User{ID: alice.ID, Password: string(legacy)}); err != nil {
+		t.Fatal(err)
+	}
+
+	before, err := h.Repos.User.GetUsersByID(context.Background(), alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signIn(t, h, "alice@example.com", apitest.DefaultPassword)
+	upgraded := storedHash(t, h, alice.ID)
+	if !strings.HasPrefix(upgraded, "$argon2id$") {
+		t.Fatalf("hash was not upgraded: %q", upgraded)
+	}
+	// nothing a client sees changed, nor does the ETag of the user
+	after, err := h.Repos.User.GetUsersByID(context.Background(), alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !after.UpdatedAt.Equal(before.UpdatedAt) {
+		t.Fatalf("expected updated_at kept, got %v then %v", before.UpdatedAt, after.UpdatedAt)
+	}
+
+	// the new hash is used from now on
+	signIn(t, h, "alice@example.com", apitest.DefaultPassword)
+	if again := storedHash(t, h, alice.ID); again != upgraded {
+		t.Fatal("an up to date hash was replaced")
+	}
+}
+
+func TestLoginUpgradesArgon2idParams(t *testing.T) {
+	params := password.DefaultArgon2idParams()
+	params.Iterations++
+	h := apitest.New(apitest.WithPasswordParams(params))
+	alice := h.CreateUser(t, "alice")
+
+	signIn(t, h, "alice@example.com", apitest.DefaultPassword)
+	if hash := storedHash(t, h, alice.ID); password.NewHasher(params).NeedsRehash(hash) {
+		t.Fatalf("hash still has the old parameters: %q", hash)
+	}
+}
+
+func TestFailedLoginKeepsHash(t *testing.T) {
+	h := apitest.New()
+	alice := h.CreateUser(t, "alice")
+
+	legacy, err := bcrypt.GenerateFromPassword([]byte(apitest.DefaultPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Repos.User.EditUser(context.Background(), model.User{ID: alice.ID, Password: string(legacy)}); err != nil {
+		t.Fatal(err)
+	}
+
+	h.Request(t, http.MethodPost, "/users/login", model.UserSignIn{Email: "alice@example.com", Password: "wrong-password"}, "")
+	if hash := storedHash(t, h, alice.ID); hash != string(legacy) {
+		t.Fatal("hash changed after a failed login")
+	}
+}
@@ -0,0 +1,129 @@
+package router_test
+
+import (
+	"fmt"
+	"mygram/internal/apitest"
+	"mygram/internal/model"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func newAccessToken(t *testing.T, h *apitest.Harness, session string, scopes ...string) model.PersonalAccessTokenCreateRes {
+	t.Helper()
+
+	rec := h.Request(t, http.MethodPost, "/users/tokens", model.PersonalAccessTokenCreateReq{Name: "script", Scopes: scopes}, session)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create token: %d %s", rec.Code, rec.Body.String())
+	}
+	res := model.PersonalAccessTokenCreateRes{}
+	apitest.Decode(t, rec, &res)
+	return res
+}
+
+func TestPersonalAccessTokenLifecycle(t *testing.T) {
+	f := newFixture(t)
+	created := newAccessToken(t, f.h, f.aliceToken, model.SCOPE_PHOTOS_READ, model.SCOPE_PHOTOS_WRITE)
+	if !strings.HasPrefix(created.Token, model.PERSONAL_ACCESS_TOKEN_PREFIX) || !strings.HasPrefix(created.Token, created.TokenPrefix) {
+		t.Fatalf("unexpected token %q with prefix %q", created.Token, created.TokenPrefix)
+	}
+
+	rec := f.h.Request(t, http.MethodGet, "/photos?user_id=1", nil, created.Token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("read with token: %d %s", rec.Code, rec.Body.String())
+	}
+	rec = f.h.Request(t, http.MethodPost, "/photos", model.PhotoCreateReq{Title: "t", Caption: "c", PhotoUrl: "u"}, created.Token)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("write with token: %d %s", rec.Code, rec.Body.String())
+	}
+	photo := model.PhotoCreateRes{}
+	apitest.Decode(t, rec, &photo)
+	if photo.UserId != 1 {
+		t.Fatalf("expected photo owned by alice, got user %d", photo.UserId)
+	}
+
+	// listing never shows the token again, but shows when it was used
+	rec = f.h.Request(t, http.MethodGet, "/users/tokens", nil, f.aliceToken)
+	if strings.Contains(rec.Body.String(), created.Token) {
+		t.Fatal("token listed in clear")
+	}
+	listed := []model.PersonalAccessToken{}
+	apitest.Decode(t, rec, &listed)
+	if len(listed) != 1 || listed[0].LastUsedAt == nil {
+		t.Fatalf("expected one used token, got %+v", listed)
+	}
+
+	// other users cannot revoke it
+	path := fmt.Sprintf("/users/tokens/%d", created.ID)
+	if rec := f.h.Request(t, http.MethodDelete, path, nil, f.bobToken); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 revoking someone else's token, got %d", rec.Code)
+	}
+	if rec := f.h.Request(t, http.MethodDelete, path, nil, f.aliceToken); rec.Code != http.StatusOK {
+		t.Fatalf("revoke: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := f.h.Request(t, http.MethodGet, "/photos?user_id=1", nil, created.Token); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected revoked token to be refused, got %d", rec.Code)
+	}
+}
+
+func TestPersonalAccessTokenStopsWorkingWithDeletedAccount(t *testing.T) {
+	f := newFixture(t)
+	created := newAccessToken(t, f.h, f.aliceToken, model.SCOPE_PROFILE_READ)
+
+	if rec := f.h.Request(t, http.MethodDelete, "/users/1", nil, f.aliceToken); rec.Code != http.StatusOK {
+		t.Fatalf("delete account: %d", rec.Code)
+	}
+	if rec := f.h.Request(t, http.MethodGet, "/users/2", nil, created.Token); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected token of deleted account to be refused, got %d", rec.Code)
+	}
+}
+
+// TestEveryProtectedRouteDeclaresScope uses a token that can only read
+// profiles, so any other route letting it through lacks a scope.
+func TestEveryProtectedRouteDeclaresScope(t *testing.T) {
+	// routes reading the profile and what hangs off it
+	profileReads := map[apitest.Route]bool{getUser: true, followReqs: true, listBlocks: true, listMutes: true}
+
+	for _, tc := range protected {
+		t.Run(tc.route.String(), func(t *testing.T) {
+			t.Parallel()
+			f := newFixture(t)
+			token := newAccessToken(t, f.h, f.aliceToken, model.SCOPE_PROFILE_READ).Token
+
+			rec := f.h.Request(t, tc.route.Method, tc.path, nil, token)
+			if profileReads[tc.route] {
+				if rec.Code != http.StatusOK {
+					t.Fatalf("expected 200 with profile:read, got %d: %s", rec.Code, rec.Body.String())
+				}
+				return
+			}
+			if rec.Code != http.StatusForbidden {
+				t.Fatalf("expected 403, got %d: %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestPersonalAccessTokenCannotChangeEmail(t *testing.T) {
+	f := newFixture(t)
+	token := newAccessToken(t, f.h, f.aliceToken, model.SCOPE_PROFILE_WRITE).Token
+
+	if rec := f.h.Request(t, http.MethodPatch, "/users/1", map[string]any{"username": "alice2"}, token); rec.Code != http.StatusOK {
+		t.Fatalf("expected the username edited, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := f.h.Request(t, http.MethodPatch, "/users/1", map[string]any{"email": "mallory@example.com"}, token); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 changing the email with a token, got %d", rec.Code)
+	}
+	if rec := f.h.Request(t, http.MethodPut, "/users/1", map[string]any{"email": "mallory@example.com", "username": "alice2"}, token); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 replacing the email with a token, got %d", rec.Code)
+	}
+
+	user := model.User{}
+	apitest.Decode(t, f.h.Request(t, http.MethodGet, "/users/1", nil, f.aliceToken), &user)
+	if user.Email != "alice@example.com" {
+		t.Fatalf("expected the email kept, got %q", user.Email)
+	}
+	if rec := f.h.Request(t, http.MethodPatch, "/users/1", map[string]any{"email": "alice@example.org"}, f.aliceToken); rec.Code != http.StatusOK {
+		t.Fatalf("expected a session to change the email, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -3,29 +3,42 @@ package router
 import (
 	"mygram/internal/handler"
 	"mygram/internal/middleware"
+	"mygram/internal/model"
+	"mygram/internal/ratelimit"
 
 	"github.com/gin-gonic/gin"
 )
 
 type PhotoRouter interface {
-	Mount()
+	Mount(g *gin.RouterGroup)
 }
 
 type photoRouterImpl struct {
-	v *gin.RouterGroup
 	handler handler.PhotoHandler
+	limiter *middleware.RateLimiter
+	auth *middleware.Authenticator
+	verified gin.HandlerFunc
+	idempotent gin.HandlerFunc
 }
 
-func NewPhotoRouter(v *gin.RouterGroup, handler handler.PhotoHandler) PhotoRouter{
-	return &photoRouterImpl{v:v, handler: handler}
+func NewPhotoRouter(handler handler.PhotoHandler, limiter *middleware.RateLimiter, auth *middleware.Authenticator, verified gin.HandlerFunc, idempotent gin.HandlerFunc) PhotoRouter{
+	return &photoRouterImpl{handler: handler, limiter: limiter, auth: auth, verified: verified, idempotent: idempotent}
 }
 
-func (p *photoRouterImpl) Mount() {
-	p.v.Use(middleware.CheckAuthBearer)
-	p.v.POST("", p.handler.CreatePhoto)
-	p.v.GET("",p.handler.GetPhotosByUserId)
-	p.v.PUT("/:id",p.handler.EditPhoto)
-	p.v.DELETE("/:id", p.handler.DeletePhoto)
+func (p *photoRouterImpl) Mount(g *gin.RouterGroup) {
+	v := g.Group("/photos")
+	v.Use(p.limiter.ByIP(ratelimit.GroupPhotos))
+	// guests can read, the authenticator refuses their writes
+	v.Use(p.auth.CheckAuthBearerOrGuest)
+	v.Use(p.limiter.ByGuest(ratelimit.GroupPhotos))
+	v.Use(p.limiter.ByUser(ratelimit.GroupPhotos))
+	v.Use(p.verified)
+	v.POST("", middleware.RequireScope(model.SCOPE_PHOTOS_WRITE), p.idempotent, p.handler.CreatePhoto)
+	v.GET("", middleware.RequireScope(model.SCOPE_PHOTOS_READ), p.handler.GetPhotosByUserId)
+	v.GET("/:id", middleware.RequireScope(model.SCOPE_PHOTOS_READ), p.handler.GetPhotoById)
+	v.PUT("/:id", middleware.RequireScope(model.SCOPE_PHOTOS_WRITE), p.handler.EditPhoto)
+	v.PATCH("/:id", middleware.RequireScope(model.SCOPE_PHOTOS_WRITE), p.handler.EditPhoto)
+	v.DELETE("/:id", middleware.RequireScope(model.SCOPE_PHOTOS_WRITE), p.handler.DeletePhoto)
 
 }
 
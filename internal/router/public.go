@@ -0,0 +1,28 @@
+package router
+
+import (
+	"mygram/internal/handler"
+	"mygram/internal/middleware"
+	"mygram/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PublicRouter interface {
+	Mount(g *gin.RouterGroup)
+}
+
+type publicRouterImpl struct {
+	handler handler.PublicHandler
+	limiter *middleware.RateLimiter
+}
+
+func NewPublicRouter(handler handler.PublicHandler, limiter *middleware.RateLimiter) PublicRouter {
+	return &publicRouterImpl{handler: handler, limiter: limiter}
+}
+
+func (p *publicRouterImpl) Mount(g *gin.RouterGroup) {
+	v := g.Group("/public")
+	v.Use(p.limiter.ByIP(ratelimit.GroupPublic))
+	v.GET("", p.handler.GetPublicToken)
+}
@@ -0,0 +1,110 @@
+package router_test
+
+import (
+	"mygram/internal/apitest"
+	"mygram/internal/model"
+	"mygram/internal/ratelimit"
+	"mygram/pkg/helper"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGuestReadsWithoutEmails(t *testing.T) {
+	f := newFixture(t)
+
+	for _, path := range []string{"/users/1", "/photos?user_id=1", "/comments?photo_id=1", "/social-medias?user_id=1",
+		"/photos/1?expand=user,comments", "/comments/1", "/social-medias/1"} {
+		rec := f.h.Request(t, http.MethodGet, path, nil, f.guestToken)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: %d %s", path, rec.Code, rec.Body.String())
+		}
+		if strings.Contains(rec.Body.String(), "@example.com") {
+			t.Fatalf("%s: guest sees an email: %s", path, rec.Body.String())
+		}
+	}
+
+	// users still see them
+	rec := f.h.Request(t, http.MethodGet, "/photos?user_id=1", nil, f.bobToken)
+	if !strings.Contains(rec.Body.String(), "alice@example.com") {
+		t.Fatalf("expected email for users: %s", rec.Body.String())
+	}
+}
+
+func TestOnlyTheUserReadsTheirWholeProfile(t *testing.T) {
+	f := newFixture(t)
+
+	rec := f.h.Request(t, http.MethodGet, "/users/1", nil, f.bobToken)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if body := rec.Body.String(); strings.Contains(body, "@example.com") || strings.Contains(body, "dob") {
+		t.Fatalf("expected the public profile for another user, got %s", body)
+	}
+
+	rec = f.h.Request(t, http.MethodGet, "/users/1", nil, f.aliceToken)
+	if !strings.Contains(rec.Body.String(), "alice@example.com") {
+		t.Fatalf("expected the whole user for themselves, got %s", rec.Body.String())
+	}
+}
+
+func TestPublicTokenIsNotAUserToken(t *testing.T) {
+	f := newFixture(t)
+
+	for _, route := range []apitest.Route{listSessions, listTokens, enroll2FA} {
+		rec := f.h.Request(t, route.Method, route.Path, nil, f.guestToken)
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("%s: expected 401, got %d: %s", route, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestTokensForOtherAudiencesAreRefused(t *testing.T) {
+	f := newFixture(t)
+	now := time.Now()
+
+	tests := map[string]model.StandardClaim{
+		"other audience": {Iss: helper.TOKEN_ISSUER, Aud: "other-api", Sub: model.SUBJECT_PUBLIC_TOKEN},
+		"other issuer":   {Iss: "someone-else", Aud: helper.TOKEN_AUDIENCE, Sub: model.SUBJECT_PUBLIC_TOKEN},
+		"oauth state":    {Iss: helper.TOKEN_ISSUER, Aud: helper.TOKEN_AUDIENCE, Sub: model.SUBJECT_OAUTH_STATE},
+	}
+	for name, claim := range tests {
+		t.Run(name, func(t *testing.T) {
+			claim.Exp = uint64(now.Add(time.Hour).Unix())
+			claim.Iat = uint64(now.Unix())
+			claim.Nbf = uint64(now.Unix())
+			token, err := helper.GenerateToken(claim)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rec := f.h.Request(t, http.MethodGet, "/photos?user_id=1", nil, token)
+			if rec.Code != http.StatusUnauthorized {
+				t.Fatalf("expected 401, got %d: %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestGuestsAreRateLimited(t *testing.T) {
+	h := apitest.New(apitest.WithRateLimits(ratelimit.Config{
+		ratelimit.GroupPhotos: {Guest: ratelimit.PerMinute(2)},
+	}))
+	alice := h.CreateUser(t, "alice")
+	token := h.Token(t, alice)
+	guestToken := h.PublicToken(t)
+
+	for i := 0; i < 2; i++ {
+		if rec := h.Request(t, http.MethodGet, "/photos?user_id=1", nil, guestToken); rec.Code != http.StatusNotFound {
+			t.Fatalf("attempt %d: expected 404, got %d", i+1, rec.Code)
+		}
+	}
+	if rec := h.Request(t, http.MethodGet, "/photos?user_id=1", nil, guestToken); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected guest to be limited, got %d", rec.Code)
+	}
+	// the same address signed in is not a guest
+	if rec := h.Request(t, http.MethodGet, "/photos?user_id=1", nil, token); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected user not to be limited, got %d", rec.Code)
+	}
+}
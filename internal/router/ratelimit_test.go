@@ -0,0 +1,144 @@
+package router_test
+
+import (
+	"fmt"
+	"mygram/internal/apitest"
+	"mygram/internal/model"
+	"mygram/internal/ratelimit"
+	"mygram/pkg"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestLoginErrorsDoNotRevealAccounts(t *testing.T) {
+	f := newFixture(t)
+
+	wrongPassword := f.h.Request(t, http.MethodPost, "/users/login", model.UserSignIn{Email: "alice@example.com", Password: "wrong-password"}, "")
+	unknownEmail := f.h.Request(t, http.MethodPost, "/users/login", model.UserSignIn{Email: "nobody@example.com", Password: "wrong-password"}, "")
+
+	if wrongPassword.Code != unknownEmail.Code {
+		t.Fatalf("status differs: %d vs %d", wrongPassword.Code, unknownEmail.Code)
+	}
+	if wrongPassword.Body.String() != unknownEmail.Body.String() {
+		t.Fatalf("body differs: %s vs %s", wrongPassword.Body.String(), unknownEmail.Body.String())
+	}
+}
+
+func TestLoginLocksOutAfterRepeatedFailures(t *testing.T) {
+	h := apitest.New(apitest.WithLockoutPolicy(ratelimit.LockoutPolicy{
+		Threshold: 3,
+		BaseDelay: time.Minute,
+		MaxDelay:  time.Hour,
+		Window:    time.Hour,
+	}))
+	h.CreateUser(t, "alice")
+
+	for i := 0; i < 3; i++ {
+		rec := h.Request(t, http.MethodPost, "/users/login", model.UserSignIn{Email: "alice@example.com", Password: "wrong-password"}, "")
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: expected 401, got %d", i+1, rec.Code)
+		}
+	}
+
+	// even the right password is refused while locked
+	rec := h.Request(t, http.MethodPost, "/users/login", model.UserSignIn{Email: "alice@example.com", Password: apitest.DefaultPassword}, "")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 while locked, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Fatal("expected Retry-After header")
+	}
+}
+
+func TestLoginRateLimitedByEmail(t *testing.T) {
+	h := apitest.New(apitest.WithRateLimits(ratelimit.Config{
+		ratelimit.GroupLogin: {Email: ratelimit.PerMinute(2)},
+	}))
+	h.CreateUser(t, "alice")
+
+	for i := 0; i < 2; i++ {
+		rec := h.Request(t, http.MethodPost, "/users/login", model.UserSignIn{Email: "alice@example.com", Password: apitest.DefaultPassword}, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("attempt %d: expected 200, got %d", i+1, rec.Code)
+		}
+		if got := rec.Header().Get("RateLimit-Remaining"); got != []string{"1", "0"}[i] {
+			t.Fatalf("attempt %d: expected RateLimit-Remaining %d, got %q", i+1, 1-i, got)
+		}
+	}
+
+	rec := h.Request(t, http.MethodPost, "/users/login", model.UserSignIn{Email: "ALICE@example.com", Password: apitest.DefaultPassword}, "")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", rec.Code)
+	}
+	if rec.Header().Get("RateLimit-Limit") != "2" || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("missing rate limit headers: %v", rec.Header())
+	}
+	res := pkg.ErrorResponse{}
+	apitest.Decode(t, rec, &res)
+	if res.Message != "too many requests" {
+		t.Fatalf("unexpected message %q", res.Message)
+	}
+
+	// another account from the same address is unaffected
+	h.CreateUser(t, "bob")
+	rec = h.Request(t, http.MethodPost, "/users/login", model.UserSignIn{Email: "bob@example.com", Password: apitest.DefaultPassword}, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for another email, got %d", rec.Code)
+	}
+}
+
+func TestWritesRateLimitedByUser(t *testing.T) {
+	h := apitest.New(apitest.WithRateLimits(ratelimit.Config{
+		ratelimit.GroupPhotos: {User: ratelimit.PerMinute(1)},
+	}))
+	alice := h.CreateUser(t, "alice")
+	bob := h.CreateUser(t, "bob")
+	aliceToken, bobToken := h.Token(t, alice), h.Token(t, bob)
+	photo := model.PhotoCreateReq{Title: "t", Caption: "c", PhotoUrl: "u"}
+
+	if rec := h.Request(t, http.MethodPost, "/photos", photo, aliceToken); rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", rec.Code)
+	}
+	if rec := h.Request(t, http.MethodPost, "/photos", photo, aliceToken); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", rec.Code)
+	}
+	// reads are not counted against the write limit
+	if rec := h.Request(t, http.MethodGet, "/photos?user_id=1", nil, aliceToken); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for read, got %d", rec.Code)
+	}
+	if rec := h.Request(t, http.MethodPost, "/photos", photo, bobToken); rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201 for another user, got %d", rec.Code)
+	}
+}
+
+func TestForwardedForIsOnlyBelievedFromTrustedProxies(t *testing.T) {
+	limits := apitest.WithRateLimits(ratelimit.Config{
+		ratelimit.GroupPublic: {IP: ratelimit.PerMinute(2)},
+	})
+
+	publicToken := func(h *apitest.Harness, forwardedFor string) int {
+		header := http.Header{}
+		header.Set("X-Forwarded-For", forwardedFor)
+		return h.RequestWithHeaders(t, http.MethodGet, "/public", nil, header).Code
+	}
+
+	// a new address per request does not reset the bucket of the client
+	h := apitest.New(limits)
+	for i := 0; i < 2; i++ {
+		if code := publicToken(h, fmt.Sprintf("203.0.113.%d", i)); code != http.StatusOK {
+			t.Fatalf("attempt %d: expected 200, got %d", i+1, code)
+		}
+	}
+	if code := publicToken(h, "203.0.113.99"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected a spoofed X-Forwarded-For to be limited, got %d", code)
+	}
+
+	// behind a trusted proxy every client has its own bucket
+	h = apitest.New(limits, apitest.WithTrustedProxies("192.0.2.1"))
+	for i := 0; i < 3; i++ {
+		if code := publicToken(h, fmt.Sprintf("203.0.113.%d", i)); code != http.StatusOK {
+			t.Fatalf("client %d: expected 200, got %d", i+1, code)
+		}
+	}
+}
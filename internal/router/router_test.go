@@ -0,0 +1,540 @@
+package router_test
+
+import (
+	"context"
+	"mygram/internal/apitest"
+	"mygram/internal/app"
+	"mygram/internal/model"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type fixture struct {
+	h          *apitest.Harness
+	aliceToken string
+	bobToken   string
+	guestToken string
+}
+
+// newFixture creates alice (id 1) with photo 1, comment 1 and social media 1,
+// and bob (id 2) with nothing.
+func newFixture(t *testing.T) fixture {
+	t.Helper()
+
+	h := apitest.New()
+	alice := h.CreateUser(t, "alice")
+	bob := h.CreateUser(t, "bob")
+
+	ctx := context.Background()
+	photo, err := h.Services.Photo.CreatePhoto(ctx, model.Photo{Title: "sunset", Caption: "at the beach", PhotoUrl: "https://img.example.com/1.jpg", UserId: alice.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.Services.Comment.CreateComment(ctx, model.Comment{Message: "nice", PhotoId: photo.ID, UserId: alice.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.Services.SocialMedia.CreateSocialMedia(ctx, model.SocialMedia{Name: "instagram", SocialMediaUrl: "https://instagram.com/alice", UserId: alice.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	return fixture{
+		h:          h,
+		aliceToken: h.Token(t, alice),
+		bobToken:   h.Token(t, bob),
+		guestToken: h.PublicToken(t),
+	}
+}
+
+type routeCase struct {
+	name   string
+	route  apitest.Route
+	path   string
+	body   any
+	token  func(f fixture) string
+	status int
+}
+
+func alice(f fixture) string {
+	return f.aliceToken
+}
+
+func bob(f fixture) string {
+	return f.bobToken
+}
+
+func guest(f fixture) string {
+	return f.guestToken
+}
+
+func anonymous(fixture) string {
+	return ""
+}
+
+var (
+	publicToken  = apitest.Route{Method: http.MethodGet, Path: "/public"}
+	register     = apitest.Route{Method: http.MethodPost, Path: "/users/register"}
+	login        = apitest.Route{Method: http.MethodPost, Path: "/users/login"}
+	getUser      = apitest.Route{Method: http.MethodGet, Path: "/users/:id"}
+	editUser     = apitest.Route{Method: http.MethodPut, Path: "/users/:id"}
+	patchUser    = apitest.Route{Method: http.MethodPatch, Path: "/users/:id"}
+	deleteUser   = apitest.Route{Method: http.MethodDelete, Path: "/users/:id"}
+	verifyEmail  = apitest.Route{Method: http.MethodPost, Path: "/users/verify-email"}
+	resendEmail  = apitest.Route{Method: http.MethodPost, Path: "/users/verify-email/resend"}
+	forgotPass   = apitest.Route{Method: http.MethodPost, Path: "/users/password/forgot"}
+	resetPass    = apitest.Route{Method: http.MethodPost, Path: "/users/password/reset"}
+	loginMFA     = apitest.Route{Method: http.MethodPost, Path: "/users/login/mfa"}
+	oauthLogin   = apitest.Route{Method: http.MethodGet, Path: "/users/oauth/:provider/login"}
+	oauthCb      = apitest.Route{Method: http.MethodGet, Path: "/users/oauth/:provider/callback"}
+	enroll2FA    = apitest.Route{Method: http.MethodPost, Path: "/users/2fa/enroll"}
+	confirm2FA   = apitest.Route{Method: http.MethodPost, Path: "/users/2fa/confirm"}
+	disable2FA   = apitest.Route{Method: http.MethodPost, Path: "/users/2fa/disable"}
+	createToken  = apitest.Route{Method: http.MethodPost, Path: "/users/tokens"}
+	listTokens   = apitest.Route{Method: http.MethodGet, Path: "/users/tokens"}
+	revokeToken  = apitest.Route{Method: http.MethodDelete, Path: "/users/tokens/:tokenId"}
+	listSessions = apitest.Route{Method: http.MethodGet, Path: "/users/me/sessions"}
+	revokeOthers = apitest.Route{Method: http.MethodDelete, Path: "/users/me/sessions"}
+	revokeSess   = apitest.Route{Method: http.MethodDelete, Path: "/users/me/sessions/:sessionId"}
+	loginHistory = apitest.Route{Method: http.MethodGet, Path: "/users/me/login-history"}
+	setPrivacy   = apitest.Route{Method: http.MethodPut, Path: "/users/me/privacy"}
+	follow       = apitest.Route{Method: http.MethodPost, Path: "/users/:id/follow"}
+	unfollow     = apitest.Route{Method: http.MethodDelete, Path: "/users/:id/follow"}
+	followReqs   = apitest.Route{Method: http.MethodGet, Path: "/users/me/follow-requests"}
+	approveReq   = apitest.Route{Method: http.MethodPost, Path: "/users/me/follow-requests/:followerId/approve"}
+	denyReq      = apitest.Route{Method: http.MethodDelete, Path: "/users/me/follow-requests/:followerId"}
+	block        = apitest.Route{Method: http.MethodPost, Path: "/users/:id/block"}
+	unblock      = apitest.Route{Method: http.MethodDelete, Path: "/users/:id/block"}
+	listBlocks   = apitest.Route{Method: http.MethodGet, Path: "/users/me/blocks"}
+	mute         = apitest.Route{Method: http.MethodPost, Path: "/users/:id/mute"}
+	unmute       = apitest.Route{Method: http.MethodDelete, Path: "/users/:id/mute"}
+	listMutes    = apitest.Route{Method: http.MethodGet, Path: "/users/me/mutes"}
+	createPhoto  = apitest.Route{Method: http.MethodPost, Path: "/photos"}
+	listPhotos   = apitest.Route{Method: http.MethodGet, Path: "/photos"}
+	getPhoto     = apitest.Route{Method: http.MethodGet, Path: "/photos/:id"}
+	editPhoto    = apitest.Route{Method: http.MethodPut, Path: "/photos/:id"}
+	patchPhoto   = apitest.Route{Method: http.MethodPatch, Path: "/photos/:id"}
+	deletePhoto  = apitest.Route{Method: http.MethodDelete, Path: "/photos/:id"}
+	createCmt    = apitest.Route{Method: http.MethodPost, Path: "/comments"}
+	listCmts     = apitest.Route{Method: http.MethodGet, Path: "/comments"}
+	getCmt       = apitest.Route{Method: http.MethodGet, Path: "/comments/:id"}
+	editCmt      = apitest.Route{Method: http.MethodPut, Path: "/comments/:id"}
+	patchCmt     = apitest.Route{Method: http.MethodPatch, Path: "/comments/:id"}
+	deleteCmt    = apitest.Route{Method: http.MethodDelete, Path: "/comments/:id"}
+	createSocial = apitest.Route{Method: http.MethodPost, Path: "/social-medias"}
+	listSocials  = apitest.Route{Method: http.MethodGet, Path: "/social-medias"}
+	getSocial    = apitest.Route{Method: http.MethodGet, Path: "/social-medias/:id"}
+	editSocial   = apitest.Route{Method: http.MethodPut, Path: "/social-medias/:id"}
+	patchSocial  = apitest.Route{Method: http.MethodPatch, Path: "/social-medias/:id"}
+	deleteSocial = apitest.Route{Method: http.MethodDelete, Path: "/social-medias/:id"}
+	search       = apitest.Route{Method: http.MethodGet, Path: "/search"}
+	explore      = apitest.Route{Method: http.MethodGet, Path: "/explore"}
+	trendingTags = apitest.Route{Method: http.MethodGet, Path: "/explore/tags"}
+	exploreTag   = apitest.Route{Method: http.MethodGet, Path: "/explore/tags/:tag"}
+	openAPI      = apitest.Route{Method: http.MethodGet, Path: "/openapi.json"}
+	graphQLGet   = apitest.Route{Method: http.MethodGet, Path: "/graphql"}
+	graphQLPost  = apitest.Route{Method: http.MethodPost, Path: "/graphql"}
+	graphQLSDL   = apitest.Route{Method: http.MethodGet, Path: "/graphql/schema.graphql"}
+)
+
+// protected lists every route behind CheckAuthBearer with a concrete path.
+var protected = []routeCase{
+	{route: getUser, path: "/users/1"},
+	{route: editUser, path: "/users/1"},
+	{route: patchUser, path: "/users/1"},
+	{route: deleteUser, path: "/users/1"},
+	{route: enroll2FA, path: "/users/2fa/enroll"},
+	{route: confirm2FA, path: "/users/2fa/confirm"},
+	{route: disable2FA, path: "/users/2fa/disable"},
+	{route: createToken, path: "/users/tokens"},
+	{route: listTokens, path: "/users/tokens"},
+	{route: revokeToken, path: "/users/tokens/1"},
+	{route: listSessions, path: "/users/me/sessions"},
+	{route: revokeOthers, path: "/users/me/sessions"},
+	{route: revokeSess, path: "/users/me/sessions/1"},
+	{route: loginHistory, path: "/users/me/login-history"},
+	{route: setPrivacy, path: "/users/me/privacy"},
+	{route: follow, path: "/users/2/follow"},
+	{route: unfollow, path: "/users/2/follow"},
+	{route: followReqs, path: "/users/me/follow-requests"},
+	{route: approveReq, path: "/users/me/follow-requests/2/approve"},
+	{route: denyReq, path: "/users/me/follow-requests/2"},
+	{route: block, path: "/users/2/block"},
+	{route: unblock, path: "/users/2/block"},
+	{route: listBlocks, path: "/users/me/blocks"},
+	{route: mute, path: "/users/2/mute"},
+	{route: unmute, path: "/users/2/mute"},
+	{route: listMutes, path: "/users/me/mutes"},
+	{route: createPhoto, path: "/photos"},
+	{route: listPhotos, path: "/photos?user_id=1"},
+	{route: getPhoto, path: "/photos/1"},
+	{route: search, path: "/search?q=sunset"},
+	{route: explore, path: "/explore"},
+	{route: trendingTags, path: "/explore/tags"},
+	{route: exploreTag, path: "/explore/tags/sunset"},
+	{route: editPhoto, path: "/photos/1"},
+	{route: patchPhoto, path: "/photos/1"},
+	{route: deletePhoto, path: "/photos/1"},
+	{route: createCmt, path: "/comments"},
+	{route: listCmts, path: "/comments?photo_id=1"},
+	{route: getCmt, path: "/comments/1"},
+	{route: editCmt, path: "/comments/1"},
+	{route: patchCmt, path: "/comments/1"},
+	{route: deleteCmt, path: "/comments/1"},
+	{route: createSocial, path: "/social-medias"},
+	{route: listSocials, path: "/social-medias?user_id=1"},
+	{route: getSocial, path: "/social-medias/1"},
+	{route: editSocial, path: "/social-medias/1"},
+	{route: patchSocial, path: "/social-medias/1"},
+	{route: deleteSocial, path: "/social-medias/1"},
+}
+
+var cases = []routeCase{
+	// public
+	{name: "public token", route: publicToken, path: "/public", token: anonymous, status: http.StatusOK},
+
+	// users
+	{name: "register", route: register, path: "/users/register", token: anonymous, status: http.StatusCreated,
+		body: model.UserSignUp{Username: "carol", Email: "carol@example.com", Password: "harbor-sunset-42", DoB: "2000-01-01"}},
+	{name: "register invalid email", route: register, path: "/users/register", token: anonymous, status: http.StatusBadRequest,
+		body: model.UserSignUp{Username: "carol", Email: "carol", Password: "harbor-sunset-42", DoB: "2000-01-01"}},
+	{name: "register breached password", route: register, path: "/users/register", token: anonymous, status: http.StatusBadRequest,
+		body: model.UserSignUp{Username: "carol", Email: "carol@example.com", Password: "Password123", DoB: "2000-01-01"}},
+	{name: "register guessable password", route: register, path: "/users/register", token: anonymous, status: http.StatusBadRequest,
+		body: model.UserSignUp{Username: "carol", Email: "carol@example.com", Password: "abcdefghij", DoB: "2000-01-01"}},
+	{name: "register too young", route: register, path: "/users/register", token: anonymous, status: http.StatusBadRequest,
+		body: model.UserSignUp{Username: "carol", Email: "carol@example.com", Password: "harbor-sunset-42", DoB: "2024-01-01"}},
+	{name: "login", route: login, path: "/users/login", token: anonymous, status: http.StatusOK,
+		body: model.UserSignIn{Email: "alice@example.com", Password: apitest.DefaultPassword}},
+	{name: "login wrong password", route: login, path: "/users/login", token: anonymous, status: http.StatusUnauthorized,
+		body: model.UserSignIn{Email: "alice@example.com", Password: "wrong-password"}},
+	{name: "login unknown email", route: login, path: "/users/login", token: anonymous, status: http.StatusUnauthorized,
+		body: model.UserSignIn{Email: "nobody@example.com", Password: apitest.DefaultPassword}},
+	{name: "login missing password", route: login, path: "/users/login", token: anonymous, status: http.StatusBadRequest,
+		body: model.UserSignIn{Email: "alice@example.com"}},
+	{name: "get user", route: getUser, path: "/users/2", token: alice, status: http.StatusOK},
+	{name: "get unknown user", route: getUser, path: "/users/99", token: alice, status: http.StatusNotFound},
+	{name: "get user invalid id", route: getUser, path: "/users/abc", token: alice, status: http.StatusBadRequest},
+	{name: "get user as guest", route: getUser, path: "/users/1", token: guest, status: http.StatusOK},
+	{name: "edit own user", route: editUser, path: "/users/1", token: alice, status: http.StatusOK,
+		body: model.UserEditReq{Email: "alice2@example.com", Username: "alice2"}},
+	{name: "edit other user", route: editUser, path: "/users/1", token: bob, status: http.StatusUnauthorized,
+		body: model.UserEditReq{Email: "alice2@example.com", Username: "alice2"}},
+	{name: "edit user invalid body", route: editUser, path: "/users/1", token: alice, status: http.StatusBadRequest,
+		body: model.UserEditReq{Email: "not-an-email", Username: "alice2"}},
+	{name: "patch own user", route: patchUser, path: "/users/1", token: alice, status: http.StatusOK,
+		body: `{"username": "alice2"}`},
+	{name: "patch user invalid email", route: patchUser, path: "/users/1", token: alice, status: http.StatusBadRequest,
+		body: `{"email": "not-an-email"}`},
+	{name: "patch user removing username", route: patchUser, path: "/users/1", token: alice, status: http.StatusBadRequest,
+		body: `{"username": null}`},
+	{name: "patch other user", route: patchUser, path: "/users/1", token: bob, status: http.StatusUnauthorized,
+		body: `{"username": "alice2"}`},
+	{name: "delete own user", route: deleteUser, path: "/users/1", token: alice, status: http.StatusOK},
+	{name: "delete other user", route: deleteUser, path: "/users/1", token: bob, status: http.StatusUnauthorized},
+	{name: "edit user as guest", route: editUser, path: "/users/1", token: guest, status: http.StatusUnauthorized,
+		body: model.UserEditReq{Email: "alice2@example.com", Username: "alice2"}},
+	{name: "verify email unknown token", route: verifyEmail, path: "/users/verify-email", token: anonymous, status: http.StatusBadRequest,
+		body: model.EmailVerifyReq{Token: "not-a-token"}},
+	{name: "verify email missing token", route: verifyEmail, path: "/users/verify-email", token: anonymous, status: http.StatusBadRequest,
+		body: model.EmailVerifyReq{}},
+	{name: "resend verification", route: resendEmail, path: "/users/verify-email/resend", token: anonymous, status: http.StatusAccepted,
+		body: model.EmailReq{Email: "alice@example.com"}},
+	{name: "resend verification unknown email", route: resendEmail, path: "/users/verify-email/resend", token: anonymous, status: http.StatusAccepted,
+		body: model.EmailReq{Email: "nobody@example.com"}},
+	{name: "resend verification invalid email", route: resendEmail, path: "/users/verify-email/resend", token: anonymous, status: http.StatusBadRequest,
+		body: model.EmailReq{Email: "alice"}},
+	{name: "forgot password", route: forgotPass, path: "/users/password/forgot", token: anonymous, status: http.StatusAccepted,
+		body: model.EmailReq{Email: "alice@example.com"}},
+	{name: "forgot password unknown email", route: forgotPass, path: "/users/password/forgot", token: anonymous, status: http.StatusAccepted,
+		body: model.EmailReq{Email: "nobody@example.com"}},
+	{name: "forgot password invalid email", route: forgotPass, path: "/users/password/forgot", token: anonymous, status: http.StatusBadRequest,
+		body: model.EmailReq{}},
+	{name: "reset password unknown token", route: resetPass, path: "/users/password/reset", token: anonymous, status: http.StatusBadRequest,
+		body: model.PasswordResetReq{Token: "not-a-token", Password: "new-secret"}},
+	{name: "reset password too short", route: resetPass, path: "/users/password/reset", token: anonymous, status: http.StatusBadRequest,
+		body: model.PasswordResetReq{Token: "not-a-token", Password: "abc"}},
+	{name: "login mfa bad pending token", route: loginMFA, path: "/users/login/mfa", token: anonymous, status: http.StatusUnauthorized,
+		body: model.TwoFactorLoginReq{MFAPendingToken: "not-a-jwt", Code: "123456"}},
+	{name: "login mfa missing code", route: loginMFA, path: "/users/login/mfa", token: anonymous, status: http.StatusBadRequest,
+		body: model.TwoFactorLoginReq{MFAPendingToken: "not-a-jwt"}},
+	{name: "oauth login unknown provider", route: oauthLogin, path: "/users/oauth/nope/login", token: anonymous, status: http.StatusNotFound},
+	{name: "oauth callback unknown provider", route: oauthCb, path: "/users/oauth/nope/callback?code=abc&state=xyz", token: anonymous, status: http.StatusNotFound},
+	{name: "oauth callback missing code", route: oauthCb, path: "/users/oauth/nope/callback?state=xyz", token: anonymous, status: http.StatusBadRequest},
+	{name: "oauth callback provider error", route: oauthCb, path: "/users/oauth/nope/callback?error=access_denied", token: anonymous, status: http.StatusBadRequest},
+	{name: "enroll 2fa", route: enroll2FA, path: "/users/2fa/enroll", token: alice, status: http.StatusOK},
+	{name: "confirm 2fa not enrolled", route: confirm2FA, path: "/users/2fa/confirm", token: alice, status: http.StatusBadRequest,
+		body: model.TwoFactorConfirmReq{Code: "123456"}},
+	{name: "disable 2fa not enabled", route: disable2FA, path: "/users/2fa/disable", token: alice, status: http.StatusBadRequest,
+		body: model.TwoFactorDisableReq{Password: apitest.DefaultPassword, Code: "123456"}},
+	{name: "create token", route: createToken, path: "/users/tokens", token: alice, status: http.StatusCreated,
+		body: model.PersonalAccessTokenCreateReq{Name: "backup script", Scopes: []string{model.SCOPE_PHOTOS_READ}}},
+	{name: "create token unknown scope", route: createToken, path: "/users/tokens", token: alice, status: http.StatusBadRequest,
+		body: model.PersonalAccessTokenCreateReq{Name: "backup script", Scopes: []string{"photos:admin"}}},
+	{name: "create token without scopes", route: createToken, path: "/users/tokens", token: alice, status: http.StatusBadRequest,
+		body: model.PersonalAccessTokenCreateReq{Name: "backup script"}},
+	{name: "list tokens", route: listTokens, path: "/users/tokens", token: alice, status: http.StatusOK},
+	{name: "revoke unknown token", route: revokeToken, path: "/users/tokens/99", token: alice, status: http.StatusNotFound},
+	{name: "revoke token invalid id", route: revokeToken, path: "/users/tokens/abc", token: alice, status: http.StatusBadRequest},
+	{name: "list sessions", route: listSessions, path: "/users/me/sessions", token: alice, status: http.StatusOK},
+	{name: "revoke other sessions", route: revokeOthers, path: "/users/me/sessions", token: alice, status: http.StatusOK},
+	{name: "revoke other user's session", route: revokeSess, path: "/users/me/sessions/1", token: bob, status: http.StatusNotFound},
+	{name: "revoke unknown session", route: revokeSess, path: "/users/me/sessions/99", token: alice, status: http.StatusNotFound},
+	{name: "revoke session invalid id", route: revokeSess, path: "/users/me/sessions/abc", token: alice, status: http.StatusBadRequest},
+	{name: "login history", route: loginHistory, path: "/users/me/login-history", token: alice, status: http.StatusOK},
+
+	// follows
+	{name: "go private", route: setPrivacy, path: "/users/me/privacy", token: alice, status: http.StatusOK,
+		body: map[string]any{"private": true}},
+	{name: "set privacy invalid", route: setPrivacy, path: "/users/me/privacy", token: alice, status: http.StatusBadRequest,
+		body: map[string]any{}},
+	{name: "set privacy as guest", route: setPrivacy, path: "/users/me/privacy", token: guest, status: http.StatusUnauthorized},
+	{name: "follow", route: follow, path: "/users/1/follow", token: bob, status: http.StatusOK},
+	{name: "follow self", route: follow, path: "/users/2/follow", token: bob, status: http.StatusBadRequest},
+	{name: "follow unknown user", route: follow, path: "/users/99/follow", token: bob, status: http.StatusNotFound},
+	{name: "follow invalid id", route: follow, path: "/users/abc/follow", token: bob, status: http.StatusBadRequest},
+	{name: "unfollow not followed", route: unfollow, path: "/users/1/follow", token: bob, status: http.StatusNotFound},
+	{name: "list follow requests", route: followReqs, path: "/users/me/follow-requests", token: alice, status: http.StatusOK},
+	{name: "approve unknown request", route: approveReq, path: "/users/me/follow-requests/2/approve", token: alice, status: http.StatusNotFound},
+	{name: "approve invalid id", route: approveReq, path: "/users/me/follow-requests/abc/approve", token: alice, status: http.StatusBadRequest},
+	{name: "deny unknown request", route: denyReq, path: "/users/me/follow-requests/2", token: alice, status: http.StatusNotFound},
+
+	// blocks and mutes
+	{name: "block", route: block, path: "/users/2/block", token: alice, status: http.StatusOK},
+	{name: "block self", route: block, path: "/users/1/block", token: alice, status: http.StatusBadRequest},
+	{name: "block unknown user", route: block, path: "/users/99/block", token: alice, status: http.StatusNotFound},
+	{name: "block invalid id", route: block, path: "/users/abc/block", token: alice, status: http.StatusBadRequest},
+	{name: "unblock not blocked", route: unblock, path: "/users/2/block", token: alice, status: http.StatusNotFound},
+	{name: "list blocks", route: listBlocks, path: "/users/me/blocks", token: alice, status: http.StatusOK},
+	{name: "mute", route: mute, path: "/users/2/mute", token: alice, status: http.StatusOK},
+	{name: "mute self", route: mute, path: "/users/1/mute", token: alice, status: http.StatusBadRequest},
+	{name: "mute unknown user", route: mute, path: "/users/99/mute", token: alice, status: http.StatusNotFound},
+	{name: "unmute not muted", route: unmute, path: "/users/2/mute", token: alice, status: http.StatusNotFound},
+	{name: "list mutes", route: listMutes, path: "/users/me/mutes", token: alice, status: http.StatusOK},
+	{name: "block as guest", route: block, path: "/users/2/block", token: guest, status: http.StatusUnauthorized},
+
+	// photos
+	{name: "create photo", route: createPhoto, path: "/photos", token: bob, status: http.StatusCreated,
+		body: model.PhotoCreateReq{Title: "t", Caption: "c", PhotoUrl: "https://img.example.com/2.jpg"}},
+	{name: "create photo invalid", route: createPhoto, path: "/photos", token: bob, status: http.StatusBadRequest,
+		body: model.PhotoCreateReq{Caption: "c"}},
+	{name: "list photos", route: listPhotos, path: "/photos?user_id=1", token: bob, status: http.StatusOK},
+	{name: "list photos as guest", route: listPhotos, path: "/photos?user_id=1", token: guest, status: http.StatusOK},
+	{name: "create photo as guest", route: createPhoto, path: "/photos", token: guest, status: http.StatusForbidden,
+		body: model.PhotoCreateReq{Title: "t", Caption: "c", PhotoUrl: "https://img.example.com/2.jpg"}},
+	{name: "delete photo as guest", route: deletePhoto, path: "/photos/1", token: guest, status: http.StatusForbidden},
+	{name: "list photos empty", route: listPhotos, path: "/photos?user_id=2", token: bob, status: http.StatusNotFound},
+	{name: "list photos missing user", route: listPhotos, path: "/photos", token: bob, status: http.StatusBadRequest},
+	{name: "list photos expanded", route: listPhotos, path: "/photos?user_id=1&expand=user,comments&fields=title", token: bob, status: http.StatusOK},
+	{name: "list photos unknown expand", route: listPhotos, path: "/photos?user_id=1&expand=photo", token: bob, status: http.StatusBadRequest},
+	{name: "get photo", route: getPhoto, path: "/photos/1", token: bob, status: http.StatusOK},
+	{name: "get photo as guest", route: getPhoto, path: "/photos/1?expand=comments", token: guest, status: http.StatusOK},
+	{name: "get unknown photo", route: getPhoto, path: "/photos/99", token: bob, status: http.StatusNotFound},
+	{name: "get photo invalid id", route: getPhoto, path: "/photos/abc", token: bob, status: http.StatusBadRequest},
+	{name: "get photo unknown field", route: getPhoto, path: "/photos/1?fields=title,likes", token: bob, status: http.StatusBadRequest},
+	{name: "edit own photo", route: editPhoto, path: "/photos/1", token: alice, status: http.StatusOK,
+		body: model.PhotoUpdateReq{Title: "t2", Caption: "c2", PhotoUrl: "https://img.example.com/1b.jpg"}},
+	{name: "edit other photo", route: editPhoto, path: "/photos/1", token: bob, status: http.StatusUnauthorized,
+		body: model.PhotoUpdateReq{Title: "t2", Caption: "c2", PhotoUrl: "https://img.example.com/1b.jpg"}},
+	{name: "edit unknown photo", route: editPhoto, path: "/photos/99", token: alice, status: http.StatusNotFound,
+		body: model.PhotoUpdateReq{Title: "t2", Caption: "c2", PhotoUrl: "https://img.example.com/1b.jpg"}},
+	{name: "patch own photo", route: patchPhoto, path: "/photos/1", token: alice, status: http.StatusOK,
+		body: `{"caption": "at the lake"}`},
+	{name: "patch photo unknown member", route: patchPhoto, path: "/photos/1", token: alice, status: http.StatusBadRequest,
+		body: `{"likes": 10}`},
+	{name: "patch photo not an object", route: patchPhoto, path: "/photos/1", token: alice, status: http.StatusBadRequest,
+		body: `["caption"]`},
+	{name: "patch other photo", route: patchPhoto, path: "/photos/1", token: bob, status: http.StatusUnauthorized,
+		body: `{"caption": "at the lake"}`},
+	{name: "patch unknown photo", route: patchPhoto, path: "/photos/99", token: alice, status: http.StatusNotFound,
+		body: `{"caption": "at the lake"}`},
+	{name: "patch photo as guest", route: patchPhoto, path: "/photos/1", token: guest, status: http.StatusForbidden,
+		body: `{"caption": "at the lake"}`},
+	{name: "delete own photo", route: deletePhoto, path: "/photos/1", token: alice, status: http.StatusOK},
+	{name: "delete other photo", route: deletePhoto, path: "/photos/1", token: bob, status: http.StatusUnauthorized},
+	{name: "delete unknown photo", route: deletePhoto, path: "/photos/99", token: alice, status: http.StatusNotFound},
+
+	// comments
+	{name: "create comment", route: createCmt, path: "/comments", token: bob, status: http.StatusCreated,
+		body: model.CommentCreateReq{Message: "wow", PhotoId: 1}},
+	{name: "create comment unknown photo", route: createCmt, path: "/comments", token: bob, status: http.StatusNotFound,
+		body: model.CommentCreateReq{Message: "wow", PhotoId: 99}},
+	{name: "create comment invalid", route: createCmt, path: "/comments", token: bob, status: http.StatusBadRequest,
+		body: model.CommentCreateReq{PhotoId: 1}},
+	{name: "list comments", route: listCmts, path: "/comments?photo_id=1", token: bob, status: http.StatusOK},
+	{name: "list comments as guest", route: listCmts, path: "/comments?photo_id=1", token: guest, status: http.StatusOK},
+	{name: "create comment as guest", route: createCmt, path: "/comments", token: guest, status: http.StatusForbidden,
+		body: model.CommentCreateReq{Message: "wow", PhotoId: 1}},
+	{name: "list comments empty", route: listCmts, path: "/comments?photo_id=99", token: bob, status: http.StatusNotFound},
+	{name: "list comments missing photo", route: listCmts, path: "/comments", token: bob, status: http.StatusBadRequest},
+	{name: "list comments unknown expand", route: listCmts, path: "/comments?photo_id=1&expand=comments", token: bob, status: http.StatusBadRequest},
+	{name: "get comment", route: getCmt, path: "/comments/1?expand=photo&fields=message", token: bob, status: http.StatusOK},
+	{name: "get comment as guest", route: getCmt, path: "/comments/1", token: guest, status: http.StatusOK},
+	{name: "get unknown comment", route: getCmt, path: "/comments/99", token: bob, status: http.StatusNotFound},
+	{name: "get comment invalid id", route: getCmt, path: "/comments/0", token: bob, status: http.StatusBadRequest},
+	{name: "edit own comment", route: editCmt, path: "/comments/1", token: alice, status: http.StatusOK,
+		body: model.CommentUpdateReq{Message: "nicer"}},
+	{name: "edit other comment", route: editCmt, path: "/comments/1", token: bob, status: http.StatusUnauthorized,
+		body: model.CommentUpdateReq{Message: "nicer"}},
+	{name: "edit unknown comment", route: editCmt, path: "/comments/99", token: alice, status: http.StatusNotFound,
+		body: model.CommentUpdateReq{Message: "nicer"}},
+	{name: "patch own comment", route: patchCmt, path: "/comments/1", token: alice, status: http.StatusOK,
+		body: `{"message": "nicer"}`},
+	{name: "patch comment empty message", route: patchCmt, path: "/comments/1", token: alice, status: http.StatusBadRequest,
+		body: `{"message": ""}`},
+	{name: "patch other comment", route: patchCmt, path: "/comments/1", token: bob, status: http.StatusUnauthorized,
+		body: `{"message": "nicer"}`},
+	{name: "delete own comment", route: deleteCmt, path: "/comments/1", token: alice, status: http.StatusOK},
+	{name: "delete other comment", route: deleteCmt, path: "/comments/1", token: bob, status: http.StatusUnauthorized},
+	{name: "delete unknown comment", route: deleteCmt, path: "/comments/99", token: alice, status: http.StatusNotFound},
+
+	// social medias
+	{name: "create social media", route: createSocial, path: "/social-medias", token: bob, status: http.StatusCreated,
+		body: model.SocialMediaReq{Name: "twitter", SocialMediaUrl: "https://twitter.com/bob"}},
+	{name: "create social media invalid", route: createSocial, path: "/social-medias", token: bob, status: http.StatusBadRequest,
+		body: model.SocialMediaReq{Name: "twitter"}},
+	{name: "list social medias", route: listSocials, path: "/social-medias?user_id=1", token: bob, status: http.StatusOK},
+	{name: "list social medias as guest", route: listSocials, path: "/social-medias?user_id=1", token: guest, status: http.StatusOK},
+	{name: "edit social media as guest", route: editSocial, path: "/social-medias/1", token: guest, status: http.StatusForbidden,
+		body: model.SocialMediaReq{Name: "ig", SocialMediaUrl: "https://instagram.com/alice2"}},
+	{name: "list social medias empty", route: listSocials, path: "/social-medias?user_id=2", token: bob, status: http.StatusNotFound},
+	{name: "list social medias missing user", route: listSocials, path: "/social-medias", token: bob, status: http.StatusBadRequest},
+	{name: "get social media", route: getSocial, path: "/social-medias/1?expand=", token: bob, status: http.StatusOK},
+	{name: "get social media as guest", route: getSocial, path: "/social-medias/1", token: guest, status: http.StatusOK},
+	{name: "get unknown social media", route: getSocial, path: "/social-medias/99", token: bob, status: http.StatusNotFound},
+	{name: "get social media unknown field", route: getSocial, path: "/social-medias/1?fields=password", token: bob, status: http.StatusBadRequest},
+	{name: "edit own social media", route: editSocial, path: "/social-medias/1", token: alice, status: http.StatusOK,
+		body: model.SocialMediaReq{Name: "ig", SocialMediaUrl: "https://instagram.com/alice2"}},
+	{name: "patch own social media", route: patchSocial, path: "/social-medias/1", token: alice, status: http.StatusOK,
+		body: `{"social_media_url": "https://instagram.com/alice2"}`},
+	{name: "patch unknown social media", route: patchSocial, path: "/social-medias/99", token: alice, status: http.StatusNotFound,
+		body: `{"name": "ig"}`},
+	{name: "edit other social media", route: editSocial, path: "/social-medias/1", token: bob, status: http.StatusUnauthorized,
+		body: model.SocialMediaReq{Name: "ig", SocialMediaUrl: "https://instagram.com/alice2"}},
+	{name: "edit unknown social media", route: editSocial, path: "/social-medias/99", token: alice, status: http.StatusNotFound,
+		body: model.SocialMediaReq{Name: "ig", SocialMediaUrl: "https://instagram.com/alice2"}},
+	{name: "delete own social media", route: deleteSocial, path: "/social-medias/1", token: alice, status: http.StatusOK},
+	{name: "delete other social media", route: deleteSocial, path: "/social-medias/1", token: bob, status: http.StatusUnauthorized},
+	{name: "delete unknown social media", route: deleteSocial, path: "/social-medias/99", token: alice, status: http.StatusNotFound},
+
+	// search
+	{name: "search photos", route: search, path: "/search?q=sunset", token: bob, status: http.StatusOK},
+	{name: "search users", route: search, path: "/search?q=ali&type=users", token: bob, status: http.StatusOK},
+	{name: "search hashtags", route: search, path: "/search?q=beach&type=hashtags", token: bob, status: http.StatusOK},
+	{name: "search as guest", route: search, path: "/search?q=sunset", token: guest, status: http.StatusOK},
+	{name: "search missing query", route: search, path: "/search", token: bob, status: http.StatusBadRequest},
+	{name: "search unknown type", route: search, path: "/search?q=sunset&type=comments", token: bob, status: http.StatusBadRequest},
+	{name: "search limit too high", route: search, path: "/search?q=sunset&limit=51", token: bob, status: http.StatusBadRequest},
+
+	// explore
+	{name: "explore", route: explore, path: "/explore", token: bob, status: http.StatusOK},
+	{name: "explore as guest", route: explore, path: "/explore?limit=10&offset=10", token: guest, status: http.StatusOK},
+	{name: "explore negative offset", route: explore, path: "/explore?offset=-1", token: bob, status: http.StatusBadRequest},
+	{name: "explore limit too high", route: explore, path: "/explore?limit=51", token: bob, status: http.StatusBadRequest},
+	{name: "trending hashtags", route: trendingTags, path: "/explore/tags", token: bob, status: http.StatusOK},
+	{name: "explore hashtag", route: exploreTag, path: "/explore/tags/sunset", token: guest, status: http.StatusOK},
+
+	// openapi
+	{name: "openapi document", route: openAPI, path: "/openapi.json", token: anonymous, status: http.StatusOK},
+
+	// graphql, scopes are checked per field so it is not in protected
+	{name: "graphql query", route: graphQLPost, path: "/graphql", token: bob, status: http.StatusOK,
+		body: `{"query": "{ photo(id: 1) { title user { username } } }"}`},
+	{name: "graphql query as guest", route: graphQLPost, path: "/graphql", token: guest, status: http.StatusOK,
+		body: `{"query": "{ photos(userId: 1) { title } }"}`},
+	{name: "graphql query without token", route: graphQLPost, path: "/graphql", token: anonymous, status: http.StatusUnauthorized,
+		body: `{"query": "{ me { id } }"}`},
+	{name: "graphql invalid query", route: graphQLPost, path: "/graphql", token: bob, status: http.StatusBadRequest,
+		body: `{"query": "{ photo(id: 1) { likes } }"}`},
+	{name: "graphql query with get", route: graphQLGet, path: "/graphql?query=%7B%20me%20%7B%20username%20%7D%20%7D", token: alice, status: http.StatusOK},
+	{name: "graphql mutation with get", route: graphQLGet, path: "/graphql?query=mutation%20%7B%20deletePhoto(id%3A%201)%20%7D", token: alice, status: http.StatusBadRequest},
+	{name: "graphql schema", route: graphQLSDL, path: "/graphql/schema.graphql", token: anonymous, status: http.StatusOK},
+}
+
+func TestRoutes(t *testing.T) {
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			f := newFixture(t)
+			rec := f.h.Request(t, tc.route.Method, tc.path, tc.body, tc.token(f))
+			if rec.Code != tc.status {
+				t.Fatalf("%s %s: expected %d, got %d: %s", tc.route.Method, tc.path, tc.status, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestProtectedRoutesRejectMissingOrInvalidAuth(t *testing.T) {
+	headers := map[string]string{
+		"no header":    "",
+		"basic auth":   "Basic Z29sYW5nMDA2YXdlc29tZTpteXNlY3JldHBhc3N3b3Jk",
+		"bad token":    "Bearer not-a-jwt",
+		"empty bearer": "Bearer",
+	}
+
+	for _, tc := range protected {
+		for name, header := range headers {
+			t.Run(tc.route.String()+"/"+name, func(t *testing.T) {
+				t.Parallel()
+				f := newFixture(t)
+				rec := f.h.RequestWithAuthorization(t, tc.route.Method, tc.path, nil, header)
+				if rec.Code != http.StatusUnauthorized {
+					t.Fatalf("expected 401, got %d: %s", rec.Code, rec.Body.String())
+				}
+			})
+		}
+	}
+}
+
+func TestEveryRouteIsCovered(t *testing.T) {
+	covered := map[apitest.Route]bool{}
+	for _, tc := range cases {
+		covered[tc.route] = true
+	}
+	for _, tc := range protected {
+		covered[tc.route] = true
+	}
+
+	// the cases go through the unversioned aliases of /v1
+	for _, r := range apitest.New().Routes() {
+		r.Path = strings.TrimPrefix(r.Path, app.API_V1)
+		if !covered[r] {
+			t.Errorf("route %s has no test case", r)
+		}
+	}
+}
+
+func TestDeletedPhotoDisappearsFromList(t *testing.T) {
+	f := newFixture(t)
+
+	rec := f.h.Request(t, http.MethodDelete, "/photos/1", nil, f.aliceToken)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("delete: %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = f.h.Request(t, http.MethodGet, "/photos?user_id=1", nil, f.bobToken)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 after delete, got %d", rec.Code)
+	}
+}
+
+func TestLoginReturnsUsableToken(t *testing.T) {
+	f := newFixture(t)
+
+	rec := f.h.Request(t, http.MethodPost, "/users/login", model.UserSignIn{Email: "bob@example.com", Password: apitest.DefaultPassword}, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("login: %d %s", rec.Code, rec.Body.String())
+	}
+	res := map[string]string{}
+	apitest.Decode(t, rec, &res)
+
+	rec = f.h.Request(t, http.MethodPost, "/photos", model.PhotoCreateReq{Title: "t", Caption: "c", PhotoUrl: "u"}, res["token"])
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create photo with login token: %d %s", rec.Code, rec.Body.String())
+	}
+
+	created := model.PhotoCreateRes{}
+	apitest.Decode(t, rec, &created)
+	if created.UserId != 2 {
+		t.Fatalf("expected photo owned by bob, got user %d", created.UserId)
+	}
+}
@@ -0,0 +1,32 @@
+package router
+
+import (
+	"mygram/internal/handler"
+	"mygram/internal/middleware"
+	"mygram/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SearchRouter interface {
+	Mount(g *gin.RouterGroup)
+}
+
+type searchRouterImpl struct {
+	handler handler.SearchHandler
+	limiter *middleware.RateLimiter
+	auth    *middleware.Authenticator
+}
+
+func NewSearchRouter(handler handler.SearchHandler, limiter *middleware.RateLimiter, auth *middleware.Authenticator) SearchRouter {
+	return &searchRouterImpl{handler: handler, limiter: limiter, auth: auth}
+}
+
+func (s *searchRouterImpl) Mount(g *gin.RouterGroup) {
+	v := g.Group("/search")
+	v.Use(s.limiter.ByIP(ratelimit.GroupSearch))
+	v.Use(s.auth.CheckAuthBearerOrGuest)
+	v.Use(s.limiter.ByGuest(ratelimit.GroupSearch))
+	// the scope depends on the type searched, the handler checks it
+	v.GET("", s.handler.Search)
+}
@@ -0,0 +1,163 @@
+package router_test
+
+import (
+	"mygram/internal/apitest"
+	"mygram/internal/model"
+	"net/http"
+	"testing"
+)
+
+func postPhoto(t *testing.T, h *apitest.Harness, token string, title string, caption string) model.PhotoCreateRes {
+	t.Helper()
+
+	rec := h.Request(t, http.MethodPost, "/photos", model.PhotoCreateReq{Title: title, Caption: caption, PhotoUrl: "https://img.example.com/p.jpg"}, token)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create photo: %d %s", rec.Code, rec.Body.String())
+	}
+	photo := model.PhotoCreateRes{}
+	apitest.Decode(t, rec, &photo)
+	return photo
+}
+
+func searchPhotos(t *testing.T, h *apitest.Harness, token string, query string) []uint64 {
+	t.Helper()
+
+	rec := h.Request(t, http.MethodGet, "/search?q="+query, nil, token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("search %q: %d %s", query, rec.Code, rec.Body.String())
+	}
+	photos := []model.PhotoGetRes{}
+	apitest.Decode(t, rec, &photos)
+
+	ids := []uint64{}
+	for _, photo := range photos {
+		ids = append(ids, photo.ID)
+	}
+	return ids
+}
+
+func expectIds(t *testing.T, got []uint64, want ...uint64) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestSearchRanksTitleAboveCaption(t *testing.T) {
+	f := newFixture(t)
+	inCaption := postPhoto(t, f.h, f.bobToken, "morning", "sunrise over the volcano")
+	inTitle := postPhoto(t, f.h, f.bobToken, "volcano", "hike with friends")
+
+	expectIds(t, searchPhotos(t, f.h, f.aliceToken, "volcano"), inTitle.ID, inCaption.ID)
+	// the last word is a prefix, for type-ahead
+	expectIds(t, searchPhotos(t, f.h, f.aliceToken, "volc"), inTitle.ID, inCaption.ID)
+	expectIds(t, searchPhotos(t, f.h, f.aliceToken, "hike+volc"), inTitle.ID)
+	expectIds(t, searchPhotos(t, f.h, f.aliceToken, "olcano"))
+}
+
+func TestSearchIgnoresAccents(t *testing.T) {
+	f := newFixture(t)
+	photo := postPhoto(t, f.h, f.bobToken, "Kopi Susu Café", "ngopi di Bandung")
+
+	expectIds(t, searchPhotos(t, f.h, f.aliceToken, "cafe"), photo.ID)
+	expectIds(t, searchPhotos(t, f.h, f.aliceToken, "CAFÉ"), photo.ID)
+}
+
+func TestSearchFollowsPhotoEditsAndDeletes(t *testing.T) {
+	f := newFixture(t)
+	expectIds(t, searchPhotos(t, f.h, f.bobToken, "sunset"), 1)
+
+	rec := f.h.Request(t, http.MethodPut, "/photos/1", model.PhotoUpdateReq{Title: "dawn", Caption: "at the lake", PhotoUrl: "https://img.example.com/1.jpg"}, f.aliceToken)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("edit photo: %d %s", rec.Code, rec.Body.String())
+	}
+	expectIds(t, searchPhotos(t, f.h, f.bobToken, "sunset"))
+	expectIds(t, searchPhotos(t, f.h, f.bobToken, "lake"), 1)
+
+	if rec := f.h.Request(t, http.MethodDelete, "/photos/1", nil, f.aliceToken); rec.Code != http.StatusOK {
+		t.Fatalf("delete photo: %d %s", rec.Code, rec.Body.String())
+	}
+	expectIds(t, searchPhotos(t, f.h, f.bobToken, "lake"))
+}
+
+func TestSearchKeepsToWhatTheViewerMaySee(t *testing.T) {
+	f := newFixture(t)
+	carol := f.h.CreateUser(t, "carol")
+	carolToken := f.h.Token(t, carol)
+	bobs := postPhoto(t, f.h, f.bobToken, "sunset", "from the roof")
+
+	setPrivate(t, f.h, f.aliceToken, true)
+	// equal ranks list the newest first
+	expectIds(t, searchPhotos(t, f.h, carolToken, "sunset"), bobs.ID)
+	expectIds(t, searchPhotos(t, f.h, f.aliceToken, "sunset"), bobs.ID, 1)
+
+	if rec := f.h.Request(t, http.MethodPost, "/users/3/block", nil, f.bobToken); rec.Code != http.StatusOK {
+		t.Fatalf("block: %d %s", rec.Code, rec.Body.String())
+	}
+	expectIds(t, searchPhotos(t, f.h, carolToken, "sunset"))
+
+	rec := f.h.Request(t, http.MethodGet, "/search?q=bo&type=users", nil, carolToken)
+	users := []model.PublicProfile{}
+	apitest.Decode(t, rec, &users)
+	if len(users) != 0 {
+		t.Fatalf("expected bob hidden from carol, got %+v", users)
+	}
+}
+
+func TestSearchUsersByUsernamePrefix(t *testing.T) {
+	f := newFixture(t)
+	f.h.CreateUser(t, "alicia")
+
+	rec := f.h.Request(t, http.MethodGet, "/search?q=ali&type=users", nil, f.guestToken)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("search users: %d %s", rec.Code, rec.Body.String())
+	}
+	users := []model.PublicProfile{}
+	apitest.Decode(t, rec, &users)
+	if len(users) != 2 {
+		t.Fatalf("expected alice and alicia, got %+v", users)
+	}
+
+	rec = f.h.Request(t, http.MethodGet, "/search?q=alice&type=users", nil, f.guestToken)
+	users = []model.PublicProfile{}
+	apitest.Decode(t, rec, &users)
+	if len(users) == 0 || users[0].Username != "alice" {
+		t.Fatalf("expected the exact username first, got %+v", users)
+	}
+}
+
+func TestSearchHashtagsCountsPhotos(t *testing.T) {
+	f := newFixture(t)
+	postPhoto(t, f.h, f.bobToken, "pagi", "#kopi #Kopitiam")
+	postPhoto(t, f.h, f.aliceToken, "siang", "lagi #kopi lagi")
+	postPhoto(t, f.h, f.aliceToken, "malam", "#teh")
+
+	rec := f.h.Request(t, http.MethodGet, "/search?q=%23kop&type=hashtags", nil, f.bobToken)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("search hashtags: %d %s", rec.Code, rec.Body.String())
+	}
+	tags := []model.HashtagRes{}
+	apitest.Decode(t, rec, &tags)
+	want := []model.HashtagRes{{Tag: "kopi", Photos: 2}, {Tag: "kopitiam", Photos: 1}}
+	if len(tags) != len(want) || tags[0] != want[0] || tags[1] != want[1] {
+		t.Fatalf("expected %+v, got %+v", want, tags)
+	}
+}
+
+func TestSearchScopeDependsOnType(t *testing.T) {
+	f := newFixture(t)
+	token := newAccessToken(t, f.h, f.aliceToken, model.SCOPE_PROFILE_READ).Token
+
+	if rec := f.h.Request(t, http.MethodGet, "/search?q=bob&type=users", nil, token); rec.Code != http.StatusOK {
+		t.Fatalf("expected users search with profile:read, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := f.h.Request(t, http.MethodGet, "/search?q=kopi&type=hashtags", nil, token); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected hashtags search to need photos:read, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
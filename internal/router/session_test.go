@@ -0,0 +1,179 @@
+package router_test
+
+import (
+	"fmt"
+	"mygram/internal/apitest"
+	"mygram/internal/model"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const firefox = "Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0"
+
+// loginFrom signs in through the endpoint with the given User-Agent and
+// returns the access token.
+func loginFrom(t *testing.T, h *apitest.Harness, email string, userAgent string) string {
+	t.Helper()
+
+	body := model.UserSignIn{Email: email, Password: apitest.DefaultPassword}
+	rec := h.RequestWithHeaders(t, http.MethodPost, "/users/login", body, http.Header{"User-Agent": {userAgent}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("login: %d %s", rec.Code, rec.Body.String())
+	}
+	res := loginRes{}
+	apitest.Decode(t, rec, &res)
+	return res.Token
+}
+
+func listSessionsOf(t *testing.T, h *apitest.Harness, token string) []model.Session {
+	t.Helper()
+
+	rec := h.Request(t, http.MethodGet, "/users/me/sessions", nil, token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("list sessions: %d %s", rec.Code, rec.Body.String())
+	}
+	sessions := []model.Session{}
+	apitest.Decode(t, rec, &sessions)
+	return sessions
+}
+
+func TestSessionsListDevices(t *testing.T) {
+	f := newFixture(t)
+	browser := loginFrom(t, f.h, "alice@example.com", firefox)
+
+	sessions := listSessionsOf(t, f.h, browser)
+	if len(sessions) != 2 {
+		t.Fatalf("expected 2 sessions, got %+v", sessions)
+	}
+	current := sessions[0]
+	if !current.Current || sessions[1].Current {
+		t.Fatalf("expected only the newest session to be current, got %+v", sessions)
+	}
+	if current.ClientName != "Firefox on Linux" || current.UserAgent != firefox || current.IP == "" {
+		t.Fatalf("unexpected device of current session %+v", current)
+	}
+
+	// bob's sessions are his own
+	if got := listSessionsOf(t, f.h, f.bobToken); len(got) != 1 {
+		t.Fatalf("expected bob to see his one session, got %+v", got)
+	}
+}
+
+func TestRevokedSessionTokenIsRefused(t *testing.T) {
+	f := newFixture(t)
+	browser := loginFrom(t, f.h, "alice@example.com", firefox)
+
+	sessions := listSessionsOf(t, f.h, browser)
+	other := sessions[1]
+	path := fmt.Sprintf("/users/me/sessions/%d", other.ID)
+	if rec := f.h.Request(t, http.MethodDelete, path, nil, f.bobToken); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 revoking someone else's session, got %d", rec.Code)
+	}
+	if rec := f.h.Request(t, http.MethodDelete, path, nil, browser); rec.Code != http.StatusOK {
+		t.Fatalf("revoke: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := f.h.Request(t, http.MethodGet, "/users/2", nil, f.aliceToken); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected token of revoked session to be refused, got %d", rec.Code)
+	}
+	if rec := f.h.Request(t, http.MethodDelete, path, nil, browser); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected revoking twice to 404, got %d", rec.Code)
+	}
+	if got := listSessionsOf(t, f.h, browser); len(got) != 1 {
+		t.Fatalf("expected one session left, got %+v", got)
+	}
+}
+
+func TestRevokeOtherSessions(t *testing.T) {
+	f := newFixture(t)
+	browser := loginFrom(t, f.h, "alice@example.com", firefox)
+	cli := loginFrom(t, f.h, "alice@example.com", "curl/8.4.0")
+
+	rec := f.h.Request(t, http.MethodDelete, "/users/me/sessions", nil, browser)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("revoke others: %d %s", rec.Code, rec.Body.String())
+	}
+	res := map[string]any{}
+	apitest.Decode(t, rec, &res)
+	if res["revoked"] != float64(2) {
+		t.Fatalf("expected 2 sessions revoked, got %v", res["revoked"])
+	}
+
+	for name, token := range map[string]string{"fixture": f.aliceToken, "cli": cli} {
+		if rec := f.h.Request(t, http.MethodGet, "/users/2", nil, token); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("expected %s session to be signed out, got %d", name, rec.Code)
+		}
+	}
+	if rec := f.h.Request(t, http.MethodGet, "/users/2", nil, browser); rec.Code != http.StatusOK {
+		t.Fatalf("expected current session to stay, got %d", rec.Code)
+	}
+	// bob is not signed out by alice
+	if rec := f.h.Request(t, http.MethodGet, "/users/1", nil, f.bobToken); rec.Code != http.StatusOK {
+		t.Fatalf("expected bob to stay signed in, got %d", rec.Code)
+	}
+}
+
+func TestLoginHistoryRecordsAttempts(t *testing.T) {
+	f := newFixture(t)
+
+	wrong := model.UserSignIn{Email: "alice@example.com", Password: "wrong-password"}
+	rec := f.h.RequestWithHeaders(t, http.MethodPost, "/users/login", wrong, http.Header{"User-Agent": {"curl/8.4.0"}})
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong password: %d %s", rec.Code, rec.Body.String())
+	}
+	browser := loginFrom(t, f.h, "alice@example.com", firefox)
+
+	rec = f.h.Request(t, http.MethodGet, "/users/me/login-history", nil, browser)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("login history: %d %s", rec.Code, rec.Body.String())
+	}
+	history := []model.LoginAttempt{}
+	apitest.Decode(t, rec, &history)
+	if len(history) != 3 {
+		t.Fatalf("expected fixture login, failure and login, got %+v", history)
+	}
+
+	latest, failed := history[0], history[1]
+	if !latest.Success || latest.Method != model.LOGIN_METHOD_PASSWORD || latest.ClientName != "Firefox on Linux" {
+		t.Fatalf("unexpected latest attempt %+v", latest)
+	}
+	if failed.Success || failed.FailureReason != "invalid credentials" || failed.ClientName != "curl" {
+		t.Fatalf("unexpected failed attempt %+v", failed)
+	}
+
+	// attempts on other accounts stay out of it
+	rec = f.h.Request(t, http.MethodGet, "/users/me/login-history", nil, f.bobToken)
+	apitest.Decode(t, rec, &history)
+	if len(history) != 1 {
+		t.Fatalf("expected bob's one login, got %+v", history)
+	}
+}
+
+func TestNewDeviceLoginIsNotified(t *testing.T) {
+	f := newFixture(t)
+
+	loginFrom(t, f.h, "alice@example.com", firefox)
+	msgs := f.h.Outbox.Messages("alice@example.com")
+	if len(msgs) != 1 {
+		t.Fatalf("expected one notification, got %d", len(msgs))
+	}
+	if msgs[0].Subject != "New sign-in to your MyGram account" || !strings.Contains(msgs[0].Text, "Firefox on Linux") {
+		t.Fatalf("unexpected notification %q: %s", msgs[0].Subject, msgs[0].Text)
+	}
+
+	// the same device again is not news
+	loginFrom(t, f.h, "alice@example.com", firefox)
+	if got := len(f.h.Outbox.Messages("alice@example.com")); got != 1 {
+		t.Fatalf("expected no notification for a known device, got %d", got-1)
+	}
+}
+
+func TestFirstLoginIsNotNotified(t *testing.T) {
+	h := apitest.New()
+	h.CreateUser(t, "carol")
+
+	loginFrom(t, h, "carol@example.com", firefox)
+	if got := len(h.Outbox.Messages("carol@example.com")); got != 0 {
+		t.Fatalf("expected no notification for the first login, got %d", got)
+	}
+}
@@ -0,0 +1,263 @@
+package router_test
+
+import (
+	"context"
+	"mygram/internal/apitest"
+	"mygram/internal/model"
+	"mygram/internal/sso/ssotest"
+	"mygram/pkg/helper"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const oauthCallbackURL = "http://localhost:3000/users/oauth/stub/callback"
+
+func newSocialLogin(t *testing.T) (*apitest.Harness, *ssotest.Provider) {
+	t.Helper()
+
+	idp := ssotest.NewProvider(t)
+	return apitest.New(apitest.WithSSOProviders(idp.Config("stub", oauthCallbackURL))), idp
+}
+
+type oauthStart struct {
+	cookie string
+	code   string
+	state  string
+}
+
+// startSocialLogin follows /users/oauth/stub/login through the provider's
+// authorization endpoint, as the browser would.
+func startSocialLogin(t *testing.T, h *apitest.Harness, idp *ssotest.Provider) oauthStart {
+	t.Helper()
+
+	rec := h.Request(t, http.MethodGet, "/users/oauth/stub/login", nil, "")
+	if rec.Code != http.StatusFound {
+		t.Fatalf("login: %d %s", rec.Code, rec.Body.String())
+	}
+	authURL := rec.Header().Get("Location")
+	if !strings.HasPrefix(authURL, idp.Issuer()+"/authorize?") {
+		t.Fatalf("unexpected redirect %q", authURL)
+	}
+
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "oauth_state" || !cookies[0].HttpOnly || cookies[0].Path != "/users/oauth/stub" {
+		t.Fatalf("unexpected cookies %v", cookies)
+	}
+
+	code, state := idp.Authorize(t, authURL)
+	return oauthStart{cookie: cookies[0].Value, code: code, state: state}
+}
+
+func finishSocialLogin(t *testing.T, h *apitest.Harness, start oauthStart) *httptest.ResponseRecorder {
+	t.Helper()
+
+	query := url.Values{"code": {start.code}, "state": {start.state}}
+	header := http.Header{}
+	if start.cookie != "" {
+		header.Set("Cookie", "oauth_state="+start.cookie)
+	}
+	return h.RequestWithHeaders(t, http.MethodGet, "/users/oauth/stub/callback?"+query.Encode(), nil, header)
+}
+
+func socialLogin(t *testing.T, h *apitest.Harness, idp *ssotest.Provider) loginRes {
+	t.Helper()
+
+	rec := finishSocialLogin(t, h, startSocialLogin(t, h, idp))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("callback: %d %s", rec.Code, rec.Body.String())
+	}
+	res := loginRes{}
+	apitest.Decode(t, rec, &res)
+	return res
+}
+
+func tokenUserID(t *testing.T, token string) uint64 {
+	t.Helper()
+
+	claims, err := helper.ValidateToken(token, model.SUBJECT_ACCESS_TOKEN)
+	if err != nil {
+		t.Fatalf("validate token: %v", err)
+	}
+	return uint64(claims["user_id"].(float64))
+}
+
+func TestSocialLoginCreatesUser(t *testing.T) {
+	h, idp := newSocialLogin(t)
+	idp.SignInAs(ssotest.Account{Subject: "carol-1", Email: "carol@example.org", EmailVerified: true, PreferredUsername: "Carol"})
+
+	res := socialLogin(t, h, idp)
+	if res.Token == "" {
+		t.Fatal("expected an access token")
+	}
+
+	user, err := h.Repos.User.GetUsersByID(context.Background(), tokenUserID(t, res.Token))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Username != "carol" || user.Email != "carol@example.org" || user.EmailVerifiedAt == nil {
+		t.Fatalf("unexpected user %+v", user)
+	}
+
+	// the email was verified by the provider, so posting works right away
+	rec := h.Request(t, http.MethodPost, "/photos", model.PhotoCreateReq{Title: "t", Caption: "c", PhotoUrl: "u"}, res.Token)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create photo: %d %s", rec.Code, rec.Body.String())
+	}
+
+	// and the next sign in finds the same user
+	if again := socialLogin(t, h, idp); tokenUserID(t, again.Token) != user.ID {
+		t.Fatal("second sign in created another user")
+	}
+}
+
+func TestSocialLoginPicksFreeUsername(t *testing.T) {
+	h, idp := newSocialLogin(t)
+	h.CreateUser(t, "alice")
+	idp.SignInAs(ssotest.Account{Subject: "other-alice", Email: "alice@example.org", EmailVerified: true, PreferredUsername: "alice"})
+
+	res := socialLogin(t, h, idp)
+	user, err := h.Repos.User.GetUsersByID(context.Background(), tokenUserID(t, res.Token))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Username == "alice" || !strings.HasPrefix(user.Username, "alice") {
+		t.Fatalf("unexpected username %q", user.Username)
+	}
+}
+
+func TestSocialLoginLinksVerifiedEmail(t *testing.T) {
+	h, idp := newSocialLogin(t)
+	alice := h.CreateUser(t, "alice")
+	idp.SignInAs(ssotest.Account{Subject: "alice-1", Email: "alice@example.com", EmailVerified: true})
+
+	res := socialLogin(t, h, idp)
+	if tokenUserID(t, res.Token) != alice.ID {
+		t.Fatal("identity was not linked to the existing user")
+	}
+	identity, err := h.Repos.UserIdentity.GetIdentity(context.Background(), "stub", "alice-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.UserId != alice.ID {
+		t.Fatalf("unexpected identity %+v", identity)
+	}
+
+	// the password keeps working
+	if res := signIn(t, h, "alice@example.com", apitest.DefaultPassword); res.Token == "" {
+		t.Fatal("password login failed after linking")
+	}
+}
+
+func TestSocialLoginDoesNotLinkUnprovenEmail(t *testing.T) {
+	tests := map[string]struct {
+		verifiedLocally bool
+		verifiedByIdP   bool
+	}{
+		"local account unverified":  {verifiedLocally: false, verifiedByIdP: true},
+		"provider email unverified": {verifiedLocally: true, verifiedByIdP: false},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			h, idp := newSocialLogin(t)
+			if tc.verifiedLocally {
+				h.CreateUser(t, "alice")
+			} else {
+				h.CreateUnverifiedUser(t, "alice")
+			}
+			idp.SignInAs(ssotest.Account{Subject: "alice-1", Email: "alice@example.com", EmailVerified: tc.verifiedByIdP})
+
+			rec := finishSocialLogin(t, h, startSocialLogin(t, h, idp))
+			if rec.Code != http.StatusConflict {
+				t.Fatalf("expected 409, got %d: %s", rec.Code, rec.Body.String())
+			}
+			identity, err := h.Repos.UserIdentity.GetIdentity(context.Background(), "stub", "alice-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if identity.ID != 0 {
+				t.Fatal("identity was linked")
+			}
+		})
+	}
+}
+
+func TestSocialLoginRequiresSecondFactor(t *testing.T) {
+	h, idp := newSocialLogin(t)
+	alice := h.CreateUser(t, "alice")
+	secret, _ := enableTwoFactor(t, h, h.Token(t, alice))
+	idp.SignInAs(ssotest.Account{Subject: "alice-1", Email: "alice@example.com", EmailVerified: true})
+
+	res := socialLogin(t, h, idp)
+	if !res.MFARequired || res.Token != "" {
+		t.Fatalf("expected a second factor to be required, got %+v", res)
+	}
+
+	rec := h.Request(t, http.MethodPost, "/users/login/mfa", model.TwoFactorLoginReq{MFAPendingToken: res.MFAPendingToken, Code: totpCode(t, secret, 1)}, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("login mfa: %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestSocialLoginCallbackRejectsForgedRequests(t *testing.T) {
+	h, idp := newSocialLogin(t)
+	idp.SignInAs(ssotest.Account{Subject: "carol-1", Email: "carol@example.org", EmailVerified: true})
+	alice := h.CreateUser(t, "alice")
+
+	tests := map[string]struct {
+		tamper func(start *oauthStart)
+		status int
+	}{
+		"missing cookie": {tamper: func(s *oauthStart) { s.cookie = "" }, status: http.StatusBadRequest},
+		"forged cookie":  {tamper: func(s *oauthStart) { s.cookie = "not-a-jwt" }, status: http.StatusBadRequest},
+		"state mismatch": {tamper: func(s *oauthStart) { s.state = "other" }, status: http.StatusBadRequest},
+		"unknown code":   {tamper: func(s *oauthStart) { s.code = "other" }, status: http.StatusUnauthorized},
+		"access token as cookie": {
+			tamper: func(s *oauthStart) { s.cookie = h.Token(t, alice) },
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			start := startSocialLogin(t, h, idp)
+			tc.tamper(&start)
+			rec := finishSocialLogin(t, h, start)
+			if rec.Code != tc.status {
+				t.Fatalf("expected %d, got %d: %s", tc.status, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestSocialLoginCodeIsSingleUse(t *testing.T) {
+	h, idp := newSocialLogin(t)
+	idp.SignInAs(ssotest.Account{Subject: "carol-1", Email: "carol@example.org", EmailVerified: true})
+
+	start := startSocialLogin(t, h, idp)
+	if rec := finishSocialLogin(t, h, start); rec.Code != http.StatusOK {
+		t.Fatalf("callback: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := finishSocialLogin(t, h, start); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected replayed code to be rejected, got %d", rec.Code)
+	}
+}
+
+// a state cookie from one login cannot complete another, which binds the
+// PKCE verifier to the browser that started the login
+func TestSocialLoginStateBelongsToItsLogin(t *testing.T) {
+	h, idp := newSocialLogin(t)
+	idp.SignInAs(ssotest.Account{Subject: "carol-1", Email: "carol@example.org", EmailVerified: true})
+
+	victim := startSocialLogin(t, h, idp)
+	attacker := startSocialLogin(t, h, idp)
+	attacker.cookie = victim.cookie
+
+	rec := finishSocialLogin(t, h, attacker)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
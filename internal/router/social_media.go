@@ -3,29 +3,42 @@ package router
 import (
 	"mygram/internal/handler"
 	"mygram/internal/middleware"
+	"mygram/internal/model"
+	"mygram/internal/ratelimit"
 
 	"github.com/gin-gonic/gin"
 )
 
 type SocialMediaRouter interface {
-	Mount()
+	Mount(g *gin.RouterGroup)
 }
 
 type socialMediaRouterImpl struct {
-	v *gin.RouterGroup
 	handler handler.SocialMediaHandler
+	limiter *middleware.RateLimiter
+	auth *middleware.Authenticator
+	verified gin.HandlerFunc
+	idempotent gin.HandlerFunc
 }
 
-func NewSocialMediaRouter(v *gin.RouterGroup, handler handler.SocialMediaHandler) SocialMediaRouter{
-	return &socialMediaRouterImpl{v:v, handler: handler}
+func NewSocialMediaRouter(handler handler.SocialMediaHandler, limiter *middleware.RateLimiter, auth *middleware.Authenticator, verified gin.HandlerFunc, idempotent gin.HandlerFunc) SocialMediaRouter{
+	return &socialMediaRouterImpl{handler: handler, limiter: limiter, auth: auth, verified: verified, idempotent: idempotent}
 }
 
-func (s *socialMediaRouterImpl) Mount() {
-	s.v.Use(middleware.CheckAuthBearer)
-	s.v.POST("", s.handler.CreateSocialMedia)
-	s.v.GET("",s.handler.GetSocialMediasByUserId)
-	s.v.PUT("/:id",s.handler.EditSocialMedia)
-	s.v.DELETE("/:id", s.handler.DeleteSocialMedia)
+func (s *socialMediaRouterImpl) Mount(g *gin.RouterGroup) {
+	v := g.Group("/social-medias")
+	v.Use(s.limiter.ByIP(ratelimit.GroupSocialMedias))
+	// guests can read, the authenticator refuses their writes
+	v.Use(s.auth.CheckAuthBearerOrGuest)
+	v.Use(s.limiter.ByGuest(ratelimit.GroupSocialMedias))
+	v.Use(s.limiter.ByUser(ratelimit.GroupSocialMedias))
+	v.Use(s.verified)
+	v.POST("", middleware.RequireScope(model.SCOPE_SOCIAL_MEDIAS_WRITE), s.idempotent, s.handler.CreateSocialMedia)
+	v.GET("", middleware.RequireScope(model.SCOPE_SOCIAL_MEDIAS_READ), s.handler.GetSocialMediasByUserId)
+	v.GET("/:id", middleware.RequireScope(model.SCOPE_SOCIAL_MEDIAS_READ), s.handler.GetSocialMediaById)
+	v.PUT("/:id", middleware.RequireScope(model.SCOPE_SOCIAL_MEDIAS_WRITE), s.handler.EditSocialMedia)
+	v.PATCH("/:id", middleware.RequireScope(model.SCOPE_SOCIAL_MEDIAS_WRITE), s.handler.EditSocialMedia)
+	v.DELETE("/:id", middleware.RequireScope(model.SCOPE_SOCIAL_MEDIAS_WRITE), s.handler.DeleteSocialMedia)
 
 }
 
@@ -0,0 +1,175 @@
+package router_test
+
+import (
+	"mygram/internal/apitest"
+	"mygram/internal/model"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+)
+
+type loginRes struct {
+	Token           string `json:"token"`
+	MFARequired     bool   `json:"mfa_required"`
+	MFAPendingToken string `json:"mfa_pending_token"`
+}
+
+func signIn(t *testing.T, h *apitest.Harness, email, password string) loginRes {
+	t.Helper()
+
+	rec := h.Request(t, http.MethodPost, "/users/login", model.UserSignIn{Email: email, Password: password}, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("login: %d %s", rec.Code, rec.Body.String())
+	}
+	res := loginRes{}
+	apitest.Decode(t, rec, &res)
+	return res
+}
+
+// totpCode returns the code steps time steps from now. One step ahead is
+// still accepted for clock drift, which lets a test use a fresh step.
+func totpCode(t *testing.T, secret string, steps int) string {
+	t.Helper()
+
+	code, err := totp.GenerateCode(secret, time.Now().Add(time.Duration(steps)*30*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+// enableTwoFactor enrolls and confirms alice, returning the secret and the
+// recovery codes.
+func enableTwoFactor(t *testing.T, h *apitest.Harness, token string) (string, []string) {
+	t.Helper()
+
+	rec := h.Request(t, http.MethodPost, "/users/2fa/enroll", nil, token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("enroll: %d %s", rec.Code, rec.Body.String())
+	}
+	enrolled := model.TwoFactorEnrollRes{}
+	apitest.Decode(t, rec, &enrolled)
+	if !strings.HasPrefix(enrolled.ProvisioningURI, "otpauth://totp/MyGram:alice@example.com?") {
+		t.Fatalf("unexpected provisioning uri %q", enrolled.ProvisioningURI)
+	}
+	if !strings.HasPrefix(enrolled.QRCode, "data:image/png;base64,") {
+		t.Fatalf("unexpected qr code %.40q", enrolled.QRCode)
+	}
+
+	// enrolling alone does not change login
+	if res := signIn(t, h, "alice@example.com", apitest.DefaultPassword); res.MFARequired {
+		t.Fatal("login asked for a second factor before confirmation")
+	}
+
+	rec = h.Request(t, http.MethodPost, "/users/2fa/confirm", model.TwoFactorConfirmReq{Code: "000000"}, token)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected wrong confirmation code to be rejected, got %d", rec.Code)
+	}
+	rec = h.Request(t, http.MethodPost, "/users/2fa/confirm", model.TwoFactorConfirmReq{Code: totpCode(t, enrolled.Secret, 0)}, token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("confirm: %d %s", rec.Code, rec.Body.String())
+	}
+	confirmed := model.TwoFactorConfirmRes{}
+	apitest.Decode(t, rec, &confirmed)
+	if len(confirmed.RecoveryCodes) != 10 {
+		t.Fatalf("expected 10 recovery codes, got %d", len(confirmed.RecoveryCodes))
+	}
+	return enrolled.Secret, confirmed.RecoveryCodes
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	h := apitest.New()
+	alice := h.CreateUser(t, "alice")
+	secret, recoveryCodes := enableTwoFactor(t, h, h.Token(t, alice))
+
+	res := signIn(t, h, "alice@example.com", apitest.DefaultPassword)
+	if !res.MFARequired || res.MFAPendingToken == "" || res.Token != "" {
+		t.Fatalf("expected a pending token only, got %+v", res)
+	}
+
+	// the pending token is no access token
+	rec := h.Request(t, http.MethodGet, "/users/1", nil, res.MFAPendingToken)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected pending token to be refused, got %d", rec.Code)
+	}
+	// nor is an access token a pending token
+	rec = h.Request(t, http.MethodPost, "/users/login/mfa", model.TwoFactorLoginReq{MFAPendingToken: h.Token(t, alice), Code: totpCode(t, secret, 1)}, "")
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected access token to be refused as pending token, got %d", rec.Code)
+	}
+
+	secondStep := func(code string) *loginRes {
+		rec := h.Request(t, http.MethodPost, "/users/login/mfa", model.TwoFactorLoginReq{MFAPendingToken: res.MFAPendingToken, Code: code}, "")
+		if rec.Code != http.StatusOK {
+			return nil
+		}
+		out := loginRes{}
+		apitest.Decode(t, rec, &out)
+		return &out
+	}
+
+	code := totpCode(t, secret, 1)
+	out := secondStep(code)
+	if out == nil || out.Token == "" {
+		t.Fatal("expected access token for a valid code")
+	}
+	if rec := h.Request(t, http.MethodGet, "/users/1", nil, out.Token); rec.Code != http.StatusOK {
+		t.Fatalf("expected access token to work, got %d", rec.Code)
+	}
+	if secondStep(code) != nil {
+		t.Fatal("expected a used code to be refused")
+	}
+
+	// recovery codes work once, with or without the dash
+	if secondStep(strings.ToUpper(strings.ReplaceAll(recoveryCodes[0], "-", ""))) == nil {
+		t.Fatal("expected recovery code to be accepted")
+	}
+	if secondStep(recoveryCodes[0]) != nil {
+		t.Fatal("expected a used recovery code to be refused")
+	}
+}
+
+func TestTwoFactorDisableRequiresReauthentication(t *testing.T) {
+	h := apitest.New()
+	alice := h.CreateUser(t, "alice")
+	token := h.Token(t, alice)
+	secret, _ := enableTwoFactor(t, h, token)
+
+	rec := h.Request(t, http.MethodPost, "/users/2fa/disable", model.TwoFactorDisableReq{Password: "wrong-password", Code: totpCode(t, secret, 1)}, token)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected wrong password to be refused, got %d", rec.Code)
+	}
+	rec = h.Request(t, http.MethodPost, "/users/2fa/disable", model.TwoFactorDisableReq{Password: apitest.DefaultPassword, Code: "000000"}, token)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected wrong code to be refused, got %d", rec.Code)
+	}
+	rec = h.Request(t, http.MethodPost, "/users/2fa/disable", model.TwoFactorDisableReq{Password: apitest.DefaultPassword, Code: totpCode(t, secret, 1)}, token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("disable: %d %s", rec.Code, rec.Body.String())
+	}
+
+	if res := signIn(t, h, "alice@example.com", apitest.DefaultPassword); res.MFARequired || res.Token == "" {
+		t.Fatalf("expected plain login after disabling, got %+v", res)
+	}
+}
+
+func TestTwoFactorCodesLockOut(t *testing.T) {
+	h := apitest.New()
+	alice := h.CreateUser(t, "alice")
+	secret, _ := enableTwoFactor(t, h, h.Token(t, alice))
+	res := signIn(t, h, "alice@example.com", apitest.DefaultPassword)
+
+	for i := 0; i < 5; i++ {
+		rec := h.Request(t, http.MethodPost, "/users/login/mfa", model.TwoFactorLoginReq{MFAPendingToken: res.MFAPendingToken, Code: "000000"}, "")
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: expected 401, got %d", i+1, rec.Code)
+		}
+	}
+	rec := h.Request(t, http.MethodPost, "/users/login/mfa", model.TwoFactorLoginReq{MFAPendingToken: res.MFAPendingToken, Code: totpCode(t, secret, 1)}, "")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 while locked, got %d", rec.Code)
+	}
+}
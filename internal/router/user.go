@@ -3,31 +3,105 @@ package router
 import (
 	"mygram/internal/handler"
 	"mygram/internal/middleware"
+	"mygram/internal/model"
+	"mygram/internal/ratelimit"
 
 	"github.com/gin-gonic/gin"
 )
 
 type UserRouter interface {
-	Mount()
+	Mount(g *gin.RouterGroup)
 }
 
 type userRouterImpl struct {
-	v *gin.RouterGroup
 	handler handler.UserHandler
+	limiter *middleware.RateLimiter
+	auth *middleware.Authenticator
 }
 
-func NewUserRouter(v *gin.RouterGroup, handler handler.UserHandler) UserRouter{
-	return &userRouterImpl{v:v, handler: handler}
+func NewUserRouter(handler handler.UserHandler, limiter *middleware.RateLimiter, auth *middleware.Authenticator) UserRouter{
+	return &userRouterImpl{handler: handler, limiter: limiter, auth: auth}
 }
 
-func (u *userRouterImpl) Mount() {
+func (u *userRouterImpl) Mount(g *gin.RouterGroup) {
+	v := g.Group("/users")
+	v.Use(u.limiter.ByIP(ratelimit.GroupUsers))
+
 	// activity
-	u.v.POST("/register", u.handler.UserSignUp)
-	u.v.POST("/login", u.handler.UserSignIn)
+	v.POST("/register", u.handler.UserSignUp)
+	v.POST("/login",
+		u.limiter.ByIP(ratelimit.GroupLogin),
+		u.limiter.ByLoginEmail(ratelimit.GroupLogin),
+		u.handler.UserSignIn,
+	)
+	v.POST("/login/mfa",
+		u.limiter.ByIP(ratelimit.GroupLogin),
+		u.handler.UserSignInMFA,
+	)
+
+	// account, endpoints sending mail are limited per address so they
+	// cannot be used to flood an inbox
+	v.POST("/verify-email", u.handler.VerifyEmail)
+	v.POST("/verify-email/resend",
+		u.limiter.ByIP(ratelimit.GroupAccountMail),
+		u.limiter.ByLoginEmail(ratelimit.GroupAccountMail),
+		u.handler.ResendEmailVerification,
+	)
+	v.POST("/password/forgot",
+		u.limiter.ByIP(ratelimit.GroupAccountMail),
+		u.limiter.ByLoginEmail(ratelimit.GroupAccountMail),
+		u.handler.ForgotPassword,
+	)
+	v.POST("/password/reset", u.handler.ResetPassword)
+
+	// social login, the callback ends like /login
+	v.GET("/oauth/:provider/login", u.limiter.ByIP(ratelimit.GroupLogin), u.handler.SocialLogin)
+	v.GET("/oauth/:provider/callback", u.limiter.ByIP(ratelimit.GroupLogin), u.handler.SocialLoginCallback)
+
+	// profiles are public, everything after needs an account
+	v.GET("/:id",
+		u.auth.CheckAuthBearerOrGuest,
+		u.limiter.ByGuest(ratelimit.GroupUsers),
+		middleware.RequireScope(model.SCOPE_PROFILE_READ),
+		u.handler.GetUsersById,
+	)
+
+	v.Use(u.auth.CheckAuthBearer)
+	v.Use(u.limiter.ByUser(ratelimit.GroupUsers))
+	v.PUT("/:id", middleware.RequireScope(model.SCOPE_PROFILE_WRITE), u.handler.EditUser)
+	v.PATCH("/:id", middleware.RequireScope(model.SCOPE_PROFILE_WRITE), u.handler.EditUser)
+	v.DELETE("/:id", middleware.RequireSession, u.handler.DeleteUsersById)
+
+	// two-factor authentication
+	v.POST("/2fa/enroll", middleware.RequireSession, u.handler.EnrollTwoFactor)
+	v.POST("/2fa/confirm", middleware.RequireSession, u.handler.ConfirmTwoFactor)
+	v.POST("/2fa/disable", middleware.RequireSession, u.handler.DisableTwoFactor)
+
+	// personal access tokens
+	v.POST("/tokens", middleware.RequireSession, u.handler.CreatePersonalAccessToken)
+	v.GET("/tokens", middleware.RequireSession, u.handler.GetPersonalAccessTokens)
+	v.DELETE("/tokens/:tokenId", middleware.RequireSession, u.handler.RevokePersonalAccessToken)
+
+	// sessions, DELETE /me/sessions signs out everywhere else
+	v.GET("/me/sessions", middleware.RequireSession, u.handler.GetSessions)
+	v.DELETE("/me/sessions", middleware.RequireSession, u.handler.RevokeOtherSessions)
+	v.DELETE("/me/sessions/:sessionId", middleware.RequireSession, u.handler.RevokeSession)
+	v.GET("/me/login-history", middleware.RequireSession, u.handler.GetLoginHistory)
+
+	// follows, a private account approves its followers
+	v.PUT("/me/privacy", middleware.RequireScope(model.SCOPE_PROFILE_WRITE), u.handler.SetPrivacy)
+	v.POST("/:id/follow", middleware.RequireScope(model.SCOPE_PROFILE_WRITE), u.handler.FollowUser)
+	v.DELETE("/:id/follow", middleware.RequireScope(model.SCOPE_PROFILE_WRITE), u.handler.UnfollowUser)
+	v.GET("/me/follow-requests", middleware.RequireScope(model.SCOPE_PROFILE_READ), u.handler.GetFollowRequests)
+	v.POST("/me/follow-requests/:followerId/approve", middleware.RequireScope(model.SCOPE_PROFILE_WRITE), u.handler.ApproveFollowRequest)
+	v.DELETE("/me/follow-requests/:followerId", middleware.RequireScope(model.SCOPE_PROFILE_WRITE), u.handler.DenyFollowRequest)
 
-	u.v.Use(middleware.CheckAuthBearer)
-	u.v.GET("/:id", u.handler.GetUsersById)
-	u.v.PUT("/:id", u.handler.EditUser)
-	u.v.DELETE("/:id", u.handler.DeleteUsersById)
+	// blocks and mutes
+	v.POST("/:id/block", middleware.RequireScope(model.SCOPE_PROFILE_WRITE), u.handler.BlockUser)
+	v.DELETE("/:id/block", middleware.RequireScope(model.SCOPE_PROFILE_WRITE), u.handler.UnblockUser)
+	v.GET("/me/blocks", middleware.RequireScope(model.SCOPE_PROFILE_READ), u.handler.GetBlocks)
+	v.POST("/:id/mute", middleware.RequireScope(model.SCOPE_PROFILE_WRITE), u.handler.MuteUser)
+	v.DELETE("/:id/mute", middleware.RequireScope(model.SCOPE_PROFILE_WRITE), u.handler.UnmuteUser)
+	v.GET("/me/mutes", middleware.RequireScope(model.SCOPE_PROFILE_READ), u.handler.GetMutes)
 }
 
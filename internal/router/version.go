@@ -0,0 +1,40 @@
+package router
+
+import (
+	"mygram/internal/middleware"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Router registers the routes of one resource under its own prefix.
+type Router interface {
+	Mount(g *gin.RouterGroup)
+}
+
+// Version is a set of routers served under a common prefix, such as /v1.
+type Version struct {
+	Prefix  string
+	Routers []Router
+	// Deprecated marks single routes of the routers deprecated, keyed as
+	// middleware.DeprecatedRoutes takes them.
+	Deprecated map[string]middleware.Deprecation
+}
+
+// Mount registers every router of the version under g. handlers run before
+// those of the routers.
+func (v Version) Mount(g *gin.RouterGroup, handlers ...gin.HandlerFunc) {
+	group := g.Group(v.Prefix, handlers...)
+	if len(v.Deprecated) > 0 {
+		group.Use(middleware.DeprecatedRoutes(strings.TrimSuffix(group.BasePath(), "/"), v.Deprecated))
+	}
+	for _, r := range v.Routers {
+		r.Mount(group)
+	}
+}
+
+// MountAlias registers the routers once more without the version prefix,
+// for clients from before the API was versioned.
+func (v Version) MountAlias(g *gin.RouterGroup, handlers ...gin.HandlerFunc) {
+	Version{Routers: v.Routers, Deprecated: v.Deprecated}.Mount(g, handlers...)
+}
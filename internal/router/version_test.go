@@ -0,0 +1,129 @@
+package router_test
+
+import (
+	"encoding/json"
+	"mygram/internal/apitest"
+	"mygram/internal/app"
+	"mygram/internal/middleware"
+	"mygram/internal/openapi"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEveryV1RouteHasAnUnversionedAlias(t *testing.T) {
+	routes := map[apitest.Route]bool{}
+	for _, r := range apitest.New().Routes() {
+		routes[r] = true
+	}
+
+	// the document describes the versions, it is not part of one
+	delete(routes, openAPI)
+	// and the GraphQL schema evolves without versions
+	delete(routes, graphQLGet)
+	delete(routes, graphQLPost)
+	delete(routes, graphQLSDL)
+
+	for r := range routes {
+		if !strings.HasPrefix(r.Path, app.API_V1+"/") {
+			continue
+		}
+		alias := apitest.Route{Method: r.Method, Path: strings.TrimPrefix(r.Path, app.API_V1)}
+		if !routes[alias] {
+			t.Errorf("route %s has no alias %s", r, alias)
+		}
+		delete(routes, r)
+		delete(routes, alias)
+	}
+	for r := range routes {
+		t.Errorf("route %s is not under %s", r, app.API_V1)
+	}
+}
+
+func TestUnversionedAliasesAreDeprecated(t *testing.T) {
+	f := newFixture(t)
+
+	v1 := f.h.Request(t, http.MethodGet, "/v1/photos/1", nil, f.bobToken)
+	if v1.Code != http.StatusOK {
+		t.Fatalf("get photo: %d %s", v1.Code, v1.Body.String())
+	}
+	if v1.Header().Get(middleware.HEADER_DEPRECATION) != "" {
+		t.Fatalf("expected /v1 not deprecated, got %v", v1.Header())
+	}
+
+	alias := f.h.Request(t, http.MethodGet, "/photos/1", nil, f.bobToken)
+	if alias.Code != http.StatusOK || alias.Body.String() != v1.Body.String() {
+		t.Fatalf("expected the alias to answer as /v1, got %d %s", alias.Code, alias.Body.String())
+	}
+	if got := alias.Header().Get(middleware.HEADER_DEPRECATION); got != "@1792368000" {
+		t.Fatalf("expected the deprecation date, got %q", got)
+	}
+	if got := alias.Header().Get(middleware.HEADER_LINK); got != `</v1/photos/1>; rel="successor-version"` {
+		t.Fatalf("expected a link to /v1, got %q", got)
+	}
+	// no sunset is announced yet
+	if got := alias.Header().Get(middleware.HEADER_SUNSET); got != "" {
+		t.Fatalf("expected no sunset, got %q", got)
+	}
+
+	// responses the middleware of the routers cut short are marked too
+	if rec := f.h.Request(t, http.MethodGet, "/photos/1", nil, ""); rec.Code != http.StatusUnauthorized || rec.Header().Get(middleware.HEADER_DEPRECATION) == "" {
+		t.Fatalf("expected a deprecated 401, got %d %v", rec.Code, rec.Header())
+	}
+}
+
+func TestUnversionedAliasesAnnounceTheirSunset(t *testing.T) {
+	d := app.DefaultUnversionedDeprecation()
+	d.Sunset = time.Date(2027, time.April, 1, 0, 0, 0, 0, time.UTC)
+	h := apitest.New(apitest.WithUnversionedDeprecation(d))
+
+	rec := h.Request(t, http.MethodGet, "/public", nil, "")
+	if got := rec.Header().Get(middleware.HEADER_SUNSET); got != "Thu, 01 Apr 2027 00:00:00 GMT" {
+		t.Fatalf("expected the sunset date, got %q", got)
+	}
+	if rec := h.Request(t, http.MethodGet, "/v1/public", nil, ""); rec.Header().Get(middleware.HEADER_SUNSET) != "" {
+		t.Fatalf("expected no sunset on /v1, got %v", rec.Header())
+	}
+}
+
+func TestSingleRoutesCanBeDeprecated(t *testing.T) {
+	h := apitest.New(apitest.WithDeprecatedRoutes(map[string]middleware.Deprecation{
+		"GET /photos": {
+			Since:       time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC),
+			Sunset:      time.Date(2027, time.May, 1, 0, 0, 0, 0, time.UTC),
+			Replacement: "/v1/search",
+		},
+	}))
+	token := h.PublicToken(t)
+
+	for _, path := range []string{"/v1/photos?user_id=1", "/photos?user_id=1"} {
+		rec := h.Request(t, http.MethodGet, path, nil, token)
+		if got := rec.Header().Get(middleware.HEADER_DEPRECATION); got != "@1793491200" {
+			t.Fatalf("%s: expected the deprecation of the route, got %q", path, got)
+		}
+		if got := rec.Header().Get(middleware.HEADER_SUNSET); got != "Sat, 01 May 2027 00:00:00 GMT" {
+			t.Fatalf("%s: expected the sunset of the route, got %q", path, got)
+		}
+		if got := rec.Header().Get(middleware.HEADER_LINK); got != `</v1/search>; rel="successor-version"` {
+			t.Fatalf("%s: expected a link to the replacement, got %q", path, got)
+		}
+	}
+
+	// the other routes of the router are left alone
+	if rec := h.Request(t, http.MethodGet, "/v1/photos/1", nil, token); rec.Header().Get(middleware.HEADER_DEPRECATION) != "" {
+		t.Fatalf("expected GET /v1/photos/:id not deprecated, got %v", rec.Header())
+	}
+	if rec := h.Request(t, http.MethodPost, "/v1/photos", nil, token); rec.Header().Get(middleware.HEADER_DEPRECATION) != "" {
+		t.Fatalf("expected POST /v1/photos not deprecated, got %v", rec.Header())
+	}
+
+	rec := h.Request(t, http.MethodGet, "/openapi.json", nil, "")
+	doc := openapi.Document{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if !doc.Paths["/photos"]["get"].Deprecated || doc.Paths["/photos"]["post"].Deprecated || doc.Paths["/photos/{id}"]["get"].Deprecated {
+		t.Fatal("expected only GET /photos deprecated in the document")
+	}
+}
@@ -0,0 +1,163 @@
+// Package rpc serves the services over gRPC for internal backends, next to
+// the HTTP API and with the same authentication. The messages and service
+// stubs in mygram/v1 are generated from proto/ with buf generate.
+package rpc
+
+import (
+	"context"
+	"errors"
+	"mygram/internal/model"
+	mygramv1 "mygram/internal/rpc/mygram/v1"
+	"mygram/internal/service"
+	"mygram/pkg/helper"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// ScopeNone is the scope of the methods that need no token.
+	ScopeNone = "none"
+	// ScopeSession is the scope of the methods only login sessions may call,
+	// as the routes behind middleware.RequireSession.
+	ScopeSession = "session"
+)
+
+// MethodScopes is the scope every method of the mygram.v1 services needs.
+// Methods missing from it are refused, whatever the token.
+var MethodScopes = map[string]string{
+	mygramv1.UserService_GetUser_FullMethodName:       model.SCOPE_PROFILE_READ,
+	mygramv1.UserService_BatchGetUsers_FullMethodName: model.SCOPE_PROFILE_READ,
+	mygramv1.UserService_SignUp_FullMethodName:        ScopeNone,
+	mygramv1.UserService_EditUser_FullMethodName:      model.SCOPE_PROFILE_WRITE,
+	mygramv1.UserService_DeleteUser_FullMethodName:    ScopeSession,
+
+	mygramv1.PhotoService_GetPhoto_FullMethodName:    model.SCOPE_PHOTOS_READ,
+	mygramv1.PhotoService_ListPhotos_FullMethodName:  model.SCOPE_PHOTOS_READ,
+	mygramv1.PhotoService_CreatePhoto_FullMethodName: model.SCOPE_PHOTOS_WRITE,
+	mygramv1.PhotoService_UpdatePhoto_FullMethodName: model.SCOPE_PHOTOS_WRITE,
+	mygramv1.PhotoService_DeletePhoto_FullMethodName: model.SCOPE_PHOTOS_WRITE,
+
+	mygramv1.CommentService_GetComment_FullMethodName:    model.SCOPE_COMMENTS_READ,
+	mygramv1.CommentService_ListComments_FullMethodName:  model.SCOPE_COMMENTS_READ,
+	mygramv1.CommentService_CreateComment_FullMethodName: model.SCOPE_COMMENTS_WRITE,
+	mygramv1.CommentService_UpdateComment_FullMethodName: model.SCOPE_COMMENTS_WRITE,
+	mygramv1.CommentService_DeleteComment_FullMethodName: model.SCOPE_COMMENTS_WRITE,
+
+	mygramv1.SocialMediaService_GetSocialMedia_FullMethodName:    model.SCOPE_SOCIAL_MEDIAS_READ,
+	mygramv1.SocialMediaService_ListSocialMedias_FullMethodName:  model.SCOPE_SOCIAL_MEDIAS_READ,
+	mygramv1.SocialMediaService_CreateSocialMedia_FullMethodName: model.SCOPE_SOCIAL_MEDIAS_WRITE,
+	mygramv1.SocialMediaService_UpdateSocialMedia_FullMethodName: model.SCOPE_SOCIAL_MEDIAS_WRITE,
+	mygramv1.SocialMediaService_DeleteSocialMedia_FullMethodName: model.SCOPE_SOCIAL_MEDIAS_WRITE,
+}
+
+// Authenticator authenticates calls with the bearer token in their
+// authorization metadata, a login session or a personal access token, as
+// middleware.Authenticator does requests. There are no guests.
+type Authenticator struct {
+	tokens   service.PersonalAccessTokenService
+	sessions service.SessionService
+}
+
+func NewAuthenticator(tokens service.PersonalAccessTokenService, sessions service.SessionService) *Authenticator {
+	return &Authenticator{tokens: tokens, sessions: sessions}
+}
+
+type callerKey struct{}
+
+// caller is who a call is made by.
+type caller struct {
+	userId uint64
+	// scopes are those of a personal access token, sessions have every
+	// scope.
+	scopes  []string
+	session bool
+}
+
+func (c caller) allows(scope string) bool {
+	return c.session || slices.Contains(c.scopes, scope)
+}
+
+// callerID is the user a call is made by, set by Authenticator.Unary.
+func callerID(ctx context.Context) uint64 {
+	c, _ := ctx.Value(callerKey{}).(caller)
+	return c.userId
+}
+
+// requireSession is middleware.CheckSession for the calls that only need a
+// login session for some requests.
+func requireSession(ctx context.Context) error {
+	if c, _ := ctx.Value(callerKey{}).(caller); !c.session {
+		return status.Error(codes.PermissionDenied, "method requires a login session")
+	}
+	return nil
+}
+
+// Unary authenticates the calls of the mygram.v1 services, and checks the
+// token was granted the scope of the method. Health checks, reflection and
+// the methods of ScopeNone need no token.
+func (a *Authenticator) Unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if !strings.HasPrefix(info.FullMethod, "/mygram.v1.") {
+		return handler(ctx, req)
+	}
+	scope, ok := MethodScopes[info.FullMethod]
+	if !ok {
+		return nil, status.Errorf(codes.PermissionDenied, "method %s declares no scope", info.FullMethod)
+	}
+	if scope == ScopeNone {
+		return handler(ctx, req)
+	}
+
+	c, err := a.authenticate(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	ctx = context.WithValue(ctx, callerKey{}, c)
+	if scope == ScopeSession {
+		if err := requireSession(ctx); err != nil {
+			return nil, err
+		}
+	} else if !c.allows(scope) {
+		return nil, status.Errorf(codes.PermissionDenied, "token requires scope %s", scope)
+	}
+	return handler(ctx, req)
+}
+
+func (a *Authenticator) authenticate(ctx context.Context) (caller, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	auth := md.Get("authorization")
+	if len(auth) == 0 {
+		return caller{}, errors.New("missing authorization metadata")
+	}
+	method, token, _ := strings.Cut(auth[0], " ")
+	if method != "Bearer" {
+		return caller{}, errors.New("invalid authorization method")
+	}
+
+	if strings.HasPrefix(token, model.PERSONAL_ACCESS_TOKEN_PREFIX) {
+		pat, err := a.tokens.AuthenticateToken(ctx, token)
+		if err != nil {
+			return caller{}, err
+		}
+		return caller{userId: pat.UserId, scopes: pat.Scopes}, nil
+	}
+
+	claims, err := helper.ValidateToken(token, model.SUBJECT_ACCESS_TOKEN)
+	if err != nil {
+		return caller{}, errors.New("invalid token")
+	}
+	jti, _ := claims["jti"].(string)
+	userId, _ := claims["user_id"].(float64)
+	session, err := a.sessions.AuthenticateSession(ctx, jti)
+	if err != nil {
+		return caller{}, err
+	}
+	if float64(session.UserId) != userId {
+		return caller{}, errors.New("session belongs to another user")
+	}
+	return caller{userId: session.UserId, session: true}, nil
+}
@@ -0,0 +1,166 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"mygram/internal/model"
+	mygramv1 "mygram/internal/rpc/mygram/v1"
+	"mygram/internal/service"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type commentServerImpl struct {
+	mygramv1.UnimplementedCommentServiceServer
+	svc        service.CommentService
+	accountSvc service.AccountService
+}
+
+func NewCommentServer(svc service.CommentService, accountSvc service.AccountService) mygramv1.CommentServiceServer {
+	return &commentServerImpl{svc: svc, accountSvc: accountSvc}
+}
+
+func (c *commentServerImpl) GetComment(ctx context.Context, req *mygramv1.GetCommentRequest) (*mygramv1.Comment, error) {
+	if err := requireID(req.GetId(), "comment"); err != nil {
+		return nil, err
+	}
+	comment, err := c.getComment(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return commentMessage(comment), nil
+}
+
+func (c *commentServerImpl) ListComments(ctx context.Context, req *mygramv1.ListCommentsRequest) (*mygramv1.ListCommentsResponse, error) {
+	if err := requireID(req.GetPhotoId(), "photo"); err != nil {
+		return nil, err
+	}
+
+	comments, err := c.svc.GetCommentsByPhotoIds(ctx, []uint64{req.GetPhotoId()}, callerID(ctx))
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	res := &mygramv1.ListCommentsResponse{Comments: make([]*mygramv1.Comment, len(comments))}
+	for i, comment := range comments {
+		res.Comments[i] = commentMessage(model.Comment{
+			ID:        comment.ID,
+			UserId:    comment.UserId,
+			PhotoId:   comment.PhotoId,
+			Message:   comment.Message,
+			CreatedAt: comment.CreatedAt,
+			UpdatedAt: comment.UpdatedAt,
+		})
+	}
+	return res, nil
+}
+
+func (c *commentServerImpl) CreateComment(ctx context.Context, req *mygramv1.CreateCommentRequest) (*mygramv1.Comment, error) {
+	if err := requireVerifiedEmail(ctx, c.accountSvc); err != nil {
+		return nil, err
+	}
+	if err := validate(model.CommentCreateReq{Message: req.GetMessage(), PhotoId: req.GetPhotoId()}); err != nil {
+		return nil, err
+	}
+
+	created, err := c.svc.CreateComment(ctx, model.Comment{
+		UserId:  callerID(ctx),
+		PhotoId: req.GetPhotoId(),
+		Message: req.GetMessage(),
+	})
+	if errors.Is(err, service.ErrPhotoNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return nil, commentError(err)
+	}
+	return c.GetComment(ctx, &mygramv1.GetCommentRequest{Id: created.ID})
+}
+
+func (c *commentServerImpl) UpdateComment(ctx context.Context, req *mygramv1.UpdateCommentRequest) (*mygramv1.Comment, error) {
+	if err := requireVerifiedEmail(ctx, c.accountSvc); err != nil {
+		return nil, err
+	}
+	if err := requireID(req.GetId(), "comment"); err != nil {
+		return nil, err
+	}
+	if err := validate(model.CommentUpdateReq{Message: req.GetMessage()}); err != nil {
+		return nil, err
+	}
+	comment, err := c.ownComment(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = c.svc.EditComment(ctx, model.Comment{
+		ID:      req.GetId(),
+		UserId:  comment.UserId,
+		PhotoId: comment.PhotoId,
+		Message: req.GetMessage(),
+	}, time.Time{})
+	if err != nil {
+		return nil, commentError(err)
+	}
+	return c.GetComment(ctx, &mygramv1.GetCommentRequest{Id: req.GetId()})
+}
+
+func (c *commentServerImpl) DeleteComment(ctx context.Context, req *mygramv1.DeleteCommentRequest) (*emptypb.Empty, error) {
+	if err := requireID(req.GetId(), "comment"); err != nil {
+		return nil, err
+	}
+	if _, err := c.ownComment(ctx, req.GetId()); err != nil {
+		return nil, err
+	}
+
+	if err := c.svc.DeleteComment(ctx, req.GetId(), callerID(ctx)); err != nil {
+		return nil, internalError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (c *commentServerImpl) getComment(ctx context.Context, id uint64) (model.Comment, error) {
+	comment, err := c.svc.GetCommentById(ctx, id, callerID(ctx))
+	if err != nil {
+		return model.Comment{}, internalError(err)
+	}
+	if comment.ID == 0 {
+		return model.Comment{}, status.Error(codes.NotFound, "comment not found")
+	}
+	return comment, nil
+}
+
+// ownComment is getComment for a comment of the caller.
+func (c *commentServerImpl) ownComment(ctx context.Context, id uint64) (model.Comment, error) {
+	comment, err := c.getComment(ctx, id)
+	if err != nil {
+		return model.Comment{}, err
+	}
+	if comment.UserId != callerID(ctx) {
+		return model.Comment{}, status.Error(codes.PermissionDenied, "invalid user request")
+	}
+	return comment, nil
+}
+
+// commentError answers PERMISSION_DENIED for messages mentioning a user
+// the caller blocked or was blocked by.
+func commentError(err error) error {
+	if errors.Is(err, service.ErrMentionBlocked) {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	return internalError(err)
+}
+
+func commentMessage(comment model.Comment) *mygramv1.Comment {
+	return &mygramv1.Comment{
+		Id:        comment.ID,
+		Message:   comment.Message,
+		PhotoId:   comment.PhotoId,
+		UserId:    comment.UserId,
+		CreatedAt: timestamppb.New(comment.CreatedAt),
+		UpdatedAt: timestamppb.New(comment.UpdatedAt),
+	}
+}
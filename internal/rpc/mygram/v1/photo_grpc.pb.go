@@ -0,0 +1,264 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: mygram/v1/photo.proto
+
+package mygramv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	PhotoService_GetPhoto_FullMethodName    = "/mygram.v1.PhotoService/GetPhoto"
+	PhotoService_ListPhotos_FullMethodName  = "/mygram.v1.PhotoService/ListPhotos"
+	PhotoService_CreatePhoto_FullMethodName = "/mygram.v1.PhotoService/CreatePhoto"
+	PhotoService_UpdatePhoto_FullMethodName = "/mygram.v1.PhotoService/UpdatePhoto"
+	PhotoService_DeletePhoto_FullMethodName = "/mygram.v1.PhotoService/DeletePhoto"
+)
+
+// PhotoServiceClient is the client API for PhotoService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PhotoServiceClient interface {
+	GetPhoto(ctx context.Context, in *GetPhotoRequest, opts ...grpc.CallOption) (*Photo, error)
+	// ListPhotos lists the photos of a user, empty when the caller may not
+	// see them.
+	ListPhotos(ctx context.Context, in *ListPhotosRequest, opts ...grpc.CallOption) (*ListPhotosResponse, error)
+	CreatePhoto(ctx context.Context, in *CreatePhotoRequest, opts ...grpc.CallOption) (*Photo, error)
+	// UpdatePhoto replaces the title, caption and URL of a photo.
+	UpdatePhoto(ctx context.Context, in *UpdatePhotoRequest, opts ...grpc.CallOption) (*Photo, error)
+	DeletePhoto(ctx context.Context, in *DeletePhotoRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+}
+
+type photoServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPhotoServiceClient(cc grpc.ClientConnInterface) PhotoServiceClient {
+	return &photoServiceClient{cc}
+}
+
+func (c *photoServiceClient) GetPhoto(ctx context.Context, in *GetPhotoRequest, opts ...grpc.CallOption) (*Photo, error) {
+	out := new(Photo)
+	err := c.cc.Invoke(ctx, PhotoService_GetPhoto_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *photoServiceClient) ListPhotos(ctx context.Context, in *ListPhotosRequest, opts ...grpc.CallOption) (*ListPhotosResponse, error) {
+	out := new(ListPhotosResponse)
+	err := c.cc.Invoke(ctx, PhotoService_ListPhotos_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *photoServiceClient) CreatePhoto(ctx context.Context, in *CreatePhotoRequest, opts ...grpc.CallOption) (*Photo, error) {
+	out := new(Photo)
+	err := c.cc.Invoke(ctx, PhotoService_CreatePhoto_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *photoServiceClient) UpdatePhoto(ctx context.Context, in *UpdatePhotoRequest, opts ...grpc.CallOption) (*Photo, error) {
+	out := new(Photo)
+	err := c.cc.Invoke(ctx, PhotoService_UpdatePhoto_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *photoServiceClient) DeletePhoto(ctx context.Context, in *DeletePhotoRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, PhotoService_DeletePhoto_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PhotoServiceServer is the server API for PhotoService service.
+// All implementations must embed UnimplementedPhotoServiceServer
+// for forward compatibility
+type PhotoServiceServer interface {
+	GetPhoto(context.Context, *GetPhotoRequest) (*Photo, error)
+	// ListPhotos lists the photos of a user, empty when the caller may not
+	// see them.
+	ListPhotos(context.Context, *ListPhotosRequest) (*ListPhotosResponse, error)
+	CreatePhoto(context.Context, *CreatePhotoRequest) (*Photo, error)
+	// UpdatePhoto replaces the title, caption and URL of a photo.
+	UpdatePhoto(context.Context, *UpdatePhotoRequest) (*Photo, error)
+	DeletePhoto(context.Context, *DeletePhotoRequest) (*emptypb.Empty, error)
+	mustEmbedUnimplementedPhotoServiceServer()
+}
+
+// UnimplementedPhotoServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedPhotoServiceServer struct {
+}
+
+func (UnimplementedPhotoServiceServer) GetPhoto(context.Context, *GetPhotoRequest) (*Photo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPhoto not implemented")
+}
+func (UnimplementedPhotoServiceServer) ListPhotos(context.Context, *ListPhotosRequest) (*ListPhotosResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPhotos not implemented")
+}
+func (UnimplementedPhotoServiceServer) CreatePhoto(context.Context, *CreatePhotoRequest) (*Photo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreatePhoto not implemented")
+}
+func (UnimplementedPhotoServiceServer) UpdatePhoto(context.Context, *UpdatePhotoRequest) (*Photo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdatePhoto not implemented")
+}
+func (UnimplementedPhotoServiceServer) DeletePhoto(context.Context, *DeletePhotoRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeletePhoto not implemented")
+}
+func (UnimplementedPhotoServiceServer) mustEmbedUnimplementedPhotoServiceServer() {}
+
+// UnsafePhotoServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PhotoServiceServer will
+// result in compilation errors.
+type UnsafePhotoServiceServer interface {
+	mustEmbedUnimplementedPhotoServiceServer()
+}
+
+func RegisterPhotoServiceServer(s grpc.ServiceRegistrar, srv PhotoServiceServer) {
+	s.RegisterService(&PhotoService_ServiceDesc, srv)
+}
+
+func _PhotoService_GetPhoto_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPhotoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PhotoServiceServer).GetPhoto(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PhotoService_GetPhoto_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PhotoServiceServer).GetPhoto(ctx, req.(*GetPhotoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PhotoService_ListPhotos_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPhotosRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PhotoServiceServer).ListPhotos(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PhotoService_ListPhotos_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PhotoServiceServer).ListPhotos(ctx, req.(*ListPhotosRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PhotoService_CreatePhoto_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreatePhotoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PhotoServiceServer).CreatePhoto(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PhotoService_CreatePhoto_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PhotoServiceServer).CreatePhoto(ctx, req.(*CreatePhotoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PhotoService_UpdatePhoto_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdatePhotoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PhotoServiceServer).UpdatePhoto(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PhotoService_UpdatePhoto_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PhotoServiceServer).UpdatePhoto(ctx, req.(*UpdatePhotoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PhotoService_DeletePhoto_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeletePhotoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PhotoServiceServer).DeletePhoto(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PhotoService_DeletePhoto_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PhotoServiceServer).DeletePhoto(ctx, req.(*DeletePhotoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// PhotoService_ServiceDesc is the grpc.ServiceDesc for PhotoService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PhotoService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "mygram.v1.PhotoService",
+	HandlerType: (*PhotoServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetPhoto",
+			Handler:    _PhotoService_GetPhoto_Handler,
+		},
+		{
+			MethodName: "ListPhotos",
+			Handler:    _PhotoService_ListPhotos_Handler,
+		},
+		{
+			MethodName: "CreatePhoto",
+			Handler:    _PhotoService_CreatePhoto_Handler,
+		},
+		{
+			MethodName: "UpdatePhoto",
+			Handler:    _PhotoService_UpdatePhoto_Handler,
+		},
+		{
+			MethodName: "DeletePhoto",
+			Handler:    _PhotoService_DeletePhoto_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "mygram/v1/photo.proto",
+}
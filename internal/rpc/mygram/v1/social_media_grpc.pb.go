@@ -0,0 +1,258 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: mygram/v1/social_media.proto
+
+package mygramv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	SocialMediaService_GetSocialMedia_FullMethodName    = "/mygram.v1.SocialMediaService/GetSocialMedia"
+	SocialMediaService_ListSocialMedias_FullMethodName  = "/mygram.v1.SocialMediaService/ListSocialMedias"
+	SocialMediaService_CreateSocialMedia_FullMethodName = "/mygram.v1.SocialMediaService/CreateSocialMedia"
+	SocialMediaService_UpdateSocialMedia_FullMethodName = "/mygram.v1.SocialMediaService/UpdateSocialMedia"
+	SocialMediaService_DeleteSocialMedia_FullMethodName = "/mygram.v1.SocialMediaService/DeleteSocialMedia"
+)
+
+// SocialMediaServiceClient is the client API for SocialMediaService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type SocialMediaServiceClient interface {
+	GetSocialMedia(ctx context.Context, in *GetSocialMediaRequest, opts ...grpc.CallOption) (*SocialMedia, error)
+	ListSocialMedias(ctx context.Context, in *ListSocialMediasRequest, opts ...grpc.CallOption) (*ListSocialMediasResponse, error)
+	CreateSocialMedia(ctx context.Context, in *CreateSocialMediaRequest, opts ...grpc.CallOption) (*SocialMedia, error)
+	UpdateSocialMedia(ctx context.Context, in *UpdateSocialMediaRequest, opts ...grpc.CallOption) (*SocialMedia, error)
+	DeleteSocialMedia(ctx context.Context, in *DeleteSocialMediaRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+}
+
+type socialMediaServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSocialMediaServiceClient(cc grpc.ClientConnInterface) SocialMediaServiceClient {
+	return &socialMediaServiceClient{cc}
+}
+
+func (c *socialMediaServiceClient) GetSocialMedia(ctx context.Context, in *GetSocialMediaRequest, opts ...grpc.CallOption) (*SocialMedia, error) {
+	out := new(SocialMedia)
+	err := c.cc.Invoke(ctx, SocialMediaService_GetSocialMedia_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *socialMediaServiceClient) ListSocialMedias(ctx context.Context, in *ListSocialMediasRequest, opts ...grpc.CallOption) (*ListSocialMediasResponse, error) {
+	out := new(ListSocialMediasResponse)
+	err := c.cc.Invoke(ctx, SocialMediaService_ListSocialMedias_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *socialMediaServiceClient) CreateSocialMedia(ctx context.Context, in *CreateSocialMediaRequest, opts ...grpc.CallOption) (*SocialMedia, error) {
+	out := new(SocialMedia)
+	err := c.cc.Invoke(ctx, SocialMediaService_CreateSocialMedia_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *socialMediaServiceClient) UpdateSocialMedia(ctx context.Context, in *UpdateSocialMediaRequest, opts ...grpc.CallOption) (*SocialMedia, error) {
+	out := new(SocialMedia)
+	err := c.cc.Invoke(ctx, SocialMediaService_UpdateSocialMedia_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *socialMediaServiceClient) DeleteSocialMedia(ctx context.Context, in *DeleteSocialMediaRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, SocialMediaService_DeleteSocialMedia_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SocialMediaServiceServer is the server API for SocialMediaService service.
+// All implementations must embed UnimplementedSocialMediaServiceServer
+// for forward compatibility
+type SocialMediaServiceServer interface {
+	GetSocialMedia(context.Context, *GetSocialMediaRequest) (*SocialMedia, error)
+	ListSocialMedias(context.Context, *ListSocialMediasRequest) (*ListSocialMediasResponse, error)
+	CreateSocialMedia(context.Context, *CreateSocialMediaRequest) (*SocialMedia, error)
+	UpdateSocialMedia(context.Context, *UpdateSocialMediaRequest) (*SocialMedia, error)
+	DeleteSocialMedia(context.Context, *DeleteSocialMediaRequest) (*emptypb.Empty, error)
+	mustEmbedUnimplementedSocialMediaServiceServer()
+}
+
+// UnimplementedSocialMediaServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedSocialMediaServiceServer struct {
+}
+
+func (UnimplementedSocialMediaServiceServer) GetSocialMedia(context.Context, *GetSocialMediaRequest) (*SocialMedia, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSocialMedia not implemented")
+}
+func (UnimplementedSocialMediaServiceServer) ListSocialMedias(context.Context, *ListSocialMediasRequest) (*ListSocialMediasResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSocialMedias not implemented")
+}
+func (UnimplementedSocialMediaServiceServer) CreateSocialMedia(context.Context, *CreateSocialMediaRequest) (*SocialMedia, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateSocialMedia not implemented")
+}
+func (UnimplementedSocialMediaServiceServer) UpdateSocialMedia(context.Context, *UpdateSocialMediaRequest) (*SocialMedia, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateSocialMedia not implemented")
+}
+func (UnimplementedSocialMediaServiceServer) DeleteSocialMedia(context.Context, *DeleteSocialMediaRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteSocialMedia not implemented")
+}
+func (UnimplementedSocialMediaServiceServer) mustEmbedUnimplementedSocialMediaServiceServer() {}
+
+// UnsafeSocialMediaServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SocialMediaServiceServer will
+// result in compilation errors.
+type UnsafeSocialMediaServiceServer interface {
+	mustEmbedUnimplementedSocialMediaServiceServer()
+}
+
+func RegisterSocialMediaServiceServer(s grpc.ServiceRegistrar, srv SocialMediaServiceServer) {
+	s.RegisterService(&SocialMediaService_ServiceDesc, srv)
+}
+
+func _SocialMediaService_GetSocialMedia_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSocialMediaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SocialMediaServiceServer).GetSocialMedia(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SocialMediaService_GetSocialMedia_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SocialMediaServiceServer).GetSocialMedia(ctx, req.(*GetSocialMediaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SocialMediaService_ListSocialMedias_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSocialMediasRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SocialMediaServiceServer).ListSocialMedias(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SocialMediaService_ListSocialMedias_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SocialMediaServiceServer).ListSocialMedias(ctx, req.(*ListSocialMediasRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SocialMediaService_CreateSocialMedia_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateSocialMediaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SocialMediaServiceServer).CreateSocialMedia(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SocialMediaService_CreateSocialMedia_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SocialMediaServiceServer).CreateSocialMedia(ctx, req.(*CreateSocialMediaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SocialMediaService_UpdateSocialMedia_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateSocialMediaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SocialMediaServiceServer).UpdateSocialMedia(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SocialMediaService_UpdateSocialMedia_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SocialMediaServiceServer).UpdateSocialMedia(ctx, req.(*UpdateSocialMediaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SocialMediaService_DeleteSocialMedia_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteSocialMediaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SocialMediaServiceServer).DeleteSocialMedia(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SocialMediaService_DeleteSocialMedia_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SocialMediaServiceServer).DeleteSocialMedia(ctx, req.(*DeleteSocialMediaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// SocialMediaService_ServiceDesc is the grpc.ServiceDesc for SocialMediaService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SocialMediaService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "mygram.v1.SocialMediaService",
+	HandlerType: (*SocialMediaServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetSocialMedia",
+			Handler:    _SocialMediaService_GetSocialMedia_Handler,
+		},
+		{
+			MethodName: "ListSocialMedias",
+			Handler:    _SocialMediaService_ListSocialMedias_Handler,
+		},
+		{
+			MethodName: "CreateSocialMedia",
+			Handler:    _SocialMediaService_CreateSocialMedia_Handler,
+		},
+		{
+			MethodName: "UpdateSocialMedia",
+			Handler:    _SocialMediaService_UpdateSocialMedia_Handler,
+		},
+		{
+			MethodName: "DeleteSocialMedia",
+			Handler:    _SocialMediaService_DeleteSocialMedia_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "mygram/v1/social_media.proto",
+}
@@ -0,0 +1,156 @@
+package rpc
+
+import (
+	"context"
+	"mygram/internal/model"
+	mygramv1 "mygram/internal/rpc/mygram/v1"
+	"mygram/internal/service"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type photoServerImpl struct {
+	mygramv1.UnimplementedPhotoServiceServer
+	svc        service.PhotoService
+	accountSvc service.AccountService
+}
+
+func NewPhotoServer(svc service.PhotoService, accountSvc service.AccountService) mygramv1.PhotoServiceServer {
+	return &photoServerImpl{svc: svc, accountSvc: accountSvc}
+}
+
+func (p *photoServerImpl) GetPhoto(ctx context.Context, req *mygramv1.GetPhotoRequest) (*mygramv1.Photo, error) {
+	if err := requireID(req.GetId(), "photo"); err != nil {
+		return nil, err
+	}
+	photo, err := p.getPhoto(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return photoMessage(photo), nil
+}
+
+func (p *photoServerImpl) ListPhotos(ctx context.Context, req *mygramv1.ListPhotosRequest) (*mygramv1.ListPhotosResponse, error) {
+	if err := requireID(req.GetUserId(), "user"); err != nil {
+		return nil, err
+	}
+
+	photos, err := p.svc.GetPhotosByUserIds(ctx, []uint64{req.GetUserId()}, callerID(ctx))
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	res := &mygramv1.ListPhotosResponse{Photos: make([]*mygramv1.Photo, len(photos))}
+	for i, photo := range photos {
+		res.Photos[i] = photoMessage(model.Photo{
+			ID:        photo.ID,
+			Title:     photo.Title,
+			Caption:   photo.Caption,
+			PhotoUrl:  photo.PhotoUrl,
+			UserId:    photo.UserId,
+			CreatedAt: photo.CreatedAt,
+			UpdatedAt: photo.UpdatedAt,
+		})
+	}
+	return res, nil
+}
+
+func (p *photoServerImpl) CreatePhoto(ctx context.Context, req *mygramv1.CreatePhotoRequest) (*mygramv1.Photo, error) {
+	if err := requireVerifiedEmail(ctx, p.accountSvc); err != nil {
+		return nil, err
+	}
+	if err := validate(model.PhotoCreateReq{Title: req.GetTitle(), Caption: req.GetCaption(), PhotoUrl: req.GetPhotoUrl()}); err != nil {
+		return nil, err
+	}
+
+	created, err := p.svc.CreatePhoto(ctx, model.Photo{
+		Title:    req.GetTitle(),
+		Caption:  req.GetCaption(),
+		PhotoUrl: req.GetPhotoUrl(),
+		UserId:   callerID(ctx),
+	})
+	if err != nil {
+		return nil, internalError(err)
+	}
+	return p.GetPhoto(ctx, &mygramv1.GetPhotoRequest{Id: created.ID})
+}
+
+func (p *photoServerImpl) UpdatePhoto(ctx context.Context, req *mygramv1.UpdatePhotoRequest) (*mygramv1.Photo, error) {
+	if err := requireVerifiedEmail(ctx, p.accountSvc); err != nil {
+		return nil, err
+	}
+	if err := requireID(req.GetId(), "photo"); err != nil {
+		return nil, err
+	}
+	if err := validate(model.PhotoUpdateReq{Title: req.GetTitle(), Caption: req.GetCaption(), PhotoUrl: req.GetPhotoUrl()}); err != nil {
+		return nil, err
+	}
+	if _, err := p.ownPhoto(ctx, req.GetId()); err != nil {
+		return nil, err
+	}
+
+	_, err := p.svc.EditPhoto(ctx, model.Photo{
+		ID:       req.GetId(),
+		Title:    req.GetTitle(),
+		Caption:  req.GetCaption(),
+		PhotoUrl: req.GetPhotoUrl(),
+		UserId:   callerID(ctx),
+	}, time.Time{})
+	if err != nil {
+		return nil, internalError(err)
+	}
+	return p.GetPhoto(ctx, &mygramv1.GetPhotoRequest{Id: req.GetId()})
+}
+
+func (p *photoServerImpl) DeletePhoto(ctx context.Context, req *mygramv1.DeletePhotoRequest) (*emptypb.Empty, error) {
+	if err := requireID(req.GetId(), "photo"); err != nil {
+		return nil, err
+	}
+	if _, err := p.ownPhoto(ctx, req.GetId()); err != nil {
+		return nil, err
+	}
+
+	if err := p.svc.DeletePhoto(ctx, req.GetId(), callerID(ctx)); err != nil {
+		return nil, internalError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (p *photoServerImpl) getPhoto(ctx context.Context, id uint64) (model.Photo, error) {
+	photo, err := p.svc.GetPhotoById(ctx, id, callerID(ctx))
+	if err != nil {
+		return model.Photo{}, internalError(err)
+	}
+	if photo.ID == 0 {
+		return model.Photo{}, status.Error(codes.NotFound, "photo not found")
+	}
+	return photo, nil
+}
+
+// ownPhoto is getPhoto for a photo of the caller.
+func (p *photoServerImpl) ownPhoto(ctx context.Context, id uint64) (model.Photo, error) {
+	photo, err := p.getPhoto(ctx, id)
+	if err != nil {
+		return model.Photo{}, err
+	}
+	if photo.UserId != callerID(ctx) {
+		return model.Photo{}, status.Error(codes.PermissionDenied, "invalid user request")
+	}
+	return photo, nil
+}
+
+func photoMessage(photo model.Photo) *mygramv1.Photo {
+	return &mygramv1.Photo{
+		Id:        photo.ID,
+		Title:     photo.Title,
+		Caption:   photo.Caption,
+		PhotoUrl:  photo.PhotoUrl,
+		UserId:    photo.UserId,
+		CreatedAt: timestamppb.New(photo.CreatedAt),
+		UpdatedAt: timestamppb.New(photo.UpdatedAt),
+	}
+}
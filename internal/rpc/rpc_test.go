@@ -0,0 +1,297 @@
+package rpc_test
+
+import (
+	"context"
+	"mygram/internal/apitest"
+	"mygram/internal/app"
+	"mygram/internal/model"
+	"mygram/internal/rpc"
+	mygramv1 "mygram/internal/rpc/mygram/v1"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+)
+
+type fixture struct {
+	h     *apitest.Harness
+	conn  *grpc.ClientConn
+	alice model.User
+	bob   model.User
+}
+
+func newFixture(t *testing.T) fixture {
+	t.Helper()
+
+	h := apitest.New()
+	return fixture{h: h, conn: h.GRPCConn(t), alice: h.CreateUser(t, "alice"), bob: h.CreateUser(t, "bob")}
+}
+
+// as sends the calls made with the returned context with token.
+func as(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func expectCode(t *testing.T, err error, want codes.Code) {
+	t.Helper()
+
+	if got := status.Code(err); got != want {
+		t.Fatalf("expected %s, got %v", want, err)
+	}
+}
+
+func TestCallsNeedAToken(t *testing.T) {
+	f := newFixture(t)
+	users := mygramv1.NewUserServiceClient(f.conn)
+
+	_, err := users.GetUser(context.Background(), &mygramv1.GetUserRequest{Id: f.alice.ID})
+	expectCode(t, err, codes.Unauthenticated)
+	_, err = users.GetUser(as("not-a-jwt"), &mygramv1.GetUserRequest{Id: f.alice.ID})
+	expectCode(t, err, codes.Unauthenticated)
+	_, err = users.GetUser(as(f.h.PublicToken(t)), &mygramv1.GetUserRequest{Id: f.alice.ID})
+	expectCode(t, err, codes.Unauthenticated)
+
+	user, err := users.GetUser(as(f.h.Token(t, f.bob)), &mygramv1.GetUserRequest{Id: f.alice.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.GetUsername() != "alice" {
+		t.Fatalf("expected alice, got %v", user)
+	}
+}
+
+func TestPhotoLifecycle(t *testing.T) {
+	f := newFixture(t)
+	photos := mygramv1.NewPhotoServiceClient(f.conn)
+	alice, bob := as(f.h.Token(t, f.alice)), as(f.h.Token(t, f.bob))
+
+	_, err := photos.CreatePhoto(alice, &mygramv1.CreatePhotoRequest{Title: "sunset"})
+	expectCode(t, err, codes.InvalidArgument)
+	created, err := photos.CreatePhoto(alice, &mygramv1.CreatePhotoRequest{Title: "sunset", Caption: "at the beach", PhotoUrl: "https://img.example.com/1.jpg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.GetUserId() != f.alice.ID || created.GetCreatedAt() == nil {
+		t.Fatalf("expected a photo of alice, got %v", created)
+	}
+
+	update := &mygramv1.UpdatePhotoRequest{Id: created.GetId(), Title: "dusk", Caption: "at the beach", PhotoUrl: "https://img.example.com/1.jpg"}
+	_, err = photos.UpdatePhoto(bob, update)
+	expectCode(t, err, codes.PermissionDenied)
+	updated, err := photos.UpdatePhoto(alice, update)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.GetTitle() != "dusk" {
+		t.Fatalf("expected the title updated, got %v", updated)
+	}
+
+	list, err := photos.ListPhotos(bob, &mygramv1.ListPhotosRequest{UserId: f.alice.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.GetPhotos()) != 1 || list.GetPhotos()[0].GetTitle() != "dusk" {
+		t.Fatalf("expected the photo listed, got %v", list)
+	}
+
+	_, err = photos.DeletePhoto(bob, &mygramv1.DeletePhotoRequest{Id: created.GetId()})
+	expectCode(t, err, codes.PermissionDenied)
+	if _, err := photos.DeletePhoto(alice, &mygramv1.DeletePhotoRequest{Id: created.GetId()}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = photos.GetPhoto(bob, &mygramv1.GetPhotoRequest{Id: created.GetId()})
+	expectCode(t, err, codes.NotFound)
+}
+
+func TestCommentsFollowTheRulesOfTheAPI(t *testing.T) {
+	f := newFixture(t)
+	photos := mygramv1.NewPhotoServiceClient(f.conn)
+	comments := mygramv1.NewCommentServiceClient(f.conn)
+	alice, bob := as(f.h.Token(t, f.alice)), as(f.h.Token(t, f.bob))
+
+	photo, err := photos.CreatePhoto(alice, &mygramv1.CreatePhotoRequest{Title: "sunset", Caption: "at the beach", PhotoUrl: "https://img.example.com/1.jpg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = comments.CreateComment(bob, &mygramv1.CreateCommentRequest{PhotoId: 99, Message: "nice"})
+	expectCode(t, err, codes.NotFound)
+	comment, err := comments.CreateComment(bob, &mygramv1.CreateCommentRequest{PhotoId: photo.GetId(), Message: "nice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.h.Services.Block.Block(context.Background(), f.bob.ID, f.alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	_, err = comments.UpdateComment(bob, &mygramv1.UpdateCommentRequest{Id: comment.GetId(), Message: "hi @alice"})
+	expectCode(t, err, codes.PermissionDenied)
+
+	unverified := as(f.h.Token(t, f.h.CreateUnverifiedUser(t, "carol")))
+	_, err = comments.CreateComment(unverified, &mygramv1.CreateCommentRequest{PhotoId: photo.GetId(), Message: "first"})
+	expectCode(t, err, codes.PermissionDenied)
+}
+
+func TestPersonalAccessTokensNeedTheScopeOfTheMethod(t *testing.T) {
+	f := newFixture(t)
+	pat, err := f.h.Services.PersonalAccessToken.CreateToken(context.Background(), f.alice.ID, model.PersonalAccessTokenCreateReq{Name: "backend", Scopes: []string{model.SCOPE_PROFILE_READ}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := mygramv1.NewUserServiceClient(f.conn).BatchGetUsers(as(pat.Token), &mygramv1.BatchGetUsersRequest{Ids: []uint64{f.bob.ID, f.alice.ID, 99}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.GetUsers()) != 2 || res.GetUsers()[0].GetId() != f.alice.ID {
+		t.Fatalf("expected alice then bob, got %v", res.GetUsers())
+	}
+	_, err = mygramv1.NewSocialMediaServiceClient(f.conn).ListSocialMedias(as(pat.Token), &mygramv1.ListSocialMediasRequest{UserId: f.alice.ID})
+	expectCode(t, err, codes.PermissionDenied)
+}
+
+func TestEveryMethodDeclaresScope(t *testing.T) {
+	h := apitest.New()
+
+	for name, info := range h.GRPC.GetServiceInfo() {
+		if !strings.HasPrefix(name, "mygram.") {
+			continue
+		}
+		for _, method := range info.Methods {
+			if _, ok := rpc.MethodScopes["/"+name+"/"+method.Name]; !ok {
+				t.Errorf("method %s/%s declares no scope", name, method.Name)
+			}
+		}
+	}
+}
+
+func TestHealthAndReflectionNeedNoToken(t *testing.T) {
+	conn := apitest.New(apitest.WithGRPCConfig(app.GRPCConfig{Reflection: true})).GRPCConn(t)
+	ctx := context.Background()
+
+	res, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: "mygram.v1.PhotoService"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("expected SERVING, got %s", res.GetStatus())
+	}
+
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = stream.Send(&reflectionpb.ServerReflectionRequest{MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	listed, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	services := []string{}
+	for _, s := range listed.GetListServicesResponse().GetService() {
+		services = append(services, s.GetName())
+	}
+	if !strings.Contains(strings.Join(services, " "), "mygram.v1.CommentService") {
+		t.Fatalf("expected the services listed, got %v", services)
+	}
+}
+
+func TestReflectionIsOffByDefault(t *testing.T) {
+	f := newFixture(t)
+
+	stream, err := reflectionpb.NewServerReflectionClient(f.conn).ServerReflectionInfo(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = stream.Send(&reflectionpb.ServerReflectionRequest{MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = stream.Recv()
+	expectCode(t, err, codes.Unimplemented)
+}
+
+func TestUserLifecycle(t *testing.T) {
+	f := newFixture(t)
+	users := mygramv1.NewUserServiceClient(f.conn)
+	ctx := context.Background()
+
+	_, err := users.SignUp(ctx, &mygramv1.SignUpRequest{Username: "carol", Email: "carol@example.com", Password: "correct horse battery staple", Dob: "yesterday"})
+	expectCode(t, err, codes.InvalidArgument)
+	created, err := users.SignUp(ctx, &mygramv1.SignUpRequest{Username: "carol", Email: "carol@example.com", Password: "correct horse battery staple", Dob: "1990-01-01"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.GetId() == 0 || created.GetUsername() != "carol" {
+		t.Fatalf("expected carol signed up, got %v", created)
+	}
+	if len(f.h.Outbox.Messages("carol@example.com")) != 1 {
+		t.Fatalf("expected a verification email, got %d", len(f.h.Outbox.Messages("carol@example.com")))
+	}
+
+	// personal access tokens can edit the username, not the email
+	pat, err := f.h.Services.PersonalAccessToken.CreateToken(ctx, f.alice.ID, model.PersonalAccessTokenCreateReq{Name: "backend", Scopes: []string{model.SCOPE_PROFILE_WRITE}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	edited, err := users.EditUser(as(pat.Token), &mygramv1.EditUserRequest{Username: "alicia", Email: f.alice.Email})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited.GetUsername() != "alicia" {
+		t.Fatalf("expected the username edited, got %v", edited)
+	}
+	_, err = users.EditUser(as(pat.Token), &mygramv1.EditUserRequest{Username: "alicia", Email: "alicia@example.com"})
+	expectCode(t, err, codes.PermissionDenied)
+
+	alice := as(f.h.Token(t, f.alice))
+	if _, err := users.EditUser(alice, &mygramv1.EditUserRequest{Username: "alicia", Email: "alicia@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	verified, err := f.h.Services.Account.IsEmailVerified(ctx, f.alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verified {
+		t.Fatal("expected the new email to need confirming")
+	}
+
+	// deleting needs a login session
+	_, err = users.DeleteUser(as(pat.Token), &mygramv1.DeleteUserRequest{})
+	expectCode(t, err, codes.PermissionDenied)
+	if _, err := users.DeleteUser(alice, &mygramv1.DeleteUserRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = users.GetUser(as(f.h.Token(t, f.bob)), &mygramv1.GetUserRequest{Id: f.alice.ID})
+	expectCode(t, err, codes.NotFound)
+}
+
+func TestUsersOnlySeeTheirOwnEmail(t *testing.T) {
+	f := newFixture(t)
+	users := mygramv1.NewUserServiceClient(f.conn)
+	bob := as(f.h.Token(t, f.bob))
+
+	alice, err := users.GetUser(bob, &mygramv1.GetUserRequest{Id: f.alice.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alice.GetEmail() != "" {
+		t.Fatalf("expected the email of alice left out, got %v", alice)
+	}
+
+	res, err := users.BatchGetUsers(bob, &mygramv1.BatchGetUsersRequest{Ids: []uint64{f.alice.ID, f.bob.ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, user := range res.GetUsers() {
+		if own := user.GetId() == f.bob.ID; own != (user.GetEmail() != "") {
+			t.Errorf("expected only the email of bob, got %v", user)
+		}
+	}
+}
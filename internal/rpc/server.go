@@ -0,0 +1,60 @@
+package rpc
+
+import (
+	"context"
+	"mygram/internal/service"
+
+	"github.com/go-playground/validator/v10"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// maxBatchSize bounds the IDs of a batch get.
+const maxBatchSize = 100
+
+// RegisterHealth serves grpc.health.v1, reporting every service registered
+// on s so far, and the server as a whole, as serving.
+func RegisterHealth(s *grpc.Server) *health.Server {
+	h := health.NewServer()
+	for name := range s.GetServiceInfo() {
+		h.SetServingStatus(name, healthpb.HealthCheckResponse_SERVING)
+	}
+	h.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(s, h)
+	return h
+}
+
+func internalError(err error) error {
+	return status.Error(codes.Internal, err.Error())
+}
+
+// validate checks req with its validate tags, as the handlers do bodies.
+func validate(req any) error {
+	if err := validator.New().Struct(req); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return nil
+}
+
+func requireID(id uint64, name string) error {
+	if id == 0 {
+		return status.Errorf(codes.InvalidArgument, "invalid %s ID", name)
+	}
+	return nil
+}
+
+// requireVerifiedEmail is middleware.RequireVerifiedEmail for the calls
+// that create and edit.
+func requireVerifiedEmail(ctx context.Context, accountSvc service.AccountService) error {
+	verified, err := accountSvc.IsEmailVerified(ctx, callerID(ctx))
+	if err != nil {
+		return internalError(err)
+	}
+	if !verified {
+		return status.Error(codes.PermissionDenied, "confirm your email address before posting")
+	}
+	return nil
+}
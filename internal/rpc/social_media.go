@@ -0,0 +1,152 @@
+package rpc
+
+import (
+	"context"
+	"mygram/internal/model"
+	mygramv1 "mygram/internal/rpc/mygram/v1"
+	"mygram/internal/service"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type socialMediaServerImpl struct {
+	mygramv1.UnimplementedSocialMediaServiceServer
+	svc        service.SocialMediaService
+	accountSvc service.AccountService
+}
+
+func NewSocialMediaServer(svc service.SocialMediaService, accountSvc service.AccountService) mygramv1.SocialMediaServiceServer {
+	return &socialMediaServerImpl{svc: svc, accountSvc: accountSvc}
+}
+
+func (s *socialMediaServerImpl) GetSocialMedia(ctx context.Context, req *mygramv1.GetSocialMediaRequest) (*mygramv1.SocialMedia, error) {
+	if err := requireID(req.GetId(), "social media"); err != nil {
+		return nil, err
+	}
+	social, err := s.getSocialMedia(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return socialMediaMessage(social), nil
+}
+
+func (s *socialMediaServerImpl) ListSocialMedias(ctx context.Context, req *mygramv1.ListSocialMediasRequest) (*mygramv1.ListSocialMediasResponse, error) {
+	if err := requireID(req.GetUserId(), "user"); err != nil {
+		return nil, err
+	}
+
+	socials, err := s.svc.GetSocialMediasByUserIds(ctx, []uint64{req.GetUserId()}, callerID(ctx))
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	res := &mygramv1.ListSocialMediasResponse{SocialMedias: make([]*mygramv1.SocialMedia, len(socials))}
+	for i, social := range socials {
+		res.SocialMedias[i] = socialMediaMessage(model.SocialMedia{
+			ID:             social.ID,
+			Name:           social.Name,
+			SocialMediaUrl: social.SocialMediaUrl,
+			UserId:         social.UserId,
+			CreatedAt:      social.CreatedAt,
+			UpdatedAt:      social.UpdatedAt,
+		})
+	}
+	return res, nil
+}
+
+func (s *socialMediaServerImpl) CreateSocialMedia(ctx context.Context, req *mygramv1.CreateSocialMediaRequest) (*mygramv1.SocialMedia, error) {
+	if err := requireVerifiedEmail(ctx, s.accountSvc); err != nil {
+		return nil, err
+	}
+	if err := validate(model.SocialMediaReq{Name: req.GetName(), SocialMediaUrl: req.GetSocialMediaUrl()}); err != nil {
+		return nil, err
+	}
+
+	created, err := s.svc.CreateSocialMedia(ctx, model.SocialMedia{
+		Name:           req.GetName(),
+		SocialMediaUrl: req.GetSocialMediaUrl(),
+		UserId:         callerID(ctx),
+	})
+	if err != nil {
+		return nil, internalError(err)
+	}
+	return s.GetSocialMedia(ctx, &mygramv1.GetSocialMediaRequest{Id: created.ID})
+}
+
+func (s *socialMediaServerImpl) UpdateSocialMedia(ctx context.Context, req *mygramv1.UpdateSocialMediaRequest) (*mygramv1.SocialMedia, error) {
+	if err := requireVerifiedEmail(ctx, s.accountSvc); err != nil {
+		return nil, err
+	}
+	if err := requireID(req.GetId(), "social media"); err != nil {
+		return nil, err
+	}
+	if err := validate(model.SocialMediaReq{Name: req.GetName(), SocialMediaUrl: req.GetSocialMediaUrl()}); err != nil {
+		return nil, err
+	}
+	if _, err := s.ownSocialMedia(ctx, req.GetId()); err != nil {
+		return nil, err
+	}
+
+	_, err := s.svc.EditSocialMedia(ctx, model.SocialMedia{
+		ID:             req.GetId(),
+		Name:           req.GetName(),
+		SocialMediaUrl: req.GetSocialMediaUrl(),
+		UserId:         callerID(ctx),
+	}, time.Time{})
+	if err != nil {
+		return nil, internalError(err)
+	}
+	return s.GetSocialMedia(ctx, &mygramv1.GetSocialMediaRequest{Id: req.GetId()})
+}
+
+func (s *socialMediaServerImpl) DeleteSocialMedia(ctx context.Context, req *mygramv1.DeleteSocialMediaRequest) (*emptypb.Empty, error) {
+	if err := requireID(req.GetId(), "social media"); err != nil {
+		return nil, err
+	}
+	if _, err := s.ownSocialMedia(ctx, req.GetId()); err != nil {
+		return nil, err
+	}
+
+	if err := s.svc.DeleteSocialMedia(ctx, req.GetId(), callerID(ctx)); err != nil {
+		return nil, internalError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *socialMediaServerImpl) getSocialMedia(ctx context.Context, id uint64) (model.SocialMedia, error) {
+	social, err := s.svc.GetSocialMediaById(ctx, id, callerID(ctx))
+	if err != nil {
+		return model.SocialMedia{}, internalError(err)
+	}
+	if social.ID == 0 {
+		return model.SocialMedia{}, status.Error(codes.NotFound, "social media not found")
+	}
+	return social, nil
+}
+
+// ownSocialMedia is getSocialMedia for a social media of the caller.
+func (s *socialMediaServerImpl) ownSocialMedia(ctx context.Context, id uint64) (model.SocialMedia, error) {
+	social, err := s.getSocialMedia(ctx, id)
+	if err != nil {
+		return model.SocialMedia{}, err
+	}
+	if social.UserId != callerID(ctx) {
+		return model.SocialMedia{}, status.Error(codes.PermissionDenied, "invalid user request")
+	}
+	return social, nil
+}
+
+func socialMediaMessage(social model.SocialMedia) *mygramv1.SocialMedia {
+	return &mygramv1.SocialMedia{
+		Id:             social.ID,
+		Name:           social.Name,
+		SocialMediaUrl: social.SocialMediaUrl,
+		UserId:         social.UserId,
+		CreatedAt:      timestamppb.New(social.CreatedAt),
+		UpdatedAt:      timestamppb.New(social.UpdatedAt),
+	}
+}
@@ -0,0 +1,158 @@
+package rpc
+
+import (
+	"cmp"
+	"context"
+	"log"
+	"mygram/internal/mailer"
+	"mygram/internal/model"
+	"mygram/internal/password"
+	mygramv1 "mygram/internal/rpc/mygram/v1"
+	"mygram/internal/service"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type userServerImpl struct {
+	mygramv1.UnimplementedUserServiceServer
+	svc        service.UserService
+	accountSvc service.AccountService
+}
+
+func NewUserServer(svc service.UserService, accountSvc service.AccountService) mygramv1.UserServiceServer {
+	return &userServerImpl{svc: svc, accountSvc: accountSvc}
+}
+
+func (u *userServerImpl) GetUser(ctx context.Context, req *mygramv1.GetUserRequest) (*mygramv1.User, error) {
+	if err := requireID(req.GetId(), "user"); err != nil {
+		return nil, err
+	}
+
+	users, err := u.svc.GetUsersByIds(ctx, []uint64{req.GetId()}, callerID(ctx))
+	if err != nil {
+		return nil, internalError(err)
+	}
+	if len(users) == 0 {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	return userMessage(users[0], callerID(ctx)), nil
+}
+
+func (u *userServerImpl) BatchGetUsers(ctx context.Context, req *mygramv1.BatchGetUsersRequest) (*mygramv1.BatchGetUsersResponse, error) {
+	if len(req.GetIds()) > maxBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d IDs can be read at once", maxBatchSize)
+	}
+
+	users, err := u.svc.GetUsersByIds(ctx, req.GetIds(), callerID(ctx))
+	if err != nil {
+		return nil, internalError(err)
+	}
+	slices.SortFunc(users, func(a, b model.User) int { return cmp.Compare(a.ID, b.ID) })
+
+	res := &mygramv1.BatchGetUsersResponse{Users: make([]*mygramv1.User, len(users))}
+	for i, user := range users {
+		res.Users[i] = userMessage(user, callerID(ctx))
+	}
+	return res, nil
+}
+
+func (u *userServerImpl) SignUp(ctx context.Context, req *mygramv1.SignUpRequest) (*mygramv1.User, error) {
+	userSignUp := model.UserSignUp{
+		Username: req.GetUsername(),
+		Email:    req.GetEmail(),
+		Password: req.GetPassword(),
+		DoB:      req.GetDob(),
+	}
+	if err := userSignUp.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	user, err := u.svc.SignUp(ctx, userSignUp)
+	if password.IsPolicyViolation(err) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	// the account exists either way, a failed email can be resent
+	if err := u.accountSvc.SendEmailVerification(ctx, user, locale(ctx)); err != nil {
+		log.Printf("send verification email to user %d: %v", user.ID, err)
+	}
+	// the account is the caller's own
+	return userMessage(user, user.ID), nil
+}
+
+func (u *userServerImpl) EditUser(ctx context.Context, req *mygramv1.EditUserRequest) (*mygramv1.User, error) {
+	if err := validate(model.UserEditReq{Email: req.GetEmail(), Username: req.GetUsername()}); err != nil {
+		return nil, err
+	}
+	current, err := u.svc.GetUsersById(ctx, callerID(ctx))
+	if err != nil {
+		return nil, internalError(err)
+	}
+	if current.ID == 0 {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+
+	// the email is where password resets go, which makes changing it taking
+	// over the account, so personal access tokens cannot
+	user := model.User{ID: current.ID, Username: req.GetUsername(), Email: req.GetEmail()}
+	if user.Email != current.Email {
+		if err := requireSession(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := u.svc.EditUser(ctx, user, time.Time{}); err != nil {
+		return nil, internalError(err)
+	}
+
+	// the edit stands either way, a failed email can be resent
+	if user.Email != current.Email {
+		if err := u.accountSvc.SendEmailVerification(ctx, user, locale(ctx)); err != nil {
+			log.Printf("send verification email to user %d: %v", user.ID, err)
+		}
+	}
+	return u.GetUser(ctx, &mygramv1.GetUserRequest{Id: user.ID})
+}
+
+func (u *userServerImpl) DeleteUser(ctx context.Context, req *mygramv1.DeleteUserRequest) (*emptypb.Empty, error) {
+	user, err := u.svc.DeleteUsersById(ctx, callerID(ctx))
+	if err != nil {
+		return nil, internalError(err)
+	}
+	if user.ID == 0 {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// locale is the locale of the emails of a call, from its accept-language
+// metadata.
+func locale(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return mailer.Locale(strings.Join(md.Get("accept-language"), ","))
+}
+
+// userMessage is user as callerId sees them, the email only being the
+// caller's own.
+func userMessage(user model.User, callerId uint64) *mygramv1.User {
+	res := &mygramv1.User{
+		Id:        user.ID,
+		Username:  user.Username,
+		Private:   user.Private,
+		CreatedAt: timestamppb.New(user.CreatedAt),
+	}
+	if user.ID == callerId {
+		res.Email = user.Email
+	}
+	return res
+}
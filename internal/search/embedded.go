@@ -0,0 +1,183 @@
+package search
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"mygram/internal/model"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Weights of the fields of a photo, the defaults of ts_rank for the A and
+// B labels PostgresIndex gives titles and captions.
+const (
+	titleWeight   = 1.0
+	captionWeight = 0.4
+)
+
+type field struct {
+	terms  []string
+	weight float64
+}
+
+type userDoc struct {
+	username string
+	terms    []string
+}
+
+// EmbeddedIndex matches the way PostgresIndex does, in process. It only
+// knows what was indexed through it since it was created, and it does not
+// roll back with a failed transaction, callers index last.
+type EmbeddedIndex struct {
+	mu     sync.RWMutex
+	photos map[uint64][]field
+	tags   map[uint64][]string
+	users  map[uint64]userDoc
+}
+
+func NewEmbeddedIndex() *EmbeddedIndex {
+	return &EmbeddedIndex{
+		photos: map[uint64][]field{},
+		tags:   map[uint64][]string{},
+		users:  map[uint64]userDoc{},
+	}
+}
+
+func (e *EmbeddedIndex) IndexPhoto(ctx context.Context, photo model.Photo) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.photos[photo.ID] = []field{
+		{terms: Terms(photo.Title), weight: titleWeight},
+		{terms: Terms(photo.Caption), weight: captionWeight},
+	}
+	e.tags[photo.ID] = Hashtags(photo.Title + " " + photo.Caption)
+	return nil
+}
+
+func (e *EmbeddedIndex) RemovePhoto(ctx context.Context, id uint64) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	delete(e.photos, id)
+	delete(e.tags, id)
+	return nil
+}
+
+func (e *EmbeddedIndex) IndexUser(ctx context.Context, user model.User) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.users[user.ID] = userDoc{username: Normalize(user.Username), terms: Terms(user.Username)}
+	return nil
+}
+
+func (e *EmbeddedIndex) RemoveUser(ctx context.Context, id uint64) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	delete(e.users, id)
+	return nil
+}
+
+func (e *EmbeddedIndex) Search(ctx context.Context, query Query) ([]Hit, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	hits := []Hit{}
+	switch query.Type {
+	case model.SEARCH_TYPE_PHOTOS:
+		terms := Terms(query.Text)
+		for id, fields := range e.photos {
+			if rank, ok := rankFields(terms, fields); ok {
+				hits = append(hits, Hit{ID: id, Rank: rank})
+			}
+		}
+	case model.SEARCH_TYPE_USERS:
+		terms := Terms(query.Text)
+		exact := Normalize(strings.TrimSpace(query.Text))
+		for id, user := range e.users {
+			rank, ok := rankFields(terms, []field{{terms: user.terms, weight: titleWeight}})
+			if !ok {
+				continue
+			}
+			if user.username == exact {
+				rank++
+			}
+			hits = append(hits, Hit{ID: id, Rank: rank})
+		}
+	case model.SEARCH_TYPE_HASHTAGS:
+		return e.searchHashtags(hashtagPrefix(query.Text), query.Limit), nil
+	default:
+		return nil, fmt.Errorf("unknown search type %q", query.Type)
+	}
+
+	slices.SortFunc(hits, func(a, b Hit) int {
+		if c := cmp.Compare(b.Rank, a.Rank); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.ID, a.ID)
+	})
+	return limit(hits, query.Limit), nil
+}
+
+func (e *EmbeddedIndex) searchHashtags(prefix string, n int) []Hit {
+	if prefix == "" {
+		return []Hit{}
+	}
+
+	counts := map[string]int{}
+	for _, tags := range e.tags {
+		for _, tag := range tags {
+			if strings.HasPrefix(tag, prefix) {
+				counts[tag]++
+			}
+		}
+	}
+
+	hits := make([]Hit, 0, len(counts))
+	for tag, count := range counts {
+		hits = append(hits, Hit{Tag: tag, Count: count, Rank: float64(count)})
+	}
+	slices.SortFunc(hits, func(a, b Hit) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Tag, b.Tag)
+	})
+	return limit(hits, n)
+}
+
+// rankFields matches every query term as a prefix of a term of the fields,
+// adding up the weight of the best field for each.
+func rankFields(terms []string, fields []field) (float64, bool) {
+	if len(terms) == 0 {
+		return 0, false
+	}
+
+	rank := 0.0
+	for _, term := range terms {
+		best := 0.0
+		for _, f := range fields {
+			if f.weight > best && slices.ContainsFunc(f.terms, func(t string) bool {
+				return strings.HasPrefix(t, term)
+			}) {
+				best = f.weight
+			}
+		}
+		if best == 0 {
+			return 0, false
+		}
+		rank += best
+	}
+	return rank, true
+}
+
+func limit(hits []Hit, n int) []Hit {
+	if n > 0 && len(hits) > n {
+		return hits[:n]
+	}
+	return hits
+}
@@ -0,0 +1,150 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"mygram/internal/infrastructure"
+	"mygram/internal/model"
+	"mygram/internal/repository"
+	"strings"
+)
+
+// photoVector weighs titles above captions. unaccent needs the extension of
+// the same name, see migration/009_search.sql.
+const photoVector = `setweight(to_tsvector('simple', unaccent(?)), 'A') || setweight(to_tsvector('simple', unaccent(?)), 'B')`
+
+// PostgresIndex stores photos.search_vector, users.search_vector and
+// photo_hashtags. It writes through the connection of the running
+// transaction, so the index commits or rolls back with the rows.
+type PostgresIndex struct {
+	db infrastructure.GormPostgres
+}
+
+func NewPostgresIndex(db infrastructure.GormPostgres) *PostgresIndex {
+	return &PostgresIndex{db: db}
+}
+
+func (p *PostgresIndex) IndexPhoto(ctx context.Context, photo model.Photo) error {
+	ctx, span := tracer.Start(ctx, "PostgresIndex.IndexPhoto")
+	defer span.End()
+
+	db := p.db.GetWriteConnection(ctx).WithContext(ctx)
+	if err := db.
+		Exec(`UPDATE photos SET search_vector = `+photoVector+` WHERE id = ?`, photo.Title, photo.Caption, photo.ID).
+		Error; err != nil {
+		return err
+	}
+
+	if err := db.Exec(`DELETE FROM photo_hashtags WHERE photo_id = ?`, photo.ID).Error; err != nil {
+		return err
+	}
+	for _, tag := range Hashtags(photo.Title + " " + photo.Caption) {
+		if err := db.Exec(`INSERT INTO photo_hashtags (photo_id, tag) VALUES (?, ?)`, photo.ID, tag).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *PostgresIndex) RemovePhoto(ctx context.Context, id uint64) error {
+	ctx, span := tracer.Start(ctx, "PostgresIndex.RemovePhoto")
+	defer span.End()
+
+	db := p.db.GetWriteConnection(ctx).WithContext(ctx)
+	if err := db.Exec(`UPDATE photos SET search_vector = NULL WHERE id = ?`, id).Error; err != nil {
+		return err
+	}
+	return db.Exec(`DELETE FROM photo_hashtags WHERE photo_id = ?`, id).Error
+}
+
+func (p *PostgresIndex) IndexUser(ctx context.Context, user model.User) error {
+	ctx, span := tracer.Start(ctx, "PostgresIndex.IndexUser")
+	defer span.End()
+
+	db := p.db.GetWriteConnection(ctx).WithContext(ctx)
+	return db.
+		Exec(`UPDATE users SET search_vector = to_tsvector('simple', unaccent(?)) WHERE id = ?`, user.Username, user.ID).
+		Error
+}
+
+func (p *PostgresIndex) RemoveUser(ctx context.Context, id uint64) error {
+	ctx, span := tracer.Start(ctx, "PostgresIndex.RemoveUser")
+	defer span.End()
+
+	db := p.db.GetWriteConnection(ctx).WithContext(ctx)
+	return db.Exec(`UPDATE users SET search_vector = NULL WHERE id = ?`, id).Error
+}
+
+func (p *PostgresIndex) Search(ctx context.Context, query Query) ([]Hit, error) {
+	ctx, span := tracer.Start(ctx, "PostgresIndex.Search")
+	defer span.End()
+
+	db := p.db.GetReadConnection(ctx).WithContext(ctx)
+	hits := []Hit{}
+
+	if query.Type == model.SEARCH_TYPE_HASHTAGS {
+		prefix := hashtagPrefix(query.Text)
+		if prefix == "" {
+			return hits, nil
+		}
+		// counts only take in public accounts, what a tag is used for
+		// elsewhere is nobody's business
+		err := db.Raw(`
+			SELECT h.tag, COUNT(*) AS count, COUNT(*) AS rank
+			FROM photo_hashtags h
+			JOIN photos p ON p.id = h.photo_id AND p.deleted_at IS NULL
+			JOIN users u ON u.id = p.user_id AND u.deleted_at IS NULL AND u.private = false
+			WHERE h.tag LIKE ? ESCAPE '\' AND `+repository.NotBlocked("u.id")+`
+			GROUP BY h.tag
+			ORDER BY count DESC, h.tag ASC
+			LIMIT ?`, escapeLike(prefix)+"%", query.Limit, repository.Viewer(query.ViewerId)).
+			Scan(&hits).Error
+		return hits, err
+	}
+
+	tsquery := prefixQuery(Terms(query.Text))
+	if tsquery == "" {
+		return hits, nil
+	}
+
+	var sql string
+	var args []any
+	switch query.Type {
+	case model.SEARCH_TYPE_PHOTOS:
+		sql = `
+			SELECT id, ts_rank(search_vector, q) AS rank
+			FROM photos, to_tsquery('simple', ?) q
+			WHERE deleted_at IS NULL AND search_vector @@ q AND ` + repository.VisibleOwner("photos.user_id") + `
+			ORDER BY rank DESC, id DESC
+			LIMIT ?`
+		args = []any{tsquery}
+	case model.SEARCH_TYPE_USERS:
+		// whoever types a whole username means that user
+		sql = `
+			SELECT id, ts_rank(search_vector, q) + CASE WHEN lower(unaccent(username)) = ? THEN 1 ELSE 0 END AS rank
+			FROM users, to_tsquery('simple', ?) q
+			WHERE deleted_at IS NULL AND search_vector @@ q AND ` + repository.NotBlocked("users.id") + `
+			ORDER BY rank DESC, id DESC
+			LIMIT ?`
+		args = []any{Normalize(strings.TrimSpace(query.Text)), tsquery}
+	default:
+		return nil, fmt.Errorf("unknown search type %q", query.Type)
+	}
+
+	err := db.Raw(sql, append(args, query.Limit, repository.Viewer(query.ViewerId))...).Scan(&hits).Error
+	return hits, err
+}
+
+// prefixQuery builds a to_tsquery matching every term as a prefix. Terms
+// only hold letters and digits, nothing tsquery would read as an operator.
+func prefixQuery(terms []string) string {
+	parts := make([]string, 0, len(terms))
+	for _, term := range terms {
+		parts = append(parts, term+":*")
+	}
+	return strings.Join(parts, " & ")
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
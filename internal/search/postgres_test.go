@@ -0,0 +1,62 @@
+package search_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"mygram/internal/infrastructure"
+	"mygram/internal/model"
+	"mygram/internal/search"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// TestPostgresSearchFiltersBeforeTheLimit checks what the viewer may not see
+// is left out by the query itself, so a page of private or blocked matches
+// does not come back empty.
+func TestPostgresSearchFiltersBeforeTheLimit(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := search.NewPostgresIndex(infrastructure.NewGormPostgresFromDB(db))
+
+	for _, tc := range []struct {
+		query search.Query
+		sql   string
+		args  []driver.Value
+	}{
+		{
+			query: search.Query{Type: model.SEARCH_TYPE_PHOTOS, Text: "sun", Limit: 10, ViewerId: 7},
+			sql:   `FROM photos.*photos\.user_id IN \(.*v\.private = false.*OR v\.id = \$2.*blocks b.*LIMIT \$6`,
+			args:  []driver.Value{"sun:*", 7, 7, 7, 7, 10},
+		},
+		{
+			query: search.Query{Type: model.SEARCH_TYPE_USERS, Text: "al", Limit: 10, ViewerId: 7},
+			sql:   `FROM users.*NOT EXISTS \(.*blocks b.*b\.blocked_id = \$3.*LIMIT \$5`,
+			args:  []driver.Value{"al", "al:*", 7, 7, 10},
+		},
+		{
+			query: search.Query{Type: model.SEARCH_TYPE_HASHTAGS, Text: "#be", Limit: 10, ViewerId: 7},
+			sql:   `JOIN users u ON u\.id = p\.user_id AND u\.deleted_at IS NULL AND u\.private = false.*NOT EXISTS \(.*blocks b.*LIMIT \$4`,
+			args:  []driver.Value{"be%", 7, 7, 10},
+		},
+	} {
+		mock.ExpectQuery(tc.sql).
+			WithArgs(tc.args...).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "rank"}))
+		if _, err := index.Search(context.Background(), tc.query); err != nil {
+			t.Fatalf("%s: %v", tc.query.Type, err)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,95 @@
+// Package search finds photos, users and hashtags by text, with prefix
+// matching for type-ahead. The Postgres index keeps tsvector columns next
+// to the rows, the embedded index keeps everything in process for a single
+// instance and for tests.
+package search
+
+import (
+	"context"
+	"mygram/internal/model"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Index is kept in sync by the services writing photos and users. Removed
+// documents stop matching right away, whatever the soft delete leaves in
+// the tables.
+type Index interface {
+	IndexPhoto(ctx context.Context, photo model.Photo) error
+	RemovePhoto(ctx context.Context, id uint64) error
+	IndexUser(ctx context.Context, user model.User) error
+	RemoveUser(ctx context.Context, id uint64) error
+
+	// Search returns the best hits first. The Postgres index leaves out
+	// what Query.ViewerId may not see before the limit, the embedded index
+	// knows nothing of users and callers drop it afterwards.
+	Search(ctx context.Context, query Query) ([]Hit, error)
+}
+
+type Query struct {
+	// Type is one of model.SEARCH_TYPE_*.
+	Type string
+	// Text is matched word by word, each word as a prefix for type-ahead.
+	Text  string
+	Limit int
+	// ViewerId is who is searching, 0 for guests.
+	ViewerId uint64
+}
+
+// Hit is a photo or user ID, or a hashtag with the number of photos using
+// it.
+type Hit struct {
+	ID    uint64
+	Tag   string
+	Count int
+	Rank  float64
+}
+
+// maxHashtagLength matches photo_hashtags.tag, longer tags are not indexed.
+const maxHashtagLength = 100
+
+var (
+	termPattern    = regexp.MustCompile(`[\pL\pN]+`)
+	hashtagPattern = regexp.MustCompile(`#([\pL\pN_]+)`)
+)
+
+// Normalize lowercases and strips accents, so "Café" and "cafe" match.
+func Normalize(text string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	s, _, err := transform.String(t, text)
+	if err != nil {
+		s = text
+	}
+	return strings.ToLower(s)
+}
+
+// Terms splits text into normalized words, splitting on punctuation the way
+// the Postgres simple parser does.
+func Terms(text string) []string {
+	return termPattern.FindAllString(Normalize(text), -1)
+}
+
+// Hashtags returns the distinct normalized hashtags in text, without #.
+func Hashtags(text string) []string {
+	tags := []string{}
+	seen := map[string]bool{}
+	for _, match := range hashtagPattern.FindAllStringSubmatch(text, -1) {
+		tag := Normalize(match[1])
+		if !seen[tag] && utf8.RuneCountInString(tag) <= maxHashtagLength {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// hashtagPrefix is what a hashtag query matches tags against.
+func hashtagPrefix(text string) string {
+	return Normalize(strings.TrimPrefix(strings.TrimSpace(text), "#"))
+}
@@ -0,0 +1,5 @@
+package search
+
+import "go.opentelemetry.io/otel"
+
+var tracer = otel.Tracer("mygram/internal/search")
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"mygram/internal/infrastructure"
+	"mygram/internal/mailer"
+	"mygram/internal/model"
+	"mygram/internal/password"
+	"mygram/internal/repository"
+	"net/url"
+	"time"
+)
+
+// AccountService covers the flows driven by tokens mailed to the user:
+// confirming the email address and resetting a forgotten password.
+type AccountService interface {
+	SendEmailVerification(ctx context.Context, user model.User, locale string) error
+	ResendEmailVerification(ctx context.Context, email string, locale string) error
+	VerifyEmail(ctx context.Context, token string) error
+	IsEmailVerified(ctx context.Context, userId uint64) (bool, error)
+
+	ForgotPassword(ctx context.Context, email string, locale string) error
+	// ResetPassword also signs the user out everywhere and revokes their
+	// personal access tokens.
+	ResetPassword(ctx context.Context, token string, password string) error
+}
+
+// ErrInvalidToken is returned for unknown, expired and already used tokens
+// alike.
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+type AccountConfig struct {
+	// BaseURL is where the links in emails point to, the frontend reads the
+	// token from the query and posts it back to the API.
+	BaseURL          string
+	VerificationTTL  time.Duration
+	PasswordResetTTL time.Duration
+}
+
+func DefaultAccountConfig() AccountConfig {
+	return AccountConfig{
+		BaseURL:          "http://localhost:3000",
+		VerificationTTL:  24 * time.Hour,
+		PasswordResetTTL: time.Hour,
+	}
+}
+
+type accountServiceImpl struct {
+	users     repository.UserQuery
+	tokens    repository.UserTokenQuery
+	sessions  repository.SessionQuery
+	pats      repository.PersonalAccessTokenQuery
+	uow       infrastructure.UnitOfWork
+	mailer    mailer.Mailer
+	cfg       AccountConfig
+	passwords password.Hasher
+	policy    password.Policy
+}
+
+func NewAccountService(users repository.UserQuery, tokens repository.UserTokenQuery, sessions repository.SessionQuery, pats repository.PersonalAccessTokenQuery, uow infrastructure.UnitOfWork, m mailer.Mailer, cfg AccountConfig, passwords password.Hasher, policy password.Policy) AccountService {
+	return &accountServiceImpl{users: users, tokens: tokens, sessions: sessions, pats: pats, uow: uow, mailer: m, cfg: cfg, passwords: passwords, policy: policy}
+}
+
+type tokenMail struct {
+	Username       string
+	Link           string
+	ExpiresInHours int
+}
+
+func (a *accountServiceImpl) SendEmailVerification(ctx context.Context, user model.User, locale string) error {
+	ctx, span := tracer.Start(ctx, "AccountService.SendEmailVerification")
+	defer span.End()
+
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+	return a.sendToken(ctx, user, model.TOKEN_PURPOSE_VERIFY_EMAIL, a.cfg.VerificationTTL, "/verify-email", mailer.TemplateVerifyEmail, locale)
+}
+
+// ResendEmailVerification does nothing for unknown or already verified
+// addresses, callers answer the same either way.
+func (a *accountServiceImpl) ResendEmailVerification(ctx context.Context, email string, locale string) error {
+	ctx, span := tracer.Start(ctx, "AccountService.ResendEmailVerification")
+	defer span.End()
+
+	user, err := a.users.GetUserByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if user.ID == 0 {
+		return nil
+	}
+	return a.SendEmailVerification(ctx, user, locale)
+}
+
+func (a *accountServiceImpl) VerifyEmail(ctx context.Context, token string) error {
+	ctx, span := tracer.Start(ctx, "AccountService.VerifyEmail")
+	defer span.End()
+
+	return a.uow.Do(ctx, func(ctx context.Context) error {
+		userToken, err := a.useToken(ctx, model.TOKEN_PURPOSE_VERIFY_EMAIL, token)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		return a.users.EditUser(ctx, model.User{ID: userToken.UserId, EmailVerifiedAt: &now})
+	})
+}
+
+func (a *accountServiceImpl) IsEmailVerified(ctx context.Context, userId uint64) (bool, error) {
+	ctx, span := tracer.Start(ctx, "AccountService.IsEmailVerified")
+	defer span.End()
+
+	user, err := a.users.GetUsersByID(ctx, userId)
+	if err != nil {
+		return false, err
+	}
+	return user.EmailVerifiedAt != nil, nil
+}
+
+// ForgotPassword does nothing for unknown addresses, callers answer the same
+// either way.
+func (a *accountServiceImpl) ForgotPassword(ctx context.Context, email string, locale string) error {
+	ctx, span := tracer.Start(ctx, "AccountService.ForgotPassword")
+	defer span.End()
+
+	user, err := a.users.GetUserByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if user.ID == 0 {
+		return nil
+	}
+	return a.sendToken(ctx, user, model.TOKEN_PURPOSE_PASSWORD_RESET, a.cfg.PasswordResetTTL, "/reset-password", mailer.TemplatePasswordReset, locale)
+}
+
+func (a *accountServiceImpl) ResetPassword(ctx context.Context, token string, plain string) error {
+	ctx, span := tracer.Start(ctx, "AccountService.ResetPassword")
+	defer span.End()
+
+	if err := a.policy.Check(plain); err != nil {
+		return err
+	}
+	hash, err := a.passwords.Hash(plain)
+	if err != nil {
+		return err
+	}
+
+	return a.uow.Do(ctx, func(ctx context.Context) error {
+		userToken, err := a.useToken(ctx, model.TOKEN_PURPOSE_PASSWORD_RESET, token)
+		if err != nil {
+			return err
+		}
+
+		// the reset link reached the inbox, which proves the address too
+		user := model.User{ID: userToken.UserId, Password: hash}
+		current, err := a.users.GetUsersByID(ctx, userToken.UserId)
+		if err != nil {
+			return err
+		}
+		if current.EmailVerifiedAt == nil {
+			now := time.Now()
+			user.EmailVerifiedAt = &now
+		}
+		if err := a.users.EditUser(ctx, user); err != nil {
+			return err
+		}
+
+		// whoever knew the old password may still be signed in, or have
+		// made themselves a token
+		if _, err := a.sessions.RevokeOtherSessions(ctx, userToken.UserId, 0); err != nil {
+			return err
+		}
+		if err := a.pats.RevokeTokensByUserId(ctx, userToken.UserId); err != nil {
+			return err
+		}
+		return a.tokens.DeleteUnusedTokens(ctx, userToken.UserId, model.TOKEN_PURPOSE_PASSWORD_RESET)
+	})
+}
+
+// sendToken replaces any outstanding token of the purpose with a new one
+// and mails it. Only the hash is stored, the token itself lives in the
+// email.
+func (a *accountServiceImpl) sendToken(ctx context.Context, user model.User, purpose string, ttl time.Duration, path string, template string, locale string) error {
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+
+	err = a.uow.Do(ctx, func(ctx context.Context) error {
+		if err := a.tokens.DeleteUnusedTokens(ctx, user.ID, purpose); err != nil {
+			return err
+		}
+		_, err := a.tokens.CreateToken(ctx, model.UserToken{
+			UserId:    user.ID,
+			Purpose:   purpose,
+			TokenHash: hashToken(token),
+			ExpiresAt: time.Now().Add(ttl),
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	msg, err := mailer.Render(template, locale, tokenMail{
+		Username:       user.Username,
+		Link:           a.cfg.BaseURL + path + "?token=" + url.QueryEscape(token),
+		ExpiresInHours: int(ttl.Round(time.Hour) / time.Hour),
+	})
+	if err != nil {
+		return err
+	}
+	msg.To = user.Email
+	return a.mailer.Send(ctx, msg)
+}
+
+// useToken consumes a valid token. Marking it used is conditional on it
+// not being used yet, so of two concurrent requests only one gets through.
+func (a *accountServiceImpl) useToken(ctx context.Context, purpose string, token string) (model.UserToken, error) {
+	userToken, err := a.tokens.GetTokenByHash(ctx, purpose, hashToken(token))
+	if err != nil {
+		return model.UserToken{}, err
+	}
+	if userToken.ID == 0 || userToken.UsedAt != nil || time.Now().After(userToken.ExpiresAt) {
+		return model.UserToken{}, ErrInvalidToken
+	}
+
+	ok, err := a.tokens.UseToken(ctx, userToken.ID)
+	if err != nil {
+		return model.UserToken{}, err
+	}
+	if !ok {
+		return model.UserToken{}, ErrInvalidToken
+	}
+	return userToken, nil
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
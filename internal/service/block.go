@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"mygram/internal/infrastructure"
+	"mygram/internal/model"
+	"mygram/internal/repository"
+	"regexp"
+)
+
+// BlockService manages blocks and mutes. A block separates two users both
+// ways, a mute only quiets the muted user for the muter.
+type BlockService interface {
+	// Block also removes any follow or follow request between the users.
+	Block(ctx context.Context, userId uint64, blockedId uint64) error
+	Unblock(ctx context.Context, userId uint64, blockedId uint64) error
+	GetBlocks(ctx context.Context, userId uint64) ([]model.ListedUser, error)
+
+	Mute(ctx context.Context, userId uint64, mutedId uint64) error
+	Unmute(ctx context.Context, userId uint64, mutedId uint64) error
+	GetMutes(ctx context.Context, userId uint64) ([]model.ListedUser, error)
+}
+
+var (
+	ErrBlockSelf      = errors.New("you cannot block yourself")
+	ErrBlockNotFound  = errors.New("you have not blocked this user")
+	ErrMuteSelf       = errors.New("you cannot mute yourself")
+	ErrMuteNotFound   = errors.New("you have not muted this user")
+	ErrMentionBlocked = errors.New("you cannot mention this user")
+)
+
+// mentionPattern leaves out the @ of email addresses. Usernames may take
+// letters and digits of any script.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@])@([\p{L}\p{N}_]+)`)
+
+type blockServiceImpl struct {
+	blocks  repository.BlockQuery
+	mutes   repository.MuteQuery
+	follows repository.FollowQuery
+	users   repository.UserQuery
+	uow     infrastructure.UnitOfWork
+}
+
+func NewBlockService(blocks repository.BlockQuery, mutes repository.MuteQuery, follows repository.FollowQuery, users repository.UserQuery, uow infrastructure.UnitOfWork) BlockService {
+	return &blockServiceImpl{blocks: blocks, mutes: mutes, follows: follows, users: users, uow: uow}
+}
+
+func (b *blockServiceImpl) Block(ctx context.Context, userId uint64, blockedId uint64) error {
+	ctx, span := tracer.Start(ctx, "BlockService.Block")
+	defer span.End()
+
+	if userId == blockedId {
+		return ErrBlockSelf
+	}
+
+	return b.uow.Do(ctx, func(ctx context.Context) error {
+		if err := b.checkUser(ctx, blockedId); err != nil {
+			return err
+		}
+
+		block, err := b.blocks.GetBlock(ctx, userId, blockedId)
+		if err != nil || block.BlockerId != 0 {
+			return err
+		}
+		if _, err := b.blocks.CreateBlock(ctx, model.Block{BlockerId: userId, BlockedId: blockedId}); err != nil {
+			return err
+		}
+
+		if _, err := b.follows.DeleteFollow(ctx, userId, blockedId); err != nil {
+			return err
+		}
+		_, err = b.follows.DeleteFollow(ctx, blockedId, userId)
+		return err
+	})
+}
+
+func (b *blockServiceImpl) Unblock(ctx context.Context, userId uint64, blockedId uint64) error {
+	ctx, span := tracer.Start(ctx, "BlockService.Unblock")
+	defer span.End()
+
+	ok, err := b.blocks.DeleteBlock(ctx, userId, blockedId)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrBlockNotFound
+	}
+	return nil
+}
+
+func (b *blockServiceImpl) GetBlocks(ctx context.Context, userId uint64) ([]model.ListedUser, error) {
+	ctx, span := tracer.Start(ctx, "BlockService.GetBlocks")
+	defer span.End()
+
+	return b.blocks.GetBlocks(ctx, userId)
+}
+
+func (b *blockServiceImpl) Mute(ctx context.Context, userId uint64, mutedId uint64) error {
+	ctx, span := tracer.Start(ctx, "BlockService.Mute")
+	defer span.End()
+
+	if userId == mutedId {
+		return ErrMuteSelf
+	}
+
+	return b.uow.Do(ctx, func(ctx context.Context) error {
+		if err := b.checkUser(ctx, mutedId); err != nil {
+			return err
+		}
+
+		mute, err := b.mutes.GetMute(ctx, userId, mutedId)
+		if err != nil || mute.MuterId != 0 {
+			return err
+		}
+		_, err = b.mutes.CreateMute(ctx, model.Mute{MuterId: userId, MutedId: mutedId})
+		return err
+	})
+}
+
+func (b *blockServiceImpl) Unmute(ctx context.Context, userId uint64, mutedId uint64) error {
+	ctx, span := tracer.Start(ctx, "BlockService.Unmute")
+	defer span.End()
+
+	ok, err := b.mutes.DeleteMute(ctx, userId, mutedId)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrMuteNotFound
+	}
+	return nil
+}
+
+func (b *blockServiceImpl) GetMutes(ctx context.Context, userId uint64) ([]model.ListedUser, error) {
+	ctx, span := tracer.Start(ctx, "BlockService.GetMutes")
+	defer span.End()
+
+	return b.mutes.GetMutes(ctx, userId)
+}
+
+// checkMentions refuses a message mentioning, as @username, a user the
+// author blocked or was blocked by.
+func checkMentions(ctx context.Context, users repository.UserQuery, blocks repository.BlockQuery, authorId uint64, message string) error {
+	checked := map[string]bool{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(message, -1) {
+		username := match[1]
+		if checked[username] {
+			continue
+		}
+		checked[username] = true
+
+		user, err := users.GetUserByUsername(ctx, username)
+		if err != nil {
+			return err
+		}
+		if user.ID == 0 {
+			continue
+		}
+		blocked, err := blocks.IsBlocked(ctx, authorId, user.ID)
+		if err != nil {
+			return err
+		}
+		if blocked {
+			return ErrMentionBlocked
+		}
+	}
+	return nil
+}
+
+// checkUser tells apart users that can be blocked or muted from unknown
+// ones.
+func (b *blockServiceImpl) checkUser(ctx context.Context, id uint64) error {
+	user, err := b.users.GetUsersByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if user.ID == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
@@ -2,32 +2,75 @@ package service
 
 import (
 	"context"
+	"errors"
+	"mygram/internal/infrastructure"
+	"mygram/internal/metrics"
 	"mygram/internal/model"
 	"mygram/internal/repository"
 	"time"
 )
 
 type CommentService interface {
+	// CreateComment fails with ErrPhotoNotFound for photos the author cannot
+	// see, and with ErrMentionBlocked for messages mentioning a user the
+	// author blocked or was blocked by.
 	CreateComment(ctx context.Context, comment model.Comment) (model.CommentCreateRes, error)
-	GetCommentsByPhotoId(ctx context.Context, photoId uint64) ([]model.CommentGetRes, error)
-	EditComment(ctx context.Context, comment model.Comment) (model.CommentUpdateRes, error)
-	GetCommentById(ctx context.Context, id uint64) (model.Comment, error)
-	DeleteComment(ctx context.Context, id uint64) error
+	GetCommentsByPhotoId(ctx context.Context, photoId uint64, viewerId uint64, expand model.Expand) ([]model.CommentGetRes, error)
+	// EditComment only applies while the comment was last updated at
+	// updatedAt, unless it is zero, and fails with ErrPreconditionFailed
+	// otherwise. Mentions are checked as by CreateComment.
+	EditComment(ctx context.Context, comment model.Comment, updatedAt time.Time) (model.CommentUpdateRes, error)
+	GetCommentById(ctx context.Context, id uint64, viewerId uint64) (model.Comment, error)
+	// GetExpandedComment is GetCommentById with the relations expand asks
+	// for.
+	GetExpandedComment(ctx context.Context, id uint64, viewerId uint64, expand model.Expand) (model.CommentGetRes, error)
+	DeleteComment(ctx context.Context, id uint64, userId uint64) error
+	// GetCommentsByPhotoIds is GetCommentsByPhotoId for several photos at
+	// once, without relations, ordered by ID.
+	GetCommentsByPhotoIds(ctx context.Context, photoIds []uint64, viewerId uint64) ([]model.CommentGetRes, error)
 }
 
+// ErrPhotoNotFound is also returned for photos hidden by a block or by a
+// private account.
+var ErrPhotoNotFound = errors.New("photo not found")
+
 type commentServiceImpl struct {
-	repo repository.CommentQuery
+	repo    repository.CommentQuery
+	photos  repository.PhotoQuery
+	users   repository.UserQuery
+	blocks  repository.BlockQuery
+	uow     infrastructure.UnitOfWork
+	metrics *metrics.Metrics
 }
 
-func NewCommentService(repo repository.CommentQuery) CommentService {
-	return &commentServiceImpl{repo: repo}
+func NewCommentService(repo repository.CommentQuery, photos repository.PhotoQuery, users repository.UserQuery, blocks repository.BlockQuery, uow infrastructure.UnitOfWork, m *metrics.Metrics) CommentService {
+	return &commentServiceImpl{repo: repo, photos: photos, users: users, blocks: blocks, uow: uow, metrics: m}
 }
 
 func (c *commentServiceImpl) CreateComment(ctx context.Context, comment model.Comment) (model.CommentCreateRes, error) {
-	res, err := c.repo.CreateComment(ctx, comment)
+	ctx, span := tracer.Start(ctx, "CommentService.CreateComment")
+	defer span.End()
+
+	res := model.Comment{}
+	err := c.uow.Do(ctx, func(ctx context.Context) error {
+		photo, err := c.photos.GetPhotoById(ctx, comment.PhotoId, comment.UserId)
+		if err != nil {
+			return err
+		}
+		if photo.ID == 0 {
+			return ErrPhotoNotFound
+		}
+		if err := checkMentions(ctx, c.users, c.blocks, comment.UserId, comment.Message); err != nil {
+			return err
+		}
+
+		res, err = c.repo.CreateComment(ctx, comment)
+		return err
+	})
 	if err != nil {
 		return model.CommentCreateRes{}, err
 	}
+	c.metrics.CommentsCreated.Inc()
 
 	commentResponse := model.CommentCreateRes{}
 	commentResponse.ID = res.ID
@@ -39,8 +82,11 @@ func (c *commentServiceImpl) CreateComment(ctx context.Context, comment model.Co
 	return commentResponse, nil
 }
 
-func (c *commentServiceImpl) GetCommentsByPhotoId(ctx context.Context, photoId uint64) ([]model.CommentGetRes, error) {
-	comments, err := c.repo.GetCommentsByPhotoId(ctx, photoId)
+func (c *commentServiceImpl) GetCommentsByPhotoId(ctx context.Context, photoId uint64, viewerId uint64, expand model.Expand) ([]model.CommentGetRes, error) {
+	ctx, span := tracer.Start(ctx, "CommentService.GetCommentsByPhotoId")
+	defer span.End()
+
+	comments, err := c.repo.GetCommentsByPhotoId(ctx, photoId, viewerId, expand)
 	if err != nil {
 		return nil, err
 	}
@@ -48,9 +94,23 @@ func (c *commentServiceImpl) GetCommentsByPhotoId(ctx context.Context, photoId u
 	return comments, nil
 }
 
-func (c *commentServiceImpl) EditComment(ctx context.Context, comment model.Comment) (model.CommentUpdateRes, error) {
-	err := c.repo.EditComment(ctx, comment)
-
+func (c *commentServiceImpl) EditComment(ctx context.Context, comment model.Comment, updatedAt time.Time) (model.CommentUpdateRes, error) {
+	ctx, span := tracer.Start(ctx, "CommentService.EditComment")
+	defer span.End()
+
+	stored := model.Comment{}
+	err := c.uow.Do(ctx, func(ctx context.Context) error {
+		if err := checkMentions(ctx, c.users, c.blocks, comment.UserId, comment.Message); err != nil {
+			return err
+		}
+		if err := editIfUnmodified(ctx, comment, updatedAt, c.repo.EditComment, c.repo.EditCommentIfUnmodified); err != nil {
+			return err
+		}
+
+		var err error
+		stored, err = c.repo.GetCommentById(ctx, comment.ID, comment.UserId)
+		return err
+	})
 	if err != nil {
 		return model.CommentUpdateRes{}, err
 	}
@@ -60,13 +120,16 @@ func (c *commentServiceImpl) EditComment(ctx context.Context, comment model.Comm
 	commentResponse.Message = comment.Message
 	commentResponse.PhotoId = comment.PhotoId
 	commentResponse.UserId = comment.UserId
-	commentResponse.UpdatedAt = time.Now()
+	commentResponse.UpdatedAt = stored.UpdatedAt
 
 	return commentResponse, nil
 }
 
-func (c *commentServiceImpl) GetCommentById(ctx context.Context, id uint64) (model.Comment, error) {
-	comment, err := c.repo.GetCommentById(ctx, id)
+func (c *commentServiceImpl) GetCommentById(ctx context.Context, id uint64, viewerId uint64) (model.Comment, error) {
+	ctx, span := tracer.Start(ctx, "CommentService.GetCommentById")
+	defer span.End()
+
+	comment, err := c.repo.GetCommentById(ctx, id, viewerId)
 	if err != nil {
 		return model.Comment{}, err
 	}
@@ -74,22 +137,36 @@ func (c *commentServiceImpl) GetCommentById(ctx context.Context, id uint64) (mod
 	return comment, nil
 }
 
+func (c *commentServiceImpl) GetExpandedComment(ctx context.Context, id uint64, viewerId uint64, expand model.Expand) (model.CommentGetRes, error) {
+	ctx, span := tracer.Start(ctx, "CommentService.GetExpandedComment")
+	defer span.End()
 
+	return c.repo.GetExpandedComment(ctx, id, viewerId, expand)
+}
 
-func (c *commentServiceImpl) DeleteComment(ctx context.Context, id uint64) error {
-	cekComment, err := c.repo.GetCommentById(ctx, id)
-	if err != nil {
-		return err
-	}
 
-	if cekComment.ID == 0 {
-		return nil
-	}
-	
-	err = c.repo.DeleteComment(ctx, id)
-	if err != nil {
-		return err
-	}
 
-	return err
+func (c *commentServiceImpl) DeleteComment(ctx context.Context, id uint64, userId uint64) error {
+	ctx, span := tracer.Start(ctx, "CommentService.DeleteComment")
+	defer span.End()
+
+	return c.uow.Do(ctx, func(ctx context.Context) error {
+		cekComment, err := c.repo.GetCommentById(ctx, id, userId)
+		if err != nil {
+			return err
+		}
+
+		if cekComment.ID == 0 {
+			return nil
+		}
+
+		return c.repo.DeleteComment(ctx, id)
+	})
+}
+
+func (c *commentServiceImpl) GetCommentsByPhotoIds(ctx context.Context, photoIds []uint64, viewerId uint64) ([]model.CommentGetRes, error) {
+	ctx, span := tracer.Start(ctx, "CommentService.GetCommentsByPhotoIds")
+	defer span.End()
+
+	return c.repo.GetCommentsByPhotoIds(ctx, photoIds, viewerId)
 }
@@ -0,0 +1,75 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"mygram/internal/metrics"
+	"mygram/internal/model"
+	"mygram/internal/repository/memory"
+	"mygram/internal/service"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestCommentsCannotReachBlockedUsers(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	c := newContent(t, store)
+	comments := service.NewCommentService(memory.NewCommentQuery(store), memory.NewPhotoQuery(store), memory.NewUserQuery(store), memory.NewBlockQuery(store),
+		memory.NewUnitOfWork(store), metrics.New(prometheus.NewRegistry()))
+
+	carol, err := memory.NewUserQuery(store).CreateUser(ctx, model.User{Username: "carol", Email: "carol@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := memory.NewBlockQuery(store).CreateBlock(ctx, model.Block{BlockerId: c.bob.ID, BlockedId: carol.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the photo of bob is not found, whoever blocked whom
+	_, err = comments.CreateComment(ctx, model.Comment{Message: "hi", PhotoId: c.bobPhoto.ID, UserId: carol.ID})
+	if !errors.Is(err, service.ErrPhotoNotFound) {
+		t.Fatalf("expected ErrPhotoNotFound, got %v", err)
+	}
+
+	_, err = comments.CreateComment(ctx, model.Comment{Message: "cc @bob", PhotoId: c.photo.ID, UserId: carol.ID})
+	if !errors.Is(err, service.ErrMentionBlocked) {
+		t.Fatalf("expected ErrMentionBlocked creating, got %v", err)
+	}
+
+	created, err := comments.CreateComment(ctx, model.Comment{Message: "hi @alice", PhotoId: c.photo.ID, UserId: carol.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = comments.EditComment(ctx, model.Comment{ID: created.ID, Message: "cc @bob", PhotoId: c.photo.ID, UserId: carol.ID}, time.Time{})
+	if !errors.Is(err, service.ErrMentionBlocked) {
+		t.Fatalf("expected ErrMentionBlocked editing, got %v", err)
+	}
+}
+
+func TestMentionsOfUnicodeUsernamesAreChecked(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	c := newContent(t, store)
+	comments := service.NewCommentService(memory.NewCommentQuery(store), memory.NewPhotoQuery(store), memory.NewUserQuery(store), memory.NewBlockQuery(store),
+		memory.NewUnitOfWork(store), metrics.New(prometheus.NewRegistry()))
+
+	zoe, err := memory.NewUserQuery(store).CreateUser(ctx, model.User{Username: "zoë", Email: "zoe@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := memory.NewBlockQuery(store).CreateBlock(ctx, model.Block{BlockerId: zoe.ID, BlockedId: c.bob.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = comments.CreateComment(ctx, model.Comment{Message: "cc @zoë!", PhotoId: c.photo.ID, UserId: c.bob.ID})
+	if !errors.Is(err, service.ErrMentionBlocked) {
+		t.Fatalf("expected ErrMentionBlocked, got %v", err)
+	}
+	// nor is an email address a mention
+	if _, err := comments.CreateComment(ctx, model.Comment{Message: "write to zoë@example.com", PhotoId: c.photo.ID, UserId: c.bob.ID}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,28 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrPreconditionFailed is returned by edits made against a version of the
+// resource that is no longer the current one.
+var ErrPreconditionFailed = errors.New("the resource was changed since it was read")
+
+// editIfUnmodified runs edit, or conditional when updatedAt is set, which
+// only writes while the resource was last updated then.
+func editIfUnmodified[T any](ctx context.Context, value T, updatedAt time.Time, edit func(context.Context, T) error, conditional func(context.Context, T, time.Time) (bool, error)) error {
+	if updatedAt.IsZero() {
+		return edit(ctx, value)
+	}
+
+	ok, err := conditional(ctx, value, updatedAt)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrPreconditionFailed
+	}
+	return nil
+}
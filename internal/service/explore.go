@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"mygram/internal/model"
+	"mygram/internal/repository"
+	"mygram/internal/search"
+	"strings"
+)
+
+// ExploreService reads the ranking computed by ranking.Job, for users to
+// discover photos of accounts they do not follow yet.
+type ExploreService interface {
+	GetExplore(ctx context.Context, limit int, offset int, viewerId uint64) ([]model.PhotoGetRes, error)
+	// GetHashtagExplore is GetExplore for the photos using a hashtag, given
+	// with or without #.
+	GetHashtagExplore(ctx context.Context, tag string, limit int, offset int, viewerId uint64) ([]model.PhotoGetRes, error)
+	GetTrendingHashtags(ctx context.Context, limit int) ([]model.TrendingHashtag, error)
+}
+
+type exploreServiceImpl struct {
+	rankings repository.RankingQuery
+}
+
+func NewExploreService(rankings repository.RankingQuery) ExploreService {
+	return &exploreServiceImpl{rankings: rankings}
+}
+
+func (e *exploreServiceImpl) GetExplore(ctx context.Context, limit int, offset int, viewerId uint64) ([]model.PhotoGetRes, error) {
+	ctx, span := tracer.Start(ctx, "ExploreService.GetExplore")
+	defer span.End()
+
+	return e.rankings.GetRankedPhotos(ctx, "", limit, offset, viewerId)
+}
+
+func (e *exploreServiceImpl) GetHashtagExplore(ctx context.Context, tag string, limit int, offset int, viewerId uint64) ([]model.PhotoGetRes, error) {
+	ctx, span := tracer.Start(ctx, "ExploreService.GetHashtagExplore")
+	defer span.End()
+
+	// stored the way search.Hashtags finds them
+	tag = search.Normalize(strings.TrimPrefix(tag, "#"))
+	if tag == "" {
+		return []model.PhotoGetRes{}, nil
+	}
+	return e.rankings.GetRankedPhotos(ctx, tag, limit, offset, viewerId)
+}
+
+func (e *exploreServiceImpl) GetTrendingHashtags(ctx context.Context, limit int) ([]model.TrendingHashtag, error) {
+	ctx, span := tracer.Start(ctx, "ExploreService.GetTrendingHashtags")
+	defer span.End()
+
+	return e.rankings.GetTrendingHashtags(ctx, limit)
+}
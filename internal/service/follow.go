@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"mygram/internal/infrastructure"
+	"mygram/internal/model"
+	"mygram/internal/repository"
+	"time"
+)
+
+// FollowService manages who follows whom, which decides who sees the
+// content of private accounts.
+type FollowService interface {
+	// Follow follows a public account at once and asks a private one.
+	Follow(ctx context.Context, followerId uint64, followeeId uint64) (model.FollowRes, error)
+	// Unfollow also withdraws a pending request.
+	Unfollow(ctx context.Context, followerId uint64, followeeId uint64) error
+
+	GetFollowRequests(ctx context.Context, userId uint64) ([]model.FollowRequest, error)
+	ApproveFollowRequest(ctx context.Context, userId uint64, followerId uint64) error
+	DenyFollowRequest(ctx context.Context, userId uint64, followerId uint64) error
+
+	// SetPrivate switches an account between public and private. Going
+	// public approves the pending requests, there is nothing left to ask.
+	SetPrivate(ctx context.Context, userId uint64, private bool) error
+}
+
+var (
+	ErrFollowSelf            = errors.New("you cannot follow yourself")
+	ErrFollowNotFound        = errors.New("you do not follow this user")
+	ErrFollowRequestNotFound = errors.New("follow request not found")
+)
+
+type followServiceImpl struct {
+	follows repository.FollowQuery
+	blocks  repository.BlockQuery
+	users   repository.UserQuery
+	uow     infrastructure.UnitOfWork
+}
+
+func NewFollowService(follows repository.FollowQuery, blocks repository.BlockQuery, users repository.UserQuery, uow infrastructure.UnitOfWork) FollowService {
+	return &followServiceImpl{follows: follows, blocks: blocks, users: users, uow: uow}
+}
+
+func (f *followServiceImpl) Follow(ctx context.Context, followerId uint64, followeeId uint64) (model.FollowRes, error) {
+	ctx, span := tracer.Start(ctx, "FollowService.Follow")
+	defer span.End()
+
+	if followerId == followeeId {
+		return model.FollowRes{}, ErrFollowSelf
+	}
+
+	follow := model.Follow{}
+	err := f.uow.Do(ctx, func(ctx context.Context) error {
+		followee, err := f.users.GetUsersByID(ctx, followeeId)
+		if err != nil {
+			return err
+		}
+		if followee.ID == 0 {
+			return ErrUserNotFound
+		}
+		// a block hides the users from each other
+		blocked, err := f.blocks.IsBlocked(ctx, followerId, followeeId)
+		if err != nil {
+			return err
+		}
+		if blocked {
+			return ErrUserNotFound
+		}
+
+		// following twice keeps the first follow, approved or not
+		follow, err = f.follows.GetFollow(ctx, followerId, followeeId)
+		if err != nil || follow.FollowerId != 0 {
+			return err
+		}
+
+		follow = model.Follow{FollowerId: followerId, FolloweeId: followeeId}
+		if !followee.Private {
+			now := time.Now()
+			follow.ApprovedAt = &now
+		}
+		follow, err = f.follows.CreateFollow(ctx, follow)
+		return err
+	})
+	if err != nil {
+		return model.FollowRes{}, err
+	}
+	return model.FollowRes{FolloweeId: followeeId, Status: follow.Status()}, nil
+}
+
+func (f *followServiceImpl) Unfollow(ctx context.Context, followerId uint64, followeeId uint64) error {
+	ctx, span := tracer.Start(ctx, "FollowService.Unfollow")
+	defer span.End()
+
+	ok, err := f.follows.DeleteFollow(ctx, followerId, followeeId)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrFollowNotFound
+	}
+	return nil
+}
+
+func (f *followServiceImpl) GetFollowRequests(ctx context.Context, userId uint64) ([]model.FollowRequest, error) {
+	ctx, span := tracer.Start(ctx, "FollowService.GetFollowRequests")
+	defer span.End()
+
+	return f.follows.GetPendingFollows(ctx, userId)
+}
+
+func (f *followServiceImpl) ApproveFollowRequest(ctx context.Context, userId uint64, followerId uint64) error {
+	ctx, span := tracer.Start(ctx, "FollowService.ApproveFollowRequest")
+	defer span.End()
+
+	ok, err := f.follows.ApproveFollow(ctx, followerId, userId, time.Now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrFollowRequestNotFound
+	}
+	return nil
+}
+
+func (f *followServiceImpl) DenyFollowRequest(ctx context.Context, userId uint64, followerId uint64) error {
+	ctx, span := tracer.Start(ctx, "FollowService.DenyFollowRequest")
+	defer span.End()
+
+	return f.uow.Do(ctx, func(ctx context.Context) error {
+		// only pending requests, approved followers are not denied after
+		// the fact here
+		follow, err := f.follows.GetFollow(ctx, followerId, userId)
+		if err != nil {
+			return err
+		}
+		if follow.FollowerId == 0 || follow.ApprovedAt != nil {
+			return ErrFollowRequestNotFound
+		}
+		_, err = f.follows.DeleteFollow(ctx, followerId, userId)
+		return err
+	})
+}
+
+func (f *followServiceImpl) SetPrivate(ctx context.Context, userId uint64, private bool) error {
+	ctx, span := tracer.Start(ctx, "FollowService.SetPrivate")
+	defer span.End()
+
+	return f.uow.Do(ctx, func(ctx context.Context) error {
+		if err := f.users.UpdatePrivate(ctx, userId, private); err != nil {
+			return err
+		}
+		if private {
+			return nil
+		}
+		return f.follows.ApproveAllFollows(ctx, userId, time.Now())
+	})
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"log"
+	"mygram/internal/model"
+	"mygram/internal/repository"
+	"time"
+)
+
+const (
+	// idempotencyKeyTTL is how long a response is replayed for.
+	idempotencyKeyTTL = 24 * time.Hour
+	// idempotencyLockTTL bounds how long a request that never finished, on
+	// a crash say, keeps its retries out.
+	idempotencyLockTTL = time.Minute
+)
+
+// IdempotencyService keeps the first response to each Idempotency-Key of a
+// user, so retries of a request are answered without running it again.
+type IdempotencyService interface {
+	// Begin locks key for a request with fingerprint and reports true.
+	// When the user already used the key it returns it as stored instead,
+	// for the caller to replay or refuse.
+	Begin(ctx context.Context, userId uint64, key string, fingerprint string) (model.IdempotencyKey, bool, error)
+	Complete(ctx context.Context, id uint64, res model.IdempotentResponse) error
+	// Release gives the key up after a failure, so a retry runs again.
+	Release(ctx context.Context, id uint64) error
+	// PurgeExpired drops the expired keys of every user. Begin only drops
+	// those of the user it reserves for, which leaves the keys of users
+	// who stopped posting.
+	PurgeExpired(ctx context.Context) (int64, error)
+}
+
+type idempotencyServiceImpl struct {
+	keys repository.IdempotencyKeyQuery
+}
+
+func NewIdempotencyService(keys repository.IdempotencyKeyQuery) IdempotencyService {
+	return &idempotencyServiceImpl{keys: keys}
+}
+
+func (i *idempotencyServiceImpl) Begin(ctx context.Context, userId uint64, key string, fingerprint string) (model.IdempotencyKey, bool, error) {
+	ctx, span := tracer.Start(ctx, "IdempotencyService.Begin")
+	defer span.End()
+
+	now := time.Now()
+	return i.keys.ReserveKey(ctx, model.IdempotencyKey{
+		UserId:      userId,
+		Key:         key,
+		Fingerprint: fingerprint,
+		LockedUntil: now.Add(idempotencyLockTTL),
+		ExpiresAt:   now.Add(idempotencyKeyTTL),
+	}, now)
+}
+
+func (i *idempotencyServiceImpl) Complete(ctx context.Context, id uint64, res model.IdempotentResponse) error {
+	ctx, span := tracer.Start(ctx, "IdempotencyService.Complete")
+	defer span.End()
+
+	return i.keys.CompleteKey(ctx, id, res, time.Now())
+}
+
+func (i *idempotencyServiceImpl) Release(ctx context.Context, id uint64) error {
+	ctx, span := tracer.Start(ctx, "IdempotencyService.Release")
+	defer span.End()
+
+	return i.keys.ReleaseKey(ctx, id)
+}
+
+func (i *idempotencyServiceImpl) PurgeExpired(ctx context.Context) (int64, error) {
+	ctx, span := tracer.Start(ctx, "IdempotencyService.PurgeExpired")
+	defer span.End()
+
+	return i.keys.DeleteExpiredKeys(ctx, time.Now())
+}
+
+// PurgeIdempotencyKeys purges the expired keys at once and then every
+// interval until ctx is done. Failed runs are logged, the next one catches
+// up.
+func PurgeIdempotencyKeys(ctx context.Context, keys IdempotencyService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := keys.PurgeExpired(ctx); err != nil {
+			log.Printf("purge idempotency keys: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package service_test
+
+import (
+	"context"
+	"mygram/internal/metrics"
+	"mygram/internal/model"
+	"mygram/internal/password"
+	"mygram/internal/repository/memory"
+	"mygram/internal/search"
+	"mygram/internal/service"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// cheapHashes keeps argon2id out of the way of the tests.
+var cheapHashes = password.Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestUserServiceCountsSignUpsAndLogins(t *testing.T) {
+	ctx := context.Background()
+	m := metrics.New(prometheus.NewRegistry())
+	store := memory.NewStore()
+	svc := newUserService(store, m, search.NewEmbeddedIndex())
+
+	if _, err := svc.SignUp(ctx, model.UserSignUp{Username: "alice", Email: "alice@example.com", Password: "harbor-sunset-42", DoB: "2000-01-01"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.SignIn(ctx, model.UserSignIn{Email: "alice@example.com", Password: "harbor-sunset-42"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, email := range []string{"alice@example.com", "nobody@example.com"} {
+		if _, err := svc.SignIn(ctx, model.UserSignIn{Email: email, Password: "wrong-password"}); err == nil {
+			t.Fatalf("%s: expected the sign in refused", email)
+		}
+	}
+
+	if got := testutil.ToFloat64(m.SignUps); got != 1 {
+		t.Fatalf("expected 1 sign up, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.Logins.WithLabelValues(metrics.LoginSuccess)); got != 1 {
+		t.Fatalf("expected 1 successful login, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.Logins.WithLabelValues(metrics.LoginFailure)); got != 2 {
+		t.Fatalf("expected 2 failed logins, got %v", got)
+	}
+}
+
+func TestPhotoAndCommentServicesCountCreations(t *testing.T) {
+	ctx := context.Background()
+	m := metrics.New(prometheus.NewRegistry())
+	store := memory.NewStore()
+	uow := memory.NewUnitOfWork(store)
+	user, err := memory.NewUserQuery(store).CreateUser(ctx, model.User{Username: "alice", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	photos := service.NewPhotoService(memory.NewPhotoQuery(store), uow, m, search.NewEmbeddedIndex())
+	comments := service.NewCommentService(memory.NewCommentQuery(store), memory.NewPhotoQuery(store), memory.NewUserQuery(store), memory.NewBlockQuery(store), uow, m)
+
+	photo, err := photos.CreatePhoto(ctx, model.Photo{Title: "sunset", PhotoUrl: "https://img.example.com/1.jpg", UserId: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, message := range []string{"nice", "wow"} {
+		if _, err := comments.CreateComment(ctx, model.Comment{Message: message, PhotoId: photo.ID, UserId: user.ID}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a failed creation is not counted
+	if _, err := comments.CreateComment(ctx, model.Comment{Message: "lost", PhotoId: 99, UserId: user.ID}); err == nil {
+		t.Fatal("expected a comment on an unknown photo refused")
+	}
+
+	if got := testutil.ToFloat64(m.PhotosCreated); got != 1 {
+		t.Fatalf("expected 1 photo created, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.CommentsCreated); got != 2 {
+		t.Fatalf("expected 2 comments created, got %v", got)
+	}
+}
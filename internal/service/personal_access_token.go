@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"mygram/internal/model"
+	"mygram/internal/repository"
+	"slices"
+	"strings"
+	"time"
+)
+
+// touchInterval limits how often last_used_at is written for a token that
+// is used on every request.
+const touchInterval = time.Minute
+
+type PersonalAccessTokenService interface {
+	CreateToken(ctx context.Context, userId uint64, req model.PersonalAccessTokenCreateReq) (model.PersonalAccessTokenCreateRes, error)
+	GetTokensByUserId(ctx context.Context, userId uint64) ([]model.PersonalAccessToken, error)
+	RevokeToken(ctx context.Context, userId uint64, id uint64) error
+	// AuthenticateToken returns the active token matching a bearer token and
+	// records that it was used.
+	AuthenticateToken(ctx context.Context, token string) (model.PersonalAccessToken, error)
+}
+
+var (
+	ErrTokenNotFound      = errors.New("token not found")
+	ErrInvalidAccessToken = errors.New("invalid, expired or revoked personal access token")
+)
+
+type personalAccessTokenServiceImpl struct {
+	repo  repository.PersonalAccessTokenQuery
+	users repository.UserQuery
+}
+
+func NewPersonalAccessTokenService(repo repository.PersonalAccessTokenQuery, users repository.UserQuery) PersonalAccessTokenService {
+	return &personalAccessTokenServiceImpl{repo: repo, users: users}
+}
+
+func (p *personalAccessTokenServiceImpl) CreateToken(ctx context.Context, userId uint64, req model.PersonalAccessTokenCreateReq) (model.PersonalAccessTokenCreateRes, error) {
+	ctx, span := tracer.Start(ctx, "PersonalAccessTokenService.CreateToken")
+	defer span.End()
+
+	secret, err := newToken()
+	if err != nil {
+		return model.PersonalAccessTokenCreateRes{}, err
+	}
+	plain := model.PERSONAL_ACCESS_TOKEN_PREFIX + secret
+
+	scopes := slices.Clone(req.Scopes)
+	slices.Sort(scopes)
+	token := model.PersonalAccessToken{
+		UserId:      userId,
+		Name:        req.Name,
+		TokenPrefix: plain[:len(model.PERSONAL_ACCESS_TOKEN_PREFIX)+4],
+		TokenHash:   hashToken(plain),
+		Scopes:      slices.Compact(scopes),
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+
+	token, err = p.repo.CreateToken(ctx, token)
+	if err != nil {
+		return model.PersonalAccessTokenCreateRes{}, err
+	}
+	return model.PersonalAccessTokenCreateRes{PersonalAccessToken: token, Token: plain}, nil
+}
+
+func (p *personalAccessTokenServiceImpl) GetTokensByUserId(ctx context.Context, userId uint64) ([]model.PersonalAccessToken, error) {
+	ctx, span := tracer.Start(ctx, "PersonalAccessTokenService.GetTokensByUserId")
+	defer span.End()
+
+	return p.repo.GetTokensByUserId(ctx, userId)
+}
+
+func (p *personalAccessTokenServiceImpl) RevokeToken(ctx context.Context, userId uint64, id uint64) error {
+	ctx, span := tracer.Start(ctx, "PersonalAccessTokenService.RevokeToken")
+	defer span.End()
+
+	ok, err := p.repo.RevokeToken(ctx, userId, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrTokenNotFound
+	}
+	return nil
+}
+
+func (p *personalAccessTokenServiceImpl) AuthenticateToken(ctx context.Context, plain string) (model.PersonalAccessToken, error) {
+	ctx, span := tracer.Start(ctx, "PersonalAccessTokenService.AuthenticateToken")
+	defer span.End()
+
+	if !strings.HasPrefix(plain, model.PERSONAL_ACCESS_TOKEN_PREFIX) {
+		return model.PersonalAccessToken{}, ErrInvalidAccessToken
+	}
+
+	token, err := p.repo.GetTokenByHash(ctx, hashToken(plain))
+	if err != nil {
+		return model.PersonalAccessToken{}, err
+	}
+	now := time.Now()
+	if token.ID == 0 || token.RevokedAt != nil || (token.ExpiresAt != nil && now.After(*token.ExpiresAt)) {
+		return model.PersonalAccessToken{}, ErrInvalidAccessToken
+	}
+
+	// tokens outlive their owner's account otherwise
+	user, err := p.users.GetUsersByID(ctx, token.UserId)
+	if err != nil {
+		return model.PersonalAccessToken{}, err
+	}
+	if user.ID == 0 {
+		return model.PersonalAccessToken{}, ErrInvalidAccessToken
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= touchInterval {
+		if err := p.repo.TouchToken(ctx, token.ID, now); err != nil {
+			return model.PersonalAccessToken{}, err
+		}
+		token.LastUsedAt = &now
+	}
+	return token, nil
+}
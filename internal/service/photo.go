@@ -2,32 +2,60 @@ package service
 
 import (
 	"context"
+	"mygram/internal/infrastructure"
+	"mygram/internal/metrics"
 	"mygram/internal/model"
 	"mygram/internal/repository"
+	"mygram/internal/search"
 	"time"
 )
 
 type PhotoService interface {
 	CreatePhoto(ctx context.Context, photo model.Photo) (model.PhotoCreateRes, error)
-	GetPhotosByUserId(ctx context.Context, userId uint64) ([]model.PhotoGetRes, error)
-	EditPhoto(ctx context.Context, photo model.Photo) (model.PhotoUpdateRes, error)
-	GetPhotoById(ctx context.Context, id uint64) (model.Photo, error)
-	DeletePhoto(ctx context.Context, id uint64) error
+	GetPhotosByUserId(ctx context.Context, userId uint64, viewerId uint64, expand model.Expand) ([]model.PhotoGetRes, error)
+	// EditPhoto only applies while the photo was last updated at updatedAt,
+	// unless it is zero, and fails with ErrPreconditionFailed otherwise.
+	EditPhoto(ctx context.Context, photo model.Photo, updatedAt time.Time) (model.PhotoUpdateRes, error)
+	GetPhotoById(ctx context.Context, id uint64, viewerId uint64) (model.Photo, error)
+	// GetExpandedPhoto is GetPhotoById with the relations expand asks for.
+	GetExpandedPhoto(ctx context.Context, id uint64, viewerId uint64, expand model.Expand) (model.PhotoGetRes, error)
+	DeletePhoto(ctx context.Context, id uint64, userId uint64) error
+	// GetPhotosByIds returns the photos among ids that viewerId may see, in
+	// no particular order.
+	GetPhotosByIds(ctx context.Context, ids []uint64, viewerId uint64) ([]model.PhotoGetRes, error)
+	// GetPhotosByUserIds is GetPhotosByUserId for several users at once,
+	// without relations, ordered by ID.
+	GetPhotosByUserIds(ctx context.Context, userIds []uint64, viewerId uint64) ([]model.PhotoGetRes, error)
 }
 
 type photoServiceImpl struct {
-	repo repository.PhotoQuery
+	repo    repository.PhotoQuery
+	uow     infrastructure.UnitOfWork
+	metrics *metrics.Metrics
+	index   search.Index
 }
 
-func NewPhotoService(repo repository.PhotoQuery) PhotoService {
-	return &photoServiceImpl{repo: repo}
+func NewPhotoService(repo repository.PhotoQuery, uow infrastructure.UnitOfWork, m *metrics.Metrics, index search.Index) PhotoService {
+	return &photoServiceImpl{repo: repo, uow: uow, metrics: m, index: index}
 }
 
 func (p *photoServiceImpl) CreatePhoto(ctx context.Context, photo model.Photo) (model.PhotoCreateRes, error) {
-	res, err := p.repo.CreatePhoto(ctx, photo)
+	ctx, span := tracer.Start(ctx, "PhotoService.CreatePhoto")
+	defer span.End()
+
+	res := model.Photo{}
+	err := p.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		res, err = p.repo.CreatePhoto(ctx, photo)
+		if err != nil {
+			return err
+		}
+		return p.index.IndexPhoto(ctx, res)
+	})
 	if err != nil {
 		return model.PhotoCreateRes{}, err
 	}
+	p.metrics.PhotosCreated.Inc()
 
 	photoResponse := model.PhotoCreateRes{}
 	photoResponse.ID = res.ID
@@ -40,8 +68,11 @@ func (p *photoServiceImpl) CreatePhoto(ctx context.Context, photo model.Photo) (
 	return photoResponse, nil
 }
 
-func (p *photoServiceImpl) GetPhotosByUserId(ctx context.Context, userId uint64) ([]model.PhotoGetRes, error) {
-	photos, err := p.repo.GetPhotosByUserId(ctx, userId)
+func (p *photoServiceImpl) GetPhotosByUserId(ctx context.Context, userId uint64, viewerId uint64, expand model.Expand) ([]model.PhotoGetRes, error) {
+	ctx, span := tracer.Start(ctx, "PhotoService.GetPhotosByUserId")
+	defer span.End()
+
+	photos, err := p.repo.GetPhotosByUserId(ctx, userId, viewerId, expand)
 	if err != nil {
 		return nil, err
 	}
@@ -49,8 +80,24 @@ func (p *photoServiceImpl) GetPhotosByUserId(ctx context.Context, userId uint64)
 	return photos, nil
 }
 
-func (p *photoServiceImpl) EditPhoto(ctx context.Context, photo model.Photo) (model.PhotoUpdateRes, error) {
-	err := p.repo.EditPhoto(ctx, photo)
+func (p *photoServiceImpl) EditPhoto(ctx context.Context, photo model.Photo, updatedAt time.Time) (model.PhotoUpdateRes, error) {
+	ctx, span := tracer.Start(ctx, "PhotoService.EditPhoto")
+	defer span.End()
+
+	stored := model.Photo{}
+	err := p.uow.Do(ctx, func(ctx context.Context) error {
+		if err := editIfUnmodified(ctx, photo, updatedAt, p.repo.EditPhoto, p.repo.EditPhotoIfUnmodified); err != nil {
+			return err
+		}
+
+		// index what was stored, the update skips empty fields
+		var err error
+		stored, err = p.repo.GetPhotoById(ctx, photo.ID, photo.UserId)
+		if err != nil {
+			return err
+		}
+		return p.index.IndexPhoto(ctx, stored)
+	})
 	if err != nil {
 		return model.PhotoUpdateRes{}, err
 	}
@@ -61,13 +108,16 @@ func (p *photoServiceImpl) EditPhoto(ctx context.Context, photo model.Photo) (mo
 	photoResponse.Caption = photo.Caption
 	photoResponse.PhotoUrl = photo.PhotoUrl
 	photoResponse.UserId = photo.UserId
-	photoResponse.UpdatedAt = time.Now()
+	photoResponse.UpdatedAt = stored.UpdatedAt
 
 	return photoResponse, nil
 }
 
-func (p *photoServiceImpl) GetPhotoById(ctx context.Context, id uint64) (model.Photo, error) {
-	photo, err := p.repo.GetPhotoById(ctx, id)
+func (p *photoServiceImpl) GetPhotoById(ctx context.Context, id uint64, viewerId uint64) (model.Photo, error) {
+	ctx, span := tracer.Start(ctx, "PhotoService.GetPhotoById")
+	defer span.End()
+
+	photo, err := p.repo.GetPhotoById(ctx, id, viewerId)
 	if err != nil {
 		return model.Photo{}, err
 	}
@@ -75,21 +125,45 @@ func (p *photoServiceImpl) GetPhotoById(ctx context.Context, id uint64) (model.P
 	return photo, nil
 }
 
+func (p *photoServiceImpl) GetExpandedPhoto(ctx context.Context, id uint64, viewerId uint64, expand model.Expand) (model.PhotoGetRes, error) {
+	ctx, span := tracer.Start(ctx, "PhotoService.GetExpandedPhoto")
+	defer span.End()
 
-func (p *photoServiceImpl) DeletePhoto(ctx context.Context, id uint64) error {
-	cekPhoto, err := p.repo.GetPhotoById(ctx, id)
-	if err != nil {
-		return err
-	}
+	return p.repo.GetExpandedPhoto(ctx, id, viewerId, expand)
+}
 
-	if cekPhoto.ID == 0 {
-		return nil
-	}
-	
-	err = p.repo.DeletePhoto(ctx, id)
-	if err != nil {
-		return err
-	}
 
-	return err
+func (p *photoServiceImpl) DeletePhoto(ctx context.Context, id uint64, userId uint64) error {
+	ctx, span := tracer.Start(ctx, "PhotoService.DeletePhoto")
+	defer span.End()
+
+	return p.uow.Do(ctx, func(ctx context.Context) error {
+		cekPhoto, err := p.repo.GetPhotoById(ctx, id, userId)
+		if err != nil {
+			return err
+		}
+
+		if cekPhoto.ID == 0 {
+			return nil
+		}
+
+		if err := p.repo.DeletePhoto(ctx, id); err != nil {
+			return err
+		}
+		return p.index.RemovePhoto(ctx, id)
+	})
+}
+
+func (p *photoServiceImpl) GetPhotosByIds(ctx context.Context, ids []uint64, viewerId uint64) ([]model.PhotoGetRes, error) {
+	ctx, span := tracer.Start(ctx, "PhotoService.GetPhotosByIds")
+	defer span.End()
+
+	return p.repo.GetPhotosByIds(ctx, ids, viewerId)
+}
+
+func (p *photoServiceImpl) GetPhotosByUserIds(ctx context.Context, userIds []uint64, viewerId uint64) ([]model.PhotoGetRes, error) {
+	ctx, span := tracer.Start(ctx, "PhotoService.GetPhotosByUserIds")
+	defer span.End()
+
+	return p.repo.GetPhotosByUserIds(ctx, userIds, viewerId)
 }
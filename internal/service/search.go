@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"mygram/internal/model"
+	"mygram/internal/repository"
+	"mygram/internal/search"
+)
+
+// searchCandidates is how many hits are asked of the index before dropping
+// what the viewer may not see, so a page still fills up when some of the
+// best matches are private or blocked. Only the embedded index needs it,
+// Postgres leaves them out itself.
+const searchCandidates = 200
+
+// SearchService runs text searches and keeps to what the viewer may see.
+type SearchService interface {
+	SearchPhotos(ctx context.Context, text string, limit int, viewerId uint64) ([]model.PhotoGetRes, error)
+	SearchUsers(ctx context.Context, text string, limit int, viewerId uint64) ([]model.PublicProfile, error)
+	SearchHashtags(ctx context.Context, text string, limit int, viewerId uint64) ([]model.HashtagRes, error)
+}
+
+type searchServiceImpl struct {
+	index  search.Index
+	photos repository.PhotoQuery
+	users  repository.UserQuery
+}
+
+func NewSearchService(index search.Index, photos repository.PhotoQuery, users repository.UserQuery) SearchService {
+	return &searchServiceImpl{index: index, photos: photos, users: users}
+}
+
+func (s *searchServiceImpl) SearchPhotos(ctx context.Context, text string, limit int, viewerId uint64) ([]model.PhotoGetRes, error) {
+	ctx, span := tracer.Start(ctx, "SearchService.SearchPhotos")
+	defer span.End()
+
+	hits, err := s.index.Search(ctx, search.Query{Type: model.SEARCH_TYPE_PHOTOS, Text: text, Limit: searchCandidates, ViewerId: viewerId})
+	if err != nil {
+		return nil, err
+	}
+	if len(hits) == 0 {
+		return []model.PhotoGetRes{}, nil
+	}
+
+	photos, err := s.photos.GetPhotosByIds(ctx, hitIds(hits), viewerId)
+	if err != nil {
+		return nil, err
+	}
+	byId := make(map[uint64]model.PhotoGetRes, len(photos))
+	for _, photo := range photos {
+		byId[photo.ID] = photo
+	}
+
+	res := []model.PhotoGetRes{}
+	for _, hit := range hits {
+		if photo, ok := byId[hit.ID]; ok && len(res) < limit {
+			res = append(res, photo)
+		}
+	}
+	return res, nil
+}
+
+func (s *searchServiceImpl) SearchUsers(ctx context.Context, text string, limit int, viewerId uint64) ([]model.PublicProfile, error) {
+	ctx, span := tracer.Start(ctx, "SearchService.SearchUsers")
+	defer span.End()
+
+	hits, err := s.index.Search(ctx, search.Query{Type: model.SEARCH_TYPE_USERS, Text: text, Limit: searchCandidates, ViewerId: viewerId})
+	if err != nil {
+		return nil, err
+	}
+	if len(hits) == 0 {
+		return []model.PublicProfile{}, nil
+	}
+
+	users, err := s.users.GetUsersByIds(ctx, hitIds(hits), viewerId)
+	if err != nil {
+		return nil, err
+	}
+	byId := make(map[uint64]model.User, len(users))
+	for _, user := range users {
+		byId[user.ID] = user
+	}
+
+	res := []model.PublicProfile{}
+	for _, hit := range hits {
+		if user, ok := byId[hit.ID]; ok && len(res) < limit {
+			res = append(res, user.PublicProfile())
+		}
+	}
+	return res, nil
+}
+
+func (s *searchServiceImpl) SearchHashtags(ctx context.Context, text string, limit int, viewerId uint64) ([]model.HashtagRes, error) {
+	ctx, span := tracer.Start(ctx, "SearchService.SearchHashtags")
+	defer span.End()
+
+	hits, err := s.index.Search(ctx, search.Query{Type: model.SEARCH_TYPE_HASHTAGS, Text: text, Limit: limit, ViewerId: viewerId})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]model.HashtagRes, 0, len(hits))
+	for _, hit := range hits {
+		res = append(res, model.HashtagRes{Tag: hit.Tag, Photos: hit.Count})
+	}
+	return res, nil
+}
+
+func hitIds(hits []search.Hit) []uint64 {
+	ids := make([]uint64, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, hit.ID)
+	}
+	return ids
+}
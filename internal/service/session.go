@@ -0,0 +1,279 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"mygram/internal/mailer"
+	"mygram/internal/model"
+	"mygram/internal/repository"
+	"strings"
+	"time"
+)
+
+const (
+	// accessTokenTTL is also how long a session lasts, there is no refresh.
+	accessTokenTTL = time.Hour
+	publicTokenTTL = time.Hour
+	// loginHistoryLimit caps the login history, older entries stay stored.
+	loginHistoryLimit = 50
+)
+
+// SessionService records every login as a session its access tokens are
+// bound to, so sessions can be listed and revoked, and keeps the login
+// history.
+type SessionService interface {
+	// StartSession records a completed sign in, and mails the user when it
+	// came from a device they did not use before.
+	StartSession(ctx context.Context, user model.User, device model.Device, method string, locale string) (model.Session, error)
+	// RecordFailedLogin adds a failed attempt to the history of the user
+	// with email, when there is one.
+	RecordFailedLogin(ctx context.Context, email string, device model.Device, method string, reason string) error
+	// AuthenticateSession returns the active session of an access token.
+	AuthenticateSession(ctx context.Context, jti string) (model.Session, error)
+
+	GetSessions(ctx context.Context, userId uint64, currentJti string) ([]model.Session, error)
+	RevokeSession(ctx context.Context, userId uint64, id uint64) error
+	// RevokeOtherSessions signs out everywhere but the current session and
+	// returns how many sessions were revoked.
+	RevokeOtherSessions(ctx context.Context, userId uint64, currentJti string) (int64, error)
+	GetLoginHistory(ctx context.Context, userId uint64) ([]model.LoginAttempt, error)
+}
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrInvalidSession  = errors.New("session expired or signed out")
+)
+
+type sessionServiceImpl struct {
+	sessions repository.SessionQuery
+	attempts repository.LoginAttemptQuery
+	users    repository.UserQuery
+	mailer   mailer.Mailer
+}
+
+func NewSessionService(sessions repository.SessionQuery, attempts repository.LoginAttemptQuery, users repository.UserQuery, m mailer.Mailer) SessionService {
+	return &sessionServiceImpl{sessions: sessions, attempts: attempts, users: users, mailer: m}
+}
+
+type newLoginMail struct {
+	Username   string
+	ClientName string
+	IP         string
+	Time       string
+}
+
+func (s *sessionServiceImpl) StartSession(ctx context.Context, user model.User, device model.Device, method string, locale string) (model.Session, error) {
+	ctx, span := tracer.Start(ctx, "SessionService.StartSession")
+	defer span.End()
+
+	jti, err := newToken()
+	if err != nil {
+		return model.Session{}, err
+	}
+
+	// the first session has nothing to compare against
+	hadSessions, knownDevice, err := s.sessions.HasSessions(ctx, user.ID, device.UserAgent)
+	if err != nil {
+		return model.Session{}, err
+	}
+
+	now := time.Now()
+	session, err := s.sessions.CreateSession(ctx, model.Session{
+		UserId:     user.ID,
+		Jti:        jti,
+		UserAgent:  device.UserAgent,
+		IP:         device.IP,
+		ClientName: clientName(device.UserAgent),
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(accessTokenTTL),
+	})
+	if err != nil {
+		return model.Session{}, err
+	}
+
+	if _, err := s.attempts.CreateAttempt(ctx, model.LoginAttempt{
+		UserId:     user.ID,
+		Method:     method,
+		Success:    true,
+		UserAgent:  device.UserAgent,
+		IP:         device.IP,
+		ClientName: session.ClientName,
+	}); err != nil {
+		return model.Session{}, err
+	}
+
+	// the login went through either way, a lost notification is logged
+	if hadSessions && !knownDevice {
+		if err := s.notifyNewLogin(ctx, user, session, locale); err != nil {
+			log.Printf("send new login notification to user %d: %v", user.ID, err)
+		}
+	}
+	return session, nil
+}
+
+func (s *sessionServiceImpl) notifyNewLogin(ctx context.Context, user model.User, session model.Session, locale string) error {
+	msg, err := mailer.Render(mailer.TemplateNewLogin, locale, newLoginMail{
+		Username:   user.Username,
+		ClientName: session.ClientName,
+		IP:         session.IP,
+		Time:       session.CreatedAt.UTC().Format("2 Jan 2006 15:04 MST"),
+	})
+	if err != nil {
+		return err
+	}
+	msg.To = user.Email
+	return s.mailer.Send(ctx, msg)
+}
+
+func (s *sessionServiceImpl) RecordFailedLogin(ctx context.Context, email string, device model.Device, method string, reason string) error {
+	ctx, span := tracer.Start(ctx, "SessionService.RecordFailedLogin")
+	defer span.End()
+
+	user, err := s.users.GetUserByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if user.ID == 0 {
+		return nil
+	}
+
+	_, err = s.attempts.CreateAttempt(ctx, model.LoginAttempt{
+		UserId:        user.ID,
+		Method:        method,
+		Success:       false,
+		FailureReason: reason,
+		UserAgent:     device.UserAgent,
+		IP:            device.IP,
+		ClientName:    clientName(device.UserAgent),
+	})
+	return err
+}
+
+func (s *sessionServiceImpl) AuthenticateSession(ctx context.Context, jti string) (model.Session, error) {
+	ctx, span := tracer.Start(ctx, "SessionService.AuthenticateSession")
+	defer span.End()
+
+	if jti == "" {
+		return model.Session{}, ErrInvalidSession
+	}
+	session, err := s.sessions.GetSessionByJti(ctx, jti)
+	if err != nil {
+		return model.Session{}, err
+	}
+	now := time.Now()
+	if session.ID == 0 || !session.Active(now) {
+		return model.Session{}, ErrInvalidSession
+	}
+
+	if now.Sub(session.LastSeenAt) >= touchInterval {
+		if err := s.sessions.TouchSession(ctx, session.ID, now); err != nil {
+			return model.Session{}, err
+		}
+		session.LastSeenAt = now
+	}
+	return session, nil
+}
+
+func (s *sessionServiceImpl) GetSessions(ctx context.Context, userId uint64, currentJti string) ([]model.Session, error) {
+	ctx, span := tracer.Start(ctx, "SessionService.GetSessions")
+	defer span.End()
+
+	sessions, err := s.sessions.GetActiveSessionsByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].Jti == currentJti
+	}
+	return sessions, nil
+}
+
+func (s *sessionServiceImpl) RevokeSession(ctx context.Context, userId uint64, id uint64) error {
+	ctx, span := tracer.Start(ctx, "SessionService.RevokeSession")
+	defer span.End()
+
+	ok, err := s.sessions.RevokeSession(ctx, userId, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+func (s *sessionServiceImpl) RevokeOtherSessions(ctx context.Context, userId uint64, currentJti string) (int64, error) {
+	ctx, span := tracer.Start(ctx, "SessionService.RevokeOtherSessions")
+	defer span.End()
+
+	current, err := s.sessions.GetSessionByJti(ctx, currentJti)
+	if err != nil {
+		return 0, err
+	}
+	if current.ID == 0 || current.UserId != userId {
+		return 0, ErrInvalidSession
+	}
+	return s.sessions.RevokeOtherSessions(ctx, userId, current.ID)
+}
+
+func (s *sessionServiceImpl) GetLoginHistory(ctx context.Context, userId uint64) ([]model.LoginAttempt, error) {
+	ctx, span := tracer.Start(ctx, "SessionService.GetLoginHistory")
+	defer span.End()
+
+	return s.attempts.GetAttemptsByUserId(ctx, userId, loginHistoryLimit)
+}
+
+// clientName turns a user agent into something like "Firefox on Linux". It
+// only needs to be recognisable in a session list, not exact.
+func clientName(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown client"
+	}
+
+	browser := ""
+	for _, b := range []struct{ token, name string }{
+		// order matters, Edge and Opera also claim to be Chrome, and
+		// Chrome claims to be Safari
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+	} {
+		if strings.Contains(userAgent, b.token) {
+			browser = b.name
+			break
+		}
+	}
+
+	platform := ""
+	for _, p := range []struct{ token, name string }{
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	} {
+		if strings.Contains(userAgent, p.token) {
+			platform = p.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && platform != "":
+		return browser + " on " + platform
+	case browser != "":
+		return browser
+	case platform != "":
+		return platform
+	}
+
+	// command line tools and libraries, curl/8.4.0 or Go-http-client/1.1
+	product, _, _ := strings.Cut(userAgent, " ")
+	product, _, _ = strings.Cut(product, "/")
+	return product
+}
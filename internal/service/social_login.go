@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"mygram/internal/infrastructure"
+	"mygram/internal/model"
+	"mygram/internal/password"
+	"mygram/internal/repository"
+	"mygram/internal/search"
+	"mygram/internal/sso"
+	"mygram/pkg/helper"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const oauthStateTTL = 10 * time.Minute
+
+// SocialLoginService signs users in through external OpenID Connect
+// providers. Identities are linked to existing users by verified email.
+type SocialLoginService interface {
+	// Begin returns where to send the user and the signed state the
+	// callback has to be called with.
+	Begin(ctx context.Context, provider string) (authURL string, stateToken string, err error)
+	// Complete redeems the code returned to the callback and returns the
+	// user to hand an access token to, creating or linking them on first
+	// sign in.
+	Complete(ctx context.Context, provider string, stateToken string, state string, code string) (model.User, error)
+}
+
+var (
+	ErrUnknownProvider      = errors.New("unknown identity provider")
+	ErrInvalidOAuthState    = errors.New("invalid or expired login state")
+	ErrIdentityEmailTaken   = errors.New("an account with this email already exists, sign in with its password and verify the email first")
+	ErrIdentityEmailMissing = errors.New("the identity provider did not share an email address")
+)
+
+var usernameInvalidChars = regexp.MustCompile(`[^a-z0-9_.]+`)
+
+type socialLoginServiceImpl struct {
+	providers  sso.Providers
+	users      repository.UserQuery
+	identities repository.UserIdentityQuery
+	uow        infrastructure.UnitOfWork
+	passwords  password.Hasher
+	index      search.Index
+}
+
+func NewSocialLoginService(providers sso.Providers, users repository.UserQuery, identities repository.UserIdentityQuery, uow infrastructure.UnitOfWork, passwords password.Hasher, index search.Index) SocialLoginService {
+	return &socialLoginServiceImpl{providers: providers, users: users, identities: identities, uow: uow, passwords: passwords, index: index}
+}
+
+func (s *socialLoginServiceImpl) Begin(ctx context.Context, provider string) (string, string, error) {
+	ctx, span := tracer.Start(ctx, "SocialLoginService.Begin")
+	defer span.End()
+
+	p, ok := s.providers[provider]
+	if !ok {
+		return "", "", ErrUnknownProvider
+	}
+
+	state, err := sso.NewState(provider)
+	if err != nil {
+		return "", "", err
+	}
+	authURL, err := p.AuthCodeURL(ctx, state)
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+	stateToken, err := helper.GenerateToken(model.OAuthStateClaim{
+		StandardClaim: model.StandardClaim{
+			Jti: fmt.Sprintf("%v", now.UnixNano()),
+			Iss: helper.TOKEN_ISSUER,
+			Aud: helper.TOKEN_AUDIENCE,
+			Sub: model.SUBJECT_OAUTH_STATE,
+			Exp: uint64(now.Add(oauthStateTTL).Unix()),
+			Iat: uint64(now.Unix()),
+			Nbf: uint64(now.Unix()),
+		},
+		Provider: state.Provider,
+		State:    state.State,
+		Nonce:    state.Nonce,
+		Verifier: state.Verifier,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return authURL, stateToken, nil
+}
+
+func (s *socialLoginServiceImpl) Complete(ctx context.Context, provider string, stateToken string, state string, code string) (model.User, error) {
+	ctx, span := tracer.Start(ctx, "SocialLoginService.Complete")
+	defer span.End()
+
+	p, ok := s.providers[provider]
+	if !ok {
+		return model.User{}, ErrUnknownProvider
+	}
+
+	expected, err := parseOAuthState(stateToken)
+	if err != nil || expected.Provider != provider || state == "" || state != expected.State {
+		return model.User{}, ErrInvalidOAuthState
+	}
+
+	identity, err := p.Exchange(ctx, expected, code)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	user := model.User{}
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		user, err = s.userForIdentity(ctx, identity)
+		return err
+	})
+	if err != nil {
+		return model.User{}, err
+	}
+	return user, nil
+}
+
+// userForIdentity returns the user linked to the identity. On first sign in
+// the identity is linked to the user with the same email, when both the
+// provider and the user have verified it, or to a new user otherwise.
+func (s *socialLoginServiceImpl) userForIdentity(ctx context.Context, identity sso.Identity) (model.User, error) {
+	linked, err := s.identities.GetIdentity(ctx, identity.Provider, identity.Subject)
+	if err != nil {
+		return model.User{}, err
+	}
+	if linked.ID != 0 {
+		user, err := s.users.GetUsersByID(ctx, linked.UserId)
+		if err != nil {
+			return model.User{}, err
+		}
+		if user.ID == 0 {
+			return model.User{}, ErrInvalidCredentials
+		}
+		return user, nil
+	}
+
+	if identity.Email == "" {
+		return model.User{}, ErrIdentityEmailMissing
+	}
+
+	user, err := s.users.GetUserByEmail(ctx, identity.Email)
+	if err != nil {
+		return model.User{}, err
+	}
+	if user.ID != 0 {
+		// anyone can sign up with someone else's address, so an account
+		// whose email was never proven is not handed over
+		if !identity.EmailVerified || user.EmailVerifiedAt == nil {
+			return model.User{}, ErrIdentityEmailTaken
+		}
+	} else {
+		user, err = s.createUser(ctx, identity)
+		if err != nil {
+			return model.User{}, err
+		}
+	}
+
+	_, err = s.identities.CreateIdentity(ctx, model.UserIdentity{
+		UserId:   user.ID,
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	})
+	if err != nil {
+		return model.User{}, err
+	}
+	return user, nil
+}
+
+// createUser signs up a user without a usable password, one can be set
+// through the password reset flow.
+func (s *socialLoginServiceImpl) createUser(ctx context.Context, identity sso.Identity) (model.User, error) {
+	username, err := s.availableUsername(ctx, identity)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	secret, err := newToken()
+	if err != nil {
+		return model.User{}, err
+	}
+	hash, err := s.passwords.Hash(secret)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	user := model.User{
+		Username: username,
+		Email:    identity.Email,
+		Password: hash,
+	}
+	if identity.EmailVerified {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+	user, err = s.users.CreateUser(ctx, user)
+	if err != nil {
+		return model.User{}, err
+	}
+	return user, s.index.IndexUser(ctx, user)
+}
+
+// availableUsername derives a username from the preferred username or the
+// email, with a random suffix when it is taken.
+func (s *socialLoginServiceImpl) availableUsername(ctx context.Context, identity sso.Identity) (string, error) {
+	base := identity.PreferredUsername
+	if base == "" {
+		base, _, _ = strings.Cut(identity.Email, "@")
+	}
+	base = strings.Trim(usernameInvalidChars.ReplaceAllString(strings.ToLower(base), ""), ".")
+	if len(base) > 30 {
+		base = base[:30]
+	}
+	if base == "" {
+		base = "user"
+	}
+
+	candidate := base
+	for attempt := 0; attempt < 5; attempt++ {
+		exists, err := s.users.UsernameExists(ctx, candidate)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return candidate, nil
+		}
+
+		n, err := rand.Int(rand.Reader, big.NewInt(10000))
+		if err != nil {
+			return "", err
+		}
+		candidate = fmt.Sprintf("%s%04d", base, n.Int64())
+	}
+	return "", fmt.Errorf("no available username for %q", base)
+}
+
+func parseOAuthState(stateToken string) (sso.State, error) {
+	claims, err := helper.ValidateToken(stateToken, model.SUBJECT_OAUTH_STATE)
+	if err != nil {
+		return sso.State{}, ErrInvalidOAuthState
+	}
+
+	state := sso.State{}
+	state.Provider, _ = claims["provider"].(string)
+	state.State, _ = claims["state"].(string)
+	state.Nonce, _ = claims["nonce"].(string)
+	state.Verifier, _ = claims["verifier"].(string)
+	if state.State == "" || state.Nonce == "" || state.Verifier == "" {
+		return sso.State{}, ErrInvalidOAuthState
+	}
+	return state, nil
+}
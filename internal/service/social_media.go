@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"mygram/internal/infrastructure"
 	"mygram/internal/model"
 	"mygram/internal/repository"
 	"time"
@@ -9,21 +10,34 @@ import (
 
 type SocialMediaService interface {
 	CreateSocialMedia(ctx context.Context, social model.SocialMedia) (model.SocialMediaCreateRes, error)
-	GetSocialMediasByUserId(ctx context.Context, userId uint64) ([]model.SocialMediaGetRes, error)
-	EditSocialMedia(ctx context.Context, social model.SocialMedia) (model.SocialMediaUpdateRes, error)
-	GetSocialMediaById(ctx context.Context, id uint64) (model.SocialMedia, error)
-	DeleteSocialMedia(ctx context.Context, id uint64) error
+	GetSocialMediasByUserId(ctx context.Context, userId uint64, viewerId uint64, expand model.Expand) ([]model.SocialMediaGetRes, error)
+	// EditSocialMedia only applies while the social media was last updated
+	// at updatedAt, unless it is zero, and fails with ErrPreconditionFailed
+	// otherwise.
+	EditSocialMedia(ctx context.Context, social model.SocialMedia, updatedAt time.Time) (model.SocialMediaUpdateRes, error)
+	GetSocialMediaById(ctx context.Context, id uint64, viewerId uint64) (model.SocialMedia, error)
+	// GetExpandedSocialMedia is GetSocialMediaById with the relations
+	// expand asks for.
+	GetExpandedSocialMedia(ctx context.Context, id uint64, viewerId uint64, expand model.Expand) (model.SocialMediaGetRes, error)
+	DeleteSocialMedia(ctx context.Context, id uint64, userId uint64) error
+	// GetSocialMediasByUserIds is GetSocialMediasByUserId for several users
+	// at once, without relations, ordered by ID.
+	GetSocialMediasByUserIds(ctx context.Context, userIds []uint64, viewerId uint64) ([]model.SocialMediaGetRes, error)
 }
 
 type socialMediaServiceImpl struct {
 	repo repository.SocialMediaQuery
+	uow  infrastructure.UnitOfWork
 }
 
-func NewSocialMediaService(repo repository.SocialMediaQuery) SocialMediaService {
-	return &socialMediaServiceImpl{repo: repo}
+func NewSocialMediaService(repo repository.SocialMediaQuery, uow infrastructure.UnitOfWork) SocialMediaService {
+	return &socialMediaServiceImpl{repo: repo, uow: uow}
 }
 
 func (s *socialMediaServiceImpl) CreateSocialMedia(ctx context.Context, social model.SocialMedia) (model.SocialMediaCreateRes, error) {
+	ctx, span := tracer.Start(ctx, "SocialMediaService.CreateSocialMedia")
+	defer span.End()
+
 	res, err := s.repo.CreateSocialMedia(ctx, social)
 	if err != nil {
 		return model.SocialMediaCreateRes{}, err
@@ -39,8 +53,11 @@ func (s *socialMediaServiceImpl) CreateSocialMedia(ctx context.Context, social m
 	return socialMediaResponse, nil
 }
 
-func (s *socialMediaServiceImpl) GetSocialMediasByUserId(ctx context.Context, userId uint64) ([]model.SocialMediaGetRes, error) {
-	socials, err := s.repo.GetSocialMediasByUserId(ctx, userId)
+func (s *socialMediaServiceImpl) GetSocialMediasByUserId(ctx context.Context, userId uint64, viewerId uint64, expand model.Expand) ([]model.SocialMediaGetRes, error) {
+	ctx, span := tracer.Start(ctx, "SocialMediaService.GetSocialMediasByUserId")
+	defer span.End()
+
+	socials, err := s.repo.GetSocialMediasByUserId(ctx, userId, viewerId, expand)
 	if err != nil {
 		return nil, err
 	}
@@ -48,8 +65,20 @@ func (s *socialMediaServiceImpl) GetSocialMediasByUserId(ctx context.Context, us
 	return socials, nil
 }
 
-func (s *socialMediaServiceImpl) EditSocialMedia(ctx context.Context, social model.SocialMedia) (model.SocialMediaUpdateRes, error) {
-	err := s.repo.EditSocialMedia(ctx, social)
+func (s *socialMediaServiceImpl) EditSocialMedia(ctx context.Context, social model.SocialMedia, updatedAt time.Time) (model.SocialMediaUpdateRes, error) {
+	ctx, span := tracer.Start(ctx, "SocialMediaService.EditSocialMedia")
+	defer span.End()
+
+	stored := model.SocialMedia{}
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := editIfUnmodified(ctx, social, updatedAt, s.repo.EditSocialMedia, s.repo.EditSocialMediaIfUnmodified); err != nil {
+			return err
+		}
+
+		var err error
+		stored, err = s.repo.GetSocialMediaById(ctx, social.ID, social.UserId)
+		return err
+	})
 	if err != nil {
 		return model.SocialMediaUpdateRes{}, err
 	}
@@ -59,13 +88,16 @@ func (s *socialMediaServiceImpl) EditSocialMedia(ctx context.Context, social mod
 	socialMediaRes.Name = social.Name
 	socialMediaRes.SocialMediaUrl = social.SocialMediaUrl
 	socialMediaRes.UserId = social.UserId
-	socialMediaRes.UpdatedAt = time.Now()
+	socialMediaRes.UpdatedAt = stored.UpdatedAt
 
 	return socialMediaRes, nil
 }
 
-func (s *socialMediaServiceImpl) GetSocialMediaById(ctx context.Context, id uint64) (model.SocialMedia, error) {
-	social, err := s.repo.GetSocialMediaById(ctx, id)
+func (s *socialMediaServiceImpl) GetSocialMediaById(ctx context.Context, id uint64, viewerId uint64) (model.SocialMedia, error) {
+	ctx, span := tracer.Start(ctx, "SocialMediaService.GetSocialMediaById")
+	defer span.End()
+
+	social, err := s.repo.GetSocialMediaById(ctx, id, viewerId)
 	if err != nil {
 		return model.SocialMedia{}, err
 	}
@@ -73,22 +105,36 @@ func (s *socialMediaServiceImpl) GetSocialMediaById(ctx context.Context, id uint
 	return social, nil
 }
 
+func (s *socialMediaServiceImpl) GetExpandedSocialMedia(ctx context.Context, id uint64, viewerId uint64, expand model.Expand) (model.SocialMediaGetRes, error) {
+	ctx, span := tracer.Start(ctx, "SocialMediaService.GetExpandedSocialMedia")
+	defer span.End()
 
+	return s.repo.GetExpandedSocialMedia(ctx, id, viewerId, expand)
+}
 
-func (s *socialMediaServiceImpl) DeleteSocialMedia(ctx context.Context, id uint64) error {
-	cekSocial, err := s.repo.GetSocialMediaById(ctx, id)
-	if err != nil {
-		return err
-	}
 
-	if cekSocial.ID == 0 {
-		return nil
-	}
 
-	err =  s.repo.DeleteSocialMedia(ctx, id)
-	if err != nil {
-		return err
-	}
+func (s *socialMediaServiceImpl) DeleteSocialMedia(ctx context.Context, id uint64, userId uint64) error {
+	ctx, span := tracer.Start(ctx, "SocialMediaService.DeleteSocialMedia")
+	defer span.End()
+
+	return s.uow.Do(ctx, func(ctx context.Context) error {
+		cekSocial, err := s.repo.GetSocialMediaById(ctx, id, userId)
+		if err != nil {
+			return err
+		}
+
+		if cekSocial.ID == 0 {
+			return nil
+		}
+
+		return s.repo.DeleteSocialMedia(ctx, id)
+	})
+}
+
+func (s *socialMediaServiceImpl) GetSocialMediasByUserIds(ctx context.Context, userIds []uint64, viewerId uint64) ([]model.SocialMediaGetRes, error) {
+	ctx, span := tracer.Start(ctx, "SocialMediaService.GetSocialMediasByUserIds")
+	defer span.End()
 
-	return err
+	return s.repo.GetSocialMediasByUserIds(ctx, userIds, viewerId)
 }
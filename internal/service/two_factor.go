@@ -0,0 +1,316 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image/png"
+	"mygram/internal/infrastructure"
+	"mygram/internal/model"
+	"mygram/internal/password"
+	"mygram/internal/ratelimit"
+	"mygram/internal/repository"
+	"mygram/pkg/helper"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+const (
+	TOTP_ISSUER = "MyGram"
+
+	totpPeriod         = 30
+	mfaPendingTTL      = 5 * time.Minute
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+)
+
+// TwoFactorService manages TOTP (RFC 6238) two-factor authentication and
+// the second step of login.
+type TwoFactorService interface {
+	// Enroll stores a new secret, which only takes effect once confirmed.
+	Enroll(ctx context.Context, userId uint64) (model.TwoFactorEnrollRes, error)
+	// Confirm turns two-factor authentication on with a first code and
+	// returns recovery codes, which are not shown again.
+	Confirm(ctx context.Context, userId uint64, code string) ([]string, error)
+	// Disable asks for the password and a code again.
+	Disable(ctx context.Context, userId uint64, password string, code string) error
+
+	GeneratePendingToken(ctx context.Context, user model.User) (string, error)
+	// VerifyLogin exchanges a pending token and a TOTP or recovery code for
+	// the user to hand an access token to.
+	VerifyLogin(ctx context.Context, pendingToken string, code string) (model.User, error)
+}
+
+var (
+	ErrTwoFactorEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnrolled = errors.New("two-factor authentication is not enrolled")
+	ErrTwoFactorNotEnabled  = errors.New("two-factor authentication is not enabled")
+	ErrInvalidCode          = errors.New("invalid code")
+	ErrInvalidPendingToken  = errors.New("invalid or expired mfa pending token")
+)
+
+var totpOpts = totp.ValidateOpts{
+	Period:    totpPeriod,
+	Digits:    otp.DigitsSix,
+	Algorithm: otp.AlgorithmSHA1,
+}
+
+type twoFactorServiceImpl struct {
+	users     repository.UserQuery
+	codes     repository.RecoveryCodeQuery
+	uow       infrastructure.UnitOfWork
+	lockout   *ratelimit.Lockout
+	passwords password.Hasher
+}
+
+func NewTwoFactorService(users repository.UserQuery, codes repository.RecoveryCodeQuery, uow infrastructure.UnitOfWork, lockout *ratelimit.Lockout, passwords password.Hasher) TwoFactorService {
+	return &twoFactorServiceImpl{users: users, codes: codes, uow: uow, lockout: lockout, passwords: passwords}
+}
+
+func (t *twoFactorServiceImpl) Enroll(ctx context.Context, userId uint64) (model.TwoFactorEnrollRes, error) {
+	ctx, span := tracer.Start(ctx, "TwoFactorService.Enroll")
+	defer span.End()
+
+	user, err := t.users.GetUsersByID(ctx, userId)
+	if err != nil {
+		return model.TwoFactorEnrollRes{}, err
+	}
+	if user.TotpEnabledAt != nil {
+		return model.TwoFactorEnrollRes{}, ErrTwoFactorEnabled
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      TOTP_ISSUER,
+		AccountName: user.Email,
+		Period:      totpPeriod,
+		Digits:      totpOpts.Digits,
+		Algorithm:   totpOpts.Algorithm,
+	})
+	if err != nil {
+		return model.TwoFactorEnrollRes{}, err
+	}
+
+	img, err := key.Image(256, 256)
+	if err != nil {
+		return model.TwoFactorEnrollRes{}, err
+	}
+	qr := &bytes.Buffer{}
+	if err := png.Encode(qr, img); err != nil {
+		return model.TwoFactorEnrollRes{}, err
+	}
+
+	if err := t.users.UpdateTOTP(ctx, userId, key.Secret(), nil); err != nil {
+		return model.TwoFactorEnrollRes{}, err
+	}
+
+	return model.TwoFactorEnrollRes{
+		Secret:          key.Secret(),
+		ProvisioningURI: key.URL(),
+		QRCode:          "data:image/png;base64," + base64.StdEncoding.EncodeToString(qr.Bytes()),
+	}, nil
+}
+
+func (t *twoFactorServiceImpl) Confirm(ctx context.Context, userId uint64, code string) ([]string, error) {
+	ctx, span := tracer.Start(ctx, "TwoFactorService.Confirm")
+	defer span.End()
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = t.uow.Do(ctx, func(ctx context.Context) error {
+		user, err := t.users.GetUsersByID(ctx, userId)
+		if err != nil {
+			return err
+		}
+		if user.TotpEnabledAt != nil {
+			return ErrTwoFactorEnabled
+		}
+		if user.TotpSecret == "" {
+			return ErrTwoFactorNotEnrolled
+		}
+
+		step, ok := matchTOTP(user.TotpSecret, code, time.Now())
+		if !ok {
+			return ErrInvalidCode
+		}
+
+		now := time.Now()
+		if err := t.users.UpdateTOTP(ctx, userId, user.TotpSecret, &now); err != nil {
+			return err
+		}
+		if _, err := t.users.UseTOTPStep(ctx, userId, step); err != nil {
+			return err
+		}
+		return t.codes.ReplaceRecoveryCodes(ctx, userId, hashes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (t *twoFactorServiceImpl) Disable(ctx context.Context, userId uint64, plain string, code string) error {
+	ctx, span := tracer.Start(ctx, "TwoFactorService.Disable")
+	defer span.End()
+
+	return t.uow.Do(ctx, func(ctx context.Context) error {
+		user, err := t.users.GetUsersByID(ctx, userId)
+		if err != nil {
+			return err
+		}
+		if user.TotpEnabledAt == nil {
+			return ErrTwoFactorNotEnabled
+		}
+
+		if err := t.checkSecondFactor(ctx, user, code, func() bool {
+			return t.passwords.Verify(plain, user.Password)
+		}); err != nil {
+			return err
+		}
+
+		if err := t.users.UpdateTOTP(ctx, userId, "", nil); err != nil {
+			return err
+		}
+		return t.codes.DeleteRecoveryCodes(ctx, userId)
+	})
+}
+
+func (t *twoFactorServiceImpl) GeneratePendingToken(ctx context.Context, user model.User) (string, error) {
+	_, span := tracer.Start(ctx, "TwoFactorService.GeneratePendingToken")
+	defer span.End()
+
+	now := time.Now()
+	return helper.GenerateToken(model.MFAPendingClaim{
+		StandardClaim: model.StandardClaim{
+			Jti: fmt.Sprintf("%v", now.UnixNano()),
+			Iss: helper.TOKEN_ISSUER,
+			Aud: helper.TOKEN_AUDIENCE,
+			Sub: model.SUBJECT_MFA_PENDING,
+			Exp: uint64(now.Add(mfaPendingTTL).Unix()),
+			Iat: uint64(now.Unix()),
+			Nbf: uint64(now.Unix()),
+		},
+		UserID: user.ID,
+	})
+}
+
+func (t *twoFactorServiceImpl) VerifyLogin(ctx context.Context, pendingToken string, code string) (model.User, error) {
+	ctx, span := tracer.Start(ctx, "TwoFactorService.VerifyLogin")
+	defer span.End()
+
+	claims, err := helper.ValidateToken(pendingToken, model.SUBJECT_MFA_PENDING)
+	if err != nil {
+		return model.User{}, ErrInvalidPendingToken
+	}
+	userId, ok := claims["user_id"].(float64)
+	if !ok {
+		return model.User{}, ErrInvalidPendingToken
+	}
+
+	user := model.User{}
+	err = t.uow.Do(ctx, func(ctx context.Context) error {
+		user, err = t.users.GetUsersByID(ctx, uint64(userId))
+		if err != nil {
+			return err
+		}
+		if user.ID == 0 || user.TotpEnabledAt == nil {
+			return ErrInvalidPendingToken
+		}
+		return t.checkSecondFactor(ctx, user, code, nil)
+	})
+	if err != nil {
+		return model.User{}, err
+	}
+	return user, nil
+}
+
+// checkSecondFactor accepts a TOTP code, each time step once, or an unused
+// recovery code, along with whatever extra check the caller needs. Failures
+// count towards the lockout of the user's second factor.
+func (t *twoFactorServiceImpl) checkSecondFactor(ctx context.Context, user model.User, code string, extra func() bool) error {
+	lockKey := fmt.Sprintf("mfa:%d", user.ID)
+	lockedFor, err := t.lockout.Check(ctx, lockKey)
+	if err != nil {
+		return err
+	}
+	if lockedFor > 0 {
+		return &AccountLockedError{RetryAfter: lockedFor}
+	}
+
+	valid := extra == nil || extra()
+	if valid {
+		valid, err = t.useCode(ctx, user, code)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !valid {
+		if err := t.lockout.Fail(ctx, lockKey); err != nil {
+			return err
+		}
+		return ErrInvalidCode
+	}
+	return t.lockout.Succeed(ctx, lockKey)
+}
+
+func (t *twoFactorServiceImpl) useCode(ctx context.Context, user model.User, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if len(code) == int(totpOpts.Digits) {
+		step, ok := matchTOTP(user.TotpSecret, code, time.Now())
+		if !ok {
+			return false, nil
+		}
+		return t.users.UseTOTPStep(ctx, user.ID, step)
+	}
+	return t.codes.UseRecoveryCode(ctx, user.ID, hashToken(normalizeRecoveryCode(code)))
+}
+
+// matchTOTP checks code against the current time step and one step either
+// side for clock drift, and returns the step it matched.
+func matchTOTP(secret string, code string, now time.Time) (uint64, bool) {
+	for _, skew := range []int64{0, -1, 1} {
+		at := now.Add(time.Duration(skew*totpPeriod) * time.Second)
+		expected, err := totp.GenerateCodeCustom(secret, at, totpOpts)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return uint64(at.Unix() / totpPeriod), true
+		}
+	}
+	return 0, false
+}
+
+// newRecoveryCodes returns codes formatted as xxxxx-xxxxx and their hashes.
+// They carry 50 random bits, enough for a fast hash to be safe.
+func newRecoveryCodes() ([]string, []string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(encoding.EncodeToString(b))[:recoveryCodeLength]
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, hashToken(raw))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(code, "-", ""))
+}
@@ -3,34 +3,93 @@ package service
 import (
 	"context"
 	"errors"
-	"fmt"
+	"log"
+	"mygram/internal/infrastructure"
+	"mygram/internal/metrics"
 	"mygram/internal/model"
+	"mygram/internal/password"
+	"mygram/internal/ratelimit"
 	"mygram/internal/repository"
+	"mygram/internal/search"
 	"mygram/pkg/helper"
+	"strings"
+	"sync"
 	"time"
 )
 
 type UserService interface {	
 	SignIn(ctx context.Context, userSignIn model.UserSignIn) (model.User, error)
 	GetUsersById(ctx context.Context, id uint64) (model.User, error)
-	EditUser(ctx context.Context, user model.User) (model.UserResponse, error)
+	// GetUsersByIds returns the users among ids that are not blocked either
+	// way with viewerId, in no particular order.
+	GetUsersByIds(ctx context.Context, ids []uint64, viewerId uint64) ([]model.User, error)
+	// EditUser only applies while the user was last updated at updatedAt,
+	// unless it is zero, and fails with ErrPreconditionFailed otherwise. A
+	// new email is unverified until confirmed again.
+	EditUser(ctx context.Context, user model.User, updatedAt time.Time) (model.UserResponse, error)
 	DeleteUsersById(ctx context.Context, id uint64) (model.User, error)
 
 	SignUp(ctx context.Context, userSignUp model.UserSignUp) (model.User, error)	
 
-	GenerateUserAccessToken(ctx context.Context, user model.User) (token string, err error)
+	// GenerateUserAccessToken issues the access token of a session started
+	// with SessionService.StartSession.
+	GenerateUserAccessToken(ctx context.Context, user model.User, session model.Session) (token string, err error)
+	// GeneratePublicToken issues a guest token that can only read.
+	GeneratePublicToken(ctx context.Context) (token string, err error)
 }
 
-type userServiceImpl struct{
-	repo repository.UserQuery
+// ErrInvalidCredentials is returned for both an unknown email and a wrong
+// password, so sign in does not reveal which accounts exist.
+var ErrInvalidCredentials = errors.New("invalid email or password")
+
+// ErrUserNotFound is also returned for users hidden by a block.
+var ErrUserNotFound = errors.New("user not found")
+
+type AccountLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *AccountLockedError) Error() string {
+	return "too many failed login attempts, account temporarily locked"
 }
 
-func NewUserService(repo repository.UserQuery) UserService{
-	return &userServiceImpl{repo: repo}
+type userServiceImpl struct{
+	repo      repository.UserQuery
+	photos    repository.PhotoQuery
+	comments  repository.CommentQuery
+	socials   repository.SocialMediaQuery
+	uow       infrastructure.UnitOfWork
+	metrics   *metrics.Metrics
+	lockout   *ratelimit.Lockout
+	passwords password.Hasher
+	policy    password.Policy
+	index     search.Index
+
+	dummyPasswordHash func() (string, error)
+}
 
+func NewUserService(repo repository.UserQuery, photos repository.PhotoQuery, comments repository.CommentQuery, socials repository.SocialMediaQuery, uow infrastructure.UnitOfWork, m *metrics.Metrics, lockout *ratelimit.Lockout, passwords password.Hasher, policy password.Policy, index search.Index) UserService{
+	return &userServiceImpl{
+		repo:      repo,
+		photos:    photos,
+		comments:  comments,
+		socials:   socials,
+		uow:       uow,
+		metrics:   m,
+		lockout:   lockout,
+		passwords: passwords,
+		policy:    policy,
+		index:     index,
+		dummyPasswordHash: sync.OnceValues(func() (string, error) {
+			return passwords.Hash("mygram-dummy-password")
+		}),
+	}
 }
 
 func (u *userServiceImpl) SignUp(ctx context.Context, userSignUp model.UserSignUp) (model.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.SignUp")
+	defer span.End()
+
 	user := model.User{
 		Username: userSignUp.Username,
 		Email:    userSignUp.Email,
@@ -42,38 +101,96 @@ func (u *userServiceImpl) SignUp(ctx context.Context, userSignUp model.UserSignU
 	}
 	user.DoB = dob
 
-	pass, err := helper.GenerateHash(userSignUp.Password)
+	if err := u.policy.Check(userSignUp.Password); err != nil {
+		return model.User{}, err
+	}
+	pass, err := u.passwords.Hash(userSignUp.Password)
 	if err != nil {
 		return model.User{}, err
 	}
 
 	user.Password = pass
 
-	res, err := u.repo.CreateUser(ctx, user)
+	res := model.User{}
+	err = u.uow.Do(ctx, func(ctx context.Context) error {
+		res, err = u.repo.CreateUser(ctx, user)
+		if err != nil {
+			return err
+		}
+		return u.index.IndexUser(ctx, res)
+	})
 	if err != nil {
 		return model.User{}, err
 	}
+	u.metrics.SignUps.Inc()
 	return res, err
 }
 
 func (u *userServiceImpl) SignIn(ctx context.Context, userSignIn model.UserSignIn) (model.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.SignIn")
+	defer span.End()
+
+	lockKey := strings.ToLower(strings.TrimSpace(userSignIn.Email))
+	lockedFor, err := u.lockout.Check(ctx, lockKey)
+	if err != nil {
+		return model.User{}, err
+	}
+	if lockedFor > 0 {
+		u.metrics.Logins.WithLabelValues(metrics.LoginFailure).Inc()
+		return model.User{}, &AccountLockedError{RetryAfter: lockedFor}
+	}
+
 	user, err := u.repo.GetUserByEmail(ctx, userSignIn.Email)
 	if err != nil {
 		return model.User{}, err
 	}
+
+	// unknown emails still pay for a hash comparison, so response time does
+	// not tell which accounts exist
+	hash := user.Password
 	if user.ID == 0 {
-		return model.User{}, errors.New("email not found")
+		hash, err = u.dummyPasswordHash()
+		if err != nil {
+			return model.User{}, err
+		}
+	}
+
+	isValidLogin := u.passwords.Verify(userSignIn.Password, hash)
+	if user.ID == 0 || !isValidLogin {
+		u.metrics.Logins.WithLabelValues(metrics.LoginFailure).Inc()
+		if err := u.lockout.Fail(ctx, lockKey); err != nil {
+			return model.User{}, err
+		}
+		return model.User{}, ErrInvalidCredentials
 	}
 
-	isValidLogin := helper.CheckPasswordHash(userSignIn.Password, user.Password)
-	if !isValidLogin {
-		return model.User{}, errors.New("password incorrect")
+	if err := u.lockout.Succeed(ctx, lockKey); err != nil {
+		return model.User{}, err
 	}
+	u.metrics.Logins.WithLabelValues(metrics.LoginSuccess).Inc()
 
+	// the password is only known now, which is the one chance to move its
+	// hash to the current algorithm and parameters
+	if u.passwords.NeedsRehash(user.Password) {
+		if err := u.rehash(ctx, user, userSignIn.Password); err != nil {
+			log.Printf("rehash password of user %d: %v", user.ID, err)
+		}
+	}
 	return user, nil
 }
 
+func (u *userServiceImpl) rehash(ctx context.Context, user model.User, plain string) error {
+	hash, err := u.passwords.Hash(plain)
+	if err != nil {
+		return err
+	}
+	return u.repo.UpdatePasswordHash(ctx, user.ID, hash)
+}
+
 func (u *userServiceImpl) GetUsersById(ctx context.Context, id uint64) (model.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetUsersById")
+	defer span.End()
+
 	user, err := u.repo.GetUsersByID(ctx, id)
 	if err != nil {
 		return model.User{}, err
@@ -81,27 +198,56 @@ func (u *userServiceImpl) GetUsersById(ctx context.Context, id uint64) (model.Us
 	return user, err
 }
 
-func (u *userServiceImpl) EditUser(ctx context.Context, user model.User) (model.UserResponse, error) {
-	cekEmail, err := u.repo.GetUserByEmail(ctx, user.Email)
-	if err != nil {
-		return model.UserResponse{}, err
-	}
-	
-	if cekEmail.ID != 0 && user.ID != cekEmail.ID {
-		return model.UserResponse{}, errors.New("email already in use")
-	}
-	
-	err = u.repo.EditUser(ctx, user)
-	if err != nil {
-		return model.UserResponse{}, err
-	}
+func (u *userServiceImpl) GetUsersByIds(ctx context.Context, ids []uint64, viewerId uint64) ([]model.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetUsersByIds")
+	defer span.End()
+
+	return u.repo.GetUsersByIds(ctx, ids, viewerId)
+}
+
+func (u *userServiceImpl) EditUser(ctx context.Context, user model.User, updatedAt time.Time) (model.UserResponse, error) {
+	ctx, span := tracer.Start(ctx, "UserService.EditUser")
+	defer span.End()
 
-	getDob, err := u.repo.GetUsersByID(ctx, user.ID)
+	getDob := model.User{}
+	err := u.uow.Do(ctx, func(ctx context.Context) error {
+		cekEmail, err := u.repo.GetUserByEmail(ctx, user.Email)
+		if err != nil {
+			return err
+		}
+
+		if cekEmail.ID != 0 && user.ID != cekEmail.ID {
+			return errors.New("email already in use")
+		}
+
+		current, err := u.repo.GetUsersByID(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		err = editIfUnmodified(ctx, user, updatedAt, u.repo.EditUser, u.repo.EditUserIfUnmodified)
+		if err != nil {
+			return err
+		}
+
+		// the new address has to be confirmed again before posting
+		if user.Email != "" && user.Email != current.Email {
+			if err := u.repo.UnverifyEmail(ctx, user.ID); err != nil {
+				return err
+			}
+		}
+
+		getDob, err = u.repo.GetUsersByID(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		return u.index.IndexUser(ctx, getDob)
+	})
 	if err != nil {
 		return model.UserResponse{}, err
 	}
 
-	user.UpdatedAt = time.Now()
+	user.UpdatedAt = getDob.UpdatedAt
 	
 	DobTime := getDob.DoB
 
@@ -117,33 +263,67 @@ func (u *userServiceImpl) EditUser(ctx context.Context, user model.User) (model.
 }
 
 func (u *userServiceImpl) DeleteUsersById(ctx context.Context, id uint64) (model.User, error) {
-	user, err := u.repo.GetUsersByID(ctx, id)
-	if err != nil {
-		return model.User{}, err
-	}
+	ctx, span := tracer.Start(ctx, "UserService.DeleteUsersById")
+	defer span.End()
 
-	if user.ID == 0 {
-		return model.User{}, nil
-	}
+	user := model.User{}
+	err := u.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		user, err = u.repo.GetUsersByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if user.ID == 0 {
+			return nil
+		}
 
-	err = u.repo.DeleteUsersByID(ctx, id)
+		// everything the user posted goes with them, comments first for
+		// the foreign keys
+		photos, err := u.photos.GetPhotosByUserIds(ctx, []uint64{id}, id)
+		if err != nil {
+			return err
+		}
+		if err := u.comments.DeleteCommentsByUserId(ctx, id); err != nil {
+			return err
+		}
+		if err := u.photos.DeletePhotosByUserId(ctx, id); err != nil {
+			return err
+		}
+		for _, photo := range photos {
+			if err := u.index.RemovePhoto(ctx, photo.ID); err != nil {
+				return err
+			}
+		}
+		if err := u.socials.DeleteSocialMediasByUserId(ctx, id); err != nil {
+			return err
+		}
+
+		if err := u.repo.DeleteUsersByID(ctx, id); err != nil {
+			return err
+		}
+		return u.index.RemoveUser(ctx, id)
+	})
 	if err != nil {
 		return model.User{}, err
 	}
 
-	return user, err
+	return user, nil
 }
 
 
-func (u *userServiceImpl) GenerateUserAccessToken(ctx context.Context, user model.User) (token string, err error) {
+func (u *userServiceImpl) GenerateUserAccessToken(ctx context.Context, user model.User, session model.Session) (token string, err error) {
+	_, span := tracer.Start(ctx, "UserService.GenerateUserAccessToken")
+	defer span.End()
+
 	now := time.Now()
 
 	claim := model.StandardClaim{
-		Jti: fmt.Sprintf("%v", time.Now().UnixNano()),
-		Iss: "go-middleware",
-		Aud: "golang-006",
-		Sub: "access-token",
-		Exp: uint64(now.Add(time.Hour).Unix()),
+		Jti: session.Jti,
+		Iss: helper.TOKEN_ISSUER,
+		Aud: helper.TOKEN_AUDIENCE,
+		Sub: model.SUBJECT_ACCESS_TOKEN,
+		Exp: uint64(session.ExpiresAt.Unix()),
 		Iat: uint64(now.Unix()),
 		Nbf: uint64(now.Unix()),
 	}
@@ -159,3 +339,23 @@ func (u *userServiceImpl) GenerateUserAccessToken(ctx context.Context, user mode
 	return
 }
 
+func (u *userServiceImpl) GeneratePublicToken(ctx context.Context) (token string, err error) {
+	_, span := tracer.Start(ctx, "UserService.GeneratePublicToken")
+	defer span.End()
+
+	jti, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	return helper.GenerateToken(model.StandardClaim{
+		Jti: jti,
+		Iss: helper.TOKEN_ISSUER,
+		Aud: helper.TOKEN_AUDIENCE,
+		Sub: model.SUBJECT_PUBLIC_TOKEN,
+		Exp: uint64(now.Add(publicTokenTTL).Unix()),
+		Iat: uint64(now.Unix()),
+		Nbf: uint64(now.Unix()),
+	})
+}
@@ -0,0 +1,150 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"mygram/internal/metrics"
+	"mygram/internal/model"
+	"mygram/internal/password"
+	"mygram/internal/ratelimit"
+	"mygram/internal/repository/memory"
+	"mygram/internal/search"
+	"mygram/internal/service"
+	"testing"
+)
+
+func newUserService(store *memory.Store, m *metrics.Metrics, index search.Index) service.UserService {
+	return service.NewUserService(memory.NewUserQuery(store), memory.NewPhotoQuery(store), memory.NewCommentQuery(store), memory.NewSocialMediaQuery(store),
+		memory.NewUnitOfWork(store), m, ratelimit.NewLockout(ratelimit.NewMemoryStore(), ratelimit.DefaultLockoutPolicy()),
+		password.NewHasher(cheapHashes), password.DefaultPolicy(), index)
+}
+
+// failingIndex fails to remove users, the last step of deleting one.
+type failingIndex struct {
+	search.Index
+}
+
+var errIndexDown = errors.New("index down")
+
+func (failingIndex) RemoveUser(ctx context.Context, id uint64) error {
+	return errIndexDown
+}
+
+type content struct {
+	alice, bob               model.User
+	photo, bobPhoto          model.Photo
+	aliceComment, bobComment model.Comment
+	aliceOnBob               model.Comment
+	socialMedia              model.SocialMedia
+}
+
+// newContent gives alice a photo commented by both, a comment on a photo
+// of bob and a social media.
+func newContent(t *testing.T, store *memory.Store) content {
+	t.Helper()
+
+	ctx := context.Background()
+	c := content{}
+	var err error
+	users := memory.NewUserQuery(store)
+	photos := memory.NewPhotoQuery(store)
+	comments := memory.NewCommentQuery(store)
+	if c.alice, err = users.CreateUser(ctx, model.User{Username: "alice", Email: "alice@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if c.bob, err = users.CreateUser(ctx, model.User{Username: "bob", Email: "bob@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if c.photo, err = photos.CreatePhoto(ctx, model.Photo{Title: "sunset", UserId: c.alice.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if c.bobPhoto, err = photos.CreatePhoto(ctx, model.Photo{Title: "dusk", UserId: c.bob.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if c.aliceComment, err = comments.CreateComment(ctx, model.Comment{Message: "nice", PhotoId: c.photo.ID, UserId: c.alice.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if c.bobComment, err = comments.CreateComment(ctx, model.Comment{Message: "wow", PhotoId: c.photo.ID, UserId: c.bob.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if c.aliceOnBob, err = comments.CreateComment(ctx, model.Comment{Message: "pretty", PhotoId: c.bobPhoto.ID, UserId: c.alice.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if c.socialMedia, err = memory.NewSocialMediaQuery(store).CreateSocialMedia(ctx, model.SocialMedia{Name: "instagram", UserId: c.alice.ID}); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// exists reports which of the photos, comments and social medias are still
+// there, as seen by bob.
+func exists(t *testing.T, store *memory.Store, c content) map[string]bool {
+	t.Helper()
+
+	ctx := context.Background()
+	found := map[string]bool{}
+	for name, id := range map[string]uint64{"photo": c.photo.ID, "bob's photo": c.bobPhoto.ID} {
+		photo, err := memory.NewPhotoQuery(store).GetPhotoById(ctx, id, c.bob.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found[name] = photo.ID != 0
+	}
+	for name, id := range map[string]uint64{"alice's comment": c.aliceComment.ID, "bob's comment": c.bobComment.ID, "alice's comment on bob": c.aliceOnBob.ID} {
+		comment, err := memory.NewCommentQuery(store).GetCommentById(ctx, id, c.bob.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found[name] = comment.ID != 0
+	}
+	social, err := memory.NewSocialMediaQuery(store).GetSocialMediaById(ctx, c.socialMedia.ID, c.bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found["social media"] = social.ID != 0
+	return found
+}
+
+func TestDeleteUserDeletesWhatTheyPosted(t *testing.T) {
+	store := memory.NewStore()
+	c := newContent(t, store)
+	svc := newUserService(store, metrics.NewNop(), search.NewEmbeddedIndex())
+
+	if _, err := svc.DeleteUsersById(context.Background(), c.alice.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]bool{
+		"photo": false, "bob's photo": true,
+		"alice's comment": false, "bob's comment": false, "alice's comment on bob": false,
+		"social media": false,
+	}
+	for name, found := range exists(t, store, c) {
+		if found != want[name] {
+			t.Errorf("%s: expected kept %v, got %v", name, want[name], found)
+		}
+	}
+}
+
+func TestDeleteUserRollsBackWhenALaterStepFails(t *testing.T) {
+	store := memory.NewStore()
+	c := newContent(t, store)
+	svc := newUserService(store, metrics.NewNop(), failingIndex{search.NewEmbeddedIndex()})
+
+	if _, err := svc.DeleteUsersById(context.Background(), c.alice.ID); !errors.Is(err, errIndexDown) {
+		t.Fatalf("expected %v, got %v", errIndexDown, err)
+	}
+
+	for name, found := range exists(t, store, c) {
+		if !found {
+			t.Errorf("%s: expected kept after the rollback", name)
+		}
+	}
+	user, err := memory.NewUserQuery(store).GetUsersByID(context.Background(), c.alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID == 0 {
+		t.Fatal("expected alice kept after the rollback")
+	}
+}
@@ -0,0 +1,48 @@
+package sso
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Providers are the configured identity providers by name.
+type Providers map[string]*Provider
+
+func NewProviders(cfgs ...ProviderConfig) Providers {
+	providers := Providers{}
+	for _, cfg := range cfgs {
+		providers[cfg.Name] = NewProvider(cfg)
+	}
+	return providers
+}
+
+// ProvidersFromEnv reads the comma separated names in OIDC_PROVIDERS, each
+// configured through OIDC_<NAME>_ISSUER, _CLIENT_ID, _CLIENT_SECRET,
+// _REDIRECT_URL and optionally _SCOPES.
+func ProvidersFromEnv() Providers {
+	cfgs := []ProviderConfig{}
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+
+		cfg := ProviderConfig{
+			Name:         name,
+			IssuerURL:    os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+		}
+		if cfg.RedirectURL == "" {
+			cfg.RedirectURL = fmt.Sprintf("http://localhost:3000/v1/users/oauth/%s/callback", name)
+		}
+		if scopes := os.Getenv(prefix + "SCOPES"); scopes != "" {
+			cfg.Scopes = strings.Split(scopes, ",")
+		}
+		cfgs = append(cfgs, cfg)
+	}
+	return NewProviders(cfgs...)
+}
@@ -0,0 +1,154 @@
+// Package sso signs users in through external OpenID Connect providers:
+// discovery, the authorization code flow with PKCE and ID token validation.
+package sso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+type ProviderConfig struct {
+	Name         string
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the callback route of the API registered with the
+	// provider.
+	RedirectURL string
+	// Scopes are requested on top of openid, email and profile by default.
+	Scopes []string
+}
+
+// Identity is what the provider asserts about the user in the ID token.
+type Identity struct {
+	Provider          string
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+}
+
+var (
+	// ErrDiscovery means the provider could not be reached or configured.
+	ErrDiscovery = errors.New("identity provider unavailable")
+	// ErrCodeExchange means the provider refused the code, for instance
+	// because it was used already or the PKCE verifier does not match.
+	ErrCodeExchange   = errors.New("authorization code rejected")
+	ErrInvalidIDToken = errors.New("invalid id token")
+)
+
+// Provider discovers its endpoints on first use, so an identity provider
+// that is down at boot only breaks its own logins.
+type Provider struct {
+	cfg    ProviderConfig
+	client *http.Client
+
+	mu       sync.Mutex
+	oauth2   *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+func NewProvider(cfg ProviderConfig) *Provider {
+	return &Provider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *Provider) Name() string {
+	return p.cfg.Name
+}
+
+func (p *Provider) discover(ctx context.Context) (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.oauth2 != nil {
+		return p.oauth2, p.verifier, nil
+	}
+
+	// the provider keeps the context to refresh signing keys later, it must
+	// outlive the request
+	ctx = oidc.ClientContext(context.WithoutCancel(ctx), p.client)
+	provider, err := oidc.NewProvider(ctx, p.cfg.IssuerURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: discover %s: %v", ErrDiscovery, p.cfg.Name, err)
+	}
+
+	scopes := p.cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"email", "profile"}
+	}
+	p.oauth2 = &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		RedirectURL:  p.cfg.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       append([]string{oidc.ScopeOpenID}, scopes...),
+	}
+	p.verifier = provider.Verifier(&oidc.Config{ClientID: p.cfg.ClientID})
+	return p.oauth2, p.verifier, nil
+}
+
+// AuthCodeURL is where to send the user, with the S256 challenge of the PKCE
+// verifier.
+func (p *Provider) AuthCodeURL(ctx context.Context, state State) (string, error) {
+	cfg, _, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return cfg.AuthCodeURL(state.State, oidc.Nonce(state.Nonce), oauth2.S256ChallengeOption(state.Verifier)), nil
+}
+
+// Exchange redeems the code and validates the ID token, including the nonce
+// sent along with the authorization request.
+func (p *Provider) Exchange(ctx context.Context, state State, code string) (Identity, error) {
+	cfg, verifier, err := p.discover(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	token, err := cfg.Exchange(oidc.ClientContext(ctx, p.client), code, oauth2.VerifierOption(state.Verifier))
+	if err != nil {
+		return Identity{}, fmt.Errorf("%w: %s: %v", ErrCodeExchange, p.cfg.Name, err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return Identity{}, fmt.Errorf("%w: missing from token response", ErrInvalidIDToken)
+	}
+
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return Identity{}, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	if idToken.Nonce != state.Nonce {
+		return Identity{}, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+
+	claims := struct {
+		Email             string `json:"email"`
+		EmailVerified     bool   `json:"email_verified"`
+		Name              string `json:"name"`
+		PreferredUsername string `json:"preferred_username"`
+	}{}
+	if err := idToken.Claims(&claims); err != nil {
+		return Identity{}, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	return Identity{
+		Provider:          p.cfg.Name,
+		Subject:           idToken.Subject,
+		Email:             claims.Email,
+		EmailVerified:     claims.EmailVerified,
+		Name:              claims.Name,
+		PreferredUsername: claims.PreferredUsername,
+	}, nil
+}
@@ -0,0 +1,43 @@
+package sso
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+
+	"golang.org/x/oauth2"
+)
+
+// State is what the callback needs from the login request: the state
+// echoed by the provider, the nonce expected in the ID token and the PKCE
+// verifier. It travels with the browser, signed, between both requests.
+type State struct {
+	Provider string `json:"provider"`
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
+
+func NewState(provider string) (State, error) {
+	state, err := random()
+	if err != nil {
+		return State{}, err
+	}
+	nonce, err := random()
+	if err != nil {
+		return State{}, err
+	}
+	return State{
+		Provider: provider,
+		State:    state,
+		Nonce:    nonce,
+		Verifier: oauth2.GenerateVerifier(),
+	}, nil
+}
+
+func random() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
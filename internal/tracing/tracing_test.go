@@ -0,0 +1,91 @@
+package tracing_test
+
+import (
+	"mygram/internal/handler"
+	"mygram/internal/infrastructure"
+	"mygram/internal/metrics"
+	"mygram/internal/middleware"
+	"mygram/internal/repository"
+	"mygram/internal/search"
+	"mygram/internal/service"
+	"mygram/internal/tracing"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestRequestIsTracedDownToGorm(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tracing.InstrumentGorm(gormDB); err != nil {
+		t.Fatal(err)
+	}
+	db := infrastructure.NewGormPostgresFromDB(gormDB)
+	mock.ExpectQuery(`SELECT .* FROM "photos"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "user_id"}).AddRow(1, "sunset", 1))
+
+	gin.SetMode(gin.TestMode)
+	g := gin.New()
+	g.ContextWithFallback = true
+	g.Use(middleware.Tracing())
+	svc := service.NewPhotoService(repository.NewPhotoQuery(db), infrastructure.NewUnitOfWork(db), metrics.NewNop(), search.NewEmbeddedIndex())
+	g.GET("/photos/:id", handler.NewPhotoHandler(svc).GetPhotoById)
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/photos/1?expand=", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	g.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		if span.SpanContext().TraceID().String() != traceID {
+			t.Errorf("span %s is not in the incoming trace: %s", span.Name(), span.SpanContext().TraceID())
+		}
+		spans[span.Name()] = span
+	}
+
+	// every layer is a child of the one above it
+	chain := []string{"GET /photos/:id", "PhotoService.GetExpandedPhoto", "PhotoQuery.GetExpandedPhoto", "gorm.query"}
+	parent := "00f067aa0ba902b7"
+	for _, name := range chain {
+		span, ok := spans[name]
+		if !ok {
+			t.Fatalf("expected a %s span, got %v", name, recorder.Ended())
+		}
+		if got := span.Parent().SpanID().String(); got != parent {
+			t.Errorf("expected %s to be a child of %s, got %s", name, parent, got)
+		}
+		parent = span.SpanContext().SpanID().String()
+	}
+	if kind := spans["GET /photos/:id"].SpanKind(); kind != trace.SpanKindServer {
+		t.Errorf("expected a server span for the request, got %s", kind)
+	}
+}
@@ -0,0 +1,19 @@
+ALTER TABLE users ADD COLUMN email_verified_at timestamp;
+
+-- accounts created before verification existed keep posting
+UPDATE users SET email_verified_at = created_at;
+
+CREATE TABLE user_tokens(
+    id serial primary key not null,
+    user_id int not null,
+    purpose varchar(32) not null,
+    token_hash varchar(64) not null unique,
+    expires_at timestamp not null,
+    used_at timestamp,
+    created_at timestamp not null default now(),
+    constraint fk_user_tokens_user_id
+        foreign key (user_id)
+        references users(id)
+);
+
+CREATE INDEX idx_user_tokens_user_id_purpose ON user_tokens(user_id, purpose);
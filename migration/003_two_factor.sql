@@ -0,0 +1,17 @@
+ALTER TABLE users
+    ADD COLUMN totp_secret varchar(64),
+    ADD COLUMN totp_enabled_at timestamp,
+    ADD COLUMN totp_last_step bigint not null default 0;
+
+CREATE TABLE user_recovery_codes(
+    id serial primary key not null,
+    user_id int not null,
+    code_hash varchar(64) not null,
+    used_at timestamp,
+    created_at timestamp not null default now(),
+    constraint fk_user_recovery_codes_user_id
+        foreign key (user_id)
+        references users(id)
+);
+
+CREATE INDEX idx_user_recovery_codes_user_id ON user_recovery_codes(user_id);
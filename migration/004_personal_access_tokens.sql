@@ -0,0 +1,17 @@
+CREATE TABLE personal_access_tokens(
+    id serial primary key not null,
+    user_id int not null,
+    name varchar(255) not null,
+    token_prefix varchar(16) not null,
+    token_hash varchar(64) not null unique,
+    scopes text not null,
+    expires_at timestamp,
+    last_used_at timestamp,
+    revoked_at timestamp,
+    created_at timestamp not null default now(),
+    constraint fk_personal_access_tokens_user_id
+        foreign key (user_id)
+        references users(id)
+);
+
+CREATE INDEX idx_personal_access_tokens_user_id ON personal_access_tokens(user_id);
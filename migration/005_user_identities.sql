@@ -0,0 +1,15 @@
+CREATE TABLE user_identities(
+    id serial primary key not null,
+    user_id int not null,
+    provider varchar(64) not null,
+    subject varchar(255) not null,
+    email varchar(255),
+    created_at timestamp not null default now(),
+    constraint fk_user_identities_user_id
+        foreign key (user_id)
+        references users(id),
+    constraint user_identities_provider_subject_key
+        unique (provider, subject)
+);
+
+CREATE INDEX idx_user_identities_user_id ON user_identities(user_id);
@@ -0,0 +1,34 @@
+CREATE TABLE user_sessions(
+    id serial primary key not null,
+    user_id int not null,
+    jti varchar(64) not null unique,
+    user_agent text not null default '',
+    ip varchar(64) not null default '',
+    client_name varchar(255) not null default '',
+    created_at timestamp not null default now(),
+    last_seen_at timestamp not null default now(),
+    expires_at timestamp not null,
+    revoked_at timestamp,
+    constraint fk_user_sessions_user_id
+        foreign key (user_id)
+        references users(id)
+);
+
+CREATE INDEX idx_user_sessions_user_id ON user_sessions(user_id);
+
+CREATE TABLE login_attempts(
+    id serial primary key not null,
+    user_id int not null,
+    method varchar(64) not null,
+    success boolean not null,
+    failure_reason varchar(255) not null default '',
+    user_agent text not null default '',
+    ip varchar(64) not null default '',
+    client_name varchar(255) not null default '',
+    created_at timestamp not null default now(),
+    constraint fk_login_attempts_user_id
+        foreign key (user_id)
+        references users(id)
+);
+
+CREATE INDEX idx_login_attempts_user_id_created_at ON login_attempts(user_id, created_at);
@@ -0,0 +1,18 @@
+ALTER TABLE users
+    ADD COLUMN private boolean not null default false;
+
+CREATE TABLE follows(
+    follower_id int not null,
+    followee_id int not null,
+    created_at timestamp not null default now(),
+    approved_at timestamp,
+    primary key (follower_id, followee_id),
+    constraint fk_follows_follower_id
+        foreign key (follower_id)
+        references users(id),
+    constraint fk_follows_followee_id
+        foreign key (followee_id)
+        references users(id)
+);
+
+CREATE INDEX idx_follows_followee_id ON follows(followee_id);
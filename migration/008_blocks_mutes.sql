@@ -0,0 +1,27 @@
+CREATE TABLE blocks(
+    blocker_id int not null,
+    blocked_id int not null,
+    created_at timestamp not null default now(),
+    primary key (blocker_id, blocked_id),
+    constraint fk_blocks_blocker_id
+        foreign key (blocker_id)
+        references users(id),
+    constraint fk_blocks_blocked_id
+        foreign key (blocked_id)
+        references users(id)
+);
+
+CREATE INDEX idx_blocks_blocked_id ON blocks(blocked_id);
+
+CREATE TABLE mutes(
+    muter_id int not null,
+    muted_id int not null,
+    created_at timestamp not null default now(),
+    primary key (muter_id, muted_id),
+    constraint fk_mutes_muter_id
+        foreign key (muter_id)
+        references users(id),
+    constraint fk_mutes_muted_id
+        foreign key (muted_id)
+        references users(id)
+);
@@ -0,0 +1,33 @@
+CREATE EXTENSION IF NOT EXISTS unaccent;
+
+ALTER TABLE photos ADD COLUMN search_vector tsvector;
+ALTER TABLE users ADD COLUMN search_vector tsvector;
+
+CREATE INDEX idx_photos_search_vector ON photos USING gin(search_vector);
+CREATE INDEX idx_users_search_vector ON users USING gin(search_vector);
+
+CREATE TABLE photo_hashtags(
+    photo_id int not null,
+    tag varchar(100) not null,
+    primary key (photo_id, tag),
+    constraint fk_photo_hashtags_photo_id
+        foreign key (photo_id)
+        references photos(id)
+);
+
+-- hashtags are searched by prefix
+CREATE INDEX idx_photo_hashtags_tag ON photo_hashtags(tag text_pattern_ops);
+
+UPDATE photos
+SET search_vector = setweight(to_tsvector('simple', unaccent(title)), 'A')
+    || setweight(to_tsvector('simple', unaccent(coalesce(caption, ''))), 'B')
+WHERE deleted_at IS NULL;
+
+UPDATE users
+SET search_vector = to_tsvector('simple', unaccent(username))
+WHERE deleted_at IS NULL;
+
+INSERT INTO photo_hashtags (photo_id, tag)
+SELECT DISTINCT p.id, lower(unaccent(m[1]))
+FROM photos p, regexp_matches(p.title || ' ' || coalesce(p.caption, ''), '#([[:alnum:]_]+)', 'g') AS m
+WHERE p.deleted_at IS NULL AND length(m[1]) <= 100;
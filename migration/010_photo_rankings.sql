@@ -0,0 +1,10 @@
+CREATE TABLE photo_rankings(
+    photo_id int primary key not null,
+    score double precision not null,
+    computed_at timestamp not null,
+    constraint fk_photo_rankings_photo_id
+        foreign key (photo_id)
+        references photos(id)
+);
+
+CREATE INDEX idx_photo_rankings_score ON photo_rankings(score DESC);
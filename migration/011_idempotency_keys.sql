@@ -0,0 +1,17 @@
+CREATE TABLE idempotency_keys(
+    id serial primary key not null,
+    user_id int not null,
+    key varchar(255) not null,
+    fingerprint varchar(64) not null,
+    locked_until timestamp not null,
+    response_status int not null default 0,
+    response_type varchar(255) not null default '',
+    response_body bytea,
+    completed_at timestamp,
+    expires_at timestamp not null,
+    created_at timestamp not null default now(),
+    constraint idempotency_keys_user_id_key_key unique (user_id, key),
+    constraint fk_idempotency_keys_user_id
+        foreign key (user_id)
+        references users(id)
+);
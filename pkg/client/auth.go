@@ -0,0 +1,122 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// Login logs in with email and password. The client keeps them to log in
+// again whenever the API refuses the token, once the session expired or was
+// signed out. Accounts with two-factor authentication get an
+// *MFARequiredError, finish with LoginMFA.
+func (c *Client) Login(ctx context.Context, email string, password string) error {
+	login := func(ctx context.Context) (string, error) {
+		var res loginRes
+		err := c.public(ctx, http.MethodPost, "/users/login", signInReq{Email: email, Password: password}, &res)
+		if err != nil {
+			return "", err
+		}
+		if res.MFARequired {
+			return "", &MFARequiredError{PendingToken: res.MFAPendingToken}
+		}
+		return res.Token, nil
+	}
+
+	token, err := login(ctx)
+	if err != nil {
+		return err
+	}
+	c.authenticate(token, login)
+	return nil
+}
+
+// LoginMFA finishes a Login with a TOTP or recovery code. The session cannot
+// be renewed without a new code, the client stops at ErrUnauthorized once it
+// ends.
+func (c *Client) LoginMFA(ctx context.Context, pendingToken string, code string) error {
+	var res tokenRes
+	body := twoFactorLoginReq{MFAPendingToken: pendingToken, Code: code}
+	if err := c.public(ctx, http.MethodPost, "/users/login/mfa", body, &res); err != nil {
+		return err
+	}
+	c.authenticate(res.Token, nil)
+	return nil
+}
+
+// LoginAsGuest authenticates with a guest token, which reads what anybody
+// may see. A new one is fetched when it expires.
+func (c *Client) LoginAsGuest(ctx context.Context) error {
+	guest := func(ctx context.Context) (string, error) {
+		var res tokenRes
+		if err := c.public(ctx, http.MethodGet, "/public", nil, &res); err != nil {
+			return "", err
+		}
+		return res.Token, nil
+	}
+
+	token, err := guest(ctx)
+	if err != nil {
+		return err
+	}
+	c.authenticate(token, guest)
+	return nil
+}
+
+// SignUp registers an account, which is verified from the email sent.
+func (c *Client) SignUp(ctx context.Context, req UserSignUp) (User, error) {
+	var res User
+	err := c.public(ctx, http.MethodPost, "/users/register", req, &res)
+	return res, err
+}
+
+func (c *Client) VerifyEmail(ctx context.Context, token string) error {
+	return c.public(ctx, http.MethodPost, "/users/verify-email", tokenReq{Token: token}, nil)
+}
+
+// ResendEmailVerification answers the same whether email has an account or
+// not.
+func (c *Client) ResendEmailVerification(ctx context.Context, email string) error {
+	return c.public(ctx, http.MethodPost, "/users/verify-email/resend", emailReq{Email: email}, nil)
+}
+
+// ForgotPassword answers the same whether email has an account or not.
+func (c *Client) ForgotPassword(ctx context.Context, email string) error {
+	return c.public(ctx, http.MethodPost, "/users/password/forgot", emailReq{Email: email}, nil)
+}
+
+func (c *Client) ResetPassword(ctx context.Context, token string, password string) error {
+	return c.public(ctx, http.MethodPost, "/users/password/reset", passwordResetReq{Token: token, Password: password}, nil)
+}
+
+// public calls the account routes, which take no token.
+func (c *Client) public(ctx context.Context, method string, path string, body any, res any) error {
+	cl, err := c.newCall(method, path, body, nil)
+	if err != nil {
+		return err
+	}
+	cl.anonymous = true
+	return c.do(ctx, cl, res)
+}
+
+// request sends an authenticated call and decodes the answer into res,
+// unless res is nil.
+func (c *Client) request(ctx context.Context, method string, path string, body any, res any, opts []CallOption) error {
+	cl, err := c.newCall(method, path, body, opts)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, cl, res)
+}
+
+// create is request for the creations, sent with an Idempotency-Key so that
+// they can be retried.
+func (c *Client) create(ctx context.Context, path string, body any, res any, opts []CallOption) error {
+	cl, err := c.newCall(http.MethodPost, path, body, opts)
+	if err != nil {
+		return err
+	}
+	if err := cl.idempotent(); err != nil {
+		return err
+	}
+	return c.do(ctx, cl, res)
+}
@@ -0,0 +1,432 @@
+// Package client is a typed client of the MyGram API for other Go services.
+//
+// Log in once and the client keeps the session going, logging in again when
+// the API refuses the token:
+//
+//	c := client.New("https://mygram.example.com")
+//	if err := c.Login(ctx, "alice@example.com", password); err != nil {
+//		return err
+//	}
+//	photo, err := c.GetPhoto(ctx, 42, client.Expand("user", "comments"))
+//	if errors.Is(err, client.ErrNotFound) {
+//		...
+//	}
+//
+// Reads, replacements, deletes and creations, which carry an Idempotency-Key,
+// are retried with backoff on network errors, 429 and 5xx answers.
+//
+// The OpenID Connect login routes are left out, they bind the login to a
+// browser.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	mathrand "math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// API_V1 is the version the client speaks.
+	API_V1 = "/v1"
+
+	HEADER_ETAG            = "ETag"
+	HEADER_IF_MATCH        = "If-Match"
+	HEADER_IF_NONE_MATCH   = "If-None-Match"
+	HEADER_IDEMPOTENCY_KEY = "Idempotency-Key"
+	HEADER_RETRY_AFTER     = "Retry-After"
+
+	MIME_JSON        = "application/json"
+	MIME_MERGE_PATCH = "application/merge-patch+json"
+)
+
+const (
+	defaultTimeout      = 30 * time.Second
+	idempotencyKeyBytes = 16
+	maxErrorBodyBytes   = 1 << 20
+)
+
+// RetryPolicy is how often and how patiently retryable calls are retried.
+type RetryPolicy struct {
+	// MaxAttempts counts the first attempt, 1 turns retries off.
+	MaxAttempts int
+	// BaseDelay doubles after every attempt, with jitter, up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 3, BaseDelay: 200 * time.Millisecond, MaxDelay: 5 * time.Second}
+}
+
+// delay is the backoff before attempt+1, a Retry-After asked by the server
+// wins when it is longer.
+func (p RetryPolicy) delay(attempt int, retryAfter time.Duration) time.Duration {
+	backoff := float64(p.BaseDelay) * math.Pow(2, float64(attempt-1))
+	if backoff > float64(p.MaxDelay) {
+		backoff = float64(p.MaxDelay)
+	}
+	// full jitter over the upper half, so clients spread out
+	d := time.Duration(backoff/2 + mathrand.Float64()*backoff/2)
+	if retryAfter > d {
+		d = retryAfter
+	}
+	return d
+}
+
+type options struct {
+	httpClient *http.Client
+	retry      RetryPolicy
+	token      string
+	userAgent  string
+}
+
+type Option func(*options)
+
+// WithHTTPClient replaces the default client, which times out after 30s.
+func WithHTTPClient(c *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = c
+	}
+}
+
+// WithRetryPolicy replaces DefaultRetryPolicy.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(o *options) {
+		o.retry = p
+	}
+}
+
+// WithToken authenticates with a token obtained elsewhere, a personal access
+// token for instance. It is not refreshed.
+func WithToken(token string) Option {
+	return func(o *options) {
+		o.token = token
+	}
+}
+
+// WithUserAgent sets the User-Agent, which shows in the sessions listed to
+// the user.
+func WithUserAgent(userAgent string) Option {
+	return func(o *options) {
+		o.userAgent = userAgent
+	}
+}
+
+// Client calls the API at one base URL. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	retry      RetryPolicy
+	userAgent  string
+
+	mu    sync.Mutex
+	token string
+	// refresh gets a new token once the API refuses the current one, nil
+	// when the token cannot be renewed.
+	refresh func(ctx context.Context) (string, error)
+}
+
+// New builds a client of the API served at baseURL, without the version.
+func New(baseURL string, opts ...Option) *Client {
+	o := options{
+		httpClient: &http.Client{Timeout: defaultTimeout},
+		retry:      DefaultRetryPolicy(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.retry.MaxAttempts < 1 {
+		o.retry.MaxAttempts = 1
+	}
+
+	return &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: o.httpClient,
+		retry:      o.retry,
+		userAgent:  o.userAgent,
+		token:      o.token,
+	}
+}
+
+// Token is the token the client authenticates with, empty before logging
+// in.
+func (c *Client) Token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
+
+// SetToken authenticates with token from now on and stops refreshing.
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+	c.refresh = nil
+}
+
+func (c *Client) authenticate(token string, refresh func(ctx context.Context) (string, error)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+	c.refresh = refresh
+}
+
+// renew replaces refused, the token the API just refused, unless another
+// call renewed it meanwhile. It reports whether there is a new token.
+func (c *Client) renew(ctx context.Context, refused string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != refused {
+		return true, nil
+	}
+	if c.refresh == nil {
+		return false, nil
+	}
+	token, err := c.refresh(ctx)
+	if err != nil {
+		return false, err
+	}
+	c.token = token
+	return true, nil
+}
+
+// call is one API call, built by the endpoint methods and the CallOptions.
+type call struct {
+	method      string
+	path        string
+	query       url.Values
+	header      http.Header
+	body        []byte
+	contentType string
+	// anonymous calls send no token, the login calls.
+	anonymous bool
+	// retryable calls may be sent again, see idempotent.
+	retryable bool
+	// etag receives the ETag of the answer, see ETag.
+	etag *string
+}
+
+// CallOption adds to a single call.
+type CallOption func(*call)
+
+// Expand names the relations to embed, none when called without any.
+func Expand(relations ...string) CallOption {
+	return func(c *call) {
+		c.query.Set("expand", strings.Join(relations, ","))
+	}
+}
+
+// Fields names the fields to answer with, the id and embedded relations are
+// always kept.
+func Fields(fields ...string) CallOption {
+	return func(c *call) {
+		c.query.Set("fields", strings.Join(fields, ","))
+	}
+}
+
+// IfMatch only applies a change while the resource still has etag, the
+// call fails with ErrPreconditionFailed otherwise.
+func IfMatch(etag string) CallOption {
+	return func(c *call) {
+		c.header.Set(HEADER_IF_MATCH, etag)
+	}
+}
+
+// IfNoneMatch makes a read fail with ErrNotModified while the resource still
+// has etag.
+func IfNoneMatch(etag string) CallOption {
+	return func(c *call) {
+		c.header.Set(HEADER_IF_NONE_MATCH, etag)
+	}
+}
+
+// IdempotencyKey replaces the key generated for a creation, to retry it
+// across restarts of the caller.
+func IdempotencyKey(key string) CallOption {
+	return func(c *call) {
+		c.header.Set(HEADER_IDEMPOTENCY_KEY, key)
+	}
+}
+
+func query(name string, value string) CallOption {
+	return func(c *call) {
+		c.query.Set(name, value)
+	}
+}
+
+// ETag stores the ETag of the answer in dst, for IfMatch and IfNoneMatch.
+func ETag(dst *string) CallOption {
+	return func(c *call) {
+		c.etag = dst
+	}
+}
+
+func (c *Client) newCall(method string, path string, body any, opts []CallOption) (*call, error) {
+	cl := &call{
+		method: method,
+		path:   API_V1 + path,
+		query:  url.Values{},
+		header: http.Header{},
+		// GET, PUT and DELETE may be repeated without changing the outcome
+		retryable: method == http.MethodGet || method == http.MethodPut || method == http.MethodDelete,
+	}
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("encode %s %s: %w", method, path, err)
+		}
+		cl.body = b
+		cl.contentType = MIME_JSON
+		if method == http.MethodPatch {
+			cl.contentType = MIME_MERGE_PATCH
+		}
+	}
+	for _, opt := range opts {
+		opt(cl)
+	}
+	return cl, nil
+}
+
+// idempotent sends cl with an Idempotency-Key, so that retries are replayed
+// the first answer instead of creating twice.
+func (cl *call) idempotent() error {
+	if cl.header.Get(HEADER_IDEMPOTENCY_KEY) == "" {
+		key := make([]byte, idempotencyKeyBytes)
+		if _, err := rand.Read(key); err != nil {
+			return err
+		}
+		cl.header.Set(HEADER_IDEMPOTENCY_KEY, hex.EncodeToString(key))
+	}
+	cl.retryable = true
+	return nil
+}
+
+// do sends cl and decodes the answer into res, unless res is nil. Refused
+// tokens are renewed once, retryable calls are retried by the RetryPolicy.
+func (c *Client) do(ctx context.Context, cl *call, res any) error {
+	renewed := false
+	for attempt := 1; ; attempt++ {
+		token := ""
+		if !cl.anonymous {
+			token = c.Token()
+		}
+
+		resp, err := c.send(ctx, cl, token)
+		if err != nil {
+			if !cl.retryable || attempt >= c.retry.MaxAttempts || ctx.Err() != nil {
+				return err
+			}
+			if err := sleep(ctx, c.retry.delay(attempt, 0)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if resp.StatusCode < http.StatusBadRequest {
+			defer resp.Body.Close()
+			if cl.etag != nil {
+				*cl.etag = resp.Header.Get(HEADER_ETAG)
+			}
+			if resp.StatusCode == http.StatusNotModified {
+				return ErrNotModified
+			}
+			if res == nil {
+				_, err := io.Copy(io.Discard, resp.Body)
+				return err
+			}
+			if err := json.NewDecoder(resp.Body).Decode(res); err != nil {
+				return fmt.Errorf("decode %s %s: %w", cl.method, cl.path, err)
+			}
+			return nil
+		}
+
+		apiErr := decodeError(resp)
+		// a refused token never reached the handler, any call may be sent again
+		if apiErr.tokenRefused() && token != "" && !renewed {
+			renewed = true
+			ok, err := c.renew(ctx, token)
+			if err != nil {
+				return fmt.Errorf("renew token: %w", err)
+			}
+			if ok {
+				attempt--
+				continue
+			}
+		}
+		if !cl.retryable || !apiErr.temporary() || attempt >= c.retry.MaxAttempts {
+			return apiErr
+		}
+		// waiting longer than the policy allows is left to the caller
+		if apiErr.RetryAfter > c.retry.MaxDelay {
+			return apiErr
+		}
+		if err := sleep(ctx, c.retry.delay(attempt, apiErr.RetryAfter)); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, cl *call, token string) (*http.Response, error) {
+	u := c.baseURL + cl.path
+	if len(cl.query) > 0 {
+		u += "?" + cl.query.Encode()
+	}
+	var body io.Reader
+	if cl.body != nil {
+		body = bytes.NewReader(cl.body)
+	}
+	req, err := http.NewRequestWithContext(ctx, cl.method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range cl.header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Accept", MIME_JSON)
+	if cl.contentType != "" {
+		req.Header.Set("Content-Type", cl.contentType)
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return c.httpClient.Do(req)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// retryAfter reads a Retry-After in seconds, 0 without one.
+func retryAfter(header http.Header) time.Duration {
+	seconds, err := strconv.Atoi(header.Get(HEADER_RETRY_AFTER))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func formatID(id uint64) string {
+	return strconv.FormatUint(id, 10)
+}
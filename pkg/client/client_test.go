@@ -0,0 +1,352 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/build"
+	"mygram/internal/apitest"
+	"mygram/pkg/client"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fastRetries keeps the suite quick, the backoff is not under test.
+var fastRetries = client.WithRetryPolicy(client.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond})
+
+func newServer(t *testing.T, h *apitest.Harness) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(h.Engine)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func login(t *testing.T, c *client.Client, username string) {
+	t.Helper()
+
+	if err := c.Login(context.Background(), username+"@example.com", apitest.DefaultPassword); err != nil {
+		t.Fatalf("login %s: %v", username, err)
+	}
+}
+
+func TestClientManagesPhotosCommentsAndSocialMedias(t *testing.T) {
+	ctx := context.Background()
+	h := apitest.New()
+	bob := h.CreateUser(t, "bob")
+	c := client.New(newServer(t, h).URL, fastRetries)
+	login(t, c, "bob")
+
+	created, err := c.CreatePhoto(ctx, client.PhotoCreateReq{Title: "sunset", Caption: "#beach", PhotoUrl: "https://example.com/sunset.jpg"})
+	if err != nil {
+		t.Fatalf("create photo: %v", err)
+	}
+
+	var etag string
+	photo, err := c.GetPhoto(ctx, created.ID, client.Expand("user"), client.ETag(&etag))
+	if err != nil {
+		t.Fatalf("get photo: %v", err)
+	}
+	if photo.Title != "sunset" || photo.User == nil || photo.User.Username != "bob" || etag == "" {
+		t.Fatalf("expected the photo with its owner and an etag, got %+v %q", photo, etag)
+	}
+	if _, err := c.GetPhoto(ctx, created.ID, client.IfNoneMatch(etag)); !errors.Is(err, client.ErrNotModified) {
+		t.Fatalf("expected ErrNotModified, got %v", err)
+	}
+
+	patched, err := c.PatchPhoto(ctx, created.ID, client.Patch{"caption": "#beach at dusk"}, client.IfMatch(etag))
+	if err != nil {
+		t.Fatalf("patch photo: %v", err)
+	}
+	if patched.Title != "sunset" || patched.Caption != "#beach at dusk" {
+		t.Fatalf("expected only the caption changed, got %+v", patched)
+	}
+	_, err = c.EditPhoto(ctx, created.ID, client.PhotoUpdateReq{Title: "stale", Caption: "stale", PhotoUrl: "https://example.com/stale.jpg"}, client.IfMatch(etag))
+	if !errors.Is(err, client.ErrPreconditionFailed) {
+		t.Fatalf("expected ErrPreconditionFailed with the old etag, got %v", err)
+	}
+
+	comment, err := c.CreateComment(ctx, client.CommentCreateReq{Message: "lovely", PhotoId: created.ID})
+	if err != nil {
+		t.Fatalf("create comment: %v", err)
+	}
+	comments, err := c.ListComments(ctx, created.ID)
+	if err != nil || len(comments) != 1 || comments[0].ID != comment.ID {
+		t.Fatalf("expected the comment listed, got %+v %v", comments, err)
+	}
+
+	social, err := c.CreateSocialMedia(ctx, client.SocialMediaReq{Name: "bob", SocialMediaUrl: "https://example.com/bob"})
+	if err != nil {
+		t.Fatalf("create social media: %v", err)
+	}
+	socials, err := c.ListSocialMedias(ctx, bob.ID)
+	if err != nil || len(socials) != 1 || socials[0].ID != social.ID {
+		t.Fatalf("expected the social media listed, got %+v %v", socials, err)
+	}
+
+	if err := c.DeletePhoto(ctx, created.ID); err != nil {
+		t.Fatalf("delete photo: %v", err)
+	}
+	// the API answers 404 for a user without photos
+	if _, err := c.ListPhotos(ctx, bob.ID); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("expected no photos left, got %v", err)
+	}
+	if _, err := c.GetPhoto(ctx, created.ID); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestClientDecodesErrorResponses(t *testing.T) {
+	ctx := context.Background()
+	h := apitest.New()
+	h.CreateUser(t, "bob")
+	c := client.New(newServer(t, h).URL, fastRetries)
+
+	err := c.Login(ctx, "bob@example.com", "wrong password")
+	if !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized, got %v", err)
+	}
+	apiErr := &client.Error{}
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized || apiErr.Message == "" {
+		t.Fatalf("expected the message of the API, got %+v", apiErr)
+	}
+
+	// without a token the API refuses, there is nothing to renew
+	if _, err := c.ListSessions(ctx); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized, got %v", err)
+	}
+
+	login(t, c, "bob")
+	_, err = c.CreatePhoto(ctx, client.PhotoCreateReq{Title: "no url"})
+	if !errors.Is(err, client.ErrBadRequest) || errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("expected only ErrBadRequest, got %v", err)
+	}
+}
+
+func TestClientLogsInAgainOnceTheSessionEnds(t *testing.T) {
+	ctx := context.Background()
+	h := apitest.New()
+	h.CreateUser(t, "bob")
+	server := newServer(t, h)
+
+	laptop := client.New(server.URL, fastRetries)
+	login(t, laptop, "bob")
+	phone := client.New(server.URL, fastRetries)
+	login(t, phone, "bob")
+
+	revoked, err := phone.RevokeOtherSessions(ctx)
+	if err != nil || revoked.Revoked != 1 {
+		t.Fatalf("expected the laptop signed out, got %+v %v", revoked, err)
+	}
+
+	signedOut := laptop.Token()
+	sessions, err := laptop.ListSessions(ctx)
+	if err != nil {
+		t.Fatalf("expected the laptop to log in again, got %v", err)
+	}
+	if laptop.Token() == signedOut || len(sessions) != 2 {
+		t.Fatalf("expected a new session beside the phone's, got %d sessions", len(sessions))
+	}
+
+	// a token given by the caller is not renewed
+	fixed := client.New(server.URL, fastRetries, client.WithToken(signedOut))
+	if _, err := fixed.ListSessions(ctx); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized, got %v", err)
+	}
+}
+
+func TestClientDoesNotLogInAgainForAnotherUsersResource(t *testing.T) {
+	ctx := context.Background()
+	h := apitest.New()
+	h.CreateUser(t, "alice")
+	h.CreateUser(t, "bob")
+	server := newServer(t, h)
+
+	alice := client.New(server.URL, fastRetries)
+	login(t, alice, "alice")
+	photo, err := alice.CreatePhoto(ctx, client.PhotoCreateReq{Title: "sunset", Caption: "at the beach", PhotoUrl: "https://example.com/sunset.jpg"})
+	if err != nil {
+		t.Fatalf("create photo: %v", err)
+	}
+
+	bob := client.New(server.URL, fastRetries)
+	login(t, bob, "bob")
+	token := bob.Token()
+	// the handler refuses the photo of alice, the token was accepted
+	if err := bob.DeletePhoto(ctx, photo.ID); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized, got %v", err)
+	}
+	sessions, err := bob.ListSessions(ctx)
+	if err != nil {
+		t.Fatalf("list sessions: %v", err)
+	}
+	if bob.Token() != token || len(sessions) != 1 {
+		t.Fatalf("expected bob to keep the session, got %d sessions", len(sessions))
+	}
+	if _, err := alice.GetPhoto(ctx, photo.ID); err != nil {
+		t.Fatalf("expected the photo kept, got %v", err)
+	}
+}
+
+func TestClientReadsAsGuest(t *testing.T) {
+	ctx := context.Background()
+	h := apitest.New()
+	bob := h.CreateUser(t, "bob")
+	server := newServer(t, h)
+
+	owner := client.New(server.URL, fastRetries)
+	login(t, owner, "bob")
+	if _, err := owner.CreatePhoto(ctx, client.PhotoCreateReq{Title: "sunset", Caption: "beach", PhotoUrl: "https://example.com/sunset.jpg"}); err != nil {
+		t.Fatalf("create photo: %v", err)
+	}
+
+	guest := client.New(server.URL, fastRetries)
+	if err := guest.LoginAsGuest(ctx); err != nil {
+		t.Fatalf("login as guest: %v", err)
+	}
+	photos, err := guest.ListPhotos(ctx, bob.ID, client.Expand())
+	if err != nil || len(photos) != 1 || photos[0].User != nil {
+		t.Fatalf("expected the photo without its owner, got %+v %v", photos, err)
+	}
+	user, err := guest.GetUser(ctx, bob.ID)
+	if err != nil || user.Username != "bob" || user.Email != "" {
+		t.Fatalf("expected the public profile, got %+v %v", user, err)
+	}
+	if _, err := guest.CreatePhoto(ctx, client.PhotoCreateReq{Title: "x", Caption: "x", PhotoUrl: "https://example.com/x.jpg"}); !errors.Is(err, client.ErrForbidden) {
+		t.Fatalf("expected guests refused, got %v", err)
+	}
+}
+
+// flaky answers 503 to the first failures requests of each path and method,
+// then hands them to the API.
+type flaky struct {
+	next     http.Handler
+	failures int
+
+	mu       sync.Mutex
+	attempts map[string]int
+	keys     map[string][]string
+}
+
+func (f *flaky) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	route := r.Method + " " + r.URL.Path
+	f.attempts[route]++
+	f.keys[route] = append(f.keys[route], r.Header.Get(client.HEADER_IDEMPOTENCY_KEY))
+	fail := f.attempts[route] <= f.failures
+	f.mu.Unlock()
+
+	if fail {
+		w.Header().Set("Content-Type", client.MIME_JSON)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `{"message":"try again"}`)
+		return
+	}
+	f.next.ServeHTTP(w, r)
+}
+
+func TestClientRetriesIdempotentCalls(t *testing.T) {
+	ctx := context.Background()
+	h := apitest.New()
+	bob := h.CreateUser(t, "bob")
+	alice := h.CreateUser(t, "alice")
+	f := &flaky{next: h.Engine, failures: 2, attempts: map[string]int{}, keys: map[string][]string{}}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+
+	c := client.New(server.URL, fastRetries, client.WithToken(h.Token(t, bob)))
+
+	created, err := c.CreatePhoto(ctx, client.PhotoCreateReq{Title: "sunset", Caption: "beach", PhotoUrl: "https://example.com/sunset.jpg"})
+	if err != nil {
+		t.Fatalf("expected the creation retried, got %v", err)
+	}
+	keys := f.keys["POST /v1/photos"]
+	if len(keys) != 3 || keys[0] == "" || keys[1] != keys[0] || keys[2] != keys[0] {
+		t.Fatalf("expected 3 attempts with the same idempotency key, got %q", keys)
+	}
+
+	if _, err := c.GetPhoto(ctx, created.ID); err != nil {
+		t.Fatalf("expected the read retried, got %v", err)
+	}
+
+	// following twice is not harmless, it is left to the caller
+	_, err = c.Follow(ctx, alice.ID)
+	if !errors.Is(err, &client.Error{StatusCode: http.StatusServiceUnavailable}) {
+		t.Fatalf("expected the 503, got %v", err)
+	}
+	if got := f.attempts[fmt.Sprintf("POST /v1/users/%d/follow", alice.ID)]; got != 1 {
+		t.Fatalf("expected a single attempt, got %d", got)
+	}
+
+	// the policy gives up after its attempts
+	f.mu.Lock()
+	f.failures = 5
+	f.mu.Unlock()
+	if _, err := c.ListPhotos(ctx, bob.ID); !errors.Is(err, &client.Error{StatusCode: http.StatusServiceUnavailable}) {
+		t.Fatalf("expected the 503 once out of attempts, got %v", err)
+	}
+	if got := f.attempts["GET /v1/photos"]; got != 3 {
+		t.Fatalf("expected 3 attempts, got %d", got)
+	}
+}
+
+func TestExplorePagesThroughTheRanking(t *testing.T) {
+	ctx := context.Background()
+	h := apitest.New()
+	h.CreateUser(t, "bob")
+	c := client.New(newServer(t, h).URL, fastRetries)
+	login(t, c, "bob")
+
+	want := map[uint64]bool{}
+	for i := 0; i < 5; i++ {
+		photo, err := c.CreatePhoto(ctx, client.PhotoCreateReq{Title: fmt.Sprint("photo ", i), Caption: "#sunset", PhotoUrl: "https://example.com/photo.jpg"})
+		if err != nil {
+			t.Fatalf("create photo: %v", err)
+		}
+		want[photo.ID] = true
+	}
+	if err := h.Ranking.Refresh(ctx); err != nil {
+		t.Fatalf("refresh ranking: %v", err)
+	}
+
+	photos, err := c.Explore(2).All(ctx)
+	if err != nil {
+		t.Fatalf("explore: %v", err)
+	}
+	if len(photos) != len(want) {
+		t.Fatalf("expected the %d photos over 3 pages, got %d", len(want), len(photos))
+	}
+	for _, photo := range photos {
+		if !want[photo.ID] {
+			t.Fatalf("expected each photo once, got %d again", photo.ID)
+		}
+		delete(want, photo.ID)
+	}
+
+	pager := c.ExploreHashtag("sunset", 10)
+	count := 0
+	for pager.Next(ctx) {
+		count++
+	}
+	if pager.Err() != nil || count != 5 {
+		t.Fatalf("expected the 5 photos tagged, got %d %v", count, pager.Err())
+	}
+}
+
+// TestClientOnlyDependsOnTheWire keeps the server's model, and with it the
+// database and validation, out of the services that import the client.
+func TestClientOnlyDependsOnTheWire(t *testing.T) {
+	pkg, err := build.ImportDir(".", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range pkg.Imports {
+		if strings.HasPrefix(path, "mygram/internal/") {
+			t.Errorf("client imports %s", path)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// CreateComment is sent with an Idempotency-Key, retries never comment twice.
+func (c *Client) CreateComment(ctx context.Context, req CommentCreateReq, opts ...CallOption) (CommentCreateRes, error) {
+	var res CommentCreateRes
+	err := c.create(ctx, "/comments", req, &res, opts)
+	return res, err
+}
+
+// ListComments lists the comments on a photo.
+func (c *Client) ListComments(ctx context.Context, photoID uint64, opts ...CallOption) ([]Comment, error) {
+	var res []Comment
+	opts = append([]CallOption{query("photo_id", formatID(photoID))}, opts...)
+	err := c.request(ctx, http.MethodGet, "/comments", nil, &res, opts)
+	return res, err
+}
+
+func (c *Client) GetComment(ctx context.Context, id uint64, opts ...CallOption) (Comment, error) {
+	var res Comment
+	err := c.request(ctx, http.MethodGet, "/comments/"+formatID(id), nil, &res, opts)
+	return res, err
+}
+
+func (c *Client) EditComment(ctx context.Context, id uint64, req CommentUpdateReq, opts ...CallOption) (CommentUpdateRes, error) {
+	var res CommentUpdateRes
+	err := c.request(ctx, http.MethodPut, "/comments/"+formatID(id), req, &res, opts)
+	return res, err
+}
+
+func (c *Client) PatchComment(ctx context.Context, id uint64, patch Patch, opts ...CallOption) (CommentUpdateRes, error) {
+	var res CommentUpdateRes
+	err := c.request(ctx, http.MethodPatch, "/comments/"+formatID(id), patch, &res, opts)
+	return res, err
+}
+
+func (c *Client) DeleteComment(ctx context.Context, id uint64) error {
+	return c.request(ctx, http.MethodDelete, "/comments/"+formatID(id), nil, nil, nil)
+}
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mygram/pkg"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Error is an answer of the API with an error status, decoded from its
+// pkg.ErrorResponse. Match the status with errors.Is and the sentinels
+// below:
+//
+//	if errors.Is(err, client.ErrNotFound) {
+type Error struct {
+	StatusCode int
+	Message    string
+	Errors     []string
+	// RetryAfter is how long the API asked to wait, 0 when it did not.
+	RetryAfter time.Duration
+}
+
+var (
+	ErrNotModified        = &Error{StatusCode: http.StatusNotModified}
+	ErrBadRequest         = &Error{StatusCode: http.StatusBadRequest}
+	ErrUnauthorized       = &Error{StatusCode: http.StatusUnauthorized}
+	ErrForbidden          = &Error{StatusCode: http.StatusForbidden}
+	ErrNotFound           = &Error{StatusCode: http.StatusNotFound}
+	ErrConflict           = &Error{StatusCode: http.StatusConflict}
+	ErrPreconditionFailed = &Error{StatusCode: http.StatusPreconditionFailed}
+	ErrTooManyRequests    = &Error{StatusCode: http.StatusTooManyRequests}
+)
+
+func (e *Error) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	if len(e.Errors) > 0 {
+		msg += ": " + strings.Join(e.Errors, "; ")
+	}
+	return fmt.Sprintf("mygram: %d %s", e.StatusCode, msg)
+}
+
+// Is matches any Error with the status of target.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.StatusCode == e.StatusCode
+}
+
+// temporary answers may change when asked again: rate limits, failures of
+// the server and creations still in progress under the same
+// Idempotency-Key.
+func (e *Error) temporary() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	case http.StatusConflict:
+		return e.RetryAfter > 0
+	}
+	return false
+}
+
+// tokenRefused reports whether the authentication middleware refused the
+// token, which it answers with the message "unauthorized". The 401 of a
+// handler, for a resource of another user, reached the handler and is not
+// fixed by a new token.
+func (e *Error) tokenRefused() bool {
+	return e.StatusCode == http.StatusUnauthorized && e.Message == "unauthorized"
+}
+
+// MFARequiredError is returned by Login for accounts with two-factor
+// authentication, finish with LoginMFA.
+type MFARequiredError struct {
+	PendingToken string
+}
+
+func (e *MFARequiredError) Error() string {
+	return "mygram: a second factor is required, finish with LoginMFA"
+}
+
+// decodeError reads the pkg.ErrorResponse of resp and closes it. Answers
+// that are not JSON, from a proxy for instance, keep the status text.
+func decodeError(resp *http.Response) *Error {
+	defer resp.Body.Close()
+
+	apiErr := &Error{StatusCode: resp.StatusCode, RetryAfter: retryAfter(resp.Header)}
+	var body pkg.ErrorResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxErrorBodyBytes)).Decode(&body); err == nil {
+		apiErr.Message = body.Message
+		apiErr.Errors = body.Errors
+	}
+	return apiErr
+}
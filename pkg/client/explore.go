@@ -0,0 +1,80 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const (
+	SEARCH_PHOTOS   = "photos"
+	SEARCH_USERS    = "users"
+	SEARCH_HASHTAGS = "hashtags"
+
+	// MaxPageSize is the most the API answers at once.
+	MaxPageSize = 50
+)
+
+// SearchPhotos answers up to limit photos matching q, 0 for the API's
+// default.
+func (c *Client) SearchPhotos(ctx context.Context, q string, limit int, opts ...CallOption) ([]Photo, error) {
+	var res []Photo
+	err := c.search(ctx, SEARCH_PHOTOS, q, limit, &res, opts)
+	return res, err
+}
+
+func (c *Client) SearchUsers(ctx context.Context, q string, limit int, opts ...CallOption) ([]PublicProfile, error) {
+	var res []PublicProfile
+	err := c.search(ctx, SEARCH_USERS, q, limit, &res, opts)
+	return res, err
+}
+
+// SearchHashtags takes q with or without the #.
+func (c *Client) SearchHashtags(ctx context.Context, q string, limit int, opts ...CallOption) ([]HashtagRes, error) {
+	var res []HashtagRes
+	err := c.search(ctx, SEARCH_HASHTAGS, q, limit, &res, opts)
+	return res, err
+}
+
+func (c *Client) search(ctx context.Context, kind string, q string, limit int, res any, opts []CallOption) error {
+	opts = append([]CallOption{query("q", q), query("type", kind)}, opts...)
+	if limit > 0 {
+		opts = append(opts, query("limit", strconv.Itoa(limit)))
+	}
+	return c.request(ctx, http.MethodGet, "/search", nil, res, opts)
+}
+
+// Explore pages through the trending photos, pageSize at a time.
+func (c *Client) Explore(pageSize int, opts ...CallOption) *Pager[Photo] {
+	return newPager(pageSize, func(ctx context.Context, limit int, offset int) ([]Photo, error) {
+		var res []Photo
+		err := c.request(ctx, http.MethodGet, "/explore", nil, &res, page(opts, limit, offset))
+		return res, err
+	})
+}
+
+// ExploreHashtag pages through the trending photos with tag, pageSize at a
+// time.
+func (c *Client) ExploreHashtag(tag string, pageSize int, opts ...CallOption) *Pager[Photo] {
+	return newPager(pageSize, func(ctx context.Context, limit int, offset int) ([]Photo, error) {
+		var res []Photo
+		err := c.request(ctx, http.MethodGet, "/explore/tags/"+url.PathEscape(tag), nil, &res, page(opts, limit, offset))
+		return res, err
+	})
+}
+
+// TrendingHashtags answers up to limit hashtags, 0 for the API's default.
+func (c *Client) TrendingHashtags(ctx context.Context, limit int) ([]TrendingHashtag, error) {
+	var res []TrendingHashtag
+	var opts []CallOption
+	if limit > 0 {
+		opts = append(opts, query("limit", strconv.Itoa(limit)))
+	}
+	err := c.request(ctx, http.MethodGet, "/explore/tags", nil, &res, opts)
+	return res, err
+}
+
+func page(opts []CallOption, limit int, offset int) []CallOption {
+	return append([]CallOption{query("limit", strconv.Itoa(limit)), query("offset", strconv.Itoa(offset))}, opts...)
+}
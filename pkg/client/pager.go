@@ -0,0 +1,72 @@
+package client
+
+import "context"
+
+// Pager iterates over a listing fetched a page at a time:
+//
+//	pager := c.Explore(20)
+//	for pager.Next(ctx) {
+//		photo := pager.Value()
+//	}
+//	if err := pager.Err(); err != nil {
+type Pager[T any] struct {
+	fetch  func(ctx context.Context, limit int, offset int) ([]T, error)
+	limit  int
+	offset int
+	page   []T
+	i      int
+	// last is set once a page came back short, there is nothing after it.
+	last bool
+	err  error
+}
+
+func newPager[T any](pageSize int, fetch func(ctx context.Context, limit int, offset int) ([]T, error)) *Pager[T] {
+	if pageSize < 1 || pageSize > MaxPageSize {
+		pageSize = MaxPageSize
+	}
+	return &Pager[T]{fetch: fetch, limit: pageSize, i: -1}
+}
+
+// Next advances to the next value, fetching the next page when needed. It
+// reports false at the end of the listing or on an error, see Err.
+func (p *Pager[T]) Next(ctx context.Context) bool {
+	if p.err != nil {
+		return false
+	}
+	if p.i+1 < len(p.page) {
+		p.i++
+		return true
+	}
+	if p.last {
+		return false
+	}
+
+	page, err := p.fetch(ctx, p.limit, p.offset)
+	if err != nil {
+		p.err = err
+		return false
+	}
+	p.page, p.i = page, 0
+	p.offset += len(page)
+	p.last = len(page) < p.limit
+	return len(page) > 0
+}
+
+// Value is the value Next advanced to.
+func (p *Pager[T]) Value() T {
+	return p.page[p.i]
+}
+
+// Err is the error that stopped Next, nil at the end of the listing.
+func (p *Pager[T]) Err() error {
+	return p.err
+}
+
+// All fetches the remaining values.
+func (p *Pager[T]) All(ctx context.Context) ([]T, error) {
+	var all []T
+	for p.Next(ctx) {
+		all = append(all, p.Value())
+	}
+	return all, p.Err()
+}
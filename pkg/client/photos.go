@@ -0,0 +1,43 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// CreatePhoto is sent with an Idempotency-Key, retries never post twice.
+func (c *Client) CreatePhoto(ctx context.Context, req PhotoCreateReq, opts ...CallOption) (PhotoCreateRes, error) {
+	var res PhotoCreateRes
+	err := c.create(ctx, "/photos", req, &res, opts)
+	return res, err
+}
+
+// ListPhotos lists the photos of a user.
+func (c *Client) ListPhotos(ctx context.Context, userID uint64, opts ...CallOption) ([]Photo, error) {
+	var res []Photo
+	opts = append([]CallOption{query("user_id", formatID(userID))}, opts...)
+	err := c.request(ctx, http.MethodGet, "/photos", nil, &res, opts)
+	return res, err
+}
+
+func (c *Client) GetPhoto(ctx context.Context, id uint64, opts ...CallOption) (Photo, error) {
+	var res Photo
+	err := c.request(ctx, http.MethodGet, "/photos/"+formatID(id), nil, &res, opts)
+	return res, err
+}
+
+func (c *Client) EditPhoto(ctx context.Context, id uint64, req PhotoUpdateReq, opts ...CallOption) (PhotoUpdateRes, error) {
+	var res PhotoUpdateRes
+	err := c.request(ctx, http.MethodPut, "/photos/"+formatID(id), req, &res, opts)
+	return res, err
+}
+
+func (c *Client) PatchPhoto(ctx context.Context, id uint64, patch Patch, opts ...CallOption) (PhotoUpdateRes, error) {
+	var res PhotoUpdateRes
+	err := c.request(ctx, http.MethodPatch, "/photos/"+formatID(id), patch, &res, opts)
+	return res, err
+}
+
+func (c *Client) DeletePhoto(ctx context.Context, id uint64) error {
+	return c.request(ctx, http.MethodDelete, "/photos/"+formatID(id), nil, nil, nil)
+}
@@ -0,0 +1,43 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// CreateSocialMedia is sent with an Idempotency-Key, retries never link twice.
+func (c *Client) CreateSocialMedia(ctx context.Context, req SocialMediaReq, opts ...CallOption) (SocialMediaCreateRes, error) {
+	var res SocialMediaCreateRes
+	err := c.create(ctx, "/social-medias", req, &res, opts)
+	return res, err
+}
+
+// ListSocialMedias lists the social media of a user.
+func (c *Client) ListSocialMedias(ctx context.Context, userID uint64, opts ...CallOption) ([]SocialMedia, error) {
+	var res []SocialMedia
+	opts = append([]CallOption{query("user_id", formatID(userID))}, opts...)
+	err := c.request(ctx, http.MethodGet, "/social-medias", nil, &res, opts)
+	return res, err
+}
+
+func (c *Client) GetSocialMedia(ctx context.Context, id uint64, opts ...CallOption) (SocialMedia, error) {
+	var res SocialMedia
+	err := c.request(ctx, http.MethodGet, "/social-medias/"+formatID(id), nil, &res, opts)
+	return res, err
+}
+
+func (c *Client) EditSocialMedia(ctx context.Context, id uint64, req SocialMediaReq, opts ...CallOption) (SocialMediaUpdateRes, error) {
+	var res SocialMediaUpdateRes
+	err := c.request(ctx, http.MethodPut, "/social-medias/"+formatID(id), req, &res, opts)
+	return res, err
+}
+
+func (c *Client) PatchSocialMedia(ctx context.Context, id uint64, patch Patch, opts ...CallOption) (SocialMediaUpdateRes, error) {
+	var res SocialMediaUpdateRes
+	err := c.request(ctx, http.MethodPatch, "/social-medias/"+formatID(id), patch, &res, opts)
+	return res, err
+}
+
+func (c *Client) DeleteSocialMedia(ctx context.Context, id uint64) error {
+	return c.request(ctx, http.MethodDelete, "/social-medias/"+formatID(id), nil, nil, nil)
+}
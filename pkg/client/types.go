@@ -0,0 +1,321 @@
+package client
+
+import "time"
+
+// The types below are those the API speaks on the wire, declared here rather
+// than taken from the server's model so that the client pulls in neither its
+// database nor its validation.
+
+type User struct {
+	ID              uint64     `json:"id"`
+	Username        string     `json:"username"`
+	Email           string     `json:"email"`
+	DoB             time.Time  `json:"dob"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	Private         bool       `json:"private"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+type PublicProfile struct {
+	ID        uint64    `json:"id"`
+	Username  string    `json:"username"`
+	Private   bool      `json:"private"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserRelation is the owner of a photo, comment or social media, or the
+// user of a list entry. Email is only shown to themselves.
+type UserRelation struct {
+	ID       uint64 `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email,omitempty"`
+}
+
+// ListedUser is an entry of the blocked or muted users.
+type ListedUser struct {
+	UserId    uint64       `json:"user_id"`
+	User      UserRelation `json:"User"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type UserSignUp struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	// DoB is a date such as 2000-01-31.
+	DoB string `json:"dob"`
+}
+
+type UserEditReq struct {
+	Email    string `json:"email"`
+	Username string `json:"username"`
+}
+
+type UserResponse struct {
+	ID        uint64    `json:"id"`
+	Email     string    `json:"email"`
+	Username  string    `json:"username"`
+	DoB       string    `json:"dob"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type TwoFactorEnrollRes struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+	// QRCode is a PNG data URI of ProvisioningURI.
+	QRCode string `json:"qr_code"`
+}
+
+type TwoFactorConfirmRes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type PersonalAccessToken struct {
+	ID          uint64     `json:"id"`
+	UserId      uint64     `json:"user_id"`
+	Name        string     `json:"name"`
+	TokenPrefix string     `json:"token_prefix"`
+	Scopes      []string   `json:"scopes"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type PersonalAccessTokenCreateReq struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// ExpiresInDays is optional, tokens without it do not expire.
+	ExpiresInDays int `json:"expires_in_days"`
+}
+
+// PersonalAccessTokenCreateRes carries the token itself, which is only ever
+// shown once.
+type PersonalAccessTokenCreateRes struct {
+	PersonalAccessToken
+	Token string `json:"token"`
+}
+
+type Session struct {
+	ID         uint64    `json:"id"`
+	UserId     uint64    `json:"user_id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	ClientName string    `json:"client_name"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// Current marks the session of the client.
+	Current bool `json:"current"`
+}
+
+type LoginAttempt struct {
+	ID            uint64    `json:"id"`
+	Method        string    `json:"method"`
+	Success       bool      `json:"success"`
+	FailureReason string    `json:"failure_reason,omitempty"`
+	UserAgent     string    `json:"user_agent"`
+	IP            string    `json:"ip"`
+	ClientName    string    `json:"client_name"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type FollowRes struct {
+	FolloweeId uint64 `json:"followee_id"`
+	Status     string `json:"status"`
+}
+
+type FollowRequest struct {
+	FollowerId  uint64       `json:"follower_id"`
+	User        UserRelation `json:"User"`
+	RequestedAt time.Time    `json:"requested_at"`
+}
+
+type Photo struct {
+	ID        uint64        `json:"id"`
+	Title     string        `json:"title"`
+	Caption   string        `json:"caption"`
+	PhotoUrl  string        `json:"photo_url"`
+	UserId    uint64        `json:"user_id"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+	User      *UserRelation `json:"User,omitempty"`
+	Comments  []Comment     `json:"Comments,omitempty"`
+}
+
+// PhotoRelation is the photo of a comment.
+type PhotoRelation struct {
+	ID       uint64 `json:"id"`
+	Title    string `json:"title"`
+	Caption  string `json:"caption"`
+	PhotoUrl string `json:"photo_url"`
+	UserId   uint64 `json:"user_id"`
+}
+
+type PhotoCreateReq struct {
+	Title    string `json:"title"`
+	Caption  string `json:"caption"`
+	PhotoUrl string `json:"photo_url"`
+}
+
+type PhotoCreateRes struct {
+	ID        uint64    `json:"id"`
+	Title     string    `json:"title"`
+	Caption   string    `json:"caption"`
+	PhotoUrl  string    `json:"photo_url"`
+	UserId    uint64    `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type PhotoUpdateReq struct {
+	Title    string `json:"title"`
+	Caption  string `json:"caption"`
+	PhotoUrl string `json:"photo_url"`
+}
+
+type PhotoUpdateRes struct {
+	ID        uint64    `json:"id"`
+	Title     string    `json:"title"`
+	Caption   string    `json:"caption"`
+	PhotoUrl  string    `json:"photo_url"`
+	UserId    uint64    `json:"user_id"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type Comment struct {
+	ID        uint64         `json:"id"`
+	Message   string         `json:"message"`
+	UserId    uint64         `json:"user_id"`
+	PhotoId   uint64         `json:"photo_id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	User      *UserRelation  `json:"User,omitempty"`
+	Photo     *PhotoRelation `json:"Photo,omitempty"`
+}
+
+type CommentCreateReq struct {
+	Message string `json:"message"`
+	PhotoId uint64 `json:"photo_id"`
+}
+
+type CommentCreateRes struct {
+	ID        uint64    `json:"id"`
+	Message   string    `json:"message"`
+	PhotoId   uint64    `json:"photo_id"`
+	UserId    uint64    `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CommentUpdateReq struct {
+	Message string `json:"message"`
+}
+
+type CommentUpdateRes struct {
+	ID        uint64    `json:"id"`
+	Message   string    `json:"message"`
+	PhotoId   uint64    `json:"photo_id"`
+	UserId    uint64    `json:"user_id"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type SocialMedia struct {
+	ID             uint64        `json:"id"`
+	Name           string        `json:"name"`
+	SocialMediaUrl string        `json:"social_media_url"`
+	UserId         uint64        `json:"user_id"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+	User           *UserRelation `json:"User,omitempty"`
+}
+
+type SocialMediaReq struct {
+	Name           string `json:"name"`
+	SocialMediaUrl string `json:"social_media_url"`
+}
+
+type SocialMediaCreateRes struct {
+	ID             uint64    `json:"id"`
+	Name           string    `json:"name"`
+	SocialMediaUrl string    `json:"social_media_url"`
+	UserId         uint64    `json:"user_id"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type SocialMediaUpdateRes struct {
+	ID             uint64    `json:"id"`
+	UserId         uint64    `json:"user_id"`
+	Name           string    `json:"name"`
+	SocialMediaUrl string    `json:"social_media_url"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type HashtagRes struct {
+	Tag    string `json:"tag"`
+	Photos int    `json:"photos"`
+}
+
+type TrendingHashtag struct {
+	Tag    string  `json:"tag"`
+	Photos int     `json:"photos"`
+	Score  float64 `json:"score"`
+}
+
+// Patch is a JSON merge patch (RFC 7396) of the fields to change, null
+// removes a field.
+type Patch map[string]any
+
+// RevokedSessions answers RevokeOtherSessions.
+type RevokedSessions struct {
+	Message string `json:"message"`
+	Revoked int64  `json:"revoked"`
+}
+
+type messageRes struct {
+	Message string `json:"message"`
+}
+
+type tokenRes struct {
+	Token string `json:"token"`
+}
+
+type loginRes struct {
+	Token           string `json:"token"`
+	MFARequired     bool   `json:"mfa_required"`
+	MFAPendingToken string `json:"mfa_pending_token"`
+}
+
+type signInReq struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type twoFactorLoginReq struct {
+	MFAPendingToken string `json:"mfa_pending_token"`
+	Code            string `json:"code"`
+}
+
+type tokenReq struct {
+	Token string `json:"token"`
+}
+
+type emailReq struct {
+	Email string `json:"email"`
+}
+
+type passwordResetReq struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type codeReq struct {
+	Code string `json:"code"`
+}
+
+type twoFactorDisableReq struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type privacyReq struct {
+	Private bool `json:"private"`
+}
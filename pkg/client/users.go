@@ -0,0 +1,161 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// GetUser reads a user. Anybody but the user only gets the fields of a
+// PublicProfile.
+func (c *Client) GetUser(ctx context.Context, id uint64, opts ...CallOption) (User, error) {
+	var res User
+	err := c.request(ctx, http.MethodGet, "/users/"+formatID(id), nil, &res, opts)
+	return res, err
+}
+
+func (c *Client) EditUser(ctx context.Context, id uint64, req UserEditReq, opts ...CallOption) (UserResponse, error) {
+	var res UserResponse
+	err := c.request(ctx, http.MethodPut, "/users/"+formatID(id), req, &res, opts)
+	return res, err
+}
+
+func (c *Client) PatchUser(ctx context.Context, id uint64, patch Patch, opts ...CallOption) (UserResponse, error) {
+	var res UserResponse
+	err := c.request(ctx, http.MethodPatch, "/users/"+formatID(id), patch, &res, opts)
+	return res, err
+}
+
+func (c *Client) DeleteUser(ctx context.Context, id uint64) error {
+	return c.request(ctx, http.MethodDelete, "/users/"+formatID(id), nil, nil, nil)
+}
+
+// two-factor authentication
+
+func (c *Client) EnrollTwoFactor(ctx context.Context) (TwoFactorEnrollRes, error) {
+	var res TwoFactorEnrollRes
+	err := c.request(ctx, http.MethodPost, "/users/2fa/enroll", nil, &res, nil)
+	return res, err
+}
+
+// ConfirmTwoFactor turns two-factor authentication on and answers the
+// recovery codes, shown this once.
+func (c *Client) ConfirmTwoFactor(ctx context.Context, code string) (TwoFactorConfirmRes, error) {
+	var res TwoFactorConfirmRes
+	err := c.request(ctx, http.MethodPost, "/users/2fa/confirm", codeReq{Code: code}, &res, nil)
+	return res, err
+}
+
+func (c *Client) DisableTwoFactor(ctx context.Context, password string, code string) error {
+	return c.request(ctx, http.MethodPost, "/users/2fa/disable", twoFactorDisableReq{Password: password, Code: code}, nil, nil)
+}
+
+// personal access tokens
+
+// CreatePersonalAccessToken answers the token, shown this once.
+func (c *Client) CreatePersonalAccessToken(ctx context.Context, req PersonalAccessTokenCreateReq) (PersonalAccessTokenCreateRes, error) {
+	var res PersonalAccessTokenCreateRes
+	err := c.request(ctx, http.MethodPost, "/users/tokens", req, &res, nil)
+	return res, err
+}
+
+func (c *Client) ListPersonalAccessTokens(ctx context.Context) ([]PersonalAccessToken, error) {
+	var res []PersonalAccessToken
+	err := c.request(ctx, http.MethodGet, "/users/tokens", nil, &res, nil)
+	return res, err
+}
+
+func (c *Client) RevokePersonalAccessToken(ctx context.Context, id uint64) error {
+	return c.request(ctx, http.MethodDelete, "/users/tokens/"+formatID(id), nil, nil, nil)
+}
+
+// sessions
+
+func (c *Client) ListSessions(ctx context.Context) ([]Session, error) {
+	var res []Session
+	err := c.request(ctx, http.MethodGet, "/users/me/sessions", nil, &res, nil)
+	return res, err
+}
+
+// RevokeOtherSessions signs out everywhere but this client.
+func (c *Client) RevokeOtherSessions(ctx context.Context) (RevokedSessions, error) {
+	var res RevokedSessions
+	err := c.request(ctx, http.MethodDelete, "/users/me/sessions", nil, &res, nil)
+	return res, err
+}
+
+func (c *Client) RevokeSession(ctx context.Context, id uint64) error {
+	return c.request(ctx, http.MethodDelete, "/users/me/sessions/"+formatID(id), nil, nil, nil)
+}
+
+func (c *Client) ListLoginHistory(ctx context.Context) ([]LoginAttempt, error) {
+	var res []LoginAttempt
+	err := c.request(ctx, http.MethodGet, "/users/me/login-history", nil, &res, nil)
+	return res, err
+}
+
+// follows
+
+// SetPrivacy makes the account private or public and answers which it is.
+func (c *Client) SetPrivacy(ctx context.Context, private bool) (bool, error) {
+	var res struct {
+		Private bool `json:"private"`
+	}
+	err := c.request(ctx, http.MethodPut, "/users/me/privacy", privacyReq{Private: private}, &res, nil)
+	return res.Private, err
+}
+
+// Follow follows a user, private accounts approve it first.
+func (c *Client) Follow(ctx context.Context, id uint64) (FollowRes, error) {
+	var res FollowRes
+	err := c.request(ctx, http.MethodPost, "/users/"+formatID(id)+"/follow", nil, &res, nil)
+	return res, err
+}
+
+// Unfollow unfollows a user or withdraws the request.
+func (c *Client) Unfollow(ctx context.Context, id uint64) error {
+	return c.request(ctx, http.MethodDelete, "/users/"+formatID(id)+"/follow", nil, nil, nil)
+}
+
+func (c *Client) ListFollowRequests(ctx context.Context) ([]FollowRequest, error) {
+	var res []FollowRequest
+	err := c.request(ctx, http.MethodGet, "/users/me/follow-requests", nil, &res, nil)
+	return res, err
+}
+
+func (c *Client) ApproveFollowRequest(ctx context.Context, followerID uint64) error {
+	return c.request(ctx, http.MethodPost, "/users/me/follow-requests/"+formatID(followerID)+"/approve", nil, nil, nil)
+}
+
+func (c *Client) DenyFollowRequest(ctx context.Context, followerID uint64) error {
+	return c.request(ctx, http.MethodDelete, "/users/me/follow-requests/"+formatID(followerID), nil, nil, nil)
+}
+
+// blocks and mutes
+
+func (c *Client) Block(ctx context.Context, id uint64) error {
+	return c.request(ctx, http.MethodPost, "/users/"+formatID(id)+"/block", nil, nil, nil)
+}
+
+func (c *Client) Unblock(ctx context.Context, id uint64) error {
+	return c.request(ctx, http.MethodDelete, "/users/"+formatID(id)+"/block", nil, nil, nil)
+}
+
+func (c *Client) ListBlocks(ctx context.Context) ([]ListedUser, error) {
+	var res []ListedUser
+	err := c.request(ctx, http.MethodGet, "/users/me/blocks", nil, &res, nil)
+	return res, err
+}
+
+func (c *Client) Mute(ctx context.Context, id uint64) error {
+	return c.request(ctx, http.MethodPost, "/users/"+formatID(id)+"/mute", nil, nil, nil)
+}
+
+func (c *Client) Unmute(ctx context.Context, id uint64) error {
+	return c.request(ctx, http.MethodDelete, "/users/"+formatID(id)+"/mute", nil, nil, nil)
+}
+
+func (c *Client) ListMutes(ctx context.Context) ([]ListedUser, error) {
+	var res []ListedUser
+	err := c.request(ctx, http.MethodGet, "/users/me/mutes", nil, &res, nil)
+	return res, err
+}
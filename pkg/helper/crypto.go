@@ -2,14 +2,26 @@ package helper
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
+	"slices"
 
 	"github.com/dgrijalva/jwt-go"
-	"golang.org/x/crypto/bcrypt"
 )
 
 const(
 	SECRET_JWT = "mysecretjwtdontsharethistoanyoneelse"
+
+	// TOKEN_ISSUER and TOKEN_AUDIENCE are the iss and aud of every token the
+	// API issues, ValidateToken refuses anything else.
+	TOKEN_ISSUER   = "go-middleware"
+	TOKEN_AUDIENCE = "golang-006"
+)
+
+var (
+	ErrTokenIssuer   = errors.New("token has an unexpected issuer")
+	ErrTokenAudience = errors.New("token is not meant for this API")
+	ErrTokenSubject  = errors.New("token cannot be used here")
 )
 
 func GenerateToken(claim any) (token string, err error) {
@@ -37,7 +49,10 @@ func GenerateToken(claim any) (token string, err error) {
 	return
 }
 
-func ValidateToken(token string) (claim jwt.MapClaims, err error) {
+// ValidateToken checks the signature, times, issuer and audience of token,
+// and that its sub is one of subjects. Tokens of every kind share the key, so
+// the subject is what keeps a public token from passing as a user token.
+func ValidateToken(token string, subjects ...string) (claim jwt.MapClaims, err error) {
 	jwtToken, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
 		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, jwt.ErrSignatureInvalid
@@ -54,21 +69,17 @@ func ValidateToken(token string) (claim jwt.MapClaims, err error) {
 	claim, ok := jwtToken.Claims.(jwt.MapClaims)
 	if !ok {
 		log.Println("error translate claim")
-		return
+		return nil, jwt.NewValidationError("invalid claims", jwt.ValidationErrorClaimsInvalid)
 	}
-	return
-}
 
-func GenerateHash(in string) (out string, err error) {
-	outByte, err := bcrypt.GenerateFromPassword([]byte(in), bcrypt.DefaultCost)
-	if err != nil {
-		log.Println("error generate hash password", err.Error())
-		return
+	if !claim.VerifyIssuer(TOKEN_ISSUER, true) {
+		return nil, ErrTokenIssuer
 	}
-	return string(outByte), err
-}
-
-func CheckPasswordHash(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
+	if !claim.VerifyAudience(TOKEN_AUDIENCE, true) {
+		return nil, ErrTokenAudience
+	}
+	if sub, _ := claim["sub"].(string); !slices.Contains(subjects, sub) {
+		return nil, ErrTokenSubject
+	}
+	return
 }